@@ -383,7 +383,7 @@ async function loadDossiers() {
                         </div>
                         <div class="dossier-header-actions">
                             <code>${safeId}</code>
-                            <button class="secondary-btn small-btn" onclick="toggleDossierPublic('${escapeAttr(d.id)}')">${d.isPublic ? 'Make Private' : 'Make Public'}</button>
+                            <button class="secondary-btn small-btn" onclick="toggleDossierPublic('${escapeAttr(d.id)}', ${!!d.isPublic})">${d.isPublic ? 'Make Private' : 'Make Public'}</button>
                             <button class="danger-btn small-btn" onclick="deleteDossier('${escapeAttr(d.id)}', '${escapeAttr(d.title)}')">Delete</button>
                         </div>
                     </div>
@@ -433,9 +433,10 @@ async function loadDossiers() {
     }
 }
 
-async function toggleDossierPublic(dossierId) {
+async function toggleDossierPublic(dossierId, isPublic) {
+    if (!isPublic && !confirm('A public dossier is visible to every user. Make it public?')) return;
     try {
-        const res = await fetch(`api/dossiers/${encodeURIComponent(dossierId)}/toggle-public`, {
+        const res = await fetch(`api/dossiers/${encodeURIComponent(dossierId)}/toggle-public${isPublic ? '' : '?confirm=public'}`, {
             method: 'POST',
             headers: { 'Content-Type': 'application/json' },
             body: JSON.stringify({})
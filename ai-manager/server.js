@@ -7,6 +7,7 @@ const { z } = require('zod');
 const fs = require('fs');
 const path = require('path');
 const axios = require('axios');
+const crypto = require('crypto');
 
 // ──────────────────────────────────────
 // Input validation schemas
@@ -27,6 +28,11 @@ const explainAuthzSchema = z.object({
     sharedAnimalsCount: z.number().int().min(0).max(10000).optional(),
 });
 
+const parseShareIntentSchema = z.object({
+    user: z.string().min(1).max(100),
+    text: z.string().min(1).max(1000),
+});
+
 const generateRuleSchema = z.object({
     prompt: z.string().min(1).max(2000),
 });
@@ -59,6 +65,7 @@ const auditEntrySchema = z.object({
     relation: z.string().max(100).optional(),
     method: z.string().max(20).optional(),
     reason: z.string().max(1000).optional(),
+    severity: z.string().max(20).optional(),
 });
 
 function validate(schema) {
@@ -111,6 +118,7 @@ const aiLimiter = rateLimit({
 app.use('/api/generate-rule', aiLimiter);
 app.use('/api/chat', aiLimiter);
 app.use('/api/explain-authz', aiLimiter);
+app.use('/api/parse-share-intent', aiLimiter);
 
 // Rate limiter for organization management (30 req per minute per IP)
 const orgLimiter = rateLimit({
@@ -181,6 +189,7 @@ function addAuditLog(entry) {
         relation: entry.relation || '',
         method: entry.method || '',
         reason: entry.reason || '',
+        severity: entry.severity || 'info',
     };
     auditLogs.unshift(log);
     if (auditLogs.length > MAX_AUDIT_LOGS) {
@@ -648,6 +657,7 @@ app.post('/audit', validate(auditEntrySchema), (req, res) => {
                 relation: entry.relation || '',
                 method: entry.method || '',
                 reason: entry.reason || '',
+                severity: entry.severity,
             });
         }
     } catch (err) {
@@ -692,6 +702,34 @@ app.get('/api/audit', (req, res) => {
 // Protected API routes (require AI Manager login)
 // ──────────────────────────────────────
 
+// Parses a natural-language sharing command into structured intents for the test-app.
+// The test-app validates, previews and executes the intents; this endpoint only parses.
+app.post('/api/parse-share-intent', validate(parseShareIntentSchema), async (req, res) => {
+    const { user, text } = req.body;
+    try {
+        const model = genAI.getGenerativeModel({ model: "gemini-2.0-flash" });
+        const prompt = `You translate sharing commands from user "${user}" of a citizen dossier system into JSON.
+Dossier types: tax, health, general. Relations: "can_view" (read-only access) and "mandate_holder" (read and edit).
+Respond ONLY with JSON of the form:
+{"intents": [{"action": "grant" | "revoke", "grantee": "<username>", "relation": "can_view" | "mandate_holder",
+  "dossierType": "tax" | "health" | "general" | "", "dossierIds": ["<id>", ...], "until": "<YYYY-MM-DD or empty>"}]}
+Use an empty dossierType and no dossierIds when the command targets all dossiers. Lowercase usernames.
+
+Command: ${text}`;
+        const result = await model.generateContent(prompt);
+        const response = await result.response;
+        const cleaned = response.text().replace(/```json\s*/g, '').replace(/```\s*/g, '');
+        const jsonMatch = cleaned.match(/\{[\s\S]*\}/);
+        if (!jsonMatch) {
+            return res.status(422).json({ error: 'Could not parse command' });
+        }
+        res.json(JSON.parse(jsonMatch[0]));
+    } catch (error) {
+        console.error("Parse share intent error:", error);
+        res.status(500).json({ error: error.message });
+    }
+});
+
 app.post('/api/generate-rule', validate(generateRuleSchema), async (req, res) => {
     const { prompt } = req.body;
     try {
@@ -1097,6 +1135,29 @@ const TEST_APP_URL = process.env.TEST_APP_URL || 'http://test-app:3000';
 // Admin header for manager - bypasses FGA checks in test-app
 const MANAGER_ADMIN_HEADERS = { 'x-manager-admin': 'true' };
 
+// Sign the identity headers of every call to test-app with SIGNING_KEY, the
+// same HMAC OPA adds at the gateway (see test-app/internal/identity).
+const SIGNED_IDENTITY_HEADERS = ['x-current-user', 'x-user-role', 'x-auth-acr', 'x-manager-admin', 'x-user-name', 'x-user-email', 'x-user-locale'];
+axios.interceptors.request.use((config) => {
+    const key = process.env.SIGNING_KEY;
+    const uri = axios.getUri(config);
+    if (!key || !uri.startsWith(TEST_APP_URL)) return config;
+    const url = new URL(uri);
+    const header = (name) => {
+        const v = config.headers?.[name];
+        return v === undefined || v === null ? '' : String(v);
+    };
+    const ts = String(Math.floor(Date.now() / 1000));
+    const payload = ['v1', ts, (config.method || 'get').toUpperCase(), url.pathname + url.search,
+        ...SIGNED_IDENTITY_HEADERS.map(header)].join('\n');
+    const sig = crypto.createHmac('sha256', key).update(payload).digest('hex');
+    config.headers['x-identity-signature'] = `t=${ts},v1=${sig}`;
+    return config;
+});
+
+// Forwards ?dryRun=true so mutations can be previewed without committing anything
+const dryRunParams = (req) => (req.query.dryRun === 'true' ? { dryRun: 'true' } : undefined);
+
 // Middleware to check ai-admin role for sensitive operations
 function requireAdminRole(req, res, next) {
     const roles = req.session?.user?.roles || [];
@@ -1108,7 +1169,7 @@ function requireAdminRole(req, res, next) {
 
 app.get('/api/organizations', async (req, res) => {
     try {
-        const result = await axios.get(`${TEST_APP_URL}/api/dossiers/organizations`);
+        const result = await axios.get(`${TEST_APP_URL}/api/dossiers/organizations`, { headers: MANAGER_ADMIN_HEADERS });
         res.json(result.data);
     } catch (e) {
         res.status(e.response?.status || 500).json({ error: e.response?.data?.error || e.message });
@@ -1220,7 +1281,7 @@ app.put('/api/dossiers/:id', requireAdminRole, async (req, res) => {
         const result = await axios.put(
             `${TEST_APP_URL}/api/dossiers/${encodeURIComponent(id)}`,
             req.body,
-            { headers: { 'x-current-user': user, ...MANAGER_ADMIN_HEADERS } }
+            { params: dryRunParams(req), headers: { 'x-current-user': user, ...MANAGER_ADMIN_HEADERS } }
         );
         res.json(result.data);
     } catch (e) {
@@ -1234,7 +1295,7 @@ app.delete('/api/dossiers/:id', requireAdminRole, async (req, res) => {
     try {
         const result = await axios.delete(
             `${TEST_APP_URL}/api/dossiers/${encodeURIComponent(id)}`,
-            { headers: { 'x-current-user': user, ...MANAGER_ADMIN_HEADERS } }
+            { params: dryRunParams(req), headers: { 'x-current-user': user, ...MANAGER_ADMIN_HEADERS } }
         );
         res.json(result.data);
     } catch (e) {
@@ -1249,7 +1310,7 @@ app.post('/api/dossiers/:id/toggle-public', requireAdminRole, async (req, res) =
         const result = await axios.post(
             `${TEST_APP_URL}/api/dossiers/${encodeURIComponent(id)}/toggle-public`,
             req.body,
-            { headers: { 'x-current-user': user, ...MANAGER_ADMIN_HEADERS } }
+            { params: { confirm: req.query.confirm }, headers: { 'x-current-user': user, ...MANAGER_ADMIN_HEADERS } }
         );
         res.json(result.data);
     } catch (e) {
@@ -1308,7 +1369,7 @@ app.post('/api/dossiers/:id/relations', requireAdminRole, async (req, res) => {
         const result = await axios.post(
             `${TEST_APP_URL}/api/dossiers/${encodeURIComponent(id)}/relations`,
             req.body,
-            { headers: { 'x-current-user': user, ...MANAGER_ADMIN_HEADERS } }
+            { params: dryRunParams(req), headers: { 'x-current-user': user, ...MANAGER_ADMIN_HEADERS } }
         );
         res.json(result.data);
     } catch (e) {
@@ -1322,7 +1383,7 @@ app.delete('/api/dossiers/:id/relations', requireAdminRole, async (req, res) =>
     try {
         const result = await axios.delete(
             `${TEST_APP_URL}/api/dossiers/${encodeURIComponent(id)}/relations`,
-            { data: req.body, headers: { 'x-current-user': user, ...MANAGER_ADMIN_HEADERS } }
+            { data: req.body, params: dryRunParams(req), headers: { 'x-current-user': user, ...MANAGER_ADMIN_HEADERS } }
         );
         res.json(result.data);
     } catch (e) {
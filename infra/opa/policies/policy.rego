@@ -8,16 +8,40 @@ default allow = false
 # Entry point - Output must follow Envoy External Authz structure
 allow = response if {
     authorized
+    identity := {
+        "x-current-user": token_payload.preferred_username,
+        "x-user-role": concat(",", token_payload.realm_access.roles),
+        "x-auth-acr": object.get(token_payload, "acr", ""),
+        # Profile claims test-app records as display name, email and locale
+        "x-user-name": object.get(token_payload, "name", ""),
+        "x-user-email": object.get(token_payload, "email", ""),
+        "x-user-locale": object.get(token_payload, "locale", ""),
+    }
     response := {
         "allowed": true,
-        "headers": {
+        "headers": object.union(identity, {
             "x-user-metadata": "authorized-by-opa",
-            "x-current-user": token_payload.preferred_username,
-            "x-user-role": concat(",", token_payload.realm_access.roles),
-        }
+            "x-identity-signature": identity_signature(identity),
+        })
     }
 }
 
+# HMAC over the request line and the identity headers, verified by test-app
+# (internal/identity) so the headers cannot be forged by calling it directly.
+# The path is the raw one with its query, as test-app sees it in RequestURI,
+# not the unversioned api_path the rules match on.
+# x-manager-admin is stripped by Envoy and therefore always signed empty.
+identity_signature(identity) := sig if {
+    ts := format_int(time.now_ns() / 1000000000, 10)
+    payload := concat("\n", [
+        "v1", ts, http_request.method, input.attributes.request.http.path,
+        identity["x-current-user"], identity["x-user-role"], identity["x-auth-acr"], "",
+        identity["x-user-name"], identity["x-user-email"], identity["x-user-locale"],
+    ])
+    key := object.get(opa.runtime().env, "SIGNING_KEY", "")
+    sig := sprintf("t=%s,v1=%s", [ts, crypto.hmac.sha256(payload, key)])
+}
+
 # Allow public paths without any auth headers
 allow = response if {
     is_public_path
@@ -151,7 +175,13 @@ allow = response if {
 # The home page "/" is accessible to any authenticated user (see below).
 # All /api/* paths require specific rules to grant access.
 
-http_request := input.attributes.request.http
+# /api/v1/... is served by test-app with the handlers of /api/..., so the rules
+# below match the unversioned path whichever form the caller used.
+http_request := object.union(input.attributes.request.http, {"path": api_path})
+
+api_path := concat("", ["/api/", trim_prefix(input.attributes.request.http.path, "/api/v1/")]) if {
+    startswith(input.attributes.request.http.path, "/api/v1/")
+} else := input.attributes.request.http.path
 
 default authorized = false
 
@@ -176,6 +206,15 @@ is_public_path if {
     startswith(http_request.path, "/grafana")
 }
 
+# Guest mode (GUEST_MODE=true): visitors without a token may read the dossier
+# list, which test-app limits to public dossiers. Writes stay 401 in test-app.
+is_public_path if {
+    object.get(opa.runtime().env, "GUEST_MODE", "") == "true"
+    not has_valid_token
+    http_request.method == "GET"
+    split(http_request.path, "?")[0] in {"/api/dossiers/list", "/partials/dossiers"}
+}
+
 # Home page and callback — any authenticated user can access
 authorized if {
     has_valid_token
@@ -216,6 +255,66 @@ authorized if {
     startswith(http_request.path, "/api/dossiers")
 }
 
+# Request traces — any authenticated user (scoped to the caller by the app)
+authorized if {
+    has_valid_token
+    startswith(http_request.path, "/api/audit/")
+}
+
+# Authorization explanations — any authenticated user (scoped to the caller by the app)
+authorized if {
+    has_valid_token
+    startswith(http_request.path, "/api/authz/")
+}
+
+# Server-rendered dossier fragments — any authenticated user (OpenFGA handles per-dossier access)
+authorized if {
+    has_valid_token
+    startswith(http_request.path, "/partials/")
+}
+
+# Generic registered resources — any authenticated user (OpenFGA handles per-object access)
+authorized if {
+    has_valid_token
+    startswith(http_request.path, "/api/resources")
+}
+
+# Pages of the registered resource types (e.g. /resources/vehicles) — any authenticated user
+authorized if {
+    has_valid_token
+    startswith(http_request.path, "/resources/")
+}
+
+# Caller-scoped endpoints (data export, memberships) — any authenticated user
+authorized if {
+    has_valid_token
+    startswith(http_request.path, "/api/me/")
+}
+
+# Saved dossier views — any authenticated user (views are scoped to their owner by the app)
+authorized if {
+    has_valid_token
+    startswith(http_request.path, "/api/views")
+}
+
+# Guided tour manifest — any authenticated user (scenario setup is restricted to admins by the app)
+authorized if {
+    has_valid_token
+    startswith(http_request.path, "/api/tour")
+}
+
+# Undo of a destructive action — any authenticated user (tokens are scoped to the caller by the app)
+authorized if {
+    has_valid_token
+    startswith(http_request.path, "/api/undo/")
+}
+
+# User profiles (display name for dossier and mandate listings) — any authenticated user
+authorized if {
+    has_valid_token
+    startswith(http_request.path, "/api/users/")
+}
+
 # --- Token Handling (JWKS signature verification) ---
 
 # Fetch JWKS from Keycloak (cached 5 min by http.send)
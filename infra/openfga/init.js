@@ -111,6 +111,9 @@ async function writeAuthModel(storeId) {
                 relations: {
                     owner: { this: {} },
                     mandate_holder: { this: {} },
+                    mandate_viewer: { this: {} },
+                    mandate_editor: { this: {} },
+                    mandate_sharer: { this: {} },
                     org_parent: { this: {} },
                     blocked: { this: {} },
                     public: { this: {} },
@@ -120,6 +123,9 @@ async function writeAuthModel(storeId) {
                                 { this: {} },
                                 { computedUserset: { relation: 'owner' } },
                                 { computedUserset: { relation: 'mandate_holder' } },
+                                { computedUserset: { relation: 'mandate_viewer' } },
+                                { computedUserset: { relation: 'mandate_editor' } },
+                                { computedUserset: { relation: 'mandate_sharer' } },
                                 { tupleToUserset: { tupleset: { relation: 'owner' }, computedUserset: { relation: 'guardian' } } },
                                 { tupleToUserset: { tupleset: { relation: 'org_parent' }, computedUserset: { relation: 'member' } } },
                                 { computedUserset: { relation: 'public' } }
@@ -136,6 +142,25 @@ async function writeAuthModel(storeId) {
                         union: {
                             child: [
                                 { this: {} },
+                                { computedUserset: { relation: 'owner' } },
+                                { computedUserset: { relation: 'mandate_holder' } },
+                                { computedUserset: { relation: 'mandate_editor' } },
+                                { computedUserset: { relation: 'mandate_sharer' } }
+                            ]
+                        }
+                    },
+                    can_share: {
+                        union: {
+                            child: [
+                                { computedUserset: { relation: 'owner' } },
+                                { computedUserset: { relation: 'mandate_holder' } },
+                                { computedUserset: { relation: 'mandate_sharer' } }
+                            ]
+                        }
+                    },
+                    can_delete: {
+                        union: {
+                            child: [
                                 { computedUserset: { relation: 'owner' } },
                                 { computedUserset: { relation: 'mandate_holder' } }
                             ]
@@ -146,6 +171,9 @@ async function writeAuthModel(storeId) {
                     relations: {
                         owner: { directly_related_user_types: [{ type: 'user' }] },
                         mandate_holder: { directly_related_user_types: [{ type: 'user' }] },
+                        mandate_viewer: { directly_related_user_types: [{ type: 'user' }] },
+                        mandate_editor: { directly_related_user_types: [{ type: 'user' }] },
+                        mandate_sharer: { directly_related_user_types: [{ type: 'user' }] },
                         org_parent: { directly_related_user_types: [{ type: 'organization' }] },
                         blocked: { directly_related_user_types: [{ type: 'user' }] },
                         public: { directly_related_user_types: [{ type: 'user', wildcard: {} }] },
@@ -153,6 +181,45 @@ async function writeAuthModel(storeId) {
                         editor: { directly_related_user_types: [{ type: 'user' }] }
                     }
                 }
+            },
+            // Vehicle registration demo: the generic resource type declared in
+            // infra/resources/types.json (GET /api/admin/resources/model renders the same).
+            {
+                type: 'vehicle',
+                relations: {
+                    owner: { this: {} },
+                    co_driver: { this: {} },
+                    insurer: { this: {} },
+                    lienholder: { this: {} },
+                    org_parent: { this: {} },
+                    editor: {
+                        union: {
+                            child: [
+                                { computedUserset: { relation: 'owner' } },
+                                { computedUserset: { relation: 'co_driver' } }
+                            ]
+                        }
+                    },
+                    viewer: {
+                        union: {
+                            child: [
+                                { computedUserset: { relation: 'editor' } },
+                                { computedUserset: { relation: 'insurer' } },
+                                { computedUserset: { relation: 'lienholder' } },
+                                { tupleToUserset: { tupleset: { relation: 'org_parent' }, computedUserset: { relation: 'member' } } }
+                            ]
+                        }
+                    }
+                },
+                metadata: {
+                    relations: {
+                        owner: { directly_related_user_types: [{ type: 'user' }] },
+                        co_driver: { directly_related_user_types: [{ type: 'user' }] },
+                        insurer: { directly_related_user_types: [{ type: 'user' }] },
+                        lienholder: { directly_related_user_types: [{ type: 'user' }] },
+                        org_parent: { directly_related_user_types: [{ type: 'organization' }] }
+                    }
+                }
             }
         ]
     };
@@ -0,0 +1,123 @@
+// Package analytics counts which demo scenarios have been exercised, keyed by
+// an anonymous participant id so presenters never see real usernames.
+package analytics
+
+import (
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/hex"
+	"sort"
+	"sync"
+	"time"
+)
+
+// Scenario milestones recorded by the handlers.
+const (
+	DossierCreated        = "dossier_created"
+	DossierMadePublic     = "dossier_made_public"
+	MandateGranted        = "mandate_granted"
+	EmergencyCheck        = "emergency_check"
+	GuardianshipRequested = "guardianship_requested"
+	GuardianshipAccepted  = "guardianship_accepted"
+	OrganizationCreated   = "organization_created"
+	UserBlocked           = "user_blocked"
+)
+
+// Milestones lists every milestone in walkthrough order.
+var Milestones = []string{
+	DossierCreated, MandateGranted, GuardianshipRequested, GuardianshipAccepted,
+	DossierMadePublic, UserBlocked, OrganizationCreated, EmergencyCheck,
+}
+
+// MilestoneStats aggregates one milestone across participants.
+type MilestoneStats struct {
+	Events       int       `json:"events"`
+	Participants int       `json:"participants"`
+	First        time.Time `json:"first,omitempty"`
+}
+
+// Report is the anonymized view of all recorded milestones.
+type Report struct {
+	Participants int                       `json:"participants"`
+	Milestones   map[string]MilestoneStats `json:"milestones"`
+	// ByParticipant maps an anonymous id to the milestones it reached, in order.
+	ByParticipant map[string][]string `json:"byParticipant"`
+}
+
+var (
+	mu     sync.Mutex
+	salt   = newSalt()
+	events = map[string]map[string]int{} // participant -> milestone -> count
+	first  = map[string]time.Time{}      // milestone -> first occurrence
+)
+
+func newSalt() []byte {
+	b := make([]byte, 16)
+	rand.Read(b)
+	return b
+}
+
+// anonymize maps a username to a stable per-process pseudonym.
+func anonymize(user string) string {
+	h := sha256.Sum256(append(append([]byte{}, salt...), user...))
+	return "p-" + hex.EncodeToString(h[:4])
+}
+
+// Record counts one occurrence of milestone for user.
+func Record(user, milestone string) {
+	id := anonymize(user)
+	mu.Lock()
+	defer mu.Unlock()
+	if events[id] == nil {
+		events[id] = map[string]int{}
+	}
+	events[id][milestone]++
+	if _, ok := first[milestone]; !ok {
+		first[milestone] = time.Now()
+	}
+}
+
+// Snapshot returns the current anonymized report.
+func Snapshot() Report {
+	mu.Lock()
+	defer mu.Unlock()
+	rep := Report{
+		Participants:  len(events),
+		Milestones:    map[string]MilestoneStats{},
+		ByParticipant: map[string][]string{},
+	}
+	for _, m := range Milestones {
+		rep.Milestones[m] = MilestoneStats{First: first[m]}
+	}
+	for id, counts := range events {
+		reached := []string{}
+		for m, n := range counts {
+			s := rep.Milestones[m]
+			s.Events += n
+			s.Participants++
+			s.First = first[m]
+			rep.Milestones[m] = s
+			reached = append(reached, m)
+		}
+		sort.Slice(reached, func(i, j int) bool { return milestoneOrder(reached[i]) < milestoneOrder(reached[j]) })
+		rep.ByParticipant[id] = reached
+	}
+	return rep
+}
+
+func milestoneOrder(m string) int {
+	for i, name := range Milestones {
+		if name == m {
+			return i
+		}
+	}
+	return len(Milestones)
+}
+
+// Reset clears all recorded milestones.
+func Reset() {
+	mu.Lock()
+	defer mu.Unlock()
+	events = map[string]map[string]int{}
+	first = map[string]time.Time{}
+}
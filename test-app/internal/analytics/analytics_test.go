@@ -0,0 +1,36 @@
+package analytics
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestRecord_AnonymizesAndAggregates(t *testing.T) {
+	Reset()
+	defer Reset()
+
+	Record("alice", DossierCreated)
+	Record("alice", DossierCreated)
+	Record("alice", MandateGranted)
+	Record("bob", DossierCreated)
+
+	rep := Snapshot()
+	if rep.Participants != 2 {
+		t.Errorf("participants = %d, want 2", rep.Participants)
+	}
+	created := rep.Milestones[DossierCreated]
+	if created.Events != 3 || created.Participants != 2 || created.First.IsZero() {
+		t.Errorf("dossier_created = %+v, want 3 events from 2 participants", created)
+	}
+	if got := rep.Milestones[EmergencyCheck]; got.Events != 0 {
+		t.Errorf("emergency_check = %+v, want no events", got)
+	}
+	for id, reached := range rep.ByParticipant {
+		if strings.Contains(id, "alice") || strings.Contains(id, "bob") {
+			t.Errorf("participant id %q leaks the username", id)
+		}
+		if len(reached) == 2 && (reached[0] != DossierCreated || reached[1] != MandateGranted) {
+			t.Errorf("milestones = %v, want walkthrough order", reached)
+		}
+	}
+}
@@ -3,15 +3,143 @@ package audit
 import (
 	"bytes"
 	"encoding/json"
+	"fmt"
 	"net/http"
+	"strings"
+	"sync"
+	"time"
 
 	"test-app/internal/config"
+	"test-app/internal/endpoints"
+	"test-app/internal/faults"
+	"test-app/internal/privacy"
 )
 
+// QueueStats describes the state of in-flight audit deliveries.
+type QueueStats struct {
+	Pending   int64  `json:"pending"`
+	Sent      int64  `json:"sent"`
+	Failed    int64  `json:"failed"`
+	LastError string `json:"lastError,omitempty"`
+}
+
+// Entry is a decision or write recorded locally in the recent-decisions buffer.
+type Entry struct {
+	Time     time.Time `json:"time"`
+	Source   string    `json:"source"`
+	Decision string    `json:"decision"`
+	User     string    `json:"user"`
+	Relation string    `json:"relation"`
+	Resource string    `json:"resource"`
+	Method   string    `json:"method"`
+	Reason   string    `json:"reason"`
+	// RequestId is the gateway's x-request-id, when the entry can be tied to one
+	RequestId string `json:"requestId,omitempty"`
+	// Severity is SeverityHigh for changes that expose data widely; empty otherwise
+	Severity string `json:"severity,omitempty"`
+}
+
+// SeverityHigh marks entries the audit sink should surface above routine
+// decisions, such as a wildcard tuple making an object public.
+const SeverityHigh = "high"
+
+const recentSize = 500
+
+var (
+	statsMu sync.Mutex
+	stats   QueueStats
+
+	recentMu sync.Mutex
+	recent   []Entry
+)
+
+func remember(e Entry) {
+	recentMu.Lock()
+	defer recentMu.Unlock()
+	recent = append(recent, e)
+	if len(recent) > recentSize {
+		recent = recent[len(recent)-recentSize:]
+	}
+}
+
+// Record keeps an entry produced elsewhere, such as an OPA decision, in the
+// local buffer only; it is not sent to the audit sink.
+func Record(e Entry) {
+	if e.Time.IsZero() {
+		e.Time = time.Now()
+	}
+	remember(e)
+}
+
+// Recent returns up to limit of the most recent entries concerning user
+// (matched with or without the "user:" prefix), newest first. An empty user matches all entries.
+func Recent(user string, limit int) []Entry {
+	recentMu.Lock()
+	defer recentMu.Unlock()
+	out := []Entry{}
+	for i := len(recent) - 1; i >= 0 && len(out) < limit; i-- {
+		e := recent[i]
+		if user == "" || e.User == user || strings.TrimPrefix(e.User, "user:") == user {
+			out = append(out, e)
+		}
+	}
+	return out
+}
+
+// Reset forgets the recent entries and request records, e.g. for a demo reset.
+// Entries already sent to the audit sink are not affected.
+func Reset() {
+	recentMu.Lock()
+	recent = nil
+	recentMu.Unlock()
+	requestsMu.Lock()
+	requests = nil
+	requestsMu.Unlock()
+}
+
+// Stats returns a snapshot of the audit delivery counters.
+func Stats() QueueStats {
+	statsMu.Lock()
+	defer statsMu.Unlock()
+	return stats
+}
+
+func record(err error) {
+	statsMu.Lock()
+	defer statsMu.Unlock()
+	stats.Pending--
+	if err != nil {
+		stats.Failed++
+		stats.LastError = err.Error()
+		return
+	}
+	stats.Sent++
+	stats.LastError = ""
+}
+
 func SendAuditLog(source, decision, user, relation, resource, method, reason string) {
-	if config.AuditURL == "" {
+	send(Entry{Source: source, Decision: decision, User: user, Relation: relation, Resource: resource, Method: method, Reason: reason})
+}
+
+// SendHighSeverity is SendAuditLog for an entry marked SeverityHigh. It is
+// never sampled out.
+func SendHighSeverity(source, decision, user, relation, resource, method, reason string) {
+	send(Entry{Source: source, Decision: decision, User: user, Relation: relation, Resource: resource, Method: method, Reason: reason, Severity: SeverityHigh})
+}
+
+func send(e Entry) {
+	e.Time = time.Now()
+	remember(e)
+	source, decision, user, relation, resource, method, reason := e.Source, e.Decision, e.User, e.Relation, e.Resource, e.Method, e.Reason
+	if config.AuditURL == "" || (e.Severity != SeverityHigh && !sample(source, decision, user)) {
 		return
 	}
+	statsMu.Lock()
+	stats.Pending++
+	statsMu.Unlock()
+	if privacy.Enabled() {
+		user, resource, reason = privacy.Subject(user), privacy.Subject(resource), privacy.Scrub(reason)
+	}
 	go func() {
 		entry := map[string]string{
 			"source":   source,
@@ -22,11 +150,30 @@ func SendAuditLog(source, decision, user, relation, resource, method, reason str
 			"method":   method,
 			"reason":   reason,
 		}
+		if e.Severity != "" {
+			entry["severity"] = e.Severity
+		}
 		b, _ := json.Marshal(entry)
-		resp, err := http.Post(config.AuditURL+"/audit", "application/json", bytes.NewReader(b))
+		if err := faults.Inject(faults.Audit); err != nil {
+			record(err)
+			return
+		}
+		resp, err := endpoints.Do(config.AuditURL, func(base string) (*http.Request, error) {
+			req, err := http.NewRequest("POST", base+"/audit", bytes.NewReader(b))
+			if err == nil {
+				req.Header.Set("Content-Type", "application/json")
+			}
+			return req, err
+		})
 		if err != nil {
+			record(err)
 			return
 		}
 		resp.Body.Close()
+		if resp.StatusCode >= 500 {
+			record(fmt.Errorf("audit target returned %d", resp.StatusCode))
+			return
+		}
+		record(nil)
 	}()
 }
@@ -69,3 +69,62 @@ func TestSendAuditLog_WithServer(t *testing.T) {
 		t.Errorf("user = %q, want alice", received["user"])
 	}
 }
+
+func TestStats_RecordsFailures(t *testing.T) {
+	origURL := config.AuditURL
+	defer func() { config.AuditURL = origURL }()
+	config.AuditURL = "http://127.0.0.1:1"
+
+	before := Stats()
+	SendAuditLog("test-source", "deny", "bob", "viewer", "dossier:d1", "CHECK", "unreachable")
+
+	for i := 0; i < 100; i++ {
+		if Stats().Failed > before.Failed {
+			break
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+	after := Stats()
+	if after.Failed != before.Failed+1 {
+		t.Errorf("Failed = %d, want %d", after.Failed, before.Failed+1)
+	}
+	if after.LastError == "" {
+		t.Error("LastError should be set after a failed delivery")
+	}
+}
+
+func TestSample(t *testing.T) {
+	ResetSampling()
+	defer ResetSampling()
+	origRoll := roll
+	defer func() { roll = origRoll }()
+	roll = func() float64 { return 0.5 }
+
+	if err := SetSampling(Sampling{AllowRate: 2}); err == nil {
+		t.Error("a rate above 1 should be refused")
+	}
+	if err := SetSampling(Sampling{AllowRate: 0.1, Sources: map[string]float64{"Dossiers": 1}, Users: map[string]float64{"user:carol": 0.9}, BurstPerSecond: 2}); err != nil {
+		t.Fatal(err)
+	}
+	if !sample("OpenFGA", "deny", "user:alice") || !sample("OpenFGA", "write", "user:alice") {
+		t.Error("denies and writes are always sent")
+	}
+	if sample("OpenFGA", "allow", "user:alice") {
+		t.Error("an allow above the rate should be skipped")
+	}
+	if !sample("Dossiers", "allow", "user:alice") || !sample("OpenFGA", "allow", "carol") {
+		t.Error("source and user overrides should apply")
+	}
+	// The burst guard allowed two this second; the next allow is held back, a deny is not.
+	if sample("Dossiers", "allow", "user:alice") || !sample("Dossiers", "deny", "user:alice") {
+		t.Error("the burst guard should hold back allows only")
+	}
+	if _, st := CurrentSampling(); st.Sent != 2 || st.Skipped != 1 || st.Throttled != 1 {
+		t.Errorf("stats = %+v", st)
+	}
+
+	ResetSampling()
+	if s, _ := CurrentSampling(); s.AllowRate != config.AuditAllowSampleRate.Get() || !sample("OpenFGA", "allow", "user:alice") {
+		t.Errorf("reset sampling = %+v", s)
+	}
+}
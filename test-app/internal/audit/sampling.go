@@ -0,0 +1,126 @@
+package audit
+
+import (
+	"fmt"
+	"math/rand"
+	"strings"
+	"sync"
+	"time"
+
+	"test-app/internal/config"
+)
+
+// Sampling decides which allow decisions reach the audit sink, so load tests
+// do not flood it. Denies, writes and every other decision are always sent,
+// and all entries still go to the local recent buffer.
+type Sampling struct {
+	// AllowRate is the fraction (0 to 1) of allow decisions sent
+	AllowRate float64 `json:"allowRate"`
+	// Sources overrides AllowRate for entries from a source, e.g. "OpenFGA"
+	Sources map[string]float64 `json:"sources,omitempty"`
+	// Users overrides AllowRate for entries about a user, and wins over Sources
+	Users map[string]float64 `json:"users,omitempty"`
+	// BurstPerSecond caps the sampled allow decisions sent in any one second; 0 disables the cap
+	BurstPerSecond int `json:"burstPerSecond"`
+}
+
+// SamplingStats counts the allow decisions kept from the audit sink.
+type SamplingStats struct {
+	Sent      int64 `json:"sent"`
+	Skipped   int64 `json:"skipped"`
+	Throttled int64 `json:"throttled"`
+}
+
+var (
+	samplingMu    sync.Mutex
+	sampling      *Sampling // nil until set: the configured defaults apply
+	samplingStats SamplingStats
+	burstWindow   time.Time
+	burstCount    int
+	roll          = rand.Float64
+)
+
+func configuredSampling() Sampling {
+	return Sampling{AllowRate: config.AuditAllowSampleRate.Get(), BurstPerSecond: config.AuditBurstPerSecond.Get()}
+}
+
+// SetSampling replaces the sampling settings until ResetSampling. User keys
+// may be given with or without the "user:" prefix.
+func SetSampling(s Sampling) error {
+	if s.BurstPerSecond < 0 {
+		return fmt.Errorf("burstPerSecond must not be negative")
+	}
+	rates := []float64{s.AllowRate}
+	for _, r := range s.Sources {
+		rates = append(rates, r)
+	}
+	users := make(map[string]float64, len(s.Users))
+	for u, r := range s.Users {
+		rates = append(rates, r)
+		users[strings.TrimPrefix(u, "user:")] = r
+	}
+	for _, r := range rates {
+		if r < 0 || r > 1 {
+			return fmt.Errorf("sampling rates must be between 0 and 1")
+		}
+	}
+	s.Users = users
+	samplingMu.Lock()
+	defer samplingMu.Unlock()
+	sampling = &s
+	return nil
+}
+
+// ResetSampling goes back to the configured settings and clears the counters.
+func ResetSampling() {
+	samplingMu.Lock()
+	defer samplingMu.Unlock()
+	sampling, samplingStats = nil, SamplingStats{}
+	burstWindow, burstCount = time.Time{}, 0
+}
+
+// CurrentSampling returns the settings in effect and the counters.
+func CurrentSampling() (Sampling, SamplingStats) {
+	samplingMu.Lock()
+	defer samplingMu.Unlock()
+	if sampling == nil {
+		return configuredSampling(), samplingStats
+	}
+	return *sampling, samplingStats
+}
+
+// sample reports whether an entry goes to the audit sink.
+func sample(source, decision, user string) bool {
+	if decision != "allow" {
+		return true
+	}
+	samplingMu.Lock()
+	defer samplingMu.Unlock()
+	s := configuredSampling()
+	if sampling != nil {
+		s = *sampling
+	}
+	rate := s.AllowRate
+	if r, ok := s.Sources[source]; ok {
+		rate = r
+	}
+	if r, ok := s.Users[strings.TrimPrefix(user, "user:")]; ok {
+		rate = r
+	}
+	if rate < 1 && roll() >= rate {
+		samplingStats.Skipped++
+		return false
+	}
+	if s.BurstPerSecond > 0 {
+		if now := time.Now().Truncate(time.Second); !now.Equal(burstWindow) {
+			burstWindow, burstCount = now, 0
+		}
+		if burstCount >= s.BurstPerSecond {
+			samplingStats.Throttled++
+			return false
+		}
+		burstCount++
+	}
+	samplingStats.Sent++
+	return true
+}
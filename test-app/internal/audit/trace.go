@@ -0,0 +1,143 @@
+package audit
+
+import (
+	"crypto/rand"
+	"encoding/hex"
+	"net/http"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+)
+
+// RequestRecord is the outcome of one HTTP request handled by the app.
+type RequestRecord struct {
+	Id     string    `json:"id"`
+	User   string    `json:"user,omitempty"`
+	Method string    `json:"method"`
+	Path   string    `json:"path"`
+	Status int       `json:"status"`
+	Start  time.Time `json:"start"`
+	End    time.Time `json:"end"`
+}
+
+// TraceStep is an audit entry in a trace. Correlation says how it was tied to
+// the request: "requestId" when the entry carries it (gateway decisions),
+// "window" when it is the same user's entry recorded while the request ran.
+type TraceStep struct {
+	Entry
+	Correlation string `json:"correlation"`
+}
+
+// Trace is the decision chain of a single request.
+type Trace struct {
+	RequestId string         `json:"requestId"`
+	Request   *RequestRecord `json:"request,omitempty"`
+	Steps     []TraceStep    `json:"steps"`
+}
+
+// User returns whose request the trace is (without the "user:" prefix), or "" when unknown.
+func (t Trace) User() string {
+	if t.Request != nil {
+		return t.Request.User
+	}
+	for _, s := range t.Steps {
+		if s.User != "" {
+			return strings.TrimPrefix(s.User, "user:")
+		}
+	}
+	return ""
+}
+
+const requestLogSize = 500
+
+var (
+	requestsMu sync.Mutex
+	requests   []RequestRecord
+)
+
+type statusRecorder struct {
+	http.ResponseWriter
+	status int
+}
+
+func (s *statusRecorder) WriteHeader(status int) {
+	if s.status == 0 {
+		s.status = status
+	}
+	s.ResponseWriter.WriteHeader(status)
+}
+
+func (s *statusRecorder) Write(p []byte) (int, error) {
+	if s.status == 0 {
+		s.status = http.StatusOK
+	}
+	return s.ResponseWriter.Write(p)
+}
+
+func (s *statusRecorder) Flush() {
+	if f, ok := s.ResponseWriter.(http.Flusher); ok {
+		f.Flush()
+	}
+}
+
+// TraceRequests records every request under its x-request-id, generating one
+// when the request did not come through Envoy, and echoes it in the response.
+func TraceRequests(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		id := r.Header.Get("x-request-id")
+		if id == "" {
+			b := make([]byte, 8)
+			rand.Read(b)
+			id = hex.EncodeToString(b)
+			r.Header.Set("x-request-id", id)
+		}
+		w.Header().Set("x-request-id", id)
+		rec := RequestRecord{Id: id, User: r.Header.Get("x-current-user"), Method: r.Method, Path: r.URL.Path, Start: time.Now()}
+		sw := &statusRecorder{ResponseWriter: w}
+		next.ServeHTTP(sw, r)
+		rec.End = time.Now()
+		rec.Status = sw.status
+		if rec.Status == 0 {
+			rec.Status = http.StatusOK
+		}
+		requestsMu.Lock()
+		requests = append(requests, rec)
+		if len(requests) > requestLogSize {
+			requests = requests[len(requests)-requestLogSize:]
+		}
+		requestsMu.Unlock()
+	})
+}
+
+// TraceOf assembles the decision chain of a request: gateway decisions logged
+// with its id plus the caller's own entries recorded while it ran, in time
+// order. It reports false when nothing is known about the id.
+func TraceOf(requestId string) (Trace, bool) {
+	t := Trace{RequestId: requestId, Steps: []TraceStep{}}
+	requestsMu.Lock()
+	for i := len(requests) - 1; i >= 0; i-- {
+		if requests[i].Id == requestId {
+			rec := requests[i]
+			t.Request = &rec
+			break
+		}
+	}
+	requestsMu.Unlock()
+
+	recentMu.Lock()
+	for _, e := range recent {
+		switch {
+		case e.RequestId == requestId:
+			t.Steps = append(t.Steps, TraceStep{Entry: e, Correlation: "requestId"})
+		case t.Request != nil && t.Request.User != "" && e.RequestId == "" &&
+			(e.User == "user:"+t.Request.User || e.User == t.Request.User) &&
+			!e.Time.Before(t.Request.Start) && !e.Time.After(t.Request.End):
+			t.Steps = append(t.Steps, TraceStep{Entry: e, Correlation: "window"})
+		}
+	}
+	recentMu.Unlock()
+
+	sort.SliceStable(t.Steps, func(i, j int) bool { return t.Steps[i].Time.Before(t.Steps[j].Time) })
+	return t, t.Request != nil || len(t.Steps) > 0
+}
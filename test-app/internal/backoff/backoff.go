@@ -0,0 +1,91 @@
+// Package backoff retries startup steps that wait on other containers with
+// exponential backoff instead of fixed sleeps, so a cold start goes on as soon
+// as its dependencies are ready. A wait can be cut short by Kick: when a
+// watched directory changes (KickOnChange) or an operator sends SIGHUP.
+package backoff
+
+import (
+	"math/rand"
+	"sync"
+	"time"
+)
+
+// Policy bounds a retry loop. Zero Attempts or Timeout leaves that bound off.
+type Policy struct {
+	// Initial is the first delay, doubled after each failed attempt up to Max
+	Initial  time.Duration
+	Max      time.Duration
+	Attempts int
+	Timeout  time.Duration
+}
+
+// Delay returns the pause after the given failed attempt (from 1): the
+// doubled delay, capped at Max, with its upper half randomized so replicas
+// do not retry in lockstep.
+func (p Policy) Delay(attempt int) time.Duration {
+	d := p.Initial
+	for i := 1; i < attempt && d < p.Max; i++ {
+		d *= 2
+	}
+	if p.Max > 0 && d > p.Max {
+		d = p.Max
+	}
+	if d <= 1 {
+		return d
+	}
+	return d/2 + time.Duration(rand.Int63n(int64(d/2)+1))
+}
+
+var (
+	kickMu sync.Mutex
+	kicked = make(chan struct{})
+)
+
+// Kick ends every wait in progress, so each retry loop tries again at once.
+func Kick() {
+	kickMu.Lock()
+	defer kickMu.Unlock()
+	close(kicked)
+	kicked = make(chan struct{})
+}
+
+func kickChan() <-chan struct{} {
+	kickMu.Lock()
+	defer kickMu.Unlock()
+	return kicked
+}
+
+// Retry calls fn until it returns nil, waiting p's delay between attempts or
+// less when kicked. It gives up after p.Attempts calls or once p.Timeout has
+// passed, returning fn's last error.
+func Retry(p Policy, fn func(attempt int) error) error {
+	var deadline time.Time
+	if p.Timeout > 0 {
+		deadline = time.Now().Add(p.Timeout)
+	}
+	for attempt := 1; ; attempt++ {
+		// Taken before the attempt, so a kick during it is not missed.
+		kick := kickChan()
+		err := fn(attempt)
+		if err == nil {
+			return nil
+		}
+		if p.Attempts > 0 && attempt >= p.Attempts {
+			return err
+		}
+		d := p.Delay(attempt)
+		if !deadline.IsZero() {
+			left := time.Until(deadline)
+			if left <= 0 {
+				return err
+			}
+			d = min(d, left)
+		}
+		timer := time.NewTimer(d)
+		select {
+		case <-timer.C:
+		case <-kick:
+			timer.Stop()
+		}
+	}
+}
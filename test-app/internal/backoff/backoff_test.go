@@ -0,0 +1,64 @@
+package backoff
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"runtime"
+	"testing"
+	"time"
+)
+
+func TestDelay(t *testing.T) {
+	p := Policy{Initial: 100 * time.Millisecond, Max: time.Second}
+	for attempt, want := range map[int]time.Duration{1: 100 * time.Millisecond, 3: 400 * time.Millisecond, 10: time.Second} {
+		if d := p.Delay(attempt); d < want/2 || d > want {
+			t.Errorf("Delay(%d) = %s, want within [%s, %s]", attempt, d, want/2, want)
+		}
+	}
+}
+
+func TestRetry(t *testing.T) {
+	calls := 0
+	err := Retry(Policy{Attempts: 3}, func(int) error {
+		calls++
+		return errors.New("not yet")
+	})
+	if err == nil || calls != 3 {
+		t.Errorf("Retry = %v after %d calls, want the last error after 3", err, calls)
+	}
+
+	// A kick cuts an hour-long wait short.
+	start := time.Now()
+	err = Retry(Policy{Initial: time.Hour}, func(attempt int) error {
+		if attempt == 1 {
+			go Kick()
+			return errors.New("not yet")
+		}
+		return nil
+	})
+	if err != nil || time.Since(start) > 5*time.Second {
+		t.Errorf("kicked Retry = %v after %s", err, time.Since(start))
+	}
+}
+
+func TestKickOnChange(t *testing.T) {
+	if runtime.GOOS != "linux" {
+		t.Skip("inotify only")
+	}
+	dir := t.TempDir()
+	stop := KickOnChange(dir)
+	defer stop()
+	path := filepath.Join(dir, "openfga-store.json")
+	start := time.Now()
+	err := Retry(Policy{Initial: time.Hour}, func(attempt int) error {
+		if attempt == 1 {
+			go os.WriteFile(path, []byte("{}"), 0o644)
+			return errors.New("not there yet")
+		}
+		return nil
+	})
+	if err != nil || time.Since(start) > 5*time.Second {
+		t.Errorf("Retry = %v after %s; the file write should have woken it", err, time.Since(start))
+	}
+}
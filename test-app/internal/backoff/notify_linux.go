@@ -0,0 +1,34 @@
+package backoff
+
+import (
+	"os"
+	"syscall"
+)
+
+// KickOnChange calls Kick whenever a file in dir is created, written or moved
+// in, until stop is called. It uses inotify; when dir cannot be watched (e.g.
+// it does not exist yet) it does nothing and the retry loops fall back to
+// their delays.
+func KickOnChange(dir string) (stop func()) {
+	fd, err := syscall.InotifyInit1(syscall.IN_CLOEXEC | syscall.IN_NONBLOCK)
+	if err != nil {
+		return func() {}
+	}
+	if _, err := syscall.InotifyAddWatch(fd, dir, syscall.IN_CREATE|syscall.IN_CLOSE_WRITE|syscall.IN_MOVED_TO); err != nil {
+		syscall.Close(fd)
+		return func() {}
+	}
+	// A non-blocking descriptor goes through the runtime poller, so Close
+	// unblocks the pending Read.
+	f := os.NewFile(uintptr(fd), "inotify:"+dir)
+	go func() {
+		buf := make([]byte, 4096)
+		for {
+			if _, err := f.Read(buf); err != nil {
+				return
+			}
+			Kick()
+		}
+	}()
+	return func() { f.Close() }
+}
@@ -0,0 +1,9 @@
+//go:build !linux
+
+package backoff
+
+// KickOnChange is a no-op where inotify is not available: the retry loops
+// rely on their delays alone.
+func KickOnChange(dir string) (stop func()) {
+	return func() {}
+}
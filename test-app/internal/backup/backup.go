@@ -0,0 +1,201 @@
+// Package backup writes timestamped copies of the data store together with a
+// full OpenFGA tuple export, prunes old ones and restores them on demand.
+package backup
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"os"
+	"path/filepath"
+	"sort"
+	"time"
+
+	"test-app/internal/config"
+	"test-app/internal/fga"
+	"test-app/internal/sandbox"
+	"test-app/internal/store"
+)
+
+const (
+	storeFile  = "dossiers.json"
+	tuplesFile = "tuples.json"
+	nameLayout = "20060102T150405Z"
+)
+
+// Info describes one backup directory.
+type Info struct {
+	Name    string    `json:"name"`
+	Created time.Time `json:"created"`
+	Size    int64     `json:"size"`
+	Tuples  bool      `json:"tuples"`
+}
+
+// RestoreReport summarises the tuple changes a Restore applied.
+type RestoreReport struct {
+	Name          string `json:"name"`
+	TuplesWritten int    `json:"tuplesWritten"`
+	TuplesDeleted int    `json:"tuplesDeleted"`
+}
+
+// Create writes the current store and, when readTuples succeeds, the full tuple
+// set into a new timestamped directory under dir.
+func Create(dir string, readTuples func() ([]store.TupleKey, error)) (Info, error) {
+	now := time.Now().UTC()
+	name := now.Format(nameLayout)
+	path := filepath.Join(dir, name)
+	if err := os.MkdirAll(path, 0700); err != nil {
+		return Info{}, err
+	}
+	if err := os.WriteFile(filepath.Join(path, storeFile), store.Snapshot(), 0600); err != nil {
+		return Info{}, err
+	}
+	if readTuples != nil {
+		tuples, err := readTuples()
+		if err != nil {
+			log.Printf("WARNING: backup %s has no tuple snapshot: %v", name, err)
+		} else {
+			data, _ := fga.EncodeTuples(tuples, "json")
+			if err := os.WriteFile(filepath.Join(path, tuplesFile), data, 0600); err != nil {
+				return Info{}, err
+			}
+		}
+	}
+	return describe(dir, name)
+}
+
+// List returns the backups in dir, newest first.
+func List(dir string) ([]Info, error) {
+	entries, err := os.ReadDir(dir)
+	if os.IsNotExist(err) {
+		return []Info{}, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	backups := []Info{}
+	for _, e := range entries {
+		if !e.IsDir() {
+			continue
+		}
+		if info, err := describe(dir, e.Name()); err == nil {
+			backups = append(backups, info)
+		}
+	}
+	sort.Slice(backups, func(i, j int) bool { return backups[i].Name > backups[j].Name })
+	return backups, nil
+}
+
+func describe(dir, name string) (Info, error) {
+	created, err := time.Parse(nameLayout, name)
+	if err != nil {
+		return Info{}, fmt.Errorf("%s is not a backup", name)
+	}
+	st, err := os.Stat(filepath.Join(dir, name, storeFile))
+	if err != nil {
+		return Info{}, err
+	}
+	info := Info{Name: name, Created: created, Size: st.Size()}
+	if tst, err := os.Stat(filepath.Join(dir, name, tuplesFile)); err == nil {
+		info.Tuples = true
+		info.Size += tst.Size()
+	}
+	return info, nil
+}
+
+// Prune keeps the newest keep backups in dir and deletes the rest.
+func Prune(dir string, keep int) error {
+	backups, err := List(dir)
+	if err != nil {
+		return err
+	}
+	for i := keep; i < len(backups); i++ {
+		if err := os.RemoveAll(filepath.Join(dir, backups[i].Name)); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// Restore replaces the store with backup name and, when it carries a tuple
+// snapshot, writes and deletes tuples until OpenFGA matches it.
+func Restore(dir, name string, readTuples func() ([]store.TupleKey, error), write func(writes, deletes []store.TupleKey) error) (RestoreReport, error) {
+	rep := RestoreReport{Name: name}
+	if _, err := describe(dir, name); err != nil {
+		return rep, fmt.Errorf("backup %s not found", name)
+	}
+	raw, err := os.ReadFile(filepath.Join(dir, name, storeFile))
+	if err != nil {
+		return rep, err
+	}
+	if tuplesRaw, err := os.ReadFile(filepath.Join(dir, name, tuplesFile)); err == nil {
+		want, err := fga.DecodeTuples(tuplesRaw, "json")
+		if err != nil {
+			return rep, fmt.Errorf("corrupt tuple snapshot: %w", err)
+		}
+		actual, err := readTuples()
+		if err != nil {
+			return rep, err
+		}
+		writes, deletes := diff(want, actual)
+		for i := 0; i < len(writes); i += 10 {
+			if err := write(writes[i:min(i+10, len(writes))], nil); err != nil {
+				return rep, err
+			}
+			rep.TuplesWritten += min(10, len(writes)-i)
+		}
+		for i := 0; i < len(deletes); i += 10 {
+			if err := write(nil, deletes[i:min(i+10, len(deletes))]); err != nil {
+				return rep, err
+			}
+			rep.TuplesDeleted += min(10, len(deletes)-i)
+		}
+	}
+	if err := store.Replace(raw); err != nil {
+		return rep, fmt.Errorf("corrupt store snapshot: %w", err)
+	}
+	store.Save()
+	return rep, nil
+}
+
+// diff returns the tuples in want missing from actual, and those in actual not in want.
+func diff(want, actual []store.TupleKey) (missing, extra []store.TupleKey) {
+	have := make(map[store.TupleKey]bool, len(actual))
+	for _, t := range actual {
+		have[t] = true
+	}
+	wanted := make(map[store.TupleKey]bool, len(want))
+	for _, t := range want {
+		wanted[t] = true
+		if !have[t] {
+			missing = append(missing, t)
+		}
+	}
+	for _, t := range actual {
+		if !wanted[t] {
+			extra = append(extra, t)
+		}
+	}
+	return missing, extra
+}
+
+// RunEvery creates a backup every interval and prunes to keep. It blocks, so
+// call it in its own goroutine.
+func RunEvery(dir string, interval time.Duration, keep int) {
+	for {
+		time.Sleep(interval)
+		readTuples := func() ([]store.TupleKey, error) { return fga.ReadAll(context.Background()) }
+		if !config.FgaReady {
+			readTuples = nil
+		}
+		var err error
+		sandbox.Live(func() { _, err = Create(dir, readTuples) })
+		if err != nil {
+			log.Printf("WARNING: scheduled backup failed: %v", err)
+			continue
+		}
+		if err := Prune(dir, keep); err != nil {
+			log.Printf("WARNING: pruning backups failed: %v", err)
+		}
+	}
+}
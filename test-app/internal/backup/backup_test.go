@@ -0,0 +1,71 @@
+package backup
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"test-app/internal/store"
+)
+
+func TestCreateListRestore(t *testing.T) {
+	origData := store.Data
+	defer func() { store.Data = origData }()
+	store.Data = &store.DataStore{Dossiers: map[string]*store.Dossier{
+		"d1": {Title: "Tax", Owners: []string{"alice"}},
+	}}
+	dir := t.TempDir()
+	snapshot := []store.TupleKey{{User: "user:alice", Relation: "owner", Object: "dossier:d1"}}
+
+	info, err := Create(dir, func() ([]store.TupleKey, error) { return snapshot, nil })
+	if err != nil {
+		t.Fatalf("Create: %v", err)
+	}
+	if !info.Tuples {
+		t.Error("backup should carry a tuple snapshot")
+	}
+	backups, _ := List(dir)
+	if len(backups) != 1 || backups[0].Name != info.Name {
+		t.Fatalf("List = %+v", backups)
+	}
+
+	store.Data = &store.DataStore{Dossiers: map[string]*store.Dossier{}}
+	current := []store.TupleKey{{User: "user:bob", Relation: "owner", Object: "dossier:d2"}}
+	var written, deleted []store.TupleKey
+	rep, err := Restore(dir, info.Name, func() ([]store.TupleKey, error) { return current, nil },
+		func(w, d []store.TupleKey) error {
+			written = append(written, w...)
+			deleted = append(deleted, d...)
+			return nil
+		})
+	if err != nil {
+		t.Fatalf("Restore: %v", err)
+	}
+	if rep.TuplesWritten != 1 || rep.TuplesDeleted != 1 || written[0] != snapshot[0] || deleted[0] != current[0] {
+		t.Errorf("report = %+v, written = %v, deleted = %v", rep, written, deleted)
+	}
+	if d, ok := store.Data.Dossiers["d1"]; !ok || d.Title != "Tax" {
+		t.Errorf("store not restored: %+v", store.Data.Dossiers)
+	}
+
+	if _, err := Restore(dir, "../etc", nil, nil); err == nil {
+		t.Error("restoring an invalid name should fail")
+	}
+}
+
+func TestPrune(t *testing.T) {
+	dir := t.TempDir()
+	for i := 0; i < 4; i++ {
+		name := time.Date(2026, 1, 1+i, 0, 0, 0, 0, time.UTC).Format(nameLayout)
+		os.MkdirAll(filepath.Join(dir, name), 0700)
+		os.WriteFile(filepath.Join(dir, name, storeFile), []byte("{}"), 0600)
+	}
+	if err := Prune(dir, 2); err != nil {
+		t.Fatalf("Prune: %v", err)
+	}
+	backups, _ := List(dir)
+	if len(backups) != 2 || backups[0].Name != "20260104T000000Z" {
+		t.Errorf("after prune: %+v", backups)
+	}
+}
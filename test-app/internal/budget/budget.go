@@ -0,0 +1,174 @@
+// Package budget counts the OpenFGA calls each HTTP request makes and enforces
+// config.FgaCallBudget, to surface N+1 check patterns such as a Check per list
+// item. Track puts the request's tally in its context and the FGA client
+// counts each call against the context it is given, so workers a handler
+// starts count as long as they pass the request's context on; calls made
+// with a context not derived from a request, e.g. by background jobs, are
+// not counted.
+package budget
+
+import (
+	"context"
+	"errors"
+	"log"
+	"net/http"
+	"strings"
+	"sync"
+
+	"test-app/internal/config"
+	"test-app/internal/httputil"
+	"test-app/internal/i18n"
+)
+
+// ErrExceeded is returned by Count once a request is over budget in reject mode.
+var ErrExceeded = errors.New("authorization call budget exceeded")
+
+// RouteStats aggregates FGA calls for one route.
+type RouteStats struct {
+	Requests   uint64 `json:"requests"`
+	Calls      uint64 `json:"calls"`
+	Max        int    `json:"max"`
+	OverBudget uint64 `json:"overBudget"`
+}
+
+type tally struct {
+	calls    int
+	exceeded bool
+}
+
+type tallyKey struct{}
+
+var (
+	mu     sync.Mutex
+	routes = map[string]*RouteStats{}
+)
+
+// Count records one FGA call for the request ctx belongs to. In reject mode
+// it returns ErrExceeded once the request has used up its budget, so the call
+// is not made.
+func Count(ctx context.Context) error {
+	t, _ := ctx.Value(tallyKey{}).(*tally)
+	if t == nil {
+		return nil
+	}
+	mu.Lock()
+	defer mu.Unlock()
+	t.calls++
+	if config.FgaCallBudget.Get() > 0 && t.calls > config.FgaCallBudget.Get() {
+		t.exceeded = true
+		if config.FgaBudgetMode.Get() == "reject" {
+			return ErrExceeded
+		}
+	}
+	return nil
+}
+
+// Track counts the FGA calls of every request handled by next and records
+// them per route of mux. A request over budget is logged; in reject mode its
+// response is replaced by a 503 unless the handler had already started it.
+func Track(next http.Handler, mux *http.ServeMux) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		t := &tally{}
+		bw := &budgetWriter{ResponseWriter: w, t: t}
+		next.ServeHTTP(bw, r.WithContext(context.WithValue(r.Context(), tallyKey{}, t)))
+		mu.Lock()
+		route := Route(r, mux)
+		rs := routes[route]
+		if rs == nil {
+			rs = &RouteStats{}
+			routes[route] = rs
+		}
+		rs.Requests++
+		rs.Calls += uint64(t.calls)
+		if t.calls > rs.Max {
+			rs.Max = t.calls
+		}
+		if t.exceeded {
+			rs.OverBudget++
+		}
+		mu.Unlock()
+		if t.exceeded {
+			log.Printf("WARNING: %s made %d FGA calls (budget %d)", route, t.calls, config.FgaCallBudget.Get())
+			if bw.dropped {
+				httputil.JSONError(w, i18n.T(r, "Authorization call budget exceeded (%d calls)", t.calls), 503)
+			}
+		}
+	})
+}
+
+// budgetWriter passes the response through until the request goes over budget
+// in reject mode; after that, a response not yet started is held back.
+type budgetWriter struct {
+	http.ResponseWriter
+	t       *tally
+	started bool
+	dropped bool
+}
+
+func (b *budgetWriter) hold() bool {
+	if !b.started {
+		mu.Lock()
+		b.dropped = b.t.exceeded && config.FgaBudgetMode.Get() == "reject"
+		mu.Unlock()
+	}
+	b.started = true
+	return b.dropped
+}
+
+func (b *budgetWriter) WriteHeader(status int) {
+	if !b.hold() {
+		b.ResponseWriter.WriteHeader(status)
+	}
+}
+
+func (b *budgetWriter) Write(p []byte) (int, error) {
+	if b.hold() {
+		return len(p), nil
+	}
+	return b.ResponseWriter.Write(p)
+}
+
+func (b *budgetWriter) Flush() {
+	if f, ok := b.ResponseWriter.(http.Flusher); ok && !b.dropped {
+		f.Flush()
+	}
+}
+
+// Route names the route r matched on mux, e.g. "GET /api/dossiers/{id}/relations":
+// for prefix patterns the first path element after the prefix, and any later
+// element containing a digit, stand for identifiers.
+func Route(r *http.Request, mux *http.ServeMux) string {
+	_, pattern := mux.Handler(r)
+	if pattern == "" {
+		return r.Method + " (unmatched)"
+	}
+	rest, ok := strings.CutPrefix(r.URL.Path, pattern)
+	if !strings.HasSuffix(pattern, "/") || !ok || rest == "" {
+		return r.Method + " " + pattern
+	}
+	parts := strings.Split(rest, "/")
+	for i, p := range parts {
+		if i == 0 || strings.ContainsAny(p, "0123456789") {
+			parts[i] = "{id}"
+		}
+	}
+	return r.Method + " " + pattern + strings.Join(parts, "/")
+}
+
+// Stats returns the per-route counters.
+func Stats() map[string]RouteStats {
+	mu.Lock()
+	defer mu.Unlock()
+	out := make(map[string]RouteStats, len(routes))
+	for route, rs := range routes {
+		out[route] = *rs
+	}
+	return out
+}
+
+// Reset forgets the per-route counters.
+func Reset() {
+	mu.Lock()
+	defer mu.Unlock()
+	routes = map[string]*RouteStats{}
+}
@@ -0,0 +1,64 @@
+package budget
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"test-app/internal/config"
+)
+
+func TestTrack(t *testing.T) {
+	defer Reset()
+	origBudget, origMode := config.FgaCallBudget.Get(), config.FgaBudgetMode.Get()
+	defer func() {
+		config.FgaCallBudget.Set(origBudget)
+		config.FgaBudgetMode.Set(origMode)
+	}()
+	config.FgaCallBudget.Set(3)
+
+	mux := http.NewServeMux()
+	var errs int
+	mux.HandleFunc("/api/dossiers/", func(w http.ResponseWriter, r *http.Request) {
+		for i := 0; i < 4; i++ {
+			if Count(r.Context()) != nil {
+				errs++
+			}
+		}
+		// A worker's calls count when it is handed the request's context.
+		done := make(chan error)
+		go func() {
+			done <- Count(r.Context())
+		}()
+		if <-done != nil {
+			errs++
+		}
+		w.Write([]byte(`{"ok":true}`))
+	})
+	handler := Track(mux, mux)
+	serve := func() *httptest.ResponseRecorder {
+		w := httptest.NewRecorder()
+		handler.ServeHTTP(w, httptest.NewRequest("GET", "/api/dossiers/ab12cd34/relations", nil))
+		return w
+	}
+
+	config.FgaBudgetMode.Set("log")
+	if w := serve(); w.Code != 200 || errs != 0 {
+		t.Errorf("log mode: status %d, %d refused calls", w.Code, errs)
+	}
+	config.FgaBudgetMode.Set("reject")
+	if w := serve(); w.Code != 503 || errs != 2 {
+		t.Errorf("reject mode: status %d, %d refused calls, want 503 and 2", w.Code, errs)
+	}
+
+	rs, ok := Stats()["GET /api/dossiers/{id}/relations"]
+	if !ok || rs.Requests != 2 || rs.Calls != 10 || rs.Max != 5 || rs.OverBudget != 2 {
+		t.Errorf("stats = %+v", Stats())
+	}
+
+	// Calls outside a tracked request are not counted.
+	if err := Count(context.Background()); err != nil {
+		t.Errorf("untracked Count = %v", err)
+	}
+}
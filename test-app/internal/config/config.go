@@ -5,9 +5,102 @@ import "time"
 var (
 	ExternalURL string
 	AuditURL    string
-	OpenfgaURL  string
-	FgaStoreId  string
-	FgaModelId  string
-	FgaReady    bool
-	StartTime   = time.Now()
+	// AuditAllowSampleRate is the fraction (0 to 1) of allow decisions sent to the audit sink; denies and writes always are
+	AuditAllowSampleRate = NewSetting(1.0)
+	// AuditBurstPerSecond caps the sampled allow decisions sent per second; 0 disables the cap
+	AuditBurstPerSecond = NewSetting(0)
+	// EndpointCooldown is how long a failing OpenFGA or audit URL is skipped when others are configured
+	EndpointCooldown = NewSetting(10 * time.Second)
+	// AIManagerURL is the base URL of the AI Manager used for explanations
+	AIManagerURL string
+	OpenfgaURL   string
+	FgaStoreId   string
+	FgaModelId   string
+	FgaReady     bool
+	// RehydrateMode controls startup tuple sync: off, verify or full
+	RehydrateMode = "full"
+	// AssertionsInterval is how often stored FGA assertions are re-run; 0 disables
+	AssertionsInterval = 5 * time.Minute
+	// StepUpAcr lists the acr claim values accepted as strong auth for secret dossiers
+	StepUpAcr = "2"
+	// CacheMaxAge is the Cache-Control max-age in seconds for list responses; 0 forces revalidation
+	CacheMaxAge = NewSetting(0)
+	// ListObjectsCacheTTL is how long ListObjects results are cached; 0 disables the cache
+	ListObjectsCacheTTL = NewSetting(5 * time.Second)
+	// VisibilityIndex serves dossier lists from the background visibility index when current
+	VisibilityIndex bool
+	// VisibilityMaxLag is the oldest index entry served; older ones fall back to ListObjects
+	VisibilityMaxLag = NewSetting(30 * time.Second)
+	// FgaCallBudget is the most OpenFGA calls one HTTP request should make; 0 only counts them
+	FgaCallBudget = NewSetting(50)
+	// FgaBudgetMode is what happens past FgaCallBudget: log, or reject (the request fails with 503)
+	FgaBudgetMode = NewSetting("log")
+	// ListCheckConcurrency is how many per-item checks a list request runs at once; 1 runs them in turn
+	ListCheckConcurrency = NewSetting(8)
+	// CompressMinSize is the smallest response body, in bytes, that gets gzip/deflate compressed
+	CompressMinSize = 1024
+	// MaxContentSize is the largest dossier content accepted, in bytes
+	MaxContentSize = NewSetting(64 << 10)
+	// IntegrityMode controls the startup data check: repair, strict (refuse on any issue) or off
+	IntegrityMode = "repair"
+	// BackupDir holds timestamped store and tuple backups
+	BackupDir = "/data/backups"
+	// BackupInterval is how often a backup is taken; 0 disables scheduled backups
+	BackupInterval = 6 * time.Hour
+	// BackupRetention is how many backups are kept
+	BackupRetention = 14
+	// StoreCompactInterval is how often decided requests and orphaned archives are compacted away; 0 disables compaction
+	StoreCompactInterval = time.Hour
+	// StoreRequestRetention is how long decided guardianship, join and access requests are kept
+	StoreRequestRetention = 30 * 24 * time.Hour
+	// StoreSizeWarnBytes is the data file size past which a warning suggests a database backend; 0 disables it
+	StoreSizeWarnBytes int64 = 10 << 20
+	// DataMinFreeBytes is the free space on the data volume under which health reports it low and a warning is logged; 0 only compares with the data file size
+	DataMinFreeBytes = NewSetting(100 << 20)
+	// FgaWriteBatchMax is the most tuples concurrent writes are merged into per OpenFGA write call
+	FgaWriteBatchMax = NewSetting(100)
+	// UndoWindow is how long a dossier deletion, guardianship removal or mandate revocation can be undone; 0 disables undo
+	UndoWindow = NewSetting(5 * time.Minute)
+	// StaleGrantAge is how long a mandate can go without an allowed check before it is reported stale
+	StaleGrantAge = NewSetting(30 * 24 * time.Hour)
+	// RecentViewsMax is how many recently viewed dossiers are remembered per user; 0 disables tracking
+	RecentViewsMax = NewSetting(20)
+	// SeedFile is a data store snapshot an admin reset can re-seed from; empty disables seeding
+	SeedFile string
+	// SandboxTTL is how long a simulation sandbox lives before it is discarded
+	SandboxTTL = 30 * time.Minute
+	// CORSAllowedOrigins lists origins allowed to call the API directly, bypassing Envoy; empty disables CORS
+	CORSAllowedOrigins   []string
+	CORSAllowCredentials bool
+	// CORSAllowUserHeader lets cross-origin callers send x-current-user themselves (development only)
+	CORSAllowUserHeader bool
+	// OPAPoliciesDir holds the Rego policies served as OPA bundles under /opa/bundles/
+	OPAPoliciesDir = "/policies"
+	// OPALogsRelayURL receives a copy of every OPA decision log batch; empty disables relaying
+	OPALogsRelayURL string
+	// IdentitySignatures is how unsigned identity headers are treated when SIGNING_KEY is set: enforce, log or off
+	IdentitySignatures = NewSetting("enforce")
+	// GuestMode lets callers without an identity read public dossiers; every other route still answers 401
+	GuestMode = NewSetting(false)
+	// HideExistence answers 404, as for an unknown dossier, when the caller cannot view an existing one
+	HideExistence = NewSetting(false)
+	// ExtAuthzAddr is the listen address of the Envoy ext_authz (HTTP) service; empty disables it
+	ExtAuthzAddr string
+	// ResponseEnvelope is the shape of list responses: legacy ({"dossiers": [...]}), envelope ({"data": [...], ...}) or both
+	ResponseEnvelope = NewSetting("both")
+	// JobRetention is how long a finished background job and its artifact are kept for download
+	JobRetention = time.Hour
+	// APISunset is announced in the Sunset header of unversioned /api/ routes; zero omits the header
+	APISunset time.Time
+	// RoleTuples maps Keycloak roles to organization memberships, e.g. "admin=admin@organization:platform"; empty disables role sync
+	RoleTuples string
+	// RoleSyncInterval is how often role holders are re-read from the Keycloak admin API; 0 only syncs at sign-in
+	RoleSyncInterval = 15 * time.Minute
+	// KeycloakURL is the internal base URL of Keycloak (up to /login) for the admin API; empty disables scheduled role sync
+	KeycloakURL string
+	// KeycloakRealm is the realm whose users and roles are synced
+	KeycloakRealm = "AuthorizationRealm"
+	// KeycloakSyncClientId is the service-account client whose KEYCLOAK_SYNC_CLIENT_SECRET reads roles from the admin API
+	KeycloakSyncClientId = "test-app-sync"
+	StartTime            = time.Now()
 )
@@ -0,0 +1,197 @@
+package config
+
+import (
+	"crypto/sha256"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Secret names loaded through Secrets.
+const (
+	OpenfgaAPIToken          = "OPENFGA_API_TOKEN"
+	AIManagerAPIKey          = "AI_MANAGER_API_KEY"
+	SigningKey               = "SIGNING_KEY"
+	ContentKeys              = "CONTENT_KEYS"
+	KeycloakSyncClientSecret = "KEYCLOAK_SYNC_CLIENT_SECRET"
+	KeycloakHookSecret       = "KEYCLOAK_HOOK_SECRET"
+	OPALogsToken             = "OPA_LOGS_TOKEN"
+)
+
+// SecretNames lists every secret the app reads.
+var SecretNames = []string{OpenfgaAPIToken, AIManagerAPIKey, SigningKey, ContentKeys, KeycloakSyncClientSecret, KeycloakHookSecret, OPALogsToken}
+
+// ErrSecretNotFound is returned by a provider that has no value for a secret.
+var ErrSecretNotFound = errors.New("secret not found")
+
+// SecretsProvider looks secrets up by name. Values must never be logged.
+type SecretsProvider interface {
+	Name() string
+	Secret(name string) (string, error)
+}
+
+// EnvSecrets reads secrets from environment variables of the same name.
+type EnvSecrets struct{}
+
+func (EnvSecrets) Name() string { return "env" }
+
+func (EnvSecrets) Secret(name string) (string, error) {
+	if v, ok := os.LookupEnv(name); ok {
+		return v, nil
+	}
+	return "", ErrSecretNotFound
+}
+
+// FileSecrets reads each secret from a file of the same name in Dir, as
+// mounted by Docker or Kubernetes secrets. A trailing newline is dropped.
+type FileSecrets struct {
+	Dir string
+}
+
+func (f FileSecrets) Name() string { return "file:" + f.Dir }
+
+func (f FileSecrets) Secret(name string) (string, error) {
+	raw, err := os.ReadFile(filepath.Join(f.Dir, name))
+	if errors.Is(err, os.ErrNotExist) {
+		return "", ErrSecretNotFound
+	}
+	if err != nil {
+		return "", err
+	}
+	return strings.TrimRight(string(raw), "\r\n"), nil
+}
+
+// VaultSecrets reads secrets from one Vault KV v2 entry, e.g.
+// Path "secret/data/authz-poc" holding a key per secret name.
+type VaultSecrets struct {
+	Addr  string
+	Token string
+	Path  string
+}
+
+func (v VaultSecrets) Name() string { return "vault:" + v.Path }
+
+var vaultClient = &http.Client{Timeout: 10 * time.Second}
+
+func (v VaultSecrets) Secret(name string) (string, error) {
+	req, err := http.NewRequest("GET", strings.TrimRight(v.Addr, "/")+"/v1/"+v.Path, nil)
+	if err != nil {
+		return "", err
+	}
+	req.Header.Set("X-Vault-Token", v.Token)
+	resp, err := vaultClient.Do(req)
+	if err != nil {
+		return "", err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode == http.StatusNotFound {
+		return "", ErrSecretNotFound
+	}
+	if resp.StatusCode != http.StatusOK {
+		return "", fmt.Errorf("vault returned %s", resp.Status)
+	}
+	var body struct {
+		Data struct {
+			Data map[string]interface{} `json:"data"`
+		} `json:"data"`
+	}
+	if err := json.NewDecoder(resp.Body).Decode(&body); err != nil {
+		return "", fmt.Errorf("decoding vault response: %w", err)
+	}
+	value, ok := body.Data.Data[name].(string)
+	if !ok {
+		return "", ErrSecretNotFound
+	}
+	return value, nil
+}
+
+var (
+	// Secrets is where LoadSecrets and WatchSecrets read from
+	Secrets SecretsProvider = EnvSecrets{}
+
+	secretsMu    sync.RWMutex
+	secretValues = map[string]string{}
+	secretDigest = map[string][sha256.Size]byte{}
+	secretHooks  = map[string]func(string) error{}
+)
+
+// Secret returns the current value of a secret, or "" when it is not set.
+func Secret(name string) string {
+	secretsMu.RLock()
+	defer secretsMu.RUnlock()
+	return secretValues[name]
+}
+
+// OnSecret registers fn to apply a secret's value when it is loaded and each
+// time it rotates. An error keeps the previous value in force.
+func OnSecret(name string, fn func(value string) error) {
+	secretsMu.Lock()
+	defer secretsMu.Unlock()
+	secretHooks[name] = fn
+}
+
+// LoadSecrets reads every secret from Secrets. Missing secrets stay unset;
+// any other failure, including a hook rejecting a value, is returned.
+func LoadSecrets() error {
+	_, err := refreshSecrets()
+	return err
+}
+
+// refreshSecrets reads all secrets and applies those whose value changed,
+// returning their names. Only names and digests are compared and logged.
+func refreshSecrets() ([]string, error) {
+	var changed []string
+	var errs []error
+	for _, name := range SecretNames {
+		value, err := Secrets.Secret(name)
+		if errors.Is(err, ErrSecretNotFound) {
+			value, err = "", nil
+		}
+		if err != nil {
+			errs = append(errs, fmt.Errorf("%s: %w", name, err))
+			continue
+		}
+		digest := sha256.Sum256([]byte(value))
+		secretsMu.RLock()
+		prev, seen := secretDigest[name]
+		hook := secretHooks[name]
+		secretsMu.RUnlock()
+		if seen && prev == digest {
+			continue
+		}
+		if hook != nil {
+			if err := hook(value); err != nil {
+				errs = append(errs, fmt.Errorf("%s: %w", name, err))
+				continue
+			}
+		}
+		secretsMu.Lock()
+		secretValues[name], secretDigest[name] = value, digest
+		secretsMu.Unlock()
+		if seen {
+			changed = append(changed, name)
+		}
+	}
+	return changed, errors.Join(errs...)
+}
+
+// WatchSecrets re-reads the secrets every interval and applies rotated values.
+func WatchSecrets(interval time.Duration) {
+	for {
+		time.Sleep(interval)
+		changed, err := refreshSecrets()
+		for _, name := range changed {
+			log.Printf("Secret %s rotated (%s)", name, Secrets.Name())
+		}
+		if err != nil {
+			log.Printf("WARNING: reading secrets from %s: %v", Secrets.Name(), err)
+		}
+	}
+}
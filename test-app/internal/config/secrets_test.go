@@ -0,0 +1,78 @@
+package config
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestLoadSecrets_FileRotation(t *testing.T) {
+	origProvider := Secrets
+	defer func() {
+		Secrets = origProvider
+		secretValues, secretDigest, secretHooks = map[string]string{}, map[string][32]byte{}, map[string]func(string) error{}
+	}()
+	dir := t.TempDir()
+	Secrets = FileSecrets{Dir: dir}
+	os.WriteFile(filepath.Join(dir, OpenfgaAPIToken), []byte("token-1\n"), 0600)
+
+	var applied []string
+	OnSecret(ContentKeys, func(v string) error {
+		if v == "bad" {
+			return errors.New("rejected")
+		}
+		applied = append(applied, v)
+		return nil
+	})
+	if err := LoadSecrets(); err != nil {
+		t.Fatal(err)
+	}
+	if Secret(OpenfgaAPIToken) != "token-1" || Secret(AIManagerAPIKey) != "" {
+		t.Errorf("token = %q, AI key = %q", Secret(OpenfgaAPIToken), Secret(AIManagerAPIKey))
+	}
+
+	os.WriteFile(filepath.Join(dir, OpenfgaAPIToken), []byte("token-2"), 0600)
+	os.WriteFile(filepath.Join(dir, ContentKeys), []byte("k1:abc"), 0600)
+	changed, err := refreshSecrets()
+	if err != nil || len(changed) != 2 || Secret(OpenfgaAPIToken) != "token-2" {
+		t.Errorf("rotation: changed = %v, err = %v, token = %q", changed, err, Secret(OpenfgaAPIToken))
+	}
+	if len(applied) != 2 || applied[1] != "k1:abc" {
+		t.Errorf("hook saw %q", applied)
+	}
+
+	// A value the hook rejects keeps the previous one in force.
+	os.WriteFile(filepath.Join(dir, ContentKeys), []byte("bad"), 0600)
+	if _, err := refreshSecrets(); err == nil || Secret(ContentKeys) != "k1:abc" {
+		t.Errorf("rejected rotation: err = %v, value = %q", err, Secret(ContentKeys))
+	}
+}
+
+func TestVaultSecrets(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/v1/secret/data/app" || r.Header.Get("X-Vault-Token") != "root" {
+			w.WriteHeader(http.StatusForbidden)
+			return
+		}
+		json.NewEncoder(w).Encode(map[string]interface{}{"data": map[string]interface{}{
+			"data": map[string]interface{}{SigningKey: "s3cret"},
+		}})
+	}))
+	defer server.Close()
+
+	v := VaultSecrets{Addr: server.URL, Token: "root", Path: "secret/data/app"}
+	if got, err := v.Secret(SigningKey); err != nil || got != "s3cret" {
+		t.Errorf("SigningKey = %q, %v", got, err)
+	}
+	if _, err := v.Secret(OpenfgaAPIToken); !errors.Is(err, ErrSecretNotFound) {
+		t.Errorf("missing key err = %v, want ErrSecretNotFound", err)
+	}
+	v.Token = "wrong"
+	if _, err := v.Secret(SigningKey); err == nil || errors.Is(err, ErrSecretNotFound) {
+		t.Errorf("forbidden err = %v, want a Vault error", err)
+	}
+}
@@ -0,0 +1,312 @@
+package config
+
+import (
+	"bufio"
+	"bytes"
+	"crypto/sha256"
+	"fmt"
+	"log"
+	"os"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// Setting holds a tunable value. Requests read it while WatchTunables or a
+// SIGHUP may replace it, so it is only accessed through Get and Set.
+type Setting[T any] struct {
+	p atomic.Pointer[T]
+}
+
+// NewSetting returns a Setting holding v.
+func NewSetting[T any](v T) *Setting[T] {
+	s := &Setting[T]{}
+	s.Set(v)
+	return s
+}
+
+// Get returns the current value.
+func (s *Setting[T]) Get() T {
+	return *s.p.Load()
+}
+
+// Set replaces the value.
+func (s *Setting[T]) Set(v T) {
+	s.p.Store(&v)
+}
+
+// Tunables are the settings that are read on every use, so they can change
+// while the app runs. Each is named after its environment variable; a mounted
+// config file (CONFIG_FILE) may set them too, and is re-read when it changes.
+// The file wins over the environment, which wins over the default; removing
+// a key from the file brings back the value the app started with.
+type tunable struct {
+	get func() string
+	set func(string) error
+}
+
+var tunables = map[string]tunable{
+	"LIST_OBJECTS_CACHE_TTL":  durationTunable(ListObjectsCacheTTL, 0),
+	"CACHE_MAX_AGE":           intTunable(CacheMaxAge, 0),
+	"VISIBILITY_MAX_LAG":      durationTunable(VisibilityMaxLag, 1),
+	"FGA_CALL_BUDGET":         intTunable(FgaCallBudget, 0),
+	"FGA_BUDGET_MODE":         enumTunable(FgaBudgetMode, "log", "reject"),
+	"LIST_CHECK_CONCURRENCY":  intTunable(ListCheckConcurrency, 1),
+	"FGA_WRITE_BATCH_MAX":     intTunable(FgaWriteBatchMax, 1),
+	"MAX_CONTENT_SIZE":        intTunable(MaxContentSize, 1),
+	"DATA_MIN_FREE_BYTES":     intTunable(DataMinFreeBytes, 0),
+	"AUDIT_ALLOW_SAMPLE_RATE": rateTunable(AuditAllowSampleRate),
+	"AUDIT_BURST_PER_SECOND":  intTunable(AuditBurstPerSecond, 0),
+	"ENDPOINT_COOLDOWN":       durationTunable(EndpointCooldown, 1),
+	"UNDO_WINDOW":             durationTunable(UndoWindow, 0),
+	"STALE_GRANT_AGE":         durationTunable(StaleGrantAge, 1),
+	"RECENT_VIEWS_MAX":        intTunable(RecentViewsMax, 0),
+	"IDENTITY_SIGNATURES":     enumTunable(IdentitySignatures, "enforce", "log", "off"),
+	"GUEST_MODE":              boolTunable(GuestMode),
+	"HIDE_EXISTENCE":          boolTunable(HideExistence),
+	"RESPONSE_ENVELOPE":       enumTunable(ResponseEnvelope, "legacy", "both", "envelope"),
+}
+
+func durationTunable(v *Setting[time.Duration], min time.Duration) tunable {
+	return tunable{
+		get: func() string { return v.Get().String() },
+		set: func(s string) error {
+			d, err := time.ParseDuration(s)
+			if err != nil || d < min {
+				return fmt.Errorf("%q is not a duration of at least %s", s, min)
+			}
+			v.Set(d)
+			return nil
+		},
+	}
+}
+
+func intTunable(v *Setting[int], min int) tunable {
+	return tunable{
+		get: func() string { return strconv.Itoa(v.Get()) },
+		set: func(s string) error {
+			n, err := strconv.Atoi(s)
+			if err != nil || n < min {
+				return fmt.Errorf("%q is not an integer of at least %d", s, min)
+			}
+			v.Set(n)
+			return nil
+		},
+	}
+}
+
+func rateTunable(v *Setting[float64]) tunable {
+	return tunable{
+		get: func() string { return strconv.FormatFloat(v.Get(), 'g', -1, 64) },
+		set: func(s string) error {
+			f, err := strconv.ParseFloat(s, 64)
+			if err != nil || f < 0 || f > 1 {
+				return fmt.Errorf("%q is not a rate between 0 and 1", s)
+			}
+			v.Set(f)
+			return nil
+		},
+	}
+}
+
+func enumTunable(v *Setting[string], values ...string) tunable {
+	return tunable{
+		get: func() string { return v.Get() },
+		set: func(s string) error {
+			for _, allowed := range values {
+				if s == allowed {
+					v.Set(s)
+					return nil
+				}
+			}
+			return fmt.Errorf("%q is not one of %s", s, strings.Join(values, ", "))
+		},
+	}
+}
+
+func boolTunable(v *Setting[bool]) tunable {
+	return tunable{
+		get: func() string { return strconv.FormatBool(v.Get()) },
+		set: func(s string) error {
+			b, err := strconv.ParseBool(s)
+			if err != nil {
+				return fmt.Errorf("%q is not true or false", s)
+			}
+			v.Set(b)
+			return nil
+		},
+	}
+}
+
+// TunableValue is the effective value of a tunable and where it comes from:
+// "default", "env" or "file".
+type TunableValue struct {
+	Key    string `json:"key"`
+	Value  string `json:"value"`
+	Source string `json:"source"`
+}
+
+// TunablesStatus describes the config file as last read.
+type TunablesStatus struct {
+	File     string         `json:"file,omitempty"`
+	LoadedAt time.Time      `json:"loadedAt,omitempty"`
+	Error    string         `json:"error,omitempty"`
+	Values   []TunableValue `json:"values"`
+}
+
+var (
+	tunablesMu sync.Mutex
+	tunesFile  string
+	tunesHash  [32]byte
+	tunesAt    time.Time
+	tunesErr   string
+	// started holds each tunable's value from the environment or default,
+	// restored when the file stops setting it.
+	started  = map[string]string{}
+	fromEnv  = map[string]bool{}
+	fromFile = map[string]bool{}
+)
+
+// InitTunables records the values set from the environment, which must
+// already be applied, then loads path when it is not empty.
+func InitTunables(path string) error {
+	tunablesMu.Lock()
+	for key, t := range tunables {
+		started[key] = t.get()
+		_, fromEnv[key] = os.LookupEnv(key)
+	}
+	tunesFile = path
+	tunablesMu.Unlock()
+	if path == "" {
+		return nil
+	}
+	_, err := ReloadTunables()
+	return err
+}
+
+// ReloadTunables re-reads the config file and applies it, returning the keys
+// whose value changed. A file that cannot be read or parsed changes nothing;
+// a key with an invalid value keeps its current value and is reported.
+func ReloadTunables() ([]string, error) {
+	tunablesMu.Lock()
+	defer tunablesMu.Unlock()
+	if tunesFile == "" {
+		return nil, nil
+	}
+	data, err := os.ReadFile(tunesFile)
+	if err != nil {
+		tunesErr = err.Error()
+		return nil, err
+	}
+	if hash := sha256.Sum256(data); hash == tunesHash && !tunesAt.IsZero() {
+		return nil, nil
+	} else {
+		tunesHash = hash
+	}
+	values, err := parseFlatYAML(data)
+	if err != nil {
+		tunesErr = err.Error()
+		return nil, err
+	}
+	var changed []string
+	var errs []string
+	for key, t := range tunables {
+		want, inFile := values[key]
+		if !inFile {
+			want = started[key]
+		}
+		before := t.get()
+		if err := t.set(want); err != nil {
+			errs = append(errs, key+": "+err.Error())
+			continue
+		}
+		fromFile[key] = inFile
+		if t.get() != before {
+			changed = append(changed, key)
+		}
+	}
+	for key := range values {
+		if _, ok := tunables[key]; !ok {
+			errs = append(errs, key+": not a tunable setting")
+		}
+	}
+	sort.Strings(changed)
+	sort.Strings(errs)
+	tunesAt, tunesErr = time.Now(), strings.Join(errs, "; ")
+	if tunesErr != "" {
+		return changed, fmt.Errorf("%s", tunesErr)
+	}
+	return changed, nil
+}
+
+// WatchTunables re-reads the config file every interval and logs what changed.
+func WatchTunables(interval time.Duration) {
+	for {
+		time.Sleep(interval)
+		changed, err := ReloadTunables()
+		for _, key := range changed {
+			log.Printf("Config %s reloaded: %s = %s", tunesFile, key, Tunables().value(key))
+		}
+		if err != nil {
+			log.Printf("WARNING: config file %s: %v", tunesFile, err)
+		}
+	}
+}
+
+// Tunables returns every tunable's effective value and source, by key.
+func Tunables() TunablesStatus {
+	tunablesMu.Lock()
+	defer tunablesMu.Unlock()
+	st := TunablesStatus{File: tunesFile, LoadedAt: tunesAt, Error: tunesErr, Values: []TunableValue{}}
+	for key, t := range tunables {
+		source := "default"
+		if fromFile[key] {
+			source = "file"
+		} else if fromEnv[key] {
+			source = "env"
+		}
+		st.Values = append(st.Values, TunableValue{Key: key, Value: t.get(), Source: source})
+	}
+	sort.Slice(st.Values, func(i, j int) bool { return st.Values[i].Key < st.Values[j].Key })
+	return st
+}
+
+func (st TunablesStatus) value(key string) string {
+	for _, v := range st.Values {
+		if v.Key == key {
+			return v.Value
+		}
+	}
+	return ""
+}
+
+// parseFlatYAML reads the subset of YAML the config file uses: one
+// "key: value" per line, with # comments and optionally quoted values. Keys
+// are matched case-insensitively, with - or _ between words.
+func parseFlatYAML(data []byte) (map[string]string, error) {
+	values := map[string]string{}
+	sc := bufio.NewScanner(bytes.NewReader(data))
+	for n := 1; sc.Scan(); n++ {
+		line := sc.Text()
+		if i := strings.Index(line, " #"); i >= 0 {
+			line = line[:i]
+		}
+		line = strings.TrimSpace(line)
+		if line == "" || strings.HasPrefix(line, "#") || line == "---" {
+			continue
+		}
+		key, value, ok := strings.Cut(line, ":")
+		if !ok || strings.HasPrefix(sc.Text(), " ") || strings.HasPrefix(sc.Text(), "\t") {
+			return nil, fmt.Errorf("line %d: expected a top-level \"key: value\"", n)
+		}
+		value = strings.TrimSpace(value)
+		if len(value) >= 2 && (value[0] == '"' || value[0] == '\'') && value[len(value)-1] == value[0] {
+			value = value[1 : len(value)-1]
+		}
+		values[strings.ToUpper(strings.ReplaceAll(strings.TrimSpace(key), "-", "_"))] = value
+	}
+	return values, sc.Err()
+}
@@ -0,0 +1,104 @@
+package config
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"sync"
+	"testing"
+	"time"
+)
+
+func TestTunables_FileReload(t *testing.T) {
+	origTTL, origMode, origGuest := ListObjectsCacheTTL.Get(), FgaBudgetMode.Get(), GuestMode.Get()
+	defer func() {
+		ListObjectsCacheTTL.Set(origTTL)
+		FgaBudgetMode.Set(origMode)
+		GuestMode.Set(origGuest)
+		InitTunables("")
+		fromFile = map[string]bool{}
+	}()
+	t.Setenv("FGA_BUDGET_MODE", "log")
+	FgaBudgetMode.Set("log")
+	path := filepath.Join(t.TempDir(), "config.yaml")
+	os.WriteFile(path, []byte("# tunables\nlist-objects-cache-ttl: 30s\nguest_mode: \"true\"\n"), 0600)
+	if err := InitTunables(path); err != nil {
+		t.Fatal(err)
+	}
+	if ListObjectsCacheTTL.Get().String() != "30s" || !GuestMode.Get() {
+		t.Errorf("after load: ttl = %s, guest = %v", ListObjectsCacheTTL.Get(), GuestMode.Get())
+	}
+	sources := map[string]string{}
+	for _, v := range Tunables().Values {
+		sources[v.Key] = v.Source
+	}
+	if sources["LIST_OBJECTS_CACHE_TTL"] != "file" || sources["FGA_BUDGET_MODE"] != "env" || sources["CACHE_MAX_AGE"] != "default" {
+		t.Errorf("sources = %v", sources)
+	}
+
+	// An invalid value keeps the current one; a removed key goes back to the start value.
+	os.WriteFile(path, []byte("FGA_BUDGET_MODE: loud\nGUEST_MODE: true\n"), 0600)
+	changed, err := ReloadTunables()
+	if err == nil || FgaBudgetMode.Get() != "log" {
+		t.Errorf("invalid value: err = %v, mode = %q", err, FgaBudgetMode.Get())
+	}
+	if len(changed) != 1 || changed[0] != "LIST_OBJECTS_CACHE_TTL" || ListObjectsCacheTTL.Get() != origTTL {
+		t.Errorf("removed key: changed = %v, ttl = %s", changed, ListObjectsCacheTTL.Get())
+	}
+
+	os.WriteFile(path, []byte("GUEST_MODE:\n  nested: true\n"), 0600)
+	if _, err := ReloadTunables(); err == nil || !GuestMode.Get() {
+		t.Errorf("nested YAML: err = %v, guest = %v", err, GuestMode.Get())
+	}
+}
+
+// Run with -race: requests read tunables while the file is reloaded.
+func TestTunables_ReloadDuringReads(t *testing.T) {
+	origTTL, origBudget, origEnvelope := ListObjectsCacheTTL.Get(), FgaCallBudget.Get(), ResponseEnvelope.Get()
+	defer func() {
+		ListObjectsCacheTTL.Set(origTTL)
+		FgaCallBudget.Set(origBudget)
+		ResponseEnvelope.Set(origEnvelope)
+		InitTunables("")
+		fromFile = map[string]bool{}
+	}()
+	path := filepath.Join(t.TempDir(), "config.yaml")
+	os.WriteFile(path, []byte("FGA_CALL_BUDGET: 1\n"), 0600)
+	if err := InitTunables(path); err != nil {
+		t.Fatal(err)
+	}
+
+	stop := make(chan struct{})
+	var wg sync.WaitGroup
+	for i := 0; i < 4; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for {
+				select {
+				case <-stop:
+					return
+				default:
+				}
+				if ListObjectsCacheTTL.Get() < 0 || FgaCallBudget.Get() < 0 || ResponseEnvelope.Get() == "" {
+					t.Error("read a torn tunable")
+					return
+				}
+			}
+		}()
+	}
+	envelopes := []string{"legacy", "both", "envelope"}
+	for i := 0; i < 200; i++ {
+		content := fmt.Sprintf("LIST_OBJECTS_CACHE_TTL: %s\nFGA_CALL_BUDGET: %d\nRESPONSE_ENVELOPE: %s\n",
+			time.Duration(i)*time.Second, i, envelopes[i%len(envelopes)])
+		os.WriteFile(path, []byte(content), 0600)
+		if _, err := ReloadTunables(); err != nil {
+			t.Fatal(err)
+		}
+	}
+	close(stop)
+	wg.Wait()
+	if FgaCallBudget.Get() != 199 || ResponseEnvelope.Get() != "both" {
+		t.Errorf("after reloads: budget = %d, envelope = %q", FgaCallBudget.Get(), ResponseEnvelope.Get())
+	}
+}
@@ -0,0 +1,134 @@
+// Package dossiertypes is the registry of dossier types. Each type declares
+// the rules its dossiers must follow: the sensitivity they start at and may
+// not go below, whether they may be public and which mandates may be granted
+// on them. The handlers check every create, update, publication and grant
+// against the registry rather than hard-coding per-type exceptions.
+package dossiertypes
+
+import (
+	"fmt"
+	"sync"
+
+	"test-app/internal/store"
+)
+
+// Type describes a dossier type and its rules.
+type Type struct {
+	Name  string `json:"name"`
+	Label string `json:"label"`
+	// DefaultSensitivity is given to new dossiers that do not ask for a level
+	DefaultSensitivity string `json:"defaultSensitivity,omitempty"`
+	// MinSensitivity is the lowest level a dossier of the type may have
+	MinSensitivity string `json:"minSensitivity,omitempty"`
+	// AllowPublic lets dossiers of the type be made public
+	AllowPublic bool `json:"allowPublic"`
+	// Mandates lists the mandate relations that may be granted; empty allows every one
+	Mandates []string `json:"mandates,omitempty"`
+}
+
+// sensitivityRank orders the levels; unset counts as normal.
+var sensitivityRank = map[string]int{"": 0, "normal": 0, "sensitive": 1, "secret": 2}
+
+// Check reports the first rule d breaks, if any.
+func (t *Type) Check(d *store.Dossier) error {
+	if d.Public && !t.AllowPublic {
+		return fmt.Errorf("%s dossiers cannot be public", t.Name)
+	}
+	if sensitivityRank[d.Sensitivity] < sensitivityRank[t.MinSensitivity] {
+		return fmt.Errorf("%s dossiers must be at least %s", t.Name, t.MinSensitivity)
+	}
+	for _, rel := range d.Relations {
+		if store.IsMandate(rel.Relation) && !t.AllowsMandate(rel.Relation) {
+			return fmt.Errorf("%s dossiers do not accept %s mandates", t.Name, rel.Relation)
+		}
+	}
+	return nil
+}
+
+// AllowsMandate reports whether relation may be granted on dossiers of the type.
+func (t *Type) AllowsMandate(relation string) bool {
+	if len(t.Mandates) == 0 {
+		return true
+	}
+	for _, m := range t.Mandates {
+		if m == relation {
+			return true
+		}
+	}
+	return false
+}
+
+var (
+	mu    sync.RWMutex
+	types = map[string]*Type{}
+	order []string
+)
+
+func init() {
+	for _, t := range []Type{
+		{Name: "tax", Label: "Tax", AllowPublic: true},
+		// Health records never leave their circle: not public, not shared onward.
+		{Name: "health", Label: "Health", DefaultSensitivity: "sensitive", MinSensitivity: "sensitive", Mandates: []string{"mandate_viewer", "mandate_editor"}},
+		{Name: "general", Label: "General", AllowPublic: true},
+	} {
+		if err := Register(t); err != nil {
+			panic(err)
+		}
+	}
+}
+
+// Register adds a dossier type, or replaces the one with the same name.
+func Register(t Type) error {
+	if t.Name == "" {
+		return fmt.Errorf("dossier type needs a name")
+	}
+	for _, level := range []string{t.DefaultSensitivity, t.MinSensitivity} {
+		if _, ok := sensitivityRank[level]; !ok {
+			return fmt.Errorf("dossier type %s: unknown sensitivity %q", t.Name, level)
+		}
+	}
+	if sensitivityRank[t.DefaultSensitivity] < sensitivityRank[t.MinSensitivity] {
+		return fmt.Errorf("dossier type %s: default sensitivity is below the minimum", t.Name)
+	}
+	for _, m := range t.Mandates {
+		if !store.IsMandate(m) {
+			return fmt.Errorf("dossier type %s: %s is not a mandate relation", t.Name, m)
+		}
+	}
+	if t.Label == "" {
+		t.Label = t.Name
+	}
+	mu.Lock()
+	defer mu.Unlock()
+	if _, ok := types[t.Name]; !ok {
+		order = append(order, t.Name)
+	}
+	types[t.Name] = &t
+	return nil
+}
+
+// Lookup returns the type with the given name.
+func Lookup(name string) (*Type, bool) {
+	mu.RLock()
+	defer mu.RUnlock()
+	t, ok := types[name]
+	return t, ok
+}
+
+// Names returns the type names in registration order.
+func Names() []string {
+	mu.RLock()
+	defer mu.RUnlock()
+	return append([]string{}, order...)
+}
+
+// All returns the types in registration order.
+func All() []Type {
+	mu.RLock()
+	defer mu.RUnlock()
+	out := make([]Type, 0, len(order))
+	for _, name := range order {
+		out = append(out, *types[name])
+	}
+	return out
+}
@@ -0,0 +1,46 @@
+package dossiertypes
+
+import (
+	"strings"
+	"testing"
+
+	"test-app/internal/store"
+)
+
+func TestCheck(t *testing.T) {
+	health, ok := Lookup("health")
+	if !ok {
+		t.Fatal("health should be registered")
+	}
+	cases := []struct {
+		name string
+		d    store.Dossier
+		want string
+	}{
+		{"ok", store.Dossier{Sensitivity: "sensitive", Relations: []store.Relation{{User: "bob", Relation: "mandate_viewer"}}}, ""},
+		{"public", store.Dossier{Sensitivity: "secret", Public: true}, "cannot be public"},
+		{"too low", store.Dossier{}, "at least sensitive"},
+		{"onward sharing", store.Dossier{Sensitivity: "sensitive", Relations: []store.Relation{{User: "bob", Relation: "mandate_holder"}}}, "mandate_holder"},
+	}
+	for _, c := range cases {
+		err := health.Check(&c.d)
+		if (c.want == "") != (err == nil) || (err != nil && !strings.Contains(err.Error(), c.want)) {
+			t.Errorf("%s: Check = %v, want %q", c.name, err, c.want)
+		}
+	}
+	if general, _ := Lookup("general"); general.Check(&store.Dossier{Public: true, Relations: []store.Relation{{User: "bob", Relation: "mandate_holder"}}}) != nil {
+		t.Error("general dossiers have no restrictions")
+	}
+}
+
+func TestRegister(t *testing.T) {
+	if Register(Type{Name: "bad", DefaultSensitivity: "normal", MinSensitivity: "secret"}) == nil {
+		t.Error("a default below the minimum should be refused")
+	}
+	if Register(Type{Name: "bad", Mandates: []string{"owner"}}) == nil {
+		t.Error("only mandate relations can be listed")
+	}
+	if got := strings.Join(Names(), ","); got != "tax,health,general" {
+		t.Errorf("Names = %s", got)
+	}
+}
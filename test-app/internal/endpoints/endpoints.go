@@ -0,0 +1,153 @@
+// Package endpoints spreads calls to a service over the base URLs it is
+// configured with (OPENFGA_URL and AUDIT_URL accept comma-separated lists).
+// A URL that fails is skipped for config.EndpointCooldown, and calls that
+// could not connect move on to the next URL, so restarting one container
+// mid-demo does not need a restart of the app. Names are resolved again on
+// every new connection, and idle connections are dropped after a failure, so
+// a container that comes back on another address is found again.
+package endpoints
+
+import (
+	"errors"
+	"net"
+	"net/http"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"test-app/internal/config"
+)
+
+// Status is the state of one base URL.
+type Status struct {
+	URL       string     `json:"url"`
+	Up        bool       `json:"up"`
+	DownUntil *time.Time `json:"downUntil,omitempty"`
+	LastError string     `json:"lastError,omitempty"`
+}
+
+// set is the state of one configured list.
+type set struct {
+	mu     sync.Mutex
+	urls   []string
+	status map[string]*Status
+}
+
+var (
+	mu   sync.Mutex
+	sets = map[string]*set{}
+	now  = time.Now
+)
+
+// lookup returns the state of list, creating it the first time. Keying by the
+// configured string lets config change at runtime, as it does in tests.
+func lookup(list string) *set {
+	mu.Lock()
+	defer mu.Unlock()
+	s := sets[list]
+	if s == nil {
+		s = &set{status: map[string]*Status{}}
+		for _, u := range strings.Split(list, ",") {
+			if u = strings.TrimRight(strings.TrimSpace(u), "/"); u != "" {
+				s.urls = append(s.urls, u)
+				s.status[u] = &Status{URL: u, Up: true}
+			}
+		}
+		sets[list] = s
+	}
+	return s
+}
+
+// order returns the URLs to try: those up in configured order, then those
+// cooling down, soonest back first, so a call is still attempted when all are down.
+func (s *set) order() []string {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	var up, down []string
+	t := now()
+	for _, u := range s.urls {
+		if st := s.status[u]; st.Up || !t.Before(*st.DownUntil) {
+			up = append(up, u)
+		} else {
+			down = append(down, u)
+		}
+	}
+	sort.SliceStable(down, func(i, j int) bool { return s.status[down[i]].DownUntil.Before(*s.status[down[j]].DownUntil) })
+	return append(up, down...)
+}
+
+func (s *set) mark(u string, err error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	st := s.status[u]
+	if err == nil {
+		st.Up, st.DownUntil, st.LastError = true, nil, ""
+		return
+	}
+	until := now().Add(config.EndpointCooldown.Get())
+	st.Up, st.DownUntil, st.LastError = false, &until, err.Error()
+}
+
+// Do sends the request build makes for a base URL of list. A URL that cannot
+// be connected to, or answers 5xx, is marked down; only when the connection
+// could not be made is the next URL tried, since the request never reached
+// the service and repeating it is safe.
+func Do(list string, build func(base string) (*http.Request, error)) (*http.Response, error) {
+	s := lookup(list)
+	urls := s.order()
+	if len(urls) == 0 {
+		urls = []string{""}
+	}
+	var lastErr error
+	for _, u := range urls {
+		req, err := build(u)
+		if err != nil {
+			return nil, err
+		}
+		resp, err := http.DefaultClient.Do(req)
+		if err != nil {
+			if u != "" {
+				s.mark(u, err)
+			}
+			http.DefaultClient.CloseIdleConnections()
+			lastErr = err
+			if notConnected(err) {
+				continue
+			}
+			return nil, err
+		}
+		if u != "" {
+			if resp.StatusCode >= 500 {
+				s.mark(u, errors.New(resp.Status))
+			} else {
+				s.mark(u, nil)
+			}
+		}
+		return resp, nil
+	}
+	return nil, lastErr
+}
+
+// notConnected reports whether err happened before the request was sent.
+func notConnected(err error) bool {
+	var op *net.OpError
+	if errors.As(err, &op) && op.Op == "dial" {
+		return true
+	}
+	var dns *net.DNSError
+	return errors.As(err, &dns)
+}
+
+// Statuses returns the state of each URL of list. A URL stays down until a
+// call to it succeeds again, even once its cooldown is over.
+func Statuses(list string) []Status {
+	s := lookup(list)
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	out := make([]Status, 0, len(s.urls))
+	for _, u := range s.urls {
+		out = append(out, *s.status[u])
+	}
+	return out
+}
@@ -0,0 +1,50 @@
+package endpoints
+
+import (
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"test-app/internal/config"
+)
+
+func TestDo_Failover(t *testing.T) {
+	var hits int
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		hits++
+		w.WriteHeader(http.StatusNoContent)
+	}))
+	defer server.Close()
+	// A port nothing listens on: connecting fails before the request is sent.
+	l, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	dead := "http://" + l.Addr().String()
+	l.Close()
+
+	clock := time.Now()
+	now = func() time.Time { return clock }
+	defer func() { now = time.Now }()
+	list := dead + ", " + server.URL + "/"
+	get := func(base string) (*http.Request, error) { return http.NewRequest("GET", base+"/x", nil) }
+
+	resp, err := Do(list, get)
+	if err != nil || resp.StatusCode != http.StatusNoContent || hits != 1 {
+		t.Fatalf("Do = %v, %v after %d hits; want the second URL to answer", resp, err, hits)
+	}
+	resp.Body.Close()
+	st := Statuses(list)
+	if st[0].Up || st[0].DownUntil == nil || !st[1].Up || st[1].URL != server.URL {
+		t.Errorf("statuses = %+v", st)
+	}
+	if urls := lookup(list).order(); urls[0] != server.URL {
+		t.Errorf("order = %v, want the healthy URL first while the other cools down", urls)
+	}
+	clock = clock.Add(config.EndpointCooldown.Get())
+	if urls := lookup(list).order(); urls[0] != dead {
+		t.Errorf("order = %v, want the first URL retried after its cooldown", urls)
+	}
+}
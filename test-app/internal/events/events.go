@@ -0,0 +1,161 @@
+// Package events is a small in-process bus for domain events such as join
+// requests being decided. Subscribers run synchronously on the publishing
+// goroutine, so they must be quick and must not call back into Publish.
+package events
+
+import (
+	"sync"
+	"time"
+)
+
+// Event types published by the handlers.
+const (
+	OrgJoinRequested  = "org.join.requested"
+	OrgJoinApproved   = "org.join.approved"
+	OrgJoinDenied     = "org.join.denied"
+	OrgAdminAppointed = "org.admin.appointed"
+
+	DossierOrgChanged = "dossier.org.changed"
+
+	DossierAccessRequested = "dossier.access.requested"
+	DossierAccessApproved  = "dossier.access.approved"
+	DossierAccessDenied    = "dossier.access.denied"
+
+	GuardianshipRequested = "guardianship.requested"
+	GuardianshipAccepted  = "guardianship.accepted"
+	GuardianshipDenied    = "guardianship.denied"
+
+	// Published by the store and the FGA client for the changes feed.
+	ObjectChanged = "store.changed"
+	ObjectDeleted = "store.deleted"
+	TupleWritten  = "tuple.written"
+	TupleDeleted  = "tuple.deleted"
+)
+
+// Event is one domain event. Recipients lists the users it concerns, so
+// notification features can fan it out without re-deriving the audience.
+type Event struct {
+	Seq        uint64            `json:"seq"`
+	Type       string            `json:"type"`
+	Actor      string            `json:"actor"`
+	Object     string            `json:"object"`
+	Recipients []string          `json:"recipients,omitempty"`
+	Data       map[string]string `json:"data,omitempty"`
+	Time       time.Time         `json:"time"`
+}
+
+// historySize bounds the history, and so how far back Since can reach.
+const historySize = 2000
+
+var (
+	mu          sync.RWMutex
+	subscribers = map[int]func(Event){}
+	nextId      int
+	history     []Event
+	seq         uint64
+	muted       bool
+)
+
+// Publish stamps e with the time and the next sequence number, keeps it in
+// the recent history and hands it to every subscriber. It does nothing while muted.
+func Publish(e Event) {
+	if e.Time.IsZero() {
+		e.Time = time.Now().UTC()
+	}
+	mu.Lock()
+	if muted {
+		mu.Unlock()
+		return
+	}
+	seq++
+	e.Seq = seq
+	history = append(history, e)
+	if len(history) > historySize {
+		history = history[len(history)-historySize:]
+	}
+	subs := make([]func(Event), 0, len(subscribers))
+	for _, fn := range subscribers {
+		subs = append(subs, fn)
+	}
+	mu.Unlock()
+	for _, fn := range subs {
+		fn(e)
+	}
+}
+
+// Subscribe registers fn for every future event and returns a function that removes it.
+func Subscribe(fn func(Event)) func() {
+	mu.Lock()
+	defer mu.Unlock()
+	id := nextId
+	nextId++
+	subscribers[id] = fn
+	return func() {
+		mu.Lock()
+		delete(subscribers, id)
+		mu.Unlock()
+	}
+}
+
+// Recent returns up to limit of the latest events, newest first.
+func Recent(limit int) []Event {
+	mu.RLock()
+	defer mu.RUnlock()
+	if limit <= 0 || limit > len(history) {
+		limit = len(history)
+	}
+	out := make([]Event, 0, limit)
+	for i := len(history) - 1; i >= 0 && len(out) < limit; i-- {
+		out = append(out, history[i])
+	}
+	return out
+}
+
+// Since returns up to limit events published after cursor, oldest first, and
+// the cursor to pass next time. complete is false when events after cursor
+// have already dropped out of the history, so the caller must resynchronise.
+func Since(cursor uint64, limit int) (evs []Event, next uint64, complete bool) {
+	mu.RLock()
+	defer mu.RUnlock()
+	next = cursor
+	if cursor > seq {
+		// A cursor from before a restart: nothing newer is known to be missing.
+		return []Event{}, seq, false
+	}
+	complete = len(history) == 0 || history[0].Seq <= cursor+1
+	evs = []Event{}
+	for _, e := range history {
+		if e.Seq <= cursor {
+			continue
+		}
+		if limit > 0 && len(evs) >= limit {
+			break
+		}
+		evs = append(evs, e)
+		next = e.Seq
+	}
+	return evs, next, complete
+}
+
+// Mute drops published events until the returned function is called. Sandboxed
+// requests use it so their changes reach neither subscribers nor the feed.
+func Mute() (unmute func()) {
+	mu.Lock()
+	defer mu.Unlock()
+	prev := muted
+	muted = true
+	return func() {
+		mu.Lock()
+		muted = prev
+		mu.Unlock()
+	}
+}
+
+// Reset clears history, the sequence and subscribers (for tests).
+func Reset() {
+	mu.Lock()
+	defer mu.Unlock()
+	history = nil
+	seq = 0
+	subscribers = map[int]func(Event){}
+}
@@ -0,0 +1,52 @@
+package events
+
+import "testing"
+
+func TestPublishSubscribe(t *testing.T) {
+	Reset()
+	defer Reset()
+
+	var got []string
+	unsubscribe := Subscribe(func(e Event) { got = append(got, e.Type) })
+	Publish(Event{Type: OrgJoinRequested, Actor: "bob", Object: "organization:o1"})
+	unsubscribe()
+	Publish(Event{Type: OrgJoinApproved, Actor: "alice", Object: "organization:o1"})
+
+	if len(got) != 1 || got[0] != OrgJoinRequested {
+		t.Errorf("subscriber saw %v, want only %s", got, OrgJoinRequested)
+	}
+	recent := Recent(10)
+	if len(recent) != 2 || recent[0].Type != OrgJoinApproved || recent[1].Time.IsZero() {
+		t.Errorf("Recent = %+v", recent)
+	}
+	if len(Recent(1)) != 1 {
+		t.Error("Recent(1) should return one event")
+	}
+}
+
+func TestSinceAndMute(t *testing.T) {
+	Reset()
+	defer Reset()
+
+	for i := 0; i < 3; i++ {
+		Publish(Event{Type: TupleWritten, Object: "dossier:d1"})
+	}
+	unmute := Mute()
+	Publish(Event{Type: TupleDeleted, Object: "dossier:d1"})
+	unmute()
+
+	evs, next, complete := Since(0, 2)
+	if len(evs) != 2 || !complete || next != evs[1].Seq {
+		t.Fatalf("Since(0, 2) = %d events, next %d, complete %v", len(evs), next, complete)
+	}
+	evs, next, _ = Since(next, 0)
+	if len(evs) != 1 || evs[0].Type != TupleWritten {
+		t.Errorf("muted event leaked or event missing: %+v", evs)
+	}
+	if evs, _, _ := Since(next, 0); len(evs) != 0 {
+		t.Errorf("nothing should follow the last cursor: %+v", evs)
+	}
+	if _, _, complete := Since(next+100, 0); complete {
+		t.Error("a cursor from the future must ask for a reset")
+	}
+}
@@ -0,0 +1,110 @@
+// Package extauthz is an Envoy ext_authz service in HTTP mode: Envoy forwards
+// the method, path and selected headers of each request, and the service
+// answers 200 to let it through or 403 to reject it. Routes in Rules are
+// checked against OpenFGA (the object id is taken from the path), so
+// fine-grained decisions can be enforced at the gateway next to OPA's coarse
+// ones. Routes without a rule are allowed; OPA remains responsible for them.
+package extauthz
+
+import (
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"test-app/internal/fga"
+	"test-app/internal/httputil"
+)
+
+// Rule maps requests matching Method and Pattern to an FGA check of Relation
+// on Type:{id}, where {id} is the path segment it stands for in Pattern.
+type Rule struct {
+	Method   string `json:"method"`
+	Pattern  string `json:"pattern"`
+	Type     string `json:"type"`
+	Relation string `json:"relation"`
+}
+
+// Rules are tried in order; literal routes sharing a prefix with {id} routes
+// (e.g. /api/dossiers/organizations/...) come first.
+// Each asks for the relation its handler checks (see handlers_test.go).
+var Rules = []Rule{
+	{"PUT", "/api/dossiers/organizations/{id}", "organization", "can_manage"},
+	{"DELETE", "/api/dossiers/organizations/{id}", "organization", "can_manage"},
+	{"GET", "/api/dossiers/organizations/{id}/join-requests", "organization", "can_manage"},
+	{"PUT", "/api/dossiers/{id}", "dossier", "editor"},
+	{"DELETE", "/api/dossiers/{id}", "dossier", "can_delete"},
+	{"GET", "/api/dossiers/{id}/relations", "dossier", "editor"},
+	{"POST", "/api/dossiers/{id}/archive", "dossier", "editor"},
+	{"POST", "/api/dossiers/{id}/restore", "dossier", "editor"},
+}
+
+// PathPrefix is the path_prefix Envoy's http_service must use; it is stripped before matching.
+const PathPrefix = "/ext-authz"
+
+// reserved are /api/dossiers/ sub-paths served by other handlers, not dossier ids.
+var reserved = map[string]bool{
+	"list": true, "admin": true, "create": true, "guardianships": true,
+	"organizations": true, "debug": true, "status": true, "shared-by-me": true,
+}
+
+// Match returns the rule for method and path and the object it targets.
+func Match(method, path string) (Rule, string, bool) {
+	segs := strings.Split(strings.Trim(path, "/"), "/")
+	for _, rule := range Rules {
+		if rule.Method != method {
+			continue
+		}
+		pat := strings.Split(strings.Trim(rule.Pattern, "/"), "/")
+		if len(pat) != len(segs) {
+			continue
+		}
+		id, ok := "", true
+		for i, p := range pat {
+			switch {
+			case p == "{id}":
+				id = segs[i]
+				ok = ok && id != "" && !reserved[id]
+			case p != segs[i]:
+				ok = false
+			}
+		}
+		if ok {
+			return rule, rule.Type + ":" + id, true
+		}
+	}
+	return Rule{}, "", false
+}
+
+// Handler serves the ext_authz checks. The caller is read from x-current-user,
+// which OPA sets when its ext_authz filter runs first.
+func Handler() http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		path := httputil.Unversioned(strings.TrimPrefix(r.URL.Path, PathPrefix))
+		rule, object, ok := Match(r.Method, path)
+		if !ok {
+			w.WriteHeader(http.StatusOK)
+			return
+		}
+		user := r.Header.Get("x-current-user")
+		if user == "" {
+			httputil.JSONError(w, "Unauthenticated", http.StatusForbidden)
+			return
+		}
+		allowed, err := fga.Allowed(r.Context(), fga.UserRef(user), rule.Relation, object)
+		if retry, ok := fga.RetryAfter(err); ok {
+			// Not a denial: the request could not be checked. Envoy passes
+			// the status through, so the client retries.
+			w.Header().Set("Retry-After", strconv.Itoa(int((retry+time.Second-1)/time.Second)))
+			httputil.JSONError(w, "Authorization service unavailable, retry later", http.StatusServiceUnavailable)
+			return
+		}
+		if !allowed {
+			w.Header().Set("x-ext-authz-denied", rule.Relation+" "+object)
+			httputil.JSONError(w, "Forbidden: "+rule.Relation+" on "+object+" required", http.StatusForbidden)
+			return
+		}
+		w.Header().Set("x-ext-authz-checked", rule.Relation+" "+object)
+		w.WriteHeader(http.StatusOK)
+	})
+}
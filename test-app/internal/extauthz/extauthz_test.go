@@ -0,0 +1,73 @@
+package extauthz
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"test-app/internal/config"
+)
+
+func TestMatch(t *testing.T) {
+	cases := []struct {
+		method, path, object, relation string
+	}{
+		{"PUT", "/api/dossiers/d1", "dossier:d1", "editor"},
+		{"GET", "/api/dossiers/d1/relations", "dossier:d1", "editor"},
+		{"PUT", "/api/dossiers/organizations/o1", "organization:o1", "can_manage"},
+		{"GET", "/api/dossiers/d1", "", ""},
+		{"DELETE", "/api/dossiers/guardianships", "", ""},
+	}
+	for _, c := range cases {
+		rule, object, ok := Match(c.method, c.path)
+		if ok != (c.object != "") || object != c.object || rule.Relation != c.relation {
+			t.Errorf("Match(%s %s) = %q %q %v", c.method, c.path, rule.Relation, object, ok)
+		}
+	}
+}
+
+func TestHandler(t *testing.T) {
+	fga := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var body map[string]interface{}
+		json.NewDecoder(r.Body).Decode(&body)
+		tk, _ := body["tuple_key"].(map[string]interface{})
+		if tk["user"] == "user:carol" {
+			w.WriteHeader(http.StatusServiceUnavailable)
+			return
+		}
+		json.NewEncoder(w).Encode(map[string]interface{}{"allowed": tk["user"] == "user:alice"})
+	}))
+	defer fga.Close()
+	origURL := config.OpenfgaURL
+	config.OpenfgaURL = fga.URL
+	defer func() { config.OpenfgaURL = origURL }()
+
+	check := func(method, path, user string) int {
+		w := httptest.NewRecorder()
+		req := httptest.NewRequest(method, PathPrefix+path, nil)
+		if user != "" {
+			req.Header.Set("x-current-user", user)
+		}
+		Handler().ServeHTTP(w, req)
+		return w.Code
+	}
+	if code := check("DELETE", "/api/dossiers/d1", "alice"); code != 200 {
+		t.Errorf("editor status = %d, want 200", code)
+	}
+	if code := check("DELETE", "/api/dossiers/d1", "bob"); code != 403 {
+		t.Errorf("non-editor status = %d, want 403", code)
+	}
+	if code := check("DELETE", "/api/v1/dossiers/d1", "bob"); code != 403 {
+		t.Errorf("non-editor status on /api/v1 = %d, want 403", code)
+	}
+	if code := check("DELETE", "/api/dossiers/d1", ""); code != 403 {
+		t.Errorf("anonymous status = %d, want 403", code)
+	}
+	if code := check("DELETE", "/api/dossiers/d1", "carol"); code != 503 {
+		t.Errorf("status while OpenFGA is down = %d, want 503", code)
+	}
+	if code := check("GET", "/api/dossiers/list", "bob"); code != 200 {
+		t.Errorf("unruled route status = %d, want 200", code)
+	}
+}
@@ -0,0 +1,120 @@
+// Package faults injects failures into outgoing calls for resilience demos:
+// added latency, a random error rate or a full outage of OpenFGA or the audit
+// sink. Nothing is injected until an admin configures a fault, and each fault
+// can expire on its own so a forgotten demo setting does not linger.
+package faults
+
+import (
+	"errors"
+	"fmt"
+	"math/rand"
+	"sync"
+	"time"
+)
+
+// Targets that can be faulted.
+const (
+	OpenFGA = "openfga"
+	Audit   = "audit"
+)
+
+// Targets lists the valid targets in display order.
+var Targets = []string{OpenFGA, Audit}
+
+// ErrInjected is wrapped by every injected failure.
+var ErrInjected = errors.New("injected fault")
+
+// Fault is the failure configured for one target.
+type Fault struct {
+	// LatencyMs is added before every call
+	LatencyMs int `json:"latencyMs"`
+	// ErrorRate is the fraction of calls (0 to 1) failing after the latency
+	ErrorRate float64 `json:"errorRate"`
+	// Outage fails every call
+	Outage bool `json:"outage"`
+	// Until is when the fault is lifted; zero keeps it until cleared
+	Until time.Time `json:"until,omitempty"`
+}
+
+func (f Fault) active() bool {
+	return (f.LatencyMs > 0 || f.ErrorRate > 0 || f.Outage) && (f.Until.IsZero() || time.Now().Before(f.Until))
+}
+
+var (
+	mu     sync.Mutex
+	faults = map[string]Fault{}
+	sleep  = time.Sleep
+	roll   = rand.Float64
+)
+
+// Set configures the fault for target, replacing any previous one.
+func Set(target string, f Fault) error {
+	if !valid(target) {
+		return fmt.Errorf("unknown fault target %q", target)
+	}
+	if f.LatencyMs < 0 || f.LatencyMs > 60000 {
+		return fmt.Errorf("latencyMs must be between 0 and 60000")
+	}
+	if f.ErrorRate < 0 || f.ErrorRate > 1 {
+		return fmt.Errorf("errorRate must be between 0 and 1")
+	}
+	mu.Lock()
+	defer mu.Unlock()
+	faults[target] = f
+	return nil
+}
+
+// Clear lifts the fault of target, or of every target when target is empty.
+func Clear(target string) {
+	mu.Lock()
+	defer mu.Unlock()
+	if target == "" {
+		faults = map[string]Fault{}
+		return
+	}
+	delete(faults, target)
+}
+
+// Active returns the faults currently in effect, by target.
+func Active() map[string]Fault {
+	mu.Lock()
+	defer mu.Unlock()
+	out := map[string]Fault{}
+	for target, f := range faults {
+		if f.active() {
+			out[target] = f
+		}
+	}
+	return out
+}
+
+// Inject applies target's fault to the call about to be made: it sleeps for
+// the configured latency and returns an error wrapping ErrInjected when the
+// call should fail. It returns nil straight away when no fault is active.
+func Inject(target string) error {
+	mu.Lock()
+	f, ok := faults[target]
+	mu.Unlock()
+	if !ok || !f.active() {
+		return nil
+	}
+	if f.LatencyMs > 0 {
+		sleep(time.Duration(f.LatencyMs) * time.Millisecond)
+	}
+	if f.Outage {
+		return fmt.Errorf("%s unavailable: %w", target, ErrInjected)
+	}
+	if f.ErrorRate > 0 && roll() < f.ErrorRate {
+		return fmt.Errorf("%s call failed: %w", target, ErrInjected)
+	}
+	return nil
+}
+
+func valid(target string) bool {
+	for _, t := range Targets {
+		if t == target {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,57 @@
+package faults
+
+import (
+	"errors"
+	"math/rand"
+	"testing"
+	"time"
+)
+
+func TestInject(t *testing.T) {
+	defer Clear("")
+	var slept time.Duration
+	sleep = func(d time.Duration) { slept += d }
+	defer func() { sleep = time.Sleep }()
+
+	if err := Inject(OpenFGA); err != nil {
+		t.Fatalf("no fault configured, got %v", err)
+	}
+	if err := Set("ldap", Fault{Outage: true}); err == nil {
+		t.Error("unknown target should be rejected")
+	}
+	if err := Set(OpenFGA, Fault{ErrorRate: 1.5}); err == nil {
+		t.Error("errorRate above 1 should be rejected")
+	}
+
+	Set(OpenFGA, Fault{LatencyMs: 200})
+	if err := Inject(OpenFGA); err != nil || slept != 200*time.Millisecond {
+		t.Errorf("latency fault: err = %v, slept = %s", err, slept)
+	}
+	if err := Inject(Audit); err != nil {
+		t.Errorf("audit is not faulted, got %v", err)
+	}
+
+	Set(Audit, Fault{Outage: true})
+	if err := Inject(Audit); !errors.Is(err, ErrInjected) {
+		t.Errorf("outage should fail with ErrInjected, got %v", err)
+	}
+
+	roll = func() float64 { return 0.3 }
+	defer func() { roll = rand.Float64 }()
+	Set(OpenFGA, Fault{ErrorRate: 0.5})
+	if err := Inject(OpenFGA); !errors.Is(err, ErrInjected) {
+		t.Errorf("roll below errorRate should fail, got %v", err)
+	}
+	Set(OpenFGA, Fault{ErrorRate: 0.2})
+	if err := Inject(OpenFGA); err != nil {
+		t.Errorf("roll above errorRate should pass, got %v", err)
+	}
+
+	Set(OpenFGA, Fault{Outage: true, Until: time.Now().Add(-time.Second)})
+	if err := Inject(OpenFGA); err != nil {
+		t.Errorf("expired fault should not apply, got %v", err)
+	}
+	if _, ok := Active()[OpenFGA]; ok {
+		t.Error("expired fault should not be listed as active")
+	}
+}
@@ -0,0 +1,142 @@
+package fga
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"sync"
+	"time"
+
+	"test-app/internal/audit"
+	"test-app/internal/config"
+)
+
+// Assertion is an expected check outcome stored alongside the authorization model.
+type Assertion struct {
+	User        string `json:"user"`
+	Relation    string `json:"relation"`
+	Object      string `json:"object"`
+	Expectation bool   `json:"expectation"`
+}
+
+// AssertionResult is the outcome of evaluating one assertion.
+type AssertionResult struct {
+	Assertion
+	Allowed bool   `json:"allowed"`
+	Passed  bool   `json:"passed"`
+	Error   string `json:"error,omitempty"`
+}
+
+// AssertionRun summarises the latest evaluation of every assertion.
+type AssertionRun struct {
+	Time    time.Time         `json:"time"`
+	ModelId string            `json:"modelId"`
+	Total   int               `json:"total"`
+	Failed  int               `json:"failed"`
+	Results []AssertionResult `json:"results"`
+}
+
+var (
+	assertionsMu     sync.Mutex
+	lastAssertionRun *AssertionRun
+)
+
+// ReadAssertions returns the assertions stored for the current model.
+func ReadAssertions(ctx context.Context) ([]Assertion, error) {
+	result, err := Request(ctx, "GET", "/stores/"+config.FgaStoreId+"/assertions/"+config.FgaModelId, nil)
+	if err != nil {
+		return nil, err
+	}
+	out := []Assertion{}
+	items, _ := result["assertions"].([]interface{})
+	for _, item := range items {
+		m, _ := item.(map[string]interface{})
+		key, _ := m["tuple_key"].(map[string]interface{})
+		a := Assertion{}
+		a.User, _ = key["user"].(string)
+		a.Relation, _ = key["relation"].(string)
+		a.Object, _ = key["object"].(string)
+		a.Expectation, _ = m["expectation"].(bool)
+		out = append(out, a)
+	}
+	return out, nil
+}
+
+// WriteAssertions replaces the assertions stored for the current model.
+func WriteAssertions(ctx context.Context, assertions []Assertion) error {
+	items := make([]map[string]interface{}, 0, len(assertions))
+	for _, a := range assertions {
+		items = append(items, map[string]interface{}{
+			"tuple_key":   map[string]string{"user": a.User, "relation": a.Relation, "object": a.Object},
+			"expectation": a.Expectation,
+		})
+	}
+	result, err := Request(ctx, "PUT", "/stores/"+config.FgaStoreId+"/assertions/"+config.FgaModelId,
+		map[string]interface{}{"assertions": items})
+	if err != nil {
+		return err
+	}
+	if msg, ok := result["message"].(string); ok && msg != "" {
+		return fmt.Errorf("%s", msg)
+	}
+	return nil
+}
+
+// RunAssertions evaluates every stored assertion against the current model and
+// tuples, audits each failure and remembers the run for the health endpoint.
+func RunAssertions(ctx context.Context) (*AssertionRun, error) {
+	assertions, err := ReadAssertions(ctx)
+	if err != nil {
+		return nil, err
+	}
+	run := &AssertionRun{Time: time.Now(), ModelId: config.FgaModelId, Total: len(assertions), Results: []AssertionResult{}}
+	for _, a := range assertions {
+		res := AssertionResult{Assertion: a}
+		allowed, err := evaluate(ctx, a.User, a.Relation, a.Object, nil, run.ModelId)
+		if err != nil {
+			res.Error = err.Error()
+		} else {
+			res.Allowed = allowed
+			res.Passed = allowed == a.Expectation
+		}
+		if !res.Passed {
+			run.Failed++
+			audit.SendAuditLog("Assertions", "fail", a.User, a.Relation, a.Object, "ASSERT",
+				fmt.Sprintf("Expected %v, got %v", a.Expectation, res.Allowed))
+		}
+		run.Results = append(run.Results, res)
+	}
+	assertionsMu.Lock()
+	lastAssertionRun = run
+	assertionsMu.Unlock()
+	return run, nil
+}
+
+// LastAssertionRun returns the most recent assertion run, or nil if none has completed.
+func LastAssertionRun() *AssertionRun {
+	assertionsMu.Lock()
+	defer assertionsMu.Unlock()
+	return lastAssertionRun
+}
+
+// RunAssertionsEvery re-runs the assertions on a fixed interval until the
+// process exits. Each run goes through live, which runs its argument against
+// the live store and model (see sandbox.Live); fga cannot import sandbox.
+func RunAssertionsEvery(interval time.Duration, live func(func())) {
+	for {
+		time.Sleep(interval)
+		if !config.FgaReady {
+			continue
+		}
+		var run *AssertionRun
+		var err error
+		live(func() { run, err = RunAssertions(context.Background()) })
+		if err != nil {
+			log.Printf("Assertions: run failed: %v", err)
+			continue
+		}
+		if run.Failed > 0 {
+			log.Printf("Assertions: %d of %d failing on model %s", run.Failed, run.Total, run.ModelId)
+		}
+	}
+}
@@ -0,0 +1,111 @@
+package fga
+
+import (
+	"strings"
+	"sync"
+	"time"
+
+	"test-app/internal/config"
+	"test-app/internal/store"
+)
+
+// listKey identifies a cached ListObjects result. The server, store and model
+// are part of it so sandboxes and model switches never see each other's lists.
+type listKey struct {
+	server, storeId, modelId string
+	user, relation, typeName string
+}
+
+type listEntry struct {
+	objects []string
+	expires time.Time
+}
+
+// CacheStats counts ListObjects cache activity since start or the last flush.
+type CacheStats struct {
+	Entries       int    `json:"entries"`
+	Hits          uint64 `json:"hits"`
+	NegativeHits  uint64 `json:"negativeHits"`
+	Misses        uint64 `json:"misses"`
+	Invalidations uint64 `json:"invalidations"`
+	TTLSeconds    int    `json:"ttlSeconds"`
+	// HitRate is Hits over lookups (Hits plus Misses), 0 before the first lookup.
+	HitRate float64 `json:"hitRate"`
+}
+
+var (
+	listMu    sync.Mutex
+	listCache = map[listKey]listEntry{}
+	listStats CacheStats
+)
+
+// cachedList returns a live cached result. Empty results are cached too, as
+// "nothing visible" is the common answer for most users and types.
+func cachedList(key listKey) ([]string, bool) {
+	if config.ListObjectsCacheTTL.Get() <= 0 {
+		return nil, false
+	}
+	listMu.Lock()
+	defer listMu.Unlock()
+	e, ok := listCache[key]
+	if !ok || time.Now().After(e.expires) {
+		delete(listCache, key)
+		listStats.Misses++
+		return nil, false
+	}
+	listStats.Hits++
+	if len(e.objects) == 0 {
+		listStats.NegativeHits++
+	}
+	return append([]string(nil), e.objects...), true
+}
+
+func storeList(key listKey, objects []string) {
+	if config.ListObjectsCacheTTL.Get() <= 0 {
+		return
+	}
+	listMu.Lock()
+	listCache[key] = listEntry{objects: append([]string(nil), objects...), expires: time.Now().Add(config.ListObjectsCacheTTL.Get())}
+	listMu.Unlock()
+}
+
+// invalidateLists drops cached lists a tuple change may affect: those of the
+// tuple's user and those of the changed object's type. Users and organizations
+// feed other types' relations (guardians, members), so changes to them drop everything.
+func invalidateLists(tuples []store.TupleKey) {
+	if len(tuples) == 0 {
+		return
+	}
+	listMu.Lock()
+	defer listMu.Unlock()
+	for _, t := range tuples {
+		typ, _, _ := strings.Cut(t.Object, ":")
+		for key := range listCache {
+			if typ == "user" || typ == "organization" || key.typeName == typ || key.user == t.User {
+				delete(listCache, key)
+				listStats.Invalidations++
+			}
+		}
+	}
+}
+
+// ListCacheStats returns the ListObjects cache counters.
+func ListCacheStats() CacheStats {
+	listMu.Lock()
+	defer listMu.Unlock()
+	stats := listStats
+	stats.Entries = len(listCache)
+	stats.TTLSeconds = int(config.ListObjectsCacheTTL.Get() / time.Second)
+	if lookups := stats.Hits + stats.Misses; lookups > 0 {
+		stats.HitRate = float64(stats.Hits) / float64(lookups)
+	}
+	return stats
+}
+
+// FlushListCache empties the ListObjects cache and resets its counters.
+func FlushListCache() {
+	listMu.Lock()
+	listCache = map[listKey]listEntry{}
+	listStats = CacheStats{}
+	listMu.Unlock()
+}
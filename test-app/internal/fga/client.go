@@ -2,43 +2,81 @@ package fga
 
 import (
 	"bytes"
+	"context"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"io"
 	"log"
 	"net/http"
+	"net/url"
 	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
 	"time"
 
 	"test-app/internal/audit"
+	"test-app/internal/backoff"
+	"test-app/internal/budget"
 	"test-app/internal/config"
+	"test-app/internal/endpoints"
+	"test-app/internal/events"
+	"test-app/internal/faults"
 	"test-app/internal/store"
 )
 
-func Request(method, path string, body interface{}) (map[string]interface{}, error) {
-	var reqBody io.Reader
-	if body != nil {
-		b, _ := json.Marshal(body)
-		reqBody = bytes.NewReader(b)
-	}
-	req, err := http.NewRequest(method, config.OpenfgaURL+path, reqBody)
-	if err != nil {
+// Request calls the OpenFGA API, counting the call against the budget of the
+// request ctx belongs to. Failures of OpenFGA itself are returned as
+// *UnavailableError, so callers can tell them apart from a denial; 4xx
+// answers other than 429 are returned as decoded bodies, with their "code"
+// and "message".
+func Request(ctx context.Context, method, path string, body interface{}) (map[string]interface{}, error) {
+	if err := budget.Count(ctx); err != nil {
 		return nil, err
 	}
-	req.Header.Set("Content-Type", "application/json")
-	resp, err := http.DefaultClient.Do(req)
+	return request(method, path, body)
+}
+
+func request(method, path string, body interface{}) (map[string]interface{}, error) {
+	if err := faults.Inject(faults.OpenFGA); err != nil {
+		return nil, unavailable(err, nil)
+	}
+	var payload []byte
+	if body != nil {
+		payload, _ = json.Marshal(body)
+	}
+	resp, err := endpoints.Do(config.OpenfgaURL, func(base string) (*http.Request, error) {
+		var reqBody io.Reader
+		if payload != nil {
+			reqBody = bytes.NewReader(payload)
+		}
+		req, err := http.NewRequest(method, base+path, reqBody)
+		if err != nil {
+			return nil, err
+		}
+		req.Header.Set("Content-Type", "application/json")
+		if token := config.Secret(config.OpenfgaAPIToken); token != "" {
+			req.Header.Set("Authorization", "Bearer "+token)
+		}
+		return req, nil
+	})
 	if err != nil {
-		return nil, err
+		return nil, unavailable(err, nil)
 	}
 	defer resp.Body.Close()
+	if resp.StatusCode >= 500 || resp.StatusCode == http.StatusTooManyRequests {
+		return nil, unavailable(fmt.Errorf("openfga answered %s", resp.Status), resp)
+	}
 	var result map[string]interface{}
 	if err := json.NewDecoder(resp.Body).Decode(&result); err != nil {
-		return nil, fmt.Errorf("failed to decode FGA response: %w", err)
+		return nil, unavailable(fmt.Errorf("failed to decode FGA response: %w", err), resp)
 	}
 	return result, nil
 }
 
-func Write(writes []store.TupleKey, deletes []store.TupleKey) error {
+// writeTo sends one write call to storeId and publishes its effects.
+func writeTo(ctx context.Context, storeId string, writes []store.TupleKey, deletes []store.TupleKey) error {
 	body := map[string]interface{}{}
 	if len(writes) > 0 {
 		body["writes"] = map[string]interface{}{"tuple_keys": writes}
@@ -46,29 +84,72 @@ func Write(writes []store.TupleKey, deletes []store.TupleKey) error {
 	if len(deletes) > 0 {
 		body["deletes"] = map[string]interface{}{"tuple_keys": deletes}
 	}
-	_, err := Request("POST", "/stores/"+config.FgaStoreId+"/write", body)
+	res, err := Request(ctx, "POST", "/stores/"+storeId+"/write", body)
+	if err == nil && res["code"] != nil {
+		// OpenFGA rejected the whole transaction, e.g. a tuple that already exists.
+		err = fmt.Errorf("openfga write rejected: %v", res["message"])
+	}
 	if err == nil {
+		store.Touch()
+		invalidateLists(writes)
+		invalidateLists(deletes)
 		for _, t := range writes {
 			audit.SendAuditLog("OpenFGA", "write", t.User, t.Relation, t.Object, "WRITE", "Tuple added: "+t.User+" "+t.Relation+" "+t.Object)
+			events.Publish(events.Event{Type: events.TupleWritten, Object: t.Object, Data: map[string]string{"user": t.User, "relation": t.Relation}})
 		}
 		for _, t := range deletes {
 			audit.SendAuditLog("OpenFGA", "delete", t.User, t.Relation, t.Object, "WRITE", "Tuple deleted: "+t.User+" "+t.Relation+" "+t.Object)
+			events.Publish(events.Event{Type: events.TupleDeleted, Object: t.Object, Data: map[string]string{"user": t.User, "relation": t.Relation}})
 		}
 	}
 	return err
 }
 
-func Check(user, relation, object string) bool {
+// evaluate runs a check against the given model without recording or auditing it.
+func evaluate(ctx context.Context, user, relation, object string, contextualTuples []store.TupleKey, modelId string) (bool, error) {
+	return evaluateIn(ctx, config.FgaStoreId, user, relation, object, contextualTuples, modelId)
+}
+
+// evaluateIn is evaluate against another store, for shadow evaluation.
+func evaluateIn(ctx context.Context, storeId, user, relation, object string, contextualTuples []store.TupleKey, modelId string) (bool, error) {
 	body := map[string]interface{}{
 		"tuple_key":              map[string]string{"user": user, "relation": relation, "object": object},
-		"authorization_model_id": config.FgaModelId,
+		"authorization_model_id": modelId,
+	}
+	if len(contextualTuples) > 0 {
+		tupleKeys := make([]map[string]string, 0, len(contextualTuples))
+		for _, t := range contextualTuples {
+			tupleKeys = append(tupleKeys, map[string]string{
+				"user": t.User, "relation": t.Relation, "object": t.Object,
+			})
+		}
+		body["contextual_tuples"] = map[string]interface{}{"tuple_keys": tupleKeys}
 	}
-	result, err := Request("POST", "/stores/"+config.FgaStoreId+"/check", body)
+	result, err := Request(ctx, "POST", "/stores/"+storeId+"/check", body)
 	if err != nil {
-		audit.SendAuditLog("OpenFGA", "deny", user, relation, object, "CHECK", "Error: "+err.Error())
-		return false
+		return false, err
 	}
 	allowed, _ := result["allowed"].(bool)
+	return allowed, nil
+}
+
+// Check reports whether user has relation on object. A failed check counts
+// as a denial; use Allowed to tell the two apart.
+func Check(ctx context.Context, user, relation, object string) bool {
+	allowed, _ := Allowed(ctx, user, relation, object)
+	return allowed
+}
+
+// Allowed is Check returning the failure, if any, along with the denial:
+// errors.Is(err, ErrUnavailable) when OpenFGA could not answer.
+func Allowed(ctx context.Context, user, relation, object string) (bool, error) {
+	allowed, err := evaluate(ctx, user, relation, object, nil, config.FgaModelId)
+	recordDecision(user, relation, object, nil, config.FgaModelId, allowed, err)
+	if err != nil {
+		audit.SendAuditLog("OpenFGA", "deny", user, relation, object, "CHECK", "Error: "+err.Error())
+		return false, err
+	}
+	shadowCheck(user, relation, object, nil, allowed)
 	decision := "deny"
 	reason := user + " does not have " + relation + " on " + object
 	if allowed {
@@ -76,27 +157,19 @@ func Check(user, relation, object string) bool {
 		reason = user + " has " + relation + " on " + object
 	}
 	audit.SendAuditLog("OpenFGA", decision, user, relation, object, "CHECK", reason)
-	return allowed
+	return allowed, nil
 }
 
-func CheckWithContext(user, relation, object string, contextualTuples []store.TupleKey) bool {
-	tupleKeys := make([]map[string]string, 0, len(contextualTuples))
-	for _, t := range contextualTuples {
-		tupleKeys = append(tupleKeys, map[string]string{
-			"user": t.User, "relation": t.Relation, "object": t.Object,
-		})
-	}
-	body := map[string]interface{}{
-		"tuple_key":              map[string]string{"user": user, "relation": relation, "object": object},
-		"authorization_model_id": config.FgaModelId,
-		"contextual_tuples":      map[string]interface{}{"tuple_keys": tupleKeys},
-	}
-	result, err := Request("POST", "/stores/"+config.FgaStoreId+"/check", body)
+// CheckWithContext is Allowed with contextual tuples added to the stored ones
+// for this check only.
+func CheckWithContext(ctx context.Context, user, relation, object string, contextualTuples []store.TupleKey) (bool, error) {
+	allowed, err := evaluate(ctx, user, relation, object, contextualTuples, config.FgaModelId)
+	recordDecision(user, relation, object, contextualTuples, config.FgaModelId, allowed, err)
 	if err != nil {
 		audit.SendAuditLog("OpenFGA", "deny", user, relation, object, "CHECK_CONTEXT", "Error: "+err.Error())
-		return false
+		return false, err
 	}
-	allowed, _ := result["allowed"].(bool)
+	shadowCheck(user, relation, object, contextualTuples, allowed)
 	decision := "deny"
 	reason := user + " does not have " + relation + " on " + object + " (contextual)"
 	if allowed {
@@ -104,25 +177,116 @@ func CheckWithContext(user, relation, object string, contextualTuples []store.Tu
 		reason = user + " has " + relation + " on " + object + " (contextual)"
 	}
 	audit.SendAuditLog("OpenFGA", decision, user, relation, object, "CHECK_CONTEXT", reason)
-	return allowed
+	return allowed, nil
 }
 
-func ListObjects(user, relation, typeName string) []string {
+// BatchCheck checks several relations of one user on one object in a single
+// batch-check call, returning a map keyed by relation. Servers without
+// batch-check fall back to one Check per relation. When OpenFGA could not
+// answer, the error matches ErrUnavailable and the map is nil.
+func BatchCheck(ctx context.Context, user, object string, relations []string) (map[string]bool, error) {
+	checks := make([]map[string]interface{}, 0, len(relations))
+	for i, rel := range relations {
+		checks = append(checks, map[string]interface{}{
+			"tuple_key":      map[string]string{"user": user, "relation": rel, "object": object},
+			"correlation_id": strconv.Itoa(i),
+		})
+	}
+	body := map[string]interface{}{"checks": checks, "authorization_model_id": config.FgaModelId}
+	out := make(map[string]bool, len(relations))
+	result, err := Request(ctx, "POST", "/stores/"+config.FgaStoreId+"/batch-check", body)
+	if errors.Is(err, ErrUnavailable) {
+		return nil, err
+	}
+	results, _ := result["result"].(map[string]interface{})
+	if err != nil || len(results) != len(relations) {
+		for _, rel := range relations {
+			allowed, err := Allowed(ctx, user, rel, object)
+			if errors.Is(err, ErrUnavailable) {
+				return nil, err
+			}
+			out[rel] = allowed
+		}
+		return out, nil
+	}
+	for i, rel := range relations {
+		res, _ := results[strconv.Itoa(i)].(map[string]interface{})
+		allowed, _ := res["allowed"].(bool)
+		var checkErr error
+		if msg, ok := res["error"].(map[string]interface{}); ok {
+			checkErr = fmt.Errorf("%v", msg["message"])
+		}
+		recordDecision(user, rel, object, nil, config.FgaModelId, allowed, checkErr)
+		if checkErr == nil {
+			shadowCheck(user, rel, object, nil, allowed)
+		}
+		decision := "deny"
+		reason := user + " does not have " + rel + " on " + object + " (batch)"
+		if allowed {
+			decision = "allow"
+			reason = user + " has " + rel + " on " + object + " (batch)"
+		}
+		audit.SendAuditLog("OpenFGA", decision, user, rel, object, "CHECK", reason)
+		out[rel] = allowed
+	}
+	return out, nil
+}
+
+// ReadChanges returns the tuples written or deleted since token (from the
+// beginning when empty) and the token to continue from. OpenFGA hands back the
+// same token once there is nothing newer. It runs in the background, outside
+// any request's call budget.
+func ReadChanges(token string) ([]store.TupleKey, string, error) {
+	path := "/stores/" + config.FgaStoreId + "/changes?page_size=100"
+	if token != "" {
+		path += "&continuation_token=" + url.QueryEscape(token)
+	}
+	result, err := Request(context.Background(), "GET", path, nil)
+	if err != nil {
+		return nil, token, err
+	}
+	var out []store.TupleKey
+	changes, _ := result["changes"].([]interface{})
+	for _, c := range changes {
+		cm, _ := c.(map[string]interface{})
+		key, _ := cm["tuple_key"].(map[string]interface{})
+		user, _ := key["user"].(string)
+		relation, _ := key["relation"].(string)
+		object, _ := key["object"].(string)
+		out = append(out, store.TupleKey{User: user, Relation: relation, Object: object})
+	}
+	next, _ := result["continuation_token"].(string)
+	if next == "" {
+		next = token
+	}
+	return out, next, nil
+}
+
+// ListObjects returns the objects of typeName user has relation on. An error
+// means the list could not be obtained, not that it is empty: callers answer
+// 503 rather than an empty list.
+func ListObjects(ctx context.Context, user, relation, typeName string) ([]string, error) {
+	key := listKey{config.OpenfgaURL, config.FgaStoreId, config.FgaModelId, user, relation, typeName}
+	if objects, ok := cachedList(key); ok {
+		audit.SendAuditLog("OpenFGA", "allow", user, relation, typeName+":*", "LIST", fmt.Sprintf("Listed %d %s objects (cached)", len(objects), typeName))
+		return objects, nil
+	}
 	body := map[string]interface{}{
 		"user":                   user,
 		"relation":               relation,
 		"type":                   typeName,
 		"authorization_model_id": config.FgaModelId,
 	}
-	result, err := Request("POST", "/stores/"+config.FgaStoreId+"/list-objects", body)
+	result, err := Request(ctx, "POST", "/stores/"+config.FgaStoreId+"/list-objects", body)
 	if err != nil {
 		audit.SendAuditLog("OpenFGA", "deny", user, relation, typeName+":*", "LIST", "Error: "+err.Error())
-		return nil
+		return nil, err
 	}
 	objects, ok := result["objects"].([]interface{})
 	if !ok {
 		audit.SendAuditLog("OpenFGA", "allow", user, relation, typeName+":*", "LIST", fmt.Sprintf("Listed 0 %s objects", typeName))
-		return nil
+		storeList(key, nil)
+		return nil, nil
 	}
 	var out []string
 	for _, o := range objects {
@@ -131,27 +295,266 @@ func ListObjects(user, relation, typeName string) []string {
 		}
 	}
 	audit.SendAuditLog("OpenFGA", "allow", user, relation, typeName+":*", "LIST", fmt.Sprintf("Listed %d %s objects", len(out), typeName))
-	return out
+	storeList(key, out)
+	return out, nil
+}
+
+// Expand returns the userset tree for relation on object.
+func Expand(ctx context.Context, relation, object string) (map[string]interface{}, error) {
+	body := map[string]interface{}{
+		"tuple_key":              map[string]string{"relation": relation, "object": object},
+		"authorization_model_id": config.FgaModelId,
+	}
+	result, err := Request(ctx, "POST", "/stores/"+config.FgaStoreId+"/expand", body)
+	if err != nil {
+		return nil, err
+	}
+	tree, _ := result["tree"].(map[string]interface{})
+	return tree, nil
 }
 
+// ListUsers returns the users of userType that have relation on object.
+// Wildcard grants are reported as "<type>:*".
+func ListUsers(ctx context.Context, object, relation, userType string) ([]string, error) {
+	objType, objId, _ := strings.Cut(object, ":")
+	body := map[string]interface{}{
+		"object":                 map[string]string{"type": objType, "id": objId},
+		"relation":               relation,
+		"user_filters":           []map[string]string{{"type": userType}},
+		"authorization_model_id": config.FgaModelId,
+	}
+	result, err := Request(ctx, "POST", "/stores/"+config.FgaStoreId+"/list-users", body)
+	if err != nil {
+		audit.SendAuditLog("OpenFGA", "deny", userType+":*", relation, object, "LIST_USERS", "Error: "+err.Error())
+		return nil, err
+	}
+	users, _ := result["users"].([]interface{})
+	var out []string
+	for _, u := range users {
+		um, _ := u.(map[string]interface{})
+		if obj, ok := um["object"].(map[string]interface{}); ok {
+			out = append(out, fmt.Sprintf("%v:%v", obj["type"], obj["id"]))
+		} else if wc, ok := um["wildcard"].(map[string]interface{}); ok {
+			out = append(out, fmt.Sprintf("%v:*", wc["type"]))
+		}
+	}
+	audit.SendAuditLog("OpenFGA", "allow", userType+":*", relation, object, "LIST_USERS", fmt.Sprintf("Listed %d users", len(out)))
+	return out, nil
+}
+
+// ProbeModel verifies OpenFGA is reachable and the configured model exists.
+func ProbeModel(ctx context.Context) error {
+	return probeModel(ctx, config.FgaStoreId, config.FgaModelId)
+}
+
+// canaryUser and canaryObject name the throwaway tuple Canary writes; nothing
+// in the data refers to them.
+const (
+	canaryUser   = "user:__warmup_canary"
+	canaryObject = "dossier:__warmup_canary"
+)
+
+// Canary writes an owner tuple, checks that the model derives viewer and
+// editor from it, and deletes it again. It bypasses Write so the round trip
+// leaves no audit entries, events or cache invalidations behind. It runs in
+// the background, outside any request's call budget.
+func Canary() error {
+	ctx := context.Background()
+	tuple := []store.TupleKey{{User: canaryUser, Relation: "owner", Object: canaryObject}}
+	write := func(key string) error {
+		result, err := Request(ctx, "POST", "/stores/"+config.FgaStoreId+"/write", map[string]interface{}{
+			key: map[string]interface{}{"tuple_keys": tuple}, "authorization_model_id": config.FgaModelId,
+		})
+		if err != nil {
+			return err
+		}
+		if msg, ok := result["message"].(string); ok {
+			return fmt.Errorf("canary %s: %s", key, msg)
+		}
+		return nil
+	}
+	if err := write("writes"); err != nil {
+		return err
+	}
+	defer write("deletes")
+	for _, relation := range []string{"viewer", "editor"} {
+		allowed, err := evaluate(ctx, canaryUser, relation, canaryObject, nil, config.FgaModelId)
+		if err != nil {
+			return fmt.Errorf("canary check %s: %w", relation, err)
+		}
+		if !allowed {
+			return fmt.Errorf("canary check: model does not derive %s from owner", relation)
+		}
+	}
+	return nil
+}
+
+// ProbeConfig checks that storeId exists and holds the authorization model modelId.
+func ProbeConfig(ctx context.Context, storeId, modelId string) error {
+	result, err := Request(ctx, "GET", "/stores/"+storeId, nil)
+	if err != nil {
+		return err
+	}
+	if id, _ := result["id"].(string); id != storeId {
+		return fmt.Errorf("store %s not found", storeId)
+	}
+	return probeModel(ctx, storeId, modelId)
+}
+
+func probeModel(ctx context.Context, storeId, modelId string) error {
+	result, err := Request(ctx, "GET", "/stores/"+storeId+"/authorization-models/"+modelId, nil)
+	if err != nil {
+		return err
+	}
+	if _, ok := result["authorization_model"]; !ok {
+		msg, _ := result["message"].(string)
+		if msg == "" {
+			msg = "authorization model not found"
+		}
+		return fmt.Errorf("%s", msg)
+	}
+	return nil
+}
+
+// WriteModel stores a new authorization model version and returns its id.
+func WriteModel(ctx context.Context, model map[string]interface{}) (string, error) {
+	result, err := Request(ctx, "POST", "/stores/"+config.FgaStoreId+"/authorization-models", model)
+	if err != nil {
+		return "", err
+	}
+	id, _ := result["authorization_model_id"].(string)
+	if id == "" {
+		msg, _ := result["message"].(string)
+		if msg == "" {
+			msg = "authorization model was not accepted"
+		}
+		return "", fmt.Errorf("%s", msg)
+	}
+	return id, nil
+}
+
+// ReadAll returns every tuple in the store, following continuation tokens.
+func ReadAll(ctx context.Context) ([]store.TupleKey, error) {
+	var out []store.TupleKey
+	err := ReadPages(ctx, func(page []store.TupleKey) error {
+		out = append(out, page...)
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+// ReadPages reads every tuple in the store page by page, handing each page to
+// fn as it arrives so large stores can be streamed. An error from fn stops the read.
+func ReadPages(ctx context.Context, fn func([]store.TupleKey) error) error {
+	token := ""
+	for {
+		page, next, err := Read(ctx, store.TupleKey{}, 100, token)
+		if err != nil {
+			return err
+		}
+		if err := fn(page); err != nil {
+			return err
+		}
+		if next == "" {
+			return nil
+		}
+		token = next
+	}
+}
+
+// Read returns one page of tuples matching filter, whose fields are passed to
+// OpenFGA as-is (Object may be just "type:"), and the continuation token of
+// the next page, empty on the last one.
+func Read(ctx context.Context, filter store.TupleKey, pageSize int, token string) ([]store.TupleKey, string, error) {
+	body := map[string]interface{}{"page_size": pageSize}
+	key := map[string]string{}
+	for field, v := range map[string]string{"user": filter.User, "relation": filter.Relation, "object": filter.Object} {
+		if v != "" {
+			key[field] = v
+		}
+	}
+	if len(key) > 0 {
+		body["tuple_key"] = key
+	}
+	if token != "" {
+		body["continuation_token"] = token
+	}
+	result, err := Request(ctx, "POST", "/stores/"+config.FgaStoreId+"/read", body)
+	if err != nil {
+		return nil, "", err
+	}
+	if msg, ok := result["message"].(string); ok && result["tuples"] == nil {
+		return nil, "", fmt.Errorf("OpenFGA read: %s", msg)
+	}
+	page := []store.TupleKey{}
+	tuples, _ := result["tuples"].([]interface{})
+	for _, t := range tuples {
+		tm, _ := t.(map[string]interface{})
+		key, _ := tm["key"].(map[string]interface{})
+		user, _ := key["user"].(string)
+		relation, _ := key["relation"].(string)
+		object, _ := key["object"].(string)
+		page = append(page, store.TupleKey{User: user, Relation: relation, Object: object})
+	}
+	next, _ := result["continuation_token"].(string)
+	return page, next, nil
+}
+
+// configPath is the file openfga-init writes the store and model ids to.
+const configPath = "/shared/openfga-store.json"
+
+// loadPolicy bounds the wait for configPath, as long as the 90 seconds the
+// fixed 3-second polling used to allow.
+var loadPolicy = backoff.Policy{Initial: 100 * time.Millisecond, Max: 5 * time.Second, Timeout: 90 * time.Second}
+
+// LoadConfig waits for openfga-init to publish the store and model ids. The
+// file is retried with backoff, and at once when its directory changes or
+// the process gets SIGHUP, so the app is ready as soon as the file is.
 func LoadConfig() {
-	configPath := "/shared/openfga-store.json"
-	for attempt := 1; attempt <= 30; attempt++ {
+	stop := backoff.KickOnChange(filepath.Dir(configPath))
+	defer stop()
+	err := backoff.Retry(loadPolicy, func(attempt int) error {
 		data, err := os.ReadFile(configPath)
-		if err == nil {
-			var cfg store.FgaConfig
-			if unmarshalErr := json.Unmarshal(data, &cfg); unmarshalErr != nil {
-				log.Printf("WARNING: failed to parse FGA config: %v", unmarshalErr)
-			} else if cfg.StoreId != "" && cfg.ModelId != "" {
-				config.FgaStoreId = cfg.StoreId
-				config.FgaModelId = cfg.ModelId
-				config.FgaReady = true
-				log.Printf("Loaded OpenFGA config: store=%s model=%s", config.FgaStoreId, config.FgaModelId)
-				return
+		if err != nil {
+			if attempt == 1 {
+				log.Printf("Waiting for OpenFGA config at %s...", configPath)
 			}
+			return err
+		}
+		var cfg store.FgaConfig
+		if err := json.Unmarshal(data, &cfg); err != nil {
+			log.Printf("WARNING: failed to parse FGA config: %v", err)
+			return err
+		}
+		if cfg.StoreId == "" || cfg.ModelId == "" {
+			return fmt.Errorf("%s has no store or model id yet", configPath)
 		}
-		log.Printf("Waiting for OpenFGA config (%d/30)...", attempt)
-		time.Sleep(3 * time.Second)
+		config.FgaStoreId = cfg.StoreId
+		config.FgaModelId = cfg.ModelId
+		config.FgaReady = true
+		log.Printf("Loaded OpenFGA config: store=%s model=%s (attempt %d)", config.FgaStoreId, config.FgaModelId, attempt)
+		// Steps waiting for FgaReady need not sit out their delay.
+		backoff.Kick()
+		return nil
+	})
+	if err != nil {
+		log.Printf("WARNING: Could not load OpenFGA config after %s: %v", loadPolicy.Timeout, err)
+	}
+}
+
+// Rehydrate reconciles OpenFGA with persisted data according to config.RehydrateMode.
+func Rehydrate() {
+	switch config.RehydrateMode {
+	case "off":
+		log.Println("Rehydration disabled, trusting tuples already in OpenFGA")
+	case "verify":
+		store.VerifyTuples(func() ([]store.TupleKey, error) { return ReadAll(context.Background()) })
+	default:
+		store.RehydrateTuples(func(writes, deletes []store.TupleKey) error {
+			return Write(context.Background(), writes, deletes)
+		})
 	}
-	log.Println("WARNING: Could not load OpenFGA config after 30 attempts")
 }
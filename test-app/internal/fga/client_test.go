@@ -0,0 +1,166 @@
+package fga
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"test-app/internal/config"
+	"test-app/internal/store"
+)
+
+func TestBatchCheck(t *testing.T) {
+	batch := true
+	var paths []string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		paths = append(paths, r.URL.Path)
+		if strings.HasSuffix(r.URL.Path, "/batch-check") && batch {
+			json.NewEncoder(w).Encode(map[string]interface{}{"result": map[string]interface{}{
+				"0": map[string]interface{}{"allowed": true},
+				"1": map[string]interface{}{"allowed": false},
+			}})
+			return
+		}
+		var body struct {
+			TupleKey map[string]string `json:"tuple_key"`
+		}
+		json.NewDecoder(r.Body).Decode(&body)
+		json.NewEncoder(w).Encode(map[string]interface{}{"allowed": body.TupleKey["relation"] == "viewer"})
+	}))
+	defer server.Close()
+	origURL := config.OpenfgaURL
+	config.OpenfgaURL = server.URL
+	defer func() { config.OpenfgaURL = origURL }()
+
+	got, _ := BatchCheck(context.Background(), "user:alice", "dossier:d1", []string{"viewer", "editor"})
+	if !got["viewer"] || got["editor"] || len(paths) != 1 {
+		t.Errorf("batch = %v after %v", got, paths)
+	}
+
+	// A server without batch-check answers with something else; fall back to single checks.
+	batch, paths = false, nil
+	got, _ = BatchCheck(context.Background(), "user:alice", "dossier:d1", []string{"viewer", "editor"})
+	if !got["viewer"] || got["editor"] || len(paths) != 3 {
+		t.Errorf("fallback = %v after %v", got, paths)
+	}
+}
+
+func TestListObjectsCache(t *testing.T) {
+	calls := 0
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if strings.HasSuffix(r.URL.Path, "/list-objects") {
+			calls++
+			json.NewEncoder(w).Encode(map[string]interface{}{"objects": []interface{}{"dossier:d1"}})
+			return
+		}
+		json.NewEncoder(w).Encode(map[string]interface{}{})
+	}))
+	defer server.Close()
+	origURL, origTTL := config.OpenfgaURL, config.ListObjectsCacheTTL.Get()
+	config.OpenfgaURL = server.URL
+	config.ListObjectsCacheTTL.Set(time.Minute)
+	defer func() {
+		config.OpenfgaURL = origURL
+		config.ListObjectsCacheTTL.Set(origTTL)
+	}()
+	FlushListCache()
+	defer FlushListCache()
+
+	ListObjects(context.Background(), "user:alice", "viewer", "dossier")
+	ListObjects(context.Background(), "user:alice", "viewer", "dossier")
+	ListObjects(context.Background(), "user:alice", "viewer", "organization")
+	if calls != 2 {
+		t.Fatalf("list-objects calls = %d, want 2 (second dossier list cached)", calls)
+	}
+
+	// A dossier write drops dossier lists but keeps the organization one.
+	Write(context.Background(), []store.TupleKey{{User: "user:bob", Relation: "owner", Object: "dossier:d2"}}, nil)
+	ListObjects(context.Background(), "user:alice", "viewer", "dossier")
+	ListObjects(context.Background(), "user:alice", "viewer", "organization")
+	if calls != 3 {
+		t.Errorf("list-objects calls = %d, want 3 after invalidation", calls)
+	}
+	// A guardianship write can change anyone's dossiers, so everything goes.
+	Write(context.Background(), []store.TupleKey{{User: "user:bob", Relation: "guardian", Object: "user:alice"}}, nil)
+	if stats := ListCacheStats(); stats.Entries != 0 || stats.Hits != 2 || stats.Invalidations != 3 || stats.HitRate != float64(stats.Hits)/float64(stats.Hits+stats.Misses) {
+		t.Errorf("stats = %+v", stats)
+	}
+}
+
+func TestShadowCheck(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var body struct {
+			TupleKey map[string]string `json:"tuple_key"`
+			ModelId  string            `json:"authorization_model_id"`
+		}
+		json.NewDecoder(r.Body).Decode(&body)
+		// The shadow model no longer grants editor.
+		allowed := body.TupleKey["relation"] == "viewer" || body.ModelId == "primary"
+		json.NewEncoder(w).Encode(map[string]interface{}{"allowed": allowed})
+	}))
+	defer server.Close()
+	origURL, origModel := config.OpenfgaURL, config.FgaModelId
+	config.OpenfgaURL, config.FgaModelId = server.URL, "primary"
+	defer func() { config.OpenfgaURL, config.FgaModelId = origURL, origModel }()
+	SetShadow(ShadowConfig{ModelId: "candidate"})
+	defer SetShadow(ShadowConfig{})
+
+	if !Check(context.Background(), "user:alice", "viewer", "dossier:d1") || !Check(context.Background(), "user:alice", "editor", "dossier:d1") {
+		t.Fatal("responses must come from the primary model")
+	}
+	deadline := time.Now().Add(2 * time.Second)
+	for Shadow().Compared < 2 && time.Now().Before(deadline) {
+		time.Sleep(5 * time.Millisecond)
+	}
+	rep := Shadow()
+	if rep.Compared != 2 || rep.Disagreements != 1 || len(rep.Recent) != 1 {
+		t.Fatalf("report = %+v", rep)
+	}
+	if d := rep.Recent[0]; d.Relation != "editor" || !d.Primary || d.Shadow || d.ShadowModelId != "candidate" {
+		t.Errorf("disagreement = %+v", d)
+	}
+
+	// Sandboxed checks are not shadowed.
+	restore := Use("sandbox-store", "primary")
+	Check(context.Background(), "user:alice", "editor", "dossier:d1")
+	restore()
+	time.Sleep(20 * time.Millisecond)
+	if Shadow().Compared != 2 {
+		t.Error("checks against a sandbox store should not be shadowed")
+	}
+}
+
+func TestAllowed_Unavailable(t *testing.T) {
+	status := http.StatusServiceUnavailable
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if status != 200 {
+			w.Header().Set("Retry-After", "7")
+			w.WriteHeader(status)
+			return
+		}
+		json.NewEncoder(w).Encode(map[string]interface{}{"allowed": false})
+	}))
+	defer server.Close()
+	origURL := config.OpenfgaURL
+	config.OpenfgaURL = server.URL
+	defer func() { config.OpenfgaURL = origURL }()
+
+	allowed, err := Allowed(context.Background(), "user:alice", "viewer", "dossier:d1")
+	if allowed || !errors.Is(err, ErrUnavailable) {
+		t.Fatalf("Allowed = %v, %v; want an unavailable error", allowed, err)
+	}
+	if d, ok := RetryAfter(err); !ok || d != 7*time.Second {
+		t.Errorf("RetryAfter = %v, %v", d, ok)
+	}
+
+	// A real denial carries no error.
+	status = 200
+	if allowed, err := Allowed(context.Background(), "user:alice", "viewer", "dossier:d1"); allowed || err != nil {
+		t.Errorf("denial = %v, %v", allowed, err)
+	}
+}
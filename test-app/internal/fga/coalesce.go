@@ -0,0 +1,159 @@
+package fga
+
+import (
+	"context"
+	"sync"
+
+	"test-app/internal/budget"
+	"test-app/internal/config"
+	"test-app/internal/store"
+)
+
+// Concurrent Write calls are group-committed: while one write is in flight,
+// writes from other handlers queue up and go out together as the next call.
+// A caller's tuples always travel in the same call, so each Write stays
+// atomic; when a merged call fails, its writes are retried one by one so a
+// bad tuple in one request cannot fail another's.
+
+type writeOp struct {
+	storeId         string
+	writes, deletes []store.TupleKey
+	done            chan error
+}
+
+func (op *writeOp) size() int { return len(op.writes) + len(op.deletes) }
+
+// WriteStats counts coalesced writes since start.
+type WriteStats struct {
+	Requests  uint64 `json:"requests"`
+	Calls     uint64 `json:"calls"`
+	Tuples    uint64 `json:"tuples"`
+	Fallbacks uint64 `json:"fallbacks"` // merged calls that failed and were retried one by one
+	// Batches counts calls by how many Write requests they carried.
+	Batches map[int]uint64 `json:"batches"`
+}
+
+var (
+	writeMu     sync.Mutex
+	writeQueue  []*writeOp
+	writeWake   = make(chan struct{}, 1)
+	writeOnce   sync.Once
+	writeCounts = WriteStats{Batches: map[int]uint64{}}
+)
+
+// Write writes and deletes tuples in one OpenFGA transaction, batched with
+// concurrent writes to the same store. Writes to a sandbox store go out directly.
+func Write(ctx context.Context, writes []store.TupleKey, deletes []store.TupleKey) error {
+	if diverted {
+		return writeTo(ctx, config.FgaStoreId, writes, deletes)
+	}
+	// The flusher runs outside the request, so count the call here.
+	if err := budget.Count(ctx); err != nil {
+		return err
+	}
+	writeOnce.Do(func() { go flushWrites() })
+	op := &writeOp{storeId: config.FgaStoreId, writes: writes, deletes: deletes, done: make(chan error, 1)}
+	writeMu.Lock()
+	writeQueue = append(writeQueue, op)
+	writeMu.Unlock()
+	select {
+	case writeWake <- struct{}{}:
+	default:
+	}
+	return <-op.done
+}
+
+// flushWrites sends queued writes until the process exits.
+func flushWrites() {
+	for range writeWake {
+		for {
+			batch := nextBatch()
+			if len(batch) == 0 {
+				break
+			}
+			sendBatch(batch)
+		}
+	}
+}
+
+// nextBatch takes the longest run of queued writes that share a store, fit in
+// config.FgaWriteBatchMax tuples and touch no tuple twice. A write larger than
+// the limit goes alone, as it would have without coalescing.
+func nextBatch() []*writeOp {
+	writeMu.Lock()
+	defer writeMu.Unlock()
+	var batch []*writeOp
+	seen := map[store.TupleKey]bool{}
+	size := 0
+	for _, op := range writeQueue {
+		if len(batch) > 0 && (op.storeId != batch[0].storeId || size+op.size() > config.FgaWriteBatchMax.Get() || overlaps(op, seen)) {
+			break
+		}
+		for _, t := range op.writes {
+			seen[t] = true
+		}
+		for _, t := range op.deletes {
+			seen[t] = true
+		}
+		batch = append(batch, op)
+		size += op.size()
+	}
+	writeQueue = writeQueue[len(batch):]
+	return batch
+}
+
+func overlaps(op *writeOp, seen map[store.TupleKey]bool) bool {
+	for _, t := range op.writes {
+		if seen[t] {
+			return true
+		}
+	}
+	for _, t := range op.deletes {
+		if seen[t] {
+			return true
+		}
+	}
+	return false
+}
+
+func sendBatch(batch []*writeOp) {
+	var writes, deletes []store.TupleKey
+	for _, op := range batch {
+		writes = append(writes, op.writes...)
+		deletes = append(deletes, op.deletes...)
+	}
+	err := writeTo(context.Background(), batch[0].storeId, writes, deletes)
+	calls, fallback := uint64(1), false
+	if err != nil && len(batch) > 1 {
+		fallback = true
+		for _, op := range batch {
+			calls++
+			op.done <- writeTo(context.Background(), op.storeId, op.writes, op.deletes)
+		}
+	} else {
+		for _, op := range batch {
+			op.done <- err
+		}
+	}
+	writeMu.Lock()
+	writeCounts.Requests += uint64(len(batch))
+	writeCounts.Calls += calls
+	writeCounts.Tuples += uint64(len(writes) + len(deletes))
+	writeCounts.Batches[len(batch)]++
+	if fallback {
+		writeCounts.Fallbacks++
+	}
+	writeMu.Unlock()
+}
+
+// CoalescedWrites reports how writes have been batched since start.
+func CoalescedWrites() WriteStats {
+	writeMu.Lock()
+	defer writeMu.Unlock()
+	st := writeCounts
+	st.Batches = make(map[int]uint64, len(writeCounts.Batches))
+	for n, c := range writeCounts.Batches {
+		st.Batches[n] = c
+	}
+	return st
+}
@@ -0,0 +1,100 @@
+package fga
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+	"time"
+
+	"test-app/internal/config"
+	"test-app/internal/store"
+)
+
+func TestWriteCoalescing(t *testing.T) {
+	release := make(chan struct{})
+	var mu sync.Mutex
+	var calls []int
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var body struct {
+			Writes struct {
+				TupleKeys []store.TupleKey `json:"tuple_keys"`
+			} `json:"writes"`
+		}
+		json.NewDecoder(r.Body).Decode(&body)
+		mu.Lock()
+		calls = append(calls, len(body.Writes.TupleKeys))
+		first := len(calls) == 1
+		mu.Unlock()
+		if first {
+			<-release
+		}
+		for _, tk := range body.Writes.TupleKeys {
+			if tk.Relation == "bad" {
+				w.WriteHeader(400)
+				json.NewEncoder(w).Encode(map[string]string{"code": "validation_error", "message": "invalid relation"})
+				return
+			}
+		}
+		json.NewEncoder(w).Encode(map[string]interface{}{})
+	}))
+	defer server.Close()
+	origURL := config.OpenfgaURL
+	config.OpenfgaURL = server.URL
+	defer func() { config.OpenfgaURL = origURL }()
+	before := CoalescedWrites()
+
+	write := func(relation, object string) error {
+		return Write(context.Background(), []store.TupleKey{{User: "user:alice", Relation: relation, Object: object}}, nil)
+	}
+	firstDone := make(chan error)
+	go func() { firstDone <- write("viewer", "dossier:d0") }()
+	// Wait for the first call to be in flight, then queue three more behind it.
+	for {
+		mu.Lock()
+		n := len(calls)
+		mu.Unlock()
+		if n == 1 {
+			break
+		}
+		time.Sleep(time.Millisecond)
+	}
+	var wg sync.WaitGroup
+	errs := make([]error, 3)
+	for i, rel := range []string{"viewer", "bad", "editor"} {
+		wg.Add(1)
+		go func(i int, rel string) {
+			defer wg.Done()
+			errs[i] = write(rel, "dossier:d1")
+		}(i, rel)
+	}
+	for {
+		writeMu.Lock()
+		n := len(writeQueue)
+		writeMu.Unlock()
+		if n == 3 {
+			break
+		}
+		time.Sleep(time.Millisecond)
+	}
+	close(release)
+	wg.Wait()
+	if err := <-firstDone; err != nil {
+		t.Fatalf("first write: %v", err)
+	}
+
+	// The three queued writes went out as one call, which failed on the bad
+	// tuple and was retried write by write: only the bad one fails.
+	if errs[0] != nil || errs[1] == nil || errs[2] != nil {
+		t.Errorf("errors = %v, want only the second to fail", errs)
+	}
+	if len(calls) != 5 || calls[1] != 3 {
+		t.Errorf("calls = %v, want [1 3 1 1 1]", calls)
+	}
+	after := CoalescedWrites()
+	if after.Requests-before.Requests != 4 || after.Fallbacks-before.Fallbacks != 1 || after.Batches[3]-before.Batches[3] != 1 {
+		t.Errorf("stats = %+v", after)
+	}
+}
@@ -0,0 +1,112 @@
+package fga
+
+import (
+	"context"
+	"fmt"
+	"sync"
+	"time"
+
+	"test-app/internal/store"
+)
+
+// Decision records the inputs and outcome of a single FGA check so it can be replayed.
+type Decision struct {
+	Id               string           `json:"id"`
+	Time             time.Time        `json:"time"`
+	User             string           `json:"user"`
+	Relation         string           `json:"relation"`
+	Object           string           `json:"object"`
+	ContextualTuples []store.TupleKey `json:"contextualTuples,omitempty"`
+	ModelId          string           `json:"modelId"`
+	Allowed          bool             `json:"allowed"`
+	Error            string           `json:"error,omitempty"`
+}
+
+const decisionLogSize = 1000
+
+var (
+	decisionsMu sync.Mutex
+	decisions   []Decision
+	decisionSeq int
+	// lastAllowed is when each user was last allowed anything on each object,
+	// kept beyond the decision log so grant usage can be judged over days.
+	lastAllowed = map[[2]string]time.Time{}
+	usageSince  = time.Now()
+)
+
+func recordDecision(user, relation, object string, contextualTuples []store.TupleKey, modelId string, allowed bool, err error) {
+	decisionsMu.Lock()
+	defer decisionsMu.Unlock()
+	decisionSeq++
+	d := Decision{
+		Id:   fmt.Sprintf("dec-%d", decisionSeq),
+		Time: time.Now(), User: user, Relation: relation, Object: object,
+		ContextualTuples: append([]store.TupleKey(nil), contextualTuples...),
+		ModelId:          modelId, Allowed: allowed,
+	}
+	if err != nil {
+		d.Error = err.Error()
+	}
+	if allowed {
+		lastAllowed[[2]string{user, object}] = d.Time
+	}
+	decisions = append(decisions, d)
+	if len(decisions) > decisionLogSize {
+		decisions = decisions[len(decisions)-decisionLogSize:]
+	}
+}
+
+// LastAllowed returns when user was last allowed a check on object, and false
+// if that has not happened since UsageSince.
+func LastAllowed(user, object string) (time.Time, bool) {
+	decisionsMu.Lock()
+	defer decisionsMu.Unlock()
+	t, ok := lastAllowed[[2]string{user, object}]
+	return t, ok
+}
+
+// UsageSince is when LastAllowed started observing checks (process start).
+func UsageSince() time.Time {
+	return usageSince
+}
+
+// Decisions returns up to limit recorded decisions, newest first.
+func Decisions(limit int) []Decision {
+	decisionsMu.Lock()
+	defer decisionsMu.Unlock()
+	out := []Decision{}
+	for i := len(decisions) - 1; i >= 0 && len(out) < limit; i-- {
+		out = append(out, decisions[i])
+	}
+	return out
+}
+
+// FindDecision looks a recorded decision up by id.
+func FindDecision(id string) (Decision, bool) {
+	decisionsMu.Lock()
+	defer decisionsMu.Unlock()
+	for _, d := range decisions {
+		if d.Id == id {
+			return d, true
+		}
+	}
+	return Decision{}, false
+}
+
+// Replay re-executes a recorded decision against modelId (the current model
+// when empty) and the current tuples, without recording or auditing the result.
+func Replay(ctx context.Context, d Decision, modelId string) (Decision, error) {
+	if modelId == "" {
+		modelId = d.ModelId
+	}
+	allowed, err := evaluate(ctx, d.User, d.Relation, d.Object, d.ContextualTuples, modelId)
+	replayed := d
+	replayed.Time = time.Now()
+	replayed.ModelId = modelId
+	replayed.Allowed = allowed
+	replayed.Error = ""
+	if err != nil {
+		replayed.Error = err.Error()
+	}
+	return replayed, err
+}
@@ -0,0 +1,52 @@
+package fga
+
+import (
+	"errors"
+	"net/http"
+	"strconv"
+	"time"
+)
+
+// ErrUnavailable matches (with errors.Is) every failure of OpenFGA itself:
+// unreachable, answering 5xx or 429, unreadable, or an injected outage. It
+// tells "the service could not answer" apart from "access denied".
+var ErrUnavailable = errors.New("authorization service unavailable")
+
+// defaultRetryAfter is the retry hint when OpenFGA gives none.
+const defaultRetryAfter = 2 * time.Second
+
+// UnavailableError is an OpenFGA call that failed upstream, with the delay
+// after which retrying makes sense.
+type UnavailableError struct {
+	Err        error
+	RetryAfter time.Duration
+}
+
+func (e *UnavailableError) Error() string {
+	return ErrUnavailable.Error() + ": " + e.Err.Error()
+}
+
+func (e *UnavailableError) Unwrap() error { return e.Err }
+
+func (e *UnavailableError) Is(target error) bool { return target == ErrUnavailable }
+
+// unavailable wraps err, taking the retry hint from resp's Retry-After header
+// (in seconds) when there is one.
+func unavailable(err error, resp *http.Response) *UnavailableError {
+	e := &UnavailableError{Err: err, RetryAfter: defaultRetryAfter}
+	if resp != nil {
+		if secs, convErr := strconv.Atoi(resp.Header.Get("Retry-After")); convErr == nil && secs > 0 {
+			e.RetryAfter = time.Duration(secs) * time.Second
+		}
+	}
+	return e
+}
+
+// RetryAfter returns the retry hint carried by err, if it is an upstream failure.
+func RetryAfter(err error) (time.Duration, bool) {
+	var e *UnavailableError
+	if errors.As(err, &e) {
+		return e.RetryAfter, true
+	}
+	return 0, false
+}
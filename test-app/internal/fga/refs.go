@@ -0,0 +1,51 @@
+package fga
+
+import "strings"
+
+// Object types of the authorization model referenced by the app.
+const (
+	TypeUser         = "user"
+	TypeDossier      = "dossier"
+	TypeOrganization = "organization"
+)
+
+// PublicUser is the wildcard subject that grants a relation to every user.
+const PublicUser = "user:*"
+
+// ObjectRef returns the FGA identifier "<typ>:<id>". An id that could change
+// the meaning of the identifier (containing ':', '#', '*' or whitespace, such
+// as "user:bob" passed as a dossier id) yields "", which OpenFGA rejects, so
+// the call fails closed instead of reaching another object or the wildcard.
+func ObjectRef(typ, id string) string {
+	if typ == "" || id == "" || strings.ContainsAny(typ, ":#* \t\r\n") || strings.ContainsAny(id, ":#* \t\r\n") {
+		return ""
+	}
+	return typ + ":" + id
+}
+
+// UserRef returns the FGA identifier of a username, "user:<name>".
+func UserRef(name string) string {
+	return ObjectRef(TypeUser, name)
+}
+
+// ParseRef splits "<type>:<id>", dropping a "#relation" suffix.
+func ParseRef(ref string) (typ, id string, ok bool) {
+	ref, _, _ = strings.Cut(ref, "#")
+	typ, id, ok = strings.Cut(ref, ":")
+	if !ok || typ == "" || id == "" {
+		return "", "", false
+	}
+	return typ, id, true
+}
+
+// IdsOf returns the ids of the refs of type typ, e.g. from ListObjects
+// results; refs of other types or that do not parse are skipped.
+func IdsOf(refs []string, typ string) []string {
+	ids := make([]string, 0, len(refs))
+	for _, ref := range refs {
+		if t, id, ok := ParseRef(ref); ok && t == typ {
+			ids = append(ids, id)
+		}
+	}
+	return ids
+}
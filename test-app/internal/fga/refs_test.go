@@ -0,0 +1,31 @@
+package fga
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestRefs(t *testing.T) {
+	if got := UserRef("alice"); got != "user:alice" {
+		t.Errorf("UserRef(alice) = %q", got)
+	}
+	if got := ObjectRef(TypeDossier, "ab12cd34"); got != "dossier:ab12cd34" {
+		t.Errorf("ObjectRef = %q", got)
+	}
+	// Ids that would change the identifier's meaning fail closed.
+	for _, id := range []string{"", "*", "user:bob", "org#member", "a b"} {
+		if got := ObjectRef(TypeDossier, id); got != "" {
+			t.Errorf("ObjectRef(dossier, %q) = %q, want empty", id, got)
+		}
+	}
+	if typ, id, ok := ParseRef("organization:acme#member"); !ok || typ != TypeOrganization || id != "acme" {
+		t.Errorf("ParseRef = %q, %q, %v", typ, id, ok)
+	}
+	if _, _, ok := ParseRef("nocolon"); ok {
+		t.Error("ParseRef accepted a ref without a type")
+	}
+	got := IdsOf([]string{"dossier:a", "organization:b", "dossier:c", "bad"}, TypeDossier)
+	if !reflect.DeepEqual(got, []string{"a", "c"}) {
+		t.Errorf("IdsOf = %v", got)
+	}
+}
@@ -0,0 +1,123 @@
+package fga
+
+import (
+	"context"
+	"log"
+	"sync"
+	"time"
+
+	"test-app/internal/config"
+	"test-app/internal/store"
+)
+
+// ShadowConfig names the secondary model (and optionally store) every check is
+// also evaluated against. An empty ModelId disables shadow evaluation; an empty
+// StoreId uses the primary store.
+type ShadowConfig struct {
+	StoreId string `json:"storeId,omitempty"`
+	ModelId string `json:"modelId"`
+}
+
+// Disagreement is a check the shadow model answered differently.
+type Disagreement struct {
+	Time           time.Time `json:"time"`
+	User           string    `json:"user"`
+	Relation       string    `json:"relation"`
+	Object         string    `json:"object"`
+	Primary        bool      `json:"primary"`
+	Shadow         bool      `json:"shadow"`
+	PrimaryModelId string    `json:"primaryModelId"`
+	ShadowModelId  string    `json:"shadowModelId"`
+}
+
+// ShadowReport is the shadow configuration with its counters since it was set.
+type ShadowReport struct {
+	Config        ShadowConfig   `json:"config"`
+	Enabled       bool           `json:"enabled"`
+	Compared      uint64         `json:"compared"`
+	Disagreements uint64         `json:"disagreements"`
+	Errors        uint64         `json:"errors"`
+	Recent        []Disagreement `json:"recent"`
+}
+
+const disagreementLogSize = 200
+
+var (
+	shadowMu     sync.Mutex
+	shadowCfg    ShadowConfig
+	shadowReport ShadowReport
+	shadowLog    []Disagreement
+	// diverted is set while Use points the client at a sandbox store, whose
+	// checks are not shadowed.
+	diverted bool
+)
+
+// SetShadow replaces the shadow configuration and resets its counters.
+func SetShadow(cfg ShadowConfig) {
+	shadowMu.Lock()
+	defer shadowMu.Unlock()
+	shadowCfg = cfg
+	shadowReport = ShadowReport{}
+	shadowLog = nil
+	if cfg.ModelId != "" {
+		log.Printf("Shadow evaluation enabled: store=%s model=%s", cfg.StoreId, cfg.ModelId)
+	}
+}
+
+// Shadow returns the shadow configuration, counters and recent disagreements, newest first.
+func Shadow() ShadowReport {
+	shadowMu.Lock()
+	defer shadowMu.Unlock()
+	rep := shadowReport
+	rep.Config = shadowCfg
+	rep.Enabled = shadowCfg.ModelId != ""
+	rep.Recent = make([]Disagreement, 0, len(shadowLog))
+	for i := len(shadowLog) - 1; i >= 0; i-- {
+		rep.Recent = append(rep.Recent, shadowLog[i])
+	}
+	return rep
+}
+
+// shadowCheck evaluates a successful primary check against the shadow model in
+// the background and records a disagreement when the answers differ. The
+// response never waits for it, and shadow errors are only counted.
+func shadowCheck(user, relation, object string, contextualTuples []store.TupleKey, primary bool) {
+	shadowMu.Lock()
+	cfg := shadowCfg
+	shadowMu.Unlock()
+	if cfg.ModelId == "" || diverted {
+		return
+	}
+	// Read the primary store now: a sandbox request may swap it before the goroutine runs.
+	storeId := cfg.StoreId
+	if storeId == "" {
+		storeId = config.FgaStoreId
+	}
+	primaryModel := config.FgaModelId
+	contextualTuples = append([]store.TupleKey(nil), contextualTuples...)
+	go func() {
+		allowed, err := evaluateIn(context.Background(), storeId, user, relation, object, contextualTuples, cfg.ModelId)
+		shadowMu.Lock()
+		defer shadowMu.Unlock()
+		if shadowCfg != cfg {
+			return
+		}
+		if err != nil {
+			shadowReport.Errors++
+			return
+		}
+		shadowReport.Compared++
+		if allowed == primary {
+			return
+		}
+		shadowReport.Disagreements++
+		shadowLog = append(shadowLog, Disagreement{
+			Time: time.Now(), User: user, Relation: relation, Object: object,
+			Primary: primary, Shadow: allowed, PrimaryModelId: primaryModel, ShadowModelId: cfg.ModelId,
+		})
+		if len(shadowLog) > disagreementLogSize {
+			shadowLog = shadowLog[len(shadowLog)-disagreementLogSize:]
+		}
+		log.Printf("SHADOW: %s %s %s: primary=%v shadow=%v", user, relation, object, primary, allowed)
+	}()
+}
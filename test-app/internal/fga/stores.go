@@ -0,0 +1,99 @@
+package fga
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+
+	"test-app/internal/config"
+	"test-app/internal/endpoints"
+)
+
+// CopyStore creates a new OpenFGA store named name holding the current
+// authorization model and every current tuple, and returns its ids. Tuples are
+// copied directly rather than through Write, so the copy is not audited.
+func CopyStore(name string) (storeId, modelId string, err error) {
+	ctx := context.Background()
+	result, err := Request(ctx, "POST", "/stores", map[string]interface{}{"name": name})
+	if err != nil {
+		return "", "", err
+	}
+	storeId, _ = result["id"].(string)
+	if storeId == "" {
+		return "", "", fmt.Errorf("store was not created: %v", result["message"])
+	}
+	fail := func(err error) (string, string, error) {
+		DeleteStore(storeId)
+		return "", "", err
+	}
+
+	result, err = Request(ctx, "GET", "/stores/"+config.FgaStoreId+"/authorization-models/"+config.FgaModelId, nil)
+	if err != nil {
+		return fail(err)
+	}
+	model, _ := result["authorization_model"].(map[string]interface{})
+	if model == nil {
+		return fail(fmt.Errorf("authorization model %s not found", config.FgaModelId))
+	}
+	delete(model, "id")
+	result, err = Request(ctx, "POST", "/stores/"+storeId+"/authorization-models", model)
+	if err != nil {
+		return fail(err)
+	}
+	modelId, _ = result["authorization_model_id"].(string)
+	if modelId == "" {
+		return fail(fmt.Errorf("authorization model was not accepted: %v", result["message"]))
+	}
+
+	tuples, err := ReadAll(ctx)
+	if err != nil {
+		return fail(err)
+	}
+	for len(tuples) > 0 {
+		n := min(len(tuples), 100)
+		body := map[string]interface{}{"writes": map[string]interface{}{"tuple_keys": tuples[:n]}}
+		result, err := Request(ctx, "POST", "/stores/"+storeId+"/write", body)
+		if err != nil {
+			return fail(err)
+		}
+		if msg, ok := result["message"].(string); ok {
+			return fail(fmt.Errorf("copying tuples: %s", msg))
+		}
+		tuples = tuples[n:]
+	}
+	return storeId, modelId, nil
+}
+
+// DeleteStore removes an OpenFGA store, e.g. one made by CopyStore. OpenFGA
+// answers with an empty 204, so this bypasses Request's JSON decoding.
+func DeleteStore(storeId string) error {
+	resp, err := endpoints.Do(config.OpenfgaURL, func(base string) (*http.Request, error) {
+		req, err := http.NewRequest("DELETE", base+"/stores/"+storeId, nil)
+		if err != nil {
+			return nil, err
+		}
+		if token := config.Secret(config.OpenfgaAPIToken); token != "" {
+			req.Header.Set("Authorization", "Bearer "+token)
+		}
+		return req, nil
+	})
+	if err != nil {
+		return err
+	}
+	resp.Body.Close()
+	if resp.StatusCode >= 300 && resp.StatusCode != http.StatusNotFound {
+		return fmt.Errorf("deleting store %s: %s", storeId, resp.Status)
+	}
+	return nil
+}
+
+// Use points the client at another store and model until the returned
+// function is called. Callers must serialise it against other FGA use, as
+// sandbox.Route does.
+func Use(storeId, modelId string) (restore func()) {
+	prevStore, prevModel, prevDiverted := config.FgaStoreId, config.FgaModelId, diverted
+	config.FgaStoreId, config.FgaModelId, diverted = storeId, modelId, true
+	return func() {
+		config.FgaStoreId, config.FgaModelId, diverted = prevStore, prevModel, prevDiverted
+	}
+}
@@ -0,0 +1,127 @@
+package fga
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"strconv"
+	"strings"
+
+	"test-app/internal/store"
+)
+
+// Tuple files use the layout accepted by `fga tuple write --file` and produced by
+// `fga tuple read --output-format=simple-json`: a flat list of user/relation/object
+// entries, either as a JSON array or as a YAML sequence of mappings.
+
+// EncodeTuples renders tuples as a JSON or YAML tuple file.
+func EncodeTuples(tuples []store.TupleKey, format string) ([]byte, error) {
+	if tuples == nil {
+		tuples = []store.TupleKey{}
+	}
+	switch format {
+	case "", "json":
+		return json.MarshalIndent(tuples, "", "  ")
+	case "yaml":
+		var b bytes.Buffer
+		for _, t := range tuples {
+			fmt.Fprintf(&b, "- user: %s\n  relation: %s\n  object: %s\n",
+				yamlScalar(t.User), yamlScalar(t.Relation), yamlScalar(t.Object))
+		}
+		return b.Bytes(), nil
+	}
+	return nil, fmt.Errorf("unsupported format %q", format)
+}
+
+// DecodeTuples parses a JSON or YAML tuple file.
+func DecodeTuples(data []byte, format string) ([]store.TupleKey, error) {
+	switch format {
+	case "", "json":
+		var tuples []store.TupleKey
+		if err := json.Unmarshal(data, &tuples); err != nil {
+			return nil, err
+		}
+		return tuples, validateTuples(tuples)
+	case "yaml":
+		return decodeTuplesYAML(data)
+	}
+	return nil, fmt.Errorf("unsupported format %q", format)
+}
+
+// decodeTuplesYAML understands the subset of YAML used by tuple files: a
+// top-level sequence of flat mappings with scalar values.
+func decodeTuplesYAML(data []byte) ([]store.TupleKey, error) {
+	var tuples []store.TupleKey
+	var cur *store.TupleKey
+	scanner := bufio.NewScanner(bytes.NewReader(data))
+	line := 0
+	for scanner.Scan() {
+		line++
+		text := strings.TrimRight(scanner.Text(), " \t\r")
+		trimmed := strings.TrimSpace(text)
+		if trimmed == "" || strings.HasPrefix(trimmed, "#") || trimmed == "---" {
+			continue
+		}
+		if strings.HasPrefix(trimmed, "- ") || trimmed == "-" {
+			tuples = append(tuples, store.TupleKey{})
+			cur = &tuples[len(tuples)-1]
+			trimmed = strings.TrimSpace(strings.TrimPrefix(trimmed, "-"))
+			if trimmed == "" {
+				continue
+			}
+		}
+		if cur == nil {
+			return nil, fmt.Errorf("line %d: expected a list entry", line)
+		}
+		key, value, ok := strings.Cut(trimmed, ":")
+		if !ok {
+			return nil, fmt.Errorf("line %d: expected key: value", line)
+		}
+		value, err := yamlUnquote(strings.TrimSpace(value))
+		if err != nil {
+			return nil, fmt.Errorf("line %d: %v", line, err)
+		}
+		switch strings.TrimSpace(key) {
+		case "user":
+			cur.User = value
+		case "relation":
+			cur.Relation = value
+		case "object":
+			cur.Object = value
+		default:
+			return nil, fmt.Errorf("line %d: unsupported key %q", line, key)
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, err
+	}
+	return tuples, validateTuples(tuples)
+}
+
+func validateTuples(tuples []store.TupleKey) error {
+	for i, t := range tuples {
+		if t.User == "" || t.Relation == "" || t.Object == "" {
+			return fmt.Errorf("tuple %d: user, relation and object are required", i+1)
+		}
+	}
+	return nil
+}
+
+// yamlScalar quotes values that plain YAML scalars cannot represent as-is.
+func yamlScalar(s string) string {
+	if s == "" || strings.ContainsAny(s, "#'\"\n") || strings.Contains(s, ": ") || strings.HasPrefix(s, "-") || strings.HasPrefix(s, " ") {
+		return strconv.Quote(s)
+	}
+	return s
+}
+
+func yamlUnquote(s string) (string, error) {
+	if strings.HasPrefix(s, `"`) {
+		return strconv.Unquote(s)
+	}
+	if strings.HasPrefix(s, "'") && strings.HasSuffix(s, "'") && len(s) >= 2 {
+		return strings.ReplaceAll(s[1:len(s)-1], "''", "'"), nil
+	}
+	return s, nil
+}
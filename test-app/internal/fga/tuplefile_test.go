@@ -0,0 +1,56 @@
+package fga
+
+import (
+	"reflect"
+	"testing"
+
+	"test-app/internal/store"
+)
+
+func TestTupleFile_RoundTrip(t *testing.T) {
+	tuples := []store.TupleKey{
+		{User: "user:alice", Relation: "owner", Object: "dossier:d1"},
+		{User: "user:*", Relation: "viewer", Object: "dossier:d2"},
+		{User: "organization:acme#member", Relation: "can_view", Object: "dossier:d3"},
+	}
+	for _, format := range []string{"json", "yaml"} {
+		data, err := EncodeTuples(tuples, format)
+		if err != nil {
+			t.Fatalf("%s encode: %v", format, err)
+		}
+		got, err := DecodeTuples(data, format)
+		if err != nil {
+			t.Fatalf("%s decode: %v", format, err)
+		}
+		if !reflect.DeepEqual(got, tuples) {
+			t.Errorf("%s round trip = %+v, want %+v", format, got, tuples)
+		}
+	}
+}
+
+func TestDecodeTuples_CLIYaml(t *testing.T) {
+	data := []byte(`# exported with the fga CLI
+- user: user:anne
+  relation: owner
+  object: "dossier:1"
+-
+  user: 'user:bob'
+  relation: viewer
+  object: dossier:1
+`)
+	got, err := DecodeTuples(data, "yaml")
+	if err != nil {
+		t.Fatalf("decode: %v", err)
+	}
+	want := []store.TupleKey{
+		{User: "user:anne", Relation: "owner", Object: "dossier:1"},
+		{User: "user:bob", Relation: "viewer", Object: "dossier:1"},
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("got %+v, want %+v", got, want)
+	}
+
+	if _, err := DecodeTuples([]byte("- user: user:anne\n  relation: owner\n"), "yaml"); err == nil {
+		t.Error("expected error for tuple without object")
+	}
+}
@@ -0,0 +1,200 @@
+package handlers
+
+import (
+	"errors"
+	"net/http"
+
+	"test-app/internal/audit"
+	"test-app/internal/config"
+	"test-app/internal/events"
+	"test-app/internal/fga"
+	"test-app/internal/httputil"
+	"test-app/internal/i18n"
+	"test-app/internal/store"
+)
+
+// DossiersAccessRequest records the caller's request for a mandate on a
+// dossier they know the id of. Owners approve or deny it through
+// DossiersAccessDecide.
+func DossiersAccessRequest(w http.ResponseWriter, r *http.Request, id string) {
+	if !config.FgaReady {
+		httputil.JSONError(w, i18n.T(r, "OpenFGA not ready"), 503)
+		return
+	}
+	user := httputil.GetUser(r)
+	message := ""
+	if body, err := httputil.ReadBody(r); err == nil {
+		message = httputil.GetString(body, "message")
+	}
+
+	store.Mu.RLock()
+	dossier, ok := store.Data.Dossiers[id]
+	var owners []string
+	if ok {
+		owners = append(owners, dossier.Owners...)
+	}
+	store.Mu.RUnlock()
+	// In information-hiding mode every request to a dossier the caller cannot
+	// view gets the same acknowledgement, recorded only when the dossier exists.
+	hidden := config.HideExistence.Get()
+	if !ok {
+		if hidden {
+			httputil.JSONResponse(w, map[string]interface{}{"success": true}, 202)
+			return
+		}
+		httputil.JSONError(w, i18n.T(r, "Dossier not found"), 404)
+		return
+	}
+	viewer, err := fga.Allowed(r.Context(), fga.UserRef(user), "viewer", fga.ObjectRef(fga.TypeDossier, id))
+	if errors.Is(err, fga.ErrUnavailable) {
+		fgaUnavailable(w, r, err)
+		return
+	}
+	if viewer {
+		httputil.JSONError(w, i18n.T(r, "You already have access to this dossier"), 400)
+		return
+	}
+
+	store.Mu.Lock()
+	for _, req := range store.Data.AccessRequests {
+		if req.DossierId == id && req.User == user && req.Status == "pending" {
+			store.Mu.Unlock()
+			if hidden {
+				httputil.JSONResponse(w, map[string]interface{}{"success": true}, 202)
+				return
+			}
+			httputil.JSONError(w, i18n.T(r, "Request already pending"), 400)
+			return
+		}
+	}
+	req := store.AccessRequest{Id: store.RandId(), DossierId: id, User: user, Message: message, Status: "pending", Meta: store.NewMeta(user)}
+	store.Data.AccessRequests = append(store.Data.AccessRequests, req)
+	store.Mu.Unlock()
+	store.Save()
+
+	audit.SendAuditLog("test-app", "requested", fga.UserRef(user), "mandate_holder", fga.ObjectRef(fga.TypeDossier, id), "POST", "Access request: "+message)
+	events.Publish(events.Event{
+		Type: events.DossierAccessRequested, Actor: user, Object: fga.ObjectRef(fga.TypeDossier, id),
+		Recipients: owners, Data: map[string]string{"requestId": req.Id},
+	})
+	if hidden {
+		httputil.JSONResponse(w, map[string]interface{}{"success": true}, 202)
+		return
+	}
+	httputil.JSONResponse(w, req, 200)
+}
+
+// DossiersAccessRequests lists the pending access requests of a dossier for its owners.
+func DossiersAccessRequests(w http.ResponseWriter, r *http.Request, id string) {
+	if accessDecisionDenied(w, r, id) {
+		return
+	}
+	pending := []store.AccessRequest{}
+	users := []string{}
+	store.Mu.RLock()
+	for _, req := range store.Data.AccessRequests {
+		if req.DossierId == id && req.Status == "pending" {
+			pending = append(pending, req)
+			users = append(users, req.User)
+		}
+	}
+	store.Mu.RUnlock()
+	listResponse(w, r, "requests", pending, listMeta{Users: users})
+}
+
+// DossiersAccessDecide approves or denies a pending access request. Approval
+// writes the mandate tuple before the request is marked approved; the owner's
+// decision stands in for the guardianship a direct grant would need.
+func DossiersAccessDecide(w http.ResponseWriter, r *http.Request, id, reqId string, approve bool) {
+	if !config.FgaReady {
+		httputil.JSONError(w, i18n.T(r, "OpenFGA not ready"), 503)
+		return
+	}
+	if accessDecisionDenied(w, r, id) {
+		return
+	}
+	owner := httputil.GetUser(r)
+
+	store.Mu.Lock()
+	var req *store.AccessRequest
+	for i := range store.Data.AccessRequests {
+		if store.Data.AccessRequests[i].Id == reqId && store.Data.AccessRequests[i].DossierId == id {
+			req = &store.Data.AccessRequests[i]
+		}
+	}
+	dossier, dossierOk := store.Data.Dossiers[id]
+	if req == nil || !dossierOk {
+		store.Mu.Unlock()
+		httputil.JSONError(w, i18n.T(r, "Request not found"), 404)
+		return
+	}
+	if req.Status != "pending" {
+		store.Mu.Unlock()
+		httputil.JSONError(w, i18n.T(r, "Request already handled"), 400)
+		return
+	}
+	user := req.User
+	hasMandate := false
+	for _, rel := range dossier.Relations {
+		if rel.User == user && store.IsMandate(rel.Relation) {
+			hasMandate = true
+		}
+	}
+	store.Mu.Unlock()
+
+	status, eventType := "denied", events.DossierAccessDenied
+	if approve {
+		status, eventType = "approved", events.DossierAccessApproved
+		if !hasMandate {
+			if err := fga.Write(r.Context(), []store.TupleKey{{User: fga.UserRef(user), Relation: "mandate_holder", Object: fga.ObjectRef(fga.TypeDossier, id)}}, nil); err != nil {
+				fgaFailed(w, r, err)
+				return
+			}
+		}
+	}
+
+	store.Mu.Lock()
+	if approve && !hasMandate {
+		dossier.Relations = append(dossier.Relations, store.Relation{User: user, Relation: "mandate_holder", GrantedBy: owner})
+		dossier.Updated()
+	}
+	req.Status = status
+	req.DecidedBy = owner
+	req.Updated()
+	decided := *req
+	store.Mu.Unlock()
+	store.Save()
+
+	audit.SendAuditLog("test-app", status, fga.UserRef(user), "mandate_holder", fga.ObjectRef(fga.TypeDossier, id), "POST", "Access request "+status+" by "+owner)
+	events.Publish(events.Event{
+		Type: eventType, Actor: owner, Object: fga.ObjectRef(fga.TypeDossier, id),
+		Recipients: []string{user}, Data: map[string]string{"requestId": reqId},
+	})
+	httputil.JSONResponse(w, decided, 200)
+}
+
+// accessDecisionDenied reports whether the caller may not handle the dossier's
+// access requests, having answered why: only its owners (and the manager
+// admin) may, and an OpenFGA outage is a 503 rather than a denial.
+func accessDecisionDenied(w http.ResponseWriter, r *http.Request, id string) bool {
+	if isManagerAdminDossiers(r) {
+		return false
+	}
+	if !config.FgaReady {
+		httputil.JSONError(w, i18n.T(r, "Only owners can handle access requests"), 403)
+		return true
+	}
+	return checkDenied(w, r, "owner", fga.ObjectRef(fga.TypeDossier, id), "Only owners can handle access requests")
+}
+
+// dropAccessRequests removes the access requests of a deleted dossier. The
+// caller holds store.Mu.
+func dropAccessRequests(id string) {
+	var kept []store.AccessRequest
+	for _, req := range store.Data.AccessRequests {
+		if req.DossierId != id {
+			kept = append(kept, req)
+		}
+	}
+	store.Data.AccessRequests = kept
+}
@@ -0,0 +1,769 @@
+package handlers
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"log"
+	"net/http"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"test-app/internal/analytics"
+	"test-app/internal/audit"
+	"test-app/internal/budget"
+	"test-app/internal/config"
+	"test-app/internal/events"
+	"test-app/internal/faults"
+	"test-app/internal/fga"
+	"test-app/internal/httputil"
+	"test-app/internal/i18n"
+	"test-app/internal/privacy"
+	"test-app/internal/profiles"
+	"test-app/internal/recent"
+	"test-app/internal/resources"
+	"test-app/internal/sandbox"
+	"test-app/internal/store"
+	"test-app/internal/users"
+	"test-app/internal/visibility"
+)
+
+// collectStats gathers aggregate counts for the admin dashboards.
+func collectStats(ctx context.Context) map[string]interface{} {
+	byType := map[string]int{}
+	mandates := 0
+	publicCount := 0
+	blockedCount := 0
+	pending := 0
+	guardianships := 0
+
+	store.Mu.RLock()
+	for _, d := range store.Data.Dossiers {
+		byType[d.Type]++
+		for _, rel := range d.Relations {
+			if store.IsMandate(rel.Relation) {
+				mandates++
+			}
+		}
+		if d.Public {
+			publicCount++
+		}
+		blockedCount += len(d.BlockedUsers)
+	}
+	for _, req := range store.Data.GuardianshipRequests {
+		if req.Status == "pending" {
+			pending++
+		}
+	}
+	for _, guardians := range store.Data.Guardianships {
+		guardianships += len(guardians)
+	}
+	dossierCount := len(store.Data.Dossiers)
+	orgCount := len(store.Data.Organizations)
+	store.Mu.RUnlock()
+
+	tuples := map[string]interface{}{"local": len(store.DesiredTuples())}
+	if config.FgaReady {
+		if remote, err := fga.ReadAll(ctx); err != nil {
+			tuples["fgaError"] = err.Error()
+		} else {
+			tuples["fga"] = len(remote)
+		}
+	}
+
+	return map[string]interface{}{
+		"dossiers":                dossierCount,
+		"dossiersByType":          byType,
+		"publicDossiers":          publicCount,
+		"blockedUsers":            blockedCount,
+		"organizations":           orgCount,
+		"activeMandates":          mandates,
+		"guardianships":           guardianships,
+		"pendingGuardianshipReqs": pending,
+		"tuples":                  tuples,
+		"audit":                   audit.Stats(),
+		"visibilityIndex":         visibility.Status(),
+		"faults":                  faults.Active(),
+		"fgaCalls":                map[string]interface{}{"budget": config.FgaCallBudget.Get(), "mode": config.FgaBudgetMode.Get(), "routes": budget.Stats()},
+		"fgaWrites":               fga.CoalescedWrites(),
+		"listCache":               fga.ListCacheStats(),
+		"store":                   store.Size(config.StoreSizeWarnBytes),
+		"uptime":                  time.Since(config.StartTime).String(),
+	}
+}
+
+// AdminStats returns aggregate counts for the AI Manager dashboards (for admin use).
+// Responds in Prometheus text exposition format when ?format=prometheus is set.
+func AdminStats(w http.ResponseWriter, r *http.Request) {
+	if !isManagerAdmin(r) {
+		httputil.JSONError(w, i18n.T(r, "Admin access required"), 403)
+		return
+	}
+	stats := collectStats(r.Context())
+	if r.URL.Query().Get("format") == "prometheus" {
+		w.Header().Set("Content-Type", "text/plain; version=0.0.4")
+		w.Write([]byte(prometheusStats(stats)))
+		return
+	}
+	httputil.JSONResponse(w, stats, 200)
+}
+
+// prometheusStats renders the numeric stats as Prometheus gauges.
+func prometheusStats(stats map[string]interface{}) string {
+	var b strings.Builder
+	gauge := func(name string, value interface{}, labels string) {
+		fmt.Fprintf(&b, "testapp_%s%s %v\n", name, labels, value)
+	}
+	gauge("dossiers", stats["dossiers"], "")
+	byType := stats["dossiersByType"].(map[string]int)
+	types := make([]string, 0, len(byType))
+	for t := range byType {
+		types = append(types, t)
+	}
+	sort.Strings(types)
+	for _, t := range types {
+		gauge("dossiers_by_type", byType[t], fmt.Sprintf("{type=%q}", t))
+	}
+	gauge("public_dossiers", stats["publicDossiers"], "")
+	gauge("blocked_users", stats["blockedUsers"], "")
+	gauge("organizations", stats["organizations"], "")
+	gauge("active_mandates", stats["activeMandates"], "")
+	gauge("guardianships", stats["guardianships"], "")
+	gauge("pending_guardianship_requests", stats["pendingGuardianshipReqs"], "")
+	tuples := stats["tuples"].(map[string]interface{})
+	gauge("tuples", tuples["local"], `{source="local"}`)
+	if n, ok := tuples["fga"]; ok {
+		gauge("tuples", n, `{source="fga"}`)
+	}
+	auditStats := stats["audit"].(audit.QueueStats)
+	gauge("audit_pending", auditStats.Pending, "")
+	gauge("audit_sent", auditStats.Sent, "")
+	gauge("audit_failed", auditStats.Failed, "")
+	routes := stats["fgaCalls"].(map[string]interface{})["routes"].(map[string]budget.RouteStats)
+	names := make([]string, 0, len(routes))
+	for route := range routes {
+		names = append(names, route)
+	}
+	sort.Strings(names)
+	for _, route := range names {
+		gauge("fga_calls", routes[route].Calls, fmt.Sprintf("{route=%q}", route))
+		gauge("fga_calls_max", routes[route].Max, fmt.Sprintf("{route=%q}", route))
+		gauge("fga_over_budget", routes[route].OverBudget, fmt.Sprintf("{route=%q}", route))
+	}
+	writes := stats["fgaWrites"].(fga.WriteStats)
+	gauge("fga_write_requests", writes.Requests, "")
+	gauge("fga_write_calls", writes.Calls, "")
+	gauge("fga_write_fallbacks", writes.Fallbacks, "")
+	sizes := make([]int, 0, len(writes.Batches))
+	for n := range writes.Batches {
+		sizes = append(sizes, n)
+	}
+	sort.Ints(sizes)
+	for _, n := range sizes {
+		gauge("fga_write_batches", writes.Batches[n], fmt.Sprintf("{requests=\"%d\"}", n))
+	}
+	cache := stats["listCache"].(fga.CacheStats)
+	gauge("list_cache_entries", cache.Entries, "")
+	gauge("list_cache_hits", cache.Hits, "")
+	gauge("list_cache_negative_hits", cache.NegativeHits, "")
+	gauge("list_cache_misses", cache.Misses, "")
+	gauge("list_cache_hit_rate", cache.HitRate, "")
+	size := stats["store"].(store.SizeReport)
+	gauge("store_data_bytes", size.DataBytes, "")
+	gauge("store_archive_bytes", size.ArchiveBytes, "")
+	kinds := make([]string, 0, len(size.Entities))
+	for kind := range size.Entities {
+		kinds = append(kinds, kind)
+	}
+	sort.Strings(kinds)
+	for _, kind := range kinds {
+		gauge("store_entities", size.Entities[kind], fmt.Sprintf("{kind=%q}", kind))
+	}
+	if index := stats["visibilityIndex"].(visibility.Stats); index.Enabled {
+		gauge("visibility_index_users", index.Users, "")
+		gauge("visibility_index_queued", index.Queued, "")
+		gauge("visibility_index_watermark", index.Watermark, "")
+	}
+	return b.String()
+}
+
+// AdminDecisions lists the most recent recorded FGA check decisions (for admin use).
+func AdminDecisions(w http.ResponseWriter, r *http.Request) {
+	if !isManagerAdmin(r) {
+		httputil.JSONError(w, i18n.T(r, "Admin access required"), 403)
+		return
+	}
+	limit := 100
+	if n, err := strconv.Atoi(r.URL.Query().Get("limit")); err == nil && n > 0 {
+		limit = n
+	}
+	httputil.JSONResponse(w, fga.Decisions(limit), 200)
+}
+
+// AdminReplay re-executes a recorded decision against the current model and
+// tuples and reports whether the outcome changed. An optional modelId in the
+// body replays against a different authorization model instead.
+func AdminReplay(w http.ResponseWriter, r *http.Request, id string) {
+	if !isManagerAdmin(r) {
+		httputil.JSONError(w, i18n.T(r, "Admin access required"), 403)
+		return
+	}
+	original, ok := fga.FindDecision(id)
+	if !ok {
+		httputil.JSONError(w, i18n.T(r, "Decision not found"), 404)
+		return
+	}
+	var body struct {
+		ModelId string `json:"modelId"`
+	}
+	json.NewDecoder(r.Body).Decode(&body)
+	modelId := body.ModelId
+	if modelId == "" {
+		modelId = config.FgaModelId
+	}
+
+	replayed, err := fga.Replay(r.Context(), original, modelId)
+	if err != nil {
+		httputil.JSONError(w, i18n.T(r, "Replay failed: %s", err.Error()), 502)
+		return
+	}
+	httputil.JSONResponse(w, map[string]interface{}{
+		"original": original,
+		"replayed": replayed,
+		"changed":  original.Allowed != replayed.Allowed,
+	}, 200)
+}
+
+// modelFlip describes a recorded check whose outcome differs under a candidate model.
+type modelFlip struct {
+	DecisionId string `json:"decisionId"`
+	User       string `json:"user"`
+	Relation   string `json:"relation"`
+	Object     string `json:"object"`
+}
+
+// AdminModelDiff writes a candidate authorization model as a new version, replays
+// a sample of recorded checks against the current and candidate models and
+// reports which outcomes flip. The candidate only becomes active when apply is set.
+func AdminModelDiff(w http.ResponseWriter, r *http.Request) {
+	if !isManagerAdmin(r) {
+		httputil.JSONError(w, i18n.T(r, "Admin access required"), 403)
+		return
+	}
+	if !config.FgaReady {
+		httputil.JSONError(w, i18n.T(r, "OpenFGA not ready"), 503)
+		return
+	}
+	var body struct {
+		Model  map[string]interface{} `json:"model"`
+		Sample int                    `json:"sample"`
+		Apply  bool                   `json:"apply"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&body); err != nil || body.Model == nil {
+		httputil.JSONError(w, i18n.T(r, "model is required"), 400)
+		return
+	}
+	if body.Sample <= 0 {
+		body.Sample = 100
+	}
+
+	candidateId, err := fga.WriteModel(r.Context(), body.Model)
+	if err != nil {
+		httputil.JSONError(w, i18n.T(r, "Failed to write model: %s", err.Error()), 400)
+		return
+	}
+	currentId := config.FgaModelId
+
+	newlyAllowed := []modelFlip{}
+	newlyDenied := []modelFlip{}
+	replayed, failed := 0, 0
+	for _, d := range fga.Decisions(body.Sample) {
+		before, err := fga.Replay(r.Context(), d, currentId)
+		if err != nil {
+			failed++
+			continue
+		}
+		after, err := fga.Replay(r.Context(), d, candidateId)
+		if err != nil {
+			failed++
+			continue
+		}
+		replayed++
+		flip := modelFlip{DecisionId: d.Id, User: d.User, Relation: d.Relation, Object: d.Object}
+		if !before.Allowed && after.Allowed {
+			newlyAllowed = append(newlyAllowed, flip)
+		} else if before.Allowed && !after.Allowed {
+			newlyDenied = append(newlyDenied, flip)
+		}
+	}
+
+	if body.Apply {
+		switched := false
+		sandbox.Exclusive(r, func() {
+			// Another switch may have landed while the checks were replayed.
+			if config.FgaModelId == currentId {
+				config.FgaModelId = candidateId
+				switched = true
+			}
+		})
+		if !switched {
+			httputil.JSONError(w, i18n.T(r, "The authorization model changed during the diff; run it again"), 409)
+			return
+		}
+		log.Printf("Switched authorization model: %s -> %s", currentId, candidateId)
+	}
+
+	httputil.JSONResponse(w, map[string]interface{}{
+		"currentModelId":   currentId,
+		"candidateModelId": candidateId,
+		"replayed":         replayed,
+		"failed":           failed,
+		"newlyAllowed":     newlyAllowed,
+		"newlyDenied":      newlyDenied,
+		"applied":          body.Apply,
+	}, 200)
+}
+
+// AdminFgaConfig switches the OpenFGA store and model at runtime after checking
+// both exist, then reconciles the new store with persisted data.
+func AdminFgaConfig(w http.ResponseWriter, r *http.Request) {
+	if !isManagerAdmin(r) {
+		httputil.JSONError(w, i18n.T(r, "Admin access required"), 403)
+		return
+	}
+	var body struct {
+		StoreId string `json:"storeId"`
+		ModelId string `json:"modelId"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&body); err != nil || body.StoreId == "" || body.ModelId == "" {
+		httputil.JSONError(w, i18n.T(r, "storeId and modelId are required"), 400)
+		return
+	}
+	if err := fga.ProbeConfig(r.Context(), body.StoreId, body.ModelId); err != nil {
+		httputil.JSONError(w, i18n.T(r, "Invalid OpenFGA config: %s", err.Error()), 400)
+		return
+	}
+
+	var previous map[string]string
+	// Requests and jobs read the ids without locking; none runs during the switch.
+	sandbox.Exclusive(r, func() {
+		previous = map[string]string{"storeId": config.FgaStoreId, "modelId": config.FgaModelId}
+		config.FgaStoreId = body.StoreId
+		config.FgaModelId = body.ModelId
+		config.FgaReady = true
+	})
+	log.Printf("Switched OpenFGA config: store=%s model=%s", body.StoreId, body.ModelId)
+	fga.Rehydrate()
+
+	httputil.JSONResponse(w, map[string]interface{}{
+		"previous":  previous,
+		"storeId":   body.StoreId,
+		"modelId":   body.ModelId,
+		"rehydrate": config.RehydrateMode,
+	}, 200)
+}
+
+// AdminAnalytics reports which demo scenarios participants have exercised (for admin use).
+// DELETE clears the counters before a new walkthrough.
+func AdminAnalytics(w http.ResponseWriter, r *http.Request) {
+	if !isManagerAdmin(r) {
+		httputil.JSONError(w, i18n.T(r, "Admin access required"), 403)
+		return
+	}
+	if r.Method == "DELETE" {
+		analytics.Reset()
+		httputil.JSONResponse(w, map[string]bool{"success": true}, 200)
+		return
+	}
+	httputil.JSONResponse(w, analytics.Snapshot(), 200)
+}
+
+// writeInBatches applies writes then deletes to OpenFGA in batches of 10. When a
+// batch fails, the batches already applied are reverted on a best-effort basis.
+func writeInBatches(ctx context.Context, writes, deletes []store.TupleKey) error {
+	type batch struct{ writes, deletes []store.TupleKey }
+	var batches []batch
+	for i := 0; i < len(writes); i += 10 {
+		batches = append(batches, batch{writes: writes[i:min(i+10, len(writes))]})
+	}
+	for i := 0; i < len(deletes); i += 10 {
+		batches = append(batches, batch{deletes: deletes[i:min(i+10, len(deletes))]})
+	}
+	for i, b := range batches {
+		if err := fga.Write(ctx, b.writes, b.deletes); err != nil {
+			for j := i - 1; j >= 0; j-- {
+				fga.Write(ctx, batches[j].deletes, batches[j].writes)
+			}
+			return err
+		}
+	}
+	return nil
+}
+
+// AdminEraseUser removes a user from the data store and the authorization graph
+// (for admin use). Owned dossiers go to ?reassignTo= when set, otherwise they are
+// deleted. Any remaining OpenFGA tuple naming the user is revoked as well.
+// Other requests wait while the user is erased; when OpenFGA cannot be read
+// or written, nothing is erased.
+func AdminEraseUser(w http.ResponseWriter, r *http.Request, userId string) {
+	if !isManagerAdmin(r) {
+		httputil.JSONError(w, i18n.T(r, "Admin access required"), 403)
+		return
+	}
+	if !config.FgaReady {
+		httputil.JSONError(w, i18n.T(r, "OpenFGA not ready"), 503)
+		return
+	}
+	reassignTo := r.URL.Query().Get("reassignTo")
+	if !users.Valid(userId) || (reassignTo != "" && !users.Valid(reassignTo)) {
+		httputil.JSONError(w, i18n.T(r, "Invalid username"), 400)
+		return
+	}
+	if reassignTo == userId {
+		httputil.JSONError(w, i18n.T(r, "Cannot reassign dossiers to the erased user"), 400)
+		return
+	}
+
+	// No other request or job may change the data while it is erased, so a
+	// failed OpenFGA write can put the previous data back without losing theirs.
+	var (
+		report          store.ErasureReport
+		writes, deletes []store.TupleKey
+		err             error
+	)
+	sandbox.Exclusive(r, func() {
+		var actual []store.TupleKey
+		if actual, err = fga.ReadAll(r.Context()); err != nil {
+			return
+		}
+		backup := store.Clone()
+		before := store.DesiredTuples()
+		report = store.EraseUser(userId, reassignTo)
+		writes, deletes = store.DiffTuples(before)
+
+		// Revoke tuples that exist in OpenFGA without being backed by persisted data
+		remaining := map[store.TupleKey]bool{}
+		for _, t := range deletes {
+			remaining[t] = true
+		}
+		for _, t := range actual {
+			if (t.User == fga.UserRef(userId) || t.Object == fga.UserRef(userId)) && !remaining[t] {
+				deletes = append(deletes, t)
+				remaining[t] = true
+			}
+		}
+
+		if err = writeInBatches(r.Context(), writes, deletes); err != nil {
+			store.Mu.Lock()
+			store.Data = backup
+			store.Mu.Unlock()
+		}
+	})
+	if err != nil {
+		fgaFailed(w, r, err)
+		return
+	}
+	recent.ForgetUser(userId)
+	profiles.Forget(userId)
+	store.Save()
+	for _, id := range report.DeletedDossiers {
+		store.DropArchive(id)
+	}
+
+	audit.SendAuditLog("test-app", "erasure", userId, "", fga.UserRef(userId), "DELETE",
+		fmt.Sprintf("User erased: %d dossiers deleted, %d reassigned to %q, %d tuples revoked",
+			len(report.DeletedDossiers), len(report.ReassignedDossiers), reassignTo, len(deletes)))
+	httputil.JSONResponse(w, map[string]interface{}{
+		"erasure": report, "tuplesWritten": len(writes), "tuplesRevoked": len(deletes),
+	}, 200)
+}
+
+// AdminResourceModel returns the OpenFGA type definitions for every registered
+// resource type, ready to be added to the model (e.g. via /api/admin/model/diff).
+func AdminResourceModel(w http.ResponseWriter, r *http.Request) {
+	if !isManagerAdmin(r) {
+		httputil.JSONError(w, i18n.T(r, "Admin access required"), 403)
+		return
+	}
+	defs := []map[string]interface{}{}
+	for _, t := range resources.Types() {
+		defs = append(defs, resources.TypeDefinition(t))
+	}
+	httputil.JSONResponse(w, map[string]interface{}{"type_definitions": defs}, 200)
+}
+
+// AdminOrganizationsOrphaned lists the organizations that have no admin left
+// and therefore can only be recovered by a manager admin.
+func AdminOrganizationsOrphaned(w http.ResponseWriter, r *http.Request) {
+	if !isManagerAdmin(r) {
+		httputil.JSONError(w, i18n.T(r, "Admin access required"), 403)
+		return
+	}
+	ids := store.OrphanedOrganizations()
+	orgs := []map[string]interface{}{}
+	users := []string{}
+	store.Mu.RLock()
+	for _, id := range ids {
+		if org, ok := store.Data.Organizations[id]; ok {
+			orgs = append(orgs, map[string]interface{}{"id": id, "name": org.Name, "members": org.Members})
+			users = append(users, org.Members...)
+		}
+	}
+	store.Mu.RUnlock()
+	listResponse(w, r, "organizations", orgs, listMeta{Users: users})
+}
+
+// AdminAppointOrgAdmin makes {user} an admin (and member) of any organization,
+// bypassing the org's own admins, to recover orgs whose admins are gone. A
+// {reason} is required and recorded in the audit log.
+func AdminAppointOrgAdmin(w http.ResponseWriter, r *http.Request, orgId string) {
+	if !isManagerAdmin(r) {
+		httputil.JSONError(w, i18n.T(r, "Admin access required"), 403)
+		return
+	}
+	if !config.FgaReady {
+		httputil.JSONError(w, i18n.T(r, "OpenFGA not ready"), 503)
+		return
+	}
+	body, err := httputil.ReadBody(r)
+	if err != nil {
+		httputil.JSONError(w, i18n.T(r, "Invalid request body"), 400)
+		return
+	}
+	user, ok := userField(w, r, body, "user")
+	if !ok {
+		return
+	}
+	reason := strings.TrimSpace(httputil.GetString(body, "reason"))
+	if user == "" {
+		httputil.JSONError(w, i18n.T(r, "user is required"), 400)
+		return
+	}
+	if reason == "" {
+		httputil.JSONError(w, i18n.T(r, "reason is required"), 400)
+		return
+	}
+
+	store.Mu.RLock()
+	org, ok := store.Data.Organizations[orgId]
+	var isAdmin, isMember bool
+	var previous []string
+	if ok {
+		isAdmin = httputil.Contains(org.Admins, user)
+		isMember = httputil.Contains(org.Members, user)
+		previous = append(previous, org.Admins...)
+	}
+	store.Mu.RUnlock()
+	if !ok {
+		httputil.JSONError(w, i18n.T(r, "Organization not found"), 404)
+		return
+	}
+	if isAdmin {
+		httputil.JSONError(w, i18n.T(r, "Already an admin"), 400)
+		return
+	}
+
+	tuples := []store.TupleKey{{User: fga.UserRef(user), Relation: "admin", Object: fga.ObjectRef(fga.TypeOrganization, orgId)}}
+	if !isMember {
+		tuples = append(tuples, store.TupleKey{User: fga.UserRef(user), Relation: "member", Object: fga.ObjectRef(fga.TypeOrganization, orgId)})
+	}
+	if err := fga.Write(r.Context(), tuples, nil); err != nil {
+		fgaFailed(w, r, err)
+		return
+	}
+	store.Mu.Lock()
+	org.Admins = append(org.Admins, user)
+	if !isMember {
+		org.Members = append(org.Members, user)
+	}
+	org.Updated()
+	store.Mu.Unlock()
+	store.Save()
+
+	audit.SendAuditLog("test-app", "org_takeover", fga.UserRef(httputil.GetUser(r)), "admin", fga.ObjectRef(fga.TypeOrganization, orgId), "POST",
+		fmt.Sprintf("Manager admin appointed %s as admin (previous admins: %v): %s", user, previous, reason))
+	events.Publish(events.Event{
+		Type: events.OrgAdminAppointed, Actor: httputil.GetUser(r), Object: fga.ObjectRef(fga.TypeOrganization, orgId),
+		Recipients: append(previous, user), Data: map[string]string{"user": user, "reason": reason},
+	})
+	httputil.JSONResponse(w, map[string]interface{}{"success": true, "orgId": orgId, "admin": user, "previousAdmins": previous}, 200)
+}
+
+// AdminPrivacy reports (GET) or switches (PUT {"anonymize": bool}) the
+// anonymization of payloads sent to the AI Manager and the audit sink.
+func AdminPrivacy(w http.ResponseWriter, r *http.Request) {
+	if !isManagerAdmin(r) {
+		httputil.JSONError(w, i18n.T(r, "Admin access required"), 403)
+		return
+	}
+	if r.Method == "PUT" {
+		var body struct {
+			Anonymize *bool `json:"anonymize"`
+		}
+		if err := json.NewDecoder(r.Body).Decode(&body); err != nil || body.Anonymize == nil {
+			httputil.JSONError(w, i18n.T(r, "anonymize must be true or false"), 400)
+			return
+		}
+		privacy.SetEnabled(*body.Anonymize)
+		audit.SendAuditLog("test-app", "privacy", fga.UserRef(httputil.GetUser(r)), "", "setting:anonymize", "PUT",
+			fmt.Sprintf("Anonymization set to %v", *body.Anonymize))
+	}
+	httputil.JSONResponse(w, map[string]bool{"anonymize": privacy.Enabled()}, 200)
+}
+
+// AdminListCache reports the ListObjects cache counters (GET) or flushes the
+// cache (DELETE), e.g. after changing tuples directly in OpenFGA.
+func AdminListCache(w http.ResponseWriter, r *http.Request) {
+	if !isManagerAdmin(r) {
+		httputil.JSONError(w, i18n.T(r, "Admin access required"), 403)
+		return
+	}
+	if r.Method == "DELETE" {
+		fga.FlushListCache()
+	}
+	httputil.JSONResponse(w, fga.ListCacheStats(), 200)
+}
+
+// AdminFaults shows and configures injected faults for resilience demos (for
+// admin use). PUT takes {"target": "openfga"|"audit", "latencyMs", "errorRate",
+// "outage", "durationSeconds"}; DELETE lifts the fault of ?target=, or all of them.
+func AdminFaults(w http.ResponseWriter, r *http.Request) {
+	if !isManagerAdmin(r) {
+		httputil.JSONError(w, i18n.T(r, "Admin access required"), 403)
+		return
+	}
+	switch r.Method {
+	case "PUT":
+		body, err := httputil.ReadBody(r)
+		if err != nil {
+			httputil.JSONError(w, i18n.T(r, "Invalid request body"), 400)
+			return
+		}
+		target := httputil.GetString(body, "target")
+		f := faults.Fault{}
+		if v, ok := body["latencyMs"].(float64); ok {
+			f.LatencyMs = int(v)
+		}
+		if v, ok := body["errorRate"].(float64); ok {
+			f.ErrorRate = v
+		}
+		f.Outage, _ = body["outage"].(bool)
+		if v, ok := body["durationSeconds"].(float64); ok && v > 0 {
+			f.Until = time.Now().Add(time.Duration(v) * time.Second)
+		}
+		if err := faults.Set(target, f); err != nil {
+			httputil.JSONError(w, err.Error(), 400)
+			return
+		}
+		log.Printf("Fault injection on %s: latency=%dms errorRate=%g outage=%v", target, f.LatencyMs, f.ErrorRate, f.Outage)
+	case "DELETE":
+		faults.Clear(r.URL.Query().Get("target"))
+		log.Printf("Fault injection cleared (%q)", r.URL.Query().Get("target"))
+	}
+	httputil.JSONResponse(w, map[string]interface{}{"faults": faults.Active(), "targets": faults.Targets}, 200)
+}
+
+// AdminAuditSampling shows and configures which allow decisions are sent to
+// the audit sink (for admin use). PUT takes an audit.Sampling ({"allowRate",
+// "sources", "users", "burstPerSecond"}) and replaces the settings; DELETE goes
+// back to AUDIT_ALLOW_SAMPLE_RATE and AUDIT_BURST_PER_SECOND. Denies and
+// writes are always sent.
+func AdminAuditSampling(w http.ResponseWriter, r *http.Request) {
+	if !isManagerAdmin(r) {
+		httputil.JSONError(w, i18n.T(r, "Admin access required"), 403)
+		return
+	}
+	switch r.Method {
+	case "PUT":
+		var body audit.Sampling
+		if err := json.NewDecoder(r.Body).Decode(&body); err != nil {
+			httputil.JSONError(w, i18n.T(r, "Invalid request body"), 400)
+			return
+		}
+		if err := audit.SetSampling(body); err != nil {
+			httputil.JSONError(w, err.Error(), 400)
+			return
+		}
+		log.Printf("Audit sampling: allowRate=%g sources=%v users=%v burst=%d/s", body.AllowRate, body.Sources, body.Users, body.BurstPerSecond)
+	case "DELETE":
+		audit.ResetSampling()
+		log.Printf("Audit sampling reset to the configured defaults")
+	}
+	s, stats := audit.CurrentSampling()
+	httputil.JSONResponse(w, map[string]interface{}{"sampling": s, "stats": stats}, 200)
+}
+
+// AdminConfig shows the effective value of each tunable and whether it comes
+// from CONFIG_FILE, the environment or the default (for admin use), with the
+// time the file was last applied and what was wrong with it, if anything.
+func AdminConfig(w http.ResponseWriter, r *http.Request) {
+	if !isManagerAdmin(r) {
+		httputil.JSONError(w, i18n.T(r, "Admin access required"), 403)
+		return
+	}
+	httputil.JSONResponse(w, config.Tunables(), 200)
+}
+
+// AdminShadow shows and configures shadow evaluation (for admin use): PUT
+// {"modelId", "storeId"} evaluates every later check against that model as
+// well, after checking it exists; DELETE turns shadow evaluation off. Shadow
+// answers are only compared and logged, never returned.
+func AdminShadow(w http.ResponseWriter, r *http.Request) {
+	if !isManagerAdmin(r) {
+		httputil.JSONError(w, i18n.T(r, "Admin access required"), 403)
+		return
+	}
+	switch r.Method {
+	case "PUT":
+		var body fga.ShadowConfig
+		if err := json.NewDecoder(r.Body).Decode(&body); err != nil || body.ModelId == "" {
+			httputil.JSONError(w, i18n.T(r, "modelId is required"), 400)
+			return
+		}
+		storeId := body.StoreId
+		if storeId == "" {
+			storeId = config.FgaStoreId
+		}
+		if err := fga.ProbeConfig(r.Context(), storeId, body.ModelId); err != nil {
+			httputil.JSONError(w, i18n.T(r, "Invalid OpenFGA config: %s", err.Error()), 400)
+			return
+		}
+		fga.SetShadow(body)
+	case "DELETE":
+		fga.SetShadow(fga.ShadowConfig{})
+	}
+	httputil.JSONResponse(w, fga.Shadow(), 200)
+}
+
+// AdminEncryption reports the content keys configured and how persisted
+// dossier content is sealed, per key (for admin use).
+func AdminEncryption(w http.ResponseWriter, r *http.Request) {
+	if !isManagerAdmin(r) {
+		httputil.JSONError(w, i18n.T(r, "Admin access required"), 403)
+		return
+	}
+	httputil.JSONResponse(w, store.Encryption(), 200)
+}
+
+// AdminReseal rewrites all persisted content under the active key, e.g. after
+// enabling encryption or adding a new key first in CONTENT_KEYS (for admin use).
+func AdminReseal(w http.ResponseWriter, r *http.Request) {
+	if !isManagerAdmin(r) {
+		httputil.JSONError(w, i18n.T(r, "Admin access required"), 403)
+		return
+	}
+	archives, err := store.Reseal()
+	if err == nil {
+		store.Mu.RLock()
+		err = store.LastSaveErr
+		store.Mu.RUnlock()
+	}
+	if err != nil {
+		httputil.JSONError(w, err.Error(), 500)
+		return
+	}
+	audit.SendAuditLog("test-app", "reseal", "admin", "", "store", "POST", "Dossier content re-encrypted with the active key")
+	httputil.JSONResponse(w, map[string]interface{}{"archives": archives, "status": store.Encryption()}, 200)
+}
@@ -0,0 +1,77 @@
+package handlers
+
+import (
+	"net/http"
+
+	"test-app/internal/audit"
+	"test-app/internal/config"
+	"test-app/internal/fga"
+	"test-app/internal/httputil"
+	"test-app/internal/i18n"
+	"test-app/internal/store"
+)
+
+// DossiersArchive moves a dossier's content to cold storage. The dossier keeps
+// its metadata and tuples, so it stays listed and shareable, but its content
+// is only available again after DossiersRestore.
+func DossiersArchive(w http.ResponseWriter, r *http.Request, id string) {
+	changeArchive(w, r, id, true)
+}
+
+// DossiersRestore brings an archived dossier's content back into the store.
+// Neither answer carries the content: read it through GET /api/dossiers/{id},
+// which applies the step-up and mandate checks.
+func DossiersRestore(w http.ResponseWriter, r *http.Request, id string) {
+	changeArchive(w, r, id, false)
+}
+
+func changeArchive(w http.ResponseWriter, r *http.Request, id string, archive bool) {
+	if !config.FgaReady {
+		httputil.JSONError(w, i18n.T(r, "OpenFGA not ready"), 503)
+		return
+	}
+	user := httputil.GetUser(r)
+	l := lockDossier(w, r, id, "editor", "Not authorized")
+	if l == nil {
+		return
+	}
+	defer l.Unlock()
+	if restrictionDenied(w, r, l.D, user, id) {
+		return
+	}
+	if archived := l.D.ArchivedAt != nil; archive == archived {
+		if archived {
+			httputil.JSONError(w, i18n.T(r, "Dossier is already archived"), 409)
+		} else {
+			httputil.JSONError(w, i18n.T(r, "Dossier is not archived"), 409)
+		}
+		return
+	}
+
+	action, err := "archive", error(nil)
+	if archive {
+		err = store.Archive(id)
+	} else {
+		action = "restore"
+		err = store.Restore(id)
+	}
+	if err != nil {
+		httputil.JSONError(w, err.Error(), 500)
+		return
+	}
+	archivedAt := l.D.ArchivedAt
+	l.Unlock()
+	store.Save()
+	if !archive {
+		// The archive file is the only copy of the content on disk until the
+		// restored dossier is saved.
+		store.Mu.RLock()
+		saved := store.LastSaveErr == nil
+		store.Mu.RUnlock()
+		if saved {
+			store.DropArchive(id)
+		}
+	}
+	audit.SendAuditLog("test-app", action, fga.UserRef(user), "editor", fga.ObjectRef(fga.TypeDossier, id), "POST", "Dossier "+action+"d by "+user)
+	httputil.JSONResponse(w, map[string]interface{}{"success": true, "id": id, "archivedAt": archivedAt}, 200)
+}
@@ -0,0 +1,105 @@
+package handlers
+
+import (
+	"encoding/json"
+	"net/http"
+
+	"test-app/internal/config"
+	"test-app/internal/fga"
+	"test-app/internal/httputil"
+	"test-app/internal/i18n"
+)
+
+// AssertionsList returns the assertions stored for the current model and the latest run (for admin use).
+func AssertionsList(w http.ResponseWriter, r *http.Request) {
+	if !isManagerAdmin(r) {
+		httputil.JSONError(w, i18n.T(r, "Admin access required"), 403)
+		return
+	}
+	if !config.FgaReady {
+		httputil.JSONError(w, i18n.T(r, "OpenFGA not ready"), 503)
+		return
+	}
+	assertions, err := fga.ReadAssertions(r.Context())
+	if err != nil {
+		httputil.JSONError(w, i18n.T(r, "Failed to read assertions: %s", err.Error()), 502)
+		return
+	}
+	httputil.JSONResponse(w, map[string]interface{}{
+		"modelId": config.FgaModelId, "assertions": assertions, "lastRun": fga.LastAssertionRun(),
+	}, 200)
+}
+
+// AssertionsAdd stores new assertions for the current model (for admin use).
+// Existing assertions are kept unless replace is set; an assertion for the same
+// user, relation and object overrides the previous expectation.
+func AssertionsAdd(w http.ResponseWriter, r *http.Request) {
+	if !isManagerAdmin(r) {
+		httputil.JSONError(w, i18n.T(r, "Admin access required"), 403)
+		return
+	}
+	if !config.FgaReady {
+		httputil.JSONError(w, i18n.T(r, "OpenFGA not ready"), 503)
+		return
+	}
+	var body struct {
+		Assertions []fga.Assertion `json:"assertions"`
+		Replace    bool            `json:"replace"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&body); err != nil || len(body.Assertions) == 0 {
+		httputil.JSONError(w, i18n.T(r, "assertions are required"), 400)
+		return
+	}
+	for _, a := range body.Assertions {
+		if a.User == "" || a.Relation == "" || a.Object == "" {
+			httputil.JSONError(w, i18n.T(r, "Each assertion needs user, relation and object"), 400)
+			return
+		}
+	}
+
+	merged := []fga.Assertion{}
+	if !body.Replace {
+		existing, err := fga.ReadAssertions(r.Context())
+		if err != nil {
+			httputil.JSONError(w, i18n.T(r, "Failed to read assertions: %s", err.Error()), 502)
+			return
+		}
+		merged = existing
+	}
+	for _, a := range body.Assertions {
+		replaced := false
+		for i, m := range merged {
+			if m.User == a.User && m.Relation == a.Relation && m.Object == a.Object {
+				merged[i] = a
+				replaced = true
+				break
+			}
+		}
+		if !replaced {
+			merged = append(merged, a)
+		}
+	}
+	if err := fga.WriteAssertions(r.Context(), merged); err != nil {
+		httputil.JSONError(w, i18n.T(r, "Failed to write assertions: %s", err.Error()), 502)
+		return
+	}
+	httputil.JSONResponse(w, map[string]interface{}{"modelId": config.FgaModelId, "assertions": merged}, 200)
+}
+
+// AssertionsRun evaluates the stored assertions immediately (for admin use).
+func AssertionsRun(w http.ResponseWriter, r *http.Request) {
+	if !isManagerAdmin(r) {
+		httputil.JSONError(w, i18n.T(r, "Admin access required"), 403)
+		return
+	}
+	if !config.FgaReady {
+		httputil.JSONError(w, i18n.T(r, "OpenFGA not ready"), 503)
+		return
+	}
+	run, err := fga.RunAssertions(r.Context())
+	if err != nil {
+		httputil.JSONError(w, i18n.T(r, "Failed to read assertions: %s", err.Error()), 502)
+		return
+	}
+	httputil.JSONResponse(w, run, 200)
+}
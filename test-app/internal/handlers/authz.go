@@ -0,0 +1,196 @@
+package handlers
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strings"
+	"time"
+
+	"test-app/internal/audit"
+	"test-app/internal/config"
+	"test-app/internal/fga"
+	"test-app/internal/httputil"
+	"test-app/internal/i18n"
+	"test-app/internal/privacy"
+	"test-app/internal/store"
+)
+
+var aiClient = &http.Client{Timeout: 30 * time.Second, Transport: aiTransport{}}
+
+// aiTransport authenticates AI Manager calls with the current AI_MANAGER_API_KEY,
+// when set. Other targets of aiClient, such as a custom OPA log relay, never see it.
+type aiTransport struct{}
+
+func (aiTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	key := config.Secret(config.AIManagerAPIKey)
+	if key != "" && config.AIManagerURL != "" && strings.HasPrefix(req.URL.String(), config.AIManagerURL) {
+		req = req.Clone(req.Context())
+		req.Header.Set("Authorization", "Bearer "+key)
+	}
+	return http.DefaultTransport.RoundTrip(req)
+}
+
+// explainFacts assembles everything known about the caller's authorization state.
+func explainFacts(ctx context.Context, user, object, relation string, graph *store.Graph) map[string]interface{} {
+	var tuples []store.TupleKey
+	if all, err := fga.ReadAll(ctx); err == nil {
+		for _, t := range all {
+			if t.User == fga.UserRef(user) || t.Object == fga.UserRef(user) {
+				tuples = append(tuples, t)
+			}
+		}
+	}
+	if tuples == nil {
+		tuples = []store.TupleKey{}
+	}
+
+	var visible []string
+	owned, shared := 0, 0
+	views, _ := visibleDossiers(user, accessContext{Now: time.Now(), Ctx: ctx})
+	for _, d := range views {
+		visible = append(visible, d.Id+": "+d.Title)
+		if httputil.Contains(d.Owners, user) {
+			owned++
+		} else {
+			shared++
+		}
+	}
+	if visible == nil {
+		visible = []string{}
+	}
+
+	guardians := graph.Guardians(user)
+	wards := graph.Wards(user)
+
+	facts := map[string]interface{}{
+		"user":                user,
+		"tuples":              tuples,
+		"visibleDossiers":     visible,
+		"myDossiersCount":     owned,
+		"sharedDossiersCount": shared,
+		"guardians":           guardians,
+		"wards":               wards,
+		"recentDecisions":     audit.Recent(user, 20),
+	}
+	if object != "" && relation != "" {
+		if tree, err := fga.Expand(ctx, relation, object); err == nil {
+			facts["expand"] = map[string]interface{}{"object": object, "relation": relation, "tree": tree}
+		} else {
+			facts["expand"] = map[string]interface{}{"object": object, "relation": relation, "error": err.Error()}
+		}
+	}
+	return facts
+}
+
+// askAIManager forwards the assembled facts to the AI Manager explain endpoint.
+// When anonymization is on, usernames are pseudonymized and dossier titles
+// redacted, and pseudonyms in the explanation are mapped back.
+func askAIManager(facts map[string]interface{}, deniedPath, reason string) (string, error) {
+	payload := map[string]interface{}{
+		"user":                facts["user"],
+		"visibleDossiers":     facts["visibleDossiers"],
+		"guardians":           facts["guardians"],
+		"wards":               facts["wards"],
+		"myDossiersCount":     facts["myDossiersCount"],
+		"sharedDossiersCount": facts["sharedDossiersCount"],
+	}
+	if deniedPath != "" {
+		payload["denied"] = true
+		payload["deniedPath"] = deniedPath
+		payload["reason"] = reason
+	}
+	if privacy.Enabled() {
+		anonymizeFacts(payload)
+	}
+	b, _ := json.Marshal(payload)
+	resp, err := aiClient.Post(config.AIManagerURL+"/api/explain-authz", "application/json", bytes.NewReader(b))
+	if err != nil {
+		return "", err
+	}
+	defer resp.Body.Close()
+	var result map[string]interface{}
+	if err := json.NewDecoder(resp.Body).Decode(&result); err != nil {
+		return "", fmt.Errorf("failed to decode AI Manager response: %w", err)
+	}
+	if resp.StatusCode != http.StatusOK {
+		msg, _ := result["error"].(string)
+		return "", fmt.Errorf("AI Manager returned %d: %s", resp.StatusCode, msg)
+	}
+	explanation, _ := result["explanation"].(string)
+	return privacy.Reveal(explanation), nil
+}
+
+// anonymizeFacts pseudonymizes the users and redacts the dossier titles in an
+// AI Manager explain payload, in place.
+func anonymizeFacts(payload map[string]interface{}) {
+	user, _ := payload["user"].(string)
+	payload["user"] = privacy.Pseudonym(user)
+	for _, key := range []string{"guardians", "wards"} {
+		if users, ok := payload[key].([]string); ok {
+			payload[key] = privacy.Pseudonyms(users)
+		}
+	}
+	if visible, ok := payload["visibleDossiers"].([]string); ok {
+		redacted := make([]string, len(visible))
+		for i, v := range visible {
+			id, _, _ := strings.Cut(v, ": ")
+			redacted[i] = id + ": " + privacy.Redacted
+		}
+		payload["visibleDossiers"] = redacted
+	}
+	if reason, ok := payload["reason"].(string); ok {
+		payload["reason"] = privacy.Scrub(reason)
+	}
+}
+
+// expandDenied reports whether the caller may not see who has access to
+// object, having answered 403 (or 503 when OpenFGA could not answer): the
+// manager admin always may, anyone else only on objects they can view
+// themselves (organizations they are a member of).
+func expandDenied(w http.ResponseWriter, r *http.Request, object string) bool {
+	if isManagerAdmin(r) {
+		return false
+	}
+	relation := "viewer"
+	if strings.HasPrefix(object, fga.TypeOrganization+":") {
+		relation = "member"
+	}
+	return checkDenied(w, r, relation, object, "Not authorized")
+}
+
+// AuthzExplain gathers the caller's tuples, an optional expand tree
+// (?object=dossier:d1&relation=viewer, only on objects the caller can view)
+// and recent decisions. With ?ai=true
+// the facts are sent to the AI Manager and its explanation is returned alongside them.
+func AuthzExplain(w http.ResponseWriter, r *http.Request) {
+	if !config.FgaReady {
+		httputil.JSONError(w, i18n.T(r, "OpenFGA not ready"), 503)
+		return
+	}
+	user := httputil.GetUser(r)
+	q := r.URL.Query()
+	object := q.Get("object")
+	relation := q.Get("relation")
+	if object != "" && !strings.Contains(object, ":") {
+		httputil.JSONError(w, i18n.T(r, "object must be of the form type:id"), 400)
+		return
+	}
+	if object != "" && relation != "" && expandDenied(w, r, object) {
+		return
+	}
+
+	facts := explainFacts(r.Context(), user, object, relation, store.SnapshotGraph())
+	if q.Get("ai") != "true" {
+		httputil.JSONResponse(w, map[string]interface{}{"facts": facts}, 200)
+		return
+	}
+	explanation, err := askAIManager(facts, q.Get("deniedPath"), q.Get("reason"))
+	if err != nil {
+		httputil.JSONResponse(w, map[string]interface{}{"facts": facts, "aiError": err.Error()}, 502)
+		return
+	}
+	httputil.JSONResponse(w, map[string]interface{}{"facts": facts, "explanation": explanation}, 200)
+}
@@ -0,0 +1,73 @@
+package handlers
+
+import (
+	"net/http"
+
+	"test-app/internal/audit"
+	"test-app/internal/backup"
+	"test-app/internal/config"
+	"test-app/internal/fga"
+	"test-app/internal/httputil"
+	"test-app/internal/i18n"
+	"test-app/internal/profiles"
+	"test-app/internal/store"
+)
+
+// BackupsList returns the available backups, newest first (for admin use).
+func BackupsList(w http.ResponseWriter, r *http.Request) {
+	if !isManagerAdmin(r) {
+		httputil.JSONError(w, i18n.T(r, "Admin access required"), 403)
+		return
+	}
+	backups, err := backup.List(config.BackupDir)
+	if err != nil {
+		httputil.JSONError(w, err.Error(), 500)
+		return
+	}
+	httputil.JSONResponse(w, map[string]interface{}{
+		"backups": backups, "dir": config.BackupDir,
+		"interval": config.BackupInterval.String(), "retention": config.BackupRetention,
+	}, 200)
+}
+
+// BackupsCreate takes a backup immediately (for admin use).
+func BackupsCreate(w http.ResponseWriter, r *http.Request) {
+	if !isManagerAdmin(r) {
+		httputil.JSONError(w, i18n.T(r, "Admin access required"), 403)
+		return
+	}
+	readTuples := func() ([]store.TupleKey, error) { return fga.ReadAll(r.Context()) }
+	if !config.FgaReady {
+		readTuples = nil
+	}
+	info, err := backup.Create(config.BackupDir, readTuples)
+	if err != nil {
+		fgaFailed(w, r, err)
+		return
+	}
+	backup.Prune(config.BackupDir, config.BackupRetention)
+	httputil.JSONResponse(w, info, 200)
+}
+
+// BackupsRestore replaces the store and the tuple set with a backup (for admin use).
+func BackupsRestore(w http.ResponseWriter, r *http.Request, name string) {
+	if !isManagerAdmin(r) {
+		httputil.JSONError(w, i18n.T(r, "Admin access required"), 403)
+		return
+	}
+	if !config.FgaReady {
+		httputil.JSONError(w, i18n.T(r, "OpenFGA not ready"), 503)
+		return
+	}
+	report, err := backup.Restore(config.BackupDir, name,
+		func() ([]store.TupleKey, error) { return fga.ReadAll(r.Context()) },
+		func(writes, deletes []store.TupleKey) error { return fga.Write(r.Context(), writes, deletes) })
+	if err != nil {
+		fgaFailed(w, r, err)
+		return
+	}
+	profiles.Reset()
+	forgetUndo()
+	audit.SendAuditLog("test-app", "restore", "admin", "", "backup:"+name, "POST", "Data store and tuples restored from backup "+name)
+	httputil.JSONResponse(w, report, 200)
+}
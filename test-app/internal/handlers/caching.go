@@ -0,0 +1,26 @@
+package handlers
+
+import (
+	"net/http"
+	"strconv"
+	"time"
+
+	"test-app/internal/config"
+	"test-app/internal/httputil"
+	"test-app/internal/i18n"
+	"test-app/internal/store"
+)
+
+// notModified answers a conditional GET. The ETag covers the store version,
+// the active FGA store and model, the caller and their access context, so any
+// write, model switch or change in step-up, client IP or minute invalidates it.
+func notModified(w http.ResponseWriter, r *http.Request, parts ...string) bool {
+	ac := accessContextFrom(r)
+	key := append([]string{
+		strconv.FormatUint(store.Version(), 10), config.FgaStoreId, config.FgaModelId,
+		httputil.GetUser(r), strconv.FormatBool(ac.StepUp), ac.ClientIP,
+		ac.Now.Truncate(time.Minute).Format(time.RFC3339), i18n.Lang(r),
+		strconv.FormatBool(isManagerAdmin(r)), r.URL.RawQuery, config.ResponseEnvelope.Get(),
+	}, parts...)
+	return httputil.NotModified(w, r, httputil.ETag(key...), config.CacheMaxAge.Get())
+}
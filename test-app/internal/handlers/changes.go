@@ -0,0 +1,67 @@
+package handlers
+
+import (
+	"encoding/json"
+	"net/http"
+	"strconv"
+
+	"test-app/internal/events"
+	"test-app/internal/httputil"
+	"test-app/internal/i18n"
+	"test-app/internal/store"
+)
+
+const (
+	defaultChangesLimit = 200
+	maxChangesLimit     = 1000
+)
+
+type change struct {
+	events.Event
+	Value json.RawMessage `json:"value,omitempty"`
+}
+
+// AdminChanges returns the store mutations, tuple writes and domain events
+// published after ?since= (a cursor from a previous call; 0 for everything
+// retained), so the AI Manager can follow changes instead of re-reading full
+// dumps. Store changes carry the object's current value. reset is true when
+// the cursor is too old or from before a restart: the caller must reload in
+// full and continue from the returned cursor.
+func AdminChanges(w http.ResponseWriter, r *http.Request) {
+	if !isManagerAdmin(r) {
+		httputil.JSONError(w, i18n.T(r, "Admin access required"), 403)
+		return
+	}
+	q := r.URL.Query()
+	var since uint64
+	if v := q.Get("since"); v != "" {
+		n, err := strconv.ParseUint(v, 10, 64)
+		if err != nil {
+			httputil.JSONError(w, i18n.T(r, "since must be a cursor returned by this endpoint"), 400)
+			return
+		}
+		since = n
+	}
+	limit := defaultChangesLimit
+	if v := q.Get("limit"); v != "" {
+		n, err := strconv.Atoi(v)
+		if err != nil || n < 1 {
+			httputil.JSONError(w, i18n.T(r, "limit must be a positive integer"), 400)
+			return
+		}
+		limit = min(n, maxChangesLimit)
+	}
+
+	evs, next, complete := events.Since(since, limit)
+	changes := make([]change, len(evs))
+	for i, e := range evs {
+		changes[i] = change{Event: e}
+		if e.Type == events.ObjectChanged {
+			changes[i].Value, _ = store.ObjectJSON(e.Object)
+		}
+	}
+	_, latest, _ := events.Since(next, 1)
+	httputil.JSONResponse(w, map[string]interface{}{
+		"changes": changes, "cursor": next, "reset": !complete, "more": latest > next,
+	}, 200)
+}
@@ -0,0 +1,133 @@
+package handlers
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"html"
+	"html/template"
+	"net/http"
+	"regexp"
+	"strings"
+
+	"test-app/internal/config"
+	"test-app/internal/httputil"
+	"test-app/internal/i18n"
+	"test-app/internal/store"
+)
+
+// validContentTypes are the formats a dossier's content may be stored in.
+var validContentTypes = []string{"text", "markdown", "json"}
+
+// bodyOverhead is the room left for the other JSON fields of a dossier request
+// on top of config.MaxContentSize.
+const bodyOverhead = 16 << 10
+
+// contentTypeOf returns the dossier's content format, treating unset as text.
+func contentTypeOf(d *store.Dossier) string {
+	if d.ContentType == "" {
+		return "text"
+	}
+	return d.ContentType
+}
+
+// readDossierBody decodes a dossier request body, answering 413 when it is
+// larger than the configured content limit allows.
+func readDossierBody(w http.ResponseWriter, r *http.Request) (map[string]interface{}, bool) {
+	r.Body = http.MaxBytesReader(w, r.Body, int64(config.MaxContentSize.Get())+bodyOverhead)
+	body, err := httputil.ReadBody(r)
+	if err != nil {
+		var tooLarge *http.MaxBytesError
+		if errors.As(err, &tooLarge) {
+			httputil.JSONError(w, i18n.T(r, "Content exceeds the maximum size of %d bytes", config.MaxContentSize.Get()), 413)
+		} else {
+			httputil.JSONError(w, i18n.T(r, "Invalid request body"), 400)
+		}
+		return nil, false
+	}
+	return body, true
+}
+
+// checkContent validates content against the size limit and its declared type,
+// writing the error response and returning false when it is rejected.
+func checkContent(w http.ResponseWriter, r *http.Request, content, contentType string) bool {
+	if len(content) > config.MaxContentSize.Get() {
+		httputil.JSONError(w, i18n.T(r, "Content exceeds the maximum size of %d bytes", config.MaxContentSize.Get()), 413)
+		return false
+	}
+	if !httputil.Contains(validContentTypes, contentType) {
+		httputil.JSONError(w, i18n.T(r, "Content type must be one of: text, markdown, json"), 400)
+		return false
+	}
+	if contentType == "json" && content != "" && !json.Valid([]byte(content)) {
+		httputil.JSONError(w, i18n.T(r, "Content is not valid JSON"), 400)
+		return false
+	}
+	return true
+}
+
+var (
+	mdBold   = regexp.MustCompile(`\*\*([^*]+)\*\*`)
+	mdItalic = regexp.MustCompile(`\*([^*]+)\*`)
+	mdCode   = regexp.MustCompile("`([^`]+)`")
+	mdLink   = regexp.MustCompile(`\[([^\]]+)\]\((https?://[^\s)"]+)\)`)
+)
+
+// renderContent turns stored content into HTML that is safe to embed: every
+// input character is escaped first, and only a small markdown subset (headings,
+// lists, bold, italic, code and http(s) links) is turned back into markup.
+func renderContent(content, contentType string) template.HTML {
+	switch contentType {
+	case "json":
+		var buf bytes.Buffer
+		if err := json.Indent(&buf, []byte(content), "", "  "); err != nil {
+			buf.Reset()
+			buf.WriteString(content)
+		}
+		return template.HTML("<pre>" + html.EscapeString(buf.String()) + "</pre>")
+	case "markdown":
+		return renderMarkdown(content)
+	}
+	return template.HTML(strings.ReplaceAll(html.EscapeString(content), "\n", "<br>"))
+}
+
+func renderMarkdown(content string) template.HTML {
+	var out strings.Builder
+	inList := false
+	for _, line := range strings.Split(content, "\n") {
+		line = strings.TrimRight(line, "\r")
+		if item, ok := strings.CutPrefix(line, "- "); ok {
+			if !inList {
+				out.WriteString("<ul>")
+				inList = true
+			}
+			out.WriteString("<li>" + inlineMarkdown(item) + "</li>")
+			continue
+		}
+		if inList {
+			out.WriteString("</ul>")
+			inList = false
+		}
+		level := len(line) - len(strings.TrimLeft(line, "#"))
+		switch {
+		case strings.TrimSpace(line) == "":
+		case level >= 1 && level <= 3 && strings.HasPrefix(line[level:], " "):
+			fmt.Fprintf(&out, "<h%d>%s</h%d>", level+2, inlineMarkdown(line[level+1:]), level+2)
+		default:
+			out.WriteString("<p>" + inlineMarkdown(line) + "</p>")
+		}
+	}
+	if inList {
+		out.WriteString("</ul>")
+	}
+	return template.HTML(out.String())
+}
+
+func inlineMarkdown(s string) string {
+	s = html.EscapeString(s)
+	s = mdCode.ReplaceAllString(s, "<code>$1</code>")
+	s = mdLink.ReplaceAllString(s, `<a href="$2" rel="noopener noreferrer">$1</a>`)
+	s = mdBold.ReplaceAllString(s, "<strong>$1</strong>")
+	return mdItalic.ReplaceAllString(s, "<em>$1</em>")
+}
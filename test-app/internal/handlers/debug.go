@@ -1,37 +1,60 @@
 package handlers
 
 import (
-	"fmt"
 	"net/http"
+	"strconv"
 
 	"test-app/internal/config"
 	"test-app/internal/fga"
 	"test-app/internal/httputil"
+	"test-app/internal/i18n"
+	"test-app/internal/store"
 )
 
+// debugPageSize is OpenFGA's maximum Read page size, used when ?pageSize= is absent.
+const debugPageSize = 100
+
+// DebugTuples returns one page of the raw tuples in the store for admins and
+// auditors. ?type= limits it to one object type and ?user= (which OpenFGA only
+// accepts together with a type) to one user; ?cursor= continues from the
+// cursor of the previous page, which is empty once everything was read.
 func DebugTuples(w http.ResponseWriter, r *http.Request) {
-	if !config.FgaReady {
-		httputil.JSONError(w, "OpenFGA not ready", 503)
+	if !isManagerAdmin(r) && !httputil.HasRole(r, "admin") && !httputil.HasRole(r, "auditor") {
+		httputil.JSONError(w, i18n.T(r, "Admin or auditor role required"), 403)
 		return
 	}
-	result, err := fga.Request("POST", "/stores/"+config.FgaStoreId+"/read", map[string]interface{}{})
-	if err != nil {
-		httputil.JSONError(w, err.Error(), 500)
+	if !config.FgaReady {
+		httputil.JSONError(w, i18n.T(r, "OpenFGA not ready"), 503)
 		return
 	}
-	tuples, _ := result["tuples"].([]interface{})
-	var keys []map[string]string
-	for _, t := range tuples {
-		tm, _ := t.(map[string]interface{})
-		key, _ := tm["key"].(map[string]interface{})
-		keys = append(keys, map[string]string{
-			"user":     fmt.Sprintf("%v", key["user"]),
-			"relation": fmt.Sprintf("%v", key["relation"]),
-			"object":   fmt.Sprintf("%v", key["object"]),
-		})
+	q := r.URL.Query()
+	pageSize := debugPageSize
+	if v := q.Get("pageSize"); v != "" {
+		n, err := strconv.Atoi(v)
+		if err != nil || n < 1 || n > debugPageSize {
+			httputil.JSONError(w, i18n.T(r, "pageSize must be between 1 and %d", debugPageSize), 400)
+			return
+		}
+		pageSize = n
 	}
-	if keys == nil {
-		keys = []map[string]string{}
+	var filter store.TupleKey
+	if typ := q.Get("type"); typ != "" {
+		filter.Object = typ + ":"
+	}
+	if user := q.Get("user"); user != "" {
+		if filter.Object == "" {
+			httputil.JSONError(w, i18n.T(r, "The user filter requires a type"), 400)
+			return
+		}
+		if filter.User = fga.UserRef(user); filter.User == "" {
+			httputil.JSONError(w, i18n.T(r, "Invalid username"), 400)
+			return
+		}
+	}
+	tuples, cursor, err := fga.Read(r.Context(), filter, pageSize, q.Get("cursor"))
+	if err != nil {
+		fgaFailed(w, r, err)
+		return
 	}
-	httputil.JSONResponse(w, map[string]interface{}{"tuples": keys}, 200)
+	listResponse(w, r, "tuples", tuples, listMeta{Pagination: map[string]interface{}{"cursor": cursor}})
 }
@@ -0,0 +1,101 @@
+package handlers
+
+import (
+	"net/http"
+
+	"test-app/internal/audit"
+	"test-app/internal/config"
+	"test-app/internal/events"
+	"test-app/internal/fga"
+	"test-app/internal/httputil"
+	"test-app/internal/i18n"
+	"test-app/internal/store"
+)
+
+// DossiersSetOrg attaches, detaches or moves a dossier's organization.
+// {"orgId": "x"} attaches or moves it to x, {"orgId": ""} detaches it.
+// The caller must own the dossier or administer its current organization,
+// and must belong to the target organization. The org_parent tuples are
+// swapped in a single OpenFGA write so the dossier is never in both orgs.
+func DossiersSetOrg(w http.ResponseWriter, r *http.Request, id string) {
+	if !config.FgaReady {
+		httputil.JSONError(w, i18n.T(r, "OpenFGA not ready"), 503)
+		return
+	}
+	user := httputil.GetUser(r)
+	body, err := httputil.ReadBody(r)
+	if err != nil {
+		httputil.JSONError(w, i18n.T(r, "Invalid request body"), 400)
+		return
+	}
+	target := httputil.GetString(body, "orgId")
+
+	store.Mu.RLock()
+	dossier, ok := store.Data.Dossiers[id]
+	var source string
+	var owner bool
+	if ok {
+		source = dossier.OrgId
+		owner = dossier.IsOwner(user)
+	}
+	_, targetExists := store.Data.Organizations[target]
+	store.Mu.RUnlock()
+	if !ok {
+		httputil.JSONError(w, i18n.T(r, "Dossier not found"), 404)
+		return
+	}
+	if target != "" && !targetExists {
+		httputil.JSONError(w, i18n.T(r, "Organization not found"), 404)
+		return
+	}
+	if target == source {
+		httputil.JSONResponse(w, map[string]interface{}{"success": true, "id": id, "orgId": source, "changed": false}, 200)
+		return
+	}
+	admin := isManagerAdmin(r)
+	if !admin && !owner {
+		if source == "" {
+			httputil.JSONError(w, i18n.T(r, "Only the owner or an admin of its organization can move this dossier"), 403)
+			return
+		}
+		if checkDenied(w, r, "can_manage", fga.ObjectRef(fga.TypeOrganization, source), "Only the owner or an admin of its organization can move this dossier") {
+			return
+		}
+	}
+	if !admin && target != "" && checkDenied(w, r, "member", fga.ObjectRef(fga.TypeOrganization, target), "You must be a member of the target organization") {
+		return
+	}
+
+	var writes, deletes []store.TupleKey
+	if source != "" {
+		deletes = append(deletes, store.TupleKey{User: fga.ObjectRef(fga.TypeOrganization, source), Relation: "org_parent", Object: fga.ObjectRef(fga.TypeDossier, id)})
+	}
+	if target != "" {
+		writes = append(writes, store.TupleKey{User: fga.ObjectRef(fga.TypeOrganization, target), Relation: "org_parent", Object: fga.ObjectRef(fga.TypeDossier, id)})
+	}
+	if err := fga.Write(r.Context(), writes, deletes); err != nil {
+		fgaFailed(w, r, err)
+		return
+	}
+	store.Mu.Lock()
+	dossier.OrgId = target
+	dossier.Updated()
+	owners := append([]string(nil), dossier.Owners...)
+	store.Mu.Unlock()
+	store.Save()
+
+	audit.SendAuditLog("test-app", "org_change", fga.UserRef(user), "org_parent", fga.ObjectRef(fga.TypeDossier, id), "POST",
+		"Dossier organization changed from "+orgLabel(source)+" to "+orgLabel(target))
+	events.Publish(events.Event{
+		Type: events.DossierOrgChanged, Actor: user, Object: fga.ObjectRef(fga.TypeDossier, id), Recipients: owners,
+		Data: map[string]string{"from": source, "to": target},
+	})
+	httputil.JSONResponse(w, map[string]interface{}{"success": true, "id": id, "orgId": target, "previousOrgId": source, "changed": true}, 200)
+}
+
+func orgLabel(orgId string) string {
+	if orgId == "" {
+		return "none"
+	}
+	return fga.ObjectRef(fga.TypeOrganization, orgId)
+}
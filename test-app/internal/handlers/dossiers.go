@@ -1,78 +1,85 @@
 package handlers
 
 import (
+	"context"
+	"errors"
+	"html/template"
+	"log"
 	"net/http"
+	"sort"
 	"strings"
+	"sync"
+	"time"
 
+	"test-app/internal/analytics"
+	"test-app/internal/audit"
 	"test-app/internal/config"
+	"test-app/internal/dossiertypes"
 	"test-app/internal/fga"
 	"test-app/internal/httputil"
+	"test-app/internal/i18n"
+	"test-app/internal/recent"
 	"test-app/internal/store"
+	"test-app/internal/users"
+	"test-app/internal/visibility"
 )
 
-var validDossierTypes = []string{"tax", "health", "general"}
+// lookupDossierType returns the registered dossier type called name, answering 400
+// when there is none.
+func lookupDossierType(w http.ResponseWriter, r *http.Request, name string) (*dossiertypes.Type, bool) {
+	t, ok := dossiertypes.Lookup(name)
+	if !ok {
+		httputil.JSONError(w, i18n.T(r, "Type must be one of: %s", strings.Join(dossiertypes.Names(), ", ")), 400)
+	}
+	return t, ok
+}
+
+// checkDossierType answers 400 when d, as it would be after a change, breaks
+// a rule of its type. Dossiers of a type no longer registered are not checked.
+func checkDossierType(w http.ResponseWriter, r *http.Request, d *store.Dossier) bool {
+	t, ok := dossiertypes.Lookup(d.Type)
+	if !ok {
+		return true
+	}
+	if err := t.Check(d); err != nil {
+		httputil.JSONError(w, i18n.T(r, "Dossier type rule violated: %s", err.Error()), 400)
+		return false
+	}
+	return true
+}
 
 // isManagerAdminDossiers checks if the request comes from the AI Manager with admin privileges
 func isManagerAdminDossiers(r *http.Request) bool {
 	return r.Header.Get("x-manager-admin") == "true"
 }
 
+// userField reads the username in body[key], normalized for use in tuples. An
+// invalid name is answered with 400 and reported as !ok; a missing one is
+// returned empty for the caller's own "required" check.
+func userField(w http.ResponseWriter, r *http.Request, body map[string]interface{}, key string) (string, bool) {
+	name, err := users.Normalize(httputil.GetString(body, key))
+	if err != nil {
+		httputil.JSONError(w, i18n.T(r, "Invalid username for %s: usernames cannot contain ':', '#', '*' or whitespace", key), 400)
+		return "", false
+	}
+	return name, true
+}
+
 // UsersList returns all known users in the system (for admin use)
 func UsersList(w http.ResponseWriter, r *http.Request) {
 	if !isManagerAdminDossiers(r) {
-		httputil.JSONError(w, "Admin access required", 403)
+		httputil.JSONError(w, i18n.T(r, "Admin access required"), 403)
 		return
 	}
 
-	// Collect users from all sources
-	userSet := make(map[string]bool)
-
-	store.Mu.RLock()
-	// From dossiers (owners and relations)
-	for _, d := range store.Data.Dossiers {
-		userSet[d.Owner] = true
-		for _, rel := range d.Relations {
-			userSet[rel.User] = true
-		}
-		for _, blocked := range d.BlockedUsers {
-			userSet[blocked] = true
-		}
-	}
-	// From guardianships
-	for userId, guardians := range store.Data.Guardianships {
-		userSet[userId] = true
-		for _, g := range guardians {
-			userSet[g] = true
-		}
-	}
-	// From guardianship requests
-	for _, req := range store.Data.GuardianshipRequests {
-		userSet[req.From] = true
-		userSet[req.To] = true
-	}
-	// From organizations
-	for _, org := range store.Data.Organizations {
-		for _, m := range org.Members {
-			userSet[m] = true
-		}
-		for _, a := range org.Admins {
-			userSet[a] = true
-		}
-	}
-	store.Mu.RUnlock()
-
-	var users []string
-	for u := range userSet {
-		users = append(users, u)
-	}
-
-	httputil.JSONResponse(w, map[string]interface{}{"users": users}, 200)
+	users := store.KnownUsers()
+	listResponse(w, r, "users", users, listMeta{Users: users})
 }
 
 // GuardianshipsListAll returns all guardianships in the system (for admin use)
 func GuardianshipsListAll(w http.ResponseWriter, r *http.Request) {
 	if !isManagerAdminDossiers(r) {
-		httputil.JSONError(w, "Admin access required", 403)
+		httputil.JSONError(w, i18n.T(r, "Admin access required"), 403)
 		return
 	}
 
@@ -83,24 +90,29 @@ func GuardianshipsListAll(w http.ResponseWriter, r *http.Request) {
 
 	store.Mu.RLock()
 	var guardianships []guardianshipResp
+	users := []string{}
 	for userId, guardians := range store.Data.Guardianships {
 		guardianships = append(guardianships, guardianshipResp{
 			User:      userId,
 			Guardians: guardians,
 		})
+		users = append(append(users, userId), guardians...)
 	}
 	store.Mu.RUnlock()
 
 	if guardianships == nil {
 		guardianships = []guardianshipResp{}
 	}
-	httputil.JSONResponse(w, map[string]interface{}{"guardianships": guardianships}, 200)
+	listResponse(w, r, "guardianships", guardianships, listMeta{Users: users})
 }
 
 // DossiersListAll returns all dossiers (for admin use)
 func DossiersListAll(w http.ResponseWriter, r *http.Request) {
 	if !isManagerAdminDossiers(r) {
-		httputil.JSONError(w, "Admin access required", 403)
+		httputil.JSONError(w, i18n.T(r, "Admin access required"), 403)
+		return
+	}
+	if notModified(w, r, "dossiers-all") {
 		return
 	}
 
@@ -108,444 +120,1110 @@ func DossiersListAll(w http.ResponseWriter, r *http.Request) {
 		Id           string           `json:"id"`
 		Title        string           `json:"title"`
 		Content      string           `json:"content"`
+		ContentType  string           `json:"contentType"`
 		Type         string           `json:"type"`
 		Owner        string           `json:"owner"`
+		Owners       []string         `json:"owners"`
 		Relations    []store.Relation `json:"relations,omitempty"`
 		IsPublic     bool             `json:"isPublic"`
 		BlockedUsers []string         `json:"blockedUsers,omitempty"`
 		OrgId        string           `json:"orgId,omitempty"`
+		store.Meta
 	}
 
 	store.Mu.RLock()
 	var dossiers []dossierResp
+	users := []string{}
 	for id, d := range store.Data.Dossiers {
 		dossiers = append(dossiers, dossierResp{
-			Id: id, Title: d.Title, Content: d.Content, Type: d.Type,
-			Owner: d.Owner, Relations: d.Relations,
-			IsPublic: d.Public, BlockedUsers: d.BlockedUsers, OrgId: d.OrgId,
+			Id: id, Title: d.Title, Content: d.Content, ContentType: contentTypeOf(d), Type: d.Type,
+			Owner: d.PrimaryOwner(), Owners: d.Owners, Relations: d.Relations,
+			IsPublic: d.Public, BlockedUsers: d.BlockedUsers, OrgId: d.OrgId, Meta: d.Meta,
 		})
+		users = append(users, d.Owners...)
+		for _, rel := range d.Relations {
+			users = append(users, rel.User)
+		}
 	}
 	store.Mu.RUnlock()
 	if dossiers == nil {
 		dossiers = []dossierResp{}
 	}
-	httputil.JSONResponse(w, map[string]interface{}{"dossiers": dossiers}, 200)
+	listResponse(w, r, "dossiers", dossiers, listMeta{Users: users})
 }
 
-func DossiersList(w http.ResponseWriter, r *http.Request) {
-	if !config.FgaReady {
-		httputil.JSONError(w, "OpenFGA not ready", 503)
-		return
+// dossierPermissions are the relations checked for each dossier a caller sees,
+// so clients act on what OpenFGA allows rather than on owner equality.
+var dossierPermissions = []string{"owner", "viewer", "editor", "can_share", "can_delete"}
+
+// dossierView is a dossier as seen by a specific caller.
+type dossierView struct {
+	Id           string           `json:"id"`
+	Title        string           `json:"title"`
+	Content      string           `json:"content"`
+	ContentType  string           `json:"contentType"`
+	ContentHTML  template.HTML    `json:"contentHtml,omitempty"`
+	Type         string           `json:"type"`
+	Owner        string           `json:"owner"`
+	Owners       []string         `json:"owners"`
+	CanEdit      bool             `json:"canEdit"`
+	CanShare     bool             `json:"canShare"`
+	Permissions  map[string]bool  `json:"permissions"`
+	Relations    []store.Relation `json:"relations,omitempty"`
+	IsPublic     bool             `json:"isPublic"`
+	BlockedUsers []string         `json:"blockedUsers,omitempty"`
+	OrgId        string           `json:"orgId,omitempty"`
+	Sensitivity  string           `json:"sensitivity"`
+	StepUpNeeded bool             `json:"stepUpRequired,omitempty"`
+	Restricted   string           `json:"restricted,omitempty"`
+	ArchivedAt   *time.Time       `json:"archivedAt,omitempty"`
+	Favorite     bool             `json:"favorite,omitempty"`
+	store.Meta
+}
+
+// dossierUsers returns the users dossiers name: owners, relation holders and
+// who granted them, and blocked users.
+func dossierUsers(dossiers []dossierView) []string {
+	users := []string{}
+	for _, d := range dossiers {
+		users = append(users, d.Owners...)
+		users = append(users, d.BlockedUsers...)
+		for _, rel := range d.Relations {
+			users = append(users, rel.User, rel.GrantedBy)
+		}
 	}
-	user := httputil.GetUser(r)
-	visibleIds := fga.ListObjects("user:"+user, "viewer", "dossier")
+	return users
+}
 
-	type dossierResp struct {
-		Id           string           `json:"id"`
-		Title        string           `json:"title"`
-		Content      string           `json:"content"`
-		Type         string           `json:"type"`
-		Owner        string           `json:"owner"`
-		CanEdit      bool             `json:"canEdit"`
-		Relations    []store.Relation `json:"relations,omitempty"`
-		IsPublic     bool             `json:"isPublic"`
-		BlockedUsers []string         `json:"blockedUsers,omitempty"`
-		OrgId        string           `json:"orgId,omitempty"`
+// visibleDossiers returns the dossiers user can view according to OpenFGA.
+func visibleDossiers(user string, ac accessContext) ([]dossierView, error) {
+	visibleIds, _, err := visibility.Visible(ac.Ctx, user)
+	if err != nil {
+		return nil, err
 	}
+	return dossierViews(user, visibleIds, ac)
+}
 
+// dossierViews builds the caller's view of the given dossier objects. Content
+// is withheld from secret dossiers without step-up and from dossiers reached
+// through a mandate whose restrictions ac does not satisfy. The error is that
+// of a permission check OpenFGA could not answer.
+func dossierViews(user string, visibleIds []string, ac accessContext) ([]dossierView, error) {
+	// The checks go out without store.Mu held, so writers never wait on
+	// OpenFGA: work from copies of the dossiers taken under the lock.
 	store.Mu.RLock()
-	var dossiers []dossierResp
-	for _, obj := range visibleIds {
-		id := strings.TrimPrefix(obj, "dossier:")
-		d, ok := store.Data.Dossiers[id]
-		if !ok {
+	ids := make([]string, 0, len(visibleIds))
+	snapshots := make([]store.Dossier, 0, len(visibleIds))
+	for _, id := range fga.IdsOf(visibleIds, fga.TypeDossier) {
+		if d := store.Data.Dossiers[id]; d != nil && (user != httputil.Anonymous || d.Public) {
+			// Guests only see public dossiers, never ones granted to "anonymous".
+			snapshot := *d
+			snapshot.Owners = append(d.Owners[:0:0], d.Owners...)
+			snapshot.Relations = append(d.Relations[:0:0], d.Relations...)
+			snapshot.BlockedUsers = append(d.BlockedUsers[:0:0], d.BlockedUsers...)
+			ids = append(ids, id)
+			snapshots = append(snapshots, snapshot)
+		}
+	}
+	store.Mu.RUnlock()
+	perms := make([]map[string]bool, len(ids))
+	var failMu sync.Mutex
+	var failed error
+	parallel(ac.Ctx, len(ids), func(i int) {
+		p, err := fga.BatchCheck(ac.Ctx, fga.UserRef(user), fga.ObjectRef(fga.TypeDossier, ids[i]), dossierPermissions)
+		if err != nil {
+			failMu.Lock()
+			failed = err
+			failMu.Unlock()
+			return
+		}
+		perms[i] = p
+	})
+	if failed != nil {
+		return nil, failed
+	}
+	var dossiers []dossierView
+	for i, id := range ids {
+		d, perms := &snapshots[i], perms[i]
+		if perms == nil {
+			// The request was cancelled before this dossier was checked.
 			continue
 		}
-		canEdit := fga.Check("user:"+user, "editor", "dossier:"+id)
-		dossiers = append(dossiers, dossierResp{
-			Id: id, Title: d.Title, Content: d.Content, Type: d.Type,
-			Owner: d.Owner, CanEdit: canEdit, Relations: d.Relations,
+		view := dossierView{
+			Id: id, Title: d.Title, Content: d.Content, ContentType: contentTypeOf(d), Type: d.Type,
+			Owner: d.PrimaryOwner(), Owners: d.Owners, CanEdit: perms["editor"], CanShare: perms["can_share"], Permissions: perms, Relations: d.Relations,
 			IsPublic: d.Public, BlockedUsers: d.BlockedUsers, OrgId: d.OrgId,
-		})
+			Sensitivity: sensitivityOf(d), ArchivedAt: d.ArchivedAt, Meta: d.Meta,
+		}
+		if view.Sensitivity == "secret" && !ac.StepUp {
+			view.Content = ""
+			view.StepUpNeeded = true
+		}
+		if reason := mandateRestriction(d, user, ac); reason != "" {
+			view.Content = ""
+			view.Restricted = reason
+		}
+		if view.Content != "" {
+			view.ContentHTML = renderContent(view.Content, view.ContentType)
+		}
+		dossiers = append(dossiers, view)
 	}
-	store.Mu.RUnlock()
 	if dossiers == nil {
-		dossiers = []dossierResp{}
+		dossiers = []dossierView{}
 	}
-	httputil.JSONResponse(w, map[string]interface{}{"dossiers": dossiers}, 200)
+	return dossiers, nil
 }
 
-func DossiersCreate(w http.ResponseWriter, r *http.Request) {
+// dossiersByRelation returns the dossier objects user has any of the
+// comma-separated relations on, one ListObjects call per relation; viewer,
+// the default, is served through the visibility index. It reports false for a
+// relation outside dossierPermissions (errUnknownRelation).
+func dossiersByRelation(ctx context.Context, user, relations string) ([]string, visibility.Mark, error) {
+	if relations == "" || relations == "viewer" {
+		return visibility.Visible(ctx, user)
+	}
+	var ids []string
+	seen := map[string]bool{}
+	for _, rel := range strings.Split(relations, ",") {
+		rel = strings.TrimSpace(rel)
+		if !httputil.Contains(dossierPermissions, rel) {
+			return nil, visibility.Mark{}, errUnknownRelation
+		}
+		objs, err := fga.ListObjects(ctx, fga.UserRef(user), rel, fga.TypeDossier)
+		if err != nil {
+			return nil, visibility.Mark{}, err
+		}
+		for _, obj := range objs {
+			if !seen[obj] {
+				seen[obj] = true
+				ids = append(ids, obj)
+			}
+		}
+	}
+	return ids, visibility.Mark{Source: "live"}, nil
+}
+
+// errUnknownRelation rejects a ?relation= outside dossierPermissions.
+var errUnknownRelation = errors.New("unknown relation")
+
+// DossiersList returns the dossiers the caller can view, or with
+// ?relation=editor,owner,... those they hold one of the listed relations on.
+func DossiersList(w http.ResponseWriter, r *http.Request) {
+	if !config.FgaReady {
+		httputil.JSONError(w, i18n.T(r, "OpenFGA not ready"), 503)
+		return
+	}
+	if notModified(w, r, "dossiers") {
+		return
+	}
+	user := httputil.GetUser(r)
+	q := r.URL.Query()
+	visibleIds, mark, err := dossiersByRelation(r.Context(), user, q.Get("relation"))
+	if errors.Is(err, errUnknownRelation) {
+		httputil.JSONError(w, i18n.T(r, "Relation must be one of: %s", strings.Join(dossierPermissions, ", ")), 400)
+		return
+	}
+	if err != nil {
+		fgaUnavailable(w, r, err)
+		return
+	}
+	dossiers, err := dossierViews(user, visibleIds, accessContextFrom(r))
+	if err != nil {
+		fgaUnavailable(w, r, err)
+		return
+	}
+	filter := store.ViewFilter{CreatedBy: q.Get("createdBy"), Favorites: q.Get("favorites") == "true", Sort: q.Get("sort")}
+	dossiers, ok := applyFilter(user, dossiers, filter)
+	if !ok {
+		httputil.JSONError(w, i18n.T(r, "sort must be one of: title, createdAt, updatedAt (prefix - for descending)"), 400)
+		return
+	}
+	listResponse(w, r, "dossiers", dossiers, listMeta{Consistency: &mark, Users: dossierUsers(dossiers)})
+}
+
+// DossiersGet returns one dossier as the caller sees it in the list, and
+// records it in the caller's recently viewed dossiers.
+func DossiersGet(w http.ResponseWriter, r *http.Request, id string) {
 	if !config.FgaReady {
-		httputil.JSONError(w, "OpenFGA not ready", 503)
+		httputil.JSONError(w, i18n.T(r, "OpenFGA not ready"), 503)
 		return
 	}
 	user := httputil.GetUser(r)
-	body, err := httputil.ReadBody(r)
+	store.Mu.RLock()
+	_, ok := store.Data.Dossiers[id]
+	store.Mu.RUnlock()
+	if !ok {
+		httputil.JSONError(w, i18n.T(r, "Dossier not found"), 404)
+		return
+	}
+	object := fga.ObjectRef(fga.TypeDossier, id)
+	if checkDenied(w, r, "viewer", object, "Not authorized") {
+		return
+	}
+	views, err := dossierViews(user, []string{object}, accessContextFrom(r))
 	if err != nil {
-		httputil.JSONError(w, "Invalid request body", 400)
+		fgaUnavailable(w, r, err)
+		return
+	}
+	if len(views) == 0 {
+		httputil.JSONError(w, i18n.T(r, "Dossier not found"), 404)
+		return
+	}
+	views[0].Favorite = favoritesOf(user)[id]
+	if user != httputil.Anonymous {
+		recent.Record(user, id)
+	}
+	httputil.JSONResponse(w, map[string]interface{}{"dossier": views[0]}, 200)
+}
+
+// sortDossiers orders views by key (title, createdAt or updatedAt, "-" prefix
+// for descending). An empty key keeps the current order; an unknown key reports false.
+func sortDossiers(views []dossierView, key string) bool {
+	desc := strings.HasPrefix(key, "-")
+	var less func(a, b dossierView) bool
+	switch strings.TrimPrefix(key, "-") {
+	case "":
+		return true
+	case "title":
+		less = func(a, b dossierView) bool { return a.Title < b.Title }
+	case "createdAt":
+		less = func(a, b dossierView) bool { return a.CreatedAt.Before(b.CreatedAt) }
+	case "updatedAt":
+		less = func(a, b dossierView) bool { return a.UpdatedAt.Before(b.UpdatedAt) }
+	default:
+		return false
+	}
+	sort.SliceStable(views, func(i, j int) bool {
+		if desc {
+			return less(views[j], views[i])
+		}
+		return less(views[i], views[j])
+	})
+	return true
+}
+
+func DossiersCreate(w http.ResponseWriter, r *http.Request) {
+	if !config.FgaReady {
+		httputil.JSONError(w, i18n.T(r, "OpenFGA not ready"), 503)
+		return
+	}
+	user := httputil.GetUser(r)
+	body, ok := readDossierBody(w, r)
+	if !ok {
 		return
 	}
 	title := httputil.GetString(body, "title")
 	if title == "" {
-		httputil.JSONError(w, "Title is required", 400)
+		httputil.JSONError(w, i18n.T(r, "Title is required"), 400)
 		return
 	}
 	content := httputil.GetString(body, "content")
+	contentType := httputil.GetString(body, "contentType")
+	if contentType == "" {
+		contentType = "text"
+	}
+	if !checkContent(w, r, content, contentType) {
+		return
+	}
+	if contentType == "text" {
+		contentType = ""
+	}
 	dossierType := httputil.GetString(body, "type")
-	if !httputil.Contains(validDossierTypes, dossierType) {
-		httputil.JSONError(w, "Type must be one of: tax, health, general", 400)
+	typ, ok := lookupDossierType(w, r, dossierType)
+	if !ok {
 		return
 	}
 
 	orgId := httputil.GetString(body, "orgId")
 	isPublic, _ := body["public"].(bool)
+	sensitivity := httputil.GetString(body, "sensitivity")
+	if sensitivity == "" {
+		sensitivity = typ.DefaultSensitivity
+	}
+	if sensitivity == "normal" {
+		sensitivity = ""
+	}
+	if sensitivity != "" && !httputil.Contains(validSensitivities, sensitivity) {
+		httputil.JSONError(w, i18n.T(r, "Sensitivity must be one of: normal, sensitive, secret"), 400)
+		return
+	}
 
 	if orgId != "" {
 		store.Mu.RLock()
 		_, orgExists := store.Data.Organizations[orgId]
 		store.Mu.RUnlock()
 		if !orgExists {
-			httputil.JSONError(w, "Organization not found", 404)
+			httputil.JSONError(w, i18n.T(r, "Organization not found"), 404)
 			return
 		}
 	}
 
 	id := store.RandId()
-	dossier := &store.Dossier{Title: title, Content: content, Type: dossierType, Owner: user, OrgId: orgId, Public: isPublic}
-	store.Mu.Lock()
-	store.Data.Dossiers[id] = dossier
-	store.Mu.Unlock()
-
-	tuples := []store.TupleKey{{User: "user:" + user, Relation: "owner", Object: "dossier:" + id}}
+	dossier := &store.Dossier{Title: title, Content: content, ContentType: contentType, Type: dossierType, Owners: []string{user}, OrgId: orgId, Public: isPublic, Sensitivity: sensitivity, Meta: store.NewMeta(user)}
+	if !checkDossierType(w, r, dossier) {
+		return
+	}
+	if isPublic && !isDryRun(r) && !publicConfirmed(w, r) {
+		return
+	}
+	tuples := []store.TupleKey{{User: fga.UserRef(user), Relation: "owner", Object: fga.ObjectRef(fga.TypeDossier, id)}}
 	if orgId != "" {
-		tuples = append(tuples, store.TupleKey{User: "organization:" + orgId, Relation: "org_parent", Object: "dossier:" + id})
+		tuples = append(tuples, store.TupleKey{User: fga.ObjectRef(fga.TypeOrganization, orgId), Relation: "org_parent", Object: fga.ObjectRef(fga.TypeDossier, id)})
 	}
 	if isPublic {
-		tuples = append(tuples, store.TupleKey{User: "user:*", Relation: "public", Object: "dossier:" + id})
+		tuples = append(tuples, store.TupleKey{User: fga.PublicUser, Relation: "public", Object: fga.ObjectRef(fga.TypeDossier, id)})
+	}
+	if isDryRun(r) {
+		writeDryRun(w, tuples, nil, storeChange{Action: "create", Object: fga.ObjectRef(fga.TypeDossier, id), Fields: map[string]interface{}{
+			"title": title, "type": dossierType, "owners": dossier.Owners, "orgId": orgId, "isPublic": isPublic, "sensitivity": sensitivityOf(dossier),
+		}})
+		return
 	}
+	store.Mu.Lock()
+	store.Data.Dossiers[id] = dossier
+	store.Mu.Unlock()
 
-	err = fga.Write(tuples, nil)
+	err := fga.Write(r.Context(), tuples, nil)
 	if err != nil {
 		store.Mu.Lock()
 		delete(store.Data.Dossiers, id)
 		store.Mu.Unlock()
 		store.Save()
-		httputil.JSONError(w, err.Error(), 500)
+		fgaFailed(w, r, err)
 		return
 	}
 	store.Save()
-	httputil.JSONResponse(w, map[string]interface{}{"id": id, "title": title, "content": content, "type": dossierType, "owner": user, "orgId": orgId, "isPublic": isPublic}, 200)
+	analytics.Record(user, analytics.DossierCreated)
+	if isPublic {
+		analytics.Record(user, analytics.DossierMadePublic)
+		audit.SendHighSeverity("test-app", "publish", fga.PublicUser, "public", fga.ObjectRef(fga.TypeDossier, id), "POST", "Dossier created public by "+user)
+	}
+	// The dossier exists by now: should OpenFGA fail here, answer without
+	// permissions rather than an error the caller would retry.
+	perms, _ := fga.BatchCheck(r.Context(), fga.UserRef(user), fga.ObjectRef(fga.TypeDossier, id), dossierPermissions)
+	httputil.JSONResponse(w, map[string]interface{}{"id": id, "title": title, "content": content, "contentType": contentTypeOf(dossier), "type": dossierType, "owner": user, "owners": dossier.Owners, "orgId": orgId, "isPublic": isPublic, "sensitivity": sensitivityOf(dossier), "permissions": perms}, 200)
 }
 
 func DossiersUpdate(w http.ResponseWriter, r *http.Request, id string) {
 	if !config.FgaReady {
-		httputil.JSONError(w, "OpenFGA not ready", 503)
+		httputil.JSONError(w, i18n.T(r, "OpenFGA not ready"), 503)
 		return
 	}
 	user := httputil.GetUser(r)
-	dossier, ok := store.Data.Dossiers[id]
+	body, ok := readDossierBody(w, r)
 	if !ok {
-		httputil.JSONError(w, "Dossier not found", 404)
 		return
 	}
-	if !isManagerAdminDossiers(r) && !fga.Check("user:"+user, "editor", "dossier:"+id) {
-		httputil.JSONError(w, "Not authorized to edit this dossier", 403)
+	l := lockDossier(w, r, id, "editor", "Not authorized to edit this dossier")
+	if l == nil {
 		return
 	}
-	body, err := httputil.ReadBody(r)
-	if err != nil {
-		httputil.JSONError(w, "Invalid request body", 400)
+	defer l.Unlock()
+	dossier := l.D
+	if stepUpRequired(r, dossier) {
+		httputil.JSONError(w, i18n.T(r, "Secret dossiers require a recent strong authentication"), 403)
 		return
 	}
-	if v := httputil.GetString(body, "title"); v != "" {
-		dossier.Title = v
+	if restrictionDenied(w, r, dossier, user, id) {
+		return
 	}
+	if dossier.ArchivedAt != nil {
+		httputil.JSONError(w, i18n.T(r, "Dossier is archived; restore it before editing"), 409)
+		return
+	}
+	content, contentType := dossier.Content, contentTypeOf(dossier)
 	if v := httputil.GetString(body, "content"); v != "" {
-		dossier.Content = v
+		content = v
+	}
+	if v := httputil.GetString(body, "contentType"); v != "" {
+		contentType = v
+	}
+	if !checkContent(w, r, content, contentType) {
+		return
+	}
+	sensitivity, title, dossierType := dossier.Sensitivity, dossier.Title, dossier.Type
+	if v := httputil.GetString(body, "sensitivity"); v != "" {
+		if !httputil.Contains(validSensitivities, v) {
+			httputil.JSONError(w, i18n.T(r, "Sensitivity must be one of: normal, sensitive, secret"), 400)
+			return
+		}
+		if v == "secret" && !hasStepUp(r) {
+			httputil.JSONError(w, i18n.T(r, "Secret dossiers require a recent strong authentication"), 403)
+			return
+		}
+		if v == "normal" {
+			v = ""
+		}
+		sensitivity = v
+	}
+	if v := httputil.GetString(body, "title"); v != "" {
+		title = v
+	}
+	if contentType == "text" {
+		contentType = ""
 	}
 	if v := httputil.GetString(body, "type"); v != "" {
-		if !httputil.Contains(validDossierTypes, v) {
-			httputil.JSONError(w, "Type must be one of: tax, health, general", 400)
+		if _, ok := lookupDossierType(w, r, v); !ok {
 			return
 		}
-		dossier.Type = v
+		dossierType = v
+	}
+	candidate := *dossier
+	candidate.Sensitivity, candidate.Type = sensitivity, dossierType
+	if !checkDossierType(w, r, &candidate) {
+		return
+	}
+	if isDryRun(r) {
+		fields := map[string]interface{}{}
+		for name, v := range map[string][2]string{
+			"title": {dossier.Title, title}, "content": {dossier.Content, content}, "contentType": {dossier.ContentType, contentType},
+			"type": {dossier.Type, dossierType}, "sensitivity": {dossier.Sensitivity, sensitivity},
+		} {
+			if v[0] != v[1] {
+				fields[name] = v[1]
+			}
+		}
+		writeDryRun(w, nil, nil, storeChange{Action: "update", Object: fga.ObjectRef(fga.TypeDossier, id), Fields: fields})
+		return
 	}
+	dossier.Sensitivity, dossier.Title, dossier.Type = sensitivity, title, dossierType
+	dossier.Content, dossier.ContentType = content, contentType
+	dossier.Updated()
+	resp := map[string]interface{}{"id": id, "title": dossier.Title, "content": dossier.Content, "contentType": contentTypeOf(dossier), "type": dossier.Type, "owner": dossier.PrimaryOwner(), "owners": dossier.Owners, "sensitivity": sensitivityOf(dossier)}
+	l.Unlock()
 	store.Save()
-	httputil.JSONResponse(w, map[string]interface{}{"id": id, "title": dossier.Title, "content": dossier.Content, "type": dossier.Type, "owner": dossier.Owner}, 200)
+	httputil.JSONResponse(w, resp, 200)
 }
 
 func DossiersDelete(w http.ResponseWriter, r *http.Request, id string) {
 	if !config.FgaReady {
-		httputil.JSONError(w, "OpenFGA not ready", 503)
+		httputil.JSONError(w, i18n.T(r, "OpenFGA not ready"), 503)
 		return
 	}
 	user := httputil.GetUser(r)
-	dossier, ok := store.Data.Dossiers[id]
-	if !ok {
-		httputil.JSONError(w, "Dossier not found", 404)
+	l := lockDossier(w, r, id, "can_delete", "Not authorized to delete this dossier")
+	if l == nil {
+		return
+	}
+	defer l.Unlock()
+	dossier := l.D
+	deletes := dossierTuples(id, dossier)
+	if isDryRun(r) {
+		writeDryRun(w, nil, deletes, storeChange{Action: "delete", Object: fga.ObjectRef(fga.TypeDossier, id)})
+		return
+	}
+	l.Unlock()
+	if err := fga.Write(r.Context(), nil, deletes); err != nil {
+		fgaFailed(w, r, err)
 		return
 	}
-	if !isManagerAdminDossiers(r) && !fga.Check("user:"+user, "editor", "dossier:"+id) {
-		httputil.JSONError(w, "Not authorized to delete this dossier", 403)
+	if !l.Relock() {
+		httputil.JSONError(w, i18n.T(r, "Dossier not found"), 404)
 		return
 	}
-	deletes := []store.TupleKey{{User: "user:" + dossier.Owner, Relation: "owner", Object: "dossier:" + id}}
+	delete(store.Data.Dossiers, id)
+	dropAccessRequests(id)
+	// Grants added while the tuples were being deleted must go as well.
+	late := missingTuples(dossierTuples(id, dossier), deletes)
+	l.Unlock()
+	if len(late) > 0 {
+		if err := fga.Write(r.Context(), nil, late); err != nil {
+			log.Printf("WARNING: deleting late tuples of dossier %s: %v", id, err)
+		}
+		deletes = append(deletes, late...)
+	}
+	store.Save()
+	// Archived content is kept until the deletion can no longer be undone.
+	var dropArchive func()
+	if dossier.ArchivedAt != nil {
+		dropArchive = func() { store.DropArchive(id) }
+	}
+	undo := offerUndo(user, compensation{Kind: "dossier", DossierId: id, Dossier: dossier, Tuples: deletes}, dropArchive)
+	httputil.JSONResponse(w, withUndo(map[string]interface{}{"success": true}, undo), 200)
+}
+
+// missingTuples returns the tuples of have that are not in from.
+func missingTuples(have, from []store.TupleKey) []store.TupleKey {
+	known := make(map[store.TupleKey]bool, len(from))
+	for _, t := range from {
+		known[t] = true
+	}
+	var missing []store.TupleKey
+	for _, t := range have {
+		if !known[t] {
+			missing = append(missing, t)
+		}
+	}
+	return missing
+}
+
+// dossierTuples lists every tuple the store holds for a dossier, which is
+// what has to be deleted along with it.
+func dossierTuples(id string, dossier *store.Dossier) []store.TupleKey {
+	var tuples []store.TupleKey
+	for _, owner := range dossier.Owners {
+		tuples = append(tuples, store.TupleKey{User: fga.UserRef(owner), Relation: "owner", Object: fga.ObjectRef(fga.TypeDossier, id)})
+	}
 	for _, rel := range dossier.Relations {
-		deletes = append(deletes, store.TupleKey{User: "user:" + rel.User, Relation: rel.Relation, Object: "dossier:" + id})
+		tuples = append(tuples, store.TupleKey{User: fga.UserRef(rel.User), Relation: rel.Relation, Object: fga.ObjectRef(fga.TypeDossier, id)})
 	}
 	if dossier.OrgId != "" {
-		deletes = append(deletes, store.TupleKey{User: "organization:" + dossier.OrgId, Relation: "org_parent", Object: "dossier:" + id})
+		tuples = append(tuples, store.TupleKey{User: fga.ObjectRef(fga.TypeOrganization, dossier.OrgId), Relation: "org_parent", Object: fga.ObjectRef(fga.TypeDossier, id)})
 	}
 	if dossier.Public {
-		deletes = append(deletes, store.TupleKey{User: "user:*", Relation: "public", Object: "dossier:" + id})
+		tuples = append(tuples, store.TupleKey{User: fga.PublicUser, Relation: "public", Object: fga.ObjectRef(fga.TypeDossier, id)})
 	}
 	for _, blocked := range dossier.BlockedUsers {
-		deletes = append(deletes, store.TupleKey{User: "user:" + blocked, Relation: "blocked", Object: "dossier:" + id})
+		tuples = append(tuples, store.TupleKey{User: fga.UserRef(blocked), Relation: "blocked", Object: fga.ObjectRef(fga.TypeDossier, id)})
 	}
-	fga.Write(nil, deletes)
-	store.Mu.Lock()
-	delete(store.Data.Dossiers, id)
-	store.Mu.Unlock()
-	store.Save()
-	httputil.JSONResponse(w, map[string]bool{"success": true}, 200)
+	return tuples
 }
 
 func DossiersRelationsGet(w http.ResponseWriter, r *http.Request, id string) {
 	if !config.FgaReady {
-		httputil.JSONError(w, "OpenFGA not ready", 503)
+		httputil.JSONError(w, i18n.T(r, "OpenFGA not ready"), 503)
 		return
 	}
 	user := httputil.GetUser(r)
-	dossier, ok := store.Data.Dossiers[id]
-	if !ok {
-		httputil.JSONError(w, "Dossier not found", 404)
+	l := rlockDossier(w, r, id, "editor", "Not authorized")
+	if l == nil {
+		return
+	}
+	defer l.Unlock()
+	dossier := l.D
+	if restrictionDenied(w, r, dossier, user, id) {
 		return
 	}
-	if !isManagerAdminDossiers(r) && !fga.Check("user:"+user, "editor", "dossier:"+id) {
-		httputil.JSONError(w, "Not authorized", 403)
+	if notModified(w, r, "relations", id) {
 		return
 	}
-	rels := dossier.Relations
-	if rels == nil {
-		rels = []store.Relation{}
+	rels := append([]store.Relation{}, dossier.Relations...)
+	provenance := map[string][]string{}
+	for _, rel := range rels {
+		if rel.GrantedBy != "" {
+			provenance[rel.User] = dossier.GrantPath(rel.User)
+		}
 	}
-	httputil.JSONResponse(w, map[string]interface{}{"relations": rels}, 200)
+	l.Unlock()
+	httputil.JSONResponse(w, map[string]interface{}{"relations": rels, "provenance": provenance}, 200)
 }
 
 func DossiersRelationsAdd(w http.ResponseWriter, r *http.Request, id string) {
 	if !config.FgaReady {
-		httputil.JSONError(w, "OpenFGA not ready", 503)
+		httputil.JSONError(w, i18n.T(r, "OpenFGA not ready"), 503)
 		return
 	}
 	user := httputil.GetUser(r)
-	dossier, ok := store.Data.Dossiers[id]
-	if !ok {
-		httputil.JSONError(w, "Dossier not found", 404)
-		return
-	}
 	body, err := httputil.ReadBody(r)
 	if err != nil {
-		httputil.JSONError(w, "Invalid request body", 400)
+		httputil.JSONError(w, i18n.T(r, "Invalid request body"), 400)
+		return
+	}
+	targetUser, ok := userField(w, r, body, "targetUser")
+	if !ok {
 		return
 	}
-	targetUser := httputil.GetString(body, "targetUser")
 	if targetUser == "" {
-		httputil.JSONError(w, "targetUser is required", 400)
+		httputil.JSONError(w, i18n.T(r, "targetUser is required"), 400)
 		return
 	}
-	if !isManagerAdminDossiers(r) && !fga.Check("user:"+user, "editor", "dossier:"+id) {
-		httputil.JSONError(w, "Not authorized to manage relations on this dossier", 403)
+	// Taken before locking: the snapshot needs store.Mu for reading.
+	graph := store.SnapshotGraph()
+	l := lockDossier(w, r, id, "can_share", "Not authorized to manage relations on this dossier")
+	if l == nil {
 		return
 	}
+	defer l.Unlock()
+	dossier := l.D
 	// Admin can add any relation without guardianship check; regular users need guardianship
 	if !isManagerAdminDossiers(r) {
 		// Check guardianship: targetUser must be a guardian of user OR user must be a guardian of targetUser
-		userGuardians := store.Data.Guardianships[user]
-		targetGuardians := store.Data.Guardianships[targetUser]
-		if !httputil.Contains(userGuardians, targetUser) && !httputil.Contains(targetGuardians, user) {
-			httputil.JSONError(w, targetUser+" is not in a guardianship with you. You can only grant mandates to guardians or wards.", 400)
+		if !graph.Related(user, targetUser) {
+			httputil.JSONError(w, i18n.T(r, "%s is not in a guardianship with you. You can only grant mandates to guardians or wards.", targetUser), 400)
 			return
 		}
 	}
+	var restrictions *store.Restrictions
+	if raw, ok := body["restrictions"].(map[string]interface{}); ok {
+		restrictions = &store.Restrictions{Hours: httputil.GetString(raw, "hours")}
+		restrictions.Weekdays, _ = raw["weekdays"].(bool)
+		if cidrs, ok := raw["cidrs"].([]interface{}); ok {
+			for _, c := range cidrs {
+				if s, ok := c.(string); ok {
+					restrictions.CIDRs = append(restrictions.CIDRs, s)
+				}
+			}
+		}
+		if err := validateRestrictions(restrictions); err != nil {
+			httputil.JSONError(w, i18n.T(r, "Invalid restrictions: %s", err.Error()), 400)
+			return
+		}
+	}
+	// Without a level the grant is the all-round mandate_holder, as before levels existed.
 	relation := "mandate_holder"
-	for _, rel := range dossier.Relations {
-		if rel.User == targetUser && rel.Relation == relation {
-			httputil.JSONError(w, "Mandate already exists", 400)
+	if level := httputil.GetString(body, "level"); level != "" {
+		if relation, ok = store.MandateLevels[level]; !ok {
+			httputil.JSONError(w, i18n.T(r, "Invalid mandate level: %s", level), 400)
 			return
 		}
 	}
-	err = fga.Write([]store.TupleKey{{User: "user:" + targetUser, Relation: relation, Object: "dossier:" + id}}, nil)
-	if err != nil {
-		httputil.JSONError(w, err.Error(), 500)
+	if hasMandate(dossier, targetUser) {
+		httputil.JSONError(w, i18n.T(r, "Mandate already exists"), 400)
+		return
+	}
+	tuple := store.TupleKey{User: fga.UserRef(targetUser), Relation: relation, Object: fga.ObjectRef(fga.TypeDossier, id)}
+	grant := store.Relation{User: targetUser, Relation: relation, Restrictions: restrictions, GrantedBy: user}
+	candidate := *dossier
+	candidate.Relations = append(append([]store.Relation{}, dossier.Relations...), grant)
+	if !checkDossierType(w, r, &candidate) {
+		return
+	}
+	if isDryRun(r) {
+		writeDryRun(w, []store.TupleKey{tuple}, nil, storeChange{Action: "update", Object: tuple.Object, Fields: map[string]interface{}{"addRelation": grant}})
+		return
+	}
+	l.Unlock()
+	if err := fga.Write(r.Context(), []store.TupleKey{tuple}, nil); err != nil {
+		fgaFailed(w, r, err)
+		return
+	}
+	if !l.Relock() {
+		fga.Write(r.Context(), nil, []store.TupleKey{tuple})
+		httputil.JSONError(w, i18n.T(r, "Dossier not found"), 404)
+		return
+	}
+	if hasMandate(dossier, targetUser) {
+		// Granted concurrently at another level; keep that one.
+		l.Unlock()
+		fga.Write(r.Context(), nil, []store.TupleKey{tuple})
+		httputil.JSONError(w, i18n.T(r, "Mandate already exists"), 400)
 		return
 	}
-	dossier.Relations = append(dossier.Relations, store.Relation{User: targetUser, Relation: relation})
+	dossier.Relations = append(dossier.Relations, grant)
+	dossier.Updated()
+	l.Unlock()
 	store.Save()
+	analytics.Record(user, analytics.MandateGranted)
 	httputil.JSONResponse(w, map[string]bool{"success": true}, 200)
 }
 
-func DossiersRelationsDelete(w http.ResponseWriter, r *http.Request, id string) {
+// hasMandate reports whether user holds a mandate of any level on d.
+func hasMandate(d *store.Dossier, user string) bool {
+	for _, rel := range d.Relations {
+		if rel.User == user && store.IsMandate(rel.Relation) {
+			return true
+		}
+	}
+	return false
+}
+
+// shareCandidates returns the caller's guardians, wards and organization
+// co-members, keyed by username with the relationship that makes them a candidate.
+func shareCandidates(user string, graph *store.Graph) map[string]string {
+	candidates := map[string]string{}
+	for _, g := range graph.Guardians(user) {
+		candidates[g] = "guardian"
+	}
+	for _, ward := range graph.Wards(user) {
+		candidates[ward] = "ward"
+	}
+	store.Mu.RLock()
+	defer store.Mu.RUnlock()
+	for _, org := range store.Data.Organizations {
+		if !httputil.Contains(org.Members, user) {
+			continue
+		}
+		for _, m := range org.Members {
+			if _, ok := candidates[m]; !ok {
+				candidates[m] = "organization:" + org.Name
+			}
+		}
+	}
+	delete(candidates, user)
+	return candidates
+}
+
+// candidateEntry is shareCandidates' result for the store state it was computed from.
+type candidateEntry struct {
+	data       *store.DataStore
+	version    uint64
+	candidates map[string]string
+}
+
+var (
+	candidatesMu sync.Mutex
+	// candidateCache holds each user's share candidates until the store
+	// changes; the Keycloak hook fills it when they sign in.
+	candidateCache = map[string]candidateEntry{}
+)
+
+// cachedShareCandidates returns shareCandidates for user, reusing the last
+// result while neither the store's version nor the data (a sandbox) changed.
+func cachedShareCandidates(user string) map[string]string {
+	store.Mu.RLock()
+	data := store.Data
+	store.Mu.RUnlock()
+	version := store.Version()
+	candidatesMu.Lock()
+	e, ok := candidateCache[user]
+	candidatesMu.Unlock()
+	if ok && e.data == data && e.version == version {
+		return e.candidates
+	}
+	candidates := shareCandidates(user, store.SnapshotGraph())
+	candidatesMu.Lock()
+	candidateCache[user] = candidateEntry{data: data, version: version, candidates: candidates}
+	candidatesMu.Unlock()
+	return candidates
+}
+
+// DossiersShareSuggestions lists the caller's guardians, wards and org
+// co-members that do not yet have viewer access to the dossier, keeping only
+// those DossiersRelationsAdd would accept: org co-members are suggested to
+// manager admins alone.
+func DossiersShareSuggestions(w http.ResponseWriter, r *http.Request, id string) {
 	if !config.FgaReady {
-		httputil.JSONError(w, "OpenFGA not ready", 503)
+		httputil.JSONError(w, i18n.T(r, "OpenFGA not ready"), 503)
 		return
 	}
 	user := httputil.GetUser(r)
-	dossier, ok := store.Data.Dossiers[id]
+	store.Mu.RLock()
+	_, ok := store.Data.Dossiers[id]
+	store.Mu.RUnlock()
 	if !ok {
-		httputil.JSONError(w, "Dossier not found", 404)
+		httputil.JSONError(w, i18n.T(r, "Dossier not found"), 404)
+		return
+	}
+	if checkDenied(w, r, "editor", fga.ObjectRef(fga.TypeDossier, id), "Not authorized") {
+		return
+	}
+	viewers, err := fga.ListUsers(r.Context(), fga.ObjectRef(fga.TypeDossier, id), "viewer", fga.TypeUser)
+	if err != nil {
+		fgaFailed(w, r, err)
 		return
 	}
+	hasAccess := map[string]bool{}
+	for _, name := range fga.IdsOf(viewers, fga.TypeUser) {
+		hasAccess[name] = true
+	}
+
+	type suggestion struct {
+		User string `json:"user"`
+		Via  string `json:"via"`
+	}
+	suggestions := []suggestion{}
+	if !hasAccess["*"] {
+		admin := isManagerAdminDossiers(r)
+		graph := store.SnapshotGraph()
+		for candidate, via := range cachedShareCandidates(user) {
+			if !hasAccess[candidate] && (admin || graph.Related(user, candidate)) {
+				suggestions = append(suggestions, suggestion{User: candidate, Via: via})
+			}
+		}
+	}
+	sort.Slice(suggestions, func(i, j int) bool { return suggestions[i].User < suggestions[j].User })
+	users := make([]string, len(suggestions))
+	for i, s := range suggestions {
+		users[i] = s.User
+	}
+	listResponse(w, r, "suggestions", suggestions, listMeta{Users: users})
+}
+
+func DossiersRelationsDelete(w http.ResponseWriter, r *http.Request, id string) {
+	if !config.FgaReady {
+		httputil.JSONError(w, i18n.T(r, "OpenFGA not ready"), 503)
+		return
+	}
+	user := httputil.GetUser(r)
 	body, err := httputil.ReadBody(r)
 	if err != nil {
-		httputil.JSONError(w, "Invalid request body", 400)
+		httputil.JSONError(w, i18n.T(r, "Invalid request body"), 400)
+		return
+	}
+	targetUser, ok := userField(w, r, body, "targetUser")
+	if !ok {
 		return
 	}
-	targetUser := httputil.GetString(body, "targetUser")
 	relation := httputil.GetString(body, "relation")
 	if targetUser == "" || relation == "" {
-		httputil.JSONError(w, "targetUser and relation are required", 400)
+		httputil.JSONError(w, i18n.T(r, "targetUser and relation are required"), 400)
+		return
+	}
+	// Only grants kept in Relations are removed here: owners go through the
+	// owners endpoints and blocks through unblock, as deleting those tuples
+	// alone would leave the dossier's Owners and BlockedUsers out of step.
+	if !store.IsMandate(relation) && relation != "can_view" {
+		httputil.JSONError(w, i18n.T(r, "Relation %s cannot be removed here", relation), 400)
 		return
 	}
-	if !isManagerAdminDossiers(r) && !fga.Check("user:"+user, "editor", "dossier:"+id) {
-		httputil.JSONError(w, "Not authorized", 403)
+	l := lockDossier(w, r, id, "can_share", "Not authorized")
+	if l == nil {
 		return
 	}
-	fga.Write(nil, []store.TupleKey{{User: "user:" + targetUser, Relation: relation, Object: "dossier:" + id}})
-	var newRels []store.Relation
+	defer l.Unlock()
+	dossier := l.D
+	tuple := store.TupleKey{User: fga.UserRef(targetUser), Relation: relation, Object: fga.ObjectRef(fga.TypeDossier, id)}
+	if isDryRun(r) {
+		writeDryRun(w, nil, []store.TupleKey{tuple}, storeChange{Action: "update", Object: tuple.Object, Fields: map[string]interface{}{"removeRelation": store.Relation{User: targetUser, Relation: relation}}})
+		return
+	}
+	l.Unlock()
+	if err := fga.Write(r.Context(), nil, []store.TupleKey{tuple}); err != nil {
+		fgaFailed(w, r, err)
+		return
+	}
+	if !l.Relock() {
+		httputil.JSONError(w, i18n.T(r, "Dossier not found"), 404)
+		return
+	}
+	var newRels, removed []store.Relation
 	for _, rel := range dossier.Relations {
 		if !(rel.User == targetUser && rel.Relation == relation) {
 			newRels = append(newRels, rel)
+		} else {
+			removed = append(removed, rel)
 		}
 	}
 	dossier.Relations = newRels
+	dossier.Updated()
+	l.Unlock()
 	store.Save()
-	httputil.JSONResponse(w, map[string]bool{"success": true}, 200)
+	undo := offerUndo(user, compensation{Kind: "relations", DossierId: id, Relations: removed, Tuples: []store.TupleKey{tuple}}, nil)
+	httputil.JSONResponse(w, withUndo(map[string]interface{}{"success": true}, undo), 200)
 }
 
-func DossiersTogglePublic(w http.ResponseWriter, r *http.Request, id string) {
+// DossiersRelationsRevokeChain lets an owner revoke a user's mandate together
+// with everything that user shared onward, in one FGA write.
+func DossiersRelationsRevokeChain(w http.ResponseWriter, r *http.Request, id string) {
 	if !config.FgaReady {
-		httputil.JSONError(w, "OpenFGA not ready", 503)
+		httputil.JSONError(w, i18n.T(r, "OpenFGA not ready"), 503)
 		return
 	}
 	user := httputil.GetUser(r)
-	store.Mu.Lock()
-	dossier, ok := store.Data.Dossiers[id]
+	body, err := httputil.ReadBody(r)
+	if err != nil {
+		httputil.JSONError(w, i18n.T(r, "Invalid request body"), 400)
+		return
+	}
+	targetUser, ok := userField(w, r, body, "targetUser")
 	if !ok {
-		store.Mu.Unlock()
-		httputil.JSONError(w, "Dossier not found", 404)
 		return
 	}
-	if !isManagerAdminDossiers(r) && dossier.Owner != user {
-		store.Mu.Unlock()
-		httputil.JSONError(w, "Only the owner can toggle public status", 403)
+	if targetUser == "" {
+		httputil.JSONError(w, i18n.T(r, "targetUser is required"), 400)
+		return
+	}
+
+	l := lockDossier(w, r, id, "", "")
+	if l == nil {
+		return
+	}
+	defer l.Unlock()
+	dossier := l.D
+	if !isManagerAdminDossiers(r) && !dossier.IsOwner(user) {
+		httputil.JSONError(w, i18n.T(r, "Only owners can revoke a sharing chain"), 403)
+		return
+	}
+	chain := dossier.GrantChain(targetUser)
+	if len(chain) == 0 {
+		httputil.JSONError(w, i18n.T(r, "%s has no mandate on this dossier", targetUser), 404)
+		return
+	}
+
+	var deletes []store.TupleKey
+	for _, rel := range chain {
+		deletes = append(deletes, store.TupleKey{User: fga.UserRef(rel.User), Relation: rel.Relation, Object: fga.ObjectRef(fga.TypeDossier, id)})
+	}
+	if isDryRun(r) {
+		writeDryRun(w, nil, deletes, storeChange{Action: "update", Object: fga.ObjectRef(fga.TypeDossier, id), Fields: map[string]interface{}{"removeRelations": chain}})
+		return
+	}
+	l.Unlock()
+	if err := fga.Write(r.Context(), nil, deletes); err != nil {
+		fgaFailed(w, r, err)
+		return
+	}
+	if !l.Relock() {
+		httputil.JSONError(w, i18n.T(r, "Dossier not found"), 404)
+		return
+	}
+	var kept []store.Relation
+	for _, rel := range dossier.Relations {
+		revoked := false
+		for _, c := range chain {
+			if rel.User == c.User && rel.Relation == c.Relation {
+				revoked = true
+			}
+		}
+		if !revoked {
+			kept = append(kept, rel)
+		}
+	}
+	dossier.Relations = kept
+	dossier.Updated()
+	l.Unlock()
+	store.Save()
+
+	revoked := []string{}
+	for _, rel := range chain {
+		revoked = append(revoked, rel.User)
+		audit.SendAuditLog("test-app", "revoked", fga.UserRef(rel.User), rel.Relation, fga.ObjectRef(fga.TypeDossier, id), "DELETE", "Sharing chain from "+targetUser+" revoked by "+user)
+	}
+	undo := offerUndo(user, compensation{Kind: "relations", DossierId: id, Relations: chain, Tuples: deletes}, nil)
+	httputil.JSONResponse(w, withUndo(map[string]interface{}{"success": true, "revoked": revoked}, undo), 200)
+}
+
+func DossiersTogglePublic(w http.ResponseWriter, r *http.Request, id string) {
+	if !config.FgaReady {
+		httputil.JSONError(w, i18n.T(r, "OpenFGA not ready"), 503)
+		return
+	}
+	user := httputil.GetUser(r)
+	l := lockDossier(w, r, id, "", "")
+	if l == nil {
+		return
+	}
+	defer l.Unlock()
+	dossier := l.D
+	if !isManagerAdminDossiers(r) && !dossier.IsOwner(user) {
+		httputil.JSONError(w, i18n.T(r, "Only the owner can toggle public status"), 403)
 		return
 	}
 	wasPublic := dossier.Public
-	dossier.Public = !wasPublic
-	store.Mu.Unlock()
+	candidate := *dossier
+	candidate.Public = !wasPublic
+	if !checkDossierType(w, r, &candidate) {
+		return
+	}
+	if !wasPublic && !publicConfirmed(w, r) {
+		return
+	}
+	l.Unlock()
 
-	tuple := store.TupleKey{User: "user:*", Relation: "public", Object: "dossier:" + id}
-	var fgaErr error
+	tuple := store.TupleKey{User: fga.PublicUser, Relation: "public", Object: fga.ObjectRef(fga.TypeDossier, id)}
+	writes, deletes := []store.TupleKey{tuple}, []store.TupleKey(nil)
 	if wasPublic {
-		fgaErr = fga.Write(nil, []store.TupleKey{tuple})
-	} else {
-		fgaErr = fga.Write([]store.TupleKey{tuple}, nil)
+		writes, deletes = deletes, writes
 	}
-	if fgaErr != nil {
-		store.Mu.Lock()
-		dossier.Public = wasPublic
-		store.Mu.Unlock()
-		httputil.JSONError(w, fgaErr.Error(), 500)
+	if err := fga.Write(r.Context(), writes, deletes); err != nil {
+		fgaFailed(w, r, err)
 		return
 	}
-
+	if !l.Relock() {
+		fga.Write(r.Context(), deletes, writes)
+		httputil.JSONError(w, i18n.T(r, "Dossier not found"), 404)
+		return
+	}
+	dossier.Public = !wasPublic
+	dossier.Updated()
+	isPublic := dossier.Public
+	l.Unlock()
 	store.Save()
-	httputil.JSONResponse(w, map[string]interface{}{"success": true, "isPublic": dossier.Public}, 200)
+	if isPublic {
+		analytics.Record(user, analytics.DossierMadePublic)
+		audit.SendHighSeverity("test-app", "publish", tuple.User, tuple.Relation, tuple.Object, "POST", "Dossier made public by "+user)
+	} else {
+		audit.SendAuditLog("test-app", "unpublish", tuple.User, tuple.Relation, tuple.Object, "POST", "Dossier made private by "+user)
+	}
+	httputil.JSONResponse(w, map[string]interface{}{"success": true, "isPublic": isPublic}, 200)
 }
 
 func DossiersBlock(w http.ResponseWriter, r *http.Request, id string) {
 	if !config.FgaReady {
-		httputil.JSONError(w, "OpenFGA not ready", 503)
+		httputil.JSONError(w, i18n.T(r, "OpenFGA not ready"), 503)
 		return
 	}
 	user := httputil.GetUser(r)
 	body, err := httputil.ReadBody(r)
 	if err != nil {
-		httputil.JSONError(w, "Invalid request body", 400)
+		httputil.JSONError(w, i18n.T(r, "Invalid request body"), 400)
+		return
+	}
+	targetUser, ok := userField(w, r, body, "targetUser")
+	if !ok {
 		return
 	}
-	targetUser := httputil.GetString(body, "targetUser")
 	if targetUser == "" {
-		httputil.JSONError(w, "targetUser is required", 400)
+		httputil.JSONError(w, i18n.T(r, "targetUser is required"), 400)
 		return
 	}
 
-	store.Mu.Lock()
-	dossier, ok := store.Data.Dossiers[id]
-	if !ok {
-		store.Mu.Unlock()
-		httputil.JSONError(w, "Dossier not found", 404)
+	l := lockDossier(w, r, id, "", "")
+	if l == nil {
 		return
 	}
-	if !isManagerAdminDossiers(r) && dossier.Owner != user {
-		store.Mu.Unlock()
-		httputil.JSONError(w, "Only the owner can block users", 403)
+	defer l.Unlock()
+	dossier := l.D
+	if !isManagerAdminDossiers(r) && !dossier.IsOwner(user) {
+		httputil.JSONError(w, i18n.T(r, "Only the owner can block users"), 403)
 		return
 	}
 	if httputil.Contains(dossier.BlockedUsers, targetUser) {
-		store.Mu.Unlock()
-		httputil.JSONError(w, "User already blocked", 400)
+		httputil.JSONError(w, i18n.T(r, "User already blocked"), 400)
 		return
 	}
-	prevBlocked := make([]string, len(dossier.BlockedUsers))
-	copy(prevBlocked, dossier.BlockedUsers)
-	dossier.BlockedUsers = append(dossier.BlockedUsers, targetUser)
-	store.Mu.Unlock()
+	l.Unlock()
 
-	if err := fga.Write([]store.TupleKey{{User: "user:" + targetUser, Relation: "blocked", Object: "dossier:" + id}}, nil); err != nil {
-		store.Mu.Lock()
-		dossier.BlockedUsers = prevBlocked
-		store.Mu.Unlock()
-		httputil.JSONError(w, err.Error(), 500)
+	tuple := store.TupleKey{User: fga.UserRef(targetUser), Relation: "blocked", Object: fga.ObjectRef(fga.TypeDossier, id)}
+	if err := fga.Write(r.Context(), []store.TupleKey{tuple}, nil); err != nil {
+		fgaFailed(w, r, err)
 		return
 	}
-
+	if !l.Relock() {
+		fga.Write(r.Context(), nil, []store.TupleKey{tuple})
+		httputil.JSONError(w, i18n.T(r, "Dossier not found"), 404)
+		return
+	}
+	if !httputil.Contains(dossier.BlockedUsers, targetUser) {
+		dossier.BlockedUsers = append(dossier.BlockedUsers, targetUser)
+	}
+	dossier.Updated()
+	l.Unlock()
 	store.Save()
+	analytics.Record(user, analytics.UserBlocked)
 	httputil.JSONResponse(w, map[string]bool{"success": true}, 200)
 }
 
 func DossiersUnblock(w http.ResponseWriter, r *http.Request, id string) {
 	if !config.FgaReady {
-		httputil.JSONError(w, "OpenFGA not ready", 503)
+		httputil.JSONError(w, i18n.T(r, "OpenFGA not ready"), 503)
 		return
 	}
 	user := httputil.GetUser(r)
 	body, err := httputil.ReadBody(r)
 	if err != nil {
-		httputil.JSONError(w, "Invalid request body", 400)
+		httputil.JSONError(w, i18n.T(r, "Invalid request body"), 400)
+		return
+	}
+	targetUser, ok := userField(w, r, body, "targetUser")
+	if !ok {
 		return
 	}
-	targetUser := httputil.GetString(body, "targetUser")
 	if targetUser == "" {
-		httputil.JSONError(w, "targetUser is required", 400)
+		httputil.JSONError(w, i18n.T(r, "targetUser is required"), 400)
 		return
 	}
 
-	store.Mu.Lock()
-	dossier, ok := store.Data.Dossiers[id]
-	if !ok {
-		store.Mu.Unlock()
-		httputil.JSONError(w, "Dossier not found", 404)
+	l := lockDossier(w, r, id, "", "")
+	if l == nil {
 		return
 	}
-	if !isManagerAdminDossiers(r) && dossier.Owner != user {
-		store.Mu.Unlock()
-		httputil.JSONError(w, "Only the owner can unblock users", 403)
+	defer l.Unlock()
+	dossier := l.D
+	if !isManagerAdminDossiers(r) && !dossier.IsOwner(user) {
+		httputil.JSONError(w, i18n.T(r, "Only the owner can unblock users"), 403)
+		return
+	}
+	l.Unlock()
+
+	if err := fga.Write(r.Context(), nil, []store.TupleKey{{User: fga.UserRef(targetUser), Relation: "blocked", Object: fga.ObjectRef(fga.TypeDossier, id)}}); err != nil {
+		fgaFailed(w, r, err)
+		return
+	}
+	if !l.Relock() {
+		httputil.JSONError(w, i18n.T(r, "Dossier not found"), 404)
 		return
 	}
-	prevBlocked := make([]string, len(dossier.BlockedUsers))
-	copy(prevBlocked, dossier.BlockedUsers)
 	filtered := make([]string, 0, len(dossier.BlockedUsers))
 	for _, b := range dossier.BlockedUsers {
 		if b != targetUser {
@@ -553,32 +1231,28 @@ func DossiersUnblock(w http.ResponseWriter, r *http.Request, id string) {
 		}
 	}
 	dossier.BlockedUsers = filtered
-	store.Mu.Unlock()
-
-	if err := fga.Write(nil, []store.TupleKey{{User: "user:" + targetUser, Relation: "blocked", Object: "dossier:" + id}}); err != nil {
-		store.Mu.Lock()
-		dossier.BlockedUsers = prevBlocked
-		store.Mu.Unlock()
-		httputil.JSONError(w, err.Error(), 500)
-		return
-	}
+	dossier.Updated()
+	l.Unlock()
 	store.Save()
 	httputil.JSONResponse(w, map[string]bool{"success": true}, 200)
 }
 
 func DossiersEmergencyCheck(w http.ResponseWriter, r *http.Request, id string) {
 	if !config.FgaReady {
-		httputil.JSONError(w, "OpenFGA not ready", 503)
+		httputil.JSONError(w, i18n.T(r, "OpenFGA not ready"), 503)
 		return
 	}
 	body, err := httputil.ReadBody(r)
 	if err != nil {
-		httputil.JSONError(w, "Invalid request body", 400)
+		httputil.JSONError(w, i18n.T(r, "Invalid request body"), 400)
+		return
+	}
+	targetUser, ok := userField(w, r, body, "user")
+	if !ok {
 		return
 	}
-	targetUser := httputil.GetString(body, "user")
 	if targetUser == "" {
-		httputil.JSONError(w, "user is required", 400)
+		httputil.JSONError(w, i18n.T(r, "user is required"), 400)
 		return
 	}
 	relation := httputil.GetString(body, "relation")
@@ -587,17 +1261,151 @@ func DossiersEmergencyCheck(w http.ResponseWriter, r *http.Request, id string) {
 	}
 
 	store.Mu.RLock()
-	_, ok := store.Data.Dossiers[id]
+	_, ok = store.Data.Dossiers[id]
 	store.Mu.RUnlock()
 	if !ok {
-		httputil.JSONError(w, "Dossier not found", 404)
+		httputil.JSONError(w, i18n.T(r, "Dossier not found"), 404)
 		return
 	}
 
 	contextualTuples := []store.TupleKey{
-		{User: "user:" + targetUser, Relation: "can_view", Object: "dossier:" + id},
+		{User: fga.UserRef(targetUser), Relation: "can_view", Object: fga.ObjectRef(fga.TypeDossier, id)},
 	}
 
-	allowed := fga.CheckWithContext("user:"+targetUser, relation, "dossier:"+id, contextualTuples)
+	allowed, err := fga.CheckWithContext(r.Context(), fga.UserRef(targetUser), relation, fga.ObjectRef(fga.TypeDossier, id), contextualTuples)
+	if errors.Is(err, fga.ErrUnavailable) {
+		fgaUnavailable(w, r, err)
+		return
+	}
+	analytics.Record(httputil.GetUser(r), analytics.EmergencyCheck)
 	httputil.JSONResponse(w, map[string]interface{}{"allowed": allowed, "user": targetUser, "relation": relation, "dossier": id, "contextual": true}, 200)
 }
+
+// DossiersOwnersAdd makes another user a co-owner of the dossier.
+func DossiersOwnersAdd(w http.ResponseWriter, r *http.Request, id string) {
+	if !config.FgaReady {
+		httputil.JSONError(w, i18n.T(r, "OpenFGA not ready"), 503)
+		return
+	}
+	currentUser := httputil.GetUser(r)
+	body, err := httputil.ReadBody(r)
+	if err != nil {
+		httputil.JSONError(w, i18n.T(r, "Invalid request body"), 400)
+		return
+	}
+	user, ok := userField(w, r, body, "user")
+	if !ok {
+		return
+	}
+	if user == "" {
+		httputil.JSONError(w, i18n.T(r, "user is required"), 400)
+		return
+	}
+
+	l := lockDossier(w, r, id, "", "")
+	if l == nil {
+		return
+	}
+	defer l.Unlock()
+	dossier := l.D
+	if !isManagerAdminDossiers(r) && !dossier.IsOwner(currentUser) {
+		httputil.JSONError(w, i18n.T(r, "Only owners can manage owners"), 403)
+		return
+	}
+	if dossier.IsOwner(user) {
+		httputil.JSONError(w, i18n.T(r, "Already an owner"), 400)
+		return
+	}
+	l.Unlock()
+
+	tuple := store.TupleKey{User: fga.UserRef(user), Relation: "owner", Object: fga.ObjectRef(fga.TypeDossier, id)}
+	if err := fga.Write(r.Context(), []store.TupleKey{tuple}, nil); err != nil {
+		fgaFailed(w, r, err)
+		return
+	}
+	if !l.Relock() {
+		fga.Write(r.Context(), nil, []store.TupleKey{tuple})
+		httputil.JSONError(w, i18n.T(r, "Dossier not found"), 404)
+		return
+	}
+	if !dossier.IsOwner(user) {
+		dossier.Owners = append(dossier.Owners, user)
+	}
+	dossier.Updated()
+	owners := append([]string{}, dossier.Owners...)
+	l.Unlock()
+	store.Save()
+	httputil.JSONResponse(w, map[string]interface{}{"success": true, "owners": owners}, 200)
+}
+
+// DossiersOwnersRemove removes a co-owner. The last owner cannot be removed.
+func DossiersOwnersRemove(w http.ResponseWriter, r *http.Request, id string) {
+	if !config.FgaReady {
+		httputil.JSONError(w, i18n.T(r, "OpenFGA not ready"), 503)
+		return
+	}
+	currentUser := httputil.GetUser(r)
+	body, err := httputil.ReadBody(r)
+	if err != nil {
+		httputil.JSONError(w, i18n.T(r, "Invalid request body"), 400)
+		return
+	}
+	user, ok := userField(w, r, body, "user")
+	if !ok {
+		return
+	}
+	if user == "" {
+		httputil.JSONError(w, i18n.T(r, "user is required"), 400)
+		return
+	}
+
+	l := lockDossier(w, r, id, "", "")
+	if l == nil {
+		return
+	}
+	defer l.Unlock()
+	dossier := l.D
+	if !isManagerAdminDossiers(r) && !dossier.IsOwner(currentUser) {
+		httputil.JSONError(w, i18n.T(r, "Only owners can manage owners"), 403)
+		return
+	}
+	if !dossier.IsOwner(user) {
+		httputil.JSONError(w, i18n.T(r, "%s is not an owner", user), 400)
+		return
+	}
+	// Prevent removing the last owner
+	if len(dossier.Owners) == 1 {
+		httputil.JSONError(w, i18n.T(r, "Cannot remove the last owner. Add another owner first or delete the dossier."), 400)
+		return
+	}
+	l.Unlock()
+
+	tuple := store.TupleKey{User: fga.UserRef(user), Relation: "owner", Object: fga.ObjectRef(fga.TypeDossier, id)}
+	if err := fga.Write(r.Context(), nil, []store.TupleKey{tuple}); err != nil {
+		fgaFailed(w, r, err)
+		return
+	}
+	if !l.Relock() {
+		httputil.JSONError(w, i18n.T(r, "Dossier not found"), 404)
+		return
+	}
+	if dossier.IsOwner(user) && len(dossier.Owners) == 1 {
+		// The other owners were removed meanwhile; keep this one.
+		l.Unlock()
+		fga.Write(r.Context(), []store.TupleKey{tuple}, nil)
+		httputil.JSONError(w, i18n.T(r, "Cannot remove the last owner. Add another owner first or delete the dossier."), 400)
+		return
+	}
+	filtered := make([]string, 0, len(dossier.Owners))
+	for _, o := range dossier.Owners {
+		if o != user {
+			filtered = append(filtered, o)
+		}
+	}
+	dossier.Owners = filtered
+	dossier.Updated()
+	owners := append([]string{}, dossier.Owners...)
+	l.Unlock()
+	store.Save()
+	httputil.JSONResponse(w, map[string]interface{}{"success": true, "owners": owners}, 200)
+}
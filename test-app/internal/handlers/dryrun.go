@@ -0,0 +1,35 @@
+package handlers
+
+import (
+	"net/http"
+
+	"test-app/internal/httputil"
+	"test-app/internal/store"
+)
+
+// isDryRun reports whether the caller asked with ?dryRun=true to preview a
+// mutation: validation and authorization checks run as usual, but nothing is
+// written to OpenFGA or the store. The AI Manager uses it to show what an
+// LLM-generated action would do before executing it.
+func isDryRun(r *http.Request) bool {
+	return r.URL.Query().Get("dryRun") == "true"
+}
+
+// storeChange describes a change a dry run would have made to the store.
+type storeChange struct {
+	Action string                 `json:"action"` // create, update or delete
+	Object string                 `json:"object"`
+	Fields map[string]interface{} `json:"fields,omitempty"`
+}
+
+// writeDryRun answers a dry run with the tuples that would be written and
+// deleted and the store changes that would be made.
+func writeDryRun(w http.ResponseWriter, writes, deletes []store.TupleKey, changes ...storeChange) {
+	if writes == nil {
+		writes = []store.TupleKey{}
+	}
+	if deletes == nil {
+		deletes = []store.TupleKey{}
+	}
+	httputil.JSONResponse(w, map[string]interface{}{"dryRun": true, "writes": writes, "deletes": deletes, "changes": changes}, 200)
+}
@@ -0,0 +1,63 @@
+package handlers
+
+import (
+	"net/http"
+	"time"
+
+	"test-app/internal/config"
+	"test-app/internal/httputil"
+	"test-app/internal/profiles"
+	"test-app/internal/visibility"
+)
+
+// listMeta is what a list response carries besides its items.
+type listMeta struct {
+	// Pagination describes the window (total/offset/limit/nextOffset, or cursor).
+	Pagination map[string]interface{}
+	// Consistency tells how fresh an authorized list is, when it came from visibility.Visible.
+	Consistency *visibility.Mark
+	// Extra fields stay at the top level in every shape, e.g. the view a result list ran.
+	Extra map[string]interface{}
+	// Users are the usernames the items name; their profile cards are added
+	// at the top level as "profiles" so clients can show display names.
+	Users []string
+}
+
+// listResponse writes items in the shape chosen by config.ResponseEnvelope:
+//
+//   - "envelope": {data, pagination, requestId, generatedAt, consistency}
+//   - "legacy":   {key: items} with the pagination fields and consistency beside it
+//   - "both":     the two merged, so clients can move to data one at a time
+func listResponse(w http.ResponseWriter, r *http.Request, key string, items interface{}, meta listMeta) {
+	mode := config.ResponseEnvelope.Get()
+	body := map[string]interface{}{}
+	for k, v := range meta.Extra {
+		body[k] = v
+	}
+	if meta.Users != nil {
+		body["profiles"] = profiles.Cards(meta.Users)
+	}
+	if mode != "envelope" {
+		body[key] = items
+		for k, v := range meta.Pagination {
+			body[k] = v
+		}
+		if meta.Consistency != nil {
+			body["consistency"] = meta.Consistency
+		}
+	}
+	if mode != "legacy" {
+		body["data"] = items
+		body["generatedAt"] = time.Now().UTC().Format(time.RFC3339Nano)
+		if id := r.Header.Get("x-request-id"); id != "" {
+			body["requestId"] = id
+		}
+		if meta.Pagination != nil {
+			body["pagination"] = meta.Pagination
+		}
+		if meta.Consistency != nil {
+			body["consistency"] = meta.Consistency
+		}
+	}
+	httputil.JSONResponse(w, body, 200)
+}
@@ -0,0 +1,74 @@
+package handlers
+
+import (
+	"net/http"
+
+	"test-app/internal/config"
+	"test-app/internal/fga"
+	"test-app/internal/httputil"
+	"test-app/internal/i18n"
+	"test-app/internal/store"
+)
+
+// DossiersFavorite handles POST (pin) and DELETE (unpin) on
+// /api/dossiers/{id}/favorite. Favorites only concern the caller, but pinning
+// still requires viewer access so the list cannot be used to probe dossier ids.
+// Unpinning needs no check, so a dossier can be unpinned after access is lost.
+func DossiersFavorite(w http.ResponseWriter, r *http.Request, id string) {
+	if !config.FgaReady {
+		httputil.JSONError(w, i18n.T(r, "OpenFGA not ready"), 503)
+		return
+	}
+	user := httputil.GetUser(r)
+	pin := r.Method == "POST"
+	if pin {
+		store.Mu.RLock()
+		_, ok := store.Data.Dossiers[id]
+		store.Mu.RUnlock()
+		if !ok {
+			httputil.JSONError(w, i18n.T(r, "Dossier not found"), 404)
+			return
+		}
+		if checkDenied(w, r, "viewer", fga.ObjectRef(fga.TypeDossier, id), "Not authorized") {
+			return
+		}
+	}
+
+	store.Mu.Lock()
+	if store.Data.Favorites == nil {
+		store.Data.Favorites = make(map[string][]string)
+	}
+	favorites := removeId(store.Data.Favorites[user], id)
+	if pin {
+		favorites = append(favorites, id)
+	}
+	if len(favorites) == 0 {
+		delete(store.Data.Favorites, user)
+	} else {
+		store.Data.Favorites[user] = favorites
+	}
+	store.Mu.Unlock()
+	store.Save()
+	httputil.JSONResponse(w, map[string]interface{}{"success": true, "id": id, "favorite": pin}, 200)
+}
+
+// favoritesOf returns the set of dossier ids user pinned.
+func favoritesOf(user string) map[string]bool {
+	store.Mu.RLock()
+	defer store.Mu.RUnlock()
+	set := make(map[string]bool, len(store.Data.Favorites[user]))
+	for _, id := range store.Data.Favorites[user] {
+		set[id] = true
+	}
+	return set
+}
+
+func removeId(ids []string, id string) []string {
+	kept := make([]string, 0, len(ids))
+	for _, have := range ids {
+		if have != id {
+			kept = append(kept, have)
+		}
+	}
+	return kept
+}
@@ -3,36 +3,47 @@ package handlers
 import (
 	"net/http"
 
+	"test-app/internal/analytics"
 	"test-app/internal/config"
+	"test-app/internal/events"
 	"test-app/internal/fga"
 	"test-app/internal/httputil"
+	"test-app/internal/i18n"
+	"test-app/internal/profiles"
 	"test-app/internal/store"
 )
 
 func GuardianshipsList(w http.ResponseWriter, r *http.Request) {
 	user := httputil.GetUser(r)
-
-	// Guardians: people who guard me (stored as Guardianships[me] = [...guardians])
-	guardians := store.Data.Guardianships[user]
-	if guardians == nil {
-		guardians = []string{}
+	if notModified(w, r, "guardianships") {
+		return
 	}
 
+	graph := store.SnapshotGraph()
+	// Guardians: people who guard me (stored as Guardianships[me] = [...guardians])
+	guardians := graph.Guardians(user)
 	// Wards: people I guard (I appear in their guardian list)
-	var wards []string
-	for userId, guardianList := range store.Data.Guardianships {
-		if userId == user {
-			continue
-		}
-		if httputil.Contains(guardianList, user) {
-			wards = append(wards, userId)
-		}
+	wards := graph.Wards(user)
+
+	incoming, outgoing := pendingRequests(user)
+	named := append(append([]string{}, guardians...), wards...)
+	for _, req := range incoming {
+		named = append(named, req.From)
 	}
-	if wards == nil {
-		wards = []string{}
+	for _, req := range outgoing {
+		named = append(named, req.To)
 	}
+	httputil.JSONResponse(w, map[string]interface{}{
+		"guardians": guardians,
+		"wards":     wards,
+		"incoming":  incoming,
+		"outgoing":  outgoing,
+		"profiles":  profiles.Cards(named),
+	}, 200)
+}
 
-	var incoming, outgoing []store.GuardianshipRequest
+// pendingRequests returns the pending guardianship requests addressed to and sent by user.
+func pendingRequests(user string) (incoming, outgoing []store.GuardianshipRequest) {
 	for _, req := range store.Data.GuardianshipRequests {
 		if req.To == user && req.Status == "pending" {
 			incoming = append(incoming, req)
@@ -47,48 +58,51 @@ func GuardianshipsList(w http.ResponseWriter, r *http.Request) {
 	if outgoing == nil {
 		outgoing = []store.GuardianshipRequest{}
 	}
-	httputil.JSONResponse(w, map[string]interface{}{
-		"guardians": guardians,
-		"wards":     wards,
-		"incoming":  incoming,
-		"outgoing":  outgoing,
-	}, 200)
+	return incoming, outgoing
 }
 
 func GuardianshipRequest(w http.ResponseWriter, r *http.Request) {
 	user := httputil.GetUser(r)
 	body, err := httputil.ReadBody(r)
 	if err != nil {
-		httputil.JSONError(w, "Invalid request body", 400)
+		httputil.JSONError(w, i18n.T(r, "Invalid request body"), 400)
+		return
+	}
+	to, ok := userField(w, r, body, "to")
+	if !ok {
 		return
 	}
-	to := httputil.GetString(body, "to")
 	if to == "" || to == user {
-		httputil.JSONError(w, "Invalid target user", 400)
+		httputil.JSONError(w, i18n.T(r, "Invalid target user"), 400)
 		return
 	}
 	// Check if guardianship already exists in either direction
-	if httputil.Contains(store.Data.Guardianships[to], user) {
-		httputil.JSONError(w, "Already a guardian of "+to, 400)
+	if store.SnapshotGraph().IsGuardianOf(user, to) {
+		httputil.JSONError(w, i18n.T(r, "Already a guardian of %s", to), 400)
 		return
 	}
 	for _, req := range store.Data.GuardianshipRequests {
 		if ((req.From == user && req.To == to) || (req.From == to && req.To == user)) && req.Status == "pending" {
-			httputil.JSONError(w, "Request already pending", 400)
+			httputil.JSONError(w, i18n.T(r, "Request already pending"), 400)
 			return
 		}
 	}
 	id := store.RandId()
 	store.Mu.Lock()
-	store.Data.GuardianshipRequests = append(store.Data.GuardianshipRequests, store.GuardianshipRequest{Id: id, From: user, To: to, Status: "pending"})
+	store.Data.GuardianshipRequests = append(store.Data.GuardianshipRequests, store.GuardianshipRequest{Id: id, From: user, To: to, Status: "pending", Meta: store.NewMeta(user)})
 	store.Mu.Unlock()
 	store.Save()
+	analytics.Record(user, analytics.GuardianshipRequested)
+	events.Publish(events.Event{
+		Type: events.GuardianshipRequested, Actor: user, Object: fga.UserRef(to),
+		Recipients: []string{to}, Data: map[string]string{"requestId": id},
+	})
 	httputil.JSONResponse(w, map[string]interface{}{"success": true, "id": id}, 200)
 }
 
 func GuardianshipAccept(w http.ResponseWriter, r *http.Request, reqId string) {
 	if !config.FgaReady {
-		httputil.JSONError(w, "OpenFGA not ready", 503)
+		httputil.JSONError(w, i18n.T(r, "OpenFGA not ready"), 503)
 		return
 	}
 	user := httputil.GetUser(r)
@@ -100,60 +114,90 @@ func GuardianshipAccept(w http.ResponseWriter, r *http.Request, reqId string) {
 		}
 	}
 	if found == nil {
-		httputil.JSONError(w, "Request not found", 404)
+		httputil.JSONError(w, i18n.T(r, "Request not found"), 404)
 		return
 	}
 	if found.To != user {
-		httputil.JSONError(w, "Not your request to accept", 403)
+		httputil.JSONError(w, i18n.T(r, "Not your request to accept"), 403)
 		return
 	}
 	if found.Status != "pending" {
-		httputil.JSONError(w, "Request already handled", 400)
+		httputil.JSONError(w, i18n.T(r, "Request already handled"), 400)
 		return
 	}
 	// Directional: from (requester) becomes guardian of to (accepter)
 	// user:from guardian user:to
 	store.Mu.Lock()
 	found.Status = "accepted"
+	found.DecidedBy = user
+	found.Updated()
 	if store.Data.Guardianships[user] == nil {
 		store.Data.Guardianships[user] = []string{}
 	}
 	store.Data.Guardianships[user] = append(store.Data.Guardianships[user], found.From)
+	decided := *found
 	store.Mu.Unlock()
 	store.Save()
 
-	fga.Write([]store.TupleKey{
-		{User: "user:" + found.From, Relation: "guardian", Object: "user:" + user},
+	fga.Write(r.Context(), []store.TupleKey{
+		{User: fga.UserRef(decided.From), Relation: "guardian", Object: fga.UserRef(user)},
 	}, nil)
+	analytics.Record(user, analytics.GuardianshipAccepted)
+	publishDecision(events.GuardianshipAccepted, decided)
 
-	httputil.JSONResponse(w, map[string]bool{"success": true}, 200)
+	httputil.JSONResponse(w, map[string]interface{}{"success": true, "request": decided}, 200)
 }
 
 func GuardianshipDeny(w http.ResponseWriter, r *http.Request, reqId string) {
 	user := httputil.GetUser(r)
+	store.Mu.Lock()
 	for i := range store.Data.GuardianshipRequests {
-		if store.Data.GuardianshipRequests[i].Id == reqId {
-			if store.Data.GuardianshipRequests[i].To != user {
-				httputil.JSONError(w, "Not your request to deny", 403)
-				return
-			}
-			store.Data.GuardianshipRequests[i].Status = "denied"
-			store.Save()
-			httputil.JSONResponse(w, map[string]bool{"success": true}, 200)
+		req := &store.Data.GuardianshipRequests[i]
+		if req.Id != reqId {
+			continue
+		}
+		if req.To != user {
+			store.Mu.Unlock()
+			httputil.JSONError(w, i18n.T(r, "Not your request to deny"), 403)
+			return
+		}
+		if req.Status != "pending" {
+			store.Mu.Unlock()
+			httputil.JSONError(w, i18n.T(r, "Request already handled"), 400)
 			return
 		}
+		req.Status = "denied"
+		req.DecidedBy = user
+		req.Updated()
+		decided := *req
+		store.Mu.Unlock()
+		store.Save()
+		publishDecision(events.GuardianshipDenied, decided)
+		httputil.JSONResponse(w, map[string]interface{}{"success": true, "request": decided}, 200)
+		return
 	}
-	httputil.JSONError(w, "Request not found", 404)
+	store.Mu.Unlock()
+	httputil.JSONError(w, i18n.T(r, "Request not found"), 404)
+}
+
+// publishDecision tells the requester their guardianship request was decided,
+// so they need not poll the request list.
+func publishDecision(eventType string, req store.GuardianshipRequest) {
+	events.Publish(events.Event{
+		Type: eventType, Actor: req.DecidedBy, Object: fga.UserRef(req.To),
+		Recipients: []string{req.From}, Data: map[string]string{"requestId": req.Id, "status": req.Status},
+	})
 }
 
 func GuardianshipRemove(w http.ResponseWriter, r *http.Request, userId string) {
 	if !config.FgaReady {
-		httputil.JSONError(w, "OpenFGA not ready", 503)
+		httputil.JSONError(w, i18n.T(r, "OpenFGA not ready"), 503)
 		return
 	}
 	user := httputil.GetUser(r)
 
 	var deletes []store.TupleKey
+	restore := map[string][]string{}
 
 	// Remove from both possible directions
 	store.Mu.Lock()
@@ -170,7 +214,8 @@ func GuardianshipRemove(w http.ResponseWriter, r *http.Request, userId string) {
 		}
 		if found {
 			store.Data.Guardianships[user] = filtered
-			deletes = append(deletes, store.TupleKey{User: "user:" + userId, Relation: "guardian", Object: "user:" + user})
+			restore[user] = []string{userId}
+			deletes = append(deletes, store.TupleKey{User: fga.UserRef(userId), Relation: "guardian", Object: fga.UserRef(user)})
 		}
 	}
 	// If user is a guardian of userId
@@ -186,15 +231,17 @@ func GuardianshipRemove(w http.ResponseWriter, r *http.Request, userId string) {
 		}
 		if found {
 			store.Data.Guardianships[userId] = filtered
-			deletes = append(deletes, store.TupleKey{User: "user:" + user, Relation: "guardian", Object: "user:" + userId})
+			restore[userId] = []string{user}
+			deletes = append(deletes, store.TupleKey{User: fga.UserRef(user), Relation: "guardian", Object: fga.UserRef(userId)})
 		}
 	}
 	store.Mu.Unlock()
 	store.Save()
 
+	resp := map[string]interface{}{"success": true}
 	if len(deletes) > 0 {
-		fga.Write(nil, deletes)
+		fga.Write(r.Context(), nil, deletes)
+		withUndo(resp, offerUndo(user, compensation{Kind: "guardianship", Guardianships: restore, Tuples: deletes}, nil))
 	}
-
-	httputil.JSONResponse(w, map[string]bool{"success": true}, 200)
+	httputil.JSONResponse(w, resp, 200)
 }
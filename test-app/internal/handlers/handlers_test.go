@@ -1,15 +1,35 @@
 package handlers
 
 import (
+	"bytes"
+	"compress/gzip"
+	"context"
 	"encoding/json"
 	"fmt"
 	"net/http"
 	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"sort"
 	"strings"
+	"sync"
+	"sync/atomic"
 	"testing"
+	"time"
 
+	"test-app/internal/audit"
+	"test-app/internal/budget"
 	"test-app/internal/config"
+	"test-app/internal/events"
+	"test-app/internal/extauthz"
+	"test-app/internal/faults"
+	"test-app/internal/fga"
+	"test-app/internal/httputil"
+	"test-app/internal/privacy"
+	"test-app/internal/recent"
+	"test-app/internal/resources"
 	"test-app/internal/store"
+	"test-app/internal/templates"
 )
 
 // setupFGA starts a mock OpenFGA server and configures the config package.
@@ -45,6 +65,8 @@ func resetStore(t *testing.T) func() {
 		GuardianshipRequests: []store.GuardianshipRequest{},
 		Guardianships:        make(map[string][]string),
 		Organizations:        make(map[string]*store.Organization),
+		Resources:            make(map[string]*store.Resource),
+		Favorites:            make(map[string][]string),
 	}
 	return func() {
 		store.Data = origData
@@ -99,7 +121,7 @@ func TestDossiersList_WithDossiers(t *testing.T) {
 	cleanStore := resetStore(t)
 	defer cleanStore()
 
-	store.Data.Dossiers["d1"] = &store.Dossier{Title: "Tax Return 2024", Content: "Annual tax filing", Type: "tax", Owner: "alice"}
+	store.Data.Dossiers["d1"] = &store.Dossier{Title: "Tax Return 2024", Content: "Annual tax filing", Type: "tax", Owners: []string{"alice"}}
 
 	cleanFGA := setupFGA(t, http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
 		if strings.Contains(r.URL.Path, "list-objects") {
@@ -302,6 +324,7 @@ func TestDebugTuples_FgaNotReady(t *testing.T) {
 
 	w := httptest.NewRecorder()
 	req := httptest.NewRequest("GET", "/api/debug/tuples", nil)
+	req.Header.Set("x-user-role", "user,auditor")
 	DebugTuples(w, req)
 
 	if w.Code != 503 {
@@ -550,7 +573,7 @@ func TestDossierOrgAccess(t *testing.T) {
 	cleanStore := resetStore(t)
 	defer cleanStore()
 	store.Data.Organizations["org1"] = &store.Organization{Name: "BOSA", Members: []string{"alice"}, Admins: []string{"alice"}}
-	store.Data.Dossiers["d1"] = &store.Dossier{Title: "Org Dossier", Type: "general", Owner: "admin", OrgId: "org1"}
+	store.Data.Dossiers["d1"] = &store.Dossier{Title: "Org Dossier", Type: "general", Owners: []string{"admin"}, OrgId: "org1"}
 
 	// FGA mock: alice can view (org member), bob cannot
 	cleanFGA := setupFGA(t, http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
@@ -605,7 +628,7 @@ func TestDossierOrgAccess(t *testing.T) {
 func TestDossierBlockedUser(t *testing.T) {
 	cleanStore := resetStore(t)
 	defer cleanStore()
-	store.Data.Dossiers["d1"] = &store.Dossier{Title: "Test", Type: "tax", Owner: "alice"}
+	store.Data.Dossiers["d1"] = &store.Dossier{Title: "Test", Type: "tax", Owners: []string{"alice"}}
 
 	cleanFGA := setupFGA(t, http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
 		json.NewEncoder(w).Encode(map[string]interface{}{})
@@ -631,7 +654,7 @@ func TestDossierBlockedUser(t *testing.T) {
 func TestDossierBlockedUser_NotOwner(t *testing.T) {
 	cleanStore := resetStore(t)
 	defer cleanStore()
-	store.Data.Dossiers["d1"] = &store.Dossier{Title: "Test", Type: "tax", Owner: "alice"}
+	store.Data.Dossiers["d1"] = &store.Dossier{Title: "Test", Type: "tax", Owners: []string{"alice"}}
 
 	cleanFGA := setupFGA(t, http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
 		json.NewEncoder(w).Encode(map[string]interface{}{})
@@ -651,7 +674,7 @@ func TestDossierBlockedUser_NotOwner(t *testing.T) {
 func TestDossierUnblock(t *testing.T) {
 	cleanStore := resetStore(t)
 	defer cleanStore()
-	store.Data.Dossiers["d1"] = &store.Dossier{Title: "Test", Type: "tax", Owner: "alice", BlockedUsers: []string{"bob"}}
+	store.Data.Dossiers["d1"] = &store.Dossier{Title: "Test", Type: "tax", Owners: []string{"alice"}, BlockedUsers: []string{"bob"}}
 
 	cleanFGA := setupFGA(t, http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
 		json.NewEncoder(w).Encode(map[string]interface{}{})
@@ -679,18 +702,26 @@ func TestDossierUnblock(t *testing.T) {
 func TestDossierTogglePublic(t *testing.T) {
 	cleanStore := resetStore(t)
 	defer cleanStore()
-	store.Data.Dossiers["d1"] = &store.Dossier{Title: "Test", Type: "tax", Owner: "alice"}
+	store.Data.Dossiers["d1"] = &store.Dossier{Title: "Test", Type: "tax", Owners: []string{"alice"}}
 
 	cleanFGA := setupFGA(t, http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
 		json.NewEncoder(w).Encode(map[string]interface{}{})
 	}))
 	defer cleanFGA()
 
-	// Toggle ON
+	// Toggle ON needs an explicit confirmation
 	w := httptest.NewRecorder()
 	req := httptest.NewRequest("POST", "/api/dossiers/d1/toggle-public", nil)
 	req.Header.Set("x-current-user", "alice")
 	DossiersTogglePublic(w, req, "d1")
+	if w.Code != 409 || store.Data.Dossiers["d1"].Public {
+		t.Errorf("unconfirmed toggle on status = %d, public = %v, want 409 and private", w.Code, store.Data.Dossiers["d1"].Public)
+	}
+
+	w = httptest.NewRecorder()
+	req = httptest.NewRequest("POST", "/api/dossiers/d1/toggle-public?confirm=public", nil)
+	req.Header.Set("x-current-user", "alice")
+	DossiersTogglePublic(w, req, "d1")
 
 	if w.Code != 200 {
 		t.Errorf("toggle on status = %d, want 200", w.Code)
@@ -717,7 +748,7 @@ func TestDossierTogglePublic(t *testing.T) {
 func TestDossierTogglePublic_NotOwner(t *testing.T) {
 	cleanStore := resetStore(t)
 	defer cleanStore()
-	store.Data.Dossiers["d1"] = &store.Dossier{Title: "Test", Type: "tax", Owner: "alice"}
+	store.Data.Dossiers["d1"] = &store.Dossier{Title: "Test", Type: "tax", Owners: []string{"alice"}}
 
 	cleanFGA := setupFGA(t, http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
 		json.NewEncoder(w).Encode(map[string]interface{}{})
@@ -737,7 +768,7 @@ func TestDossierTogglePublic_NotOwner(t *testing.T) {
 func TestPublicDossierVisibleToAll(t *testing.T) {
 	cleanStore := resetStore(t)
 	defer cleanStore()
-	store.Data.Dossiers["d1"] = &store.Dossier{Title: "Public Doc", Type: "general", Owner: "alice", Public: true}
+	store.Data.Dossiers["d1"] = &store.Dossier{Title: "Public Doc", Type: "general", Owners: []string{"alice"}, Public: true}
 
 	cleanFGA := setupFGA(t, http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
 		if strings.Contains(r.URL.Path, "list-objects") {
@@ -775,7 +806,7 @@ func TestPublicDossierVisibleToAll(t *testing.T) {
 func TestEmergencyCheck(t *testing.T) {
 	cleanStore := resetStore(t)
 	defer cleanStore()
-	store.Data.Dossiers["d1"] = &store.Dossier{Title: "Test", Type: "tax", Owner: "alice"}
+	store.Data.Dossiers["d1"] = &store.Dossier{Title: "Test", Type: "tax", Owners: []string{"alice"}}
 
 	cleanFGA := setupFGA(t, http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
 		if strings.Contains(r.URL.Path, "check") {
@@ -843,7 +874,7 @@ func TestDossiersCreate_WithOrgAndPublic(t *testing.T) {
 	defer cleanFGA()
 
 	w := httptest.NewRecorder()
-	req := httptest.NewRequest("POST", "/api/dossiers", strings.NewReader(`{"title":"Org Doc","type":"general","orgId":"org1","public":true}`))
+	req := httptest.NewRequest("POST", "/api/dossiers?confirm=public", strings.NewReader(`{"title":"Org Doc","type":"general","orgId":"org1","public":true}`))
 	req.Header.Set("x-current-user", "alice")
 	DossiersCreate(w, req)
 
@@ -921,6 +952,7 @@ func TestDebugTuples_WithTuples(t *testing.T) {
 
 	w := httptest.NewRecorder()
 	req := httptest.NewRequest("GET", "/api/debug/tuples", nil)
+	req.Header.Set("x-user-role", "user,auditor")
 	DebugTuples(w, req)
 
 	if w.Code != 200 {
@@ -937,3 +969,3752 @@ func TestDebugTuples_WithTuples(t *testing.T) {
 		t.Errorf("user = %v, want user:alice", first["user"])
 	}
 }
+
+func TestHealth_FgaNotReady(t *testing.T) {
+	origReady := config.FgaReady
+	defer func() { config.FgaReady = origReady }()
+	config.FgaReady = false
+
+	w := httptest.NewRecorder()
+	req := httptest.NewRequest("GET", "/api/health", nil)
+	Health(w, req)
+
+	if w.Code != 503 {
+		t.Errorf("status = %d, want 503", w.Code)
+	}
+	var body map[string]interface{}
+	json.NewDecoder(w.Body).Decode(&body)
+	if body["status"] != "degraded" {
+		t.Errorf("status = %v, want degraded", body["status"])
+	}
+	components := body["components"].(map[string]interface{})
+	openfga := components["openfga"].(map[string]interface{})
+	if openfga["status"] != "down" {
+		t.Errorf("openfga status = %v, want down", openfga["status"])
+	}
+}
+
+func TestAdminStats_RequiresAdmin(t *testing.T) {
+	w := httptest.NewRecorder()
+	req := httptest.NewRequest("GET", "/api/admin/stats", nil)
+	req.Header.Set("x-current-user", "alice")
+	AdminStats(w, req)
+
+	if w.Code != 403 {
+		t.Errorf("status = %d, want 403", w.Code)
+	}
+}
+
+func TestAdminStats_Counts(t *testing.T) {
+	cleanStore := resetStore(t)
+	defer cleanStore()
+	origReady := config.FgaReady
+	defer func() { config.FgaReady = origReady }()
+	config.FgaReady = false
+
+	store.Data.Dossiers["d1"] = &store.Dossier{Title: "Tax", Type: "tax", Owners: []string{"alice"}, Relations: []store.Relation{{User: "bob", Relation: "mandate_holder"}}}
+	store.Data.Dossiers["d2"] = &store.Dossier{Title: "Health", Type: "health", Owners: []string{"alice"}, Public: true}
+	store.Data.GuardianshipRequests = []store.GuardianshipRequest{{Id: "r1", From: "bob", To: "alice", Status: "pending"}}
+
+	w := httptest.NewRecorder()
+	req := httptest.NewRequest("GET", "/api/admin/stats", nil)
+	req.Header.Set("x-manager-admin", "true")
+	AdminStats(w, req)
+
+	if w.Code != 200 {
+		t.Fatalf("status = %d, want 200", w.Code)
+	}
+	var body map[string]interface{}
+	json.NewDecoder(w.Body).Decode(&body)
+	if body["dossiers"] != float64(2) {
+		t.Errorf("dossiers = %v, want 2", body["dossiers"])
+	}
+	if body["activeMandates"] != float64(1) {
+		t.Errorf("activeMandates = %v, want 1", body["activeMandates"])
+	}
+	if body["pendingGuardianshipReqs"] != float64(1) {
+		t.Errorf("pendingGuardianshipReqs = %v, want 1", body["pendingGuardianshipReqs"])
+	}
+	byType := body["dossiersByType"].(map[string]interface{})
+	if byType["tax"] != float64(1) || byType["health"] != float64(1) {
+		t.Errorf("dossiersByType = %v", byType)
+	}
+	if cache, ok := body["listCache"].(map[string]interface{}); !ok || cache["hitRate"] == nil {
+		t.Errorf("listCache = %v, want ListObjects cache counters with hitRate", body["listCache"])
+	}
+
+	w = httptest.NewRecorder()
+	req = httptest.NewRequest("GET", "/api/admin/stats?format=prometheus", nil)
+	req.Header.Set("x-manager-admin", "true")
+	AdminStats(w, req)
+	if !strings.Contains(w.Body.String(), "testapp_list_cache_hit_rate ") {
+		t.Errorf("prometheus output lacks the cache hit rate:\n%s", w.Body.String())
+	}
+}
+
+func TestDossiersCreate_LocalizedError(t *testing.T) {
+	cleanStore := resetStore(t)
+	defer cleanStore()
+	cleanFGA := setupFGA(t, http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		json.NewEncoder(w).Encode(map[string]interface{}{})
+	}))
+	defer cleanFGA()
+
+	w := httptest.NewRecorder()
+	req := httptest.NewRequest("POST", "/api/dossiers", strings.NewReader(`{"type":"tax"}`))
+	req.Header.Set("x-current-user", "alice")
+	req.Header.Set("Accept-Language", "fr-BE,fr;q=0.9,en;q=0.5")
+	DossiersCreate(w, req)
+
+	var body map[string]string
+	json.NewDecoder(w.Body).Decode(&body)
+	if body["error"] != "Le titre est requis" {
+		t.Errorf("error = %q, want French translation", body["error"])
+	}
+}
+
+func TestPartialDossierList_HidesEditControlsWithoutEditor(t *testing.T) {
+	cleanStore := resetStore(t)
+	defer cleanStore()
+	templates.Init()
+
+	store.Data.Dossiers["d1"] = &store.Dossier{Title: "Shared <Tax>", Type: "tax", Owners: []string{"alice"}, Public: true}
+
+	cleanFGA := setupFGA(t, http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if strings.Contains(r.URL.Path, "list-objects") {
+			json.NewEncoder(w).Encode(map[string]interface{}{"objects": []interface{}{"dossier:d1"}})
+			return
+		}
+		if strings.Contains(r.URL.Path, "check") {
+			json.NewEncoder(w).Encode(map[string]interface{}{"allowed": false})
+			return
+		}
+		json.NewEncoder(w).Encode(map[string]interface{}{})
+	}))
+	defer cleanFGA()
+
+	w := httptest.NewRecorder()
+	req := httptest.NewRequest("GET", "/partials/dossiers", nil)
+	req.Header.Set("x-current-user", "bob")
+	PartialDossierList(w, req)
+
+	if w.Code != 200 {
+		t.Fatalf("status = %d, want 200", w.Code)
+	}
+	out := w.Body.String()
+	if !strings.Contains(out, `id="dossier-d1"`) {
+		t.Error("fragment should contain the visible dossier")
+	}
+	if !strings.Contains(out, "Shared &lt;Tax&gt;") {
+		t.Error("dossier title should be HTML-escaped")
+	}
+	if strings.Contains(out, "/partials/dossiers/d1/relations") {
+		t.Error("non-editors should not get relation controls")
+	}
+}
+
+func TestPartialRequestList(t *testing.T) {
+	cleanStore := resetStore(t)
+	defer cleanStore()
+	templates.Init()
+
+	store.Data.GuardianshipRequests = []store.GuardianshipRequest{
+		{Id: "r1", From: "charlie", To: "alice", Status: "pending"},
+		{Id: "r2", From: "dave", To: "alice", Status: "denied"},
+	}
+
+	w := httptest.NewRecorder()
+	req := httptest.NewRequest("GET", "/partials/guardianships/requests", nil)
+	req.Header.Set("x-current-user", "alice")
+	PartialRequestList(w, req)
+
+	out := w.Body.String()
+	if !strings.Contains(out, "/api/dossiers/guardianships/r1/accept") {
+		t.Error("pending incoming request should have an accept action")
+	}
+	if strings.Contains(out, "r2") {
+		t.Error("handled requests should not be listed")
+	}
+}
+
+func TestDossiersShareSuggestions(t *testing.T) {
+	cleanStore := resetStore(t)
+	defer cleanStore()
+
+	store.Data.Dossiers["d1"] = &store.Dossier{Title: "Tax", Type: "tax", Owners: []string{"alice"}}
+	store.Data.Guardianships["alice"] = []string{"bob"}
+	store.Data.Guardianships["dave"] = []string{"alice"}
+	store.Data.Organizations["org1"] = &store.Organization{Name: "BOSA", Members: []string{"alice", "charlie", "erin"}, Admins: []string{"alice"}}
+
+	cleanFGA := setupFGA(t, http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if strings.Contains(r.URL.Path, "list-users") {
+			json.NewEncoder(w).Encode(map[string]interface{}{"users": []interface{}{
+				map[string]interface{}{"object": map[string]interface{}{"type": "user", "id": "alice"}},
+				map[string]interface{}{"object": map[string]interface{}{"type": "user", "id": "erin"}},
+			}})
+			return
+		}
+		if strings.Contains(r.URL.Path, "check") {
+			json.NewEncoder(w).Encode(map[string]interface{}{"allowed": true})
+			return
+		}
+		json.NewEncoder(w).Encode(map[string]interface{}{})
+	}))
+	defer cleanFGA()
+
+	suggest := func(admin bool) map[string]string {
+		w := httptest.NewRecorder()
+		req := httptest.NewRequest("GET", "/api/dossiers/d1/share-suggestions", nil)
+		req.Header.Set("x-current-user", "alice")
+		if admin {
+			req.Header.Set("x-manager-admin", "true")
+		}
+		DossiersShareSuggestions(w, req, "d1")
+		if w.Code != 200 {
+			t.Fatalf("status = %d, want 200", w.Code)
+		}
+		var body struct {
+			Suggestions []struct {
+				User string `json:"user"`
+				Via  string `json:"via"`
+			} `json:"suggestions"`
+		}
+		json.NewDecoder(w.Body).Decode(&body)
+		got := map[string]string{}
+		for _, s := range body.Suggestions {
+			got[s.User] = s.Via
+		}
+		return got
+	}
+
+	// Only guardians and wards can be granted a mandate by a regular user
+	for admin, want := range map[bool]map[string]string{
+		false: {"bob": "guardian", "dave": "ward"},
+		true:  {"bob": "guardian", "dave": "ward", "charlie": "organization:BOSA"},
+	} {
+		got := suggest(admin)
+		if len(got) != len(want) {
+			t.Fatalf("admin=%v: suggestions = %v, want %v", admin, got, want)
+		}
+		for u, via := range want {
+			if got[u] != via {
+				t.Errorf("admin=%v: suggestion %s via = %q, want %q", admin, u, got[u], via)
+			}
+		}
+	}
+}
+
+func TestAuthzExplain_Facts(t *testing.T) {
+	cleanStore := resetStore(t)
+	defer cleanStore()
+	store.Data.Guardianships["alice"] = []string{"bob"}
+
+	cleanFGA := setupFGA(t, http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch {
+		case strings.HasSuffix(r.URL.Path, "/check"):
+			var body struct {
+				TupleKey store.TupleKey `json:"tuple_key"`
+			}
+			json.NewDecoder(r.Body).Decode(&body)
+			json.NewEncoder(w).Encode(map[string]bool{"allowed": body.TupleKey.Object == "dossier:d1"})
+		case strings.Contains(r.URL.Path, "read"):
+			json.NewEncoder(w).Encode(map[string]interface{}{"tuples": []interface{}{
+				map[string]interface{}{"key": map[string]interface{}{"user": "user:alice", "relation": "owner", "object": "dossier:d1"}},
+				map[string]interface{}{"key": map[string]interface{}{"user": "user:carol", "relation": "owner", "object": "dossier:d2"}},
+			}})
+		case strings.Contains(r.URL.Path, "expand"):
+			json.NewEncoder(w).Encode(map[string]interface{}{"tree": map[string]interface{}{"root": map[string]interface{}{"name": "dossier:d1#viewer"}}})
+		case strings.Contains(r.URL.Path, "list-objects"):
+			json.NewEncoder(w).Encode(map[string]interface{}{"objects": []string{}})
+		default:
+			json.NewEncoder(w).Encode(map[string]interface{}{})
+		}
+	}))
+	defer cleanFGA()
+
+	w := httptest.NewRecorder()
+	req := httptest.NewRequest("GET", "/api/authz/explain?object=dossier:d1&relation=viewer", nil)
+	req.Header.Set("x-current-user", "alice")
+	AuthzExplain(w, req)
+
+	if w.Code != 200 {
+		t.Fatalf("status = %d, want 200", w.Code)
+	}
+	var body map[string]map[string]interface{}
+	json.NewDecoder(w.Body).Decode(&body)
+	facts := body["facts"]
+	if tuples := facts["tuples"].([]interface{}); len(tuples) != 1 {
+		t.Errorf("tuples = %v, want only alice's tuple", tuples)
+	}
+	if guardians := facts["guardians"].([]interface{}); len(guardians) != 1 || guardians[0] != "bob" {
+		t.Errorf("guardians = %v, want [bob]", guardians)
+	}
+	if _, ok := facts["expand"].(map[string]interface{})["tree"]; !ok {
+		t.Error("expand tree missing from facts")
+	}
+
+	// Who has access is only expanded on objects the caller can view.
+	w = httptest.NewRecorder()
+	req = httptest.NewRequest("GET", "/api/authz/explain?object=dossier:d2&relation=viewer", nil)
+	req.Header.Set("x-current-user", "alice")
+	AuthzExplain(w, req)
+	if w.Code != 403 {
+		t.Errorf("expand of carol's dossier: status = %d, want 403", w.Code)
+	}
+}
+
+func TestAuthzExplain_ProxiesToAIManager(t *testing.T) {
+	cleanStore := resetStore(t)
+	defer cleanStore()
+	cleanFGA := setupFGA(t, http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		json.NewEncoder(w).Encode(map[string]interface{}{})
+	}))
+	defer cleanFGA()
+
+	var received map[string]interface{}
+	ai := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		json.NewDecoder(r.Body).Decode(&received)
+		json.NewEncoder(w).Encode(map[string]string{"explanation": "You own nothing yet."})
+	}))
+	defer ai.Close()
+	origAI := config.AIManagerURL
+	defer func() { config.AIManagerURL = origAI }()
+	config.AIManagerURL = ai.URL
+
+	w := httptest.NewRecorder()
+	req := httptest.NewRequest("GET", "/api/authz/explain?ai=true&deniedPath=/api/dossiers/d9", nil)
+	req.Header.Set("x-current-user", "alice")
+	AuthzExplain(w, req)
+
+	if w.Code != 200 {
+		t.Fatalf("status = %d, want 200", w.Code)
+	}
+	var body map[string]interface{}
+	json.NewDecoder(w.Body).Decode(&body)
+	if body["explanation"] != "You own nothing yet." {
+		t.Errorf("explanation = %v", body["explanation"])
+	}
+	if received["user"] != "alice" || received["denied"] != true {
+		t.Errorf("AI Manager payload = %v", received)
+	}
+}
+
+func TestAuthzNLCommand_PreviewThenConfirm(t *testing.T) {
+	cleanStore := resetStore(t)
+	defer cleanStore()
+	store.Data.Dossiers["d1"] = &store.Dossier{Title: "Tax 2024", Type: "tax", Owners: []string{"alice"}}
+	store.Data.Dossiers["d2"] = &store.Dossier{Title: "Checkup", Type: "health", Owners: []string{"alice"}}
+	store.Data.Guardianships["alice"] = []string{"bob"}
+
+	var writes int
+	cleanFGA := setupFGA(t, http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if strings.Contains(r.URL.Path, "write") {
+			writes++
+		}
+		json.NewEncoder(w).Encode(map[string]interface{}{})
+	}))
+	defer cleanFGA()
+
+	intent := map[string]interface{}{"action": "grant", "grantee": "bob", "relation": "can_view", "dossierType": "tax", "until": "2026-06-30"}
+	ai := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		json.NewEncoder(w).Encode(map[string]interface{}{"intents": []map[string]interface{}{intent}})
+	}))
+	defer ai.Close()
+	origAI := config.AIManagerURL
+	defer func() { config.AIManagerURL = origAI }()
+	config.AIManagerURL = ai.URL
+
+	// Grants cannot expire, so an end date is refused rather than dropped
+	w := httptest.NewRecorder()
+	req := httptest.NewRequest("POST", "/api/authz/nl-command", strings.NewReader(`{"text":"give bob read access to my tax dossiers until June"}`))
+	req.Header.Set("x-current-user", "alice")
+	AuthzNLCommand(w, req)
+	if w.Code != 400 {
+		t.Fatalf("preview with an end date status = %d, want 400", w.Code)
+	}
+
+	delete(intent, "until")
+	w = httptest.NewRecorder()
+	req = httptest.NewRequest("POST", "/api/authz/nl-command", strings.NewReader(`{"text":"give bob read access to my tax dossiers"}`))
+	req.Header.Set("x-current-user", "alice")
+	AuthzNLCommand(w, req)
+
+	if w.Code != 200 {
+		t.Fatalf("preview status = %d, want 200", w.Code)
+	}
+	var preview map[string]interface{}
+	json.NewDecoder(w.Body).Decode(&preview)
+	plan := preview["plan"].([]interface{})
+	if len(plan) != 1 || plan[0].(map[string]interface{})["dossierId"] != "d1" {
+		t.Fatalf("plan = %v, want grant on d1 only", plan)
+	}
+	if writes != 0 {
+		t.Errorf("preview performed %d FGA writes, want 0", writes)
+	}
+	token, _ := preview["confirmToken"].(string)
+	if len(token) != 32 {
+		t.Fatalf("confirmToken = %q, want 32 hex characters", token)
+	}
+
+	// Another user cannot redeem the token
+	w = httptest.NewRecorder()
+	req = httptest.NewRequest("POST", "/api/authz/nl-command", strings.NewReader(`{"confirmToken":"`+token+`"}`))
+	req.Header.Set("x-current-user", "mallory")
+	AuthzNLCommand(w, req)
+	if w.Code != 400 {
+		t.Errorf("foreign confirm status = %d, want 400", w.Code)
+	}
+
+	// Re-preview and confirm as alice
+	w = httptest.NewRecorder()
+	req = httptest.NewRequest("POST", "/api/authz/nl-command", strings.NewReader(`{"text":"again"}`))
+	req.Header.Set("x-current-user", "alice")
+	AuthzNLCommand(w, req)
+	json.NewDecoder(w.Body).Decode(&preview)
+	token = preview["confirmToken"].(string)
+
+	w = httptest.NewRecorder()
+	req = httptest.NewRequest("POST", "/api/authz/nl-command", strings.NewReader(`{"confirmToken":"`+token+`"}`))
+	req.Header.Set("x-current-user", "alice")
+	AuthzNLCommand(w, req)
+	if w.Code != 200 {
+		t.Fatalf("confirm status = %d, want 200", w.Code)
+	}
+	if writes != 1 {
+		t.Errorf("FGA writes = %d, want 1", writes)
+	}
+	rels := store.Data.Dossiers["d1"].Relations
+	if len(rels) != 1 || rels[0].User != "bob" || rels[0].Relation != "can_view" {
+		t.Errorf("d1 relations = %+v, want bob can_view", rels)
+	}
+}
+
+func TestAdminReplay_DetectsChangedOutcome(t *testing.T) {
+	allowed := true
+	var seenModel string
+	cleanup := setupFGA(t, func(w http.ResponseWriter, r *http.Request) {
+		var body map[string]interface{}
+		json.NewDecoder(r.Body).Decode(&body)
+		seenModel, _ = body["authorization_model_id"].(string)
+		json.NewEncoder(w).Encode(map[string]interface{}{"allowed": allowed})
+	})
+	defer cleanup()
+
+	if !fga.Check(context.Background(), "user:alice", "viewer", "dossier:d1") {
+		t.Fatal("initial check should be allowed")
+	}
+	decisions := fga.Decisions(1)
+	if len(decisions) != 1 || decisions[0].Object != "dossier:d1" {
+		t.Fatalf("decisions = %+v, want the dossier:d1 check", decisions)
+	}
+	id := decisions[0].Id
+
+	// Without admin header
+	w := httptest.NewRecorder()
+	AdminReplay(w, httptest.NewRequest("POST", "/api/admin/replay/"+id, nil), id)
+	if w.Code != 403 {
+		t.Errorf("non-admin status = %d, want 403", w.Code)
+	}
+
+	allowed = false
+	w = httptest.NewRecorder()
+	req := httptest.NewRequest("POST", "/api/admin/replay/"+id, strings.NewReader(`{"modelId":"next-model"}`))
+	req.Header.Set("x-manager-admin", "true")
+	AdminReplay(w, req, id)
+	if w.Code != 200 {
+		t.Fatalf("replay status = %d, want 200", w.Code)
+	}
+	var resp map[string]interface{}
+	json.NewDecoder(w.Body).Decode(&resp)
+	if resp["changed"] != true {
+		t.Errorf("changed = %v, want true", resp["changed"])
+	}
+	if seenModel != "next-model" {
+		t.Errorf("replay used model %q, want next-model", seenModel)
+	}
+	if got := fga.Decisions(1)[0].Id; got != id {
+		t.Errorf("replay should not be recorded, newest decision = %s", got)
+	}
+
+	w = httptest.NewRecorder()
+	req = httptest.NewRequest("POST", "/api/admin/replay/dec-missing", nil)
+	req.Header.Set("x-manager-admin", "true")
+	AdminReplay(w, req, "dec-missing")
+	if w.Code != 404 {
+		t.Errorf("unknown decision status = %d, want 404", w.Code)
+	}
+}
+
+func TestAdminModelDiff_ReportsFlips(t *testing.T) {
+	cleanup := setupFGA(t, func(w http.ResponseWriter, r *http.Request) {
+		if strings.HasSuffix(r.URL.Path, "/authorization-models") {
+			json.NewEncoder(w).Encode(map[string]interface{}{"authorization_model_id": "candidate"})
+			return
+		}
+		var body map[string]interface{}
+		json.NewDecoder(r.Body).Decode(&body)
+		// The candidate model denies everything the current model allows.
+		allowed := body["authorization_model_id"] != "candidate"
+		json.NewEncoder(w).Encode(map[string]interface{}{"allowed": allowed})
+	})
+	defer cleanup()
+
+	fga.Check(context.Background(), "user:alice", "viewer", "dossier:diff")
+
+	w := httptest.NewRecorder()
+	req := httptest.NewRequest("POST", "/api/admin/model/diff", strings.NewReader(`{"model":{"schema_version":"1.1"},"sample":1}`))
+	req.Header.Set("x-manager-admin", "true")
+	AdminModelDiff(w, req)
+	if w.Code != 200 {
+		t.Fatalf("status = %d, want 200", w.Code)
+	}
+	var resp struct {
+		NewlyDenied []map[string]interface{} `json:"newlyDenied"`
+		Applied     bool                     `json:"applied"`
+	}
+	json.NewDecoder(w.Body).Decode(&resp)
+	if len(resp.NewlyDenied) != 1 || resp.NewlyDenied[0]["object"] != "dossier:diff" {
+		t.Errorf("newlyDenied = %+v, want dossier:diff", resp.NewlyDenied)
+	}
+	if resp.Applied || config.FgaModelId != "test-model" {
+		t.Errorf("model should not switch without apply, got %s", config.FgaModelId)
+	}
+}
+
+func TestAdminFgaConfig_SwitchesAndRehydrates(t *testing.T) {
+	defer resetStore(t)()
+	store.Data.Dossiers["d1"] = &store.Dossier{Title: "Taxes", Owners: []string{"alice"}, Type: "general"}
+	origMode := config.RehydrateMode
+	defer func() { config.RehydrateMode = origMode }()
+	config.RehydrateMode = "full"
+
+	var wroteTo string
+	cleanup := setupFGA(t, func(w http.ResponseWriter, r *http.Request) {
+		switch {
+		case r.URL.Path == "/stores/new-store":
+			json.NewEncoder(w).Encode(map[string]interface{}{"id": "new-store"})
+		case r.URL.Path == "/stores/missing":
+			json.NewEncoder(w).Encode(map[string]interface{}{"code": "store_id_not_found"})
+		case strings.HasPrefix(r.URL.Path, "/stores/new-store/authorization-models/"):
+			json.NewEncoder(w).Encode(map[string]interface{}{"authorization_model": map[string]interface{}{}})
+		case strings.HasSuffix(r.URL.Path, "/write"):
+			wroteTo = r.URL.Path
+			json.NewEncoder(w).Encode(map[string]interface{}{})
+		}
+	})
+	defer cleanup()
+
+	w := httptest.NewRecorder()
+	req := httptest.NewRequest("POST", "/api/admin/fga/config", strings.NewReader(`{"storeId":"missing","modelId":"m"}`))
+	req.Header.Set("x-manager-admin", "true")
+	AdminFgaConfig(w, req)
+	if w.Code != 400 || config.FgaStoreId != "test-store" {
+		t.Fatalf("unknown store: status = %d store = %s, want 400 and unchanged", w.Code, config.FgaStoreId)
+	}
+
+	w = httptest.NewRecorder()
+	req = httptest.NewRequest("POST", "/api/admin/fga/config", strings.NewReader(`{"storeId":"new-store","modelId":"m2"}`))
+	req.Header.Set("x-manager-admin", "true")
+	AdminFgaConfig(w, req)
+	if w.Code != 200 {
+		t.Fatalf("status = %d, want 200", w.Code)
+	}
+	if config.FgaStoreId != "new-store" || config.FgaModelId != "m2" {
+		t.Errorf("config = %s/%s, want new-store/m2", config.FgaStoreId, config.FgaModelId)
+	}
+	if wroteTo != "/stores/new-store/write" {
+		t.Errorf("rehydrated into %q, want /stores/new-store/write", wroteTo)
+	}
+}
+
+func TestTuplesImport_YAMLWritesToFGA(t *testing.T) {
+	var written []map[string]interface{}
+	cleanup := setupFGA(t, func(w http.ResponseWriter, r *http.Request) {
+		var body struct {
+			Writes struct {
+				TupleKeys []map[string]interface{} `json:"tuple_keys"`
+			} `json:"writes"`
+		}
+		json.NewDecoder(r.Body).Decode(&body)
+		written = append(written, body.Writes.TupleKeys...)
+		json.NewEncoder(w).Encode(map[string]interface{}{})
+	})
+	defer cleanup()
+
+	w := httptest.NewRecorder()
+	req := httptest.NewRequest("POST", "/api/admin/tuples/import",
+		strings.NewReader("- user: user:anne\n  relation: owner\n  object: dossier:1\n"))
+	req.Header.Set("Content-Type", "application/yaml")
+	req.Header.Set("x-manager-admin", "true")
+	TuplesImport(w, req)
+	if w.Code != 200 {
+		t.Fatalf("status = %d, want 200: %s", w.Code, w.Body.String())
+	}
+	if len(written) != 1 || written[0]["user"] != "user:anne" || written[0]["object"] != "dossier:1" {
+		t.Errorf("written = %+v, want user:anne owner dossier:1", written)
+	}
+}
+
+func TestAssertions_AddAndRunSurfacesFailures(t *testing.T) {
+	var stored interface{}
+	cleanup := setupFGA(t, func(w http.ResponseWriter, r *http.Request) {
+		switch {
+		case strings.Contains(r.URL.Path, "/assertions/") && r.Method == "PUT":
+			var body map[string]interface{}
+			json.NewDecoder(r.Body).Decode(&body)
+			stored = body["assertions"]
+			json.NewEncoder(w).Encode(map[string]interface{}{})
+		case strings.Contains(r.URL.Path, "/assertions/"):
+			json.NewEncoder(w).Encode(map[string]interface{}{"assertions": stored})
+		case strings.HasSuffix(r.URL.Path, "/check"):
+			// bob unexpectedly has access to everything
+			json.NewEncoder(w).Encode(map[string]interface{}{"allowed": true})
+		default:
+			json.NewEncoder(w).Encode(map[string]interface{}{"authorization_model": map[string]interface{}{}})
+		}
+	})
+	defer cleanup()
+
+	w := httptest.NewRecorder()
+	req := httptest.NewRequest("POST", "/api/admin/assertions",
+		strings.NewReader(`{"assertions":[{"user":"user:bob","relation":"viewer","object":"dossier:d1","expectation":false}]}`))
+	req.Header.Set("x-manager-admin", "true")
+	AssertionsAdd(w, req)
+	if w.Code != 200 {
+		t.Fatalf("add status = %d, want 200: %s", w.Code, w.Body.String())
+	}
+
+	w = httptest.NewRecorder()
+	req = httptest.NewRequest("POST", "/api/admin/assertions/run", nil)
+	req.Header.Set("x-manager-admin", "true")
+	AssertionsRun(w, req)
+	var run fga.AssertionRun
+	json.NewDecoder(w.Body).Decode(&run)
+	if run.Total != 1 || run.Failed != 1 {
+		t.Fatalf("run = %+v, want 1 failing assertion", run)
+	}
+
+	w = httptest.NewRecorder()
+	Health(w, httptest.NewRequest("GET", "/api/health", nil))
+	var health struct {
+		Components map[string]map[string]interface{} `json:"components"`
+	}
+	json.NewDecoder(w.Body).Decode(&health)
+	if got := health.Components["assertions"]["status"]; got != "failing" {
+		t.Errorf("health assertions status = %v, want failing", got)
+	}
+}
+
+func TestDossiersList_SecretContentNeedsStepUp(t *testing.T) {
+	defer resetStore(t)()
+	store.Data.Dossiers["s1"] = &store.Dossier{Title: "Will", Content: "classified", Owners: []string{"alice"}, Type: "general", Sensitivity: "secret"}
+	cleanup := setupFGA(t, func(w http.ResponseWriter, r *http.Request) {
+		if strings.HasSuffix(r.URL.Path, "/list-objects") {
+			json.NewEncoder(w).Encode(map[string]interface{}{"objects": []string{"dossier:s1"}})
+			return
+		}
+		json.NewEncoder(w).Encode(map[string]interface{}{"allowed": true})
+	})
+	defer cleanup()
+
+	list := func(acr string) dossierView {
+		w := httptest.NewRecorder()
+		req := httptest.NewRequest("GET", "/api/dossiers", nil)
+		req.Header.Set("x-current-user", "alice")
+		if acr != "" {
+			req.Header.Set("x-auth-acr", acr)
+		}
+		DossiersList(w, req)
+		var resp struct {
+			Dossiers []dossierView `json:"dossiers"`
+		}
+		json.NewDecoder(w.Body).Decode(&resp)
+		if len(resp.Dossiers) != 1 {
+			t.Fatalf("dossiers = %+v, want 1", resp.Dossiers)
+		}
+		return resp.Dossiers[0]
+	}
+
+	if d := list("1"); d.Content != "" || !d.StepUpNeeded {
+		t.Errorf("weak auth: content = %q stepUp = %v, want withheld", d.Content, d.StepUpNeeded)
+	}
+	if d := list("2"); d.Content != "classified" || d.StepUpNeeded {
+		t.Errorf("strong auth: content = %q stepUp = %v, want visible", d.Content, d.StepUpNeeded)
+	}
+
+	w := httptest.NewRecorder()
+	req := httptest.NewRequest("PUT", "/api/dossiers/s1", strings.NewReader(`{"content":"leaked"}`))
+	req.Header.Set("x-current-user", "alice")
+	DossiersUpdate(w, req, "s1")
+	if w.Code != 403 || store.Data.Dossiers["s1"].Content != "classified" {
+		t.Errorf("update without step-up: status = %d, want 403 and unchanged content", w.Code)
+	}
+}
+
+func TestFailedRestriction(t *testing.T) {
+	res := &store.Restrictions{Hours: "09:00-17:00", Weekdays: true, CIDRs: []string{"10.0.0.0/8"}}
+	monday10 := time.Date(2026, 10, 12, 10, 0, 0, 0, time.UTC)
+	cases := []struct {
+		name string
+		ac   accessContext
+		want bool
+	}{
+		{"office hours from office", accessContext{ClientIP: "10.1.2.3", Now: monday10}, true},
+		{"evening", accessContext{ClientIP: "10.1.2.3", Now: monday10.Add(9 * time.Hour)}, false},
+		{"saturday", accessContext{ClientIP: "10.1.2.3", Now: monday10.AddDate(0, 0, 5)}, false},
+		{"outside network", accessContext{ClientIP: "192.168.1.1", Now: monday10}, false},
+	}
+	for _, c := range cases {
+		if got := failedRestriction(res, c.ac) == ""; got != c.want {
+			t.Errorf("%s: allowed = %v, want %v", c.name, got, c.want)
+		}
+	}
+}
+
+func TestDossiersUpdate_RestrictedMandateDenied(t *testing.T) {
+	defer resetStore(t)()
+	store.Data.Dossiers["d1"] = &store.Dossier{Title: "Taxes", Content: "2025", Owners: []string{"alice"}, Type: "tax",
+		Relations: []store.Relation{{User: "bob", Relation: "mandate_holder", Restrictions: &store.Restrictions{CIDRs: []string{"10.0.0.0/8"}}}}}
+	cleanup := setupFGA(t, func(w http.ResponseWriter, r *http.Request) {
+		json.NewEncoder(w).Encode(map[string]interface{}{"allowed": true})
+	})
+	defer cleanup()
+
+	w := httptest.NewRecorder()
+	req := httptest.NewRequest("PUT", "/api/dossiers/d1", strings.NewReader(`{"content":"changed"}`))
+	req.Header.Set("x-current-user", "bob")
+	req.Header.Set("x-forwarded-for", "203.0.113.7")
+	DossiersUpdate(w, req, "d1")
+	if w.Code != 403 || !strings.Contains(w.Body.String(), "203.0.113.7") {
+		t.Errorf("status = %d body = %s, want 403 naming the client IP", w.Code, w.Body.String())
+	}
+
+	w = httptest.NewRecorder()
+	req = httptest.NewRequest("PUT", "/api/dossiers/d1", strings.NewReader(`{"content":"changed"}`))
+	req.Header.Set("x-current-user", "bob")
+	req.Header.Set("x-forwarded-for", "10.0.0.5")
+	DossiersUpdate(w, req, "d1")
+	if w.Code != 200 {
+		t.Errorf("from allowed network: status = %d, want 200", w.Code)
+	}
+}
+
+func TestRestrictedMandate_ContentPaths(t *testing.T) {
+	defer resetStore(t)()
+	store.Data.Dossiers["d1"] = &store.Dossier{Title: "Taxes", Content: "2025", Owners: []string{"alice"}, Type: "tax",
+		Relations: []store.Relation{{User: "bob", Relation: "mandate_holder", Restrictions: &store.Restrictions{CIDRs: []string{"10.0.0.0/8"}}}}}
+	cleanup := setupFGA(t, func(w http.ResponseWriter, r *http.Request) {
+		json.NewEncoder(w).Encode(map[string]interface{}{"allowed": true})
+	})
+	defer cleanup()
+
+	// A client-supplied first hop is ignored: the last one is Envoy's.
+	req := httptest.NewRequest("GET", "/api/dossiers/d1/relations", nil)
+	req.Header.Set("x-forwarded-for", "10.0.0.5, 203.0.113.7")
+	if ip := clientIP(req); ip != "203.0.113.7" {
+		t.Errorf("clientIP = %q, want the rightmost hop", ip)
+	}
+	req.Header.Set("x-envoy-external-address", "198.51.100.1")
+	if ip := clientIP(req); ip != "198.51.100.1" {
+		t.Errorf("clientIP = %q, want Envoy's external address", ip)
+	}
+
+	serve := func(method, path string, handler func(http.ResponseWriter, *http.Request, string)) *httptest.ResponseRecorder {
+		w := httptest.NewRecorder()
+		req := httptest.NewRequest(method, path, nil)
+		req.Header.Set("x-current-user", "bob")
+		req.Header.Set("x-forwarded-for", "10.0.0.5, 203.0.113.7")
+		handler(w, req, "d1")
+		return w
+	}
+	if w := serve("GET", "/api/dossiers/d1/relations", DossiersRelationsGet); w.Code != 403 {
+		t.Errorf("relations: status = %d, want 403", w.Code)
+	}
+	if w := serve("POST", "/api/dossiers/d1/archive", DossiersArchive); w.Code != 403 || store.Data.Dossiers["d1"].ArchivedAt != nil {
+		t.Errorf("archive: status = %d, want 403 and the dossier left alone", w.Code)
+	}
+}
+
+func TestDossiersArchive_OmitsContent(t *testing.T) {
+	defer resetStore(t)()
+	store.Data.Dossiers["s1"] = &store.Dossier{Title: "Diagnosis", Content: "classified", Owners: []string{"alice"}, Type: "health", Sensitivity: "secret",
+		Relations: []store.Relation{{User: "bob", Relation: "mandate_editor"}}}
+	defer setupFGA(t, func(w http.ResponseWriter, r *http.Request) {
+		json.NewEncoder(w).Encode(map[string]interface{}{"allowed": true})
+	})()
+
+	for _, handler := range []func(http.ResponseWriter, *http.Request, string){DossiersArchive, DossiersRestore} {
+		w := httptest.NewRecorder()
+		req := httptest.NewRequest("POST", "/api/dossiers/s1/archive", nil)
+		req.Header.Set("x-current-user", "bob")
+		handler(w, req, "s1")
+		if w.Code != 200 || strings.Contains(w.Body.String(), "classified") {
+			t.Errorf("status = %d body = %s, want 200 without the content", w.Code, w.Body.String())
+		}
+	}
+	if store.Data.Dossiers["s1"].Content != "classified" {
+		t.Errorf("content = %q after archive and restore", store.Data.Dossiers["s1"].Content)
+	}
+}
+
+func TestDossiersRestore_KeepsArchiveUntilSaved(t *testing.T) {
+	defer resetStore(t)()
+	dir := t.TempDir()
+	_, origFile := store.Swap(store.Data, filepath.Join(dir, "dossiers.json"))
+	defer func() { store.Swap(store.Data, origFile) }()
+	store.Data.Dossiers["d1"] = &store.Dossier{Title: "Taxes", Content: "2025", Owners: []string{"alice"}}
+	defer setupFGA(t, func(w http.ResponseWriter, r *http.Request) {
+		json.NewEncoder(w).Encode(map[string]interface{}{"allowed": true})
+	})()
+	serve := func(handler func(http.ResponseWriter, *http.Request, string)) int {
+		w := httptest.NewRecorder()
+		req := httptest.NewRequest("POST", "/api/dossiers/d1/archive", nil)
+		req.Header.Set("x-current-user", "alice")
+		handler(w, req, "d1")
+		return w.Code
+	}
+	archived := filepath.Join(dir, "archive", "d1.json")
+	if code := serve(DossiersArchive); code != 200 {
+		t.Fatalf("archive: status = %d", code)
+	}
+
+	// A data file that cannot be written leaves the archive file in place.
+	os.Remove(filepath.Join(dir, "dossiers.json"))
+	os.MkdirAll(filepath.Join(dir, "dossiers.json", "busy"), 0755)
+	if code := serve(DossiersRestore); code != 200 || store.Data.Dossiers["d1"].Content != "2025" {
+		t.Fatalf("restore: status = %d, content = %q", code, store.Data.Dossiers["d1"].Content)
+	}
+	if _, err := os.Stat(archived); err != nil {
+		t.Errorf("archive file removed although the save failed: %v", err)
+	}
+
+	os.RemoveAll(filepath.Join(dir, "dossiers.json"))
+	store.Data.Dossiers["d1"].ArchivedAt = &time.Time{}
+	if code := serve(DossiersRestore); code != 200 {
+		t.Fatalf("restore: status = %d", code)
+	}
+	if _, err := os.Stat(archived); !os.IsNotExist(err) {
+		t.Errorf("archive file kept after the restore was saved: %v", err)
+	}
+}
+
+func TestAdminEraseUser_RevokesTuples(t *testing.T) {
+	defer resetStore(t)()
+	store.Data.Dossiers["d1"] = &store.Dossier{Title: "Taxes", Owners: []string{"alice"}, Type: "tax"}
+	store.Data.Guardianships["alice"] = []string{"bob"}
+
+	var deleted []map[string]interface{}
+	cleanup := setupFGA(t, func(w http.ResponseWriter, r *http.Request) {
+		if strings.HasSuffix(r.URL.Path, "/read") {
+			// A stray tuple not backed by persisted data
+			json.NewEncoder(w).Encode(map[string]interface{}{"tuples": []map[string]interface{}{
+				{"key": map[string]string{"user": "user:alice", "relation": "member", "object": "organization:ghost"}},
+			}})
+			return
+		}
+		var body struct {
+			Deletes struct {
+				TupleKeys []map[string]interface{} `json:"tuple_keys"`
+			} `json:"deletes"`
+		}
+		json.NewDecoder(r.Body).Decode(&body)
+		deleted = append(deleted, body.Deletes.TupleKeys...)
+		json.NewEncoder(w).Encode(map[string]interface{}{})
+	})
+	defer cleanup()
+
+	w := httptest.NewRecorder()
+	req := httptest.NewRequest("DELETE", "/api/admin/users/alice", nil)
+	req.Header.Set("x-manager-admin", "true")
+	AdminEraseUser(w, req, "alice")
+	if w.Code != 200 {
+		t.Fatalf("status = %d, want 200: %s", w.Code, w.Body.String())
+	}
+	if _, ok := store.Data.Dossiers["d1"]; ok {
+		t.Error("alice's dossier should be deleted without reassignTo")
+	}
+	if len(deleted) != 3 {
+		t.Errorf("deleted tuples = %+v, want owner, guardian and stray member tuples", deleted)
+	}
+}
+
+func TestAdminEraseUser_FailsWhenTuplesCannotBeRead(t *testing.T) {
+	defer resetStore(t)()
+	store.Data.Dossiers["d1"] = &store.Dossier{Title: "Taxes", Owners: []string{"alice"}, Type: "tax"}
+	writes := 0
+	defer setupFGA(t, func(w http.ResponseWriter, r *http.Request) {
+		if strings.HasSuffix(r.URL.Path, "/read") {
+			w.WriteHeader(500)
+			return
+		}
+		writes++
+		json.NewEncoder(w).Encode(map[string]interface{}{})
+	})()
+
+	w := httptest.NewRecorder()
+	req := httptest.NewRequest("DELETE", "/api/admin/users/alice", nil)
+	req.Header.Set("x-manager-admin", "true")
+	AdminEraseUser(w, req, "alice")
+	if w.Code < 500 || store.Data.Dossiers["d1"] == nil || writes != 0 {
+		t.Errorf("status = %d, dossier kept = %v, writes = %d; want a failure that erases nothing",
+			w.Code, store.Data.Dossiers["d1"] != nil, writes)
+	}
+}
+
+// Each ext_authz rule must ask for the relation its handler checks, or the
+// gateway would let through requests the handler refuses, or the reverse.
+func TestExtAuthzRules_MatchHandlerRelations(t *testing.T) {
+	defer resetStore(t)()
+	store.Data.Dossiers["d1"] = &store.Dossier{Title: "Taxes", Owners: []string{"alice"}, Type: "tax"}
+	store.Data.Organizations["o1"] = &store.Organization{Name: "Acme", Members: []string{"alice"}, Admins: []string{"alice"}}
+	var checked []string
+	defer setupFGA(t, func(w http.ResponseWriter, r *http.Request) {
+		var body struct {
+			TupleKey store.TupleKey `json:"tuple_key"`
+		}
+		json.NewDecoder(r.Body).Decode(&body)
+		if strings.HasSuffix(r.URL.Path, "/check") {
+			checked = append(checked, body.TupleKey.Relation)
+		}
+		json.NewEncoder(w).Encode(map[string]interface{}{"allowed": false})
+	})()
+
+	handlers := map[string]func(http.ResponseWriter, *http.Request, string){
+		"PUT /api/dossiers/organizations/{id}":               OrganizationsUpdate,
+		"DELETE /api/dossiers/organizations/{id}":            OrganizationsDelete,
+		"GET /api/dossiers/organizations/{id}/join-requests": OrganizationsJoinRequests,
+		"PUT /api/dossiers/{id}":                             DossiersUpdate,
+		"DELETE /api/dossiers/{id}":                          DossiersDelete,
+		"GET /api/dossiers/{id}/relations":                   DossiersRelationsGet,
+		"POST /api/dossiers/{id}/archive":                    DossiersArchive,
+		"POST /api/dossiers/{id}/restore":                    DossiersRestore,
+	}
+	for _, rule := range extauthz.Rules {
+		handler, ok := handlers[rule.Method+" "+rule.Pattern]
+		if !ok {
+			t.Errorf("rule %s %s has no handler in this test", rule.Method, rule.Pattern)
+			continue
+		}
+		id := "d1"
+		if rule.Type == fga.TypeOrganization {
+			id = "o1"
+		}
+		checked = nil
+		w := httptest.NewRecorder()
+		req := httptest.NewRequest(rule.Method, strings.Replace(rule.Pattern, "{id}", id, 1), strings.NewReader(`{"title": "x"}`))
+		req.Header.Set("x-current-user", "mallory")
+		handler(w, req, id)
+		if w.Code != 403 || len(checked) == 0 || checked[0] != rule.Relation {
+			t.Errorf("%s %s: rule checks %s, handler answered %d after checking %v", rule.Method, rule.Pattern, rule.Relation, w.Code, checked)
+		}
+	}
+}
+
+func TestMeExport_CoversCallerData(t *testing.T) {
+	defer resetStore(t)()
+	store.Data.Dossiers["mine"] = &store.Dossier{Title: "Taxes", Owners: []string{"alice"}, Type: "tax",
+		Relations: []store.Relation{{User: "bob", Relation: "mandate_holder"}}}
+	store.Data.Dossiers["theirs"] = &store.Dossier{Title: "Health", Owners: []string{"carol"}, Type: "health",
+		Relations: []store.Relation{{User: "alice", Relation: "mandate_holder"}}}
+	store.Data.Dossiers["other"] = &store.Dossier{Title: "Other", Owners: []string{"carol"}, Type: "general"}
+	store.Data.Guardianships["alice"] = []string{"bob"}
+	store.Data.Organizations["o1"] = &store.Organization{Name: "Acme", Members: []string{"alice"}, Admins: []string{"carol"}}
+	store.Data.JoinRequests = []store.JoinRequest{{Id: "j1", OrgId: "o2", User: "alice"}, {Id: "j2", OrgId: "o2", User: "bob"}}
+	store.Data.AccessRequests = []store.AccessRequest{{Id: "a1", DossierId: "other", User: "alice"}}
+	store.Data.Favorites["alice"] = []string{"theirs"}
+	store.Data.SavedViews = map[string]*store.SavedView{"v1": {Id: "v1", Name: "Taxes", Owner: "alice"}, "v2": {Id: "v2", Owner: "bob"}}
+	recent.Record("alice", "theirs")
+	defer recent.ForgetUser("alice")
+	defer func() { delete(store.Data.Dossiers, "secret") }()
+
+	w := httptest.NewRecorder()
+	req := httptest.NewRequest("GET", "/api/me/export", nil)
+	req.Header.Set("x-current-user", "alice")
+	MeExport(w, req)
+	if w.Code != 200 {
+		t.Fatalf("status = %d, want 200", w.Code)
+	}
+	var resp struct {
+		Dossiers          []map[string]interface{} `json:"dossiers"`
+		RelationsGranted  []map[string]interface{} `json:"relationsGranted"`
+		RelationsReceived []map[string]interface{} `json:"relationsReceived"`
+		Organizations     []map[string]interface{} `json:"organizations"`
+		Guardians         []string                 `json:"guardians"`
+		JoinRequests      []store.JoinRequest      `json:"joinRequests"`
+		AccessRequests    []store.AccessRequest    `json:"accessRequests"`
+		Favorites         []string                 `json:"favorites"`
+		SavedViews        []store.SavedView        `json:"savedViews"`
+		RecentViews       []recent.Entry           `json:"recentViews"`
+	}
+	json.NewDecoder(w.Body).Decode(&resp)
+	if len(resp.Dossiers) != 1 || resp.Dossiers[0]["id"] != "mine" || resp.Dossiers[0]["title"] != "Taxes" {
+		t.Errorf("dossiers = %+v, want only mine", resp.Dossiers)
+	}
+	if len(resp.RelationsGranted) != 1 || len(resp.RelationsReceived) != 1 || resp.RelationsReceived[0]["owners"].([]interface{})[0] != "carol" {
+		t.Errorf("granted = %+v received = %+v", resp.RelationsGranted, resp.RelationsReceived)
+	}
+	if len(resp.Organizations) != 1 || resp.Organizations[0]["role"] != "member" {
+		t.Errorf("organizations = %+v, want Acme as member", resp.Organizations)
+	}
+	if len(resp.Guardians) != 1 || resp.Guardians[0] != "bob" {
+		t.Errorf("guardians = %v, want [bob]", resp.Guardians)
+	}
+	// Everything store.EraseUser removes is exported first.
+	if len(resp.JoinRequests) != 1 || len(resp.AccessRequests) != 1 || len(resp.Favorites) != 1 ||
+		len(resp.SavedViews) != 1 || resp.SavedViews[0].Id != "v1" || len(resp.RecentViews) != 1 {
+		t.Errorf("join = %v, access = %v, favorites = %v, views = %v, recent = %v",
+			resp.JoinRequests, resp.AccessRequests, resp.Favorites, resp.SavedViews, resp.RecentViews)
+	}
+
+	// Secret content needs step-up, as it does when the dossier is read.
+	store.Data.Dossiers["secret"] = &store.Dossier{Title: "Diagnosis", Content: "classified", Owners: []string{"alice"}, Type: "health", Sensitivity: "secret"}
+	export := func(acr string) string {
+		w := httptest.NewRecorder()
+		req := httptest.NewRequest("GET", "/api/me/export", nil)
+		req.Header.Set("x-current-user", "alice")
+		req.Header.Set("x-auth-acr", acr)
+		MeExport(w, req)
+		return w.Body.String()
+	}
+	if body := export("1"); strings.Contains(body, "classified") || !strings.Contains(body, `"stepUpNeeded":true`) {
+		t.Errorf("weak auth export leaks secret content: %s", body)
+	}
+	if body := export("2"); !strings.Contains(body, "classified") {
+		t.Errorf("strong auth export lacks secret content: %s", body)
+	}
+}
+
+func TestDossiersOwners_AddAndLastOwnerProtection(t *testing.T) {
+	defer resetStore(t)()
+	store.Data.Dossiers["d1"] = &store.Dossier{Title: "Household taxes", Owners: []string{"alice"}, Type: "tax"}
+	var writes int
+	cleanup := setupFGA(t, func(w http.ResponseWriter, r *http.Request) {
+		writes++
+		json.NewEncoder(w).Encode(map[string]interface{}{})
+	})
+	defer cleanup()
+
+	call := func(handler func(http.ResponseWriter, *http.Request, string), user, body string) int {
+		w := httptest.NewRecorder()
+		req := httptest.NewRequest("POST", "/api/dossiers/d1/owners", strings.NewReader(body))
+		req.Header.Set("x-current-user", user)
+		handler(w, req, "d1")
+		return w.Code
+	}
+
+	if code := call(DossiersOwnersAdd, "mallory", `{"user":"mallory"}`); code != 403 {
+		t.Errorf("non-owner add status = %d, want 403", code)
+	}
+	if code := call(DossiersOwnersAdd, "alice", `{"user":"bob"}`); code != 200 {
+		t.Fatalf("add status = %d, want 200", code)
+	}
+	if !store.Data.Dossiers["d1"].IsOwner("bob") || writes != 1 {
+		t.Errorf("owners = %v writes = %d, want bob added via FGA", store.Data.Dossiers["d1"].Owners, writes)
+	}
+	// The co-owner can remove the original owner, but not themselves as last owner
+	if code := call(DossiersOwnersRemove, "bob", `{"user":"alice"}`); code != 200 {
+		t.Fatalf("remove status = %d, want 200", code)
+	}
+	if code := call(DossiersOwnersRemove, "bob", `{"user":"bob"}`); code != 400 {
+		t.Errorf("last owner removal status = %d, want 400", code)
+	}
+	if got := store.Data.Dossiers["d1"].Owners; len(got) != 1 || got[0] != "bob" {
+		t.Errorf("owners = %v, want [bob]", got)
+	}
+}
+
+func TestResourcesRouter_GenericCRUD(t *testing.T) {
+	defer resetStore(t)()
+	resources.Register(resources.Type{
+		Name: "bike", Plural: "bikes",
+		Fields:  []resources.Field{{Name: "frame", Required: true}},
+		Viewers: []string{"rider"},
+	})
+
+	var created string
+	cleanup := setupFGA(t, func(w http.ResponseWriter, r *http.Request) {
+		switch {
+		case strings.HasSuffix(r.URL.Path, "/list-objects"):
+			json.NewEncoder(w).Encode(map[string]interface{}{"objects": []string{created}})
+		case strings.HasSuffix(r.URL.Path, "/check"):
+			var body struct {
+				TupleKey map[string]string `json:"tuple_key"`
+			}
+			json.NewDecoder(r.Body).Decode(&body)
+			json.NewEncoder(w).Encode(map[string]interface{}{"allowed": body.TupleKey["user"] == "user:alice"})
+		default:
+			json.NewEncoder(w).Encode(map[string]interface{}{})
+		}
+	})
+	defer cleanup()
+
+	do := func(method, path, user, body string) *httptest.ResponseRecorder {
+		w := httptest.NewRecorder()
+		req := httptest.NewRequest(method, path, strings.NewReader(body))
+		req.Header.Set("x-current-user", user)
+		ResourcesRouter(w, req)
+		return w
+	}
+
+	if w := do("POST", "/api/resources/bikes", "alice", `{"fields":{}}`); w.Code != 400 {
+		t.Errorf("missing required field status = %d, want 400", w.Code)
+	}
+	w := do("POST", "/api/resources/bikes", "alice", `{"fields":{"frame":"steel"}}`)
+	if w.Code != 200 {
+		t.Fatalf("create status = %d, want 200: %s", w.Code, w.Body.String())
+	}
+	var res store.Resource
+	json.NewDecoder(w.Body).Decode(&res)
+	created = res.Object()
+
+	w = do("GET", "/api/resources/bikes", "alice", "")
+	var list map[string][]map[string]interface{}
+	json.NewDecoder(w.Body).Decode(&list)
+	if len(list["bikes"]) != 1 || list["bikes"][0]["canEdit"] != true {
+		t.Errorf("list = %+v, want the created bike, editable", list)
+	}
+
+	if w := do("POST", "/api/resources/bikes/"+res.Id+"/relations", "alice", `{"targetUser":"bob","relation":"owner"}`); w.Code != 400 {
+		t.Errorf("non-assignable relation status = %d, want 400", w.Code)
+	}
+	if w := do("POST", "/api/resources/bikes/"+res.Id+"/relations", "alice", `{"targetUser":"bob","relation":"rider"}`); w.Code != 200 {
+		t.Errorf("grant status = %d, want 200", w.Code)
+	}
+	if w := do("PUT", "/api/resources/bikes/"+res.Id, "bob", `{"fields":{"frame":"carbon"}}`); w.Code != 403 {
+		t.Errorf("update by rider status = %d, want 403", w.Code)
+	}
+	if got := store.Data.Resources[created].Relations; len(got) != 1 || got[0].User != "bob" {
+		t.Errorf("relations = %+v, want bob rider", got)
+	}
+	if w := do("PUT", "/api/resources/bikes/"+res.Id, "alice", `{"fields":{"frame":""}}`); w.Code != 400 {
+		t.Errorf("blanking a required field status = %d, want 400", w.Code)
+	}
+	if frame := store.Data.Resources[created].Fields["frame"]; frame != "steel" {
+		t.Errorf("frame = %q, want steel", frame)
+	}
+	if w := do("GET", "/api/resources/cars", "alice", ""); w.Code != 404 {
+		t.Errorf("unknown type status = %d, want 404", w.Code)
+	}
+}
+
+func TestResourcesRelationsRevoke_KeepsConcurrentGrant(t *testing.T) {
+	defer resetStore(t)()
+	resources.Register(resources.Type{Name: "boat", Plural: "boats", Viewers: []string{"sailor"}})
+	res := &store.Resource{Type: "boat", Id: "b1", Fields: map[string]string{}, Owners: []string{"alice"},
+		Relations: []store.Relation{{User: "bob", Relation: "sailor"}}}
+	store.Data.Resources[res.Object()] = res
+	defer setupFGA(t, func(w http.ResponseWriter, r *http.Request) {
+		if strings.HasSuffix(r.URL.Path, "/write") {
+			// Another request grants carol while bob's tuple is being deleted.
+			store.Mu.Lock()
+			res.Relations = append(res.Relations, store.Relation{User: "carol", Relation: "sailor"})
+			store.Mu.Unlock()
+		}
+		json.NewEncoder(w).Encode(map[string]interface{}{"allowed": true})
+	})()
+
+	w := httptest.NewRecorder()
+	req := httptest.NewRequest("DELETE", "/api/resources/boats/b1/relations", strings.NewReader(`{"targetUser":"bob","relation":"sailor"}`))
+	req.Header.Set("x-current-user", "alice")
+	ResourcesRouter(w, req)
+	if w.Code != 200 {
+		t.Fatalf("revoke status = %d: %s", w.Code, w.Body.String())
+	}
+	if got := res.Relations; len(got) != 1 || got[0].User != "carol" {
+		t.Errorf("relations = %+v, want carol's grant kept and bob's gone", got)
+	}
+}
+
+func TestResourcesGet_ConcurrentUpdate(t *testing.T) {
+	defer resetStore(t)()
+	resources.Register(resources.Type{Name: "kayak", Plural: "kayaks", Fields: []resources.Field{{Name: "color"}}})
+	store.Data.Resources["kayak:k1"] = &store.Resource{Type: "kayak", Id: "k1", Fields: map[string]string{"color": "red"}, Owners: []string{"alice"}}
+	defer setupFGA(t, func(w http.ResponseWriter, r *http.Request) {
+		json.NewEncoder(w).Encode(map[string]interface{}{"allowed": true})
+	})()
+
+	// Run with -race: responses are encoded from copies, not the stored map.
+	var wg sync.WaitGroup
+	for i := 0; i < 20; i++ {
+		wg.Add(2)
+		go func() {
+			defer wg.Done()
+			req := httptest.NewRequest("GET", "/api/resources/kayaks/k1", nil)
+			req.Header.Set("x-current-user", "alice")
+			ResourcesRouter(httptest.NewRecorder(), req)
+		}()
+		go func(i int) {
+			defer wg.Done()
+			req := httptest.NewRequest("PUT", "/api/resources/kayaks/k1", strings.NewReader(fmt.Sprintf(`{"fields":{"color":"c%d"}}`, i)))
+			req.Header.Set("x-current-user", "alice")
+			ResourcesRouter(httptest.NewRecorder(), req)
+		}(i)
+	}
+	wg.Wait()
+}
+
+func TestDossiersList_ConditionalGet(t *testing.T) {
+	defer resetStore(t)()
+	store.Data.Dossiers["d1"] = &store.Dossier{Title: "Tax Return 2024", Type: "tax", Owners: []string{"alice"}}
+	defer setupFGA(t, http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if strings.Contains(r.URL.Path, "list-objects") {
+			json.NewEncoder(w).Encode(map[string]interface{}{"objects": []string{"dossier:d1"}})
+			return
+		}
+		json.NewEncoder(w).Encode(map[string]interface{}{"allowed": true})
+	}))()
+
+	get := func(etag string) *httptest.ResponseRecorder {
+		w := httptest.NewRecorder()
+		req := httptest.NewRequest("GET", "/api/dossiers/list", nil)
+		req.Header.Set("x-current-user", "alice")
+		if etag != "" {
+			req.Header.Set("If-None-Match", etag)
+		}
+		DossiersList(w, req)
+		return w
+	}
+
+	first := get("")
+	etag := first.Header().Get("ETag")
+	if first.Code != 200 || etag == "" {
+		t.Fatalf("status = %d, ETag = %q", first.Code, etag)
+	}
+	if w := get(etag); w.Code != http.StatusNotModified || w.Body.Len() != 0 {
+		t.Errorf("unchanged store: status = %d, want 304 with empty body", w.Code)
+	}
+	store.Touch()
+	if w := get(etag); w.Code != 200 {
+		t.Errorf("after a write: status = %d, want 200", w.Code)
+	}
+}
+
+func TestRenderContent_Sanitizes(t *testing.T) {
+	got := string(renderContent("# Title\n- **bold** <script>alert(1)</script>\n[x](javascript:alert(1)) [ok](https://example.org)", "markdown"))
+	if strings.Contains(got, "<script>") || strings.Contains(got, `href="javascript`) {
+		t.Errorf("unsafe markup survived: %s", got)
+	}
+	for _, want := range []string{"<h3>Title</h3>", "<li><strong>bold</strong> &lt;script&gt;", `<a href="https://example.org" rel="noopener noreferrer">ok</a>`} {
+		if !strings.Contains(got, want) {
+			t.Errorf("rendered markdown missing %q: %s", want, got)
+		}
+	}
+	if got := renderContent(`{"a":"<b>"}`, "json"); !strings.Contains(string(got), "&lt;b&gt;") {
+		t.Errorf("json content not escaped: %s", got)
+	}
+}
+
+func TestDossiersCreate_ContentLimits(t *testing.T) {
+	defer resetStore(t)()
+	defer setupFGA(t, http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		json.NewEncoder(w).Encode(map[string]interface{}{})
+	}))()
+	origMax := config.MaxContentSize.Get()
+	defer config.MaxContentSize.Set(origMax)
+	config.MaxContentSize.Set(32)
+
+	create := func(body string) int {
+		w := httptest.NewRecorder()
+		req := httptest.NewRequest("POST", "/api/dossiers/create", strings.NewReader(body))
+		req.Header.Set("x-current-user", "alice")
+		DossiersCreate(w, req)
+		return w.Code
+	}
+
+	if code := create(`{"title":"T","type":"tax","content":"` + strings.Repeat("x", 33) + `"}`); code != 413 {
+		t.Errorf("oversized content status = %d, want 413", code)
+	}
+	if code := create(`{"title":"T","type":"tax","content":"` + strings.Repeat("x", 64<<10) + `"}`); code != 413 {
+		t.Errorf("oversized body status = %d, want 413", code)
+	}
+	if code := create(`{"title":"T","type":"tax","contentType":"json","content":"{not json"}`); code != 400 {
+		t.Errorf("invalid json content status = %d, want 400", code)
+	}
+	if code := create(`{"title":"T","type":"tax","contentType":"markdown","content":"**hi**"}`); code != 200 {
+		t.Errorf("markdown content status = %d, want 200", code)
+	}
+	for _, d := range store.Data.Dossiers {
+		if d.ContentType != "markdown" {
+			t.Errorf("stored content type = %q, want markdown", d.ContentType)
+		}
+	}
+}
+
+func TestDossiersList_MetadataSortAndFilter(t *testing.T) {
+	defer resetStore(t)()
+	defer setupFGA(t, http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if strings.Contains(r.URL.Path, "list-objects") {
+			json.NewEncoder(w).Encode(map[string]interface{}{"objects": []string{"dossier:old", "dossier:new"}})
+			return
+		}
+		json.NewEncoder(w).Encode(map[string]interface{}{"allowed": true})
+	}))()
+	old := store.NewMeta("bob")
+	old.CreatedAt = old.CreatedAt.Add(-time.Hour)
+	store.Data.Dossiers["old"] = &store.Dossier{Title: "B", Owners: []string{"bob"}, Meta: old}
+	store.Data.Dossiers["new"] = &store.Dossier{Title: "A", Owners: []string{"alice"}, Meta: store.NewMeta("alice")}
+
+	list := func(query string) (int, []dossierView) {
+		w := httptest.NewRecorder()
+		req := httptest.NewRequest("GET", "/api/dossiers/list?"+query, nil)
+		req.Header.Set("x-current-user", "alice")
+		DossiersList(w, req)
+		var body struct{ Dossiers []dossierView }
+		json.NewDecoder(w.Body).Decode(&body)
+		return w.Code, body.Dossiers
+	}
+
+	if _, got := list("sort=-createdAt"); len(got) != 2 || got[0].Id != "new" || got[1].CreatedBy != "bob" {
+		t.Errorf("sort=-createdAt = %+v", got)
+	}
+	if _, got := list("sort=title"); got[0].Title != "A" {
+		t.Errorf("sort=title first = %q, want A", got[0].Title)
+	}
+	if _, got := list("createdBy=bob"); len(got) != 1 || got[0].Id != "old" {
+		t.Errorf("createdBy=bob = %+v", got)
+	}
+	if code, _ := list("sort=owner"); code != 400 {
+		t.Errorf("unknown sort status = %d, want 400", code)
+	}
+}
+
+func TestOrganizationsList_Capabilities(t *testing.T) {
+	defer resetStore(t)()
+	store.Data.Organizations["org1"] = &store.Organization{Name: "BOSA", Description: "Federal", Members: []string{"alice", "bob"}, Admins: []string{"alice"}}
+	defer setupFGA(t, http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var body struct {
+			TupleKey map[string]string `json:"tuple_key"`
+		}
+		json.NewDecoder(r.Body).Decode(&body)
+		allowed := body.TupleKey["user"] == "user:alice" || body.TupleKey["relation"] == "member"
+		json.NewEncoder(w).Encode(map[string]interface{}{"allowed": allowed})
+	}))()
+
+	caps := func(user string) map[string]interface{} {
+		w := httptest.NewRecorder()
+		req := httptest.NewRequest("GET", "/api/dossiers/organizations", nil)
+		req.Header.Set("x-current-user", user)
+		OrganizationsList(w, req)
+		var body struct{ Organizations []map[string]interface{} }
+		json.NewDecoder(w.Body).Decode(&body)
+		return body.Organizations[0]
+	}
+	if got := caps("alice"); got["amIMember"] != true || got["canManage"] != true || got["description"] != "Federal" {
+		t.Errorf("alice = %+v", got)
+	}
+	if got := caps("bob"); got["amIMember"] != true || got["canManage"] != false {
+		t.Errorf("bob = %+v", got)
+	}
+}
+
+func TestMeOrganizations(t *testing.T) {
+	defer resetStore(t)()
+	store.Data.Organizations["o1"] = &store.Organization{Name: "BOSA", Members: []string{"alice", "bob"}, Admins: []string{"alice"}}
+	store.Data.Organizations["o2"] = &store.Organization{Name: "Acme", Members: []string{"bob"}, Admins: []string{"bob"}}
+	store.Data.Organizations["o3"] = &store.Organization{Name: "Other", Members: []string{"carol"}, Admins: []string{"carol"}}
+	defer setupFGA(t, http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var body map[string]interface{}
+		json.NewDecoder(r.Body).Decode(&body)
+		objects := []string{"organization:o1", "organization:o2"}
+		if body["relation"] == "admin" {
+			objects = []string{"organization:o2"}
+		}
+		json.NewEncoder(w).Encode(map[string]interface{}{"objects": objects})
+	}))()
+
+	w := httptest.NewRecorder()
+	req := httptest.NewRequest("GET", "/api/me/organizations", nil)
+	req.Header.Set("x-current-user", "bob")
+	MeOrganizations(w, req)
+
+	var body struct{ Organizations []myOrganization }
+	json.NewDecoder(w.Body).Decode(&body)
+	got := body.Organizations
+	if len(got) != 2 || got[0].Name != "Acme" || got[0].Role != "admin" || got[1].Role != "member" || got[1].MemberCount != 2 {
+		t.Errorf("organizations = %+v", got)
+	}
+}
+
+func TestOrganizationsList_VisibilityTiers(t *testing.T) {
+	defer resetStore(t)()
+	store.Data.Organizations["org1"] = &store.Organization{Name: "BOSA", Members: []string{"alice", "bob"}, Admins: []string{"alice"}}
+	defer setupFGA(t, http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var body struct {
+			TupleKey map[string]string `json:"tuple_key"`
+		}
+		json.NewDecoder(r.Body).Decode(&body)
+		u, rel := body.TupleKey["user"], body.TupleKey["relation"]
+		allowed := u == "user:alice" || (u == "user:bob" && rel == "member")
+		json.NewEncoder(w).Encode(map[string]interface{}{"allowed": allowed})
+	}))()
+
+	list := func(user string, admin bool) map[string]interface{} {
+		w := httptest.NewRecorder()
+		req := httptest.NewRequest("GET", "/api/dossiers/organizations", nil)
+		req.Header.Set("x-current-user", user)
+		if admin {
+			req.Header.Set("x-manager-admin", "true")
+		}
+		OrganizationsList(w, req)
+		var body struct{ Organizations []map[string]interface{} }
+		json.NewDecoder(w.Body).Decode(&body)
+		return body.Organizations[0]
+	}
+
+	tests := []struct {
+		name        string
+		user        string
+		admin       bool
+		wantMembers bool
+	}{
+		{"org admin", "alice", false, true},
+		{"member", "bob", false, true},
+		{"outsider", "mallory", false, false},
+		{"manager admin", "ops", true, true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			org := list(tt.user, tt.admin)
+			_, hasMembers := org["members"]
+			_, hasAdmins := org["admins"]
+			if hasMembers != tt.wantMembers || hasAdmins != tt.wantMembers {
+				t.Errorf("members/admins exposed = %v/%v, want %v", hasMembers, hasAdmins, tt.wantMembers)
+			}
+			if org["name"] != "BOSA" {
+				t.Errorf("name = %v, want BOSA in every tier", org["name"])
+			}
+		})
+	}
+}
+
+func TestOrganizationsJoin_Flow(t *testing.T) {
+	defer resetStore(t)()
+	events.Reset()
+	defer events.Reset()
+	store.Data.Organizations["org1"] = &store.Organization{Name: "BOSA", Members: []string{"alice"}, Admins: []string{"alice"}}
+	var written []string
+	defer setupFGA(t, http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var body map[string]interface{}
+		json.NewDecoder(r.Body).Decode(&body)
+		if strings.HasSuffix(r.URL.Path, "/write") {
+			raw, _ := json.Marshal(body["writes"])
+			written = append(written, string(raw))
+		}
+		tk, _ := body["tuple_key"].(map[string]interface{})
+		json.NewEncoder(w).Encode(map[string]interface{}{"allowed": tk["user"] == "user:alice"})
+	}))()
+
+	do := func(handler func(http.ResponseWriter, *http.Request), user, body string) *httptest.ResponseRecorder {
+		w := httptest.NewRecorder()
+		req := httptest.NewRequest("POST", "/", strings.NewReader(body))
+		req.Header.Set("x-current-user", user)
+		handler(w, req)
+		return w
+	}
+
+	w := do(func(w http.ResponseWriter, r *http.Request) { OrganizationsJoin(w, r, "org1") }, "bob", `{"message":"hi"}`)
+	var req store.JoinRequest
+	json.NewDecoder(w.Body).Decode(&req)
+	if w.Code != 200 || req.Status != "pending" {
+		t.Fatalf("join status = %d, request = %+v", w.Code, req)
+	}
+	if w := do(func(w http.ResponseWriter, r *http.Request) { OrganizationsJoin(w, r, "org1") }, "bob", `{}`); w.Code != 400 {
+		t.Errorf("duplicate join status = %d, want 400", w.Code)
+	}
+	if w := do(func(w http.ResponseWriter, r *http.Request) { OrganizationsJoinDecide(w, r, "org1", req.Id, true) }, "bob", ""); w.Code != 403 {
+		t.Errorf("self-approval status = %d, want 403", w.Code)
+	}
+	if w := do(func(w http.ResponseWriter, r *http.Request) { OrganizationsJoinDecide(w, r, "org1", req.Id, true) }, "alice", ""); w.Code != 200 {
+		t.Fatalf("approve status = %d: %s", w.Code, w.Body.String())
+	}
+
+	if !httputil.Contains(store.Data.Organizations["org1"].Members, "bob") {
+		t.Error("bob should be a member after approval")
+	}
+	if len(written) != 1 || !strings.Contains(written[0], `"user:bob"`) {
+		t.Errorf("member tuple writes = %v", written)
+	}
+	var recent []events.Event
+	for _, e := range events.Recent(0) {
+		if strings.HasPrefix(e.Type, "org.join.") {
+			recent = append(recent, e)
+		}
+	}
+	if len(recent) != 2 || recent[0].Type != events.OrgJoinApproved || recent[1].Recipients[0] != "alice" {
+		t.Errorf("events = %+v", recent)
+	}
+}
+
+func TestOrganizationsDossiers_Paginated(t *testing.T) {
+	defer resetStore(t)()
+	store.Data.Organizations["org1"] = &store.Organization{Name: "BOSA", Members: []string{"alice"}, Admins: []string{"alice"}}
+	var visible []string
+	for _, id := range []string{"d1", "d2", "d3"} {
+		store.Data.Dossiers[id] = &store.Dossier{Title: id, Owners: []string{"alice"}, OrgId: "org1"}
+		visible = append(visible, "dossier:"+id)
+	}
+	store.Data.Dossiers["other"] = &store.Dossier{Title: "other", Owners: []string{"alice"}}
+	visible = append(visible, "dossier:other")
+	defer setupFGA(t, http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if strings.Contains(r.URL.Path, "list-objects") {
+			json.NewEncoder(w).Encode(map[string]interface{}{"objects": visible})
+			return
+		}
+		json.NewEncoder(w).Encode(map[string]interface{}{"allowed": true})
+	}))()
+
+	get := func(query string) (int, map[string]interface{}) {
+		w := httptest.NewRecorder()
+		req := httptest.NewRequest("GET", "/api/dossiers/organizations/org1/dossiers?"+query, nil)
+		req.Header.Set("x-current-user", "alice")
+		OrganizationsDossiers(w, req, "org1")
+		var body map[string]interface{}
+		json.NewDecoder(w.Body).Decode(&body)
+		return w.Code, body
+	}
+
+	_, body := get("limit=2")
+	if got := body["dossiers"].([]interface{}); len(got) != 2 || body["total"] != float64(3) || body["nextOffset"] != float64(2) {
+		t.Errorf("first page = %+v", body)
+	}
+	_, body = get("offset=2&limit=2")
+	if got := body["dossiers"].([]interface{}); len(got) != 1 || got[0].(map[string]interface{})["id"] != "d3" {
+		t.Errorf("last page = %+v", body)
+	}
+	if _, ok := body["nextOffset"]; ok {
+		t.Error("last page should not carry nextOffset")
+	}
+	if code, _ := get("limit=-1"); code != 400 {
+		t.Errorf("bad limit status = %d, want 400", code)
+	}
+}
+
+func TestDossiersSetOrg_Move(t *testing.T) {
+	defer resetStore(t)()
+	store.Data.Organizations["o1"] = &store.Organization{Name: "Src", Members: []string{"alice", "carol"}, Admins: []string{"carol"}}
+	store.Data.Organizations["o2"] = &store.Organization{Name: "Dst", Members: []string{"alice"}, Admins: []string{"alice"}}
+	store.Data.Dossiers["d1"] = &store.Dossier{Title: "Tax", Owners: []string{"alice"}, OrgId: "o1"}
+	var writeBody map[string]interface{}
+	defer setupFGA(t, http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var body map[string]interface{}
+		json.NewDecoder(r.Body).Decode(&body)
+		if strings.HasSuffix(r.URL.Path, "/write") {
+			writeBody = body
+		}
+		tk, _ := body["tuple_key"].(map[string]interface{})
+		// alice belongs to both orgs; bob to none.
+		json.NewEncoder(w).Encode(map[string]interface{}{"allowed": tk["user"] == "user:alice"})
+	}))()
+
+	move := func(user, orgId string) int {
+		w := httptest.NewRecorder()
+		req := httptest.NewRequest("POST", "/api/dossiers/d1/org", strings.NewReader(`{"orgId":"`+orgId+`"}`))
+		req.Header.Set("x-current-user", user)
+		DossiersSetOrg(w, req, "d1")
+		return w.Code
+	}
+
+	if code := move("bob", ""); code != 403 {
+		t.Errorf("stranger detach status = %d, want 403", code)
+	}
+	if code := move("alice", "o2"); code != 200 {
+		t.Fatalf("owner move status = %d, want 200", code)
+	}
+	if store.Data.Dossiers["d1"].OrgId != "o2" {
+		t.Errorf("OrgId = %q, want o2", store.Data.Dossiers["d1"].OrgId)
+	}
+	if writeBody["writes"] == nil || writeBody["deletes"] == nil {
+		t.Errorf("move should swap tuples in one write: %+v", writeBody)
+	}
+	if code := move("alice", "missing"); code != 404 {
+		t.Errorf("unknown org status = %d, want 404", code)
+	}
+}
+
+func TestOrganizationsDelete_Modes(t *testing.T) {
+	defer resetStore(t)()
+	defer setupFGA(t, http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		json.NewEncoder(w).Encode(map[string]interface{}{"allowed": true})
+	}))()
+	seed := func() {
+		store.Data.Organizations["o1"] = &store.Organization{Name: "Old", Members: []string{"alice"}, Admins: []string{"alice"}}
+		store.Data.Organizations["o2"] = &store.Organization{Name: "New", Members: []string{"alice"}, Admins: []string{"alice"}}
+		store.Data.Dossiers["d1"] = &store.Dossier{Title: "A", Owners: []string{"alice"}, OrgId: "o1"}
+		store.Data.Dossiers["d2"] = &store.Dossier{Title: "B", Owners: []string{"bob"}, OrgId: "o1"}
+	}
+	del := func(query string) *httptest.ResponseRecorder {
+		w := httptest.NewRecorder()
+		req := httptest.NewRequest("DELETE", "/api/dossiers/organizations/o1?"+query, nil)
+		req.Header.Set("x-current-user", "alice")
+		OrganizationsDelete(w, req, "o1")
+		return w
+	}
+
+	seed()
+	if w := del("mode=detach"); w.Code != 409 || !strings.Contains(w.Body.String(), `"affectedDossiers":2`) {
+		t.Fatalf("unconfirmed delete = %d %s, want 409 with count", w.Code, w.Body.String())
+	}
+	if _, ok := store.Data.Organizations["o1"]; !ok {
+		t.Fatal("unconfirmed delete removed the organization")
+	}
+	if w := del("mode=bogus"); w.Code != 400 {
+		t.Errorf("bad mode status = %d, want 400", w.Code)
+	}
+
+	if w := del("mode=reassign&targetOrgId=o2&confirm=2"); w.Code != 200 {
+		t.Fatalf("reassign status = %d: %s", w.Code, w.Body.String())
+	}
+	if store.Data.Dossiers["d1"].OrgId != "o2" || store.Data.Dossiers["d2"].OrgId != "o2" {
+		t.Error("reassign should move dossiers to o2")
+	}
+
+	seed()
+	if w := del("mode=delete-dossiers&confirm=2"); w.Code != 200 {
+		t.Fatalf("delete-dossiers status = %d: %s", w.Code, w.Body.String())
+	}
+	if len(store.Data.Dossiers) != 0 {
+		t.Errorf("dossiers left after delete-dossiers: %d", len(store.Data.Dossiers))
+	}
+}
+
+func TestAdminAppointOrgAdmin(t *testing.T) {
+	defer resetStore(t)()
+	defer setupFGA(t, http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		json.NewEncoder(w).Encode(map[string]interface{}{})
+	}))()
+	store.Data.Organizations["o1"] = &store.Organization{Name: "Lost", Members: []string{"bob"}, Admins: []string{}}
+
+	appoint := func(admin bool, body string) int {
+		w := httptest.NewRecorder()
+		req := httptest.NewRequest("POST", "/api/admin/organizations/o1/admins", strings.NewReader(body))
+		if admin {
+			req.Header.Set("x-manager-admin", "true")
+		}
+		AdminAppointOrgAdmin(w, req, "o1")
+		return w.Code
+	}
+	if code := appoint(false, `{"user":"bob","reason":"x"}`); code != 403 {
+		t.Errorf("non-admin status = %d, want 403", code)
+	}
+	if code := appoint(true, `{"user":"bob"}`); code != 400 {
+		t.Errorf("missing reason status = %d, want 400", code)
+	}
+	if code := appoint(true, `{"user":"bob","reason":"last admin left"}`); code != 200 {
+		t.Fatalf("appoint status = %d, want 200", code)
+	}
+	if org := store.Data.Organizations["o1"]; len(org.Admins) != 1 || org.Admins[0] != "bob" {
+		t.Errorf("admins = %v, want [bob]", org.Admins)
+	}
+	if ids := store.OrphanedOrganizations(); len(ids) != 0 {
+		t.Errorf("organization still orphaned: %v", ids)
+	}
+}
+
+func TestAdminChanges_FollowsCursor(t *testing.T) {
+	events.Reset()
+	defer events.Reset()
+	data := &store.DataStore{
+		Dossiers:      map[string]*store.Dossier{"d1": {Title: "Tax", Owners: []string{"alice"}}},
+		Organizations: map[string]*store.Organization{},
+	}
+	prev, prevFile := store.Swap(data, t.TempDir()+"/dossiers.json")
+	defer store.Swap(prev, prevFile)
+	store.Save()
+
+	get := func(query string) map[string]interface{} {
+		w := httptest.NewRecorder()
+		req := httptest.NewRequest("GET", "/api/admin/changes?"+query, nil)
+		req.Header.Set("x-manager-admin", "true")
+		AdminChanges(w, req)
+		var body map[string]interface{}
+		json.NewDecoder(w.Body).Decode(&body)
+		if w.Code != 200 {
+			t.Fatalf("status = %d: %v", w.Code, body)
+		}
+		return body
+	}
+
+	body := get("since=0")
+	changes := body["changes"].([]interface{})
+	if len(changes) != 1 || body["reset"] != false {
+		t.Fatalf("initial changes = %v", body)
+	}
+	first := changes[0].(map[string]interface{})
+	if first["object"] != "dossier:d1" || first["value"].(map[string]interface{})["title"] != "Tax" {
+		t.Errorf("change = %v", first)
+	}
+
+	cursor := fmt.Sprint(body["cursor"])
+	delete(data.Dossiers, "d1")
+	store.Save()
+	body = get("since=" + cursor)
+	changes = body["changes"].([]interface{})
+	if len(changes) != 1 || changes[0].(map[string]interface{})["type"] != events.ObjectDeleted {
+		t.Errorf("changes after delete = %v", body)
+	}
+	if body := get("since=" + fmt.Sprint(body["cursor"])); len(body["changes"].([]interface{})) != 0 {
+		t.Errorf("expected no further changes: %v", body)
+	}
+}
+
+func TestAskAIManager_Anonymized(t *testing.T) {
+	privacy.SetEnabled(true)
+	defer privacy.SetEnabled(false)
+	var sent map[string]interface{}
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		json.NewDecoder(r.Body).Decode(&sent)
+		user, _ := sent["user"].(string)
+		json.NewEncoder(w).Encode(map[string]string{"explanation": user + " cannot see it"})
+	}))
+	defer srv.Close()
+	origURL := config.AIManagerURL
+	config.AIManagerURL = srv.URL
+	defer func() { config.AIManagerURL = origURL }()
+
+	facts := map[string]interface{}{
+		"user": "alice", "visibleDossiers": []string{"d1: Cancer treatment"},
+		"guardians": []string{"bob"}, "wards": []string{},
+	}
+	explanation, err := askAIManager(facts, "/api/dossiers/d2", "user:alice is blocked")
+	if err != nil {
+		t.Fatal(err)
+	}
+	b, _ := json.Marshal(sent)
+	if payload := string(b); strings.Contains(payload, "alice") || strings.Contains(payload, "bob") || strings.Contains(payload, "Cancer") {
+		t.Errorf("payload leaks personal data: %s", payload)
+	}
+	if explanation != "alice cannot see it" {
+		t.Errorf("explanation = %q, want pseudonym mapped back", explanation)
+	}
+}
+
+func TestOPABundle_ETag(t *testing.T) {
+	origDir := config.OPAPoliciesDir
+	config.OPAPoliciesDir = t.TempDir()
+	defer func() { config.OPAPoliciesDir = origDir }()
+	os.WriteFile(filepath.Join(config.OPAPoliciesDir, "policy.rego"), []byte("package envoy.authz\n"), 0644)
+
+	get := func(name, etag string) *httptest.ResponseRecorder {
+		w := httptest.NewRecorder()
+		req := httptest.NewRequest("GET", "/opa/bundles/"+name, nil)
+		if etag != "" {
+			req.Header.Set("If-None-Match", etag)
+		}
+		OPABundle(w, req, name)
+		return w
+	}
+	w := get("main.tar.gz", "")
+	if w.Code != 200 || w.Header().Get("Content-Type") != "application/gzip" || w.Header().Get("ETag") == "" {
+		t.Fatalf("bundle = %d %v", w.Code, w.Header())
+	}
+	if w := get("main.tar.gz", w.Header().Get("ETag")); w.Code != 304 {
+		t.Errorf("unchanged bundle status = %d, want 304", w.Code)
+	}
+	if w := get("..", ""); w.Code != 404 {
+		t.Errorf("path traversal status = %d, want 404", w.Code)
+	}
+}
+
+func TestOPADecisionLogs(t *testing.T) {
+	origRelay := config.OPALogsRelayURL
+	config.OPALogsRelayURL = ""
+	defer func() { config.OPALogsRelayURL = origRelay }()
+
+	batch := `[
+	 {"decision_id":"1","input":{"attributes":{"request":{"http":{"method":"get","path":"/api/dossiers/list","headers":{"x-request-id":"req-opa-1"}}}}},
+	  "result":{"allowed":false,"headers":{"x-current-user":"opa-visitor"},"body":"{\"reason\":\"not a citizen\"}"}},
+	 {"decision_id":"2","input":{"attributes":{"request":{"http":{"method":"GET","path":"/manager/"}}}},"result":true}
+	]`
+	var buf bytes.Buffer
+	zw := gzip.NewWriter(&buf)
+	zw.Write([]byte(batch))
+	zw.Close()
+
+	post := func(token string) *httptest.ResponseRecorder {
+		w := httptest.NewRecorder()
+		req := httptest.NewRequest("POST", "/opa/decision-logs", bytes.NewReader(buf.Bytes()))
+		req.Header.Set("Content-Encoding", "gzip")
+		if token != "" {
+			req.Header.Set("Authorization", "Bearer "+token)
+		}
+		OPADecisionLogs(w, req)
+		return w
+	}
+	if w := post("opa-token"); w.Code != 503 {
+		t.Fatalf("without OPA_LOGS_TOKEN: status = %d, want 503", w.Code)
+	}
+	os.Setenv(config.OPALogsToken, "opa-token")
+	config.LoadSecrets()
+	defer func() {
+		os.Unsetenv(config.OPALogsToken)
+		config.LoadSecrets()
+	}()
+	for _, token := range []string{"", "forged"} {
+		if w := post(token); w.Code != 401 {
+			t.Fatalf("token %q: status = %d, want 401", token, w.Code)
+		}
+	}
+	if len(audit.Recent("opa-visitor", 5)) != 0 {
+		t.Fatal("an unauthenticated batch was recorded")
+	}
+
+	w := post("opa-token")
+	if w.Code != 204 {
+		t.Fatalf("status = %d: %s", w.Code, w.Body.String())
+	}
+	entries := audit.Recent("opa-visitor", 5)
+	if len(entries) != 1 {
+		t.Fatalf("entries = %+v", entries)
+	}
+	e := entries[0]
+	if e.Source != "OPA" || e.Decision != "deny" || e.Method != "GET" || e.Reason != "not a citizen" || e.RequestId != "req-opa-1" {
+		t.Errorf("entry = %+v", e)
+	}
+}
+
+func TestAuditTrace(t *testing.T) {
+	inner := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		audit.SendAuditLog("Dossiers", "deny", "user:trace-visitor", "editor", "dossier:d-trace", "PUT", "not an editor")
+		w.WriteHeader(403)
+	})
+	req := httptest.NewRequest("PUT", "/api/dossiers/d-trace", nil)
+	req.Header.Set("x-request-id", "req-trace-1")
+	req.Header.Set("x-current-user", "trace-visitor")
+	w := httptest.NewRecorder()
+	audit.TraceRequests(inner).ServeHTTP(w, req)
+	if w.Header().Get("x-request-id") != "req-trace-1" {
+		t.Fatalf("request id not echoed: %v", w.Header())
+	}
+	audit.Record(audit.Entry{Time: time.Now().Add(-time.Millisecond), Source: "OPA", Decision: "allow", User: "trace-visitor", Method: "PUT", RequestId: "req-trace-1"})
+
+	get := func(user, id string) *httptest.ResponseRecorder {
+		w := httptest.NewRecorder()
+		req := httptest.NewRequest("GET", "/api/audit/trace/"+id, nil)
+		req.Header.Set("x-current-user", user)
+		AuditTrace(w, req, id)
+		return w
+	}
+	w = get("trace-visitor", "req-trace-1")
+	if w.Code != 200 {
+		t.Fatalf("status = %d: %s", w.Code, w.Body.String())
+	}
+	var trace audit.Trace
+	json.NewDecoder(w.Body).Decode(&trace)
+	if trace.Request == nil || trace.Request.Status != 403 || len(trace.Steps) != 2 {
+		t.Fatalf("trace = %+v", trace)
+	}
+	if trace.Steps[0].Source != "OPA" || trace.Steps[0].Correlation != "requestId" || trace.Steps[1].Correlation != "window" {
+		t.Errorf("steps = %+v", trace.Steps)
+	}
+	if w := get("mallory", "req-trace-1"); w.Code != 404 {
+		t.Errorf("other user: status = %d, want 404", w.Code)
+	}
+	if w := get("trace-visitor", "req-unknown"); w.Code != 404 {
+		t.Errorf("unknown id: status = %d, want 404", w.Code)
+	}
+}
+
+func TestDossiersAccessRequest_Flow(t *testing.T) {
+	defer resetStore(t)()
+	events.Reset()
+	defer events.Reset()
+	store.Data.Dossiers["d1"] = &store.Dossier{Title: "Taxes", Owners: []string{"alice"}}
+	var written []string
+	defer setupFGA(t, http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var body map[string]interface{}
+		json.NewDecoder(r.Body).Decode(&body)
+		if strings.HasSuffix(r.URL.Path, "/write") {
+			raw, _ := json.Marshal(body["writes"])
+			written = append(written, string(raw))
+		}
+		tk, _ := body["tuple_key"].(map[string]interface{})
+		json.NewEncoder(w).Encode(map[string]interface{}{"allowed": tk["user"] == "user:alice"})
+	}))()
+
+	do := func(handler func(http.ResponseWriter, *http.Request), user, body string) *httptest.ResponseRecorder {
+		w := httptest.NewRecorder()
+		req := httptest.NewRequest("POST", "/", strings.NewReader(body))
+		req.Header.Set("x-current-user", user)
+		handler(w, req)
+		return w
+	}
+
+	if w := do(func(w http.ResponseWriter, r *http.Request) { DossiersAccessRequest(w, r, "d1") }, "alice", `{}`); w.Code != 400 {
+		t.Errorf("owner request status = %d, want 400", w.Code)
+	}
+	w := do(func(w http.ResponseWriter, r *http.Request) { DossiersAccessRequest(w, r, "d1") }, "bob", `{"message":"I am your accountant"}`)
+	var req store.AccessRequest
+	json.NewDecoder(w.Body).Decode(&req)
+	if w.Code != 200 || req.Status != "pending" {
+		t.Fatalf("request status = %d, request = %+v", w.Code, req)
+	}
+	if w := do(func(w http.ResponseWriter, r *http.Request) { DossiersAccessRequest(w, r, "d1") }, "bob", `{}`); w.Code != 400 {
+		t.Errorf("duplicate request status = %d, want 400", w.Code)
+	}
+	if w := do(func(w http.ResponseWriter, r *http.Request) { DossiersAccessDecide(w, r, "d1", req.Id, true) }, "bob", ""); w.Code != 403 {
+		t.Errorf("self-approval status = %d, want 403", w.Code)
+	}
+	if w := do(func(w http.ResponseWriter, r *http.Request) { DossiersAccessDecide(w, r, "d1", req.Id, true) }, "alice", ""); w.Code != 200 {
+		t.Fatalf("approve status = %d: %s", w.Code, w.Body.String())
+	}
+	if w := do(func(w http.ResponseWriter, r *http.Request) { DossiersAccessDecide(w, r, "d1", req.Id, false) }, "alice", ""); w.Code != 400 {
+		t.Errorf("second decision status = %d, want 400", w.Code)
+	}
+
+	rels := store.Data.Dossiers["d1"].Relations
+	if len(rels) != 1 || rels[0].User != "bob" || rels[0].Relation != "mandate_holder" {
+		t.Errorf("relations = %+v", rels)
+	}
+	if len(written) != 1 || !strings.Contains(written[0], `"user:bob"`) {
+		t.Errorf("mandate tuple writes = %v", written)
+	}
+	var recent []events.Event
+	for _, e := range events.Recent(0) {
+		if strings.HasPrefix(e.Type, "dossier.access.") {
+			recent = append(recent, e)
+		}
+	}
+	if len(recent) != 2 || recent[0].Type != events.DossierAccessApproved || recent[1].Recipients[0] != "alice" {
+		t.Errorf("events = %+v", recent)
+	}
+}
+
+func TestDossiersRelationsAdd_Levels(t *testing.T) {
+	defer resetStore(t)()
+	store.Data.Dossiers["d1"] = &store.Dossier{Title: "Taxes", Owners: []string{"alice"}}
+	store.Data.Guardianships["alice"] = []string{"bob"}
+	var written []string
+	defer setupFGA(t, http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var body map[string]interface{}
+		json.NewDecoder(r.Body).Decode(&body)
+		if strings.HasSuffix(r.URL.Path, "/write") {
+			raw, _ := json.Marshal(body["writes"])
+			written = append(written, string(raw))
+		}
+		if strings.Contains(r.URL.Path, "list-objects") {
+			json.NewEncoder(w).Encode(map[string]interface{}{"objects": []interface{}{"dossier:d1"}})
+			return
+		}
+		tk, _ := body["tuple_key"].(map[string]interface{})
+		allowed := tk["user"] == "user:alice" || (tk["user"] == "user:bob" && tk["relation"] != "can_share")
+		json.NewEncoder(w).Encode(map[string]interface{}{"allowed": allowed})
+	}))()
+
+	add := func(user, body string) *httptest.ResponseRecorder {
+		w := httptest.NewRecorder()
+		req := httptest.NewRequest("POST", "/api/dossiers/d1/relations", strings.NewReader(body))
+		req.Header.Set("x-current-user", user)
+		DossiersRelationsAdd(w, req, "d1")
+		return w
+	}
+	if w := add("alice", `{"targetUser":"bob","level":"admin"}`); w.Code != 400 {
+		t.Errorf("unknown level status = %d, want 400", w.Code)
+	}
+	if w := add("bob", `{"targetUser":"alice","level":"view"}`); w.Code != 403 {
+		t.Errorf("non-sharer status = %d, want 403", w.Code)
+	}
+	if w := add("alice", `{"targetUser":"bob","level":"edit"}`); w.Code != 200 {
+		t.Fatalf("status = %d: %s", w.Code, w.Body.String())
+	}
+	if len(written) != 1 || !strings.Contains(written[0], `"mandate_editor"`) {
+		t.Errorf("writes = %v", written)
+	}
+	if w := add("alice", `{"targetUser":"bob","level":"share"}`); w.Code != 400 {
+		t.Errorf("second mandate status = %d, want 400", w.Code)
+	}
+
+	w := httptest.NewRecorder()
+	req := httptest.NewRequest("GET", "/api/dossiers", nil)
+	req.Header.Set("x-current-user", "bob")
+	DossiersList(w, req)
+	var body struct {
+		Dossiers []map[string]interface{} `json:"dossiers"`
+	}
+	json.NewDecoder(w.Body).Decode(&body)
+	if len(body.Dossiers) != 1 || body.Dossiers[0]["canEdit"] != true || body.Dossiers[0]["canShare"] != false {
+		t.Errorf("dossiers = %+v", body.Dossiers)
+	}
+	perms, _ := body.Dossiers[0]["permissions"].(map[string]interface{})
+	if perms["viewer"] != true || perms["can_share"] != false || perms["can_delete"] != true {
+		t.Errorf("permissions = %v", perms)
+	}
+}
+
+func TestDossierWrites_ReleaseStoreLock(t *testing.T) {
+	defer resetStore(t)()
+	store.Data.Dossiers["d1"] = &store.Dossier{Title: "Taxes", Owners: []string{"alice"}}
+	store.Data.Guardianships["alice"] = []string{"bob"}
+	var deletes []string
+	var onWrite func()
+	defer setupFGA(t, http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if strings.HasSuffix(r.URL.Path, "/write") {
+			// Another request must be able to take the store meanwhile.
+			if !store.Mu.TryLock() {
+				t.Error("store.Mu held across the OpenFGA write")
+			} else {
+				store.Mu.Unlock()
+			}
+			var body map[string]interface{}
+			json.NewDecoder(r.Body).Decode(&body)
+			if d, ok := body["deletes"]; ok {
+				raw, _ := json.Marshal(d)
+				deletes = append(deletes, string(raw))
+			}
+			if onWrite != nil {
+				onWrite()
+			}
+			json.NewEncoder(w).Encode(map[string]interface{}{})
+			return
+		}
+		json.NewEncoder(w).Encode(map[string]interface{}{"allowed": true})
+	}))()
+
+	serve := func(method string, handler func(http.ResponseWriter, *http.Request, string), body string) *httptest.ResponseRecorder {
+		w := httptest.NewRecorder()
+		req := httptest.NewRequest(method, "/api/dossiers/d1/relations", strings.NewReader(body))
+		req.Header.Set("x-current-user", "alice")
+		handler(w, req, "d1")
+		return w
+	}
+	if w := serve("POST", DossiersRelationsAdd, `{"targetUser":"bob"}`); w.Code != 200 {
+		t.Fatalf("add: status = %d: %s", w.Code, w.Body.String())
+	}
+	if w := serve("DELETE", DossiersRelationsDelete, `{"targetUser":"bob","relation":"mandate_holder"}`); w.Code != 200 || len(store.Data.Dossiers["d1"].Relations) != 0 {
+		t.Fatalf("remove: status = %d: %s", w.Code, w.Body.String())
+	}
+
+	// The dossier is deleted while the grant is being written: the tuple is taken back.
+	onWrite = func() {
+		onWrite = nil
+		store.Mu.Lock()
+		delete(store.Data.Dossiers, "d1")
+		store.Mu.Unlock()
+	}
+	deletes = nil
+	if w := serve("POST", DossiersRelationsAdd, `{"targetUser":"bob"}`); w.Code != 404 {
+		t.Errorf("add to a deleted dossier: status = %d, want 404", w.Code)
+	}
+	if len(deletes) != 1 || !strings.Contains(deletes[0], `"user:bob"`) {
+		t.Errorf("compensating deletes = %v", deletes)
+	}
+}
+
+func TestDossiersRelationsRevokeChain(t *testing.T) {
+	defer resetStore(t)()
+	store.Data.Dossiers["d1"] = &store.Dossier{Title: "Taxes", Owners: []string{"alice"}, Relations: []store.Relation{
+		{User: "bob", Relation: "mandate_sharer", GrantedBy: "alice"},
+		{User: "carol", Relation: "mandate_viewer", GrantedBy: "bob"},
+		{User: "erin", Relation: "mandate_viewer", GrantedBy: "alice"},
+	}}
+	var deleted string
+	defer setupFGA(t, http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var body map[string]interface{}
+		json.NewDecoder(r.Body).Decode(&body)
+		raw, _ := json.Marshal(body["deletes"])
+		deleted = string(raw)
+		json.NewEncoder(w).Encode(map[string]interface{}{})
+	}))()
+
+	revoke := func(user string) *httptest.ResponseRecorder {
+		w := httptest.NewRecorder()
+		req := httptest.NewRequest("POST", "/api/dossiers/d1/relations/revoke-chain", strings.NewReader(`{"targetUser":"bob"}`))
+		req.Header.Set("x-current-user", user)
+		DossiersRelationsRevokeChain(w, req, "d1")
+		return w
+	}
+	if w := revoke("bob"); w.Code != 403 {
+		t.Errorf("non-owner status = %d, want 403", w.Code)
+	}
+	if w := revoke("alice"); w.Code != 200 {
+		t.Fatalf("status = %d: %s", w.Code, w.Body.String())
+	}
+	if !strings.Contains(deleted, `"user:bob"`) || !strings.Contains(deleted, `"user:carol"`) || strings.Contains(deleted, "erin") {
+		t.Errorf("deleted tuples = %s", deleted)
+	}
+	if rels := store.Data.Dossiers["d1"].Relations; len(rels) != 1 || rels[0].User != "erin" {
+		t.Errorf("relations left = %+v", rels)
+	}
+}
+
+func TestDossiersRelationsDelete_OnlyGrants(t *testing.T) {
+	defer resetStore(t)()
+	store.Data.Dossiers["d1"] = &store.Dossier{Title: "Taxes", Owners: []string{"alice"}, Relations: []store.Relation{
+		{User: "bob", Relation: "mandate_sharer", GrantedBy: "alice"},
+		{User: "carol", Relation: "mandate_viewer", GrantedBy: "bob"},
+	}}
+	var writes int
+	defer setupFGA(t, http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if strings.HasSuffix(r.URL.Path, "/check") {
+			json.NewEncoder(w).Encode(map[string]interface{}{"allowed": true})
+			return
+		}
+		writes++
+		json.NewEncoder(w).Encode(map[string]interface{}{})
+	}))()
+
+	remove := func(target, relation string) *httptest.ResponseRecorder {
+		w := httptest.NewRecorder()
+		req := httptest.NewRequest("DELETE", "/api/dossiers/d1/relations", strings.NewReader(`{"targetUser":"`+target+`","relation":"`+relation+`"}`))
+		req.Header.Set("x-current-user", "bob")
+		DossiersRelationsDelete(w, req, "d1")
+		return w
+	}
+	for _, relation := range []string{"owner", "blocked"} {
+		if w := remove("alice", relation); w.Code != 400 {
+			t.Errorf("%s status = %d, want 400", relation, w.Code)
+		}
+	}
+	if writes != 0 {
+		t.Errorf("FGA writes = %d, want none for owner or blocked", writes)
+	}
+	if w := remove("carol", "mandate_viewer"); w.Code != 200 {
+		t.Fatalf("mandate status = %d: %s", w.Code, w.Body.String())
+	}
+	if rels := store.Data.Dossiers["d1"].Relations; len(rels) != 1 || rels[0].User != "bob" {
+		t.Errorf("relations left = %+v", rels)
+	}
+}
+
+func TestDossiersRelationsRevokeChain_DeletedDuringWrite(t *testing.T) {
+	defer resetStore(t)()
+	store.Data.Dossiers["d1"] = &store.Dossier{Title: "Taxes", Owners: []string{"alice"}, Relations: []store.Relation{
+		{User: "bob", Relation: "mandate_holder", GrantedBy: "alice"},
+	}}
+	defer setupFGA(t, http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		// The dossier goes away while its tuples are being deleted.
+		store.Mu.Lock()
+		delete(store.Data.Dossiers, "d1")
+		store.Mu.Unlock()
+		json.NewEncoder(w).Encode(map[string]interface{}{})
+	}))()
+
+	w := httptest.NewRecorder()
+	req := httptest.NewRequest("POST", "/api/dossiers/d1/relations/revoke-chain", strings.NewReader(`{"targetUser":"bob"}`))
+	req.Header.Set("x-current-user", "alice")
+	DossiersRelationsRevokeChain(w, req, "d1")
+	if w.Code != 404 {
+		t.Errorf("status = %d, want 404: %s", w.Code, w.Body.String())
+	}
+}
+
+func TestAdminGraphDOT_StreamsPagesWithFilters(t *testing.T) {
+	pages := []map[string]interface{}{
+		{"tuples": []interface{}{
+			map[string]interface{}{"key": map[string]interface{}{"user": "user:alice", "relation": "owner", "object": "dossier:d1"}},
+			map[string]interface{}{"key": map[string]interface{}{"user": "organization:acme#member", "relation": "can_view", "object": "dossier:d2"}},
+		}, "continuation_token": "next"},
+		{"tuples": []interface{}{
+			map[string]interface{}{"key": map[string]interface{}{"user": "user:bob", "relation": "guardian", "object": "user:alice"}},
+		}},
+	}
+	defer setupFGA(t, http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var body map[string]interface{}
+		json.NewDecoder(r.Body).Decode(&body)
+		page := pages[0]
+		if body["continuation_token"] == "next" {
+			page = pages[1]
+		}
+		json.NewEncoder(w).Encode(page)
+	}))()
+
+	get := func(query string, admin bool) *httptest.ResponseRecorder {
+		w := httptest.NewRecorder()
+		req := httptest.NewRequest("GET", "/api/admin/graph.dot"+query, nil)
+		if admin {
+			req.Header.Set("x-manager-admin", "true")
+		}
+		AdminGraphDOT(w, req)
+		return w
+	}
+	if w := get("", false); w.Code != 403 {
+		t.Errorf("non-admin status = %d, want 403", w.Code)
+	}
+	dot := get("", true).Body.String()
+	if !strings.HasPrefix(dot, "digraph authz {") || !strings.Contains(dot, `"user:bob" -> "user:alice" [label="guardian"];`) || strings.Count(dot, "->") != 3 {
+		t.Errorf("dot = %s", dot)
+	}
+	if dot := get("?user=alice", true).Body.String(); strings.Count(dot, "->") != 2 || strings.Contains(dot, "acme") {
+		t.Errorf("user filter = %s", dot)
+	}
+	if dot := get("?type=organization", true).Body.String(); strings.Count(dot, "->") != 1 || !strings.Contains(dot, "acme#member") {
+		t.Errorf("type filter = %s", dot)
+	}
+}
+
+func TestDebugTuples_PaginatesWithFilters(t *testing.T) {
+	var bodies []map[string]interface{}
+	defer setupFGA(t, http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var body map[string]interface{}
+		json.NewDecoder(r.Body).Decode(&body)
+		bodies = append(bodies, body)
+		json.NewEncoder(w).Encode(map[string]interface{}{"tuples": []interface{}{}, "continuation_token": "page-2"})
+	}))()
+
+	get := func(query, roles string) *httptest.ResponseRecorder {
+		w := httptest.NewRecorder()
+		req := httptest.NewRequest("GET", "/api/dossiers/debug/tuples"+query, nil)
+		req.Header.Set("x-user-role", roles)
+		DebugTuples(w, req)
+		return w
+	}
+	if w := get("", "user"); w.Code != 403 {
+		t.Errorf("plain user status = %d, want 403", w.Code)
+	}
+	if w := get("?user=alice", "admin"); w.Code != 400 {
+		t.Errorf("user without type status = %d, want 400", w.Code)
+	}
+	if w := get("?pageSize=500", "admin"); w.Code != 400 {
+		t.Errorf("oversized page status = %d, want 400", w.Code)
+	}
+	w := get("?type=dossier&user=alice&pageSize=20&cursor=page-1", "admin")
+	var resp map[string]interface{}
+	json.NewDecoder(w.Body).Decode(&resp)
+	if w.Code != 200 || resp["cursor"] != "page-2" {
+		t.Fatalf("status = %d, body = %v", w.Code, resp)
+	}
+	sent := bodies[len(bodies)-1]
+	key, _ := sent["tuple_key"].(map[string]interface{})
+	if key["object"] != "dossier:" || key["user"] != "user:alice" || sent["page_size"] != float64(20) || sent["continuation_token"] != "page-1" {
+		t.Errorf("read request = %v", sent)
+	}
+}
+
+func TestAdminReset_PreviewThenConfirm(t *testing.T) {
+	defer resetStore(t)()
+	store.Data.Dossiers["d1"] = &store.Dossier{Title: "Taxes", Owners: []string{"alice"}, Type: "tax"}
+
+	seed := filepath.Join(t.TempDir(), "seed.json")
+	os.WriteFile(seed, []byte(`{"dossiers":{"s1":{"title":"Seeded","owners":["bob"],"type":"tax"}}}`), 0644)
+	origSeed := config.SeedFile
+	config.SeedFile = seed
+	defer func() { config.SeedFile = origSeed }()
+
+	var written, deleted []map[string]interface{}
+	cleanup := setupFGA(t, func(w http.ResponseWriter, r *http.Request) {
+		if strings.HasSuffix(r.URL.Path, "/read") {
+			json.NewEncoder(w).Encode(map[string]interface{}{"tuples": []map[string]interface{}{
+				{"key": map[string]string{"user": "user:alice", "relation": "owner", "object": "dossier:d1"}},
+			}})
+			return
+		}
+		var body struct {
+			Writes struct {
+				TupleKeys []map[string]interface{} `json:"tuple_keys"`
+			} `json:"writes"`
+			Deletes struct {
+				TupleKeys []map[string]interface{} `json:"tuple_keys"`
+			} `json:"deletes"`
+		}
+		json.NewDecoder(r.Body).Decode(&body)
+		written = append(written, body.Writes.TupleKeys...)
+		deleted = append(deleted, body.Deletes.TupleKeys...)
+		json.NewEncoder(w).Encode(map[string]interface{}{})
+	})
+	defer cleanup()
+
+	call := func(body string) (int, map[string]interface{}) {
+		w := httptest.NewRecorder()
+		req := httptest.NewRequest("POST", "/api/admin/reset", strings.NewReader(body))
+		req.Header.Set("x-manager-admin", "true")
+		AdminReset(w, req)
+		var resp map[string]interface{}
+		json.Unmarshal(w.Body.Bytes(), &resp)
+		return w.Code, resp
+	}
+
+	code, preview := call(`{"seed": true}`)
+	token, _ := preview["confirmToken"].(string)
+	if code != 200 || token == "" || preview["dossiers"] != float64(1) || preview["tuples"] != float64(1) {
+		t.Fatalf("preview = %d %v", code, preview)
+	}
+	if len(deleted) != 0 || store.Data.Dossiers["d1"] == nil {
+		t.Fatal("preview must not change anything")
+	}
+	if code, _ := call(`{"confirmToken": "wrong"}`); code != 400 {
+		t.Fatalf("wrong token status = %d, want 400", code)
+	}
+
+	undo := offerUndo("alice", compensation{Kind: "dossier", DossierId: "d0"}, nil)
+	recent.Record("alice", "d1")
+	cachedShareCandidates("alice")
+	nlPendingMu.Lock()
+	nlPending["nl-token"] = pendingCommand{user: "alice", expires: time.Now().Add(time.Minute)}
+	nlPendingMu.Unlock()
+	code, resp := call(`{"confirmToken": "` + token + `"}`)
+	if code != 200 || resp["tuplesDeleted"] != float64(1) {
+		t.Fatalf("reset = %d %v", code, resp)
+	}
+	undoMu.Lock()
+	_, kept := undoPending[undo["undoToken"].(string)]
+	undoMu.Unlock()
+	if kept {
+		t.Error("undo token survived the reset")
+	}
+	candidatesMu.Lock()
+	_, cached := candidateCache["alice"]
+	candidatesMu.Unlock()
+	nlPendingMu.Lock()
+	_, pendingCmd := nlPending["nl-token"]
+	nlPendingMu.Unlock()
+	if cached || pendingCmd || len(recent.List("alice")) != 0 {
+		t.Errorf("after reset: share candidates cached = %v, share command pending = %v, recent = %v", cached, pendingCmd, recent.List("alice"))
+	}
+	if _, ok := store.Data.Dossiers["d1"]; ok || store.Data.Dossiers["s1"] == nil {
+		t.Errorf("dossiers after reset = %v, want only the seeded one", store.Data.Dossiers)
+	}
+	if len(deleted) != 1 || len(written) == 0 || written[0]["object"] != "dossier:s1" {
+		t.Errorf("deleted = %v, written = %v", deleted, written)
+	}
+	if code, _ := call(`{"confirmToken": "` + token + `"}`); code != 400 {
+		t.Errorf("reused token status = %d, want 400", code)
+	}
+}
+
+func TestAdminFaults_OutageFailsFGACalls(t *testing.T) {
+	defer faults.Clear("")
+	cleanup := setupFGA(t, func(w http.ResponseWriter, r *http.Request) {
+		json.NewEncoder(w).Encode(map[string]interface{}{"allowed": true})
+	})
+	defer cleanup()
+
+	call := func(method, url, body string) *httptest.ResponseRecorder {
+		w := httptest.NewRecorder()
+		req := httptest.NewRequest(method, url, strings.NewReader(body))
+		req.Header.Set("x-manager-admin", "true")
+		AdminFaults(w, req)
+		return w
+	}
+	if w := call("PUT", "/api/admin/faults", `{"target": "ldap", "outage": true}`); w.Code != 400 {
+		t.Errorf("unknown target status = %d, want 400", w.Code)
+	}
+	if w := call("PUT", "/api/admin/faults", `{"target": "openfga", "outage": true, "durationSeconds": 60}`); w.Code != 200 {
+		t.Fatalf("PUT status = %d: %s", w.Code, w.Body.String())
+	}
+	if fga.Check(context.Background(), "user:alice", "viewer", "dossier:d1") {
+		t.Error("checks should fail closed during an injected outage")
+	}
+	w := call("DELETE", "/api/admin/faults?target=openfga", "")
+	if strings.Contains(w.Body.String(), `"openfga"`+":") {
+		t.Errorf("fault still listed after DELETE: %s", w.Body.String())
+	}
+	if !fga.Check(context.Background(), "user:alice", "viewer", "dossier:d1") {
+		t.Error("checks should pass again once the fault is cleared")
+	}
+}
+
+func TestDossiersList_ChecksRunInBoundedPool(t *testing.T) {
+	defer resetStore(t)()
+	var objects []interface{}
+	for _, id := range []string{"p1", "p2", "p3", "p4", "p5", "p6"} {
+		store.Data.Dossiers[id] = &store.Dossier{Title: id, Type: "tax", Owners: []string{"pia"}}
+		objects = append(objects, "dossier:"+id)
+	}
+	origConcurrency := config.ListCheckConcurrency.Get()
+	config.ListCheckConcurrency.Set(3)
+	defer config.ListCheckConcurrency.Set(origConcurrency)
+
+	var mu sync.Mutex
+	inFlight, maxInFlight := 0, 0
+	cleanup := setupFGA(t, func(w http.ResponseWriter, r *http.Request) {
+		if strings.HasSuffix(r.URL.Path, "/list-objects") {
+			json.NewEncoder(w).Encode(map[string]interface{}{"objects": objects})
+			return
+		}
+		mu.Lock()
+		inFlight++
+		maxInFlight = max(maxInFlight, inFlight)
+		mu.Unlock()
+		time.Sleep(20 * time.Millisecond)
+		mu.Lock()
+		inFlight--
+		mu.Unlock()
+		json.NewEncoder(w).Encode(map[string]interface{}{"allowed": true})
+	})
+	defer cleanup()
+
+	w := httptest.NewRecorder()
+	req := httptest.NewRequest("GET", "/api/dossiers/list", nil)
+	req.Header.Set("x-current-user", "pia")
+	DossiersList(w, req)
+	var body struct {
+		Dossiers []struct {
+			Id      string `json:"id"`
+			CanEdit bool   `json:"canEdit"`
+		} `json:"dossiers"`
+	}
+	json.Unmarshal(w.Body.Bytes(), &body)
+	if w.Code != 200 || len(body.Dossiers) != 6 {
+		t.Fatalf("status %d, %d dossiers: %s", w.Code, len(body.Dossiers), w.Body.String())
+	}
+	for _, d := range body.Dossiers {
+		if !d.CanEdit {
+			t.Errorf("dossier %s lost its permissions", d.Id)
+		}
+	}
+	if maxInFlight < 2 || maxInFlight > 3 {
+		t.Errorf("max concurrent checks = %d, want 2..3", maxInFlight)
+	}
+}
+
+func TestDossiersList_ChecksRunWithoutStoreLock(t *testing.T) {
+	defer resetStore(t)()
+	store.Data.Dossiers["p1"] = &store.Dossier{Title: "p1", Type: "tax", Owners: []string{"pia"}}
+	heldDuringCheck := false
+	cleanup := setupFGA(t, func(w http.ResponseWriter, r *http.Request) {
+		if strings.HasSuffix(r.URL.Path, "/list-objects") {
+			json.NewEncoder(w).Encode(map[string]interface{}{"objects": []interface{}{"dossier:p1"}})
+			return
+		}
+		// A writer must be able to take the store while checks are in flight.
+		if store.Mu.TryLock() {
+			store.Mu.Unlock()
+		} else {
+			heldDuringCheck = true
+		}
+		json.NewEncoder(w).Encode(map[string]interface{}{"allowed": true})
+	})
+	defer cleanup()
+
+	w := httptest.NewRecorder()
+	req := httptest.NewRequest("GET", "/api/dossiers/list", nil)
+	req.Header.Set("x-current-user", "pia")
+	DossiersList(w, req)
+	if w.Code != 200 {
+		t.Fatalf("status = %d: %s", w.Code, w.Body.String())
+	}
+	if heldDuringCheck {
+		t.Error("store.Mu was held during the permission checks")
+	}
+}
+
+func TestDossiersList_WorkerChecksCountAgainstBudget(t *testing.T) {
+	defer resetStore(t)()
+	defer budget.Reset()
+	origConcurrency := config.ListCheckConcurrency.Get()
+	config.ListCheckConcurrency.Set(4)
+	defer config.ListCheckConcurrency.Set(origConcurrency)
+	for _, id := range []string{"p1", "p2", "p3"} {
+		store.Data.Dossiers[id] = &store.Dossier{Title: id, Type: "tax", Owners: []string{"pia"}}
+	}
+	var calls atomic.Int64
+	defer setupFGA(t, func(w http.ResponseWriter, r *http.Request) {
+		calls.Add(1)
+		if strings.HasSuffix(r.URL.Path, "/list-objects") {
+			json.NewEncoder(w).Encode(map[string]interface{}{"objects": []interface{}{"dossier:p1", "dossier:p2", "dossier:p3"}})
+			return
+		}
+		json.NewEncoder(w).Encode(map[string]interface{}{"allowed": true})
+	})()
+	fga.FlushListCache()
+
+	mux := http.NewServeMux()
+	mux.HandleFunc("/api/dossiers/list", DossiersList)
+	w := httptest.NewRecorder()
+	req := httptest.NewRequest("GET", "/api/dossiers/list", nil)
+	req.Header.Set("x-current-user", "pia")
+	budget.Track(mux, mux).ServeHTTP(w, req)
+	if w.Code != 200 {
+		t.Fatalf("status = %d: %s", w.Code, w.Body.String())
+	}
+	if rs := budget.Stats()["GET /api/dossiers/list"]; rs.Calls != uint64(calls.Load()) || rs.Calls < 4 {
+		t.Errorf("counted %d calls, OpenFGA got %d", rs.Calls, calls.Load())
+	}
+}
+
+func TestDossiersList_GuestSeesOnlyPublic(t *testing.T) {
+	cleanStore := resetStore(t)
+	defer cleanStore()
+
+	store.Data.Dossiers["pub"] = &store.Dossier{Title: "Open Data", Type: "tax", Owners: []string{"alice"}, Public: true}
+	store.Data.Dossiers["anon"] = &store.Dossier{Title: "Created as anonymous", Type: "tax", Owners: []string{"anonymous"}}
+
+	cleanFGA := setupFGA(t, http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if strings.Contains(r.URL.Path, "list-objects") {
+			json.NewEncoder(w).Encode(map[string]interface{}{"objects": []interface{}{"dossier:pub", "dossier:anon"}})
+			return
+		}
+		json.NewEncoder(w).Encode(map[string]interface{}{"allowed": true})
+	}))
+	defer cleanFGA()
+
+	w := httptest.NewRecorder()
+	DossiersList(w, httptest.NewRequest("GET", "/api/dossiers/list", nil))
+
+	var body struct {
+		Dossiers []struct {
+			Id string `json:"id"`
+		} `json:"dossiers"`
+	}
+	json.NewDecoder(w.Body).Decode(&body)
+	if w.Code != 200 || len(body.Dossiers) != 1 || body.Dossiers[0].Id != "pub" {
+		t.Errorf("status %d, dossiers %+v, want only pub", w.Code, body.Dossiers)
+	}
+}
+
+func TestUserFields_RejectInvalidUsernames(t *testing.T) {
+	cleanStore := resetStore(t)
+	defer cleanStore()
+	store.Data.Dossiers["d1"] = &store.Dossier{Title: "T", Type: "tax", Owners: []string{"alice"}}
+	var writes int
+	cleanFGA := setupFGA(t, http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if strings.Contains(r.URL.Path, "write") {
+			writes++
+		}
+		json.NewEncoder(w).Encode(map[string]interface{}{"allowed": true})
+	}))
+	defer cleanFGA()
+
+	for _, name := range []string{"*", "bob:admin", "org#member", "bob smith"} {
+		w := httptest.NewRecorder()
+		req := httptest.NewRequest("POST", "/api/dossiers/d1/owners", strings.NewReader(`{"user":"`+name+`"}`))
+		req.Header.Set("x-current-user", "alice")
+		DossiersOwnersAdd(w, req, "d1")
+		if w.Code != 400 {
+			t.Errorf("owner %q: status = %d, want 400", name, w.Code)
+		}
+
+		w = httptest.NewRecorder()
+		req = httptest.NewRequest("POST", "/api/dossiers/guardianships/request", strings.NewReader(`{"to":"`+name+`"}`))
+		req.Header.Set("x-current-user", "alice")
+		GuardianshipRequest(w, req)
+		if w.Code != 400 {
+			t.Errorf("guardian %q: status = %d, want 400", name, w.Code)
+		}
+	}
+	if writes != 0 {
+		t.Errorf("%d tuple writes for invalid usernames", writes)
+	}
+}
+
+func TestDossiersDryRun_CommitsNothing(t *testing.T) {
+	cleanStore := resetStore(t)
+	defer cleanStore()
+	store.Data.Dossiers["d1"] = &store.Dossier{Title: "Tax", Type: "tax", Owners: []string{"alice"}}
+	store.Data.Guardianships["bob"] = []string{"alice"}
+	var writes int
+	cleanFGA := setupFGA(t, http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if strings.HasSuffix(r.URL.Path, "/write") {
+			writes++
+		}
+		json.NewEncoder(w).Encode(map[string]interface{}{"allowed": true})
+	}))
+	defer cleanFGA()
+
+	call := func(handler func(http.ResponseWriter, *http.Request), method, body string) map[string]interface{} {
+		w := httptest.NewRecorder()
+		req := httptest.NewRequest(method, "/api/dossiers/d1?dryRun=true", strings.NewReader(body))
+		req.Header.Set("x-current-user", "alice")
+		handler(w, req)
+		if w.Code != 200 {
+			t.Fatalf("%s status = %d: %s", method, w.Code, w.Body.String())
+		}
+		var resp map[string]interface{}
+		json.NewDecoder(w.Body).Decode(&resp)
+		return resp
+	}
+
+	created := call(DossiersCreate, "POST", `{"title":"New","type":"tax","public":true}`)
+	if n := len(created["writes"].([]interface{})); created["dryRun"] != true || n != 2 {
+		t.Errorf("create dry run = %v, want 2 tuples", created)
+	}
+	call(func(w http.ResponseWriter, r *http.Request) { DossiersUpdate(w, r, "d1") }, "PUT", `{"title":"Renamed"}`)
+	added := call(func(w http.ResponseWriter, r *http.Request) { DossiersRelationsAdd(w, r, "d1") }, "POST", `{"targetUser":"bob"}`)
+	if n := len(added["writes"].([]interface{})); n != 1 {
+		t.Errorf("relation dry run writes = %d, want 1", n)
+	}
+	deleted := call(func(w http.ResponseWriter, r *http.Request) { DossiersDelete(w, r, "d1") }, "DELETE", "")
+	if n := len(deleted["deletes"].([]interface{})); n != 1 {
+		t.Errorf("delete dry run deletes = %d, want 1", n)
+	}
+
+	if writes != 0 || len(store.Data.Dossiers) != 1 || store.Data.Dossiers["d1"].Title != "Tax" || len(store.Data.Dossiers["d1"].Relations) != 0 {
+		t.Errorf("dry runs changed state: %d writes, dossiers %v", writes, store.Data.Dossiers)
+	}
+}
+
+func TestUndo_RestoresDeletedDossier(t *testing.T) {
+	cleanStore := resetStore(t)
+	defer cleanStore()
+	store.Data.Dossiers["d1"] = &store.Dossier{Title: "Tax", Type: "tax", Owners: []string{"alice"}, Relations: []store.Relation{{User: "bob", Relation: "viewer"}}}
+	var written, deleted int
+	cleanFGA := setupFGA(t, http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var body struct {
+			Writes *struct {
+				TupleKeys []store.TupleKey `json:"tuple_keys"`
+			} `json:"writes"`
+			Deletes *struct {
+				TupleKeys []store.TupleKey `json:"tuple_keys"`
+			} `json:"deletes"`
+		}
+		json.NewDecoder(r.Body).Decode(&body)
+		if body.Writes != nil {
+			written += len(body.Writes.TupleKeys)
+		}
+		if body.Deletes != nil {
+			deleted += len(body.Deletes.TupleKeys)
+		}
+		json.NewEncoder(w).Encode(map[string]interface{}{"allowed": true})
+	}))
+	defer cleanFGA()
+
+	w := httptest.NewRecorder()
+	req := httptest.NewRequest("DELETE", "/api/dossiers/d1", nil)
+	req.Header.Set("x-current-user", "alice")
+	DossiersDelete(w, req, "d1")
+	var resp map[string]interface{}
+	json.NewDecoder(w.Body).Decode(&resp)
+	token, _ := resp["undoToken"].(string)
+	if w.Code != 200 || token == "" || store.Data.Dossiers["d1"] != nil || deleted != 2 {
+		t.Fatalf("delete: status %d, resp %v, %d deletes", w.Code, resp, deleted)
+	}
+
+	// Only the user who deleted it can undo.
+	w = httptest.NewRecorder()
+	req = httptest.NewRequest("POST", "/api/undo/"+token, nil)
+	req.Header.Set("x-current-user", "mallory")
+	Undo(w, req, token)
+	if w.Code != 404 {
+		t.Errorf("undo by another user status = %d, want 404", w.Code)
+	}
+
+	w = httptest.NewRecorder()
+	req = httptest.NewRequest("POST", "/api/undo/"+token, nil)
+	req.Header.Set("x-current-user", "alice")
+	Undo(w, req, token)
+	if d := store.Data.Dossiers["d1"]; w.Code != 200 || d == nil || d.Title != "Tax" || len(d.Relations) != 1 || written != 2 {
+		t.Fatalf("undo: status %d, dossier %+v, %d writes", w.Code, d, written)
+	}
+
+	w = httptest.NewRecorder()
+	Undo(w, req, token)
+	if w.Code != 404 {
+		t.Errorf("second undo status = %d, want 404", w.Code)
+	}
+}
+
+func TestDossiersFavorite_FilterAndAccessCheck(t *testing.T) {
+	cleanStore := resetStore(t)
+	defer cleanStore()
+	store.Data.Dossiers["d1"] = &store.Dossier{Title: "Tax", Type: "tax", Owners: []string{"alice"}}
+	store.Data.Dossiers["d2"] = &store.Dossier{Title: "Health", Type: "health", Owners: []string{"alice"}}
+	store.Data.Dossiers["secret"] = &store.Dossier{Title: "Not yours", Type: "tax", Owners: []string{"carol"}}
+
+	cleanFGA := setupFGA(t, http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if strings.Contains(r.URL.Path, "list-objects") {
+			json.NewEncoder(w).Encode(map[string]interface{}{"objects": []interface{}{"dossier:d1", "dossier:d2"}})
+			return
+		}
+		var req struct {
+			TupleKey store.TupleKey `json:"tuple_key"`
+		}
+		json.NewDecoder(r.Body).Decode(&req)
+		json.NewEncoder(w).Encode(map[string]interface{}{"allowed": req.TupleKey.Object != "dossier:secret"})
+	}))
+	defer cleanFGA()
+
+	favorite := func(method, id string) int {
+		w := httptest.NewRecorder()
+		req := httptest.NewRequest(method, "/api/dossiers/"+id+"/favorite", nil)
+		req.Header.Set("x-current-user", "alice")
+		DossiersFavorite(w, req, id)
+		return w.Code
+	}
+	if code := favorite("POST", "secret"); code != 403 {
+		t.Errorf("favorite without access: status = %d, want 403", code)
+	}
+	if code := favorite("POST", "missing"); code != 404 {
+		t.Errorf("favorite missing dossier: status = %d, want 404", code)
+	}
+	if code := favorite("POST", "d2"); code != 200 {
+		t.Fatalf("favorite d2: status = %d", code)
+	}
+	favorite("POST", "d2")
+	if got := store.Data.Favorites["alice"]; len(got) != 1 || got[0] != "d2" {
+		t.Errorf("favorites = %v, want [d2] once", got)
+	}
+
+	list := func(query string) []string {
+		w := httptest.NewRecorder()
+		req := httptest.NewRequest("GET", "/api/dossiers/list"+query, nil)
+		req.Header.Set("x-current-user", "alice")
+		DossiersList(w, req)
+		var body struct {
+			Dossiers []struct {
+				Id       string `json:"id"`
+				Favorite bool   `json:"favorite"`
+			} `json:"dossiers"`
+		}
+		json.NewDecoder(w.Body).Decode(&body)
+		var ids []string
+		for _, d := range body.Dossiers {
+			if d.Favorite {
+				ids = append(ids, d.Id+"*")
+			} else {
+				ids = append(ids, d.Id)
+			}
+		}
+		sort.Strings(ids)
+		return ids
+	}
+	if got := list(""); strings.Join(got, ",") != "d1,d2*" {
+		t.Errorf("list = %v, want d1 and favorite d2", got)
+	}
+	if got := list("?favorites=true"); strings.Join(got, ",") != "d2*" {
+		t.Errorf("favorites list = %v, want only d2", got)
+	}
+
+	if code := favorite("DELETE", "d2"); code != 200 {
+		t.Errorf("unfavorite: status = %d", code)
+	}
+	if _, ok := store.Data.Favorites["alice"]; ok {
+		t.Error("unfavoriting the last dossier should drop the user's entry")
+	}
+}
+
+func TestDossiersGet_RecordsRecentViews(t *testing.T) {
+	cleanStore := resetStore(t)
+	defer cleanStore()
+	store.Data.Dossiers["d1"] = &store.Dossier{Title: "Tax", Type: "tax", Owners: []string{"alice"}}
+	store.Data.Dossiers["d2"] = &store.Dossier{Title: "Health", Type: "health", Owners: []string{"alice"}}
+	origMax := config.RecentViewsMax.Get()
+	config.RecentViewsMax.Set(10)
+	defer config.RecentViewsMax.Set(origMax)
+	defer recent.ForgetUser("alice")
+
+	visible := []interface{}{"dossier:d1", "dossier:d2"}
+	cleanFGA := setupFGA(t, http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if strings.Contains(r.URL.Path, "list-objects") {
+			json.NewEncoder(w).Encode(map[string]interface{}{"objects": visible})
+			return
+		}
+		json.NewEncoder(w).Encode(map[string]interface{}{"allowed": true})
+	}))
+	defer cleanFGA()
+
+	for _, id := range []string{"d1", "d2"} {
+		w := httptest.NewRecorder()
+		req := httptest.NewRequest("GET", "/api/dossiers/"+id, nil)
+		req.Header.Set("x-current-user", "alice")
+		DossiersGet(w, req, id)
+		if w.Code != 200 || !strings.Contains(w.Body.String(), `"title":"`+store.Data.Dossiers[id].Title+`"`) {
+			t.Fatalf("get %s: status %d, body %s", id, w.Code, w.Body.String())
+		}
+	}
+
+	listRecent := func() string {
+		fga.FlushListCache()
+		w := httptest.NewRecorder()
+		req := httptest.NewRequest("GET", "/api/me/recent", nil)
+		req.Header.Set("x-current-user", "alice")
+		MeRecent(w, req)
+		var body struct {
+			Recent []struct {
+				Id string `json:"id"`
+			} `json:"recent"`
+		}
+		json.NewDecoder(w.Body).Decode(&body)
+		var ids []string
+		for _, e := range body.Recent {
+			ids = append(ids, e.Id)
+		}
+		return strings.Join(ids, ",")
+	}
+	if got := listRecent(); got != "d2,d1" {
+		t.Errorf("recent = %q, want d2,d1", got)
+	}
+	// Access to d2 is gone before its scrub ran: it is already hidden.
+	visible = []interface{}{"dossier:d1"}
+	if got := listRecent(); got != "d1" {
+		t.Errorf("recent after revocation = %q, want d1", got)
+	}
+}
+
+func TestSavedViews_RunAgainstAuthorizedList(t *testing.T) {
+	cleanStore := resetStore(t)
+	defer cleanStore()
+	store.Data.Dossiers["h1"] = &store.Dossier{Title: "Checkup", Type: "health", Owners: []string{"alice"}, Relations: []store.Relation{{User: "bob", Relation: "mandate_viewer"}}}
+	store.Data.Dossiers["h2"] = &store.Dossier{Title: "Dentist", Type: "health", Owners: []string{"alice"}}
+	store.Data.Dossiers["t1"] = &store.Dossier{Title: "Taxes", Type: "tax", Owners: []string{"alice"}, Relations: []store.Relation{{User: "bob", Relation: "mandate_viewer"}}}
+	store.Data.Dossiers["h3"] = &store.Dossier{Title: "Hidden", Type: "health", Owners: []string{"carol"}, Relations: []store.Relation{{User: "bob", Relation: "mandate_viewer"}}}
+
+	cleanFGA := setupFGA(t, http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if strings.Contains(r.URL.Path, "list-objects") {
+			json.NewEncoder(w).Encode(map[string]interface{}{"objects": []interface{}{"dossier:h1", "dossier:h2", "dossier:t1"}})
+			return
+		}
+		json.NewEncoder(w).Encode(map[string]interface{}{"allowed": true})
+	}))
+	defer cleanFGA()
+
+	create := func(user, body string) *httptest.ResponseRecorder {
+		w := httptest.NewRecorder()
+		req := httptest.NewRequest("POST", "/api/views", strings.NewReader(body))
+		req.Header.Set("x-current-user", user)
+		ViewsCreate(w, req)
+		return w
+	}
+	if w := create("alice", `{"name":"x","filter":{"colour":"red"}}`); w.Code != 400 {
+		t.Errorf("unknown filter field: status = %d, want 400", w.Code)
+	}
+	if w := create("alice", `{"name":"x","filter":{"sort":"size"}}`); w.Code != 400 {
+		t.Errorf("bad sort: status = %d, want 400", w.Code)
+	}
+	w := create("alice", `{"name":"My health dossiers shared with bob","filter":{"type":"health","owned":true,"sharedWith":"Bob","sort":"title"}}`)
+	if w.Code != 201 {
+		t.Fatalf("create: status %d, body %s", w.Code, w.Body.String())
+	}
+	var created struct {
+		View store.SavedView `json:"view"`
+	}
+	json.NewDecoder(w.Body).Decode(&created)
+	if w := create("alice", `{"name":"My health dossiers shared with bob"}`); w.Code != 409 {
+		t.Errorf("duplicate name: status = %d, want 409", w.Code)
+	}
+
+	results := func(user string) (int, string) {
+		w := httptest.NewRecorder()
+		req := httptest.NewRequest("GET", "/api/views/"+created.View.Id+"/results", nil)
+		req.Header.Set("x-current-user", user)
+		ViewsResults(w, req, created.View.Id)
+		var body struct {
+			Dossiers []struct {
+				Id string `json:"id"`
+			} `json:"dossiers"`
+		}
+		json.NewDecoder(w.Body).Decode(&body)
+		var ids []string
+		for _, d := range body.Dossiers {
+			ids = append(ids, d.Id)
+		}
+		return w.Code, strings.Join(ids, ",")
+	}
+	if code, ids := results("alice"); code != 200 || ids != "h1" {
+		t.Errorf("results: status %d, ids %q, want h1", code, ids)
+	}
+	if code, _ := results("bob"); code != 404 {
+		t.Errorf("another user's view: status = %d, want 404", code)
+	}
+
+	w = httptest.NewRecorder()
+	req := httptest.NewRequest("GET", "/api/views", nil)
+	req.Header.Set("x-current-user", "alice")
+	ViewsList(w, req)
+	if !strings.Contains(w.Body.String(), created.View.Id) {
+		t.Errorf("list = %s, want the saved view", w.Body.String())
+	}
+}
+
+func TestAdminPosture_FlagsRiskyPatterns(t *testing.T) {
+	cleanStore := resetStore(t)
+	defer cleanStore()
+	var rels []store.Relation
+	for i := 0; i < 10; i++ {
+		rels = append(rels, store.Relation{User: fmt.Sprintf("user%d", i), Relation: "mandate_viewer"})
+	}
+	store.Data.Dossiers["wide"] = &store.Dossier{Title: "Wide", Type: "tax", Owners: []string{"alice"}, Relations: rels}
+	store.Data.Dossiers["med"] = &store.Dossier{Title: "Scan", Type: "health", Owners: []string{"alice"}, Public: true}
+	for _, ward := range []string{"w1", "w2", "w3", "w4", "w5"} {
+		store.Data.Guardianships[ward] = []string{"gina"}
+	}
+	store.Data.Organizations["solo"] = &store.Organization{Name: "Solo", Members: []string{"alice"}, Admins: []string{"alice"}}
+	store.Data.Organizations["pair"] = &store.Organization{Name: "Pair", Members: []string{"alice", "bob"}, Admins: []string{"alice", "bob"}}
+
+	cleanFGA := setupFGA(t, http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		json.NewEncoder(w).Encode(map[string]interface{}{"tuples": []interface{}{
+			map[string]interface{}{"key": map[string]string{"user": "user:mallory", "relation": "owner", "object": "dossier:wide"}},
+		}})
+	}))
+	defer cleanFGA()
+
+	w := httptest.NewRecorder()
+	req := httptest.NewRequest("GET", "/api/admin/posture", nil)
+	req.Header.Set("x-manager-admin", "true")
+	AdminPosture(w, req)
+	var body struct {
+		Findings []postureFinding `json:"findings"`
+		Summary  map[string]int   `json:"summary"`
+	}
+	json.NewDecoder(w.Body).Decode(&body)
+	want := map[string]int{"overshared_dossier": 1, "public_health_dossier": 1, "guardian_of_many": 1, "single_admin_organization": 1, "stale_tuple": 1}
+	for kind, n := range want {
+		if body.Summary[kind] != n {
+			t.Errorf("summary[%s] = %d, want %d (summary %v)", kind, body.Summary[kind], n, body.Summary)
+		}
+	}
+	if len(body.Findings) == 0 || body.Findings[0].Severity != "high" || len(body.Findings[0].Links) == 0 {
+		t.Errorf("findings should be ordered by severity with links: %+v", body.Findings)
+	}
+
+	w = httptest.NewRecorder()
+	AdminPosture(w, httptest.NewRequest("GET", "/api/admin/posture", nil))
+	if w.Code != 403 {
+		t.Errorf("non-admin: status = %d, want 403", w.Code)
+	}
+}
+
+func TestStaleGrants_FlagAndRevokeUnusedMandates(t *testing.T) {
+	cleanStore := resetStore(t)
+	defer cleanStore()
+	store.Data.Dossiers["sg1"] = &store.Dossier{Title: "Tax", Type: "tax", Owners: []string{"alice"}, Relations: []store.Relation{
+		{User: "bob", Relation: "mandate_viewer"},
+		{User: "carol", Relation: "mandate_editor"},
+	}}
+	var deleted []store.TupleKey
+	cleanFGA := setupFGA(t, http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if strings.HasSuffix(r.URL.Path, "/write") {
+			var body struct {
+				Deletes struct {
+					TupleKeys []store.TupleKey `json:"tuple_keys"`
+				} `json:"deletes"`
+			}
+			json.NewDecoder(r.Body).Decode(&body)
+			deleted = append(deleted, body.Deletes.TupleKeys...)
+		}
+		json.NewEncoder(w).Encode(map[string]interface{}{"allowed": true})
+	}))
+	defer cleanFGA()
+
+	// bob exercises his mandate; carol never does.
+	fga.Check(context.Background(), "user:bob", "viewer", "dossier:sg1")
+
+	admin := func(method, path string) *httptest.ResponseRecorder {
+		w := httptest.NewRecorder()
+		req := httptest.NewRequest(method, path, nil)
+		req.Header.Set("x-manager-admin", "true")
+		if method == "GET" {
+			AdminStaleGrants(w, req)
+		} else {
+			AdminStaleGrantsRevoke(w, req)
+		}
+		return w
+	}
+	w := admin("GET", "/api/admin/stale-grants")
+	var body struct {
+		Grants   []staleGrant `json:"grants"`
+		Complete bool         `json:"complete"`
+	}
+	json.NewDecoder(w.Body).Decode(&body)
+	if w.Code != 200 || len(body.Grants) != 1 || body.Grants[0].User != "carol" || body.Grants[0].LastUsed != nil || body.Complete {
+		t.Fatalf("status %d, body %+v, want only carol's unused mandate", w.Code, body)
+	}
+	if w := admin("GET", "/api/admin/stale-grants?days=x"); w.Code != 400 {
+		t.Errorf("bad days: status = %d, want 400", w.Code)
+	}
+
+	if w := admin("POST", "/api/admin/stale-grants/revoke?dryRun=true"); w.Code != 200 || len(deleted) != 0 {
+		t.Errorf("dry run: status %d, deleted %v", w.Code, deleted)
+	}
+	if w := admin("POST", "/api/admin/stale-grants/revoke"); w.Code != 200 {
+		t.Fatalf("revoke: status %d, body %s", w.Code, w.Body.String())
+	}
+	if len(deleted) != 1 || deleted[0].User != "user:carol" {
+		t.Errorf("deleted tuples = %v, want carol's mandate", deleted)
+	}
+	if rels := store.Data.Dossiers["sg1"].Relations; len(rels) != 1 || rels[0].User != "bob" {
+		t.Errorf("relations = %v, want only bob's", rels)
+	}
+}
+
+func TestHideExistence_NoExistenceOracle(t *testing.T) {
+	cleanStore := resetStore(t)
+	defer cleanStore()
+	store.Data.Dossiers["private"] = &store.Dossier{Title: "Private", Type: "tax", Owners: []string{"alice"}}
+	origHide := config.HideExistence.Get()
+	defer config.HideExistence.Set(origHide)
+
+	cleanFGA := setupFGA(t, http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if strings.Contains(r.URL.Path, "list-objects") {
+			json.NewEncoder(w).Encode(map[string]interface{}{"objects": []interface{}{}})
+			return
+		}
+		// mallory is allowed nothing.
+		json.NewEncoder(w).Encode(map[string]interface{}{"allowed": false})
+	}))
+	defer cleanFGA()
+
+	// route mirrors the dossier router in main.go: the hiding gate runs before the handler.
+	type endpoint struct {
+		method, body string
+		handler      func(http.ResponseWriter, *http.Request, string)
+		exempt       bool
+	}
+	endpoints := []endpoint{
+		{"GET", "", DossiersGet, false},
+		{"PUT", `{"title":"x"}`, DossiersUpdate, false},
+		{"PUT", `not json`, DossiersUpdate, false},
+		{"DELETE", "", DossiersDelete, false},
+		{"GET", "", DossiersRelationsGet, false},
+		{"POST", `{"targetUser":"mallory","relation":"mandate_viewer"}`, DossiersRelationsAdd, false},
+		{"POST", `{}`, DossiersRelationsAdd, false},
+		{"DELETE", `{"targetUser":"bob","relation":"mandate_viewer"}`, DossiersRelationsDelete, false},
+		{"POST", `{"targetUser":"bob"}`, DossiersRelationsRevokeChain, false},
+		{"POST", `{"user":"mallory"}`, DossiersOwnersAdd, false},
+		{"DELETE", `{"user":"alice"}`, DossiersOwnersRemove, false},
+		{"POST", "", DossiersArchive, false},
+		{"POST", "", DossiersRestore, false},
+		{"POST", "", DossiersTogglePublic, false},
+		{"POST", `{"user":"bob"}`, DossiersBlock, false},
+		{"POST", `{"user":"bob"}`, DossiersUnblock, false},
+		{"POST", `{"orgId":"bosa"}`, DossiersSetOrg, false},
+		{"GET", "", DossiersShareSuggestions, false},
+		{"GET", "", DossiersAccessRequests, false},
+		{"POST", `{"reason":"x"}`, DossiersEmergencyCheck, false},
+		{"POST", "", DossiersFavorite, false},
+		{"POST", `{"message":"please"}`, DossiersAccessRequest, true},
+	}
+	route := func(e endpoint, id string) (int, string) {
+		w := httptest.NewRecorder()
+		req := httptest.NewRequest(e.method, "/api/dossiers/"+id, strings.NewReader(e.body))
+		req.Header.Set("x-current-user", "mallory")
+		if e.exempt || !DossierHidden(w, req, id) {
+			e.handler(w, req, id)
+		}
+		return w.Code, w.Body.String()
+	}
+
+	config.HideExistence.Set(true)
+	for i, e := range endpoints {
+		ghostCode, ghostBody := route(e, "ghost")
+		privCode, privBody := route(e, "private")
+		if ghostCode != privCode || ghostBody != privBody {
+			t.Errorf("endpoint %d (%s): unknown dossier got %d %s, existing one %d %s", i, e.method, ghostCode, ghostBody, privCode, privBody)
+		}
+	}
+	store.Mu.RLock()
+	requests := len(store.Data.AccessRequests)
+	store.Mu.RUnlock()
+	if requests != 1 {
+		t.Errorf("access requests recorded = %d, want only the one for the existing dossier", requests)
+	}
+
+	config.HideExistence.Set(false)
+	if code, _ := route(endpoints[0], "private"); code != 403 {
+		t.Errorf("without hiding, an existing dossier answers %d, want 403", code)
+	}
+}
+
+func TestFGAOutage_NotReadAsDenialOrEmpty(t *testing.T) {
+	defer resetStore(t)()
+	store.Data.Dossiers["d1"] = &store.Dossier{Title: "Taxes", Owners: []string{"alice"}}
+	defer setupFGA(t, http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusServiceUnavailable)
+	}))()
+	fga.FlushListCache()
+	origHide := config.HideExistence.Get()
+	config.HideExistence.Set(true)
+	defer config.HideExistence.Set(origHide)
+
+	w := httptest.NewRecorder()
+	req := httptest.NewRequest("GET", "/api/dossiers", nil)
+	req.Header.Set("x-current-user", "alice")
+	DossiersList(w, req)
+	if w.Code != 503 {
+		t.Errorf("list: status = %d, want 503 rather than an empty list", w.Code)
+	}
+
+	w = httptest.NewRecorder()
+	req = httptest.NewRequest("GET", "/api/dossiers/d1", nil)
+	req.Header.Set("x-current-user", "alice")
+	if !DossierHidden(w, req, "d1") || w.Code != 503 {
+		t.Errorf("hiding check: status = %d, want 503 rather than 404", w.Code)
+	}
+}
+
+func TestFGAOutage_HandlersAnswer503(t *testing.T) {
+	defer resetStore(t)()
+	store.Data.Organizations["org1"] = &store.Organization{Name: "BOSA", Members: []string{"alice"}, Admins: []string{"alice"}}
+	store.Data.Dossiers["d1"] = &store.Dossier{Title: "Taxes", Owners: []string{"alice"}}
+	defer setupFGA(t, http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Retry-After", "7")
+		w.WriteHeader(http.StatusServiceUnavailable)
+	}))()
+
+	w := httptest.NewRecorder()
+	req := httptest.NewRequest("POST", "/api/dossiers/organizations/org1/members", strings.NewReader(`{"member":"bob"}`))
+	req.Header.Set("x-current-user", "alice")
+	OrganizationsAddMember(w, req, "org1")
+	if w.Code != 503 || w.Header().Get("Retry-After") != "7" || !strings.Contains(w.Body.String(), `"retryAfter":7`) {
+		t.Errorf("add member: status %d, Retry-After %q, body %s", w.Code, w.Header().Get("Retry-After"), w.Body)
+	}
+	if members := store.Data.Organizations["org1"].Members; len(members) != 1 {
+		t.Errorf("members = %v, want unchanged", members)
+	}
+
+	w = httptest.NewRecorder()
+	req = httptest.NewRequest("PUT", "/api/dossiers/d1", strings.NewReader(`{"title":"Renamed"}`))
+	req.Header.Set("x-current-user", "alice")
+	DossiersUpdate(w, req, "d1")
+	if w.Code != 503 || w.Header().Get("Retry-After") != "7" {
+		t.Errorf("update: status %d, Retry-After %q, want 503 rather than 403", w.Code, w.Header().Get("Retry-After"))
+	}
+	if title := store.Data.Dossiers["d1"].Title; title != "Taxes" {
+		t.Errorf("title = %q, want unchanged", title)
+	}
+}
+
+func TestLockDossier_DeletedBetweenCheckAndLock(t *testing.T) {
+	cleanStore := resetStore(t)
+	defer cleanStore()
+	writes := 0
+	cleanFGA := setupFGA(t, http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if strings.Contains(r.URL.Path, "/write") {
+			writes++
+			json.NewEncoder(w).Encode(map[string]interface{}{})
+			return
+		}
+		// A concurrent delete lands while the caller's check is in flight.
+		store.Mu.Lock()
+		delete(store.Data.Dossiers, "d1")
+		store.Mu.Unlock()
+		json.NewEncoder(w).Encode(map[string]interface{}{"allowed": true})
+	}))
+	defer cleanFGA()
+
+	for _, h := range []func(http.ResponseWriter, *http.Request, string){DossiersRelationsDelete, DossiersUpdate, DossiersDelete} {
+		store.Mu.Lock()
+		store.Data.Dossiers["d1"] = &store.Dossier{Title: "Tax", Type: "tax", Owners: []string{"alice"}}
+		store.Mu.Unlock()
+		w := httptest.NewRecorder()
+		req := httptest.NewRequest("POST", "/api/dossiers/d1", strings.NewReader(`{"targetUser":"bob","relation":"mandate_viewer","title":"x"}`))
+		req.Header.Set("x-current-user", "alice")
+		h(w, req, "d1")
+		if w.Code != 404 {
+			t.Errorf("status = %d, want 404 for a dossier deleted after the check: %s", w.Code, w.Body.String())
+		}
+	}
+	if writes != 0 {
+		t.Errorf("%d OpenFGA writes for a deleted dossier, want none", writes)
+	}
+	store.Mu.RLock()
+	_, resurrected := store.Data.Dossiers["d1"]
+	store.Mu.RUnlock()
+	if resurrected {
+		t.Error("the deleted dossier was written back")
+	}
+}
+
+func TestResourcesRouter_VehicleLien(t *testing.T) {
+	defer resetStore(t)()
+	resources.Register(resources.Type{
+		Name: "car", Plural: "cars",
+		Fields:  []resources.Field{{Name: "plate", Required: true}},
+		Editors: []string{"co_driver"},
+		Viewers: []string{"insurer", "lienholder"},
+		Lien:    "lienholder",
+	})
+	store.Data.Resources["car:c1"] = &store.Resource{Type: "car", Id: "c1", Fields: map[string]string{"plate": "1-ABC-123"},
+		Owners: []string{"alice"}, Relations: []store.Relation{{User: "bob", Relation: "co_driver"}}}
+
+	// alice owns, bob co-drives (editor), bank only views.
+	failWrites := false
+	cleanup := setupFGA(t, func(w http.ResponseWriter, r *http.Request) {
+		if strings.HasSuffix(r.URL.Path, "/write") && failWrites {
+			json.NewEncoder(w).Encode(map[string]interface{}{"code": "validation_error", "message": "rejected"})
+			return
+		}
+		var body struct {
+			TupleKey map[string]string `json:"tuple_key"`
+		}
+		json.NewDecoder(r.Body).Decode(&body)
+		user, relation := body.TupleKey["user"], body.TupleKey["relation"]
+		allowed := user == "user:alice" || (user == "user:bob" && relation != "owner") || (user == "user:bank" && relation == "viewer")
+		json.NewEncoder(w).Encode(map[string]interface{}{"allowed": allowed})
+	})
+	defer cleanup()
+
+	do := func(method, path, user, body string) *httptest.ResponseRecorder {
+		w := httptest.NewRecorder()
+		req := httptest.NewRequest(method, path, strings.NewReader(body))
+		req.Header.Set("x-current-user", user)
+		ResourcesRouter(w, req)
+		return w
+	}
+	lien := `{"targetUser":"bank","relation":"lienholder"}`
+
+	if w := do("POST", "/api/resources/cars/c1/relations", "bob", lien); w.Code != 403 {
+		t.Errorf("lien granted by a co-driver: status %d, want 403", w.Code)
+	}
+	if w := do("POST", "/api/resources/cars/c1/relations", "bob", `{"targetUser":"carol","relation":"co_driver"}`); w.Code != 403 {
+		t.Errorf("co-driver granted by a co-driver: status %d, want 403", w.Code)
+	}
+	if w := do("POST", "/api/resources/cars/c1/relations", "alice", lien); w.Code != 200 {
+		t.Fatalf("lien granted by the owner: status %d: %s", w.Code, w.Body.String())
+	}
+	w := do("GET", "/api/resources/cars/c1", "alice", "")
+	var view map[string]interface{}
+	json.NewDecoder(w.Body).Decode(&view)
+	if liens, _ := view["liens"].([]interface{}); len(liens) != 1 || liens[0] != "bank" {
+		t.Errorf("liens = %v, want [bank]", view["liens"])
+	}
+	if w := do("DELETE", "/api/resources/cars/c1", "alice", ""); w.Code != 409 {
+		t.Errorf("delete under lien: status %d, want 409", w.Code)
+	}
+	if w := do("DELETE", "/api/resources/cars/c1/relations", "alice", lien); w.Code != 403 {
+		t.Errorf("lien released by the owner: status %d, want 403", w.Code)
+	}
+	if w := do("DELETE", "/api/resources/cars/c1/relations", "bank", `{"targetUser":"bob","relation":"co_driver"}`); w.Code != 403 {
+		t.Errorf("viewer revoking a co-driver: status %d, want 403", w.Code)
+	}
+	if w := do("DELETE", "/api/resources/cars/c1/relations", "bank", lien); w.Code != 200 {
+		t.Errorf("lien released by its holder: status %d, want 200", w.Code)
+	}
+	if w := do("DELETE", "/api/resources/cars/c1", "bob", ""); w.Code != 403 {
+		t.Errorf("delete by a co-driver: status %d, want 403", w.Code)
+	}
+	failWrites = true
+	if w := do("DELETE", "/api/resources/cars/c1", "alice", ""); w.Code != 500 || store.Data.Resources["car:c1"] == nil {
+		t.Errorf("delete whose tuples were not removed: status %d, want 500 and the resource kept", w.Code)
+	}
+	failWrites = false
+	if w := do("DELETE", "/api/resources/cars/c1", "alice", ""); w.Code != 200 {
+		t.Errorf("delete once the lien is released: status %d, want 200", w.Code)
+	}
+}
+
+func TestTourSetup_PreparesScenarioFixture(t *testing.T) {
+	cleanStore := resetStore(t)
+	defer cleanStore()
+	var written, deleted []map[string]interface{}
+	cleanFGA := setupFGA(t, func(w http.ResponseWriter, r *http.Request) {
+		var body struct {
+			Writes struct {
+				TupleKeys []map[string]interface{} `json:"tuple_keys"`
+			} `json:"writes"`
+			Deletes struct {
+				TupleKeys []map[string]interface{} `json:"tuple_keys"`
+			} `json:"deletes"`
+		}
+		json.NewDecoder(r.Body).Decode(&body)
+		written = append(written, body.Writes.TupleKeys...)
+		deleted = append(deleted, body.Deletes.TupleKeys...)
+		json.NewEncoder(w).Encode(map[string]interface{}{})
+	})
+	defer cleanFGA()
+
+	setup := func(id string, admin bool) int {
+		w := httptest.NewRecorder()
+		req := httptest.NewRequest("POST", "/api/tour/"+id+"/setup", nil)
+		req.Header.Set("x-current-user", "alice")
+		if admin {
+			req.Header.Set("x-manager-admin", "true")
+		}
+		TourSetup(w, req, id)
+		return w.Code
+	}
+
+	if code := setup("org-access", false); code != 403 {
+		t.Errorf("setup by a non-admin: status %d, want 403", code)
+	}
+	if code := setup("nope", true); code != 404 {
+		t.Errorf("unknown scenario: status %d, want 404", code)
+	}
+	if code := setup("org-access", true); code != 200 {
+		t.Fatalf("setup: status %d", code)
+	}
+	if d := store.Data.Dossiers["tour-org-dossier"]; d == nil || d.OrgId != "tour-org" || store.Data.Organizations["tour-org"] == nil {
+		t.Fatalf("fixture not in the store: %+v", d)
+	}
+	// owner + org_parent for the dossier, 2 members + 1 admin for the organization.
+	if len(written) != 5 || len(deleted) != 0 {
+		t.Errorf("first setup wrote %d and deleted %d tuples, want 5 and 0", len(written), len(deleted))
+	}
+
+	store.Data.Dossiers["tour-org-dossier"].Title = "Changed during the demo"
+	written, deleted = nil, nil
+	if code := setup("org-access", true); code != 200 {
+		t.Fatalf("second setup: status %d", code)
+	}
+	if len(written) != 5 || len(deleted) != 5 {
+		t.Errorf("second setup wrote %d and deleted %d tuples, want 5 and 5", len(written), len(deleted))
+	}
+	if store.Data.Dossiers["tour-org-dossier"].Title == "Changed during the demo" {
+		t.Error("setup should restore the fixture")
+	}
+
+	setup("guardian-access", true)
+	setup("guardian-access", true)
+	if got := store.Data.Guardianships["tour-child"]; len(got) != 1 || got[0] != "tour-parent" {
+		t.Errorf("guardians of tour-child = %v, want [tour-parent] once", got)
+	}
+	if got := store.Data.Guardianships["alice"]; len(got) != 0 {
+		t.Errorf("guardians of alice = %v, want none", got)
+	}
+}
+
+func TestGuardianshipDeny_NotifiesRequester(t *testing.T) {
+	defer resetStore(t)()
+	as := func(user, method, target, body string) *http.Request {
+		r := httptest.NewRequest(method, target, strings.NewReader(body))
+		r.Header.Set("x-current-user", user)
+		return r
+	}
+	w := httptest.NewRecorder()
+	GuardianshipRequest(w, as("alice", "POST", "/api/guardianships/request", `{"to":"bob"}`))
+	var created struct{ Id string }
+	json.NewDecoder(w.Body).Decode(&created)
+	if w.Code != 200 || created.Id == "" {
+		t.Fatalf("request: %d %s", w.Code, w.Body)
+	}
+	cursor := func(user string) uint64 {
+		w := httptest.NewRecorder()
+		MeNotifications(w, as(user, "GET", "/api/me/notifications", ""))
+		var resp struct{ Cursor uint64 }
+		json.NewDecoder(w.Body).Decode(&resp)
+		return resp.Cursor
+	}
+	since := cursor("alice")
+
+	w = httptest.NewRecorder()
+	GuardianshipDeny(w, as("bob", "POST", "/api/guardianships/"+created.Id+"/deny", ""), created.Id)
+	var decided struct{ Request store.GuardianshipRequest }
+	json.NewDecoder(w.Body).Decode(&decided)
+	if w.Code != 200 || decided.Request.Status != "denied" || decided.Request.DecidedBy != "bob" || decided.Request.UpdatedAt.IsZero() {
+		t.Fatalf("deny: %d %+v", w.Code, decided.Request)
+	}
+	w = httptest.NewRecorder()
+	GuardianshipDeny(w, as("bob", "POST", "/api/guardianships/"+created.Id+"/deny", ""), created.Id)
+	if w.Code != 400 {
+		t.Errorf("second deny: %d, want 400", w.Code)
+	}
+
+	w = httptest.NewRecorder()
+	MeNotifications(w, as("alice", "GET", fmt.Sprintf("/api/me/notifications?since=%d", since), ""))
+	var resp struct{ Notifications []events.Event }
+	json.NewDecoder(w.Body).Decode(&resp)
+	if len(resp.Notifications) != 1 {
+		t.Fatalf("notifications = %+v", resp.Notifications)
+	}
+	if n := resp.Notifications[0]; n.Type != events.GuardianshipDenied || n.Actor != "bob" || n.Data["requestId"] != created.Id {
+		t.Errorf("notification = %+v", n)
+	}
+}
+
+func TestMeInbox(t *testing.T) {
+	defer resetStore(t)()
+	old, recent := store.NewMeta("carol"), store.NewMeta("dave")
+	old.CreatedAt = old.CreatedAt.Add(-time.Hour)
+	store.Data.Dossiers["d1"] = &store.Dossier{Title: "Taxes", Owners: []string{"alice"}}
+	store.Data.Organizations["o1"] = &store.Organization{Name: "Acme", Admins: []string{"alice"}, Members: []string{"alice"}}
+	store.Data.GuardianshipRequests = []store.GuardianshipRequest{
+		{Id: "g1", From: "bob", To: "alice", Status: "pending", Meta: recent},
+		{Id: "g2", From: "bob", To: "alice", Status: "denied", Meta: recent},
+		{Id: "g3", From: "alice", To: "bob", Status: "pending", Meta: recent},
+	}
+	store.Data.AccessRequests = []store.AccessRequest{
+		{Id: "a1", DossierId: "d1", User: "dave", Status: "pending", Meta: recent},
+		{Id: "a2", DossierId: "d1", User: "carol", Status: "pending", Meta: old},
+	}
+	store.Data.JoinRequests = []store.JoinRequest{{Id: "j1", OrgId: "o1", User: "erin", Status: "pending", Meta: recent}}
+
+	w := httptest.NewRecorder()
+	r := httptest.NewRequest("GET", "/api/me/inbox", nil)
+	r.Header.Set("x-current-user", "alice")
+	MeInbox(w, r)
+	var resp struct {
+		Items  []inboxItem
+		Counts map[string]int
+		Total  int
+	}
+	json.NewDecoder(w.Body).Decode(&resp)
+	var ids []string
+	for _, it := range resp.Items {
+		ids = append(ids, it.Id)
+	}
+	if got := strings.Join(ids, ","); got != "a2,a1,g1,j1" {
+		t.Errorf("items = %s, want access requests oldest first, then guardianship, then join", got)
+	}
+	if resp.Total != 4 || resp.Counts["access"] != 2 || resp.Counts["guardianship"] != 1 || resp.Counts["join"] != 1 {
+		t.Errorf("counts = %v, total %d", resp.Counts, resp.Total)
+	}
+	if it := resp.Items[3]; it.Approve != "/api/dossiers/organizations/o1/join-requests/j1/approve" || it.Title != "Acme" {
+		t.Errorf("join item = %+v", it)
+	}
+}
+
+func TestDossierTypeRules_Health(t *testing.T) {
+	defer resetStore(t)()
+	var writes int
+	defer setupFGA(t, http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if strings.HasSuffix(r.URL.Path, "/write") {
+			writes++
+		}
+		json.NewEncoder(w).Encode(map[string]interface{}{"allowed": true})
+	}))()
+	send := func(handler func(http.ResponseWriter, *http.Request), method, body string) *httptest.ResponseRecorder {
+		w := httptest.NewRecorder()
+		req := httptest.NewRequest(method, "/api/dossiers", strings.NewReader(body))
+		req.Header.Set("x-current-user", "alice")
+		handler(w, req)
+		return w
+	}
+
+	if w := send(DossiersCreate, "POST", `{"title":"Scan","type":"health","public":true}`); w.Code != 400 {
+		t.Errorf("public health dossier: %d, want 400", w.Code)
+	}
+	w := send(DossiersCreate, "POST", `{"title":"Scan","type":"health"}`)
+	var created map[string]interface{}
+	json.NewDecoder(w.Body).Decode(&created)
+	if w.Code != 200 || created["sensitivity"] != "sensitive" {
+		t.Fatalf("create: %d, sensitivity %v, want the type's default", w.Code, created["sensitivity"])
+	}
+	id := created["id"].(string)
+	if w := send(func(w http.ResponseWriter, r *http.Request) { DossiersTogglePublic(w, r, id) }, "POST", ""); w.Code != 400 {
+		t.Errorf("toggle public: %d, want 400", w.Code)
+	}
+	if w := send(func(w http.ResponseWriter, r *http.Request) { DossiersUpdate(w, r, id) }, "PUT", `{"sensitivity":"normal"}`); w.Code != 400 {
+		t.Errorf("lower sensitivity: %d, want 400", w.Code)
+	}
+	store.Data.Guardianships["alice"] = []string{"bob"}
+	if w := send(func(w http.ResponseWriter, r *http.Request) { DossiersRelationsAdd(w, r, id) }, "POST", `{"targetUser":"bob"}`); w.Code != 400 {
+		t.Errorf("all-round mandate: %d, want 400", w.Code)
+	}
+	if w := send(func(w http.ResponseWriter, r *http.Request) { DossiersRelationsAdd(w, r, id) }, "POST", `{"targetUser":"bob","level":"view"}`); w.Code != 200 {
+		t.Errorf("view mandate: %d %s, want 200", w.Code, w.Body)
+	}
+	if d := store.Data.Dossiers[id]; d.Public || d.Sensitivity != "sensitive" || len(d.Relations) != 1 || writes != 2 {
+		t.Errorf("dossier = %+v after %d writes", d, writes)
+	}
+}
+
+func TestListResponse_Envelope(t *testing.T) {
+	defer resetStore(t)()
+	defer config.ResponseEnvelope.Set(config.ResponseEnvelope.Get())
+	store.Data.Organizations["org1"] = &store.Organization{Name: "BOSA", Members: []string{"alice"}, Admins: []string{"alice"}}
+
+	list := func(mode string) map[string]interface{} {
+		config.ResponseEnvelope.Set(mode)
+		w := httptest.NewRecorder()
+		req := httptest.NewRequest("GET", "/api/dossiers/organizations", nil)
+		req.Header.Set("x-request-id", "req-1")
+		OrganizationsList(w, req)
+		var body map[string]interface{}
+		json.NewDecoder(w.Body).Decode(&body)
+		return body
+	}
+	if body := list("legacy"); body["organizations"] == nil || body["data"] != nil {
+		t.Errorf("legacy body = %v", body)
+	}
+	body := list("envelope")
+	if data, _ := body["data"].([]interface{}); len(data) != 1 || body["organizations"] != nil {
+		t.Errorf("envelope body = %v", body)
+	}
+	if body["requestId"] != "req-1" || body["generatedAt"] == nil {
+		t.Errorf("envelope metadata = %v", body)
+	}
+	if body := list("both"); body["organizations"] == nil || body["data"] == nil {
+		t.Errorf("both body = %v", body)
+	}
+}
+
+func TestJobs_TupleExportRunsInBackground(t *testing.T) {
+	defer setupFGA(t, http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		json.NewEncoder(w).Encode(map[string]interface{}{"tuples": []map[string]interface{}{
+			{"key": map[string]string{"user": "user:alice", "relation": "owner", "object": "dossier:d1"}},
+		}})
+	}))()
+	admin := func(method, path, body string) *httptest.ResponseRecorder {
+		w := httptest.NewRecorder()
+		r := httptest.NewRequest(method, path, strings.NewReader(body))
+		r.Header.Set("x-manager-admin", "true")
+		parts := strings.Split(strings.TrimPrefix(path, "/api/admin/jobs/"), "/")
+		switch {
+		case method == "POST":
+			JobsCreate(w, r)
+		case len(parts) == 2:
+			JobsDownload(w, r, parts[0])
+		default:
+			JobsGet(w, r, parts[0])
+		}
+		return w
+	}
+
+	if w := admin("POST", "/api/admin/jobs", `{"kind":"tuples","format":"csv"}`); w.Code != 400 {
+		t.Errorf("csv format status = %d, want 400", w.Code)
+	}
+	w := admin("POST", "/api/admin/jobs", `{"kind":"tuples","format":"yaml"}`)
+	if w.Code != 202 || !strings.HasPrefix(w.Header().Get("Location"), "/api/admin/jobs/") {
+		t.Fatalf("create status = %d, Location %q", w.Code, w.Header().Get("Location"))
+	}
+	var job jobView
+	json.NewDecoder(w.Body).Decode(&job)
+	for i := 0; job.Status != "done" && i < 200; i++ {
+		time.Sleep(5 * time.Millisecond)
+		json.NewDecoder(admin("GET", "/api/admin/jobs/"+job.Id, "").Body).Decode(&job)
+	}
+	if job.Status != "done" || job.Done != 1 || job.Download == "" {
+		t.Fatalf("job = %+v", job)
+	}
+	w = admin("GET", job.Download, "")
+	if w.Code != 200 || w.Header().Get("Content-Type") != "application/yaml" || !strings.Contains(w.Body.String(), "dossier:d1") {
+		t.Errorf("download status = %d, %q: %s", w.Code, w.Header().Get("Content-Type"), w.Body.String())
+	}
+	JobsCancel(httptest.NewRecorder(), func() *http.Request {
+		r := httptest.NewRequest("DELETE", "/api/admin/jobs/"+job.Id, nil)
+		r.Header.Set("x-manager-admin", "true")
+		return r
+	}(), job.Id)
+	if w := admin("GET", "/api/admin/jobs/"+job.Id, ""); w.Code != 404 {
+		t.Errorf("discarded job status = %d, want 404", w.Code)
+	}
+}
+
+func TestDossiersList_ByRelation(t *testing.T) {
+	defer resetStore(t)()
+	for _, id := range []string{"d1", "d2", "d3"} {
+		store.Data.Dossiers[id] = &store.Dossier{Title: id, Type: "tax", Owners: []string{"bob"}}
+	}
+	var listed []string
+	defer setupFGA(t, http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if strings.Contains(r.URL.Path, "list-objects") {
+			var body map[string]string
+			json.NewDecoder(r.Body).Decode(&body)
+			listed = append(listed, body["relation"])
+			objects := map[string][]string{"viewer": {"dossier:d1", "dossier:d2", "dossier:d3"}, "editor": {"dossier:d2"}, "owner": {"dossier:d2", "dossier:d3"}}
+			json.NewEncoder(w).Encode(map[string]interface{}{"objects": objects[body["relation"]]})
+			return
+		}
+		json.NewEncoder(w).Encode(map[string]interface{}{"allowed": true})
+	}))()
+
+	list := func(query string) (int, []string) {
+		w := httptest.NewRecorder()
+		req := httptest.NewRequest("GET", "/api/dossiers/list"+query, nil)
+		req.Header.Set("x-current-user", "alice")
+		DossiersList(w, req)
+		var body struct{ Dossiers []dossierView }
+		json.NewDecoder(w.Body).Decode(&body)
+		var ids []string
+		for _, d := range body.Dossiers {
+			ids = append(ids, d.Id)
+		}
+		sort.Strings(ids)
+		return w.Code, ids
+	}
+	if _, ids := list("?relation=editor"); strings.Join(ids, ",") != "d2" {
+		t.Errorf("editor dossiers = %v, want d2", ids)
+	}
+	if _, ids := list("?relation=editor,owner"); strings.Join(ids, ",") != "d2,d3" {
+		t.Errorf("editor or owner dossiers = %v, want d2,d3", ids)
+	}
+	if strings.Join(listed, ",") != "editor,owner" {
+		t.Errorf("ListObjects relations = %v, want one (cached) call per relation", listed)
+	}
+	if code, _ := list("?relation=guardian"); code != 400 {
+		t.Errorf("unknown relation status = %d, want 400", code)
+	}
+}
+
+func TestDossiersSharedByMe(t *testing.T) {
+	defer resetStore(t)()
+	store.Data.Dossiers["d1"] = &store.Dossier{Title: "Taxes", Owners: []string{"alice"},
+		Relations: []store.Relation{{User: "bob", Relation: "mandate_viewer", GrantedBy: "alice"}}, Public: true}
+	store.Data.Dossiers["d2"] = &store.Dossier{Title: "Private", Owners: []string{"alice"}}
+	store.Data.Dossiers["d3"] = &store.Dossier{Title: "Not mine", Owners: []string{"carol"}, Public: true}
+	held := map[string][]store.TupleKey{
+		"dossier:d1": {
+			{User: "user:alice", Relation: "owner", Object: "dossier:d1"},
+			{User: "user:bob", Relation: "mandate_viewer", Object: "dossier:d1"},
+			{User: "user:dave", Relation: "viewer", Object: "dossier:d1"},
+		},
+	}
+	defer setupFGA(t, http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var body struct {
+			TupleKey map[string]string `json:"tuple_key"`
+		}
+		json.NewDecoder(r.Body).Decode(&body)
+		tuples := []map[string]interface{}{}
+		for _, tk := range held[body.TupleKey["object"]] {
+			tuples = append(tuples, map[string]interface{}{"key": map[string]string{"user": tk.User, "relation": tk.Relation, "object": tk.Object}})
+		}
+		json.NewEncoder(w).Encode(map[string]interface{}{"tuples": tuples})
+	}))()
+
+	w := httptest.NewRecorder()
+	req := httptest.NewRequest("GET", "/api/dossiers/shared-by-me", nil)
+	req.Header.Set("x-current-user", "alice")
+	DossiersSharedByMe(w, req)
+	if w.Code != 200 {
+		t.Fatalf("status = %d: %s", w.Code, w.Body)
+	}
+	var resp struct {
+		Shared     []sharedObject
+		Mismatched int
+	}
+	json.NewDecoder(w.Body).Decode(&resp)
+	if len(resp.Shared) != 1 || resp.Shared[0].Id != "d1" {
+		t.Fatalf("shared = %+v, want only d1", resp.Shared)
+	}
+	inFga := map[string]bool{}
+	for _, g := range resp.Shared[0].Grants {
+		inFga[g.Kind+":"+g.Grantee] = g.InFga
+	}
+	if !inFga["mandate:bob"] || inFga["public:*"] || len(inFga) != 2 {
+		t.Errorf("grants = %+v, want bob's mandate in FGA and the public flag missing", resp.Shared[0].Grants)
+	}
+	if u := resp.Shared[0].Untracked; len(u) != 1 || u[0].User != "user:dave" {
+		t.Errorf("untracked = %+v, want dave's viewer tuple", u)
+	}
+	if resp.Mismatched != 1 {
+		t.Errorf("mismatched = %d, want 1", resp.Mismatched)
+	}
+}
+
+func TestWildcardTuples_ImportGuardAndReport(t *testing.T) {
+	defer resetStore(t)()
+	store.Data.Dossiers["d1"] = &store.Dossier{Title: "Brochure", Type: "general", Owners: []string{"alice"}}
+	store.Data.Dossiers["d2"] = &store.Dossier{Title: "Scan", Type: "health", Owners: []string{"alice"}}
+	var written int
+	defer setupFGA(t, func(w http.ResponseWriter, r *http.Request) {
+		if strings.HasSuffix(r.URL.Path, "/read") {
+			tuples := []map[string]interface{}{}
+			for _, tk := range [][3]string{{"user:alice", "owner", "dossier:d1"}, {"user:*", "public", "dossier:d1"}, {"user:*", "public", "dossier:d2"}} {
+				tuples = append(tuples, map[string]interface{}{"key": map[string]string{"user": tk[0], "relation": tk[1], "object": tk[2]}})
+			}
+			json.NewEncoder(w).Encode(map[string]interface{}{"tuples": tuples})
+			return
+		}
+		written++
+		json.NewEncoder(w).Encode(map[string]interface{}{})
+	})()
+
+	importFile := func(query, file string) int {
+		w := httptest.NewRecorder()
+		req := httptest.NewRequest("POST", "/api/admin/tuples/import"+query, strings.NewReader(file))
+		req.Header.Set("Content-Type", "application/yaml")
+		req.Header.Set("x-manager-admin", "true")
+		TuplesImport(w, req)
+		return w.Code
+	}
+	if code := importFile("?confirm=public", "- user: user:*\n  relation: public\n  object: dossier:d2\n"); code != 400 {
+		t.Errorf("wildcard on a health dossier: status = %d, want 400", code)
+	}
+	if code := importFile("?confirm=public", "- user: user:*\n  relation: viewer\n  object: dossier:d1\n"); code != 400 {
+		t.Errorf("wildcard on another relation: status = %d, want 400", code)
+	}
+	if code := importFile("", "- user: user:*\n  relation: public\n  object: dossier:d1\n"); code != 409 || written != 0 {
+		t.Errorf("unconfirmed wildcard: status = %d, writes = %d, want 409 and none", code, written)
+	}
+	if code := importFile("?confirm=public", "- user: user:*\n  relation: public\n  object: dossier:d1\n"); code != 200 || written != 1 {
+		t.Errorf("confirmed wildcard: status = %d, writes = %d, want 200 and one", code, written)
+	}
+	if e := audit.Recent("user:*", 1); len(e) != 1 || e[0].Severity != audit.SeverityHigh || e[0].Resource != "dossier:d1" {
+		t.Errorf("audit entries = %+v, want a high severity publish of dossier:d1", e)
+	}
+
+	w := httptest.NewRecorder()
+	req := httptest.NewRequest("GET", "/api/admin/wildcard-tuples", nil)
+	req.Header.Set("x-manager-admin", "true")
+	AdminWildcardTuples(w, req)
+	var resp struct {
+		Tuples     []wildcardTuple
+		Violations int
+	}
+	json.NewDecoder(w.Body).Decode(&resp)
+	if len(resp.Tuples) != 2 || resp.Violations != 1 {
+		t.Fatalf("report = %+v, want two wildcard tuples, one violation", resp)
+	}
+	if d1, d2 := resp.Tuples[0], resp.Tuples[1]; !d1.Permitted || d1.InStore || d1.Title != "Brochure" || d2.Permitted || d2.Reason == "" {
+		t.Errorf("report = %+v", resp.Tuples)
+	}
+}
+
+func TestKeycloakHook(t *testing.T) {
+	defer resetStore(t)()
+	store.Data.Organizations["acme"] = &store.Organization{Name: "Acme", Members: []string{"alice", "bob"}}
+	origPrepare := prepareUser
+	defer func() { prepareUser = origPrepare }()
+	prepareUser = func(user string) { cachedShareCandidates(user) }
+
+	post := func(auth, body string) *httptest.ResponseRecorder {
+		w := httptest.NewRecorder()
+		r := httptest.NewRequest("POST", "/hooks/keycloak", strings.NewReader(body))
+		if auth != "" {
+			r.Header.Set("Authorization", "Bearer "+auth)
+		}
+		KeycloakHook(w, r)
+		return w
+	}
+	if w := post("s3cret", `{"type":"LOGIN","details":{"username":"alice"}}`); w.Code != 503 {
+		t.Fatalf("without KEYCLOAK_HOOK_SECRET: status = %d, want 503", w.Code)
+	}
+	os.Setenv(config.KeycloakHookSecret, "s3cret")
+	config.LoadSecrets()
+	defer func() {
+		os.Unsetenv(config.KeycloakHookSecret)
+		config.LoadSecrets()
+	}()
+	if w := post("wrong", `{"type":"LOGIN","details":{"username":"alice"}}`); w.Code != 401 {
+		t.Fatalf("wrong secret: status = %d, want 401", w.Code)
+	}
+
+	for _, body := range []string{`{"type":"REGISTER","details":{"username":"Alice","first_name":"Alice","last_name":"Martin"}}`, `{"type":"access.LOGIN","details":{"username":"alice"}}`} {
+		if w := post("s3cret", body); w.Code != 200 {
+			t.Fatalf("%s: status = %d: %s", body, w.Code, w.Body.String())
+		}
+	}
+	p := store.Data.Profiles["alice"]
+	if p == nil || p.Logins != 1 || p.LastLogin == nil || p.CreatedBy != "keycloak" || p.DisplayName != "Alice Martin" {
+		t.Fatalf("alice's profile = %+v, want it provisioned with one login", p)
+	}
+	candidatesMu.Lock()
+	e, ok := candidateCache["alice"]
+	candidatesMu.Unlock()
+	if !ok || e.candidates["bob"] != "organization:Acme" {
+		t.Errorf("share suggestions were not prepared at sign-in: %+v", e)
+	}
+
+	if w := post("s3cret", `{"type":"LOGOUT","details":{"username":"alice"}}`); w.Code != 200 || p.LastLogout == nil {
+		t.Errorf("logout: status = %d, lastLogout = %v", w.Code, p.LastLogout)
+	}
+	if w := post("s3cret", `{"type":"LOGOUT","details":{"username":"carol"}}`); w.Code != 200 || store.Data.Profiles["carol"] != nil {
+		t.Errorf("a logout must not provision a profile (status %d)", w.Code)
+	}
+	var resp map[string]interface{}
+	w := post("s3cret", `{"type":"UPDATE_PROFILE","details":{"username":"alice"}}`)
+	json.Unmarshal(w.Body.Bytes(), &resp)
+	if w.Code != 200 || resp["handled"] != false {
+		t.Errorf("other events should be ignored, got %d %v", w.Code, resp)
+	}
+}
+
+func TestUsersProfile(t *testing.T) {
+	defer resetStore(t)()
+	store.Data.Profiles = map[string]*store.Profile{
+		"alice": {DisplayName: "Alice Martin", Email: "alice@example.org", Color: "#2f6f8f", Logins: 3},
+	}
+	get := func(caller, id string) map[string]interface{} {
+		w := httptest.NewRecorder()
+		r := httptest.NewRequest("GET", "/api/users/"+id+"/profile", nil)
+		r.Header.Set("x-current-user", caller)
+		UsersProfile(w, r, id)
+		var body map[string]interface{}
+		json.Unmarshal(w.Body.Bytes(), &body)
+		if w.Code != 200 {
+			t.Fatalf("GET %s as %s: status %d: %s", id, caller, w.Code, w.Body.String())
+		}
+		return body
+	}
+	if own := get("alice", "alice"); own["displayName"] != "Alice Martin" || own["email"] != "alice@example.org" || own["logins"] != float64(3) {
+		t.Errorf("own profile = %v", own)
+	}
+	if other := get("bob", "alice"); other["displayName"] != "Alice Martin" || other["email"] != nil {
+		t.Errorf("profile seen by another user = %v, want no email", other)
+	}
+	if none := get("alice", "bob"); none["displayName"] != "bob" || none["provisioned"] != false {
+		t.Errorf("profile of a user without one = %v", none)
+	}
+
+	// List responses carry a card for each user they name.
+	store.Data.Guardianships["bob"] = []string{"alice"}
+	w := httptest.NewRecorder()
+	r := httptest.NewRequest("GET", "/api/dossiers/admin/guardianships", nil)
+	r.Header.Set("x-manager-admin", "true")
+	GuardianshipsListAll(w, r)
+	var list struct {
+		Profiles map[string]struct{ DisplayName, Color string }
+	}
+	json.Unmarshal(w.Body.Bytes(), &list)
+	if list.Profiles["alice"].DisplayName != "Alice Martin" || list.Profiles["bob"].DisplayName != "bob" || list.Profiles["bob"].Color == "" {
+		t.Errorf("profiles = %+v", list.Profiles)
+	}
+}
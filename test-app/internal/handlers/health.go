@@ -0,0 +1,108 @@
+package handlers
+
+import (
+	"net/http"
+	"time"
+
+	"test-app/internal/audit"
+	"test-app/internal/config"
+	"test-app/internal/endpoints"
+	"test-app/internal/fga"
+	"test-app/internal/httputil"
+	"test-app/internal/store"
+	"test-app/internal/warmup"
+)
+
+// Health probes OpenFGA, the data volume and the audit sink, returning 503
+// with a per-component breakdown when any critical dependency is degraded.
+func Health(w http.ResponseWriter, r *http.Request) {
+	healthy := true
+	components := map[string]interface{}{}
+
+	fgaStatus := map[string]interface{}{"status": "ok", "storeId": config.FgaStoreId, "modelId": config.FgaModelId}
+	if !config.FgaReady {
+		fgaStatus["status"] = "down"
+		fgaStatus["error"] = "OpenFGA config not loaded"
+		healthy = false
+	} else if err := fga.ProbeModel(r.Context()); err != nil {
+		fgaStatus["status"] = "down"
+		fgaStatus["error"] = err.Error()
+		healthy = false
+	}
+	fgaStatus["endpoints"] = endpoints.Statuses(config.OpenfgaURL)
+	components["openfga"] = fgaStatus
+
+	// Not ready until the canary passed and the caches are primed
+	warm := warmup.Current()
+	warmStatus := map[string]interface{}{"status": "ok", "warmup": warm}
+	if !warm.Done {
+		warmStatus["status"] = "warming"
+		if warm.Error != "" {
+			warmStatus["status"] = "down"
+		}
+		healthy = false
+	}
+	components["warmup"] = warmStatus
+
+	volumeStatus := map[string]interface{}{"status": "ok", "path": store.DataFile()}
+	if err := store.CheckWritable(); err != nil {
+		volumeStatus["status"] = "down"
+		volumeStatus["error"] = err.Error()
+		healthy = false
+	}
+	store.Mu.RLock()
+	if !store.LastSave.IsZero() {
+		volumeStatus["lastSave"] = store.LastSave.Format(time.RFC3339)
+	}
+	if store.LastSaveErr != nil {
+		volumeStatus["status"] = "down"
+		volumeStatus["lastSaveError"] = store.LastSaveErr.Error()
+		healthy = false
+	}
+	store.Mu.RUnlock()
+	// Low space is a warning: saves still succeed until the volume is full
+	disk := store.WarnIfLowDisk(int64(config.DataMinFreeBytes.Get()))
+	volumeStatus["disk"] = disk
+	if disk.Low && volumeStatus["status"] == "ok" {
+		volumeStatus["status"] = "low"
+	}
+	size := store.Size(config.StoreSizeWarnBytes)
+	volumeStatus["dataBytes"], volumeStatus["archiveBytes"] = size.DataBytes, size.ArchiveBytes
+	components["dataVolume"] = volumeStatus
+
+	auditStats := audit.Stats()
+	auditStatus := map[string]interface{}{"status": "ok", "url": config.AuditURL, "queue": auditStats}
+	if config.AuditURL == "" {
+		auditStatus["status"] = "disabled"
+	} else {
+		auditStatus["endpoints"] = endpoints.Statuses(config.AuditURL)
+		if auditStats.LastError != "" {
+			// Audit delivery is best-effort, so failures are reported but do not fail the probe
+			auditStatus["status"] = "degraded"
+		}
+	}
+	components["audit"] = auditStatus
+
+	// Assertion failures signal an authorization regression, not an outage, so
+	// like audit they are surfaced without failing the probe
+	if run := fga.LastAssertionRun(); run != nil {
+		assertionStatus := map[string]interface{}{
+			"status": "ok", "lastRun": run.Time.Format(time.RFC3339),
+			"total": run.Total, "failed": run.Failed,
+		}
+		if run.Failed > 0 {
+			assertionStatus["status"] = "failing"
+		}
+		components["assertions"] = assertionStatus
+	}
+
+	status, code := "healthy", http.StatusOK
+	if !healthy {
+		status, code = "degraded", http.StatusServiceUnavailable
+	}
+	httputil.JSONResponse(w, map[string]interface{}{
+		"status": status, "service": "test-app",
+		"uptime": time.Since(config.StartTime).String(), "fgaReady": config.FgaReady,
+		"components": components,
+	}, code)
+}
@@ -0,0 +1,47 @@
+package handlers
+
+import (
+	"net/http"
+
+	"test-app/internal/config"
+	"test-app/internal/fga"
+	"test-app/internal/httputil"
+	"test-app/internal/i18n"
+	"test-app/internal/store"
+)
+
+// DossierHidden reports whether, in information-hiding mode
+// (config.HideExistence), dossier id must be answered as if it did not exist
+// because it is unknown or the caller cannot view it; it then writes that
+// answer. Unknown and unviewable dossiers get the same 404 before any
+// validation runs, so neither the status nor the body of a response tells the
+// caller whether a dossier exists. Callers who can view a dossier already know
+// it exists and get the handler's own 403s. Manager admins see every dossier.
+func DossierHidden(w http.ResponseWriter, r *http.Request, id string) bool {
+	if !config.HideExistence.Get() || !config.FgaReady || isManagerAdminDossiers(r) {
+		return false
+	}
+	visible, err := viewableDossier(r, id)
+	if err != nil {
+		// Neither a 404 nor the handler's answer: the dossier could not be checked.
+		fgaUnavailable(w, r, err)
+		return true
+	}
+	if visible {
+		return false
+	}
+	httputil.JSONError(w, i18n.T(r, "Dossier not found"), 404)
+	return true
+}
+
+// viewableDossier reports whether dossier id exists and the caller can view
+// it, along with the OpenFGA failure, if any.
+func viewableDossier(r *http.Request, id string) (bool, error) {
+	store.Mu.RLock()
+	_, ok := store.Data.Dossiers[id]
+	store.Mu.RUnlock()
+	if !ok {
+		return false, nil
+	}
+	return fga.Allowed(r.Context(), fga.UserRef(httputil.GetUser(r)), "viewer", fga.ObjectRef(fga.TypeDossier, id))
+}
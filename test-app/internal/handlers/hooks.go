@@ -0,0 +1,130 @@
+package handlers
+
+import (
+	"crypto/subtle"
+	"encoding/json"
+	"log"
+	"net/http"
+	"strings"
+	"time"
+
+	"test-app/internal/config"
+	"test-app/internal/httputil"
+	"test-app/internal/i18n"
+	"test-app/internal/profiles"
+	"test-app/internal/rolesync"
+	"test-app/internal/sandbox"
+	"test-app/internal/store"
+	"test-app/internal/users"
+	"test-app/internal/warmup"
+)
+
+// maxHookBody bounds one Keycloak event.
+const maxHookBody = 1 << 20
+
+// keycloakEvent is the part of a Keycloak user event this app uses, as posted
+// by an HTTP event listener. Some listeners prefix the type with "access.".
+type keycloakEvent struct {
+	Type     string `json:"type"`
+	Username string `json:"username"`
+	Details  struct {
+		Username  string `json:"username"`
+		Email     string `json:"email"`
+		FirstName string `json:"first_name"`
+		LastName  string `json:"last_name"`
+		Locale    string `json:"locale"`
+	} `json:"details"`
+}
+
+// claims returns the profile fields the event carries (registrations do).
+func (ev keycloakEvent) claims() profiles.Claims {
+	d := ev.Details
+	return profiles.Claims{Name: strings.TrimSpace(d.FirstName + " " + d.LastName), Email: d.Email, Locale: d.Locale}
+}
+
+// prepareUser runs after a sign-in is acknowledged (synchronously in tests).
+var prepareUser = func(user string) { go prepare(user) }
+
+// prepare fills user's caches ahead of their first requests: permissions
+// (see warmup.User) and share suggestions.
+func prepare(user string) {
+	if !config.FgaReady {
+		return
+	}
+	warmup.User(user)
+	sandbox.Live(func() { cachedShareCandidates(user) })
+}
+
+// bearerMatches reports whether r carries "Authorization: Bearer <secret>".
+func bearerMatches(r *http.Request, secret string) bool {
+	given := strings.TrimPrefix(r.Header.Get("Authorization"), "Bearer ")
+	return subtle.ConstantTimeCompare([]byte(given), []byte(secret)) == 1
+}
+
+// KeycloakHook handles POST /hooks/keycloak, called by Keycloak's event
+// listener with "Authorization: Bearer <KEYCLOAK_HOOK_SECRET>". REGISTER and
+// LOGIN provision the user's profile (with the names and email a registration
+// carries) and warm their caches; LOGIN and LOGOUT make their next request
+// apply their roles afresh (see rolesync.OnLogin).
+// Other event types are acknowledged and ignored.
+func KeycloakHook(w http.ResponseWriter, r *http.Request) {
+	secret := config.Secret(config.KeycloakHookSecret)
+	if secret == "" {
+		httputil.JSONError(w, i18n.T(r, "Keycloak hook is not configured (KEYCLOAK_HOOK_SECRET)"), 503)
+		return
+	}
+	if !bearerMatches(r, secret) {
+		httputil.JSONError(w, i18n.T(r, "Invalid hook secret"), 401)
+		return
+	}
+	var ev keycloakEvent
+	if err := json.NewDecoder(http.MaxBytesReader(w, r.Body, maxHookBody)).Decode(&ev); err != nil {
+		httputil.JSONError(w, i18n.T(r, "Invalid request body"), 400)
+		return
+	}
+	event := strings.ToUpper(strings.TrimPrefix(ev.Type, "access."))
+	if event != "LOGIN" && event != "LOGOUT" && event != "REGISTER" {
+		httputil.JSONResponse(w, map[string]interface{}{"event": event, "handled": false}, 200)
+		return
+	}
+	name := ev.Details.Username
+	if name == "" {
+		name = ev.Username
+	}
+	user, err := users.Normalize(name)
+	if err != nil || user == "" {
+		httputil.JSONError(w, i18n.T(r, "Invalid username"), 400)
+		return
+	}
+
+	now := time.Now().UTC()
+	store.Mu.Lock()
+	profile := store.Data.Profiles[user]
+	if profile == nil && event != "LOGOUT" {
+		profile, _ = profiles.Ensure(user, "keycloak")
+		log.Printf("Provisioned profile for %s (%s)", user, event)
+	}
+	if profile != nil {
+		switch event {
+		case "LOGIN":
+			profile.Logins++
+			profile.LastLogin = &now
+		case "LOGOUT":
+			profile.LastLogout = &now
+		}
+		profiles.Apply(profile, ev.claims())
+		profile.Updated()
+	}
+	store.Mu.Unlock()
+	if profile != nil {
+		store.Save()
+	}
+
+	if event != "REGISTER" {
+		rolesync.Forget(user)
+	}
+	if event != "LOGOUT" {
+		prepareUser(user)
+	}
+	httputil.JSONResponse(w, map[string]interface{}{"event": event, "handled": true, "user": user}, 200)
+}
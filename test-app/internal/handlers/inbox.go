@@ -0,0 +1,90 @@
+package handlers
+
+import (
+	"net/http"
+	"sort"
+	"time"
+
+	"test-app/internal/fga"
+	"test-app/internal/httputil"
+	"test-app/internal/store"
+)
+
+// Inbox item kinds, in priority order: someone waiting on one of the caller's
+// dossiers first, then requests about the caller themselves, then organization
+// membership.
+const (
+	inboxAccess       = "access"
+	inboxGuardianship = "guardianship"
+	inboxJoin         = "join"
+)
+
+var inboxPriority = map[string]int{inboxAccess: 0, inboxGuardianship: 1, inboxJoin: 2}
+
+// inboxItem is one pending request the caller can decide.
+type inboxItem struct {
+	Kind      string    `json:"kind"`
+	Id        string    `json:"id"`
+	From      string    `json:"from"`
+	Object    string    `json:"object"`
+	Title     string    `json:"title,omitempty"`
+	Message   string    `json:"message,omitempty"`
+	CreatedAt time.Time `json:"createdAt"`
+	// Approve and Deny are the POST endpoints deciding the request
+	Approve string `json:"approve"`
+	Deny    string `json:"deny"`
+}
+
+// MeInbox lists every pending request the caller can decide in one list:
+// guardianship requests addressed to them, access requests on dossiers they
+// own and join requests to organizations they administer. Items are ordered
+// by kind, then oldest first, with counts per kind and a total for a badge.
+// Ownership and administration are read from the store; deciding still goes
+// through the usual OpenFGA checks.
+func MeInbox(w http.ResponseWriter, r *http.Request) {
+	user := httputil.GetUser(r)
+	items := []inboxItem{}
+	store.Mu.RLock()
+	for _, req := range store.Data.GuardianshipRequests {
+		if req.To == user && req.Status == "pending" {
+			base := "/api/dossiers/guardianships/" + req.Id
+			items = append(items, inboxItem{
+				Kind: inboxGuardianship, Id: req.Id, From: req.From, Object: fga.UserRef(user),
+				CreatedAt: req.CreatedAt, Approve: base + "/accept", Deny: base + "/deny",
+			})
+		}
+	}
+	for _, req := range store.Data.AccessRequests {
+		d := store.Data.Dossiers[req.DossierId]
+		if req.Status == "pending" && d != nil && d.IsOwner(user) {
+			base := "/api/dossiers/" + req.DossierId + "/access-requests/" + req.Id
+			items = append(items, inboxItem{
+				Kind: inboxAccess, Id: req.Id, From: req.User, Object: fga.ObjectRef(fga.TypeDossier, req.DossierId),
+				Title: d.Title, Message: req.Message, CreatedAt: req.CreatedAt, Approve: base + "/approve", Deny: base + "/deny",
+			})
+		}
+	}
+	for _, req := range store.Data.JoinRequests {
+		org := store.Data.Organizations[req.OrgId]
+		if req.Status == "pending" && org != nil && httputil.Contains(org.Admins, user) {
+			base := "/api/dossiers/organizations/" + req.OrgId + "/join-requests/" + req.Id
+			items = append(items, inboxItem{
+				Kind: inboxJoin, Id: req.Id, From: req.User, Object: fga.ObjectRef(fga.TypeOrganization, req.OrgId),
+				Title: org.Name, Message: req.Message, CreatedAt: req.CreatedAt, Approve: base + "/approve", Deny: base + "/deny",
+			})
+		}
+	}
+	store.Mu.RUnlock()
+
+	sort.SliceStable(items, func(i, j int) bool {
+		if pi, pj := inboxPriority[items[i].Kind], inboxPriority[items[j].Kind]; pi != pj {
+			return pi < pj
+		}
+		return items[i].CreatedAt.Before(items[j].CreatedAt)
+	})
+	counts := map[string]int{inboxAccess: 0, inboxGuardianship: 0, inboxJoin: 0}
+	for _, it := range items {
+		counts[it.Kind]++
+	}
+	listResponse(w, r, "items", items, listMeta{Pagination: map[string]interface{}{"total": len(items)}, Extra: map[string]interface{}{"counts": counts}})
+}
@@ -0,0 +1,211 @@
+package handlers
+
+import (
+	"context"
+	"encoding/json"
+	"math"
+	"net/http"
+	"sort"
+	"strings"
+
+	"test-app/internal/audit"
+	"test-app/internal/config"
+	"test-app/internal/fga"
+	"test-app/internal/httputil"
+	"test-app/internal/i18n"
+	"test-app/internal/jobs"
+	"test-app/internal/sandbox"
+	"test-app/internal/store"
+)
+
+// exporters build the job of each export kind for a format. Exports always
+// read the live data, also when started from a sandboxed request.
+var exporters = map[string]func(format string) jobs.Func{
+	"tuples": exportTuples,
+	"audit":  exportAudit,
+	"data":   exportData,
+}
+
+// exportFormats lists the formats each export kind can be written in, default first.
+var exportFormats = map[string][]string{
+	"tuples": {"json", "yaml"},
+	"audit":  {"json"},
+	"data":   {"json"},
+}
+
+func exportTuples(format string) jobs.Func {
+	return func(ctx context.Context, progress jobs.Progress) (jobs.Result, error) {
+		var tuples []store.TupleKey
+		var err error
+		sandbox.Live(func() {
+			err = fga.ReadPages(ctx, func(page []store.TupleKey) error {
+				if ctx.Err() != nil {
+					return ctx.Err()
+				}
+				tuples = append(tuples, page...)
+				progress(len(tuples), 0)
+				return nil
+			})
+		})
+		if err != nil {
+			return jobs.Result{}, err
+		}
+		data, err := fga.EncodeTuples(tuples, format)
+		if err != nil {
+			return jobs.Result{}, err
+		}
+		contentType := "application/json"
+		if format == "yaml" {
+			contentType = "application/yaml"
+		}
+		return jobs.Result{Data: data, ContentType: contentType, Filename: "tuples." + format}, nil
+	}
+}
+
+func exportAudit(string) jobs.Func {
+	return func(ctx context.Context, progress jobs.Progress) (jobs.Result, error) {
+		entries := audit.Recent("", math.MaxInt)
+		sort.Slice(entries, func(i, j int) bool { return entries[i].Time.Before(entries[j].Time) })
+		data, err := json.MarshalIndent(entries, "", "  ")
+		if err != nil {
+			return jobs.Result{}, err
+		}
+		progress(len(entries), len(entries))
+		return jobs.Result{Data: data, ContentType: "application/json", Filename: "audit.json"}, nil
+	}
+}
+
+func exportData(string) jobs.Func {
+	return func(ctx context.Context, progress jobs.Progress) (jobs.Result, error) {
+		var data []byte
+		sandbox.Live(func() { data = store.Snapshot() })
+		progress(1, 1)
+		return jobs.Result{Data: data, ContentType: "application/json", Filename: "data.json"}, nil
+	}
+}
+
+// jobView is a job as reported to admins, with its download link once done.
+type jobView struct {
+	jobs.Job
+	Download string `json:"download,omitempty"`
+}
+
+func viewJob(j jobs.Job) jobView {
+	v := jobView{Job: j}
+	if j.Status == jobs.Done {
+		v.Download = "/api/admin/jobs/" + j.Id + "/download"
+	}
+	return v
+}
+
+// JobsCreate handles POST /api/admin/jobs with {"kind": "tuples"|"audit"|"data",
+// "format"}: the export runs in the background and the job is answered with
+// 202 at once (for admin use).
+func JobsCreate(w http.ResponseWriter, r *http.Request) {
+	if !isManagerAdmin(r) {
+		httputil.JSONError(w, i18n.T(r, "Admin access required"), 403)
+		return
+	}
+	body, err := httputil.ReadBody(r)
+	if err != nil {
+		httputil.JSONError(w, i18n.T(r, "Invalid request body"), 400)
+		return
+	}
+	kind, format := httputil.GetString(body, "kind"), httputil.GetString(body, "format")
+	export, ok := exporters[kind]
+	if !ok {
+		kinds := make([]string, 0, len(exporters))
+		for k := range exporters {
+			kinds = append(kinds, k)
+		}
+		sort.Strings(kinds)
+		httputil.JSONError(w, i18n.T(r, "kind must be one of: %s", strings.Join(kinds, ", ")), 400)
+		return
+	}
+	if format == "" {
+		format = exportFormats[kind][0]
+	}
+	if !httputil.Contains(exportFormats[kind], format) {
+		httputil.JSONError(w, i18n.T(r, "format must be one of: %s", strings.Join(exportFormats[kind], ", ")), 400)
+		return
+	}
+	if kind == "tuples" && !config.FgaReady {
+		httputil.JSONError(w, i18n.T(r, "OpenFGA not ready"), 503)
+		return
+	}
+	user := httputil.GetUser(r)
+	j, err := jobs.Start("export-"+kind, user, export(format))
+	if err == jobs.ErrTooMany {
+		httputil.JSONError(w, i18n.T(r, "At most %d jobs can run at once", jobs.MaxRunning), 429)
+		return
+	}
+	if err != nil {
+		httputil.JSONError(w, err.Error(), 500)
+		return
+	}
+	audit.SendAuditLog("test-app", "job_start", "user:"+user, "", "job:"+j.Id, "POST", "Export of "+kind+" as "+format+" started")
+	w.Header().Set("Location", "/api/admin/jobs/"+j.Id)
+	httputil.JSONResponse(w, viewJob(j), 202)
+}
+
+// JobsList handles GET /api/admin/jobs: running and finished jobs, newest first (for admin use).
+func JobsList(w http.ResponseWriter, r *http.Request) {
+	if !isManagerAdmin(r) {
+		httputil.JSONError(w, i18n.T(r, "Admin access required"), 403)
+		return
+	}
+	views := []jobView{}
+	for _, j := range jobs.List() {
+		views = append(views, viewJob(j))
+	}
+	listResponse(w, r, "jobs", views, listMeta{Extra: map[string]interface{}{"retention": config.JobRetention.String()}})
+}
+
+// JobsGet handles GET /api/admin/jobs/{id}: the job's status and progress (for admin use).
+func JobsGet(w http.ResponseWriter, r *http.Request, id string) {
+	if !isManagerAdmin(r) {
+		httputil.JSONError(w, i18n.T(r, "Admin access required"), 403)
+		return
+	}
+	j, ok := jobs.Get(id)
+	if !ok {
+		httputil.JSONError(w, i18n.T(r, "Job not found or expired"), 404)
+		return
+	}
+	httputil.JSONResponse(w, viewJob(j), 200)
+}
+
+// JobsDownload handles GET /api/admin/jobs/{id}/download: the artifact of a
+// finished job, 409 while it runs or when it failed (for admin use).
+func JobsDownload(w http.ResponseWriter, r *http.Request, id string) {
+	if !isManagerAdmin(r) {
+		httputil.JSONError(w, i18n.T(r, "Admin access required"), 403)
+		return
+	}
+	if _, ok := jobs.Get(id); !ok {
+		httputil.JSONError(w, i18n.T(r, "Job not found or expired"), 404)
+		return
+	}
+	res, ok := jobs.Artifact(id)
+	if !ok {
+		httputil.JSONError(w, i18n.T(r, "Job has not finished successfully"), 409)
+		return
+	}
+	w.Header().Set("Content-Type", res.ContentType)
+	w.Header().Set("Content-Disposition", "attachment; filename="+res.Filename)
+	w.Write(res.Data)
+}
+
+// JobsCancel handles DELETE /api/admin/jobs/{id}: cancels a running job, or
+// discards a finished one with its artifact (for admin use).
+func JobsCancel(w http.ResponseWriter, r *http.Request, id string) {
+	if !isManagerAdmin(r) {
+		httputil.JSONError(w, i18n.T(r, "Admin access required"), 403)
+		return
+	}
+	if !jobs.Cancel(id) {
+		httputil.JSONError(w, i18n.T(r, "Job not found or expired"), 404)
+		return
+	}
+	httputil.JSONResponse(w, map[string]bool{"success": true}, 200)
+}
@@ -0,0 +1,147 @@
+package handlers
+
+import (
+	"net/http"
+
+	"test-app/internal/audit"
+	"test-app/internal/config"
+	"test-app/internal/events"
+	"test-app/internal/fga"
+	"test-app/internal/httputil"
+	"test-app/internal/i18n"
+	"test-app/internal/store"
+)
+
+// OrganizationsJoin records the caller's request to join an organization.
+// Admins approve or deny it through OrganizationsJoinDecide.
+func OrganizationsJoin(w http.ResponseWriter, r *http.Request, orgId string) {
+	user := httputil.GetUser(r)
+	message := ""
+	if body, err := httputil.ReadBody(r); err == nil {
+		message = httputil.GetString(body, "message")
+	}
+
+	store.Mu.Lock()
+	org, ok := store.Data.Organizations[orgId]
+	if !ok {
+		store.Mu.Unlock()
+		httputil.JSONError(w, i18n.T(r, "Organization not found"), 404)
+		return
+	}
+	if httputil.Contains(org.Members, user) {
+		store.Mu.Unlock()
+		httputil.JSONError(w, i18n.T(r, "Already a member"), 400)
+		return
+	}
+	for _, req := range store.Data.JoinRequests {
+		if req.OrgId == orgId && req.User == user && req.Status == "pending" {
+			store.Mu.Unlock()
+			httputil.JSONError(w, i18n.T(r, "Request already pending"), 400)
+			return
+		}
+	}
+	req := store.JoinRequest{Id: store.RandId(), OrgId: orgId, User: user, Message: message, Status: "pending", Meta: store.NewMeta(user)}
+	store.Data.JoinRequests = append(store.Data.JoinRequests, req)
+	admins := append([]string(nil), org.Admins...)
+	store.Mu.Unlock()
+	store.Save()
+
+	events.Publish(events.Event{
+		Type: events.OrgJoinRequested, Actor: user, Object: fga.ObjectRef(fga.TypeOrganization, orgId),
+		Recipients: admins, Data: map[string]string{"requestId": req.Id},
+	})
+	httputil.JSONResponse(w, req, 200)
+}
+
+// OrganizationsJoinRequests lists the pending join requests of an organization for its admins.
+func OrganizationsJoinRequests(w http.ResponseWriter, r *http.Request, orgId string) {
+	if manageOrgDenied(w, r, orgId) {
+		return
+	}
+	pending := []store.JoinRequest{}
+	users := []string{}
+	store.Mu.RLock()
+	for _, req := range store.Data.JoinRequests {
+		if req.OrgId == orgId && req.Status == "pending" {
+			pending = append(pending, req)
+			users = append(users, req.User)
+		}
+	}
+	store.Mu.RUnlock()
+	listResponse(w, r, "requests", pending, listMeta{Users: users})
+}
+
+// OrganizationsJoinDecide approves or denies a pending join request. Approval
+// writes the member tuple before the request is marked approved.
+func OrganizationsJoinDecide(w http.ResponseWriter, r *http.Request, orgId, reqId string, approve bool) {
+	if !config.FgaReady {
+		httputil.JSONError(w, i18n.T(r, "OpenFGA not ready"), 503)
+		return
+	}
+	if manageOrgDenied(w, r, orgId) {
+		return
+	}
+	admin := httputil.GetUser(r)
+
+	store.Mu.Lock()
+	var req *store.JoinRequest
+	for i := range store.Data.JoinRequests {
+		if store.Data.JoinRequests[i].Id == reqId && store.Data.JoinRequests[i].OrgId == orgId {
+			req = &store.Data.JoinRequests[i]
+		}
+	}
+	org, orgOk := store.Data.Organizations[orgId]
+	if req == nil || !orgOk {
+		store.Mu.Unlock()
+		httputil.JSONError(w, i18n.T(r, "Request not found"), 404)
+		return
+	}
+	if req.Status != "pending" {
+		store.Mu.Unlock()
+		httputil.JSONError(w, i18n.T(r, "Request already handled"), 400)
+		return
+	}
+	user := req.User
+	store.Mu.Unlock()
+
+	status, eventType := "denied", events.OrgJoinDenied
+	if approve {
+		status, eventType = "approved", events.OrgJoinApproved
+		if err := fga.Write(r.Context(), []store.TupleKey{{User: fga.UserRef(user), Relation: "member", Object: fga.ObjectRef(fga.TypeOrganization, orgId)}}, nil); err != nil {
+			fgaFailed(w, r, err)
+			return
+		}
+	}
+
+	store.Mu.Lock()
+	if approve && !httputil.Contains(org.Members, user) {
+		org.Members = append(org.Members, user)
+		org.Updated()
+	}
+	req.Status = status
+	req.DecidedBy = admin
+	req.Updated()
+	decided := *req
+	store.Mu.Unlock()
+	store.Save()
+
+	audit.SendAuditLog("test-app", status, fga.UserRef(user), "member", fga.ObjectRef(fga.TypeOrganization, orgId), "POST", "Join request "+status+" by "+admin)
+	events.Publish(events.Event{
+		Type: eventType, Actor: admin, Object: fga.ObjectRef(fga.TypeOrganization, orgId),
+		Recipients: []string{user}, Data: map[string]string{"requestId": reqId},
+	})
+	httputil.JSONResponse(w, decided, 200)
+}
+
+// manageOrgDenied reports whether the caller may not administer the
+// organization, having answered 403, or 503 when OpenFGA could not answer.
+func manageOrgDenied(w http.ResponseWriter, r *http.Request, orgId string) bool {
+	if isManagerAdmin(r) {
+		return false
+	}
+	if !config.FgaReady {
+		httputil.JSONError(w, i18n.T(r, "Forbidden: only admins can manage members"), 403)
+		return true
+	}
+	return checkDenied(w, r, "can_manage", fga.ObjectRef(fga.TypeOrganization, orgId), "Forbidden: only admins can manage members")
+}
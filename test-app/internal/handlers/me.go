@@ -0,0 +1,269 @@
+package handlers
+
+import (
+	"net/http"
+	"sort"
+	"strconv"
+	"time"
+
+	"test-app/internal/audit"
+	"test-app/internal/config"
+	"test-app/internal/events"
+	"test-app/internal/fga"
+	"test-app/internal/httputil"
+	"test-app/internal/i18n"
+	"test-app/internal/recent"
+	"test-app/internal/store"
+	"test-app/internal/visibility"
+)
+
+type exportRelation struct {
+	Dossier      string              `json:"dossier"`
+	User         string              `json:"user,omitempty"`
+	Owners       []string            `json:"owners,omitempty"`
+	Relation     string              `json:"relation"`
+	Restrictions *store.Restrictions `json:"restrictions,omitempty"`
+}
+
+type exportDossier struct {
+	Id string `json:"id"`
+	*store.Dossier
+	// StepUpNeeded marks a secret dossier whose content was replaced by a
+	// placeholder because the export was made without a recent strong authentication.
+	StepUpNeeded bool `json:"stepUpNeeded,omitempty"`
+}
+
+type exportOrganization struct {
+	Id   string `json:"id"`
+	Name string `json:"name"`
+	Role string `json:"role"`
+}
+
+// MeExport returns everything the system holds about the caller as a JSON
+// attachment: owned dossiers and resources, relations granted and received,
+// organization memberships, guardianships, guardianship, join and access
+// requests, favorites, saved and recently viewed dossier views, role grants,
+// the caller's profile and audit trail. It covers what store.EraseUser removes.
+func MeExport(w http.ResponseWriter, r *http.Request) {
+	user := httputil.GetUser(r)
+	graph := store.SnapshotGraph()
+
+	owned := []exportDossier{}
+	granted := []exportRelation{}
+	received := []exportRelation{}
+	blockedOn := []string{}
+	orgs := []exportOrganization{}
+	requests := []store.GuardianshipRequest{}
+	joinRequests := []store.JoinRequest{}
+	accessRequests := []store.AccessRequest{}
+	resources := []*store.Resource{}
+	views := []store.SavedView{}
+	withheld := i18n.T(r, "[Withheld: secret dossiers are only exported after a recent strong authentication]")
+
+	store.Mu.RLock()
+	for id, d := range store.Data.Dossiers {
+		if d.IsOwner(user) {
+			snapshot := *d
+			entry := exportDossier{Id: id, Dossier: &snapshot}
+			if stepUpRequired(r, d) {
+				snapshot.Content, entry.StepUpNeeded = withheld, true
+			}
+			owned = append(owned, entry)
+			for _, rel := range d.Relations {
+				granted = append(granted, exportRelation{Dossier: id, User: rel.User, Relation: rel.Relation, Restrictions: rel.Restrictions})
+			}
+		}
+		for _, rel := range d.Relations {
+			if rel.User == user && !d.IsOwner(user) {
+				received = append(received, exportRelation{Dossier: id, Owners: d.Owners, Relation: rel.Relation, Restrictions: rel.Restrictions})
+			}
+		}
+		if httputil.Contains(d.BlockedUsers, user) {
+			blockedOn = append(blockedOn, id)
+		}
+	}
+	for id, org := range store.Data.Organizations {
+		if httputil.Contains(org.Admins, user) {
+			orgs = append(orgs, exportOrganization{Id: id, Name: org.Name, Role: "admin"})
+		} else if httputil.Contains(org.Members, user) {
+			orgs = append(orgs, exportOrganization{Id: id, Name: org.Name, Role: "member"})
+		}
+	}
+	for _, req := range store.Data.GuardianshipRequests {
+		if req.From == user || req.To == user {
+			requests = append(requests, req)
+		}
+	}
+	for _, req := range store.Data.JoinRequests {
+		if req.User == user {
+			joinRequests = append(joinRequests, req)
+		}
+	}
+	for _, req := range store.Data.AccessRequests {
+		if req.User == user {
+			accessRequests = append(accessRequests, req)
+		}
+	}
+	for _, res := range store.Data.Resources {
+		if httputil.Contains(res.Owners, user) {
+			resources = append(resources, copyResource(res))
+		}
+	}
+	for _, v := range store.Data.SavedViews {
+		if v.Owner == user {
+			views = append(views, *v)
+		}
+	}
+	favorites := append([]string{}, store.Data.Favorites[user]...)
+	roleGrants := append([]string{}, store.Data.RoleGrants[user]...)
+	var profile *store.Profile
+	if p := store.Data.Profiles[user]; p != nil {
+		snapshot := *p
+		profile = &snapshot
+	}
+	store.Mu.RUnlock()
+	guardians := graph.Guardians(user)
+	wards := graph.Wards(user)
+
+	sort.Slice(owned, func(i, j int) bool { return owned[i].Id < owned[j].Id })
+	sort.Slice(received, func(i, j int) bool { return received[i].Dossier < received[j].Dossier })
+	sort.Strings(blockedOn)
+	sort.Slice(orgs, func(i, j int) bool { return orgs[i].Id < orgs[j].Id })
+	sort.Slice(resources, func(i, j int) bool { return resources[i].Object() < resources[j].Object() })
+	sort.Slice(views, func(i, j int) bool { return views[i].Id < views[j].Id })
+
+	w.Header().Set("Content-Disposition", "attachment; filename=export-"+user+".json")
+	httputil.JSONResponse(w, map[string]interface{}{
+		"user":              user,
+		"exportedAt":        time.Now().Format(time.RFC3339),
+		"dossiers":          owned,
+		"relationsGranted":  granted,
+		"relationsReceived": received,
+		"blockedOn":         blockedOn,
+		"organizations":     orgs,
+		"guardians":         guardians,
+		"wards":             wards,
+		"requests":          requests,
+		"joinRequests":      joinRequests,
+		"accessRequests":    accessRequests,
+		"resources":         resources,
+		"favorites":         favorites,
+		"savedViews":        views,
+		"recentViews":       recent.List(user),
+		"roleGrants":        roleGrants,
+		"profile":           profile,
+		"auditTrail":        audit.Recent(user, 500),
+	}, 200)
+}
+
+// myOrganization is an organization as listed for one of its members.
+type myOrganization struct {
+	Id          string `json:"id"`
+	Name        string `json:"name"`
+	Description string `json:"description,omitempty"`
+	Role        string `json:"role"`
+	MemberCount int    `json:"memberCount"`
+}
+
+// MeOrganizations lists only the organizations the caller belongs to, with
+// their role. Membership comes from OpenFGA ListObjects, not the store lists.
+func MeOrganizations(w http.ResponseWriter, r *http.Request) {
+	if !config.FgaReady {
+		httputil.JSONError(w, i18n.T(r, "OpenFGA not ready"), 503)
+		return
+	}
+	if notModified(w, r, "me-organizations") {
+		return
+	}
+	user := fga.UserRef(httputil.GetUser(r))
+	roles := map[string]string{}
+	for _, rel := range []string{"member", "admin"} {
+		objs, err := fga.ListObjects(r.Context(), user, rel, fga.TypeOrganization)
+		if err != nil {
+			fgaUnavailable(w, r, err)
+			return
+		}
+		for _, id := range fga.IdsOf(objs, fga.TypeOrganization) {
+			roles[id] = rel
+		}
+	}
+
+	orgs := []myOrganization{}
+	store.Mu.RLock()
+	for id, role := range roles {
+		org, ok := store.Data.Organizations[id]
+		if !ok {
+			continue
+		}
+		orgs = append(orgs, myOrganization{Id: id, Name: org.Name, Description: org.Description, Role: role, MemberCount: len(org.Members)})
+	}
+	store.Mu.RUnlock()
+	sort.Slice(orgs, func(i, j int) bool { return orgs[i].Name < orgs[j].Name })
+	listResponse(w, r, "organizations", orgs, listMeta{})
+}
+
+// recentView is a recently viewed dossier as listed for the caller.
+type recentView struct {
+	Id       string    `json:"id"`
+	Title    string    `json:"title"`
+	Type     string    `json:"type"`
+	ViewedAt time.Time `json:"viewedAt"`
+}
+
+// MeRecent lists the dossiers the caller opened last, most recent first.
+// Entries are scrubbed in the background when access is revoked; the list is
+// also intersected with what the caller can view now, so an entry whose scrub
+// is still pending is never shown.
+func MeRecent(w http.ResponseWriter, r *http.Request) {
+	if !config.FgaReady {
+		httputil.JSONError(w, i18n.T(r, "OpenFGA not ready"), 503)
+		return
+	}
+	user := httputil.GetUser(r)
+	visibleIds, _, err := visibility.Visible(r.Context(), user)
+	if err != nil {
+		fgaUnavailable(w, r, err)
+		return
+	}
+	visible := map[string]bool{}
+	for _, id := range fga.IdsOf(visibleIds, fga.TypeDossier) {
+		visible[id] = true
+	}
+	views := []recentView{}
+	store.Mu.RLock()
+	for _, e := range recent.List(user) {
+		if d := store.Data.Dossiers[e.Id]; d != nil && visible[e.Id] {
+			views = append(views, recentView{Id: e.Id, Title: d.Title, Type: d.Type, ViewedAt: e.ViewedAt})
+		}
+	}
+	store.Mu.RUnlock()
+	listResponse(w, r, "recent", views, listMeta{})
+}
+
+// MeNotifications returns the domain events addressed to the caller and
+// published after ?since= (a cursor from a previous call; 0 for everything
+// retained), oldest first: requests awaiting their decision and the decisions
+// on their own requests, with who decided. reset is true when events may have
+// been missed, as for the admin changes feed.
+func MeNotifications(w http.ResponseWriter, r *http.Request) {
+	user := httputil.GetUser(r)
+	var since uint64
+	if v := r.URL.Query().Get("since"); v != "" {
+		n, err := strconv.ParseUint(v, 10, 64)
+		if err != nil {
+			httputil.JSONError(w, i18n.T(r, "since must be a cursor returned by this endpoint"), 400)
+			return
+		}
+		since = n
+	}
+	evs, next, complete := events.Since(since, 0)
+	notifications := []events.Event{}
+	for _, e := range evs {
+		if e.Actor != user && httputil.Contains(e.Recipients, user) {
+			notifications = append(notifications, e)
+		}
+	}
+	httputil.JSONResponse(w, map[string]interface{}{
+		"notifications": notifications, "cursor": next, "reset": !complete,
+	}, 200)
+}
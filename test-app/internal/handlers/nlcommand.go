@@ -0,0 +1,270 @@
+package handlers
+
+import (
+	"bytes"
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"sort"
+	"sync"
+	"time"
+
+	"test-app/internal/analytics"
+	"test-app/internal/audit"
+	"test-app/internal/config"
+	"test-app/internal/fga"
+	"test-app/internal/httputil"
+	"test-app/internal/i18n"
+	"test-app/internal/privacy"
+	"test-app/internal/store"
+)
+
+// ShareIntent is one structured sharing instruction parsed from a natural-language command.
+type ShareIntent struct {
+	Action      string   `json:"action"`
+	Grantee     string   `json:"grantee"`
+	Relation    string   `json:"relation"`
+	DossierType string   `json:"dossierType,omitempty"`
+	DossierIds  []string `json:"dossierIds,omitempty"`
+	Until       string   `json:"until,omitempty"`
+}
+
+// plannedChange is a single tuple write or delete resolved from an intent.
+type plannedChange struct {
+	Action    string `json:"action"`
+	DossierId string `json:"dossierId"`
+	Title     string `json:"title"`
+	Grantee   string `json:"grantee"`
+	Relation  string `json:"relation"`
+}
+
+type pendingCommand struct {
+	user    string
+	changes []plannedChange
+	expires time.Time
+}
+
+// nlShareRelations are the relations a natural-language command may grant or revoke.
+var nlShareRelations = []string{"can_view", "mandate_holder"}
+
+const nlConfirmTTL = 5 * time.Minute
+
+var (
+	nlPendingMu sync.Mutex
+	nlPending   = map[string]pendingCommand{}
+)
+
+// parseShareCommand asks the AI Manager to turn free text into intents. When
+// anonymization is on, the caller and the people they may name (relatives) are
+// pseudonymized in the request and the grantees mapped back in the answer.
+func parseShareCommand(user, text string, relatives []string) ([]ShareIntent, error) {
+	sentUser := user
+	if privacy.Enabled() {
+		sentUser = privacy.Pseudonym(user)
+		privacy.Pseudonyms(relatives)
+		text = privacy.Scrub(text)
+	}
+	b, _ := json.Marshal(map[string]string{"user": sentUser, "text": text})
+	resp, err := aiClient.Post(config.AIManagerURL+"/api/parse-share-intent", "application/json", bytes.NewReader(b))
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+	var result struct {
+		Intents []ShareIntent `json:"intents"`
+		Error   string        `json:"error"`
+	}
+	if err := json.NewDecoder(resp.Body).Decode(&result); err != nil {
+		return nil, fmt.Errorf("failed to decode AI Manager response: %w", err)
+	}
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("AI Manager returned %d: %s", resp.StatusCode, result.Error)
+	}
+	for i := range result.Intents {
+		result.Intents[i].Grantee = privacy.Reveal(result.Intents[i].Grantee)
+	}
+	return result.Intents, nil
+}
+
+// planShareIntents resolves intents against the dossiers the caller owns,
+// returning the concrete changes plus a warning for every intent that was skipped.
+func planShareIntents(user string, intents []ShareIntent, admin bool, graph *store.Graph) ([]plannedChange, []string) {
+	var changes []plannedChange
+	var warnings []string
+
+	store.Mu.RLock()
+	defer store.Mu.RUnlock()
+	for _, in := range intents {
+		if in.Action != "grant" && in.Action != "revoke" {
+			warnings = append(warnings, fmt.Sprintf("unknown action %q skipped", in.Action))
+			continue
+		}
+		if !httputil.Contains(nlShareRelations, in.Relation) {
+			warnings = append(warnings, fmt.Sprintf("relation %q is not shareable", in.Relation))
+			continue
+		}
+		if in.Grantee == "" || in.Grantee == user {
+			warnings = append(warnings, "intent without a valid grantee skipped")
+			continue
+		}
+		if in.Action == "grant" && !admin && !graph.Related(user, in.Grantee) {
+			warnings = append(warnings, in.Grantee+" is not in a guardianship with you")
+			continue
+		}
+		ids := in.DossierIds
+		if len(ids) == 0 {
+			for id, d := range store.Data.Dossiers {
+				if d.IsOwner(user) && (in.DossierType == "" || d.Type == in.DossierType) {
+					ids = append(ids, id)
+				}
+			}
+			sort.Strings(ids)
+		}
+		for _, id := range ids {
+			d, ok := store.Data.Dossiers[id]
+			if !ok || !d.IsOwner(user) {
+				warnings = append(warnings, "dossier "+id+" is not yours and was skipped")
+				continue
+			}
+			exists := false
+			for _, rel := range d.Relations {
+				if rel.User == in.Grantee && rel.Relation == in.Relation {
+					exists = true
+				}
+			}
+			if (in.Action == "grant") == exists {
+				continue
+			}
+			changes = append(changes, plannedChange{Action: in.Action, DossierId: id, Title: d.Title, Grantee: in.Grantee, Relation: in.Relation})
+		}
+	}
+	return changes, warnings
+}
+
+// executePlan writes the planned tuples and mirrors them in the store.
+func executePlan(ctx context.Context, user string, changes []plannedChange) error {
+	var writes, deletes []store.TupleKey
+	for _, c := range changes {
+		t := store.TupleKey{User: fga.UserRef(c.Grantee), Relation: c.Relation, Object: fga.ObjectRef(fga.TypeDossier, c.DossierId)}
+		if c.Action == "grant" {
+			writes = append(writes, t)
+		} else {
+			deletes = append(deletes, t)
+		}
+	}
+	if err := fga.Write(ctx, writes, deletes); err != nil {
+		return err
+	}
+	store.Mu.Lock()
+	for _, c := range changes {
+		d, ok := store.Data.Dossiers[c.DossierId]
+		if !ok {
+			continue
+		}
+		d.Updated()
+		if c.Action == "grant" {
+			d.Relations = append(d.Relations, store.Relation{User: c.Grantee, Relation: c.Relation})
+			if c.Relation == "mandate_holder" {
+				analytics.Record(user, analytics.MandateGranted)
+			}
+			continue
+		}
+		var kept []store.Relation
+		for _, rel := range d.Relations {
+			if !(rel.User == c.Grantee && rel.Relation == c.Relation) {
+				kept = append(kept, rel)
+			}
+		}
+		d.Relations = kept
+	}
+	store.Mu.Unlock()
+	store.Save()
+	for _, c := range changes {
+		audit.SendAuditLog("test-app", c.Action, fga.UserRef(c.Grantee), c.Relation, fga.ObjectRef(fga.TypeDossier, c.DossierId), "NL_COMMAND", "Natural-language command by "+user)
+	}
+	return nil
+}
+
+// AuthzNLCommand previews or executes a natural-language sharing command.
+// {"text": "..."} parses and returns a plan with a confirmToken (nothing is written),
+// or 400 when the command asks for an end date, which grants cannot carry;
+// {"confirmToken": "..."} executes the previously previewed plan.
+func AuthzNLCommand(w http.ResponseWriter, r *http.Request) {
+	if !config.FgaReady {
+		httputil.JSONError(w, i18n.T(r, "OpenFGA not ready"), 503)
+		return
+	}
+	user := httputil.GetUser(r)
+	body, err := httputil.ReadBody(r)
+	if err != nil {
+		httputil.JSONError(w, i18n.T(r, "Invalid request body"), 400)
+		return
+	}
+
+	if token := httputil.GetString(body, "confirmToken"); token != "" {
+		nlPendingMu.Lock()
+		cmd, ok := nlPending[token]
+		delete(nlPending, token)
+		nlPendingMu.Unlock()
+		if !ok || cmd.user != user || time.Now().After(cmd.expires) {
+			httputil.JSONError(w, i18n.T(r, "Confirmation token is invalid or expired"), 400)
+			return
+		}
+		if err := executePlan(r.Context(), user, cmd.changes); err != nil {
+			fgaFailed(w, r, err)
+			return
+		}
+		httputil.JSONResponse(w, map[string]interface{}{"success": true, "executed": cmd.changes}, 200)
+		return
+	}
+
+	text := httputil.GetString(body, "text")
+	if text == "" {
+		httputil.JSONError(w, i18n.T(r, "text is required"), 400)
+		return
+	}
+	graph := store.SnapshotGraph()
+	relatives := append(append([]string{}, graph.Guardians(user)...), graph.Wards(user)...)
+	intents, err := parseShareCommand(user, text, relatives)
+	if err != nil {
+		httputil.JSONError(w, err.Error(), 502)
+		return
+	}
+	for _, in := range intents {
+		// Grants carry no expiry: previewing one with an end date would
+		// promise access that outlives it.
+		if in.Until != "" {
+			httputil.JSONError(w, i18n.T(r, "Expiring grants are not supported; remove the end date"), 400)
+			return
+		}
+	}
+	changes, warnings := planShareIntents(user, intents, isManagerAdmin(r), graph)
+	if changes == nil {
+		changes = []plannedChange{}
+	}
+	if warnings == nil {
+		warnings = []string{}
+	}
+	resp := map[string]interface{}{"intents": intents, "plan": changes, "warnings": warnings, "dryRun": true}
+	if len(changes) > 0 {
+		b := make([]byte, 16)
+		if _, err := rand.Read(b); err != nil {
+			httputil.JSONError(w, err.Error(), 500)
+			return
+		}
+		token := hex.EncodeToString(b)
+		nlPendingMu.Lock()
+		for k, c := range nlPending {
+			if time.Now().After(c.expires) {
+				delete(nlPending, k)
+			}
+		}
+		nlPending[token] = pendingCommand{user: user, changes: changes, expires: time.Now().Add(nlConfirmTTL)}
+		nlPendingMu.Unlock()
+		resp["confirmToken"] = token
+	}
+	httputil.JSONResponse(w, resp, 200)
+}
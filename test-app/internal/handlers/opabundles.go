@@ -0,0 +1,50 @@
+package handlers
+
+import (
+	"net/http"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strings"
+
+	"test-app/internal/config"
+	"test-app/internal/httputil"
+	"test-app/internal/i18n"
+	"test-app/internal/opabundle"
+)
+
+var bundleName = regexp.MustCompile(`^[A-Za-z0-9_-]+$`)
+
+// OPABundle serves config.OPAPoliciesDir as an OPA bundle at
+// /opa/bundles/{name}.tar.gz: "main" is the whole directory, any other name
+// the subdirectory of that name. The ETag is the bundle revision, so OPA's
+// polling with If-None-Match gets a 304 until a policy file changes.
+func OPABundle(w http.ResponseWriter, r *http.Request, name string) {
+	name = strings.TrimSuffix(name, ".tar.gz")
+	if !bundleName.MatchString(name) {
+		httputil.JSONError(w, i18n.T(r, "Bundle not found"), 404)
+		return
+	}
+	dir := config.OPAPoliciesDir
+	if name != "main" {
+		dir = filepath.Join(dir, name)
+	}
+	if info, err := os.Stat(dir); err != nil || !info.IsDir() {
+		httputil.JSONError(w, i18n.T(r, "Bundle not found"), 404)
+		return
+	}
+	bundle, err := opabundle.Build(dir)
+	if err != nil {
+		httputil.JSONError(w, err.Error(), 500)
+		return
+	}
+	etag := `"` + bundle.Revision + `"`
+	w.Header().Set("ETag", etag)
+	w.Header().Set("Cache-Control", "no-cache")
+	if r.Header.Get("If-None-Match") == etag {
+		w.WriteHeader(http.StatusNotModified)
+		return
+	}
+	w.Header().Set("Content-Type", "application/gzip")
+	w.Write(bundle.Data)
+}
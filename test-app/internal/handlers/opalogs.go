@@ -0,0 +1,134 @@
+package handlers
+
+import (
+	"bytes"
+	"compress/gzip"
+	"encoding/json"
+	"io"
+	"log"
+	"net/http"
+	"strings"
+	"time"
+
+	"test-app/internal/audit"
+	"test-app/internal/config"
+	"test-app/internal/httputil"
+	"test-app/internal/i18n"
+)
+
+// maxDecisionLogBody bounds one OPA decision log upload after decompression.
+const maxDecisionLogBody = 10 << 20
+
+// opaDecision is the part of an OPA decision log event this app uses. Result
+// is a bare boolean or the envoy plugin's {allowed, headers, body, ...} object.
+type opaDecision struct {
+	DecisionId string    `json:"decision_id"`
+	Timestamp  time.Time `json:"timestamp"`
+	Input      struct {
+		Attributes struct {
+			Request struct {
+				HTTP struct {
+					Method  string            `json:"method"`
+					Path    string            `json:"path"`
+					Headers map[string]string `json:"headers"`
+				} `json:"http"`
+			} `json:"request"`
+		} `json:"attributes"`
+	} `json:"input"`
+	Result json.RawMessage `json:"result"`
+}
+
+type opaResult struct {
+	Allowed bool              `json:"allowed"`
+	Headers map[string]string `json:"headers"`
+	Body    string            `json:"body"`
+}
+
+// entry converts the decision to an audit entry.
+func (d opaDecision) entry() audit.Entry {
+	req := d.Input.Attributes.Request.HTTP
+	var res opaResult
+	if err := json.Unmarshal(d.Result, &res.Allowed); err != nil {
+		json.Unmarshal(d.Result, &res)
+	}
+	decision, reason := "deny", "Policy denied"
+	if res.Allowed {
+		decision, reason = "allow", "Policy allowed"
+	}
+	if res.Body != "" {
+		var body map[string]interface{}
+		if json.Unmarshal([]byte(res.Body), &body) == nil {
+			if msg, ok := body["reason"].(string); ok && msg != "" {
+				reason = msg
+			}
+		}
+	}
+	user := res.Headers["x-current-user"]
+	if user != "" {
+		user = "user:" + user
+	}
+	return audit.Entry{
+		Time: d.Timestamp, Source: "OPA", Decision: decision, User: user,
+		Resource: req.Path, Method: strings.ToUpper(req.Method), Reason: reason,
+		RequestId: req.Headers["x-request-id"],
+	}
+}
+
+// OPADecisionLogs implements the receiving end of OPA's decision log API:
+// OPA POSTs batches of decisions, usually gzip-compressed. Each decision is
+// kept in the local audit buffer next to the app's own entries, tied to the
+// gateway's x-request-id, and the batch is relayed to config.OPALogsRelayURL.
+// OPA authenticates with "Authorization: Bearer <OPA_LOGS_TOKEN>", set as the
+// bearer credential of its test-app service.
+func OPADecisionLogs(w http.ResponseWriter, r *http.Request) {
+	token := config.Secret(config.OPALogsToken)
+	if token == "" {
+		httputil.JSONError(w, i18n.T(r, "OPA decision logs are not configured (OPA_LOGS_TOKEN)"), 503)
+		return
+	}
+	if !bearerMatches(r, token) {
+		httputil.JSONError(w, i18n.T(r, "Invalid OPA logs token"), 401)
+		return
+	}
+	var body io.Reader = r.Body
+	if r.Header.Get("Content-Encoding") == "gzip" {
+		zr, err := gzip.NewReader(r.Body)
+		if err != nil {
+			httputil.JSONError(w, i18n.T(r, "Invalid request body"), 400)
+			return
+		}
+		defer zr.Close()
+		body = zr
+	}
+	raw, err := io.ReadAll(io.LimitReader(body, maxDecisionLogBody+1))
+	if err != nil || len(raw) > maxDecisionLogBody {
+		httputil.JSONError(w, i18n.T(r, "Invalid request body"), 400)
+		return
+	}
+	var batch []opaDecision
+	if err := json.Unmarshal(raw, &batch); err != nil {
+		httputil.JSONError(w, i18n.T(r, "Invalid request body"), 400)
+		return
+	}
+	for _, d := range batch {
+		if strings.HasPrefix(d.Input.Attributes.Request.HTTP.Path, "/manager") {
+			continue
+		}
+		audit.Record(d.entry())
+	}
+	if config.OPALogsRelayURL != "" {
+		go relayDecisionLogs(raw)
+	}
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// relayDecisionLogs forwards a batch to the AI Manager, which used to receive
+// OPA's decision logs directly.
+func relayDecisionLogs(raw []byte) {
+	resp, err := aiClient.Post(config.OPALogsRelayURL, "application/json", bytes.NewReader(raw))
+	if err != nil {
+		log.Printf("WARNING: relaying OPA decision logs failed: %v", err)
+		return
+	}
+	resp.Body.Close()
+}
@@ -1,11 +1,18 @@
 package handlers
 
 import (
+	"errors"
 	"net/http"
+	"sort"
+	"strconv"
 
+	"test-app/internal/analytics"
+	"test-app/internal/audit"
 	"test-app/internal/config"
+	"test-app/internal/events"
 	"test-app/internal/fga"
 	"test-app/internal/httputil"
+	"test-app/internal/i18n"
 	"test-app/internal/store"
 )
 
@@ -15,33 +22,78 @@ func isManagerAdmin(r *http.Request) bool {
 }
 
 func OrganizationsList(w http.ResponseWriter, r *http.Request) {
+	if notModified(w, r, "organizations") {
+		return
+	}
 	store.Mu.RLock()
 	orgs := make([]map[string]interface{}, 0, len(store.Data.Organizations))
 	for id, org := range store.Data.Organizations {
 		orgs = append(orgs, map[string]interface{}{
-			"id":      id,
-			"name":    org.Name,
-			"members": org.Members,
-			"admins":  org.Admins,
+			"id":          id,
+			"name":        org.Name,
+			"description": org.Description,
+			"contact":     org.Contact,
+			"members":     org.Members,
+			"admins":      org.Admins,
+			"createdAt":   org.CreatedAt,
+			"updatedAt":   org.UpdatedAt,
+			"createdBy":   org.CreatedBy,
 		})
 	}
 	store.Mu.RUnlock()
-	httputil.JSONResponse(w, map[string]interface{}{"organizations": orgs}, 200)
+
+	// Capabilities come from OpenFGA so they match what the mutating endpoints enforce.
+	// Members and admins see the full entry, the manager admin sees everything,
+	// and everyone else gets a directory entry without the member lists.
+	user := fga.UserRef(httputil.GetUser(r))
+	admin := isManagerAdmin(r)
+	for i, org := range orgs {
+		object := fga.ObjectRef(fga.TypeOrganization, org["id"].(string))
+		member, manage := false, admin
+		if config.FgaReady {
+			var err error
+			if member, err = fga.Allowed(r.Context(), user, "member", object); errors.Is(err, fga.ErrUnavailable) {
+				fgaUnavailable(w, r, err)
+				return
+			}
+			if !admin {
+				if manage, err = fga.Allowed(r.Context(), user, "can_manage", object); errors.Is(err, fga.ErrUnavailable) {
+					fgaUnavailable(w, r, err)
+					return
+				}
+			}
+		}
+		org["amIMember"], org["canManage"] = member, manage
+		if !admin && org["amIMember"] == false && org["canManage"] == false {
+			orgs[i] = map[string]interface{}{
+				"id": org["id"], "name": org["name"], "description": org["description"],
+				"amIMember": false, "canManage": false,
+			}
+		}
+	}
+	sort.Slice(orgs, func(i, j int) bool { return orgs[i]["name"].(string) < orgs[j]["name"].(string) })
+	users := []string{}
+	for _, org := range orgs {
+		members, _ := org["members"].([]string)
+		admins, _ := org["admins"].([]string)
+		users = append(append(users, members...), admins...)
+	}
+	listResponse(w, r, "organizations", orgs, listMeta{Users: users})
 }
 
 func OrganizationsCreate(w http.ResponseWriter, r *http.Request) {
 	if !config.FgaReady {
-		httputil.JSONError(w, "OpenFGA not ready", 503)
+		httputil.JSONError(w, i18n.T(r, "OpenFGA not ready"), 503)
 		return
 	}
 	body, err := httputil.ReadBody(r)
 	if err != nil {
-		httputil.JSONError(w, "Invalid request body", 400)
+		httputil.JSONError(w, i18n.T(r, "Invalid request body"), 400)
 		return
 	}
 	name := httputil.GetString(body, "name")
 	if name == "" {
-		httputil.JSONError(w, "Name is required", 400)
+		httputil.JSONError(w, i18n.T(r, "Name is required"), 400)
 		return
 	}
 
@@ -63,7 +115,10 @@ func OrganizationsCreate(w http.ResponseWriter, r *http.Request) {
 	admins := []string{creator}
 
 	id := store.RandId()
-	org := &store.Organization{Name: name, Members: members, Admins: admins}
+	org := &store.Organization{
+		Name: name, Description: httputil.GetString(body, "description"), Contact: httputil.GetString(body, "contact"),
+		Members: members, Admins: admins, Meta: store.NewMeta(creator),
+	}
 
 	store.Mu.Lock()
 	store.Data.Organizations[id] = org
@@ -71,19 +126,20 @@ func OrganizationsCreate(w http.ResponseWriter, r *http.Request) {
 
 	var tuples []store.TupleKey
 	for _, member := range members {
-		tuples = append(tuples, store.TupleKey{User: "user:" + member, Relation: "member", Object: "organization:" + id})
+		tuples = append(tuples, store.TupleKey{User: fga.UserRef(member), Relation: "member", Object: fga.ObjectRef(fga.TypeOrganization, id)})
 	}
-	tuples = append(tuples, store.TupleKey{User: "user:" + creator, Relation: "admin", Object: "organization:" + id})
+	tuples = append(tuples, store.TupleKey{User: fga.UserRef(creator), Relation: "admin", Object: fga.ObjectRef(fga.TypeOrganization, id)})
 
-	if err := fga.Write(tuples, nil); err != nil {
+	if err := fga.Write(r.Context(), tuples, nil); err != nil {
 		store.Mu.Lock()
 		delete(store.Data.Organizations, id)
 		store.Mu.Unlock()
-		httputil.JSONError(w, err.Error(), 500)
+		fgaFailed(w, r, err)
 		return
 	}
 
 	store.Save()
+	analytics.Record(creator, analytics.OrganizationCreated)
 	httputil.JSONResponse(w, map[string]interface{}{
 		"id":      id,
 		"name":    name,
@@ -92,26 +148,65 @@ func OrganizationsCreate(w http.ResponseWriter, r *http.Request) {
 	}, 200)
 }
 
+// OrganizationsUpdate changes an organization's name, description or contact.
+func OrganizationsUpdate(w http.ResponseWriter, r *http.Request, orgId string) {
+	if !config.FgaReady {
+		httputil.JSONError(w, i18n.T(r, "OpenFGA not ready"), 503)
+		return
+	}
+	if !isManagerAdmin(r) && checkDenied(w, r, "can_manage", fga.ObjectRef(fga.TypeOrganization, orgId), "Forbidden: only admins can manage this organization") {
+		return
+	}
+	body, err := httputil.ReadBody(r)
+	if err != nil {
+		httputil.JSONError(w, i18n.T(r, "Invalid request body"), 400)
+		return
+	}
+
+	store.Mu.Lock()
+	org, ok := store.Data.Organizations[orgId]
+	if !ok {
+		store.Mu.Unlock()
+		httputil.JSONError(w, i18n.T(r, "Organization not found"), 404)
+		return
+	}
+	if v := httputil.GetString(body, "name"); v != "" {
+		org.Name = v
+	}
+	if v, ok := body["description"].(string); ok {
+		org.Description = v
+	}
+	if v, ok := body["contact"].(string); ok {
+		org.Contact = v
+	}
+	org.Updated()
+	updated := *org
+	store.Mu.Unlock()
+	store.Save()
+	httputil.JSONResponse(w, map[string]interface{}{"success": true, "id": orgId, "organization": updated}, 200)
+}
+
 func OrganizationsAddMember(w http.ResponseWriter, r *http.Request, orgId string) {
 	if !config.FgaReady {
-		httputil.JSONError(w, "OpenFGA not ready", 503)
+		httputil.JSONError(w, i18n.T(r, "OpenFGA not ready"), 503)
 		return
 	}
 
-	currentUser := httputil.GetUser(r)
-	if !isManagerAdmin(r) && !fga.Check("user:"+currentUser, "can_manage", "organization:"+orgId) {
-		httputil.JSONError(w, "Forbidden: only admins can manage members", 403)
+	if !isManagerAdmin(r) && checkDenied(w, r, "can_manage", fga.ObjectRef(fga.TypeOrganization, orgId), "Forbidden: only admins can manage members") {
 		return
 	}
 
 	body, err := httputil.ReadBody(r)
 	if err != nil {
-		httputil.JSONError(w, "Invalid request body", 400)
+		httputil.JSONError(w, i18n.T(r, "Invalid request body"), 400)
+		return
+	}
+	member, ok := userField(w, r, body, "member")
+	if !ok {
 		return
 	}
-	member := httputil.GetString(body, "member")
 	if member == "" {
-		httputil.JSONError(w, "member is required", 400)
+		httputil.JSONError(w, i18n.T(r, "member is required"), 400)
 		return
 	}
 
@@ -119,12 +214,12 @@ func OrganizationsAddMember(w http.ResponseWriter, r *http.Request, orgId string
 	org, ok := store.Data.Organizations[orgId]
 	if !ok {
 		store.Mu.Unlock()
-		httputil.JSONError(w, "Organization not found", 404)
+		httputil.JSONError(w, i18n.T(r, "Organization not found"), 404)
 		return
 	}
 	if httputil.Contains(org.Members, member) {
 		store.Mu.Unlock()
-		httputil.JSONError(w, "Already a member", 400)
+		httputil.JSONError(w, i18n.T(r, "Already a member"), 400)
 		return
 	}
 	prevMembers := make([]string, len(org.Members))
@@ -132,38 +227,42 @@ func OrganizationsAddMember(w http.ResponseWriter, r *http.Request, orgId string
 	org.Members = append(org.Members, member)
 	store.Mu.Unlock()
 
-	if err := fga.Write([]store.TupleKey{{User: "user:" + member, Relation: "member", Object: "organization:" + orgId}}, nil); err != nil {
+	if err := fga.Write(r.Context(), []store.TupleKey{{User: fga.UserRef(member), Relation: "member", Object: fga.ObjectRef(fga.TypeOrganization, orgId)}}, nil); err != nil {
 		store.Mu.Lock()
 		org.Members = prevMembers
 		store.Mu.Unlock()
-		httputil.JSONError(w, err.Error(), 500)
+		fgaFailed(w, r, err)
 		return
 	}
 
+	store.Mu.Lock()
+	org.Updated()
+	store.Mu.Unlock()
 	store.Save()
 	httputil.JSONResponse(w, map[string]bool{"success": true}, 200)
 }
 
 func OrganizationsRemoveMember(w http.ResponseWriter, r *http.Request, orgId string) {
 	if !config.FgaReady {
-		httputil.JSONError(w, "OpenFGA not ready", 503)
+		httputil.JSONError(w, i18n.T(r, "OpenFGA not ready"), 503)
 		return
 	}
 
-	currentUser := httputil.GetUser(r)
-	if !isManagerAdmin(r) && !fga.Check("user:"+currentUser, "can_manage", "organization:"+orgId) {
-		httputil.JSONError(w, "Forbidden: only admins can manage members", 403)
+	if !isManagerAdmin(r) && checkDenied(w, r, "can_manage", fga.ObjectRef(fga.TypeOrganization, orgId), "Forbidden: only admins can manage members") {
 		return
 	}
 
 	body, err := httputil.ReadBody(r)
 	if err != nil {
-		httputil.JSONError(w, "Invalid request body", 400)
+		httputil.JSONError(w, i18n.T(r, "Invalid request body"), 400)
+		return
+	}
+	member, ok := userField(w, r, body, "member")
+	if !ok {
 		return
 	}
-	member := httputil.GetString(body, "member")
 	if member == "" {
-		httputil.JSONError(w, "member is required", 400)
+		httputil.JSONError(w, i18n.T(r, "member is required"), 400)
 		return
 	}
 
@@ -171,7 +270,7 @@ func OrganizationsRemoveMember(w http.ResponseWriter, r *http.Request, orgId str
 	org, ok := store.Data.Organizations[orgId]
 	if !ok {
 		store.Mu.Unlock()
-		httputil.JSONError(w, "Organization not found", 404)
+		httputil.JSONError(w, i18n.T(r, "Organization not found"), 404)
 		return
 	}
 	prevMembers := make([]string, len(org.Members))
@@ -185,37 +284,41 @@ func OrganizationsRemoveMember(w http.ResponseWriter, r *http.Request, orgId str
 	org.Members = filtered
 	store.Mu.Unlock()
 
-	if err := fga.Write(nil, []store.TupleKey{{User: "user:" + member, Relation: "member", Object: "organization:" + orgId}}); err != nil {
+	if err := fga.Write(r.Context(), nil, []store.TupleKey{{User: fga.UserRef(member), Relation: "member", Object: fga.ObjectRef(fga.TypeOrganization, orgId)}}); err != nil {
 		store.Mu.Lock()
 		org.Members = prevMembers
 		store.Mu.Unlock()
-		httputil.JSONError(w, err.Error(), 500)
+		fgaFailed(w, r, err)
 		return
 	}
+	store.Mu.Lock()
+	org.Updated()
+	store.Mu.Unlock()
 	store.Save()
 	httputil.JSONResponse(w, map[string]bool{"success": true}, 200)
 }
 
 func OrganizationsAddAdmin(w http.ResponseWriter, r *http.Request, orgId string) {
 	if !config.FgaReady {
-		httputil.JSONError(w, "OpenFGA not ready", 503)
+		httputil.JSONError(w, i18n.T(r, "OpenFGA not ready"), 503)
 		return
 	}
 
-	currentUser := httputil.GetUser(r)
-	if !isManagerAdmin(r) && !fga.Check("user:"+currentUser, "can_manage", "organization:"+orgId) {
-		httputil.JSONError(w, "Forbidden: only admins can manage admins", 403)
+	if !isManagerAdmin(r) && checkDenied(w, r, "can_manage", fga.ObjectRef(fga.TypeOrganization, orgId), "Forbidden: only admins can manage admins") {
 		return
 	}
 
 	body, err := httputil.ReadBody(r)
 	if err != nil {
-		httputil.JSONError(w, "Invalid request body", 400)
+		httputil.JSONError(w, i18n.T(r, "Invalid request body"), 400)
+		return
+	}
+	user, ok := userField(w, r, body, "user")
+	if !ok {
 		return
 	}
-	user := httputil.GetString(body, "user")
 	if user == "" {
-		httputil.JSONError(w, "user is required", 400)
+		httputil.JSONError(w, i18n.T(r, "user is required"), 400)
 		return
 	}
 
@@ -223,12 +326,12 @@ func OrganizationsAddAdmin(w http.ResponseWriter, r *http.Request, orgId string)
 	org, ok := store.Data.Organizations[orgId]
 	if !ok {
 		store.Mu.Unlock()
-		httputil.JSONError(w, "Organization not found", 404)
+		httputil.JSONError(w, i18n.T(r, "Organization not found"), 404)
 		return
 	}
 	if httputil.Contains(org.Admins, user) {
 		store.Mu.Unlock()
-		httputil.JSONError(w, "Already an admin", 400)
+		httputil.JSONError(w, i18n.T(r, "Already an admin"), 400)
 		return
 	}
 
@@ -245,44 +348,48 @@ func OrganizationsAddAdmin(w http.ResponseWriter, r *http.Request, orgId string)
 	store.Mu.Unlock()
 
 	var tuples []store.TupleKey
-	tuples = append(tuples, store.TupleKey{User: "user:" + user, Relation: "admin", Object: "organization:" + orgId})
+	tuples = append(tuples, store.TupleKey{User: fga.UserRef(user), Relation: "admin", Object: fga.ObjectRef(fga.TypeOrganization, orgId)})
 	if !isMember {
-		tuples = append(tuples, store.TupleKey{User: "user:" + user, Relation: "member", Object: "organization:" + orgId})
+		tuples = append(tuples, store.TupleKey{User: fga.UserRef(user), Relation: "member", Object: fga.ObjectRef(fga.TypeOrganization, orgId)})
 	}
 
-	if err := fga.Write(tuples, nil); err != nil {
+	if err := fga.Write(r.Context(), tuples, nil); err != nil {
 		store.Mu.Lock()
 		org.Admins = prevAdmins
 		org.Members = prevMembers
 		store.Mu.Unlock()
-		httputil.JSONError(w, err.Error(), 500)
+		fgaFailed(w, r, err)
 		return
 	}
 
+	store.Mu.Lock()
+	org.Updated()
+	store.Mu.Unlock()
 	store.Save()
 	httputil.JSONResponse(w, map[string]bool{"success": true}, 200)
 }
 
 func OrganizationsRemoveAdmin(w http.ResponseWriter, r *http.Request, orgId string) {
 	if !config.FgaReady {
-		httputil.JSONError(w, "OpenFGA not ready", 503)
+		httputil.JSONError(w, i18n.T(r, "OpenFGA not ready"), 503)
 		return
 	}
 
-	currentUser := httputil.GetUser(r)
-	if !isManagerAdmin(r) && !fga.Check("user:"+currentUser, "can_manage", "organization:"+orgId) {
-		httputil.JSONError(w, "Forbidden: only admins can manage admins", 403)
+	if !isManagerAdmin(r) && checkDenied(w, r, "can_manage", fga.ObjectRef(fga.TypeOrganization, orgId), "Forbidden: only admins can manage admins") {
 		return
 	}
 
 	body, err := httputil.ReadBody(r)
 	if err != nil {
-		httputil.JSONError(w, "Invalid request body", 400)
+		httputil.JSONError(w, i18n.T(r, "Invalid request body"), 400)
+		return
+	}
+	user, ok := userField(w, r, body, "user")
+	if !ok {
 		return
 	}
-	user := httputil.GetString(body, "user")
 	if user == "" {
-		httputil.JSONError(w, "user is required", 400)
+		httputil.JSONError(w, i18n.T(r, "user is required"), 400)
 		return
 	}
 
@@ -290,14 +397,14 @@ func OrganizationsRemoveAdmin(w http.ResponseWriter, r *http.Request, orgId stri
 	org, ok := store.Data.Organizations[orgId]
 	if !ok {
 		store.Mu.Unlock()
-		httputil.JSONError(w, "Organization not found", 404)
+		httputil.JSONError(w, i18n.T(r, "Organization not found"), 404)
 		return
 	}
 
 	// Prevent removing the last admin
 	if len(org.Admins) == 1 && httputil.Contains(org.Admins, user) {
 		store.Mu.Unlock()
-		httputil.JSONError(w, "Cannot remove the last admin. Add another admin first or delete the organization.", 400)
+		httputil.JSONError(w, i18n.T(r, "Cannot remove the last admin. Add another admin first or delete the organization."), 400)
 		return
 	}
 
@@ -312,85 +419,211 @@ func OrganizationsRemoveAdmin(w http.ResponseWriter, r *http.Request, orgId stri
 	org.Admins = filtered
 	store.Mu.Unlock()
 
-	if err := fga.Write(nil, []store.TupleKey{{User: "user:" + user, Relation: "admin", Object: "organization:" + orgId}}); err != nil {
+	if err := fga.Write(r.Context(), nil, []store.TupleKey{{User: fga.UserRef(user), Relation: "admin", Object: fga.ObjectRef(fga.TypeOrganization, orgId)}}); err != nil {
 		store.Mu.Lock()
 		org.Admins = prevAdmins
 		store.Mu.Unlock()
-		httputil.JSONError(w, err.Error(), 500)
+		fgaFailed(w, r, err)
 		return
 	}
 
+	store.Mu.Lock()
+	org.Updated()
+	store.Mu.Unlock()
 	store.Save()
 	httputil.JSONResponse(w, map[string]bool{"success": true}, 200)
 }
 
+// Org deletion modes for the dossiers parented to the organization.
+const (
+	orgDeleteDetach   = "detach"
+	orgDeleteReassign = "reassign"
+	orgDeleteDossiers = "delete-dossiers"
+)
+
+// OrganizationsDelete deletes an organization. Its dossiers are handled per
+// ?mode=: detach (default) clears their org, reassign moves them to
+// ?targetOrgId=, delete-dossiers deletes them. When any dossier is affected
+// the caller must echo their count in ?confirm=, otherwise the response is a
+// 409 carrying affectedDossiers so the client can ask the user.
 func OrganizationsDelete(w http.ResponseWriter, r *http.Request, orgId string) {
 	if !config.FgaReady {
-		httputil.JSONError(w, "OpenFGA not ready", 503)
+		httputil.JSONError(w, i18n.T(r, "OpenFGA not ready"), 503)
 		return
 	}
 
 	currentUser := httputil.GetUser(r)
-	if !isManagerAdmin(r) && !fga.Check("user:"+currentUser, "can_manage", "organization:"+orgId) {
-		httputil.JSONError(w, "Forbidden: only admins can delete organizations", 403)
+	if !isManagerAdmin(r) && checkDenied(w, r, "can_manage", fga.ObjectRef(fga.TypeOrganization, orgId), "Forbidden: only admins can delete organizations") {
 		return
 	}
 
-	store.Mu.Lock()
+	q := r.URL.Query()
+	mode := q.Get("mode")
+	if mode == "" {
+		mode = orgDeleteDetach
+	}
+	target := q.Get("targetOrgId")
+	switch mode {
+	case orgDeleteDetach, orgDeleteDossiers:
+		target = ""
+	case orgDeleteReassign:
+		if target == "" || target == orgId {
+			httputil.JSONError(w, i18n.T(r, "targetOrgId must name another organization"), 400)
+			return
+		}
+	default:
+		httputil.JSONError(w, i18n.T(r, "mode must be one of: %s", "detach, reassign, delete-dossiers"), 400)
+		return
+	}
+
+	store.Mu.RLock()
 	org, ok := store.Data.Organizations[orgId]
+	_, targetExists := store.Data.Organizations[target]
+	var members, admins []string
+	affected := map[string]*store.Dossier{}
+	if ok {
+		members = append(members, org.Members...)
+		admins = append(admins, org.Admins...)
+		for dossId, dossier := range store.Data.Dossiers {
+			if dossier.OrgId == orgId {
+				affected[dossId] = dossier
+			}
+		}
+	}
+	store.Mu.RUnlock()
 	if !ok {
-		store.Mu.Unlock()
-		httputil.JSONError(w, "Organization not found", 404)
+		httputil.JSONError(w, i18n.T(r, "Organization not found"), 404)
 		return
 	}
-
-	// Store copies for rollback and FGA tuple deletion
-	members := make([]string, len(org.Members))
-	copy(members, org.Members)
-	admins := make([]string, len(org.Admins))
-	copy(admins, org.Admins)
-	orgCopy := &store.Organization{Name: org.Name, Members: members, Admins: admins}
-
-	// Find all dossiers linked to this organization
-	var affectedDossiers []string
-	for dossId, dossier := range store.Data.Dossiers {
-		if dossier.OrgId == orgId {
-			affectedDossiers = append(affectedDossiers, dossId)
-			dossier.OrgId = "" // Clear the org reference
+	if target != "" {
+		if !targetExists {
+			httputil.JSONError(w, i18n.T(r, "Organization not found"), 404)
+			return
+		}
+		if !isManagerAdmin(r) && checkDenied(w, r, "member", fga.ObjectRef(fga.TypeOrganization, target), "You must be a member of the target organization") {
+			return
 		}
 	}
-
-	// Delete from data store
-	delete(store.Data.Organizations, orgId)
-	store.Mu.Unlock()
+	if len(affected) > 0 && q.Get("confirm") != strconv.Itoa(len(affected)) {
+		httputil.JSONResponse(w, map[string]interface{}{
+			"error":            i18n.T(r, "This organization has %d dossiers; repeat the request with confirm=%d", len(affected), len(affected)),
+			"affectedDossiers": len(affected),
+			"mode":             mode,
+		}, 409)
+		return
+	}
 
 	// Build tuples to delete (all member, admin, and org_parent relations)
-	var deleteTuples []store.TupleKey
+	var writeTuples, deleteTuples []store.TupleKey
 	for _, member := range members {
-		deleteTuples = append(deleteTuples, store.TupleKey{User: "user:" + member, Relation: "member", Object: "organization:" + orgId})
+		deleteTuples = append(deleteTuples, store.TupleKey{User: fga.UserRef(member), Relation: "member", Object: fga.ObjectRef(fga.TypeOrganization, orgId)})
 	}
 	for _, admin := range admins {
-		deleteTuples = append(deleteTuples, store.TupleKey{User: "user:" + admin, Relation: "admin", Object: "organization:" + orgId})
+		deleteTuples = append(deleteTuples, store.TupleKey{User: fga.UserRef(admin), Relation: "admin", Object: fga.ObjectRef(fga.TypeOrganization, orgId)})
+	}
+	for dossId, dossier := range affected {
+		if mode == orgDeleteDossiers {
+			store.Mu.RLock()
+			deleteTuples = append(deleteTuples, dossierTuples(dossId, dossier)...)
+			store.Mu.RUnlock()
+			continue
+		}
+		deleteTuples = append(deleteTuples, store.TupleKey{User: fga.ObjectRef(fga.TypeOrganization, orgId), Relation: "org_parent", Object: fga.ObjectRef(fga.TypeDossier, dossId)})
+		if target != "" {
+			writeTuples = append(writeTuples, store.TupleKey{User: fga.ObjectRef(fga.TypeOrganization, target), Relation: "org_parent", Object: fga.ObjectRef(fga.TypeDossier, dossId)})
+		}
 	}
-	// Delete org_parent tuples for affected dossiers
-	for _, dossId := range affectedDossiers {
-		deleteTuples = append(deleteTuples, store.TupleKey{User: "organization:" + orgId, Relation: "org_parent", Object: "dossier:" + dossId})
+	if err := fga.Write(r.Context(), writeTuples, deleteTuples); err != nil {
+		fgaFailed(w, r, err)
+		return
 	}
 
-	if err := fga.Write(nil, deleteTuples); err != nil {
-		// Rollback: restore organization and dossier org references
-		store.Mu.Lock()
-		store.Data.Organizations[orgId] = orgCopy
-		for _, dossId := range affectedDossiers {
-			if dossier, ok := store.Data.Dossiers[dossId]; ok {
-				dossier.OrgId = orgId
-			}
+	store.Mu.Lock()
+	delete(store.Data.Organizations, orgId)
+	for dossId, dossier := range affected {
+		if mode == orgDeleteDossiers {
+			delete(store.Data.Dossiers, dossId)
+			dropAccessRequests(dossId)
+			continue
 		}
-		store.Mu.Unlock()
-		httputil.JSONError(w, err.Error(), 500)
+		dossier.OrgId = target
+		dossier.Updated()
+	}
+	var joins []store.JoinRequest
+	for _, req := range store.Data.JoinRequests {
+		if req.OrgId != orgId {
+			joins = append(joins, req)
+		}
+	}
+	store.Data.JoinRequests = joins
+	store.Mu.Unlock()
+	store.Save()
+
+	ids := make([]string, 0, len(affected))
+	for dossId := range affected {
+		ids = append(ids, dossId)
+	}
+	sort.Strings(ids)
+	for _, dossId := range ids {
+		dossier := affected[dossId]
+		if mode == orgDeleteDossiers && dossier.ArchivedAt != nil {
+			store.DropArchive(dossId)
+		}
+		audit.SendAuditLog("test-app", "org_delete_"+mode, fga.UserRef(currentUser), "org_parent", fga.ObjectRef(fga.TypeDossier, dossId), "DELETE",
+			"Organization "+orgId+" deleted; dossier "+orgDeleteOutcome(mode, target))
+		events.Publish(events.Event{
+			Type: events.DossierOrgChanged, Actor: currentUser, Object: fga.ObjectRef(fga.TypeDossier, dossId), Recipients: dossier.Owners,
+			Data: map[string]string{"from": orgId, "to": target, "reason": "org_deleted", "mode": mode},
+		})
+	}
+	httputil.JSONResponse(w, map[string]interface{}{"success": true, "mode": mode, "affectedDossiers": ids}, 200)
+}
+
+func orgDeleteOutcome(mode, target string) string {
+	switch mode {
+	case orgDeleteReassign:
+		return "moved to organization:" + target
+	case orgDeleteDossiers:
+		return "deleted"
+	}
+	return "detached"
+}
+
+// OrganizationsDossiers lists, one page at a time, the dossiers parented to an
+// organization that the caller can view.
+func OrganizationsDossiers(w http.ResponseWriter, r *http.Request, orgId string) {
+	if !config.FgaReady {
+		httputil.JSONError(w, i18n.T(r, "OpenFGA not ready"), 503)
+		return
+	}
+	store.Mu.RLock()
+	_, ok := store.Data.Organizations[orgId]
+	store.Mu.RUnlock()
+	if !ok {
+		httputil.JSONError(w, i18n.T(r, "Organization not found"), 404)
+		return
+	}
+	p, ok := pageFrom(r)
+	if !ok {
+		httputil.JSONError(w, i18n.T(r, "offset and limit must be positive integers"), 400)
+		return
+	}
+	if notModified(w, r, "org-dossiers", orgId) {
 		return
 	}
 
-	store.Save()
-	httputil.JSONResponse(w, map[string]bool{"success": true}, 200)
+	visible, err := visibleDossiers(httputil.GetUser(r), accessContextFrom(r))
+	if err != nil {
+		fgaUnavailable(w, r, err)
+		return
+	}
+	dossiers := []dossierView{}
+	for _, d := range visible {
+		if d.OrgId == orgId {
+			dossiers = append(dossiers, d)
+		}
+	}
+	sort.Slice(dossiers, func(i, j int) bool { return dossiers[i].Id < dossiers[j].Id })
+	start, end := p.bounds(len(dossiers))
+	listResponse(w, r, "dossiers", dossiers[start:end], listMeta{Pagination: p.meta(len(dossiers)), Users: dossierUsers(dossiers[start:end])})
 }
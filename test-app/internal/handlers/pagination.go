@@ -0,0 +1,53 @@
+package handlers
+
+import (
+	"net/http"
+	"strconv"
+)
+
+const (
+	defaultPageSize = 50
+	maxPageSize     = 200
+)
+
+// page is an offset/limit window parsed from ?offset= and ?limit=.
+type page struct {
+	Offset int
+	Limit  int
+}
+
+// pageFrom reads the pagination parameters, reporting false when they are malformed.
+func pageFrom(r *http.Request) (page, bool) {
+	p := page{Limit: defaultPageSize}
+	q := r.URL.Query()
+	if v := q.Get("offset"); v != "" {
+		n, err := strconv.Atoi(v)
+		if err != nil || n < 0 {
+			return p, false
+		}
+		p.Offset = n
+	}
+	if v := q.Get("limit"); v != "" {
+		n, err := strconv.Atoi(v)
+		if err != nil || n < 1 {
+			return p, false
+		}
+		p.Limit = min(n, maxPageSize)
+	}
+	return p, true
+}
+
+// bounds returns the slice indexes of the page within total items.
+func (p page) bounds(total int) (int, int) {
+	start := min(p.Offset, total)
+	return start, min(start+p.Limit, total)
+}
+
+// meta describes the page for the response body; nextOffset is omitted on the last page.
+func (p page) meta(total int) map[string]interface{} {
+	m := map[string]interface{}{"total": total, "offset": p.Offset, "limit": p.Limit}
+	if _, end := p.bounds(total); end < total {
+		m["nextOffset"] = end
+	}
+	return m
+}
@@ -0,0 +1,102 @@
+package handlers
+
+import (
+	"errors"
+	"log"
+	"net/http"
+
+	"test-app/internal/config"
+	"test-app/internal/fga"
+	"test-app/internal/httputil"
+	"test-app/internal/i18n"
+	"test-app/internal/store"
+	"test-app/internal/templates"
+)
+
+// renderFragment writes a server-rendered HTML fragment for HTMX swaps.
+func renderFragment(w http.ResponseWriter, r *http.Request, name string, items interface{}) {
+	w.Header().Set("Content-Type", "text/html; charset=utf-8")
+	if err := templates.Fragments.ExecuteTemplate(w, name, templates.FragmentData{Lang: i18n.Lang(r), Items: items}); err != nil {
+		log.Printf("Fragment %s render error: %v", name, err)
+	}
+}
+
+// fragmentError writes a plain-text error suitable for an HTMX error swap.
+func fragmentError(w http.ResponseWriter, r *http.Request, msg string, status int) {
+	w.Header().Set("Content-Type", "text/plain; charset=utf-8")
+	w.WriteHeader(status)
+	w.Write([]byte(i18n.T(r, msg)))
+}
+
+// PartialDossierList renders the caller's visible dossiers; edit controls
+// are only emitted for dossiers where OpenFGA grants editor.
+func PartialDossierList(w http.ResponseWriter, r *http.Request) {
+	if !config.FgaReady {
+		fragmentError(w, r, "OpenFGA not ready", 503)
+		return
+	}
+	if notModified(w, r, "partial-dossiers") {
+		return
+	}
+	user := httputil.GetUser(r)
+	dossiers, err := visibleDossiers(user, accessContextFrom(r))
+	if err != nil {
+		fragmentError(w, r, "Authorization service unavailable, retry later", 503)
+		return
+	}
+	renderFragment(w, r, "dossier-list", dossiers)
+}
+
+// PartialRelationRows renders the relation table rows of a dossier for its editors.
+func PartialRelationRows(w http.ResponseWriter, r *http.Request, id string) {
+	if !config.FgaReady {
+		fragmentError(w, r, "OpenFGA not ready", 503)
+		return
+	}
+	user := httputil.GetUser(r)
+	store.Mu.RLock()
+	dossier, ok := store.Data.Dossiers[id]
+	var rels []store.Relation
+	restricted := ""
+	if ok {
+		rels = append(rels, dossier.Relations...)
+		restricted = mandateRestriction(dossier, user, accessContextFrom(r))
+	}
+	store.Mu.RUnlock()
+	if !ok {
+		fragmentError(w, r, "Dossier not found", 404)
+		return
+	}
+	allowed, err := fga.Allowed(r.Context(), fga.UserRef(user), "editor", fga.ObjectRef(fga.TypeDossier, id))
+	if errors.Is(err, fga.ErrUnavailable) {
+		fragmentError(w, r, "Authorization service unavailable, retry later", 503)
+		return
+	}
+	if !allowed {
+		if config.HideExistence.Get() {
+			visible, err := viewableDossier(r, id)
+			if err != nil {
+				fragmentError(w, r, "Authorization service unavailable, retry later", 503)
+				return
+			}
+			if !visible {
+				fragmentError(w, r, "Dossier not found", 404)
+				return
+			}
+		}
+		fragmentError(w, r, "Not authorized", 403)
+		return
+	}
+	if restricted != "" {
+		fragmentError(w, r, "Not authorized", 403)
+		return
+	}
+	renderFragment(w, r, "relation-rows", rels)
+}
+
+// PartialRequestList renders the caller's pending incoming and outgoing guardianship requests.
+func PartialRequestList(w http.ResponseWriter, r *http.Request) {
+	user := httputil.GetUser(r)
+	incoming, outgoing := pendingRequests(user)
+	renderFragment(w, r, "request-list", map[string]interface{}{"Incoming": incoming, "Outgoing": outgoing})
+}
@@ -0,0 +1,41 @@
+package handlers
+
+import (
+	"context"
+	"sync"
+
+	"test-app/internal/config"
+)
+
+// parallel runs fn for every index below n on at most config.ListCheckConcurrency
+// goroutines and waits for them, so per-item checks in list handlers overlap
+// instead of queueing. No new item starts once ctx is done; fn must then
+// tolerate the items it never saw. FGA calls the workers make with ctx count
+// against the request's call budget.
+func parallel(ctx context.Context, n int, fn func(i int)) {
+	if ctx == nil {
+		ctx = context.Background()
+	}
+	if config.ListCheckConcurrency.Get() <= 1 || n <= 1 {
+		for i := 0; i < n && ctx.Err() == nil; i++ {
+			fn(i)
+		}
+		return
+	}
+	sem := make(chan struct{}, config.ListCheckConcurrency.Get())
+	var wg sync.WaitGroup
+loop:
+	for i := 0; i < n; i++ {
+		select {
+		case <-ctx.Done():
+			break loop
+		case sem <- struct{}{}:
+		}
+		wg.Add(1)
+		go func(i int) {
+			defer func() { <-sem; wg.Done() }()
+			fn(i)
+		}(i)
+	}
+	wg.Wait()
+}
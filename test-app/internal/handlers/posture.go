@@ -0,0 +1,141 @@
+package handlers
+
+import (
+	"net/http"
+	"net/url"
+	"sort"
+	"time"
+
+	"test-app/internal/config"
+	"test-app/internal/fga"
+	"test-app/internal/httputil"
+	"test-app/internal/i18n"
+	"test-app/internal/store"
+)
+
+// Thresholds past which AdminPosture flags a pattern.
+const (
+	postureMaxSharedUsers = 10
+	postureMaxWards       = 5
+)
+
+// postureFinding is one risky pattern, with links to inspect or fix it.
+type postureFinding struct {
+	Kind     string   `json:"kind"`
+	Severity string   `json:"severity"` // high, medium or low
+	Subject  string   `json:"subject"`  // FGA object or user concerned
+	Detail   string   `json:"detail"`
+	Count    int      `json:"count,omitempty"`
+	Links    []string `json:"links"`
+}
+
+// AdminPosture handles GET /api/admin/posture: a summary of risky
+// authorization patterns in the current data, for governance review. Grants
+// carry no expiry date, so the stale tuples reported are those left in OpenFGA
+// after the grant behind them was removed from the store.
+func AdminPosture(w http.ResponseWriter, r *http.Request) {
+	if !isManagerAdmin(r) {
+		httputil.JSONError(w, i18n.T(r, "Admin access required"), 403)
+		return
+	}
+	findings := postureFindings()
+	if config.FgaReady {
+		if actual, err := fga.ReadAll(r.Context()); err == nil {
+			_, extra := store.DiffTuples(actual)
+			for _, t := range extra {
+				findings = append(findings, postureFinding{
+					Kind: "stale_tuple", Severity: "high", Subject: t.Object,
+					Detail: t.User + " " + t.Relation + " " + t.Object + " is in OpenFGA but no longer backed by the store",
+					Links:  []string{"/api/dossiers/debug/tuples?user=" + url.QueryEscape(t.User), "/api/admin/tuples/export"},
+				})
+			}
+		}
+	}
+	sortFindings(findings)
+	summary := map[string]int{}
+	for _, f := range findings {
+		summary[f.Kind]++
+	}
+	httputil.JSONResponse(w, map[string]interface{}{
+		"findings": findings,
+		"summary":  summary,
+		"thresholds": map[string]int{
+			"sharedUsers": postureMaxSharedUsers,
+			"wards":       postureMaxWards,
+		},
+		"generatedAt": time.Now().UTC(),
+	}, 200)
+}
+
+// postureFindings returns the findings computed from the store alone.
+func postureFindings() []postureFinding {
+	findings := []postureFinding{}
+	store.Mu.RLock()
+	for id, d := range store.Data.Dossiers {
+		links := []string{"/api/dossiers/" + url.PathEscape(id) + "/relations"}
+		shared := map[string]bool{}
+		for _, rel := range d.Relations {
+			shared[rel.User] = true
+		}
+		if len(shared) >= postureMaxSharedUsers {
+			findings = append(findings, postureFinding{
+				Kind: "overshared_dossier", Severity: "medium", Subject: fga.ObjectRef(fga.TypeDossier, id),
+				Detail: d.Title + " is shared with many users", Count: len(shared), Links: links,
+			})
+		}
+		if d.Public && d.Type == "health" {
+			findings = append(findings, postureFinding{
+				Kind: "public_health_dossier", Severity: "high", Subject: fga.ObjectRef(fga.TypeDossier, id),
+				Detail: d.Title + " is a public health dossier", Links: links,
+			})
+		}
+	}
+	wards := map[string]int{}
+	for _, guardians := range store.Data.Guardianships {
+		for _, g := range guardians {
+			wards[g]++
+		}
+	}
+	for guardian, n := range wards {
+		if n >= postureMaxWards {
+			findings = append(findings, postureFinding{
+				Kind: "guardian_of_many", Severity: "medium", Subject: fga.UserRef(guardian),
+				Detail: guardian + " is guardian of many users", Count: n,
+				Links: []string{"/api/admin/graph.dot?user=" + url.QueryEscape(guardian), "/api/dossiers/admin/guardianships"},
+			})
+		}
+	}
+	for id, org := range store.Data.Organizations {
+		if org == nil || len(org.Admins) > 1 {
+			continue
+		}
+		links := []string{"/api/dossiers/organizations/" + url.PathEscape(id), "/api/admin/organizations/" + url.PathEscape(id) + "/admins"}
+		if len(org.Admins) == 0 {
+			findings = append(findings, postureFinding{
+				Kind: "orphaned_organization", Severity: "high", Subject: fga.ObjectRef(fga.TypeOrganization, id),
+				Detail: org.Name + " has no admin", Links: links,
+			})
+			continue
+		}
+		findings = append(findings, postureFinding{
+			Kind: "single_admin_organization", Severity: "low", Subject: fga.ObjectRef(fga.TypeOrganization, id),
+			Detail: org.Name + " has a single admin, " + org.Admins[0], Count: 1, Links: links,
+		})
+	}
+	store.Mu.RUnlock()
+	return findings
+}
+
+// sortFindings orders findings most severe first.
+func sortFindings(findings []postureFinding) {
+	rank := map[string]int{"high": 0, "medium": 1, "low": 2}
+	sort.Slice(findings, func(i, j int) bool {
+		if rank[findings[i].Severity] != rank[findings[j].Severity] {
+			return rank[findings[i].Severity] < rank[findings[j].Severity]
+		}
+		if findings[i].Kind != findings[j].Kind {
+			return findings[i].Kind < findings[j].Kind
+		}
+		return findings[i].Subject < findings[j].Subject
+	})
+}
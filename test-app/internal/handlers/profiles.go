@@ -0,0 +1,39 @@
+package handlers
+
+import (
+	"net/http"
+
+	"test-app/internal/httputil"
+	"test-app/internal/i18n"
+	"test-app/internal/profiles"
+	"test-app/internal/store"
+	"test-app/internal/users"
+)
+
+// UsersProfile handles GET /api/users/{id}/profile: the user's display name
+// and avatar color for anyone, plus email, locale and sign-in history for the
+// user themselves and admins. Users without a profile get their username as
+// display name.
+func UsersProfile(w http.ResponseWriter, r *http.Request, id string) {
+	user, err := users.Normalize(id)
+	if err != nil || user == "" {
+		httputil.JSONError(w, i18n.T(r, "Invalid username"), 400)
+		return
+	}
+	card := profiles.Cards([]string{user})[user]
+	resp := map[string]interface{}{"user": user, "displayName": card.DisplayName, "color": card.Color}
+	store.Mu.RLock()
+	p := store.Data.Profiles[user]
+	resp["provisioned"] = p != nil
+	if p != nil && (user == httputil.GetUser(r) || isManagerAdmin(r)) {
+		resp["email"] = p.Email
+		resp["locale"] = p.Locale
+		resp["logins"] = p.Logins
+		resp["lastLogin"] = p.LastLogin
+		resp["lastLogout"] = p.LastLogout
+		resp["createdAt"] = p.CreatedAt
+		resp["createdBy"] = p.CreatedBy
+	}
+	store.Mu.RUnlock()
+	httputil.JSONResponse(w, resp, 200)
+}
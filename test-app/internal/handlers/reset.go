@@ -0,0 +1,159 @@
+package handlers
+
+import (
+	"encoding/json"
+	"net/http"
+	"os"
+	"sync"
+	"time"
+
+	"test-app/internal/analytics"
+	"test-app/internal/audit"
+	"test-app/internal/config"
+	"test-app/internal/fga"
+	"test-app/internal/httputil"
+	"test-app/internal/i18n"
+	"test-app/internal/profiles"
+	"test-app/internal/recent"
+	"test-app/internal/rolesync"
+	"test-app/internal/store"
+	"test-app/internal/visibility"
+)
+
+// resetConfirmTTL is how long the confirmation token of a previewed reset stays valid.
+const resetConfirmTTL = 2 * time.Minute
+
+type pendingReset struct {
+	seed    bool
+	expires time.Time
+}
+
+var (
+	resetPendingMu sync.Mutex
+	resetPending   = map[string]pendingReset{}
+)
+
+// AdminReset wipes the demo back to a clean state (for admin use). A request
+// without a confirmToken only previews what would be removed and returns a
+// short-lived token; repeating it with {"confirmToken": "..."} deletes every
+// tuple in the OpenFGA store, empties the data store, clears the ListObjects
+// cache, the visibility index, share suggestions, recent views, pending share
+// commands and undo tokens, the roles and profile claims last recorded for
+// each user, usage analytics and the local audit buffer.
+// With {"seed": true} in the preview, the data store is then re-seeded from
+// config.SeedFile and its tuples written.
+func AdminReset(w http.ResponseWriter, r *http.Request) {
+	if !isManagerAdmin(r) {
+		httputil.JSONError(w, i18n.T(r, "Admin access required"), 403)
+		return
+	}
+	if !config.FgaReady {
+		httputil.JSONError(w, i18n.T(r, "OpenFGA not ready"), 503)
+		return
+	}
+	body, err := httputil.ReadBody(r)
+	if err != nil {
+		httputil.JSONError(w, i18n.T(r, "Invalid request body"), 400)
+		return
+	}
+
+	token := httputil.GetString(body, "confirmToken")
+	if token == "" {
+		seed, _ := body["seed"].(bool)
+		if seed && config.SeedFile == "" {
+			httputil.JSONError(w, i18n.T(r, "No seed file configured (SEED_FILE)"), 400)
+			return
+		}
+		tuples, err := fga.ReadAll(r.Context())
+		if err != nil {
+			fgaFailed(w, r, err)
+			return
+		}
+		store.Mu.RLock()
+		dossiers := len(store.Data.Dossiers)
+		store.Mu.RUnlock()
+		if token, err = newToken(); err != nil {
+			httputil.JSONError(w, err.Error(), 500)
+			return
+		}
+		resetPendingMu.Lock()
+		for k, p := range resetPending {
+			if time.Now().After(p.expires) {
+				delete(resetPending, k)
+			}
+		}
+		resetPending[token] = pendingReset{seed: seed, expires: time.Now().Add(resetConfirmTTL)}
+		resetPendingMu.Unlock()
+		httputil.JSONResponse(w, map[string]interface{}{
+			"dryRun": true, "dossiers": dossiers, "tuples": len(tuples), "seed": seed,
+			"confirmToken": token, "expiresIn": int(resetConfirmTTL / time.Second),
+		}, 200)
+		return
+	}
+
+	resetPendingMu.Lock()
+	pending, ok := resetPending[token]
+	delete(resetPending, token)
+	resetPendingMu.Unlock()
+	if !ok || time.Now().After(pending.expires) {
+		httputil.JSONError(w, i18n.T(r, "Confirmation token is invalid or expired"), 400)
+		return
+	}
+
+	// Read the seed first so a missing or corrupt file leaves everything in place.
+	raw := []byte("{}")
+	if pending.seed {
+		if raw, err = os.ReadFile(config.SeedFile); err != nil {
+			httputil.JSONError(w, i18n.T(r, "Cannot read seed file: %s", err.Error()), 500)
+			return
+		}
+		if err := json.Unmarshal(raw, &store.DataStore{}); err != nil {
+			httputil.JSONError(w, i18n.T(r, "Corrupt seed file: %s", err.Error()), 500)
+			return
+		}
+	}
+
+	tuples, err := fga.ReadAll(r.Context())
+	if err != nil {
+		fgaFailed(w, r, err)
+		return
+	}
+	if err := writeInBatches(r.Context(), nil, tuples); err != nil {
+		fgaFailed(w, r, err)
+		return
+	}
+	if err := store.Replace(raw); err != nil {
+		httputil.JSONError(w, err.Error(), 500)
+		return
+	}
+	store.Save()
+	written := 0
+	if pending.seed {
+		desired := store.DesiredTuples()
+		if err := writeInBatches(r.Context(), desired, nil); err != nil {
+			fgaFailed(w, r, err)
+			return
+		}
+		written = len(desired)
+	}
+
+	fga.FlushListCache()
+	visibility.Reset()
+	// Caches and pending tokens keyed by user or dossier describe the old data.
+	candidatesMu.Lock()
+	candidateCache = map[string]candidateEntry{}
+	candidatesMu.Unlock()
+	nlPendingMu.Lock()
+	nlPending = map[string]pendingCommand{}
+	nlPendingMu.Unlock()
+	recent.Reset()
+	rolesync.Reset()
+	profiles.Reset()
+	forgetUndo()
+	analytics.Reset()
+	audit.Reset()
+	audit.SendAuditLog("test-app", "reset", "admin", "", "store:"+config.FgaStoreId, "POST", "Demo reset: all data and tuples removed")
+	httputil.JSONResponse(w, map[string]interface{}{
+		"success": true, "tuplesDeleted": len(tuples), "tuplesWritten": written, "seeded": pending.seed,
+	}, 200)
+}
@@ -0,0 +1,455 @@
+package handlers
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"sort"
+	"strings"
+	"sync"
+
+	"test-app/internal/config"
+	"test-app/internal/fga"
+	"test-app/internal/httputil"
+	"test-app/internal/i18n"
+	"test-app/internal/resources"
+	"test-app/internal/store"
+)
+
+// ResourcesRouter serves the generic endpoints for every registered resource type:
+//
+//	GET    /api/resources                         registered types
+//	GET    /api/resources/{plural}                list visible resources
+//	POST   /api/resources/{plural}                create
+//	GET    /api/resources/{plural}/{id}           read
+//	PUT    /api/resources/{plural}/{id}           update fields
+//	DELETE /api/resources/{plural}/{id}           delete
+//	GET    /api/resources/{plural}/{id}/relations list relations
+//	POST   /api/resources/{plural}/{id}/relations grant {targetUser, relation}
+//	DELETE /api/resources/{plural}/{id}/relations revoke {targetUser, relation}
+func ResourcesRouter(w http.ResponseWriter, r *http.Request) {
+	path := strings.Trim(strings.TrimPrefix(r.URL.Path, "/api/resources"), "/")
+	if path == "" {
+		listResponse(w, r, "types", resources.Types(), listMeta{})
+		return
+	}
+	if !config.FgaReady {
+		httputil.JSONError(w, i18n.T(r, "OpenFGA not ready"), 503)
+		return
+	}
+	parts := strings.Split(path, "/")
+	t, ok := resources.ByPlural(parts[0])
+	if !ok {
+		httputil.JSONError(w, i18n.T(r, "Not found"), 404)
+		return
+	}
+	switch {
+	case len(parts) == 1 && r.Method == "GET":
+		resourcesList(w, r, t)
+	case len(parts) == 1 && r.Method == "POST":
+		resourcesCreate(w, r, t)
+	case len(parts) == 2 && r.Method == "GET":
+		resourcesGet(w, r, t, parts[1])
+	case len(parts) == 2 && r.Method == "PUT":
+		resourcesUpdate(w, r, t, parts[1])
+	case len(parts) == 2 && r.Method == "DELETE":
+		resourcesDelete(w, r, t, parts[1])
+	case len(parts) == 3 && parts[2] == "relations" && r.Method == "GET":
+		resourcesRelationsGet(w, r, t, parts[1])
+	case len(parts) == 3 && parts[2] == "relations" && (r.Method == "POST" || r.Method == "DELETE"):
+		resourcesRelationsChange(w, r, t, parts[1])
+	default:
+		httputil.JSONError(w, i18n.T(r, "Method not allowed"), 405)
+	}
+}
+
+type resourceView struct {
+	*store.Resource
+	CanEdit bool     `json:"canEdit"`
+	Liens   []string `json:"liens,omitempty"`
+}
+
+// lienHolders returns the users holding t's lien relation on res. The caller
+// holds store.Mu.
+func lienHolders(t *resources.Type, res *store.Resource) []string {
+	var holders []string
+	for _, rel := range res.Relations {
+		if t.Lien != "" && rel.Relation == t.Lien {
+			holders = append(holders, rel.User)
+		}
+	}
+	return holders
+}
+
+// copyResource returns a copy of res that can be encoded once store.Mu is
+// released: its fields, owners and relations are copied too. The caller
+// holds store.Mu.
+func copyResource(res *store.Resource) *store.Resource {
+	c := *res
+	c.Fields = make(map[string]string, len(res.Fields))
+	for k, v := range res.Fields {
+		c.Fields[k] = v
+	}
+	c.Owners = append(res.Owners[:0:0], res.Owners...)
+	c.Relations = append(res.Relations[:0:0], res.Relations...)
+	return &c
+}
+
+// relockResource takes store.Mu after an OpenFGA write and reports whether
+// res is still the stored resource, as lockedDossier.Relock does for
+// dossiers. When it was deleted or replaced meanwhile, the lock is released
+// and it returns false.
+func relockResource(res *store.Resource) bool {
+	store.Mu.Lock()
+	if store.Data.Resources[res.Object()] != res {
+		store.Mu.Unlock()
+		return false
+	}
+	return true
+}
+
+// loadResource looks the resource up and checks the caller has relation on it.
+func loadResource(w http.ResponseWriter, r *http.Request, t *resources.Type, id, relation string) (*store.Resource, bool) {
+	store.Mu.RLock()
+	res, ok := store.Data.Resources[t.Name+":"+id]
+	store.Mu.RUnlock()
+	if !ok {
+		httputil.JSONError(w, i18n.T(r, "Resource not found"), 404)
+		return nil, false
+	}
+	if !isManagerAdmin(r) {
+		allowed, err := fga.Allowed(r.Context(), fga.UserRef(httputil.GetUser(r)), relation, res.Object())
+		if errors.Is(err, fga.ErrUnavailable) {
+			fgaUnavailable(w, r, err)
+			return nil, false
+		}
+		if !allowed {
+			httputil.JSONError(w, i18n.T(r, "Not authorized to %s this resource", relation), 403)
+			return nil, false
+		}
+	}
+	return res, true
+}
+
+func resourcesList(w http.ResponseWriter, r *http.Request, t *resources.Type) {
+	if notModified(w, r, t.Plural) {
+		return
+	}
+	user := httputil.GetUser(r)
+	visible, err := fga.ListObjects(r.Context(), fga.UserRef(user), "viewer", t.Name)
+	if err != nil {
+		fgaUnavailable(w, r, err)
+		return
+	}
+	items := []resourceView{}
+	store.Mu.RLock()
+	for _, obj := range visible {
+		if res, ok := store.Data.Resources[obj]; ok {
+			items = append(items, resourceView{Resource: copyResource(res), Liens: lienHolders(t, res)})
+		}
+	}
+	store.Mu.RUnlock()
+	var failMu sync.Mutex
+	var failed error
+	parallel(r.Context(), len(items), func(i int) {
+		canEdit, err := fga.Allowed(r.Context(), fga.UserRef(user), "editor", items[i].Object())
+		if errors.Is(err, fga.ErrUnavailable) {
+			failMu.Lock()
+			failed = err
+			failMu.Unlock()
+			return
+		}
+		items[i].CanEdit = canEdit
+	})
+	if failed != nil {
+		fgaUnavailable(w, r, failed)
+		return
+	}
+	sort.Slice(items, func(i, j int) bool { return items[i].Id < items[j].Id })
+	listResponse(w, r, t.Plural, items, listMeta{})
+}
+
+// readFields decodes {"fields": {...}, "orgId": "..."} from the body.
+func readFields(r *http.Request) (map[string]string, string, error) {
+	var body struct {
+		Fields map[string]string `json:"fields"`
+		OrgId  string            `json:"orgId"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&body); err != nil {
+		return nil, "", err
+	}
+	if body.Fields == nil {
+		body.Fields = map[string]string{}
+	}
+	return body.Fields, body.OrgId, nil
+}
+
+func resourcesCreate(w http.ResponseWriter, r *http.Request, t *resources.Type) {
+	user := httputil.GetUser(r)
+	fields, orgId, err := readFields(r)
+	if err != nil {
+		httputil.JSONError(w, i18n.T(r, "Invalid request body"), 400)
+		return
+	}
+	if err := t.Validate(fields, false); err != nil {
+		httputil.JSONError(w, err.Error(), 400)
+		return
+	}
+	if orgId != "" {
+		if !t.OrgParent {
+			httputil.JSONError(w, i18n.T(r, "This resource type cannot belong to an organization"), 400)
+			return
+		}
+		store.Mu.RLock()
+		_, orgExists := store.Data.Organizations[orgId]
+		store.Mu.RUnlock()
+		if !orgExists {
+			httputil.JSONError(w, i18n.T(r, "Organization not found"), 404)
+			return
+		}
+	}
+
+	res := &store.Resource{Type: t.Name, Id: store.RandId(), Fields: fields, Owners: []string{user}, OrgId: orgId, Meta: store.NewMeta(user)}
+	view := copyResource(res)
+	store.Mu.Lock()
+	if store.Data.Resources == nil {
+		store.Data.Resources = make(map[string]*store.Resource)
+	}
+	store.Data.Resources[res.Object()] = res
+	store.Mu.Unlock()
+
+	tuples := []store.TupleKey{{User: fga.UserRef(user), Relation: "owner", Object: res.Object()}}
+	if orgId != "" {
+		tuples = append(tuples, store.TupleKey{User: fga.ObjectRef(fga.TypeOrganization, orgId), Relation: "org_parent", Object: res.Object()})
+	}
+	if err := fga.Write(r.Context(), tuples, nil); err != nil {
+		store.Mu.Lock()
+		delete(store.Data.Resources, res.Object())
+		store.Mu.Unlock()
+		fgaFailed(w, r, err)
+		return
+	}
+	store.Save()
+	httputil.JSONResponse(w, view, 200)
+}
+
+func resourcesGet(w http.ResponseWriter, r *http.Request, t *resources.Type, id string) {
+	res, ok := loadResource(w, r, t, id, "viewer")
+	if !ok {
+		return
+	}
+	if notModified(w, r, res.Object()) {
+		return
+	}
+	canEdit, err := fga.Allowed(r.Context(), fga.UserRef(httputil.GetUser(r)), "editor", res.Object())
+	if errors.Is(err, fga.ErrUnavailable) {
+		fgaUnavailable(w, r, err)
+		return
+	}
+	store.Mu.RLock()
+	view := resourceView{Resource: copyResource(res), CanEdit: canEdit, Liens: lienHolders(t, res)}
+	store.Mu.RUnlock()
+	httputil.JSONResponse(w, view, 200)
+}
+
+func resourcesUpdate(w http.ResponseWriter, r *http.Request, t *resources.Type, id string) {
+	res, ok := loadResource(w, r, t, id, "editor")
+	if !ok {
+		return
+	}
+	fields, _, err := readFields(r)
+	if err != nil {
+		httputil.JSONError(w, i18n.T(r, "Invalid request body"), 400)
+		return
+	}
+	if !relockResource(res) {
+		httputil.JSONError(w, i18n.T(r, "Resource not found"), 404)
+		return
+	}
+	// Validate the fields the resource ends up with, so an update cannot
+	// blank a required one.
+	merged := make(map[string]string, len(res.Fields)+len(fields))
+	for k, v := range res.Fields {
+		merged[k] = v
+	}
+	for k, v := range fields {
+		merged[k] = v
+	}
+	if err := t.Validate(merged, false); err != nil {
+		store.Mu.Unlock()
+		httputil.JSONError(w, err.Error(), 400)
+		return
+	}
+	res.Fields = merged
+	res.Updated()
+	view := copyResource(res)
+	store.Mu.Unlock()
+	store.Save()
+	httputil.JSONResponse(w, view, 200)
+}
+
+// resourcesDelete removes a resource and its tuples. Only owners may delete:
+// editors such as a co-driver use the resource but do not own it.
+func resourcesDelete(w http.ResponseWriter, r *http.Request, t *resources.Type, id string) {
+	res, ok := loadResource(w, r, t, id, "owner")
+	if !ok {
+		return
+	}
+	store.Mu.RLock()
+	holders := lienHolders(t, res)
+	owners := append(res.Owners[:0:0], res.Owners...)
+	rels := append(res.Relations[:0:0], res.Relations...)
+	store.Mu.RUnlock()
+	if len(holders) > 0 {
+		httputil.JSONError(w, i18n.T(r, "Resource is under a lien held by %s", strings.Join(holders, ", ")), 409)
+		return
+	}
+	var deletes []store.TupleKey
+	for _, owner := range owners {
+		deletes = append(deletes, store.TupleKey{User: fga.UserRef(owner), Relation: "owner", Object: res.Object()})
+	}
+	for _, rel := range rels {
+		deletes = append(deletes, store.TupleKey{User: fga.UserRef(rel.User), Relation: rel.Relation, Object: res.Object()})
+	}
+	if res.OrgId != "" {
+		deletes = append(deletes, store.TupleKey{User: fga.ObjectRef(fga.TypeOrganization, res.OrgId), Relation: "org_parent", Object: res.Object()})
+	}
+	if err := fga.Write(r.Context(), nil, deletes); err != nil {
+		fgaFailed(w, r, err)
+		return
+	}
+	if !relockResource(res) {
+		httputil.JSONError(w, i18n.T(r, "Resource not found"), 404)
+		return
+	}
+	// Relations granted while the tuples were being deleted go too.
+	var late []store.TupleKey
+	for _, rel := range res.Relations {
+		if !containsRelation(rels, rel) {
+			late = append(late, store.TupleKey{User: fga.UserRef(rel.User), Relation: rel.Relation, Object: res.Object()})
+		}
+	}
+	delete(store.Data.Resources, res.Object())
+	store.Mu.Unlock()
+	if len(late) > 0 {
+		fga.Write(r.Context(), nil, late)
+	}
+	store.Save()
+	httputil.JSONResponse(w, map[string]bool{"success": true}, 200)
+}
+
+func resourcesRelationsGet(w http.ResponseWriter, r *http.Request, t *resources.Type, id string) {
+	res, ok := loadResource(w, r, t, id, "viewer")
+	if !ok {
+		return
+	}
+	store.Mu.RLock()
+	owners := append([]string{}, res.Owners...)
+	rels := append([]store.Relation{}, res.Relations...)
+	store.Mu.RUnlock()
+	httputil.JSONResponse(w, map[string]interface{}{
+		"owners": owners, "relations": rels, "assignable": t.Assignable(),
+	}, 200)
+}
+
+// resourcesRelationsChange grants (POST) or revokes (DELETE) an assignable
+// relation. Owners manage relations, except that a lien is released only by
+// its holder, who may be a mere viewer. Editors cannot, or a co-driver could
+// grant themselves more access or revoke the owner's other grants.
+func resourcesRelationsChange(w http.ResponseWriter, r *http.Request, t *resources.Type, id string) {
+	res, ok := loadResource(w, r, t, id, "viewer")
+	if !ok {
+		return
+	}
+	body, err := httputil.ReadBody(r)
+	if err != nil {
+		httputil.JSONError(w, i18n.T(r, "Invalid request body"), 400)
+		return
+	}
+	targetUser, ok := userField(w, r, body, "targetUser")
+	if !ok {
+		return
+	}
+	relation := httputil.GetString(body, "relation")
+	if targetUser == "" {
+		httputil.JSONError(w, i18n.T(r, "targetUser is required"), 400)
+		return
+	}
+	if !httputil.Contains(t.Assignable(), relation) {
+		httputil.JSONError(w, i18n.T(r, "Relation must be one of: %s", strings.Join(t.Assignable(), ", ")), 400)
+		return
+	}
+	if user := httputil.GetUser(r); !isManagerAdmin(r) {
+		switch {
+		case relation == t.Lien && r.Method == "DELETE":
+			if targetUser != user {
+				httputil.JSONError(w, i18n.T(r, "Only the lien holder can release a lien"), 403)
+				return
+			}
+		case relation == t.Lien:
+			store.Mu.RLock()
+			owner := httputil.Contains(res.Owners, user)
+			store.Mu.RUnlock()
+			if !owner {
+				httputil.JSONError(w, i18n.T(r, "Only owners can grant a lien"), 403)
+				return
+			}
+		case checkDenied(w, r, "owner", res.Object(), "Only owners can manage relations on this resource"):
+			return
+		}
+	}
+
+	tuple := []store.TupleKey{{User: fga.UserRef(targetUser), Relation: relation, Object: res.Object()}}
+	grant := store.Relation{User: targetUser, Relation: relation}
+	store.Mu.RLock()
+	exists := containsRelation(res.Relations, grant)
+	store.Mu.RUnlock()
+
+	writes, deletes := tuple, []store.TupleKey(nil)
+	if r.Method == "POST" && exists {
+		httputil.JSONError(w, i18n.T(r, "Relation already exists"), 400)
+		return
+	}
+	if r.Method == "DELETE" {
+		if !exists {
+			httputil.JSONError(w, i18n.T(r, "Relation not found"), 404)
+			return
+		}
+		writes, deletes = deletes, writes
+	}
+	if err := fga.Write(r.Context(), writes, deletes); err != nil {
+		fgaFailed(w, r, err)
+		return
+	}
+	if !relockResource(res) {
+		fga.Write(r.Context(), deletes, writes)
+		httputil.JSONError(w, i18n.T(r, "Resource not found"), 404)
+		return
+	}
+	// Filter the relations as they are now, not as they were before the
+	// write: grants made meanwhile must stay.
+	var kept []store.Relation
+	for _, rel := range res.Relations {
+		if rel.User != targetUser || rel.Relation != relation {
+			kept = append(kept, rel)
+		}
+	}
+	if r.Method == "POST" {
+		kept = append(kept, grant)
+	}
+	res.Relations = kept
+	res.Updated()
+	store.Mu.Unlock()
+	store.Save()
+	httputil.JSONResponse(w, map[string]bool{"success": true}, 200)
+}
+
+// containsRelation reports whether rels grants rel's relation to its user.
+func containsRelation(rels []store.Relation, rel store.Relation) bool {
+	for _, r := range rels {
+		if r.User == rel.User && r.Relation == rel.Relation {
+			return true
+		}
+	}
+	return false
+}
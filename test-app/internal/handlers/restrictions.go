@@ -0,0 +1,143 @@
+package handlers
+
+import (
+	"context"
+	"fmt"
+	"net"
+	"net/http"
+	"strings"
+	"time"
+
+	"test-app/internal/audit"
+	"test-app/internal/fga"
+	"test-app/internal/httputil"
+	"test-app/internal/i18n"
+	"test-app/internal/store"
+)
+
+// accessContext carries the request attributes evaluated after the FGA check:
+// step-up for secret dossiers, and client IP and time for restricted mandates.
+type accessContext struct {
+	StepUp   bool
+	ClientIP string
+	Now      time.Time
+	// Ctx is the request's context, which stops per-item checks once the client
+	// is gone and carries its FGA call budget
+	Ctx context.Context
+}
+
+func accessContextFrom(r *http.Request) accessContext {
+	return accessContext{StepUp: hasStepUp(r), ClientIP: clientIP(r), Now: time.Now(), Ctx: r.Context()}
+}
+
+// clientIP returns the originating client address. Envoy runs with
+// use_remote_address, so it sets x-envoy-external-address to the peer it
+// accepted the connection from and appends that peer to x-forwarded-for;
+// earlier x-forwarded-for entries come from the client and are never trusted.
+func clientIP(r *http.Request) string {
+	if ip := r.Header.Get("x-envoy-external-address"); ip != "" {
+		return ip
+	}
+	if xff := strings.Join(r.Header.Values("x-forwarded-for"), ","); xff != "" {
+		hops := strings.Split(xff, ",")
+		if ip := strings.TrimSpace(hops[len(hops)-1]); ip != "" {
+			return ip
+		}
+	}
+	host, _, err := net.SplitHostPort(r.RemoteAddr)
+	if err != nil {
+		return r.RemoteAddr
+	}
+	return host
+}
+
+// parseHours parses an "HH:MM-HH:MM" window into minutes since midnight.
+func parseHours(hours string) (int, int, error) {
+	from, to, ok := strings.Cut(hours, "-")
+	if !ok {
+		return 0, 0, fmt.Errorf("hours must look like 09:00-17:00")
+	}
+	start, err := time.Parse("15:04", strings.TrimSpace(from))
+	if err != nil {
+		return 0, 0, fmt.Errorf("hours must look like 09:00-17:00")
+	}
+	end, err := time.Parse("15:04", strings.TrimSpace(to))
+	if err != nil {
+		return 0, 0, fmt.Errorf("hours must look like 09:00-17:00")
+	}
+	return start.Hour()*60 + start.Minute(), end.Hour()*60 + end.Minute(), nil
+}
+
+// validateRestrictions rejects malformed hour windows and networks.
+func validateRestrictions(res *store.Restrictions) error {
+	if res.Hours != "" {
+		if _, _, err := parseHours(res.Hours); err != nil {
+			return err
+		}
+	}
+	for _, c := range res.CIDRs {
+		if _, _, err := net.ParseCIDR(c); err != nil {
+			return fmt.Errorf("invalid CIDR %q", c)
+		}
+	}
+	return nil
+}
+
+// failedRestriction returns a description of the first restriction ac violates,
+// or "" when the mandate may be used.
+func failedRestriction(res *store.Restrictions, ac accessContext) string {
+	if res == nil {
+		return ""
+	}
+	if res.Weekdays && (ac.Now.Weekday() == time.Saturday || ac.Now.Weekday() == time.Sunday) {
+		return "mandate is only valid on weekdays"
+	}
+	if res.Hours != "" {
+		start, end, err := parseHours(res.Hours)
+		now := ac.Now.Hour()*60 + ac.Now.Minute()
+		if err != nil || now < start || now >= end {
+			return fmt.Sprintf("mandate is only valid between %s", res.Hours)
+		}
+	}
+	if len(res.CIDRs) > 0 {
+		ip := net.ParseIP(ac.ClientIP)
+		for _, c := range res.CIDRs {
+			if _, network, err := net.ParseCIDR(c); err == nil && ip != nil && network.Contains(ip) {
+				return ""
+			}
+		}
+		return fmt.Sprintf("mandate is not valid from %s", ac.ClientIP)
+	}
+	return ""
+}
+
+// mandateRestriction checks the restrictions of user's mandate on d. Owners are
+// never restricted; a restricted mandate limits the holder's access to d even
+// when another path would also grant it.
+func mandateRestriction(d *store.Dossier, user string, ac accessContext) string {
+	if d.IsOwner(user) {
+		return ""
+	}
+	for _, rel := range d.Relations {
+		if rel.User == user && store.IsMandate(rel.Relation) {
+			if reason := failedRestriction(rel.Restrictions, ac); reason != "" {
+				return reason
+			}
+		}
+	}
+	return ""
+}
+
+// restrictionDenied answers 403, and audits the denial, when user's mandate
+// on dossier id is restricted under the request's context. Every handler that
+// reads or changes a dossier on behalf of a mandate holder calls it after the
+// FGA check; callers hold store.Mu for reading d.
+func restrictionDenied(w http.ResponseWriter, r *http.Request, d *store.Dossier, user, id string) bool {
+	reason := mandateRestriction(d, user, accessContextFrom(r))
+	if reason == "" {
+		return false
+	}
+	audit.SendAuditLog("test-app", "deny", user, "mandate_holder", fga.ObjectRef(fga.TypeDossier, id), r.Method, "Mandate restriction: "+reason)
+	httputil.JSONError(w, i18n.T(r, "Mandate restriction not met: %s", reason), 403)
+	return true
+}
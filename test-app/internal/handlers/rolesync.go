@@ -0,0 +1,61 @@
+package handlers
+
+import (
+	"net/http"
+
+	"test-app/internal/config"
+	"test-app/internal/httputil"
+	"test-app/internal/i18n"
+	"test-app/internal/rolesync"
+	"test-app/internal/store"
+)
+
+// AdminRoleSync handles GET /api/admin/role-sync: the configured role
+// mappings, the memberships currently held because of a role and the last
+// sync (for admin use).
+func AdminRoleSync(w http.ResponseWriter, r *http.Request) {
+	if !isManagerAdmin(r) {
+		httputil.JSONError(w, i18n.T(r, "Admin access required"), 403)
+		return
+	}
+	mappings := rolesync.Mappings()
+	if mappings == nil {
+		mappings = []rolesync.Mapping{}
+	}
+	grants := map[string][]string{}
+	store.Mu.RLock()
+	for user, keys := range store.Data.RoleGrants {
+		grants[user] = append([]string{}, keys...)
+	}
+	store.Mu.RUnlock()
+	httputil.JSONResponse(w, map[string]interface{}{
+		"mappings":  mappings,
+		"grants":    grants,
+		"scheduled": config.KeycloakURL != "" && config.RoleSyncInterval > 0,
+		"interval":  config.RoleSyncInterval.String(),
+		"lastSync":  rolesync.LastReport(),
+	}, 200)
+}
+
+// AdminRoleSyncRun handles POST /api/admin/role-sync: reads the role holders
+// from the Keycloak admin API and applies the mappings now (for admin use).
+func AdminRoleSyncRun(w http.ResponseWriter, r *http.Request) {
+	if !isManagerAdmin(r) {
+		httputil.JSONError(w, i18n.T(r, "Admin access required"), 403)
+		return
+	}
+	if !config.FgaReady {
+		httputil.JSONError(w, i18n.T(r, "OpenFGA not ready"), 503)
+		return
+	}
+	if len(rolesync.Mappings()) == 0 {
+		httputil.JSONError(w, i18n.T(r, "No role mappings configured (ROLE_TUPLES)"), 400)
+		return
+	}
+	rep := rolesync.Sync(r.Context())
+	status := 200
+	if rep.Error != "" {
+		status = 502
+	}
+	httputil.JSONResponse(w, rep, status)
+}
@@ -0,0 +1,62 @@
+package handlers
+
+import (
+	"net/http"
+
+	"test-app/internal/audit"
+	"test-app/internal/config"
+	"test-app/internal/httputil"
+	"test-app/internal/i18n"
+	"test-app/internal/sandbox"
+)
+
+// SandboxCreate copies the live data and tuples into a new sandbox (for admin
+// use). Requests sent with the returned id in x-sandbox-id then run against it.
+func SandboxCreate(w http.ResponseWriter, r *http.Request) {
+	if !isManagerAdmin(r) {
+		httputil.JSONError(w, i18n.T(r, "Admin access required"), 403)
+		return
+	}
+	if !config.FgaReady {
+		httputil.JSONError(w, i18n.T(r, "OpenFGA not ready"), 503)
+		return
+	}
+	sb, err := sandbox.Create(httputil.GetUser(r), config.SandboxTTL)
+	if err == sandbox.ErrTooMany {
+		httputil.JSONError(w, i18n.T(r, "At most %d sandboxes can exist at once", sandbox.MaxSandboxes), 429)
+		return
+	}
+	if err != nil {
+		fgaFailed(w, r, err)
+		return
+	}
+	audit.SendAuditLog("test-app", "sandbox_create", "user:"+sb.CreatedBy, "", "sandbox:"+sb.Id, "POST",
+		"Sandbox created on OpenFGA store "+sb.StoreId+", expires "+sb.ExpiresAt.Format("2006-01-02T15:04:05Z"))
+	httputil.JSONResponse(w, map[string]interface{}{"sandbox": sb, "header": sandbox.Header}, 200)
+}
+
+// SandboxList returns the live sandboxes (for admin use).
+func SandboxList(w http.ResponseWriter, r *http.Request) {
+	if !isManagerAdmin(r) {
+		httputil.JSONError(w, i18n.T(r, "Admin access required"), 403)
+		return
+	}
+	listResponse(w, r, "sandboxes", sandbox.List(), listMeta{Extra: map[string]interface{}{"ttl": config.SandboxTTL.String()}})
+}
+
+// SandboxDelete discards a sandbox before its TTL (for admin use).
+func SandboxDelete(w http.ResponseWriter, r *http.Request, id string) {
+	if !isManagerAdmin(r) {
+		httputil.JSONError(w, i18n.T(r, "Admin access required"), 403)
+		return
+	}
+	if _, ok := sandbox.Get(id); !ok {
+		httputil.JSONError(w, i18n.T(r, "Sandbox not found or expired"), 404)
+		return
+	}
+	if err := sandbox.Discard(id); err != nil {
+		httputil.JSONError(w, err.Error(), 500)
+		return
+	}
+	httputil.JSONResponse(w, map[string]bool{"success": true}, 200)
+}
@@ -0,0 +1,42 @@
+package handlers
+
+import (
+	"net/http"
+	"strings"
+
+	"test-app/internal/config"
+	"test-app/internal/store"
+)
+
+// Sensitivity levels layer an attribute check on top of the FGA relation check.
+// normal and sensitive dossiers only need the relation; secret dossiers also
+// require a recent strong authentication, signalled by OPA through the acr claim.
+var validSensitivities = []string{"normal", "sensitive", "secret"}
+
+// sensitivityOf returns the dossier's level, treating unset as normal.
+func sensitivityOf(d *store.Dossier) string {
+	if d.Sensitivity == "" {
+		return "normal"
+	}
+	return d.Sensitivity
+}
+
+// hasStepUp reports whether the request carries a strong-auth acr forwarded by OPA.
+// Envoy strips x-auth-acr from client requests, so only OPA can set it.
+func hasStepUp(r *http.Request) bool {
+	acr := r.Header.Get("x-auth-acr")
+	if acr == "" {
+		return false
+	}
+	for _, v := range strings.Split(config.StepUpAcr, ",") {
+		if strings.TrimSpace(v) == acr {
+			return true
+		}
+	}
+	return false
+}
+
+// stepUpRequired reports whether accessing d needs a step-up the request lacks.
+func stepUpRequired(r *http.Request, d *store.Dossier) bool {
+	return sensitivityOf(d) == "secret" && !hasStepUp(r)
+}
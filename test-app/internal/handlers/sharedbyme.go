@@ -0,0 +1,179 @@
+package handlers
+
+import (
+	"context"
+	"net/http"
+	"sort"
+
+	"test-app/internal/config"
+	"test-app/internal/fga"
+	"test-app/internal/httputil"
+	"test-app/internal/i18n"
+	"test-app/internal/store"
+)
+
+// sharedGrant is one way an object the caller owns is exposed to someone else.
+type sharedGrant struct {
+	Kind      string `json:"kind"` // "co-owner", "mandate", "relation", "organization" or "public"
+	Grantee   string `json:"grantee"`
+	Relation  string `json:"relation"`
+	GrantedBy string `json:"grantedBy,omitempty"`
+	InFga     bool   `json:"inFga"` // false: recorded in the store but missing in OpenFGA
+
+	tuple store.TupleKey
+}
+
+// sharedObject is an object the caller owns with its outgoing grants.
+type sharedObject struct {
+	Object string        `json:"object"`
+	Type   string        `json:"type"`
+	Id     string        `json:"id"`
+	Title  string        `json:"title,omitempty"`
+	Grants []sharedGrant `json:"grants"`
+	// Untracked are tuples OpenFGA holds on the object that the store does not imply.
+	Untracked []store.TupleKey `json:"untracked,omitempty"`
+	FgaError  string           `json:"fgaError,omitempty"`
+
+	// known are the tuples the store implies that are not grants (the
+	// caller's own ownership, blocked users).
+	known map[store.TupleKey]bool
+}
+
+func (o *sharedObject) grant(kind, grantee, relation, grantedBy, user string) {
+	o.Grants = append(o.Grants, sharedGrant{Kind: kind, Grantee: grantee, Relation: relation, GrantedBy: grantedBy,
+		tuple: store.TupleKey{User: user, Relation: relation, Object: o.Object}})
+}
+
+// shareRelations adds the grants of owners other than user and of relations to o.
+func (o *sharedObject) shareRelations(user string, owners []string, relations []store.Relation, orgId string) {
+	o.known = map[store.TupleKey]bool{{User: fga.UserRef(user), Relation: "owner", Object: o.Object}: true}
+	for _, owner := range owners {
+		if owner != user {
+			o.grant("co-owner", owner, "owner", "", fga.UserRef(owner))
+		}
+	}
+	for _, rel := range relations {
+		kind := "relation"
+		if store.IsMandate(rel.Relation) {
+			kind = "mandate"
+		}
+		o.grant(kind, rel.User, rel.Relation, rel.GrantedBy, fga.UserRef(rel.User))
+	}
+	if orgId != "" {
+		o.grant("organization", orgId, "org_parent", "", "organization:"+orgId)
+	}
+}
+
+// sharedObjects returns the dossiers and resources user owns that the store
+// shows as shared beyond user, by object.
+func sharedObjects(user string) []*sharedObject {
+	var objects []*sharedObject
+	store.Mu.RLock()
+	for id, d := range store.Data.Dossiers {
+		if !httputil.Contains(d.Owners, user) {
+			continue
+		}
+		o := &sharedObject{Object: fga.ObjectRef(fga.TypeDossier, id), Type: fga.TypeDossier, Id: id, Title: d.Title}
+		o.shareRelations(user, d.Owners, d.Relations, d.OrgId)
+		if d.Public {
+			o.grant("public", "*", "public", "", fga.PublicUser)
+		}
+		for _, blocked := range d.BlockedUsers {
+			o.known[store.TupleKey{User: fga.UserRef(blocked), Relation: "blocked", Object: o.Object}] = true
+		}
+		if len(o.Grants) > 0 {
+			objects = append(objects, o)
+		}
+	}
+	for _, res := range store.Data.Resources {
+		if !httputil.Contains(res.Owners, user) {
+			continue
+		}
+		o := &sharedObject{Object: res.Object(), Type: res.Type, Id: res.Id, Title: res.Fields["title"]}
+		o.shareRelations(user, res.Owners, res.Relations, res.OrgId)
+		if len(o.Grants) > 0 {
+			objects = append(objects, o)
+		}
+	}
+	store.Mu.RUnlock()
+	sort.Slice(objects, func(i, j int) bool { return objects[i].Object < objects[j].Object })
+	return objects
+}
+
+// crossCheck reads the tuples OpenFGA holds on o, marks the grants found
+// there and lists the tuples the store does not account for.
+func (o *sharedObject) crossCheck(ctx context.Context) {
+	actual := map[store.TupleKey]bool{}
+	token := ""
+	for {
+		page, next, err := fga.Read(ctx, store.TupleKey{Object: o.Object}, 100, token)
+		if err != nil {
+			o.FgaError = err.Error()
+			return
+		}
+		for _, t := range page {
+			actual[t] = true
+		}
+		if next == "" {
+			break
+		}
+		token = next
+	}
+	granted := map[store.TupleKey]bool{}
+	for i := range o.Grants {
+		o.Grants[i].InFga = actual[o.Grants[i].tuple]
+		granted[o.Grants[i].tuple] = true
+	}
+	for t := range actual {
+		if !granted[t] && !o.known[t] {
+			o.Untracked = append(o.Untracked, t)
+		}
+	}
+	sort.Slice(o.Untracked, func(i, j int) bool {
+		a, b := o.Untracked[i], o.Untracked[j]
+		if a.User != b.User {
+			return a.User < b.User
+		}
+		return a.Relation < b.Relation
+	})
+}
+
+// DossiersSharedByMe handles GET /api/dossiers/shared-by-me: the dossiers and
+// resources the caller owns that are exposed to anyone else (co-owners,
+// mandates and other relations, an organization, the public flag), with their
+// grantees. Each grant from the store is checked against the tuples OpenFGA
+// holds, so inFga false or untracked tuples show the two disagree.
+func DossiersSharedByMe(w http.ResponseWriter, r *http.Request) {
+	if !config.FgaReady {
+		httputil.JSONError(w, i18n.T(r, "OpenFGA not ready"), 503)
+		return
+	}
+	user := httputil.GetUser(r)
+	objects := sharedObjects(user)
+	parallel(r.Context(), len(objects), func(i int) { objects[i].crossCheck(r.Context()) })
+	mismatched := 0
+	for _, o := range objects {
+		if o.FgaError != "" || len(o.Untracked) > 0 {
+			mismatched++
+			continue
+		}
+		for _, g := range o.Grants {
+			if !g.InFga {
+				mismatched++
+				break
+			}
+		}
+	}
+	if objects == nil {
+		objects = []*sharedObject{}
+	}
+	users := []string{}
+	for _, o := range objects {
+		for _, g := range o.Grants {
+			if g.Kind != "organization" && g.Kind != "public" {
+				users = append(users, g.Grantee, g.GrantedBy)
+			}
+		}
+	}
+	listResponse(w, r, "shared", objects, listMeta{Extra: map[string]interface{}{"mismatched": mismatched}, Users: users})
+}
@@ -0,0 +1,169 @@
+package handlers
+
+import (
+	"net/http"
+	"net/url"
+	"sort"
+	"strconv"
+	"time"
+
+	"test-app/internal/audit"
+	"test-app/internal/config"
+	"test-app/internal/fga"
+	"test-app/internal/httputil"
+	"test-app/internal/i18n"
+	"test-app/internal/store"
+)
+
+// staleGrant is a mandate its holder has not exercised within the window.
+type staleGrant struct {
+	DossierId string     `json:"dossierId"`
+	Title     string     `json:"title"`
+	User      string     `json:"user"`
+	Relation  string     `json:"relation"`
+	GrantedBy string     `json:"grantedBy,omitempty"`
+	LastUsed  *time.Time `json:"lastUsed"` // nil: never allowed a check since usage tracking started
+	Links     []string   `json:"links"`
+}
+
+// staleGrants returns the mandates whose holder has had no allowed check on
+// the dossier for age, judged from the decision log.
+func staleGrants(age time.Duration) []staleGrant {
+	cutoff := time.Now().Add(-age)
+	grants := []staleGrant{}
+	store.Mu.RLock()
+	for id, d := range store.Data.Dossiers {
+		for _, rel := range d.Relations {
+			if !store.IsMandate(rel.Relation) {
+				continue
+			}
+			g := staleGrant{DossierId: id, Title: d.Title, User: rel.User, Relation: rel.Relation, GrantedBy: rel.GrantedBy,
+				Links: []string{"/api/dossiers/" + url.PathEscape(id) + "/relations"}}
+			if last, ok := fga.LastAllowed(fga.UserRef(rel.User), fga.ObjectRef(fga.TypeDossier, id)); ok {
+				if last.After(cutoff) {
+					continue
+				}
+				g.LastUsed = &last
+			}
+			grants = append(grants, g)
+		}
+	}
+	store.Mu.RUnlock()
+	sort.Slice(grants, func(i, j int) bool {
+		if grants[i].DossierId != grants[j].DossierId {
+			return grants[i].DossierId < grants[j].DossierId
+		}
+		return grants[i].User < grants[j].User
+	})
+	return grants
+}
+
+// staleGrantAge returns ?days=N as a duration, or config.StaleGrantAge.
+func staleGrantAge(w http.ResponseWriter, r *http.Request) (time.Duration, bool) {
+	v := r.URL.Query().Get("days")
+	if v == "" {
+		return config.StaleGrantAge.Get(), true
+	}
+	days, err := strconv.Atoi(v)
+	if err != nil || days < 0 {
+		httputil.JSONError(w, i18n.T(r, "days must be a non-negative integer"), 400)
+		return 0, false
+	}
+	return time.Duration(days) * 24 * time.Hour, true
+}
+
+// AdminStaleGrants handles GET /api/admin/stale-grants[?days=N]: mandates not
+// exercised (no allowed check by their holder) for N days. Usage is observed
+// from process start, so until complete is true a mandate may be listed only
+// because the app has not been running for long enough.
+func AdminStaleGrants(w http.ResponseWriter, r *http.Request) {
+	if !isManagerAdmin(r) {
+		httputil.JSONError(w, i18n.T(r, "Admin access required"), 403)
+		return
+	}
+	age, ok := staleGrantAge(w, r)
+	if !ok {
+		return
+	}
+	since := fga.UsageSince()
+	httputil.JSONResponse(w, map[string]interface{}{
+		"grants":        staleGrants(age),
+		"age":           age.String(),
+		"observedSince": since.UTC(),
+		"complete":      time.Since(since) >= age,
+		"revoke":        "/api/admin/stale-grants/revoke?days=" + strconv.Itoa(int(age.Hours()/24)),
+	}, 200)
+}
+
+// AdminStaleGrantsRevoke handles POST /api/admin/stale-grants/revoke[?days=N]:
+// it revokes every mandate that is stale now, or only those listed in
+// {"grants": [{"dossierId", "user", "relation"}]} that still are, in one
+// OpenFGA write. Accepts ?dryRun=true.
+func AdminStaleGrantsRevoke(w http.ResponseWriter, r *http.Request) {
+	if !isManagerAdmin(r) {
+		httputil.JSONError(w, i18n.T(r, "Admin access required"), 403)
+		return
+	}
+	if !config.FgaReady {
+		httputil.JSONError(w, i18n.T(r, "OpenFGA not ready"), 503)
+		return
+	}
+	age, ok := staleGrantAge(w, r)
+	if !ok {
+		return
+	}
+	var selected map[[3]string]bool
+	if body, err := httputil.ReadBody(r); err == nil {
+		if list, ok := body["grants"].([]interface{}); ok {
+			selected = map[[3]string]bool{}
+			for _, item := range list {
+				g, _ := item.(map[string]interface{})
+				selected[[3]string{httputil.GetString(g, "dossierId"), httputil.GetString(g, "user"), httputil.GetString(g, "relation")}] = true
+			}
+		}
+	}
+	// Staleness is re-evaluated here, so a grant used since it was listed is kept.
+	var revoke []staleGrant
+	var deletes []store.TupleKey
+	for _, g := range staleGrants(age) {
+		if selected != nil && !selected[[3]string{g.DossierId, g.User, g.Relation}] {
+			continue
+		}
+		revoke = append(revoke, g)
+		deletes = append(deletes, store.TupleKey{User: fga.UserRef(g.User), Relation: g.Relation, Object: fga.ObjectRef(fga.TypeDossier, g.DossierId)})
+	}
+	if isDryRun(r) {
+		writeDryRun(w, nil, deletes)
+		return
+	}
+	if len(deletes) == 0 {
+		httputil.JSONResponse(w, map[string]interface{}{"success": true, "revoked": []staleGrant{}}, 200)
+		return
+	}
+	if err := fga.Write(r.Context(), nil, deletes); err != nil {
+		fgaFailed(w, r, err)
+		return
+	}
+	store.Mu.Lock()
+	for _, g := range revoke {
+		d := store.Data.Dossiers[g.DossierId]
+		if d == nil {
+			continue
+		}
+		var kept []store.Relation
+		for _, rel := range d.Relations {
+			if !(rel.User == g.User && rel.Relation == g.Relation) {
+				kept = append(kept, rel)
+			}
+		}
+		d.Relations = kept
+		d.Updated()
+	}
+	store.Mu.Unlock()
+	store.Save()
+	admin := httputil.GetUser(r)
+	for _, t := range deletes {
+		audit.SendAuditLog("test-app", "revoke", t.User, t.Relation, t.Object, "POST", "Stale grant revoked by "+admin)
+	}
+	httputil.JSONResponse(w, map[string]interface{}{"success": true, "revoked": revoke}, 200)
+}
@@ -0,0 +1,122 @@
+package handlers
+
+import (
+	"net/http"
+	"net/url"
+
+	"test-app/internal/audit"
+	"test-app/internal/config"
+	"test-app/internal/fga"
+	"test-app/internal/httputil"
+	"test-app/internal/i18n"
+	"test-app/internal/store"
+	"test-app/internal/tour"
+)
+
+// tourScenario is a scenario with the link that sets it up.
+type tourScenario struct {
+	tour.Scenario
+	Setup string `json:"setup"`
+}
+
+func withSetup(s tour.Scenario) tourScenario {
+	return tourScenario{Scenario: s, Setup: "/api/tour/" + url.PathEscape(s.Id) + "/setup"}
+}
+
+// Tour handles GET /api/tour: the demo scenarios in presentation order, with
+// their preconditions and steps.
+func Tour(w http.ResponseWriter, r *http.Request) {
+	scenarios := make([]tourScenario, 0, len(tour.Scenarios))
+	for _, s := range tour.Scenarios {
+		scenarios = append(scenarios, withSetup(s))
+	}
+	listResponse(w, r, "scenarios", scenarios, listMeta{})
+}
+
+// TourSetup handles POST /api/tour/{id}/setup: it puts the scenario's
+// fixture in place, replacing the tour-* entities and their tuples from an
+// earlier setup, so a presenter can jump straight to its steps. Guardianships
+// are only ever added, and only between tour-* users. Manager admins only.
+func TourSetup(w http.ResponseWriter, r *http.Request, id string) {
+	if !isManagerAdmin(r) {
+		httputil.JSONError(w, i18n.T(r, "Admin access required"), 403)
+		return
+	}
+	if !config.FgaReady {
+		httputil.JSONError(w, i18n.T(r, "OpenFGA not ready"), 503)
+		return
+	}
+	s, ok := tour.Lookup(id)
+	if !ok {
+		httputil.JSONError(w, i18n.T(r, "Scenario not found"), 404)
+		return
+	}
+	f := s.Fixture()
+
+	var writes, deletes []store.TupleKey
+	store.Mu.RLock()
+	for id, d := range f.Dossiers {
+		if old := store.Data.Dossiers[id]; old != nil {
+			deletes = append(deletes, dossierTuples(id, old)...)
+		}
+		writes = append(writes, dossierTuples(id, d)...)
+	}
+	for id, org := range f.Organizations {
+		if old := store.Data.Organizations[id]; old != nil {
+			deletes = append(deletes, organizationTuples(id, old)...)
+		}
+		writes = append(writes, organizationTuples(id, org)...)
+	}
+	for ward, guardians := range f.Guardianships {
+		for _, g := range guardians {
+			if !httputil.Contains(store.Data.Guardianships[ward], g) {
+				writes = append(writes, store.TupleKey{User: fga.UserRef(g), Relation: "guardian", Object: fga.UserRef(ward)})
+			}
+		}
+	}
+	store.Mu.RUnlock()
+
+	// Deletes go first: the fixture usually rewrites the very tuples it removes.
+	if err := writeInBatches(r.Context(), nil, deletes); err != nil {
+		fgaFailed(w, r, err)
+		return
+	}
+	if err := writeInBatches(r.Context(), writes, nil); err != nil {
+		fgaFailed(w, r, err)
+		return
+	}
+	store.Mu.Lock()
+	for id, d := range f.Dossiers {
+		store.Data.Dossiers[id] = d
+	}
+	for id, org := range f.Organizations {
+		store.Data.Organizations[id] = org
+	}
+	for ward, guardians := range f.Guardianships {
+		for _, g := range guardians {
+			if !httputil.Contains(store.Data.Guardianships[ward], g) {
+				store.Data.Guardianships[ward] = append(store.Data.Guardianships[ward], g)
+			}
+		}
+	}
+	store.Mu.Unlock()
+	store.Save()
+
+	audit.SendAuditLog("test-app", "tour_setup", fga.UserRef(httputil.GetUser(r)), "", "tour:"+s.Id, "POST", "Tour scenario set up: "+s.Title)
+	httputil.JSONResponse(w, map[string]interface{}{
+		"success": true, "scenario": withSetup(s), "tuplesWritten": len(writes), "tuplesDeleted": len(deletes),
+	}, 200)
+}
+
+// organizationTuples lists the member and admin tuples the store holds for an
+// organization.
+func organizationTuples(id string, org *store.Organization) []store.TupleKey {
+	var tuples []store.TupleKey
+	for _, m := range org.Members {
+		tuples = append(tuples, store.TupleKey{User: fga.UserRef(m), Relation: "member", Object: fga.ObjectRef(fga.TypeOrganization, id)})
+	}
+	for _, a := range org.Admins {
+		tuples = append(tuples, store.TupleKey{User: fga.UserRef(a), Relation: "admin", Object: fga.ObjectRef(fga.TypeOrganization, id)})
+	}
+	return tuples
+}
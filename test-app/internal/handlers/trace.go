@@ -0,0 +1,26 @@
+package handlers
+
+import (
+	"net/http"
+
+	"test-app/internal/audit"
+	"test-app/internal/httputil"
+	"test-app/internal/i18n"
+)
+
+// AuditTrace returns the complete decision chain of one request: the gateway
+// (OPA) decision, the FGA checks and audit entries it caused and the handler
+// outcome. Callers see their own requests; the manager admin sees any.
+func AuditTrace(w http.ResponseWriter, r *http.Request, requestId string) {
+	trace, ok := audit.TraceOf(requestId)
+	if !ok {
+		httputil.JSONError(w, i18n.T(r, "No trace for this request ID"), 404)
+		return
+	}
+	if !isManagerAdmin(r) && trace.User() != httputil.GetUser(r) {
+		// Do not reveal that someone else's request exists.
+		httputil.JSONError(w, i18n.T(r, "No trace for this request ID"), 404)
+		return
+	}
+	httputil.JSONResponse(w, trace, 200)
+}
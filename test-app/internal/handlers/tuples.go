@@ -0,0 +1,121 @@
+package handlers
+
+import (
+	"io"
+	"net/http"
+	"strings"
+
+	"test-app/internal/audit"
+	"test-app/internal/config"
+	"test-app/internal/fga"
+	"test-app/internal/httputil"
+	"test-app/internal/i18n"
+	"test-app/internal/store"
+)
+
+// tupleFileFormat picks json or yaml from ?format= or the Content-Type header.
+func tupleFileFormat(r *http.Request) string {
+	if f := r.URL.Query().Get("format"); f != "" {
+		return f
+	}
+	if strings.Contains(r.Header.Get("Content-Type"), "yaml") {
+		return "yaml"
+	}
+	return "json"
+}
+
+// TuplesExport dumps every tuple in OpenFGA in the fga CLI tuple file format (for admin use).
+func TuplesExport(w http.ResponseWriter, r *http.Request) {
+	if !isManagerAdmin(r) {
+		httputil.JSONError(w, i18n.T(r, "Admin access required"), 403)
+		return
+	}
+	if !config.FgaReady {
+		httputil.JSONError(w, i18n.T(r, "OpenFGA not ready"), 503)
+		return
+	}
+	tuples, err := fga.ReadAll(r.Context())
+	if err != nil {
+		httputil.JSONError(w, i18n.T(r, "Failed to read tuples: %s", err.Error()), 502)
+		return
+	}
+	format := tupleFileFormat(r)
+	data, err := fga.EncodeTuples(tuples, format)
+	if err != nil {
+		httputil.JSONError(w, err.Error(), 400)
+		return
+	}
+	if format == "yaml" {
+		w.Header().Set("Content-Type", "application/yaml")
+	} else {
+		w.Header().Set("Content-Type", "application/json")
+	}
+	w.Header().Set("Content-Disposition", "attachment; filename=tuples."+format)
+	w.Write(data)
+}
+
+// TuplesImport writes the tuples of an fga CLI tuple file to OpenFGA (for admin use).
+// Imported tuples are not backed by persisted data, so REHYDRATE=verify reports them as extra.
+// A file with wildcard tuples is refused unless each is permitted (see
+// wildcardPermitted) and the request carries ?confirm=public.
+func TuplesImport(w http.ResponseWriter, r *http.Request) {
+	if !isManagerAdmin(r) {
+		httputil.JSONError(w, i18n.T(r, "Admin access required"), 403)
+		return
+	}
+	if !config.FgaReady {
+		httputil.JSONError(w, i18n.T(r, "OpenFGA not ready"), 503)
+		return
+	}
+	data, err := io.ReadAll(r.Body)
+	if err != nil {
+		httputil.JSONError(w, i18n.T(r, "Invalid request body"), 400)
+		return
+	}
+	tuples, err := fga.DecodeTuples(data, tupleFileFormat(r))
+	if err != nil {
+		httputil.JSONError(w, i18n.T(r, "Invalid tuple file: %s", err.Error()), 400)
+		return
+	}
+	var wildcards []store.TupleKey
+	for _, t := range tuples {
+		if !isWildcard(t.User) {
+			continue
+		}
+		if err := wildcardPermitted(t); err != nil {
+			httputil.JSONError(w, i18n.T(r, "Invalid tuple file: %s", t.User+" "+t.Relation+" "+t.Object+": "+err.Error()), 400)
+			return
+		}
+		wildcards = append(wildcards, t)
+	}
+	if len(wildcards) > 0 && r.URL.Query().Get("confirm") != confirmPublic {
+		httputil.JSONResponse(w, map[string]interface{}{
+			"error":     i18n.T(r, "The file makes %d objects visible to every user; repeat the request with confirm=public", len(wildcards)),
+			"wildcards": wildcards,
+		}, 409)
+		return
+	}
+
+	admin := httputil.GetUser(r)
+	written := 0
+	failed := []string{}
+	for i := 0; i < len(tuples); i += 10 {
+		end := i + 10
+		if end > len(tuples) {
+			end = len(tuples)
+		}
+		if err := fga.Write(r.Context(), tuples[i:end], nil); err != nil {
+			failed = append(failed, err.Error())
+			continue
+		}
+		written += end - i
+		for _, t := range tuples[i:end] {
+			if isWildcard(t.User) {
+				audit.SendHighSeverity("test-app", "publish", t.User, t.Relation, t.Object, "POST", "Wildcard tuple imported by "+admin)
+			}
+		}
+	}
+	httputil.JSONResponse(w, map[string]interface{}{
+		"total": len(tuples), "written": written, "errors": failed,
+	}, 200)
+}
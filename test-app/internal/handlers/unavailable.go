@@ -0,0 +1,51 @@
+package handlers
+
+import (
+	"errors"
+	"net/http"
+	"strconv"
+	"time"
+
+	"test-app/internal/fga"
+	"test-app/internal/httputil"
+	"test-app/internal/i18n"
+)
+
+// fgaUnavailable answers 503 for a request whose OpenFGA call failed, so the
+// caller retries instead of reading a denial or an empty list. When OpenFGA
+// itself could not answer, err carries the Retry-After hint.
+func fgaUnavailable(w http.ResponseWriter, r *http.Request, err error) {
+	resp := map[string]interface{}{"error": i18n.T(r, "Authorization service unavailable, retry later")}
+	if retry, ok := fga.RetryAfter(err); ok {
+		secs := int((retry + time.Second - 1) / time.Second)
+		w.Header().Set("Retry-After", strconv.Itoa(secs))
+		resp["retryAfter"] = secs
+	}
+	httputil.JSONResponse(w, resp, 503)
+}
+
+// fgaFailed answers a failed OpenFGA call: 503 through fgaUnavailable when
+// the service could not answer, 500 with the error otherwise.
+func fgaFailed(w http.ResponseWriter, r *http.Request, err error) {
+	if errors.Is(err, fga.ErrUnavailable) {
+		fgaUnavailable(w, r, err)
+		return
+	}
+	httputil.JSONError(w, err.Error(), 500)
+}
+
+// checkDenied checks that the caller has relation on object and reports
+// whether the handler must stop: it has then answered 403 with message
+// denied, or 503 when OpenFGA could not answer, which is not a denial.
+func checkDenied(w http.ResponseWriter, r *http.Request, relation, object, denied string) bool {
+	allowed, err := fga.Allowed(r.Context(), fga.UserRef(httputil.GetUser(r)), relation, object)
+	if errors.Is(err, fga.ErrUnavailable) {
+		fgaUnavailable(w, r, err)
+		return true
+	}
+	if !allowed {
+		httputil.JSONError(w, i18n.T(r, denied), 403)
+		return true
+	}
+	return false
+}
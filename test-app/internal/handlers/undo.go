@@ -0,0 +1,197 @@
+package handlers
+
+import (
+	"crypto/rand"
+	"encoding/hex"
+	"encoding/json"
+	"log"
+	"net/http"
+	"sync"
+	"time"
+
+	"test-app/internal/audit"
+	"test-app/internal/config"
+	"test-app/internal/fga"
+	"test-app/internal/httputil"
+	"test-app/internal/i18n"
+	"test-app/internal/store"
+)
+
+// compensation is the serialized inverse of a destructive action: the tuples
+// to write back and the store state to put back.
+type compensation struct {
+	Kind      string         `json:"kind"` // dossier, guardianship or relations
+	DossierId string         `json:"dossierId,omitempty"`
+	Dossier   *store.Dossier `json:"dossier,omitempty"`
+	// Guardianships maps a ward to the guardians to give back.
+	Guardianships map[string][]string `json:"guardianships,omitempty"`
+	Relations     []store.Relation    `json:"relations,omitempty"`
+	Tuples        []store.TupleKey    `json:"tuples"`
+}
+
+type pendingUndo struct {
+	user    string
+	action  []byte // JSON compensation
+	expires time.Time
+	// onExpire finishes the action once it can no longer be undone, e.g.
+	// dropping the archived content of a deleted dossier.
+	onExpire func()
+}
+
+var (
+	undoMu      sync.Mutex
+	undoPending = map[string]*pendingUndo{}
+)
+
+// offerUndo records how to reverse an action user just performed and returns
+// the fields to add to the response: an undoToken valid for config.UndoWindow.
+// onExpire, if not nil, runs when the window closes without an undo.
+func offerUndo(user string, c compensation, onExpire func()) map[string]interface{} {
+	if config.UndoWindow.Get() <= 0 {
+		if onExpire != nil {
+			onExpire()
+		}
+		return nil
+	}
+	token, err := newToken()
+	if err != nil {
+		log.Printf("WARNING: no undo offered: %v", err)
+		if onExpire != nil {
+			onExpire()
+		}
+		return nil
+	}
+	raw, _ := json.Marshal(c)
+	undoMu.Lock()
+	undoPending[token] = &pendingUndo{user: user, action: raw, expires: time.Now().Add(config.UndoWindow.Get()), onExpire: onExpire}
+	undoMu.Unlock()
+	time.AfterFunc(config.UndoWindow.Get(), func() {
+		undoMu.Lock()
+		p, ok := undoPending[token]
+		delete(undoPending, token)
+		undoMu.Unlock()
+		if ok && p.onExpire != nil {
+			p.onExpire()
+		}
+	})
+	return map[string]interface{}{"undoToken": token, "undoExpiresIn": int(config.UndoWindow.Get().Seconds())}
+}
+
+// forgetUndo drops every pending undo, e.g. when the store is reset or
+// restored from a backup: their compensations describe data that is gone.
+// Their onExpire functions are not run, as they would act on the new data.
+func forgetUndo() {
+	undoMu.Lock()
+	undoPending = map[string]*pendingUndo{}
+	undoMu.Unlock()
+}
+
+// newToken returns a random token for the caller to present back, such as an
+// undo or confirm token.
+func newToken() (string, error) {
+	b := make([]byte, 16)
+	if _, err := rand.Read(b); err != nil {
+		return "", err
+	}
+	return hex.EncodeToString(b), nil
+}
+
+// withUndo adds the fields returned by offerUndo to a response.
+func withUndo(resp map[string]interface{}, undo map[string]interface{}) map[string]interface{} {
+	for k, v := range undo {
+		resp[k] = v
+	}
+	return resp
+}
+
+// Undo handles POST /api/undo/{token}: it reverses a dossier deletion, a
+// guardianship removal or a mandate revocation made by the caller within the
+// undo window, rewriting the deleted tuples and restoring the store. Tokens are
+// single-use.
+func Undo(w http.ResponseWriter, r *http.Request, token string) {
+	if !config.FgaReady {
+		httputil.JSONError(w, i18n.T(r, "OpenFGA not ready"), 503)
+		return
+	}
+	user := httputil.GetUser(r)
+	undoMu.Lock()
+	p, ok := undoPending[token]
+	if ok && p.user != user && !isManagerAdmin(r) {
+		ok = false
+	}
+	if ok && time.Now().After(p.expires) {
+		delete(undoPending, token)
+		ok = false
+	}
+	if ok {
+		delete(undoPending, token)
+	}
+	undoMu.Unlock()
+	if !ok {
+		httputil.JSONError(w, i18n.T(r, "Undo token is invalid or expired"), 404)
+		return
+	}
+
+	var c compensation
+	if err := json.Unmarshal(p.action, &c); err != nil {
+		httputil.JSONError(w, err.Error(), 500)
+		return
+	}
+	store.Mu.RLock()
+	exists := store.Data.Dossiers[c.DossierId] != nil
+	store.Mu.RUnlock()
+	if c.Kind == "dossier" && exists {
+		httputil.JSONError(w, i18n.T(r, "Dossier %s already exists", c.DossierId), 409)
+		return
+	}
+	if c.Kind == "relations" && !exists {
+		httputil.JSONError(w, i18n.T(r, "Dossier not found"), 404)
+		return
+	}
+	if err := fga.Write(r.Context(), c.Tuples, nil); err != nil {
+		// Put the token back so the caller can retry within the window.
+		undoMu.Lock()
+		undoPending[token] = p
+		undoMu.Unlock()
+		fgaFailed(w, r, err)
+		return
+	}
+
+	store.Mu.Lock()
+	switch c.Kind {
+	case "dossier":
+		store.Data.Dossiers[c.DossierId] = c.Dossier
+	case "guardianship":
+		for ward, guardians := range c.Guardianships {
+			for _, g := range guardians {
+				if !httputil.Contains(store.Data.Guardianships[ward], g) {
+					store.Data.Guardianships[ward] = append(store.Data.Guardianships[ward], g)
+				}
+			}
+		}
+	case "relations":
+		if d := store.Data.Dossiers[c.DossierId]; d != nil {
+			for _, rel := range c.Relations {
+				if !hasRelation(d, rel) {
+					d.Relations = append(d.Relations, rel)
+				}
+			}
+			d.Updated()
+		}
+	}
+	store.Mu.Unlock()
+	store.Save()
+	for _, t := range c.Tuples {
+		audit.SendAuditLog("test-app", "undo", t.User, t.Relation, t.Object, "POST", "Restored by "+user)
+	}
+	httputil.JSONResponse(w, map[string]interface{}{"success": true, "kind": c.Kind, "restored": len(c.Tuples)}, 200)
+}
+
+func hasRelation(d *store.Dossier, rel store.Relation) bool {
+	for _, have := range d.Relations {
+		if have.User == rel.User && have.Relation == rel.Relation {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,188 @@
+package handlers
+
+import (
+	"bytes"
+	"encoding/json"
+	"net/http"
+	"sort"
+	"strings"
+
+	"test-app/internal/config"
+	"test-app/internal/httputil"
+	"test-app/internal/i18n"
+	"test-app/internal/store"
+	"test-app/internal/users"
+	"test-app/internal/visibility"
+)
+
+// maxSavedViews bounds how many views one user can save.
+const maxSavedViews = 50
+
+// applyFilter narrows the caller's authorized dossier views to those matching
+// f and sorts them; every view is marked with whether the user pinned it. It
+// reports false when f.Sort is not a known key.
+func applyFilter(user string, views []dossierView, f store.ViewFilter) ([]dossierView, bool) {
+	favorites := favoritesOf(user)
+	query := strings.ToLower(f.Query)
+	out := make([]dossierView, 0, len(views))
+	for _, d := range views {
+		d.Favorite = favorites[d.Id]
+		switch {
+		case f.Type != "" && d.Type != f.Type,
+			f.OrgId != "" && d.OrgId != f.OrgId,
+			f.Public != nil && d.IsPublic != *f.Public,
+			f.Owned && !httputil.Contains(d.Owners, user),
+			f.SharedWith != "" && !sharedWith(d, f.SharedWith),
+			f.CreatedBy != "" && d.CreatedBy != f.CreatedBy,
+			f.Favorites && !d.Favorite,
+			query != "" && !strings.Contains(strings.ToLower(d.Title), query):
+			continue
+		}
+		out = append(out, d)
+	}
+	return out, sortDossiers(out, f.Sort)
+}
+
+func sharedWith(d dossierView, user string) bool {
+	for _, rel := range d.Relations {
+		if rel.User == user {
+			return true
+		}
+	}
+	return false
+}
+
+// ViewsList handles GET /api/views: the caller's saved views, by name.
+func ViewsList(w http.ResponseWriter, r *http.Request) {
+	user := httputil.GetUser(r)
+	views := []*store.SavedView{}
+	store.Mu.RLock()
+	for _, v := range store.Data.SavedViews {
+		if v.Owner == user {
+			views = append(views, v)
+		}
+	}
+	store.Mu.RUnlock()
+	sort.Slice(views, func(i, j int) bool { return views[i].Name < views[j].Name })
+	listResponse(w, r, "views", views, listMeta{})
+}
+
+// ViewsCreate handles POST /api/views with {"name", "filter"}. The filter is
+// validated now, so running the view later can only fail on access.
+func ViewsCreate(w http.ResponseWriter, r *http.Request) {
+	user := httputil.GetUser(r)
+	body, err := httputil.ReadBody(r)
+	if err != nil {
+		httputil.JSONError(w, i18n.T(r, "Invalid request body"), 400)
+		return
+	}
+	name := strings.TrimSpace(httputil.GetString(body, "name"))
+	if name == "" {
+		httputil.JSONError(w, i18n.T(r, "Name is required"), 400)
+		return
+	}
+	var f store.ViewFilter
+	if body["filter"] != nil {
+		raw, _ := json.Marshal(body["filter"])
+		dec := json.NewDecoder(bytes.NewReader(raw))
+		dec.DisallowUnknownFields()
+		if err := dec.Decode(&f); err != nil {
+			httputil.JSONError(w, i18n.T(r, "Invalid filter: %s", err.Error()), 400)
+			return
+		}
+	}
+	if f.Type != "" {
+		if _, ok := lookupDossierType(w, r, f.Type); !ok {
+			return
+		}
+	}
+	if f.SharedWith, err = users.Normalize(f.SharedWith); err != nil {
+		httputil.JSONError(w, i18n.T(r, "Invalid username"), 400)
+		return
+	}
+	if _, ok := applyFilter(user, nil, f); !ok {
+		httputil.JSONError(w, i18n.T(r, "sort must be one of: title, createdAt, updatedAt (prefix - for descending)"), 400)
+		return
+	}
+
+	store.Mu.Lock()
+	if store.Data.SavedViews == nil {
+		store.Data.SavedViews = make(map[string]*store.SavedView)
+	}
+	count := 0
+	for _, v := range store.Data.SavedViews {
+		if v.Owner == user {
+			count++
+			if v.Name == name {
+				store.Mu.Unlock()
+				httputil.JSONError(w, i18n.T(r, "A view named %s already exists", name), 409)
+				return
+			}
+		}
+	}
+	if count >= maxSavedViews {
+		store.Mu.Unlock()
+		httputil.JSONError(w, i18n.T(r, "At most %d saved views per user", maxSavedViews), 400)
+		return
+	}
+	view := &store.SavedView{Id: store.RandId(), Name: name, Owner: user, Filter: f, Meta: store.NewMeta(user)}
+	store.Data.SavedViews[view.Id] = view
+	store.Mu.Unlock()
+	store.Save()
+	httputil.JSONResponse(w, map[string]interface{}{"success": true, "view": view}, 201)
+}
+
+// savedView returns the caller's view id; views of other users are not found.
+func savedView(w http.ResponseWriter, r *http.Request, id string) (store.SavedView, bool) {
+	store.Mu.RLock()
+	v, ok := store.Data.SavedViews[id]
+	var view store.SavedView
+	if ok {
+		view = *v
+	}
+	store.Mu.RUnlock()
+	if !ok || view.Owner != httputil.GetUser(r) {
+		httputil.JSONError(w, i18n.T(r, "View not found"), 404)
+		return view, false
+	}
+	return view, true
+}
+
+// ViewsDelete handles DELETE /api/views/{id}.
+func ViewsDelete(w http.ResponseWriter, r *http.Request, id string) {
+	if _, ok := savedView(w, r, id); !ok {
+		return
+	}
+	store.Mu.Lock()
+	delete(store.Data.SavedViews, id)
+	store.Mu.Unlock()
+	store.Save()
+	httputil.JSONResponse(w, map[string]interface{}{"success": true}, 200)
+}
+
+// ViewsResults handles GET /api/views/{id}/results: the view's filter run
+// against the same authorized list DossiersList serves, so a saved view never
+// returns a dossier the caller cannot see now.
+func ViewsResults(w http.ResponseWriter, r *http.Request, id string) {
+	if !config.FgaReady {
+		httputil.JSONError(w, i18n.T(r, "OpenFGA not ready"), 503)
+		return
+	}
+	view, ok := savedView(w, r, id)
+	if !ok {
+		return
+	}
+	user := httputil.GetUser(r)
+	visibleIds, mark, err := visibility.Visible(r.Context(), user)
+	if err != nil {
+		fgaUnavailable(w, r, err)
+		return
+	}
+	views, err := dossierViews(user, visibleIds, accessContextFrom(r))
+	if err != nil {
+		fgaUnavailable(w, r, err)
+		return
+	}
+	dossiers, _ := applyFilter(user, views, view.Filter)
+	listResponse(w, r, "dossiers", dossiers, listMeta{Consistency: &mark, Extra: map[string]interface{}{"view": view}, Users: dossierUsers(dossiers)})
+}
@@ -0,0 +1,115 @@
+package handlers
+
+import (
+	"fmt"
+	"net/http"
+	"sort"
+	"strings"
+
+	"test-app/internal/config"
+	"test-app/internal/dossiertypes"
+	"test-app/internal/fga"
+	"test-app/internal/httputil"
+	"test-app/internal/i18n"
+	"test-app/internal/store"
+)
+
+// confirmPublic is the ?confirm= value a request must carry to write a
+// wildcard tuple, so no client exposes an object to every user by accident.
+const confirmPublic = "public"
+
+// isWildcard reports whether an FGA user reference is a type wildcard such as user:*.
+func isWildcard(user string) bool {
+	return strings.HasSuffix(user, ":*")
+}
+
+// publicConfirmed answers 409 unless the request carries ?confirm=public.
+func publicConfirmed(w http.ResponseWriter, r *http.Request) bool {
+	if r.URL.Query().Get("confirm") == confirmPublic {
+		return true
+	}
+	httputil.JSONResponse(w, map[string]interface{}{
+		"error": i18n.T(r, "This makes the dossier visible to every user; repeat the request with confirm=public"),
+	}, 409)
+	return false
+}
+
+// wildcardPermitted returns why the wildcard tuple t may not exist, or nil.
+// Only the public relation of dossiers whose type allows it takes a wildcard.
+func wildcardPermitted(t store.TupleKey) error {
+	typ, id, _ := strings.Cut(t.Object, ":")
+	if typ != fga.TypeDossier || t.Relation != "public" || t.User != fga.PublicUser {
+		return fmt.Errorf("wildcards are only allowed as %s public on dossiers", fga.PublicUser)
+	}
+	store.Mu.RLock()
+	d, ok := store.Data.Dossiers[id]
+	var dossierType string
+	if ok {
+		dossierType = d.Type
+	}
+	store.Mu.RUnlock()
+	if !ok {
+		return fmt.Errorf("dossier %s does not exist", id)
+	}
+	dt, ok := dossiertypes.Lookup(dossierType)
+	if !ok || !dt.AllowPublic {
+		return fmt.Errorf("%s dossiers cannot be public", dossierType)
+	}
+	return nil
+}
+
+// wildcardTuple is a wildcard tuple found in OpenFGA, with what the store knows of its object.
+type wildcardTuple struct {
+	store.TupleKey
+	Title     string `json:"title,omitempty"`
+	Type      string `json:"type,omitempty"`
+	InStore   bool   `json:"inStore"` // the store records the object as public
+	Permitted bool   `json:"permitted"`
+	Reason    string `json:"reason,omitempty"`
+}
+
+// AdminWildcardTuples handles GET /api/admin/wildcard-tuples: every tuple
+// granting a relation to a whole type (user:*), flagged when its object's
+// type does not permit it or the store does not record it (for admin use).
+func AdminWildcardTuples(w http.ResponseWriter, r *http.Request) {
+	if !isManagerAdmin(r) {
+		httputil.JSONError(w, i18n.T(r, "Admin access required"), 403)
+		return
+	}
+	if !config.FgaReady {
+		httputil.JSONError(w, i18n.T(r, "OpenFGA not ready"), 503)
+		return
+	}
+	found := []wildcardTuple{}
+	err := fga.ReadPages(r.Context(), func(page []store.TupleKey) error {
+		for _, t := range page {
+			if isWildcard(t.User) {
+				found = append(found, wildcardTuple{TupleKey: t})
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		httputil.JSONError(w, i18n.T(r, "Failed to read tuples: %s", err.Error()), 502)
+		return
+	}
+	violations := 0
+	for i := range found {
+		wt := &found[i]
+		if err := wildcardPermitted(wt.TupleKey); err != nil {
+			wt.Reason = err.Error()
+			violations++
+		} else {
+			wt.Permitted = true
+		}
+		if typ, id, _ := strings.Cut(wt.Object, ":"); typ == fga.TypeDossier {
+			store.Mu.RLock()
+			if d, ok := store.Data.Dossiers[id]; ok {
+				wt.Title, wt.Type, wt.InStore = d.Title, d.Type, d.Public && wt.Relation == "public"
+			}
+			store.Mu.RUnlock()
+		}
+	}
+	sort.Slice(found, func(i, j int) bool { return found[i].Object < found[j].Object })
+	listResponse(w, r, "tuples", found, listMeta{Extra: map[string]interface{}{"violations": violations}})
+}
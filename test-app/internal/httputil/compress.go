@@ -0,0 +1,139 @@
+package httputil
+
+import (
+	"compress/flate"
+	"compress/gzip"
+	"io"
+	"net/http"
+	"strconv"
+	"strings"
+)
+
+// Compress wraps next with gzip/deflate response compression negotiated via
+// Accept-Encoding. Bodies shorter than minSize are sent as-is, as are
+// Server-Sent Events streams and responses that already set Content-Encoding.
+func Compress(next http.Handler, minSize int) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		encoding := negotiateEncoding(r.Header.Get("Accept-Encoding"))
+		if encoding == "" || r.Method == "HEAD" || strings.Contains(r.Header.Get("Accept"), "text/event-stream") {
+			next.ServeHTTP(w, r)
+			return
+		}
+		w.Header().Add("Vary", "Accept-Encoding")
+		cw := &compressWriter{ResponseWriter: w, encoding: encoding, minSize: minSize}
+		defer cw.Close()
+		next.ServeHTTP(cw, r)
+	})
+}
+
+// negotiateEncoding picks gzip or deflate from an Accept-Encoding header,
+// honouring q=0 exclusions and preferring gzip on ties.
+func negotiateEncoding(header string) string {
+	best, bestQ := "", 0.0
+	for _, part := range strings.Split(header, ",") {
+		fields := strings.Split(part, ";")
+		name := strings.ToLower(strings.TrimSpace(fields[0]))
+		q := 1.0
+		for _, param := range fields[1:] {
+			if v, ok := strings.CutPrefix(strings.TrimSpace(param), "q="); ok {
+				if f, err := strconv.ParseFloat(v, 64); err == nil {
+					q = f
+				}
+			}
+		}
+		if (name != "gzip" && name != "deflate") || q <= 0 {
+			continue
+		}
+		if q > bestQ || (q == bestQ && name == "gzip") {
+			best, bestQ = name, q
+		}
+	}
+	return best
+}
+
+// compressWriter buffers the start of a response until it knows whether the
+// body reaches the size threshold, then either compresses or passes through.
+type compressWriter struct {
+	http.ResponseWriter
+	encoding    string
+	minSize     int
+	status      int
+	buf         []byte
+	zw          io.WriteCloser
+	passthrough bool
+}
+
+func (cw *compressWriter) WriteHeader(status int) {
+	if cw.status != 0 {
+		return
+	}
+	cw.status = status
+	h := cw.Header()
+	if status < 200 || status == http.StatusNoContent || status == http.StatusNotModified ||
+		h.Get("Content-Encoding") != "" || strings.HasPrefix(h.Get("Content-Type"), "text/event-stream") ||
+		h.Get("Content-Type") == "application/gzip" {
+		cw.passthrough = true
+		cw.ResponseWriter.WriteHeader(status)
+	}
+}
+
+func (cw *compressWriter) Write(p []byte) (int, error) {
+	if cw.status == 0 {
+		cw.WriteHeader(http.StatusOK)
+	}
+	if cw.passthrough {
+		return cw.ResponseWriter.Write(p)
+	}
+	if cw.zw != nil {
+		return cw.zw.Write(p)
+	}
+	cw.buf = append(cw.buf, p...)
+	if len(cw.buf) >= cw.minSize {
+		if err := cw.start(); err != nil {
+			return 0, err
+		}
+	}
+	return len(p), nil
+}
+
+// start switches to compressed output and flushes the buffered prefix.
+func (cw *compressWriter) start() error {
+	h := cw.Header()
+	h.Set("Content-Encoding", cw.encoding)
+	h.Del("Content-Length")
+	cw.ResponseWriter.WriteHeader(cw.status)
+	if cw.encoding == "gzip" {
+		cw.zw = gzip.NewWriter(cw.ResponseWriter)
+	} else {
+		cw.zw, _ = flate.NewWriter(cw.ResponseWriter, flate.DefaultCompression)
+	}
+	_, err := cw.zw.Write(cw.buf)
+	cw.buf = nil
+	return err
+}
+
+// Flush lets streaming handlers push data through the compressor.
+func (cw *compressWriter) Flush() {
+	if !cw.passthrough && cw.zw == nil && cw.status != 0 {
+		cw.start()
+	}
+	if f, ok := cw.zw.(interface{ Flush() error }); ok {
+		f.Flush()
+	}
+	if f, ok := cw.ResponseWriter.(http.Flusher); ok {
+		f.Flush()
+	}
+}
+
+// Close finishes the compressed stream, or writes a short body uncompressed.
+func (cw *compressWriter) Close() error {
+	switch {
+	case cw.zw != nil:
+		return cw.zw.Close()
+	case cw.passthrough || cw.status == 0:
+		return nil
+	}
+	cw.ResponseWriter.WriteHeader(cw.status)
+	_, err := cw.ResponseWriter.Write(cw.buf)
+	return err
+}
@@ -1,6 +1,8 @@
 package httputil
 
 import (
+	"crypto/sha256"
+	"encoding/hex"
 	"encoding/json"
 	"fmt"
 	"net/http"
@@ -22,14 +24,28 @@ func WantsJSON(r *http.Request) bool {
 		r.URL.Query().Get("format") == "json"
 }
 
+// Anonymous is the user of requests without x-current-user: a guest when
+// config.GuestMode is on, otherwise only seen on public routes.
+const Anonymous = "anonymous"
+
 func GetUser(r *http.Request) string {
 	user := r.Header.Get("x-current-user")
 	if user == "" {
-		user = "anonymous"
+		user = Anonymous
 	}
 	return user
 }
 
+// HasRole reports whether the gateway's x-user-role header lists role.
+func HasRole(r *http.Request, role string) bool {
+	for _, have := range strings.Split(r.Header.Get("x-user-role"), ",") {
+		if strings.TrimSpace(have) == role {
+			return true
+		}
+	}
+	return false
+}
+
 func ReadBody(r *http.Request) (map[string]interface{}, error) {
 	var m map[string]interface{}
 	if err := json.NewDecoder(r.Body).Decode(&m); err != nil {
@@ -61,3 +77,25 @@ func Contains(slice []string, item string) bool {
 	}
 	return false
 }
+
+// ETag derives a weak entity tag from the values that determine a response.
+func ETag(parts ...string) string {
+	h := sha256.Sum256([]byte(strings.Join(parts, "\x00")))
+	return `W/"` + hex.EncodeToString(h[:8]) + `"`
+}
+
+// NotModified sets private caching headers for etag and reports whether the
+// request's If-None-Match already matches, in which case a 304 has been written.
+func NotModified(w http.ResponseWriter, r *http.Request, etag string, maxAge int) bool {
+	w.Header().Set("ETag", etag)
+	w.Header().Set("Cache-Control", fmt.Sprintf("private, max-age=%d", maxAge))
+	w.Header().Add("Vary", "x-current-user, Accept-Language")
+	for _, candidate := range strings.Split(r.Header.Get("If-None-Match"), ",") {
+		candidate = strings.TrimSpace(candidate)
+		if candidate == etag || candidate == "*" || strings.TrimPrefix(candidate, "W/") == strings.TrimPrefix(etag, "W/") {
+			w.WriteHeader(http.StatusNotModified)
+			return true
+		}
+	}
+	return false
+}
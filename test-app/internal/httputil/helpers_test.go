@@ -1,11 +1,14 @@
 package httputil
 
 import (
+	"compress/gzip"
 	"encoding/json"
+	"io"
 	"net/http"
 	"net/http/httptest"
 	"strings"
 	"testing"
+	"time"
 )
 
 func TestJSONResponse(t *testing.T) {
@@ -151,3 +154,149 @@ func TestContains(t *testing.T) {
 		t.Error("Contains(nil, a) = true, want false")
 	}
 }
+
+func TestNotModified(t *testing.T) {
+	etag := ETag("1", "alice")
+	if etag == ETag("2", "alice") {
+		t.Fatal("different inputs produced the same ETag")
+	}
+
+	w := httptest.NewRecorder()
+	r := httptest.NewRequest("GET", "/", nil)
+	if NotModified(w, r, etag, 0) {
+		t.Error("request without If-None-Match reported not modified")
+	}
+	if w.Header().Get("ETag") != etag || w.Header().Get("Cache-Control") != "private, max-age=0" {
+		t.Errorf("headers = %v", w.Header())
+	}
+
+	w = httptest.NewRecorder()
+	r.Header.Set("If-None-Match", `"other", `+strings.TrimPrefix(etag, "W/"))
+	if !NotModified(w, r, etag, 0) || w.Code != http.StatusNotModified {
+		t.Errorf("matching If-None-Match: status = %d, want 304", w.Code)
+	}
+}
+
+func TestCompress(t *testing.T) {
+	large := strings.Repeat(`{"title":"Tax Return"}`, 100)
+	h := Compress(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/large":
+			w.Write([]byte(large))
+		case "/events":
+			w.Header().Set("Content-Type", "text/event-stream")
+			w.Write([]byte(large))
+		default:
+			w.Write([]byte("small"))
+		}
+	}), 512)
+
+	serve := func(path, accept string) *httptest.ResponseRecorder {
+		w := httptest.NewRecorder()
+		r := httptest.NewRequest("GET", path, nil)
+		r.Header.Set("Accept-Encoding", accept)
+		h.ServeHTTP(w, r)
+		return w
+	}
+
+	w := serve("/large", "deflate;q=0.5, gzip")
+	if w.Header().Get("Content-Encoding") != "gzip" {
+		t.Fatalf("Content-Encoding = %q, want gzip", w.Header().Get("Content-Encoding"))
+	}
+	zr, err := gzip.NewReader(w.Body)
+	if err != nil {
+		t.Fatalf("gzip reader: %v", err)
+	}
+	if body, _ := io.ReadAll(zr); string(body) != large {
+		t.Error("decompressed body does not match")
+	}
+
+	if w := serve("/small", "gzip"); w.Header().Get("Content-Encoding") != "" || w.Body.String() != "small" {
+		t.Errorf("small body was compressed: %q", w.Body.String())
+	}
+	if w := serve("/events", "gzip"); w.Header().Get("Content-Encoding") != "" {
+		t.Error("event stream was compressed")
+	}
+	if w := serve("/large", "gzip;q=0, identity"); w.Header().Get("Content-Encoding") != "" {
+		t.Error("gzip;q=0 should disable compression")
+	}
+}
+
+func TestCORS(t *testing.T) {
+	h := CORS(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte("ok"))
+	}), CORSConfig{
+		AllowedOrigins:   []string{"http://localhost:5173"},
+		AllowedHeaders:   []string{"Content-Type", "x-current-user"},
+		AllowCredentials: true,
+		MaxAge:           600,
+	})
+
+	w := httptest.NewRecorder()
+	r := httptest.NewRequest("OPTIONS", "/api/dossiers/list", nil)
+	r.Header.Set("Origin", "http://localhost:5173")
+	r.Header.Set("Access-Control-Request-Method", "GET")
+	h.ServeHTTP(w, r)
+	if w.Code != http.StatusNoContent {
+		t.Errorf("preflight status = %d, want 204", w.Code)
+	}
+	if got := w.Header().Get("Access-Control-Allow-Headers"); got != "Content-Type, x-current-user" {
+		t.Errorf("Allow-Headers = %q", got)
+	}
+	if w.Header().Get("Access-Control-Allow-Credentials") != "true" {
+		t.Error("credentials not allowed")
+	}
+
+	w = httptest.NewRecorder()
+	r = httptest.NewRequest("GET", "/api/dossiers/list", nil)
+	r.Header.Set("Origin", "https://evil.example")
+	h.ServeHTTP(w, r)
+	if w.Header().Get("Access-Control-Allow-Origin") != "" || w.Body.String() != "ok" {
+		t.Errorf("disallowed origin got CORS headers: %v", w.Header())
+	}
+}
+
+func TestVersioned(t *testing.T) {
+	sunset := time.Date(2027, 1, 31, 0, 0, 0, 0, time.UTC)
+	h := Versioned(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte(r.URL.Path))
+	}), sunset)
+	serve := func(path, version string) *httptest.ResponseRecorder {
+		w := httptest.NewRecorder()
+		r := httptest.NewRequest("GET", path, nil)
+		if version != "" {
+			r.Header.Set(VersionHeader, version)
+		}
+		h.ServeHTTP(w, r)
+		return w
+	}
+
+	w := serve("/api/v1/dossiers/list?relation=owner", "")
+	if w.Body.String() != "/api/dossiers/list" || w.Header().Get("Deprecation") != "" || w.Header().Get(VersionHeader) != "1" {
+		t.Errorf("v1 route: body %q, headers %v", w.Body.String(), w.Header())
+	}
+
+	w = serve("/api/dossiers/list", "")
+	if w.Body.String() != "/api/dossiers/list" || w.Header().Get("Deprecation") != "true" {
+		t.Errorf("unversioned route: body %q, headers %v", w.Body.String(), w.Header())
+	}
+	if got := w.Header().Get("Sunset"); got != "Sun, 31 Jan 2027 00:00:00 GMT" {
+		t.Errorf("Sunset = %q", got)
+	}
+	if got := w.Header().Get("Link"); got != `</api/v1/dossiers/list>; rel="successor-version"` {
+		t.Errorf("Link = %q", got)
+	}
+
+	if w := serve("/api/dossiers/list", "1"); w.Header().Get("Deprecation") != "" {
+		t.Error("API-Version: 1 should not be deprecated")
+	}
+	if w := serve("/api/dossiers/list", "2"); w.Code != http.StatusNotAcceptable {
+		t.Errorf("API-Version: 2 status = %d, want 406", w.Code)
+	}
+	if w := serve("/api/v2/dossiers/list", ""); w.Code != http.StatusNotFound {
+		t.Errorf("/api/v2 status = %d, want 404", w.Code)
+	}
+	if w := serve("/public", ""); w.Header().Get(VersionHeader) != "" || w.Body.String() != "/public" {
+		t.Errorf("non-API route was versioned: %v", w.Header())
+	}
+}
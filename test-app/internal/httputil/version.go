@@ -0,0 +1,87 @@
+package httputil
+
+import (
+	"net/http"
+	"strings"
+	"time"
+
+	"test-app/internal/i18n"
+)
+
+// APIVersion is the current version of the /api surface, served under /api/v1/.
+const APIVersion = "1"
+
+// VersionHeader lets callers of the unversioned /api/ routes name the version
+// they were written against, instead of moving to the /api/v1/ prefix.
+const VersionHeader = "API-Version"
+
+// supportedVersions lists the versions Versioned accepts, oldest first.
+var supportedVersions = []string{APIVersion}
+
+// Versioned serves /api/v{N}/... with the handler of /api/..., so routes are
+// registered once. Unversioned /api/ requests are still answered but marked
+// deprecated (Deprecation, Sunset when sunset is set, and a successor-version
+// Link), unless they name a supported version in the API-Version header. An
+// unknown version answers 404 for a path prefix and 406 for the header.
+func Versioned(next http.Handler, sunset time.Time) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if !strings.HasPrefix(r.URL.Path, "/api/") {
+			next.ServeHTTP(w, r)
+			return
+		}
+		h := w.Header()
+		h.Set("API-Supported-Versions", strings.Join(supportedVersions, ", "))
+		if version, rest, ok := splitVersion(r.URL.Path); ok {
+			if !Contains(supportedVersions, version) {
+				JSONError(w, i18n.T(r, "Unsupported API version"), http.StatusNotFound)
+				return
+			}
+			h.Set(VersionHeader, version)
+			next.ServeHTTP(w, withPath(r, "/api/"+rest))
+			return
+		}
+		if version := r.Header.Get(VersionHeader); version != "" {
+			if !Contains(supportedVersions, version) {
+				JSONError(w, i18n.T(r, "Unsupported API version"), http.StatusNotAcceptable)
+				return
+			}
+			h.Set(VersionHeader, version)
+			next.ServeHTTP(w, r)
+			return
+		}
+		h.Set(VersionHeader, APIVersion)
+		h.Set("Deprecation", "true")
+		if !sunset.IsZero() {
+			h.Set("Sunset", sunset.UTC().Format(http.TimeFormat))
+		}
+		h.Add("Link", `</api/v`+APIVersion+strings.TrimPrefix(r.URL.Path, "/api")+`>; rel="successor-version"`)
+		next.ServeHTTP(w, r)
+	})
+}
+
+// splitVersion splits "/api/v1/dossiers/list" into "1" and "dossiers/list".
+func splitVersion(path string) (version, rest string, ok bool) {
+	seg, rest, _ := strings.Cut(strings.TrimPrefix(path, "/api/"), "/")
+	if len(seg) < 2 || seg[0] != 'v' || strings.Trim(seg[1:], "0123456789") != "" {
+		return "", "", false
+	}
+	return seg[1:], rest, true
+}
+
+// Unversioned returns path without its /api/v{N} prefix, as routed by Versioned.
+func Unversioned(path string) string {
+	if _, rest, ok := splitVersion(path); ok && strings.HasPrefix(path, "/api/") {
+		return "/api/" + rest
+	}
+	return path
+}
+
+// withPath returns a shallow copy of r addressed to path.
+func withPath(r *http.Request, path string) *http.Request {
+	r2 := new(http.Request)
+	*r2 = *r
+	u := *r.URL
+	u.Path, u.RawPath = path, ""
+	r2.URL = &u
+	return r2
+}
@@ -0,0 +1,105 @@
+package i18n
+
+import (
+	"embed"
+	"encoding/json"
+	"fmt"
+	"log"
+	"net/http"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// DefaultLang is the language of the source strings; messages missing from
+// every catalog in the fallback chain are returned untranslated.
+const DefaultLang = "en"
+
+//go:embed locales/*.json
+var files embed.FS
+
+var catalogs = map[string]map[string]string{}
+
+func init() {
+	entries, err := files.ReadDir("locales")
+	if err != nil {
+		log.Printf("WARNING: failed to read message catalogs: %v", err)
+		return
+	}
+	for _, e := range entries {
+		data, err := files.ReadFile("locales/" + e.Name())
+		if err != nil {
+			continue
+		}
+		var messages map[string]string
+		if err := json.Unmarshal(data, &messages); err != nil {
+			log.Printf("WARNING: failed to parse catalog %s: %v", e.Name(), err)
+			continue
+		}
+		catalogs[strings.TrimSuffix(e.Name(), ".json")] = messages
+	}
+}
+
+// Supported reports whether a catalog (or the default language) exists for lang.
+func Supported(lang string) bool {
+	_, ok := catalogs[lang]
+	return ok || lang == DefaultLang
+}
+
+// Lang picks the best supported language from the Accept-Language header,
+// honoring q-values and falling back from regional tags (fr-BE) to their base (fr).
+func Lang(r *http.Request) string {
+	type candidate struct {
+		tag string
+		q   float64
+	}
+	var candidates []candidate
+	for _, part := range strings.Split(r.Header.Get("Accept-Language"), ",") {
+		fields := strings.Split(strings.TrimSpace(part), ";")
+		tag := strings.ToLower(strings.TrimSpace(fields[0]))
+		if tag == "" || tag == "*" {
+			continue
+		}
+		q := 1.0
+		for _, f := range fields[1:] {
+			if v, ok := strings.CutPrefix(strings.TrimSpace(f), "q="); ok {
+				if parsed, err := strconv.ParseFloat(v, 64); err == nil {
+					q = parsed
+				}
+			}
+		}
+		candidates = append(candidates, candidate{tag, q})
+	}
+	sort.SliceStable(candidates, func(i, j int) bool { return candidates[i].q > candidates[j].q })
+	for _, c := range candidates {
+		if Supported(c.tag) {
+			return c.tag
+		}
+		if base, _, found := strings.Cut(c.tag, "-"); found && Supported(base) {
+			return base
+		}
+	}
+	return DefaultLang
+}
+
+// Translate looks msg up along the fallback chain lang -> base language -> source
+// string, then applies args with fmt.Sprintf when any are given.
+func Translate(lang, msg string, args ...interface{}) string {
+	out := msg
+	if m, ok := catalogs[lang]; ok && m[msg] != "" {
+		out = m[msg]
+	} else if base, _, found := strings.Cut(lang, "-"); found {
+		if m, ok := catalogs[base]; ok && m[msg] != "" {
+			out = m[msg]
+		}
+	}
+	if len(args) > 0 {
+		return fmt.Sprintf(out, args...)
+	}
+	return out
+}
+
+// T translates msg into the language negotiated for r.
+func T(r *http.Request, msg string, args ...interface{}) string {
+	return Translate(Lang(r), msg, args...)
+}
@@ -0,0 +1,44 @@
+package i18n
+
+import (
+	"net/http/httptest"
+	"testing"
+)
+
+func TestLang(t *testing.T) {
+	tests := []struct {
+		name   string
+		header string
+		want   string
+	}{
+		{"empty", "", "en"},
+		{"french", "fr", "fr"},
+		{"regional falls back to base", "nl-BE,nl;q=0.9", "nl"},
+		{"q-values ordering", "de;q=0.9,fr;q=0.5,nl;q=0.8", "nl"},
+		{"unsupported", "de, ja", "en"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			r := httptest.NewRequest("GET", "/", nil)
+			r.Header.Set("Accept-Language", tt.header)
+			if got := Lang(r); got != tt.want {
+				t.Errorf("Lang(%q) = %q, want %q", tt.header, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestTranslate(t *testing.T) {
+	if got := Translate("fr", "Dossier not found"); got != "Dossier introuvable" {
+		t.Errorf("fr = %q", got)
+	}
+	if got := Translate("en", "Dossier not found"); got != "Dossier not found" {
+		t.Errorf("en = %q", got)
+	}
+	if got := Translate("nl", "Already a guardian of %s", "bob"); got != "Al voogd van bob" {
+		t.Errorf("nl with args = %q", got)
+	}
+	if got := Translate("fr", "Some message without translation"); got != "Some message without translation" {
+		t.Errorf("missing key should fall back to source, got %q", got)
+	}
+}
@@ -0,0 +1,190 @@
+package identity
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"testing"
+	"time"
+
+	"test-app/internal/config"
+	"test-app/internal/httputil"
+)
+
+func TestVerify(t *testing.T) {
+	os.Setenv(config.SigningKey, "k3y")
+	defer os.Unsetenv(config.SigningKey)
+	config.LoadSecrets()
+	defer func() {
+		os.Unsetenv(config.SigningKey)
+		config.LoadSecrets()
+	}()
+	origMode := config.IdentitySignatures.Get()
+	defer config.IdentitySignatures.Set(origMode)
+	config.IdentitySignatures.Set("enforce")
+
+	handler := Verify(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {}))
+	serve := func(r *http.Request) int {
+		w := httptest.NewRecorder()
+		handler.ServeHTTP(w, r)
+		return w.Code
+	}
+	signed := func(user string, at time.Time) *http.Request {
+		r := httptest.NewRequest("GET", "/api/dossiers/list?limit=5", nil)
+		r.Header.Set("x-current-user", user)
+		r.Header.Set("x-user-role", "user")
+		r.Header.Set(SignatureHeader, Sign("k3y", r.Method, r.RequestURI, r.Header, at))
+		return r
+	}
+
+	if code := serve(signed("alice", time.Now())); code != 200 {
+		t.Errorf("signed request status = %d, want 200", code)
+	}
+	if code := serve(httptest.NewRequest("GET", "/api/health", nil)); code != 200 {
+		t.Errorf("request without identity headers status = %d, want 200", code)
+	}
+
+	forged := signed("alice", time.Now())
+	forged.Header.Set("x-current-user", "bob")
+	if code := serve(forged); code != 401 {
+		t.Errorf("tampered user status = %d, want 401", code)
+	}
+	escalated := signed("alice", time.Now())
+	escalated.Header.Set("x-manager-admin", "true")
+	if code := serve(escalated); code != 401 {
+		t.Errorf("added x-manager-admin status = %d, want 401", code)
+	}
+	moved := signed("alice", time.Now())
+	moved.RequestURI = "/api/admin/reset"
+	if code := serve(moved); code != 401 {
+		t.Errorf("signature replayed on another path status = %d, want 401", code)
+	}
+	if code := serve(signed("alice", time.Now().Add(-10*time.Minute))); code != 401 {
+		t.Errorf("stale signature status = %d, want 401", code)
+	}
+	unsigned := httptest.NewRequest("GET", "/api/dossiers/list", nil)
+	unsigned.Header.Set("x-current-user", "alice")
+	if code := serve(unsigned); code != 401 {
+		t.Errorf("unsigned status = %d, want 401", code)
+	}
+
+	config.IdentitySignatures.Set("log")
+	if code := serve(unsigned); code != 200 {
+		t.Errorf("log mode status = %d, want 200", code)
+	}
+}
+
+func TestVerify_VersionedPath(t *testing.T) {
+	os.Setenv(config.SigningKey, "k3y")
+	config.LoadSecrets()
+	defer func() {
+		os.Unsetenv(config.SigningKey)
+		config.LoadSecrets()
+	}()
+	origMode := config.IdentitySignatures.Get()
+	defer config.IdentitySignatures.Set(origMode)
+	config.IdentitySignatures.Set("enforce")
+
+	// The gateway signs the raw /api/v1 path; Versioned routes it unversioned.
+	var served string
+	handler := httputil.Versioned(Verify(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		served = r.URL.Path
+	})), time.Time{})
+	r := httptest.NewRequest("GET", "/api/v1/dossiers/list?limit=5", nil)
+	r.Header.Set("x-current-user", "alice")
+	r.Header.Set(SignatureHeader, Sign("k3y", r.Method, "/api/v1/dossiers/list?limit=5", r.Header, time.Now()))
+	if !Valid("k3y", r, time.Now()) {
+		t.Error("Valid rejected a request signed on its /api/v1 path")
+	}
+	w := httptest.NewRecorder()
+	handler.ServeHTTP(w, r)
+	if w.Code != 200 || served != "/api/dossiers/list" {
+		t.Errorf("status = %d, served %q, want 200 on /api/dossiers/list", w.Code, served)
+	}
+}
+
+func TestRequire(t *testing.T) {
+	for path, want := range map[string]string{
+		"/":                    Public,
+		"/public":              Public,
+		"/api/health":          Public,
+		"/api/healthz":         Authenticated,
+		"/opa/bundles/authz":   Public,
+		"/api/dossiers/list":   Guest,
+		"/api/dossiers/create": Authenticated,
+		"/home":                Authenticated,
+	} {
+		if got := Classify(path); got != want {
+			t.Errorf("Classify(%q) = %q, want %q", path, got, want)
+		}
+	}
+
+	handler := Require(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {}))
+	serve := func(path string, headers map[string]string) int {
+		r := httptest.NewRequest("GET", path, nil)
+		for k, v := range headers {
+			r.Header.Set(k, v)
+		}
+		w := httptest.NewRecorder()
+		handler.ServeHTTP(w, r)
+		return w.Code
+	}
+	if code := serve("/api/dossiers/list", nil); code != 401 {
+		t.Errorf("anonymous protected status = %d, want 401", code)
+	}
+	if code := serve("/api/dossiers/list", map[string]string{"x-current-user": "alice"}); code != 200 {
+		t.Errorf("identified status = %d, want 200", code)
+	}
+	if code := serve("/api/dossiers/organizations", map[string]string{"x-manager-admin": "true"}); code != 200 {
+		t.Errorf("manager admin status = %d, want 200", code)
+	}
+	if code := serve("/public", nil); code != 200 {
+		t.Errorf("public status = %d, want 200", code)
+	}
+	if code := serve("/api/dossiers/list", map[string]string{"x-current-user": "anonymous"}); code != 401 {
+		t.Errorf("literal anonymous status = %d, want 401", code)
+	}
+}
+
+func TestRequire_GuestMode(t *testing.T) {
+	origGuest := config.GuestMode.Get()
+	defer config.GuestMode.Set(origGuest)
+	config.GuestMode.Set(true)
+
+	handler := Require(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {}))
+	serve := func(method, path string) int {
+		w := httptest.NewRecorder()
+		handler.ServeHTTP(w, httptest.NewRequest(method, path, nil))
+		return w.Code
+	}
+	for _, path := range []string{"/api/dossiers/list", "/partials/dossiers"} {
+		if code := serve("GET", path); code != 200 {
+			t.Errorf("guest GET %s status = %d, want 200", path, code)
+		}
+	}
+	// Guests cannot drive any write flow: dossiers, sharing, guardianships, organizations.
+	for _, rt := range [][2]string{
+		{"POST", "/api/dossiers/list"},
+		{"POST", "/api/dossiers/create"},
+		{"POST", "/api/dossiers/d1/relations"},
+		{"POST", "/api/dossiers/d1/toggle-public"},
+		{"POST", "/api/dossiers/d1/access-requests"},
+		{"POST", "/api/dossiers/guardianships/request"},
+		{"POST", "/api/dossiers/guardianships/r1/accept"},
+		{"DELETE", "/api/dossiers/guardianships/bob"},
+		{"POST", "/api/dossiers/organizations"},
+		{"POST", "/api/dossiers/organizations/o1/join"},
+		{"POST", "/api/dossiers/organizations/o1/members"},
+		{"GET", "/api/dossiers/guardianships"},
+		{"GET", "/api/me/export"},
+	} {
+		if code := serve(rt[0], rt[1]); code != 401 {
+			t.Errorf("guest %s %s status = %d, want 401", rt[0], rt[1], code)
+		}
+	}
+
+	config.GuestMode.Set(false)
+	if code := serve("GET", "/api/dossiers/list"); code != 401 {
+		t.Errorf("guest mode off status = %d, want 401", code)
+	}
+}
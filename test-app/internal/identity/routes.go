@@ -0,0 +1,88 @@
+package identity
+
+import (
+	"net/http"
+	"strings"
+
+	"test-app/internal/config"
+	"test-app/internal/httputil"
+	"test-app/internal/i18n"
+	"test-app/internal/users"
+)
+
+// Access classes of a route.
+const (
+	// Public routes are served without identity headers.
+	Public = "public"
+	// Guest routes are readable without identity headers when config.GuestMode is on.
+	Guest = "guest"
+	// Authenticated routes need x-current-user (or x-manager-admin from the AI Manager).
+	Authenticated = "authenticated"
+)
+
+// Route classifies the paths starting with Prefix, or only Prefix itself when Exact.
+type Route struct {
+	Prefix string `json:"prefix"`
+	Exact  bool   `json:"exact,omitempty"`
+	Access string `json:"access"`
+}
+
+// Routes lists the routes reachable without an identity; the longest matching
+// entry wins and anything unlisted is Authenticated. Keep the public entries
+// in line with is_public_path in infra/opa/policies/policy.rego.
+var Routes = []Route{
+	{Prefix: "/", Exact: true, Access: Public},
+	{Prefix: "/public", Access: Public},
+	{Prefix: "/logout", Access: Public},
+	{Prefix: "/api/health", Exact: true, Access: Public},
+	// Called by OPA itself for bundles and decision logs.
+	{Prefix: "/opa/", Access: Public},
+	// Called by Keycloak's event listener, authenticated by KEYCLOAK_HOOK_SECRET.
+	{Prefix: "/hooks/", Access: Public},
+	// Guests browse public dossiers; see is_public_path in the policy.
+	{Prefix: "/api/dossiers/list", Exact: true, Access: Guest},
+	{Prefix: "/partials/dossiers", Exact: true, Access: Guest},
+}
+
+// Classify returns the access class of path.
+func Classify(path string) string {
+	best, access := -1, Authenticated
+	for _, rt := range Routes {
+		match := path == rt.Prefix || (!rt.Exact && strings.HasPrefix(path, rt.Prefix))
+		if match && len(rt.Prefix) > best {
+			best, access = len(rt.Prefix), rt.Access
+		}
+	}
+	return access
+}
+
+// allowsAnonymous reports whether r may be served without an identity: public
+// routes always, guest routes only for reads and only in guest mode.
+func allowsAnonymous(r *http.Request) bool {
+	switch Classify(r.URL.Path) {
+	case Public:
+		return true
+	case Guest:
+		return config.GuestMode.Get() && (r.Method == "GET" || r.Method == "HEAD")
+	}
+	return false
+}
+
+// Require answers 401 to requests without an identity unless allowsAnonymous,
+// instead of letting handlers act as "anonymous": in particular no write can
+// be made by, or create anything owned by, the anonymous user.
+func Require(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		user := r.Header.Get("x-current-user")
+		if user != "" && !users.Valid(user) {
+			httputil.JSONError(w, i18n.T(r, "Invalid username"), 400)
+			return
+		}
+		anonymous := (user == "" || user == httputil.Anonymous) && r.Header.Get("x-manager-admin") != "true"
+		if anonymous && !allowsAnonymous(r) {
+			httputil.JSONError(w, i18n.T(r, "Authentication required"), 401)
+			return
+		}
+		next.ServeHTTP(w, r)
+	})
+}
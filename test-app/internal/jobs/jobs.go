@@ -0,0 +1,186 @@
+// Package jobs runs long operations, such as large exports, in the background
+// instead of inside a request: a job reports its progress while it runs, can
+// be cancelled, and keeps the artifact it produced in memory for download
+// until it has been finished for config.JobRetention.
+package jobs
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"errors"
+	"sort"
+	"sync"
+	"time"
+
+	"test-app/internal/config"
+)
+
+// Job states.
+const (
+	Running   = "running"
+	Done      = "done"
+	Failed    = "failed"
+	Cancelled = "cancelled"
+)
+
+// MaxRunning bounds how many jobs may run at once.
+const MaxRunning = 3
+
+// ErrTooMany is returned by Start when MaxRunning jobs are already running.
+var ErrTooMany = errors.New("too many running jobs")
+
+// Job is the state of one background job as reported to its caller. Total is
+// 0 while the amount of work is unknown.
+type Job struct {
+	Id         string     `json:"id"`
+	Kind       string     `json:"kind"`
+	CreatedBy  string     `json:"createdBy"`
+	Status     string     `json:"status"`
+	Done       int        `json:"done"`
+	Total      int        `json:"total"`
+	Error      string     `json:"error,omitempty"`
+	Size       int        `json:"size,omitempty"`
+	CreatedAt  time.Time  `json:"createdAt"`
+	FinishedAt *time.Time `json:"finishedAt,omitempty"`
+
+	cancel context.CancelFunc
+	result Result
+}
+
+// Result is the artifact a finished job offers for download.
+type Result struct {
+	Data        []byte
+	ContentType string
+	Filename    string
+}
+
+// Progress reports that done units of work out of total are complete.
+type Progress func(done, total int)
+
+// Func is the work of a job. It should return ctx.Err() soon after ctx is
+// cancelled.
+type Func func(ctx context.Context, progress Progress) (Result, error)
+
+var (
+	mu   sync.Mutex
+	jobs = map[string]*Job{}
+)
+
+// Start runs fn in the background as a job of kind started by user.
+func Start(kind, user string, fn Func) (Job, error) {
+	mu.Lock()
+	defer mu.Unlock()
+	running := 0
+	for _, j := range jobs {
+		if j.Status == Running {
+			running++
+		}
+	}
+	if running >= MaxRunning {
+		return Job{}, ErrTooMany
+	}
+	b := make([]byte, 8)
+	if _, err := rand.Read(b); err != nil {
+		return Job{}, err
+	}
+	ctx, cancel := context.WithCancel(context.Background())
+	j := &Job{Id: hex.EncodeToString(b), Kind: kind, CreatedBy: user, Status: Running, CreatedAt: time.Now().UTC(), cancel: cancel}
+	jobs[j.Id] = j
+	go run(ctx, j, fn)
+	return *j, nil
+}
+
+func run(ctx context.Context, j *Job, fn Func) {
+	res, err := fn(ctx, func(done, total int) {
+		mu.Lock()
+		j.Done, j.Total = done, total
+		mu.Unlock()
+	})
+	mu.Lock()
+	defer mu.Unlock()
+	defer j.cancel()
+	now := time.Now().UTC()
+	j.FinishedAt = &now
+	switch {
+	case ctx.Err() != nil:
+		j.Status = Cancelled
+	case err != nil:
+		j.Status, j.Error = Failed, err.Error()
+	default:
+		j.Status, j.result, j.Size = Done, res, len(res.Data)
+		if j.Total == 0 {
+			j.Total = j.Done
+		}
+	}
+}
+
+// Get returns the current state of job id.
+func Get(id string) (Job, bool) {
+	mu.Lock()
+	defer mu.Unlock()
+	j, ok := jobs[id]
+	if !ok {
+		return Job{}, false
+	}
+	return *j, true
+}
+
+// List returns every job, newest first.
+func List() []Job {
+	mu.Lock()
+	defer mu.Unlock()
+	out := []Job{}
+	for _, j := range jobs {
+		out = append(out, *j)
+	}
+	sort.Slice(out, func(i, k int) bool { return out[i].CreatedAt.After(out[k].CreatedAt) })
+	return out
+}
+
+// Artifact returns the result of job id once it is done.
+func Artifact(id string) (Result, bool) {
+	mu.Lock()
+	defer mu.Unlock()
+	j, ok := jobs[id]
+	if !ok || j.Status != Done {
+		return Result{}, false
+	}
+	return j.result, true
+}
+
+// Cancel stops a running job, or forgets a finished one and its artifact. It
+// reports false when there is no job id.
+func Cancel(id string) bool {
+	mu.Lock()
+	defer mu.Unlock()
+	j, ok := jobs[id]
+	if !ok {
+		return false
+	}
+	if j.Status == Running {
+		j.cancel()
+	} else {
+		delete(jobs, id)
+	}
+	return true
+}
+
+// expire forgets the jobs finished more than config.JobRetention before now.
+func expire(now time.Time) {
+	mu.Lock()
+	defer mu.Unlock()
+	for id, j := range jobs {
+		if j.FinishedAt != nil && now.Sub(*j.FinishedAt) > config.JobRetention {
+			delete(jobs, id)
+		}
+	}
+}
+
+// RunJanitor drops expired jobs every interval.
+func RunJanitor(interval time.Duration) {
+	for {
+		time.Sleep(interval)
+		expire(time.Now())
+	}
+}
@@ -0,0 +1,81 @@
+package jobs
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+)
+
+// wait polls job id until it leaves the running state.
+func wait(t *testing.T, id string) Job {
+	t.Helper()
+	deadline := time.Now().Add(2 * time.Second)
+	for time.Now().Before(deadline) {
+		if j, _ := Get(id); j.Status != Running {
+			return j
+		}
+		time.Sleep(5 * time.Millisecond)
+	}
+	t.Fatalf("job %s still running", id)
+	return Job{}
+}
+
+func TestJobProgressAndArtifact(t *testing.T) {
+	step := make(chan struct{})
+	j, err := Start("tuples", "admin", func(ctx context.Context, progress Progress) (Result, error) {
+		progress(1, 2)
+		<-step
+		progress(2, 2)
+		return Result{Data: []byte("[]"), ContentType: "application/json", Filename: "tuples.json"}, nil
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer Cancel(j.Id)
+	for {
+		if got, _ := Get(j.Id); got.Done == 1 {
+			break
+		}
+		time.Sleep(time.Millisecond)
+	}
+	if _, ok := Artifact(j.Id); ok {
+		t.Error("artifact available while running")
+	}
+	close(step)
+	if got := wait(t, j.Id); got.Status != Done || got.Done != 2 || got.Size != 2 {
+		t.Errorf("finished job = %+v", got)
+	}
+	if res, ok := Artifact(j.Id); !ok || res.Filename != "tuples.json" {
+		t.Errorf("artifact = %+v, %v", res, ok)
+	}
+}
+
+func TestJobCancelAndFailure(t *testing.T) {
+	j, _ := Start("audit", "admin", func(ctx context.Context, progress Progress) (Result, error) {
+		<-ctx.Done()
+		return Result{}, ctx.Err()
+	})
+	Cancel(j.Id)
+	if got := wait(t, j.Id); got.Status != Cancelled {
+		t.Errorf("status = %s, want cancelled", got.Status)
+	}
+	if !Cancel(j.Id) {
+		t.Error("finished job not found")
+	}
+	if _, ok := Get(j.Id); ok {
+		t.Error("cancelling a finished job should forget it")
+	}
+
+	j, _ = Start("data", "admin", func(ctx context.Context, progress Progress) (Result, error) {
+		return Result{}, errors.New("disk full")
+	})
+	defer Cancel(j.Id)
+	if got := wait(t, j.Id); got.Status != Failed || got.Error != "disk full" {
+		t.Errorf("failed job = %+v", got)
+	}
+	expire(time.Now().Add(48 * time.Hour))
+	if _, ok := Get(j.Id); ok {
+		t.Error("expired job kept")
+	}
+}
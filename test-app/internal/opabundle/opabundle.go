@@ -0,0 +1,101 @@
+// Package opabundle packages a directory of Rego policies as an OPA bundle:
+// a gzipped tarball holding the .rego files, any data.json files and a
+// .manifest whose revision is a digest of the content. The output is
+// deterministic, so the revision doubles as an ETag for bundle polling.
+package opabundle
+
+import (
+	"archive/tar"
+	"bytes"
+	"compress/gzip"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+)
+
+// Bundle is a built bundle.
+type Bundle struct {
+	Data     []byte
+	Revision string
+	Files    []string
+}
+
+// included reports whether a file belongs in a bundle.
+func included(name string) bool {
+	return strings.HasSuffix(name, ".rego") || filepath.Base(name) == "data.json"
+}
+
+// Build packages the policies under dir. Hidden files and directories are skipped.
+func Build(dir string) (*Bundle, error) {
+	files := map[string][]byte{}
+	err := filepath.WalkDir(dir, func(path string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		if path != dir && strings.HasPrefix(d.Name(), ".") {
+			if d.IsDir() {
+				return filepath.SkipDir
+			}
+			return nil
+		}
+		if d.IsDir() || !included(d.Name()) {
+			return nil
+		}
+		b, err := os.ReadFile(path)
+		if err != nil {
+			return err
+		}
+		rel, _ := filepath.Rel(dir, path)
+		files[filepath.ToSlash(rel)] = b
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	names := make([]string, 0, len(files))
+	for name := range files {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	sum := sha256.New()
+	for _, name := range names {
+		sum.Write([]byte(name))
+		sum.Write([]byte{0})
+		sum.Write(files[name])
+		sum.Write([]byte{0})
+	}
+	revision := hex.EncodeToString(sum.Sum(nil))[:16]
+	manifest, _ := json.Marshal(map[string]interface{}{"revision": revision, "roots": []string{""}})
+
+	var buf bytes.Buffer
+	zw := gzip.NewWriter(&buf)
+	tw := tar.NewWriter(zw)
+	add := func(name string, body []byte) error {
+		if err := tw.WriteHeader(&tar.Header{Name: "/" + name, Mode: 0644, Size: int64(len(body)), Typeflag: tar.TypeReg}); err != nil {
+			return err
+		}
+		_, err := tw.Write(body)
+		return err
+	}
+	if err := add(".manifest", manifest); err != nil {
+		return nil, err
+	}
+	for _, name := range names {
+		if err := add(name, files[name]); err != nil {
+			return nil, err
+		}
+	}
+	if err := tw.Close(); err != nil {
+		return nil, err
+	}
+	if err := zw.Close(); err != nil {
+		return nil, err
+	}
+	return &Bundle{Data: buf.Bytes(), Revision: revision, Files: names}, nil
+}
@@ -0,0 +1,60 @@
+package opabundle
+
+import (
+	"archive/tar"
+	"bytes"
+	"compress/gzip"
+	"io"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestBuild(t *testing.T) {
+	dir := t.TempDir()
+	os.WriteFile(filepath.Join(dir, "policy.rego"), []byte("package envoy.authz\n"), 0644)
+	os.WriteFile(filepath.Join(dir, "README.md"), []byte("not policy"), 0644)
+	os.MkdirAll(filepath.Join(dir, "users"), 0755)
+	os.WriteFile(filepath.Join(dir, "users", "data.json"), []byte(`{"admins":["alice"]}`), 0644)
+
+	first, err := Build(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	again, _ := Build(dir)
+	if !bytes.Equal(first.Data, again.Data) || first.Revision != again.Revision {
+		t.Error("bundle output must be deterministic")
+	}
+
+	zr, err := gzip.NewReader(bytes.NewReader(first.Data))
+	if err != nil {
+		t.Fatal(err)
+	}
+	tr := tar.NewReader(zr)
+	var names []string
+	for {
+		h, err := tr.Next()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			t.Fatal(err)
+		}
+		names = append(names, h.Name)
+	}
+	want := []string{"/.manifest", "/policy.rego", "/users/data.json"}
+	if len(names) != len(want) {
+		t.Fatalf("bundle files = %v, want %v", names, want)
+	}
+	for i := range want {
+		if names[i] != want[i] {
+			t.Errorf("bundle files = %v, want %v", names, want)
+		}
+	}
+
+	os.WriteFile(filepath.Join(dir, "policy.rego"), []byte("package envoy.authz\ndefault allow := false\n"), 0644)
+	changed, _ := Build(dir)
+	if changed.Revision == first.Revision {
+		t.Error("revision must change with the policy")
+	}
+}
@@ -0,0 +1,125 @@
+// Package privacy pseudonymizes usernames and redacts dossier text in payloads
+// that leave the application, such as requests to the AI Manager and entries
+// for the audit sink, while anonymization is enabled. The mapping between
+// usernames and pseudonyms is kept in memory only, so answers that come back
+// can be translated locally with Reveal.
+package privacy
+
+import (
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/hex"
+	"regexp"
+	"sort"
+	"strings"
+	"sync"
+	"sync/atomic"
+)
+
+// Redacted replaces dossier titles and content.
+const Redacted = "[redacted]"
+
+// minScrubLength keeps very short usernames from being replaced inside other words.
+const minScrubLength = 3
+
+var (
+	enabled atomic.Bool
+
+	mu      sync.RWMutex
+	secret  = newSecret()
+	forward = map[string]string{}
+	reverse = map[string]string{}
+
+	userRef   = regexp.MustCompile(`user:([A-Za-z0-9._@-]+)`)
+	pseudoRef = regexp.MustCompile(`user-[0-9a-f]{10}`)
+)
+
+func newSecret() []byte {
+	b := make([]byte, 32)
+	rand.Read(b)
+	return b
+}
+
+// Enabled reports whether outgoing payloads are anonymized.
+func Enabled() bool {
+	return enabled.Load()
+}
+
+// SetEnabled switches anonymization on or off.
+func SetEnabled(on bool) {
+	enabled.Store(on)
+}
+
+// Pseudonym returns the stable pseudonym of user for the life of the process.
+// The wildcard and the empty string are returned unchanged.
+func Pseudonym(user string) string {
+	if user == "" || user == "*" {
+		return user
+	}
+	mu.RLock()
+	p, ok := forward[user]
+	mu.RUnlock()
+	if ok {
+		return p
+	}
+	h := hmac.New(sha256.New, secret)
+	h.Write([]byte(user))
+	p = "user-" + hex.EncodeToString(h.Sum(nil))[:10]
+	mu.Lock()
+	forward[user] = p
+	reverse[p] = user
+	mu.Unlock()
+	return p
+}
+
+// Pseudonyms maps Pseudonym over users.
+func Pseudonyms(users []string) []string {
+	out := make([]string, len(users))
+	for i, u := range users {
+		out[i] = Pseudonym(u)
+	}
+	return out
+}
+
+// Subject pseudonymizes an FGA subject or object of type user ("user:alice");
+// any other value is returned unchanged.
+func Subject(s string) string {
+	if name, ok := strings.CutPrefix(s, "user:"); ok {
+		return "user:" + Pseudonym(name)
+	}
+	return s
+}
+
+// Scrub pseudonymizes free text: every "user:name" reference, and every
+// username already known to the mapping that appears as a whole word.
+func Scrub(s string) string {
+	s = userRef.ReplaceAllStringFunc(s, Subject)
+	mu.RLock()
+	names := make([]string, 0, len(forward))
+	for name := range forward {
+		if len(name) >= minScrubLength {
+			names = append(names, name)
+		}
+	}
+	mu.RUnlock()
+	// Longest first, so "anna" does not eat into "annabel".
+	sort.Slice(names, func(i, j int) bool { return len(names[i]) > len(names[j]) })
+	for _, name := range names {
+		re := regexp.MustCompile(`\b` + regexp.QuoteMeta(name) + `\b`)
+		s = re.ReplaceAllLiteralString(s, Pseudonym(name))
+	}
+	return s
+}
+
+// Reveal replaces every pseudonym in s with the username it stands for.
+func Reveal(s string) string {
+	mu.RLock()
+	defer mu.RUnlock()
+	return pseudoRef.ReplaceAllStringFunc(s, func(p string) string {
+		if user, ok := reverse[p]; ok {
+			return user
+		}
+		return p
+	})
+}
@@ -0,0 +1,30 @@
+package privacy
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestPseudonymRoundTrip(t *testing.T) {
+	p := Pseudonym("alice")
+	if p == "alice" || !strings.HasPrefix(p, "user-") || Pseudonym("alice") != p {
+		t.Fatalf("Pseudonym(alice) = %q, want a stable user-… pseudonym", p)
+	}
+	if Pseudonym("bob") == p {
+		t.Error("distinct users must get distinct pseudonyms")
+	}
+	if Subject("user:alice") != "user:"+p || Subject("dossier:d1") != "dossier:d1" || Subject("user:*") != "user:*" {
+		t.Errorf("Subject mapping wrong: %q %q", Subject("user:alice"), Subject("user:*"))
+	}
+
+	text := Scrub("Tuple added: user:carol owner dossier:d1; alice asked")
+	if strings.Contains(text, "carol") || strings.Contains(text, "alice") {
+		t.Errorf("Scrub left a username: %q", text)
+	}
+	if got := Reveal(text); got != "Tuple added: user:carol owner dossier:d1; alice asked" {
+		t.Errorf("Reveal(Scrub(x)) = %q", got)
+	}
+	if Reveal("user-0000000000") != "user-0000000000" {
+		t.Error("unknown pseudonyms must be left alone")
+	}
+}
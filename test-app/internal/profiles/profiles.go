@@ -0,0 +1,197 @@
+// Package profiles keeps what the app knows of each user beyond their
+// username, in store.Data.Profiles: a display name, email, avatar color and
+// locale. Claims come from the ID token, forwarded by OPA as the signed
+// x-user-name, x-user-email and x-user-locale headers, and from the Keycloak
+// hook. List responses carry a card per user they name so clients can show
+// display names instead of usernames.
+package profiles
+
+import (
+	"hash/fnv"
+	"log"
+	"net/http"
+	"strings"
+	"sync"
+	"unicode/utf8"
+
+	"test-app/internal/sandbox"
+	"test-app/internal/store"
+)
+
+// Claims are the profile fields reported by the identity provider. Empty
+// fields leave the stored value alone.
+type Claims struct {
+	Name   string
+	Email  string
+	Locale string
+}
+
+// FromHeaders reads the claims OPA forwards.
+func FromHeaders(h http.Header) Claims {
+	return Claims{Name: h.Get("x-user-name"), Email: h.Get("x-user-email"), Locale: h.Get("x-user-locale")}
+}
+
+// clean drops values that are too long or malformed to display.
+func (c Claims) clean() Claims {
+	c.Name = strings.TrimSpace(c.Name)
+	if utf8.RuneCountInString(c.Name) > 100 {
+		c.Name = ""
+	}
+	c.Email = strings.TrimSpace(c.Email)
+	if len(c.Email) > 254 || !strings.Contains(c.Email, "@") {
+		c.Email = ""
+	}
+	c.Locale = strings.TrimSpace(c.Locale)
+	if len(c.Locale) > 16 || strings.Trim(c.Locale, "abcdefghijklmnopqrstuvwxyzABCDEFGHIJKLMNOPQRSTUVWXYZ-_") != "" {
+		c.Locale = ""
+	}
+	return c
+}
+
+// empty reports whether c carries nothing to record.
+func (c Claims) empty() bool {
+	return c == Claims{}
+}
+
+// palette holds the avatar colors, readable behind a white initial.
+var palette = []string{"#2f6f8f", "#8e44ad", "#c0392b", "#16a085", "#d35400", "#2c3e50", "#7b5e2a", "#27ae60"}
+
+// ColorOf returns the avatar color of user, stable across restarts.
+func ColorOf(user string) string {
+	h := fnv.New32a()
+	h.Write([]byte(user))
+	return palette[h.Sum32()%uint32(len(palette))]
+}
+
+// Ensure returns user's profile, creating it on behalf of by. Callers hold
+// store.Mu for writing.
+func Ensure(user, by string) (p *store.Profile, created bool) {
+	if store.Data.Profiles == nil {
+		store.Data.Profiles = map[string]*store.Profile{}
+	}
+	if p = store.Data.Profiles[user]; p != nil {
+		return p, false
+	}
+	p = &store.Profile{Color: ColorOf(user), Meta: store.NewMeta(by)}
+	store.Data.Profiles[user] = p
+	return p, true
+}
+
+// Apply records c in p and reports whether anything changed. Callers hold
+// store.Mu for writing.
+func Apply(p *store.Profile, c Claims) bool {
+	c = c.clean()
+	changed := false
+	set := func(field *string, v string) {
+		if v != "" && *field != v {
+			*field, changed = v, true
+		}
+	}
+	set(&p.DisplayName, c.Name)
+	set(&p.Email, c.Email)
+	set(&p.Locale, c.Locale)
+	if changed {
+		p.Updated()
+	}
+	return changed
+}
+
+// Record applies c to user's profile, creating it if needed, and saves the
+// store when something changed. It must run against the live data (see sandbox.Live).
+func Record(user, by string, c Claims) {
+	if c.clean().empty() {
+		return
+	}
+	store.Mu.Lock()
+	p, created := Ensure(user, by)
+	changed := Apply(p, c) || created
+	store.Mu.Unlock()
+	if created {
+		log.Printf("Provisioned profile for %s (%s)", user, by)
+	}
+	if changed {
+		store.Save()
+	}
+}
+
+var (
+	seenMu sync.Mutex
+	// seen is the claims last recorded for each user, so the store is only
+	// touched when a sign-in brings new ones.
+	seen = map[string]Claims{}
+)
+
+// Track records the caller's forwarded claims whenever they differ from the
+// ones last recorded for them.
+func Track(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		user := r.Header.Get("x-current-user")
+		if c := FromHeaders(r.Header); user != "" && !c.empty() {
+			seenMu.Lock()
+			prev, ok := seen[user]
+			seen[user] = c
+			seenMu.Unlock()
+			if !ok || prev != c {
+				sandbox.Live(func() { Record(user, "oidc", c) })
+			}
+		}
+		next.ServeHTTP(w, r)
+	})
+}
+
+// Forget drops the claims last recorded for user, e.g. when the user is
+// erased, so their next request records them again.
+func Forget(user string) {
+	seenMu.Lock()
+	delete(seen, user)
+	seenMu.Unlock()
+}
+
+// Reset drops the claims recorded for every user, e.g. when the store is
+// reset or restored from a backup.
+func Reset() {
+	seenMu.Lock()
+	seen = map[string]Claims{}
+	seenMu.Unlock()
+}
+
+// DisplayName returns the name to show for user: their display name, or the
+// username when there is none.
+func DisplayName(user string) string {
+	store.Mu.RLock()
+	defer store.Mu.RUnlock()
+	if p := store.Data.Profiles[user]; p != nil && p.DisplayName != "" {
+		return p.DisplayName
+	}
+	return user
+}
+
+// Card is what list responses carry of each user they name.
+type Card struct {
+	DisplayName string `json:"displayName"`
+	Color       string `json:"color"`
+}
+
+// Cards returns a card for each of users, falling back to the username and
+// a derived color for users without a profile.
+func Cards(users []string) map[string]Card {
+	cards := make(map[string]Card, len(users))
+	store.Mu.RLock()
+	defer store.Mu.RUnlock()
+	for _, u := range users {
+		if _, done := cards[u]; done || u == "" {
+			continue
+		}
+		card := Card{DisplayName: u, Color: ColorOf(u)}
+		if p := store.Data.Profiles[u]; p != nil {
+			if p.DisplayName != "" {
+				card.DisplayName = p.DisplayName
+			}
+			if p.Color != "" {
+				card.Color = p.Color
+			}
+		}
+		cards[u] = card
+	}
+	return cards
+}
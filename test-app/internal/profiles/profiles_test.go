@@ -0,0 +1,85 @@
+package profiles
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"testing"
+
+	"test-app/internal/store"
+)
+
+func useStore(t *testing.T) {
+	t.Helper()
+	prev, prevFile := store.Swap(&store.DataStore{}, filepath.Join(t.TempDir(), "dossiers.json"))
+	t.Cleanup(func() { store.Swap(prev, prevFile) })
+}
+
+func TestRecordAndCards(t *testing.T) {
+	useStore(t)
+	Record("alice", "oidc", Claims{Name: " Alice Martin ", Email: "alice@example.org", Locale: "fr"})
+	p := store.Data.Profiles["alice"]
+	if p == nil || p.DisplayName != "Alice Martin" || p.Email != "alice@example.org" || p.Locale != "fr" || p.Color != ColorOf("alice") {
+		t.Fatalf("profile = %+v", p)
+	}
+
+	// Malformed or missing claims keep what is stored.
+	Record("alice", "oidc", Claims{Email: "not-an-email", Locale: "fr\"><script>"})
+	if p.Email != "alice@example.org" || p.Locale != "fr" {
+		t.Errorf("bad claims overwrote the profile: %+v", p)
+	}
+	Record("bob", "oidc", Claims{})
+	if store.Data.Profiles["bob"] != nil {
+		t.Error("a profile was created without any claim")
+	}
+
+	cards := Cards([]string{"alice", "bob", "alice", ""})
+	if len(cards) != 2 || cards["alice"].DisplayName != "Alice Martin" || cards["bob"] != (Card{DisplayName: "bob", Color: ColorOf("bob")}) {
+		t.Errorf("cards = %+v", cards)
+	}
+	if DisplayName("alice") != "Alice Martin" || DisplayName("bob") != "bob" {
+		t.Errorf("display names = %q, %q", DisplayName("alice"), DisplayName("bob"))
+	}
+}
+
+func TestTrack(t *testing.T) {
+	useStore(t)
+	defer func() {
+		seenMu.Lock()
+		delete(seen, "carol")
+		seenMu.Unlock()
+	}()
+	handler := Track(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {}))
+	serve := func(name string) {
+		r := httptest.NewRequest("GET", "/api/me", nil)
+		r.Header.Set("x-current-user", "carol")
+		r.Header.Set("x-user-name", name)
+		handler.ServeHTTP(httptest.NewRecorder(), r)
+	}
+	serve("Carol")
+	p := store.Data.Profiles["carol"]
+	if p == nil || p.DisplayName != "Carol" || p.CreatedBy != "oidc" {
+		t.Fatalf("profile = %+v", p)
+	}
+	updated := p.UpdatedAt
+	serve("Carol")
+	if p.UpdatedAt != updated {
+		t.Error("unchanged claims touched the profile")
+	}
+	serve("Carol D.")
+	if p.DisplayName != "Carol D." {
+		t.Errorf("display name = %q after the claim changed", p.DisplayName)
+	}
+
+	// Once the profile is gone, forgetting the user records the same claims again.
+	for _, forget := range []func(){func() { Forget("carol") }, Reset} {
+		store.Mu.Lock()
+		delete(store.Data.Profiles, "carol")
+		store.Mu.Unlock()
+		forget()
+		serve("Carol D.")
+		if p := store.Data.Profiles["carol"]; p == nil || p.DisplayName != "Carol D." {
+			t.Errorf("profile = %+v after the cache was cleared", p)
+		}
+	}
+}
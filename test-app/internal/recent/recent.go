@@ -0,0 +1,165 @@
+// Package recent remembers, in memory, the dossiers each user opened last.
+// Entries are re-checked against OpenFGA as soon as a tuple deletion may have
+// cost their user access, and dropped when it did, so the list never offers a
+// dossier the user can no longer open.
+package recent
+
+import (
+	"context"
+	"strings"
+	"sync"
+	"time"
+
+	"test-app/internal/config"
+	"test-app/internal/events"
+	"test-app/internal/fga"
+	"test-app/internal/sandbox"
+)
+
+// Entry is one recently viewed dossier.
+type Entry struct {
+	Id       string    `json:"id"`
+	ViewedAt time.Time `json:"viewedAt"`
+}
+
+// everything is the scrub target that re-checks every entry, queued when a
+// guardianship or organization tuple is deleted.
+const everything = "*"
+
+var (
+	mu    sync.Mutex
+	views = map[string][]Entry{}
+	// queued holds the dossier ids (or everything) waiting to be re-checked.
+	queued = map[string]bool{}
+	queue  = make(chan string, 1024)
+)
+
+// Record notes that user just viewed dossier id, keeping the last
+// config.RecentViewsMax entries, most recent first.
+func Record(user, id string) {
+	if config.RecentViewsMax.Get() <= 0 {
+		return
+	}
+	mu.Lock()
+	defer mu.Unlock()
+	list := []Entry{{Id: id, ViewedAt: time.Now().UTC()}}
+	for _, e := range views[user] {
+		if e.Id != id && len(list) < config.RecentViewsMax.Get() {
+			list = append(list, e)
+		}
+	}
+	views[user] = list
+}
+
+// List returns the dossiers user viewed last, most recent first.
+func List(user string) []Entry {
+	mu.Lock()
+	defer mu.Unlock()
+	return append([]Entry{}, views[user]...)
+}
+
+// Forget drops dossier id from every user's list.
+func Forget(id string) {
+	mu.Lock()
+	defer mu.Unlock()
+	for user := range views {
+		drop(user, id)
+	}
+}
+
+// ForgetUser drops user's list, e.g. when the user is erased.
+func ForgetUser(user string) {
+	mu.Lock()
+	defer mu.Unlock()
+	delete(views, user)
+}
+
+// Reset drops every user's list, e.g. when the store is reset.
+func Reset() {
+	mu.Lock()
+	defer mu.Unlock()
+	views = map[string][]Entry{}
+}
+
+// drop removes id from user's list (mu held).
+func drop(user, id string) {
+	var kept []Entry
+	for _, e := range views[user] {
+		if e.Id != id {
+			kept = append(kept, e)
+		}
+	}
+	if len(kept) == 0 {
+		delete(views, user)
+	} else {
+		views[user] = kept
+	}
+}
+
+// Run re-checks entries affected by tuple deletions until the process exits.
+// A deleted dossier tuple re-checks that dossier for everyone who viewed it; a
+// deleted user or organization tuple (guardianship, membership) can change
+// access to any dossier, so it re-checks every entry. Deleted dossiers are
+// dropped without a check.
+func Run() {
+	events.Subscribe(func(e events.Event) {
+		typ, id, _ := strings.Cut(e.Object, ":")
+		switch {
+		case e.Type == events.ObjectDeleted && typ == fga.TypeDossier:
+			Forget(id)
+		case e.Type == events.TupleDeleted && typ == fga.TypeDossier:
+			enqueue(id)
+		case e.Type == events.TupleDeleted && (typ == fga.TypeUser || typ == fga.TypeOrganization):
+			enqueue(everything)
+		}
+	})
+	for target := range queue {
+		mu.Lock()
+		delete(queued, target)
+		mu.Unlock()
+		sandbox.Live(func() { scrub(target) })
+	}
+}
+
+func enqueue(target string) {
+	mu.Lock()
+	defer mu.Unlock()
+	if queued[target] {
+		return
+	}
+	select {
+	case queue <- target:
+		queued[target] = true
+	default:
+		// A full queue already holds plenty of work; fall back to a full re-check.
+		if !queued[everything] {
+			queued[everything] = true
+			go func() { queue <- everything }()
+		}
+	}
+}
+
+// scrub re-checks the entries for dossier target, or every entry for
+// everything, and drops those whose user can no longer view the dossier.
+func scrub(target string) {
+	type pair struct{ user, id string }
+	var pairs []pair
+	mu.Lock()
+	for user, list := range views {
+		for _, e := range list {
+			if target == everything || e.Id == target {
+				pairs = append(pairs, pair{user, e.Id})
+			}
+		}
+	}
+	mu.Unlock()
+	for _, p := range pairs {
+		// An outage is not a denial: keep the entry until OpenFGA answers.
+		if allowed, err := fga.Allowed(context.Background(), fga.UserRef(p.user), "viewer", fga.ObjectRef(fga.TypeDossier, p.id)); allowed || err != nil {
+			continue
+		}
+		mu.Lock()
+		drop(p.user, p.id)
+		mu.Unlock()
+	}
+}
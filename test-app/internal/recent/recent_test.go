@@ -0,0 +1,89 @@
+package recent
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"test-app/internal/config"
+	"test-app/internal/store"
+)
+
+func reset() {
+	mu.Lock()
+	views = map[string][]Entry{}
+	queued = map[string]bool{}
+	mu.Unlock()
+}
+
+func ids(user string) []string {
+	var out []string
+	for _, e := range List(user) {
+		out = append(out, e.Id)
+	}
+	return out
+}
+
+func TestRecordKeepsLastN(t *testing.T) {
+	origMax := config.RecentViewsMax.Get()
+	config.RecentViewsMax.Set(3)
+	defer config.RecentViewsMax.Set(origMax)
+	reset()
+	defer reset()
+
+	for _, id := range []string{"d1", "d2", "d3", "d1", "d4"} {
+		Record("alice", id)
+	}
+	if got := ids("alice"); len(got) != 3 || got[0] != "d4" || got[1] != "d1" || got[2] != "d3" {
+		t.Errorf("recent = %v, want [d4 d1 d3]", got)
+	}
+	Forget("d1")
+	if got := ids("alice"); len(got) != 2 || got[0] != "d4" {
+		t.Errorf("after Forget: %v", got)
+	}
+}
+
+func TestScrubDropsRevokedEntries(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var req struct {
+			TupleKey store.TupleKey `json:"tuple_key"`
+		}
+		json.NewDecoder(r.Body).Decode(&req)
+		// bob lost access to d1; everything else is still viewable.
+		allowed := !(req.TupleKey.User == "user:bob" && req.TupleKey.Object == "dossier:d1")
+		json.NewEncoder(w).Encode(map[string]interface{}{"allowed": allowed})
+	}))
+	defer server.Close()
+	origURL, origMax := config.OpenfgaURL, config.RecentViewsMax.Get()
+	config.OpenfgaURL = server.URL
+	config.RecentViewsMax.Set(10)
+	defer func() {
+		config.OpenfgaURL = origURL
+		config.RecentViewsMax.Set(origMax)
+	}()
+	reset()
+	defer reset()
+
+	Record("alice", "d1")
+	Record("bob", "d1")
+	Record("bob", "d2")
+
+	scrub("d2")
+	if got := ids("bob"); len(got) != 2 {
+		t.Errorf("scrubbing d2 should keep bob's entries, got %v", got)
+	}
+	scrub("d1")
+	if got := ids("bob"); len(got) != 1 || got[0] != "d2" {
+		t.Errorf("bob = %v, want [d2]", got)
+	}
+	if got := ids("alice"); len(got) != 1 {
+		t.Errorf("alice still has access, got %v", got)
+	}
+	enqueue("d1")
+	enqueue("d1")
+	if len(queue) != 1 {
+		t.Errorf("queued %d scrubs, want 1", len(queue))
+	}
+	<-queue
+}
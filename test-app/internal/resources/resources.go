@@ -0,0 +1,230 @@
+// Package resources is a registry of FGA-protected resource types. Registering
+// a type is enough for the generic handlers to serve CRUD, relation and list
+// endpoints for it under /api/resources/{plural}.
+package resources
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"regexp"
+	"sort"
+	"sync"
+)
+
+// Field describes one string attribute of a resource.
+type Field struct {
+	Name     string   `json:"name"`
+	Required bool     `json:"required,omitempty"`
+	Enum     []string `json:"enum,omitempty"`
+}
+
+// Type describes a resource type and how its relations grant access.
+// Owners can always view and edit; Editors and Viewers list the assignable
+// relations that grant edit or view access on top of that.
+type Type struct {
+	Name   string `json:"name"`
+	Plural string `json:"plural"`
+	// Label is the page title and nav entry of the type's UI page; defaults to Plural.
+	Label   string   `json:"label,omitempty"`
+	Fields  []Field  `json:"fields"`
+	Editors []string `json:"editors,omitempty"`
+	Viewers []string `json:"viewers,omitempty"`
+	// OrgParent lets a resource belong to an organization whose members can view it.
+	OrgParent bool `json:"orgParent,omitempty"`
+	// GuardianInherit lets the guardians of an owner view the resource.
+	GuardianInherit bool `json:"guardianInherit,omitempty"`
+	// Lien names an assignable relation whose holders have a claim on the
+	// resource: while one is granted the resource cannot be deleted, and only
+	// the holder can release it.
+	Lien string `json:"lien,omitempty"`
+}
+
+// Assignable returns the relations that can be granted to users.
+func (t *Type) Assignable() []string {
+	return append(append([]string{}, t.Editors...), t.Viewers...)
+}
+
+// Field returns the named field definition.
+func (t *Type) Field(name string) (Field, bool) {
+	for _, f := range t.Fields {
+		if f.Name == name {
+			return f, true
+		}
+	}
+	return Field{}, false
+}
+
+// Validate checks fields against the type definition. When partial is set,
+// missing required fields are allowed (for updates).
+func (t *Type) Validate(fields map[string]string, partial bool) error {
+	for name, value := range fields {
+		f, ok := t.Field(name)
+		if !ok {
+			return fmt.Errorf("unknown field %q", name)
+		}
+		if len(f.Enum) > 0 && !containsString(f.Enum, value) {
+			return fmt.Errorf("%s must be one of: %v", name, f.Enum)
+		}
+	}
+	if !partial {
+		for _, f := range t.Fields {
+			if f.Required && fields[f.Name] == "" {
+				return fmt.Errorf("%s is required", f.Name)
+			}
+		}
+	}
+	return nil
+}
+
+var (
+	mu       sync.RWMutex
+	registry = map[string]*Type{}
+	namePat  = regexp.MustCompile(`^[a-z][a-z0-9_]*$`)
+	reserved = map[string]bool{"user": true, "organization": true, "dossier": true}
+)
+
+// Register adds a resource type. Names must be valid FGA type names and must
+// not clash with the built-in types or an already registered type.
+func Register(t Type) error {
+	if !namePat.MatchString(t.Name) || !namePat.MatchString(t.Plural) {
+		return fmt.Errorf("invalid resource type name %q/%q", t.Name, t.Plural)
+	}
+	if reserved[t.Name] {
+		return fmt.Errorf("resource type %q is built in", t.Name)
+	}
+	for _, rel := range t.Assignable() {
+		if !namePat.MatchString(rel) || rel == "owner" || rel == "viewer" || rel == "editor" || rel == "org_parent" {
+			return fmt.Errorf("invalid assignable relation %q", rel)
+		}
+	}
+	if t.Lien != "" && !containsString(t.Assignable(), t.Lien) {
+		return fmt.Errorf("lien relation %q is not assignable", t.Lien)
+	}
+	if t.Label == "" {
+		t.Label = t.Plural
+	}
+	mu.Lock()
+	defer mu.Unlock()
+	for _, existing := range registry {
+		if existing.Name == t.Name || existing.Plural == t.Plural {
+			return fmt.Errorf("resource type %q already registered", t.Name)
+		}
+	}
+	registry[t.Name] = &t
+	return nil
+}
+
+// Lookup returns the registered type with the given name.
+func Lookup(name string) (*Type, bool) {
+	mu.RLock()
+	defer mu.RUnlock()
+	t, ok := registry[name]
+	return t, ok
+}
+
+// ByPlural returns the registered type served under the given path segment.
+func ByPlural(plural string) (*Type, bool) {
+	mu.RLock()
+	defer mu.RUnlock()
+	for _, t := range registry {
+		if t.Plural == plural {
+			return t, true
+		}
+	}
+	return nil, false
+}
+
+// Types returns every registered type, sorted by name.
+func Types() []*Type {
+	mu.RLock()
+	defer mu.RUnlock()
+	out := make([]*Type, 0, len(registry))
+	for _, t := range registry {
+		out = append(out, t)
+	}
+	sort.Slice(out, func(i, j int) bool { return out[i].Name < out[j].Name })
+	return out
+}
+
+// LoadFile registers every type listed in a JSON file.
+func LoadFile(path string) error {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return err
+	}
+	var types []Type
+	if err := json.Unmarshal(data, &types); err != nil {
+		return err
+	}
+	for _, t := range types {
+		if err := Register(t); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// TypeDefinition renders the OpenFGA type definition backing t, in the same
+// JSON shape infra/openfga/init.js writes for dossiers.
+func TypeDefinition(t *Type) map[string]interface{} {
+	this := map[string]interface{}{"this": map[string]interface{}{}}
+	computed := func(rel string) map[string]interface{} {
+		return map[string]interface{}{"computedUserset": map[string]interface{}{"relation": rel}}
+	}
+	ttu := func(tupleset, rel string) map[string]interface{} {
+		return map[string]interface{}{"tupleToUserset": map[string]interface{}{
+			"tupleset":        map[string]interface{}{"relation": tupleset},
+			"computedUserset": map[string]interface{}{"relation": rel},
+		}}
+	}
+	userType := []map[string]interface{}{{"type": "user"}}
+
+	relations := map[string]interface{}{"owner": this}
+	metadata := map[string]interface{}{"owner": map[string]interface{}{"directly_related_user_types": userType}}
+	for _, rel := range t.Assignable() {
+		relations[rel] = this
+		metadata[rel] = map[string]interface{}{"directly_related_user_types": userType}
+	}
+
+	editor := []interface{}{computed("owner")}
+	for _, rel := range t.Editors {
+		editor = append(editor, computed(rel))
+	}
+	viewer := []interface{}{computed("editor")}
+	for _, rel := range t.Viewers {
+		viewer = append(viewer, computed(rel))
+	}
+	if t.OrgParent {
+		relations["org_parent"] = this
+		metadata["org_parent"] = map[string]interface{}{"directly_related_user_types": []map[string]interface{}{{"type": "organization"}}}
+		viewer = append(viewer, ttu("org_parent", "member"))
+	}
+	if t.GuardianInherit {
+		viewer = append(viewer, ttu("owner", "guardian"))
+	}
+	relations["editor"] = map[string]interface{}{"union": map[string]interface{}{"child": editor}}
+	relations["viewer"] = map[string]interface{}{"union": map[string]interface{}{"child": viewer}}
+
+	return map[string]interface{}{
+		"type":      t.Name,
+		"relations": relations,
+		"metadata":  map[string]interface{}{"relations": metadata},
+	}
+}
+
+// reset clears the registry (for tests).
+func reset() {
+	mu.Lock()
+	defer mu.Unlock()
+	registry = map[string]*Type{}
+}
+
+func containsString(list []string, s string) bool {
+	for _, v := range list {
+		if v == s {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,84 @@
+package resources
+
+import "testing"
+
+func TestRegister_Validation(t *testing.T) {
+	reset()
+	defer reset()
+
+	if err := Register(Type{Name: "dossier", Plural: "dossiers"}); err == nil {
+		t.Error("registering a built-in type should fail")
+	}
+	if err := Register(Type{Name: "Vehicle", Plural: "vehicles"}); err == nil {
+		t.Error("invalid FGA type name should fail")
+	}
+	if err := Register(Type{Name: "vehicle", Plural: "vehicles", Editors: []string{"owner"}}); err == nil {
+		t.Error("owner is not assignable")
+	}
+	if err := Register(Type{Name: "vehicle", Plural: "vehicles", Viewers: []string{"driver"}}); err != nil {
+		t.Fatalf("Register: %v", err)
+	}
+	if err := Register(Type{Name: "car", Plural: "vehicles"}); err == nil {
+		t.Error("duplicate plural should fail")
+	}
+	if got, ok := ByPlural("vehicles"); !ok || got.Name != "vehicle" {
+		t.Errorf("ByPlural(vehicles) = %v, %v", got, ok)
+	}
+}
+
+func TestType_Validate(t *testing.T) {
+	typ := Type{Name: "vehicle", Plural: "vehicles", Fields: []Field{
+		{Name: "plate", Required: true},
+		{Name: "fuel", Enum: []string{"petrol", "electric"}},
+	}}
+	if err := typ.Validate(map[string]string{"fuel": "electric"}, false); err == nil {
+		t.Error("missing required plate should fail")
+	}
+	if err := typ.Validate(map[string]string{"fuel": "electric"}, true); err != nil {
+		t.Errorf("partial update: %v", err)
+	}
+	if err := typ.Validate(map[string]string{"plate": "1-ABC-123", "fuel": "coal"}, false); err == nil {
+		t.Error("value outside enum should fail")
+	}
+	if err := typ.Validate(map[string]string{"plate": "1-ABC-123", "color": "red"}, false); err == nil {
+		t.Error("unknown field should fail")
+	}
+}
+
+func TestTypeDefinition(t *testing.T) {
+	typ := &Type{Name: "vehicle", Plural: "vehicles", Editors: []string{"co_driver"}, Viewers: []string{"passenger"}, OrgParent: true}
+	def := TypeDefinition(typ)
+	rels := def["relations"].(map[string]interface{})
+	for _, rel := range []string{"owner", "co_driver", "passenger", "org_parent", "editor", "viewer"} {
+		if _, ok := rels[rel]; !ok {
+			t.Errorf("relation %s missing from type definition", rel)
+		}
+	}
+	viewer := rels["viewer"].(map[string]interface{})["union"].(map[string]interface{})["child"].([]interface{})
+	if len(viewer) != 3 {
+		t.Errorf("viewer children = %d, want editor, passenger and org_parent->member", len(viewer))
+	}
+}
+
+func TestRegister_Lien(t *testing.T) {
+	reset()
+	defer reset()
+
+	if err := Register(Type{Name: "vehicle", Plural: "vehicles", Viewers: []string{"insurer"}, Lien: "lienholder"}); err == nil {
+		t.Error("a lien relation that is not assignable should fail")
+	}
+	// The vehicle registration demo shipped with docker-compose.
+	if err := LoadFile("../../../infra/resources/types.json"); err != nil {
+		t.Fatalf("LoadFile: %v", err)
+	}
+	v, ok := ByPlural("vehicles")
+	if !ok || v.Lien != "lienholder" || v.Label != "Vehicles" {
+		t.Fatalf("vehicle type = %+v", v)
+	}
+	if err := Register(Type{Name: "boat", Plural: "boats"}); err != nil {
+		t.Fatalf("Register: %v", err)
+	}
+	if b, _ := Lookup("boat"); b.Label != "boats" {
+		t.Errorf("label = %q, want the plural by default", b.Label)
+	}
+}
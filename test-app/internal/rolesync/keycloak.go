@@ -0,0 +1,121 @@
+package rolesync
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+
+	"test-app/internal/config"
+	"test-app/internal/users"
+)
+
+var keycloakClient = &http.Client{Timeout: 10 * time.Second}
+
+// pageSize is how many role members are asked of the admin API at a time.
+const pageSize = 100
+
+// roleHolders returns the usernames holding each role of mappings, read
+// from the Keycloak admin API as the KeycloakSyncClientId service account.
+func roleHolders(mappings []Mapping) (map[string][]string, error) {
+	if config.KeycloakURL == "" {
+		return nil, errors.New("KEYCLOAK_URL is not set")
+	}
+	token, err := serviceToken()
+	if err != nil {
+		return nil, err
+	}
+	admin := strings.TrimSuffix(config.KeycloakURL, "/") + "/admin/realms/" + url.PathEscape(config.KeycloakRealm)
+	holders := map[string][]string{}
+	for _, m := range mappings {
+		if _, done := holders[m.Role]; done {
+			continue
+		}
+		path := admin + "/roles/" + url.PathEscape(m.Role) + "/users"
+		if client, role, ok := strings.Cut(m.Role, "/"); ok {
+			id, err := clientId(admin, token, client)
+			if err != nil {
+				return nil, err
+			}
+			path = admin + "/clients/" + url.PathEscape(id) + "/roles/" + url.PathEscape(role) + "/users"
+		}
+		names := []string{}
+		for first := 0; ; first += pageSize {
+			var page []struct {
+				Username string `json:"username"`
+			}
+			if err := getJSON(fmt.Sprintf("%s?first=%d&max=%d", path, first, pageSize), token, &page); err != nil {
+				return nil, fmt.Errorf("members of role %s: %w", m.Role, err)
+			}
+			for _, u := range page {
+				if name, err := users.Normalize(u.Username); err == nil && name != "" {
+					names = append(names, name)
+				}
+			}
+			if len(page) < pageSize {
+				break
+			}
+		}
+		holders[m.Role] = names
+	}
+	return holders, nil
+}
+
+// serviceToken obtains an admin API token with the client credentials grant.
+func serviceToken() (string, error) {
+	form := url.Values{
+		"grant_type":    {"client_credentials"},
+		"client_id":     {config.KeycloakSyncClientId},
+		"client_secret": {config.Secret(config.KeycloakSyncClientSecret)},
+	}
+	endpoint := strings.TrimSuffix(config.KeycloakURL, "/") + "/realms/" + url.PathEscape(config.KeycloakRealm) + "/protocol/openid-connect/token"
+	resp, err := keycloakClient.PostForm(endpoint, form)
+	if err != nil {
+		return "", err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return "", fmt.Errorf("Keycloak token endpoint returned %d", resp.StatusCode)
+	}
+	var body struct {
+		AccessToken string `json:"access_token"`
+	}
+	if err := json.NewDecoder(resp.Body).Decode(&body); err != nil {
+		return "", err
+	}
+	return body.AccessToken, nil
+}
+
+// clientId resolves a client's clientId to the internal id the admin API uses.
+func clientId(admin, token, client string) (string, error) {
+	var clients []struct {
+		Id string `json:"id"`
+	}
+	if err := getJSON(admin+"/clients?clientId="+url.QueryEscape(client), token, &clients); err != nil {
+		return "", fmt.Errorf("client %s: %w", client, err)
+	}
+	if len(clients) == 0 {
+		return "", fmt.Errorf("client %s not found in realm %s", client, config.KeycloakRealm)
+	}
+	return clients[0].Id, nil
+}
+
+func getJSON(endpoint, token string, v interface{}) error {
+	req, err := http.NewRequest("GET", endpoint, nil)
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Authorization", "Bearer "+token)
+	resp, err := keycloakClient.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return fmt.Errorf("Keycloak admin API returned %d", resp.StatusCode)
+	}
+	return json.NewDecoder(resp.Body).Decode(v)
+}
@@ -0,0 +1,345 @@
+// Package rolesync bridges Keycloak roles to OpenFGA relations: each mapping
+// in ROLE_TUPLES makes the holders of a realm or client role members or
+// admins of an organization, e.g. "admin=admin@organization:platform". Roles
+// are applied when a user signs in with a new set of roles (the x-user-role
+// header OPA derives from the token) and, when KEYCLOAK_URL is set, on a
+// schedule from the Keycloak admin API, so revoked roles also lose their
+// tuples. Only memberships granted because of a role are ever revoked;
+// those added by hand are left alone.
+package rolesync
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"net/http"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"test-app/internal/audit"
+	"test-app/internal/config"
+	"test-app/internal/fga"
+	"test-app/internal/sandbox"
+	"test-app/internal/store"
+	"test-app/internal/users"
+)
+
+// Mapping grants Relation (member or admin) on organization OrgId to the
+// holders of Role. Client roles are written "clientId/role".
+type Mapping struct {
+	Role     string `json:"role"`
+	Relation string `json:"relation"`
+	OrgId    string `json:"orgId"`
+}
+
+// Key identifies the mapping in store.Data.RoleGrants.
+func (m Mapping) Key() string {
+	return m.Role + "=" + m.Relation + "@" + fga.ObjectRef(fga.TypeOrganization, m.OrgId)
+}
+
+// Parse reads a comma separated list of role=relation@organization:id mappings.
+func Parse(spec string) ([]Mapping, error) {
+	var out []Mapping
+	for _, item := range strings.Split(spec, ",") {
+		item = strings.TrimSpace(item)
+		if item == "" {
+			continue
+		}
+		role, target, ok := strings.Cut(item, "=")
+		relation, object, ok2 := strings.Cut(target, "@")
+		typ, orgId, ok3 := strings.Cut(object, ":")
+		if !ok || !ok2 || !ok3 || role == "" || orgId == "" {
+			return nil, fmt.Errorf("%q is not role=relation@organization:id", item)
+		}
+		if typ != fga.TypeOrganization || (relation != "member" && relation != "admin") {
+			return nil, fmt.Errorf("%q: only member or admin of an organization can be granted", item)
+		}
+		if !users.Valid(orgId) {
+			return nil, fmt.Errorf("%q: invalid organization id", item)
+		}
+		out = append(out, Mapping{Role: role, Relation: relation, OrgId: orgId})
+	}
+	return out, nil
+}
+
+// Mappings returns the configured mappings. ROLE_TUPLES is validated at
+// startup, so an error here means it is unset.
+func Mappings() []Mapping {
+	ms, _ := Parse(config.RoleTuples)
+	return ms
+}
+
+// RealmMappings returns the mappings of realm roles, the only ones the
+// x-user-role header carries.
+func RealmMappings() []Mapping {
+	var out []Mapping
+	for _, m := range Mappings() {
+		if !strings.Contains(m.Role, "/") {
+			out = append(out, m)
+		}
+	}
+	return out
+}
+
+// Change is one membership granted or revoked by a sync.
+type Change struct {
+	User    string  `json:"user"`
+	Mapping Mapping `json:"mapping"`
+	Granted bool    `json:"granted"`
+}
+
+// Apply makes user's memberships granted through mappings match roles, the
+// roles they hold now, and returns what changed. It must run against the
+// live data (see sandbox.Live).
+func Apply(ctx context.Context, user string, roles []string, mappings []Mapping) ([]Change, error) {
+	held := map[string]bool{}
+	for _, r := range roles {
+		held[r] = true
+	}
+	var changes []Change
+	var writes, deletes []store.TupleKey
+	store.Mu.RLock()
+	granted := store.Data.RoleGrants[user]
+	for _, m := range mappings {
+		tuple := store.TupleKey{User: fga.UserRef(user), Relation: m.Relation, Object: fga.ObjectRef(fga.TypeOrganization, m.OrgId)}
+		recorded := contains(granted, m.Key())
+		switch {
+		case held[m.Role] && !contains(orgList(store.Data.Organizations[m.OrgId], m.Relation), user):
+			changes = append(changes, Change{User: user, Mapping: m, Granted: true})
+			writes = append(writes, tuple)
+		case !held[m.Role] && recorded:
+			changes = append(changes, Change{User: user, Mapping: m})
+			deletes = append(deletes, tuple)
+		}
+	}
+	store.Mu.RUnlock()
+	if len(changes) == 0 {
+		return nil, nil
+	}
+	if err := fga.Write(ctx, writes, deletes); err != nil {
+		return nil, err
+	}
+
+	store.Mu.Lock()
+	if store.Data.RoleGrants == nil {
+		store.Data.RoleGrants = map[string][]string{}
+	}
+	for _, c := range changes {
+		m := c.Mapping
+		org := store.Data.Organizations[m.OrgId]
+		if org == nil {
+			org = &store.Organization{Name: m.OrgId, Members: []string{}, Admins: []string{}, Meta: store.NewMeta("role-sync")}
+			store.Data.Organizations[m.OrgId] = org
+		}
+		list := &org.Members
+		if m.Relation == "admin" {
+			list = &org.Admins
+		}
+		grants := store.Data.RoleGrants[user]
+		if c.Granted {
+			if !contains(*list, user) {
+				*list = append(*list, user)
+			}
+			if !contains(grants, m.Key()) {
+				grants = append(grants, m.Key())
+			}
+		} else {
+			*list = remove(*list, user)
+			grants = remove(grants, m.Key())
+		}
+		if len(grants) == 0 {
+			delete(store.Data.RoleGrants, user)
+		} else {
+			store.Data.RoleGrants[user] = grants
+		}
+		org.Updated()
+	}
+	store.Mu.Unlock()
+	store.Save()
+	for _, c := range changes {
+		decision, verb := "revoke", "revoked"
+		if c.Granted {
+			decision, verb = "grant", "granted"
+		}
+		audit.SendAuditLog("RoleSync", decision, fga.UserRef(user), c.Mapping.Relation, fga.ObjectRef(fga.TypeOrganization, c.Mapping.OrgId), "SYNC",
+			"Keycloak role "+c.Mapping.Role+" "+verb)
+	}
+	return changes, nil
+}
+
+func orgList(org *store.Organization, relation string) []string {
+	if org == nil {
+		return nil
+	}
+	if relation == "admin" {
+		return org.Admins
+	}
+	return org.Members
+}
+
+var (
+	seenMu sync.Mutex
+	// seen is the role header last applied for each user, so a user's roles
+	// are applied once per sign-in rather than on every request.
+	seen = map[string]string{}
+)
+
+// OnLogin applies the realm role mappings to the caller whenever their
+// x-user-role header differs from the one last applied for them, i.e. after
+// they signed in with a token carrying other roles.
+func OnLogin(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		user := r.Header.Get("x-current-user")
+		if user != "" && config.RoleTuples != "" && config.FgaReady {
+			header := r.Header.Get("x-user-role")
+			seenMu.Lock()
+			prev, ok := seen[user]
+			seen[user] = header
+			seenMu.Unlock()
+			if !ok || prev != header {
+				if err := ApplyLive(r.Context(), user, SplitRoles(header)); err != nil {
+					log.Printf("WARNING: role sync for %s: %v", user, err)
+					Forget(user)
+				}
+			}
+		}
+		next.ServeHTTP(w, r)
+	})
+}
+
+// ApplyLive runs Apply of the realm role mappings against the live data.
+func ApplyLive(ctx context.Context, user string, roles []string) error {
+	var err error
+	sandbox.Live(func() { _, err = Apply(ctx, user, roles, RealmMappings()) })
+	return err
+}
+
+// Forget drops the roles last applied for user, so their next request applies them again.
+func Forget(user string) {
+	seenMu.Lock()
+	delete(seen, user)
+	seenMu.Unlock()
+}
+
+// Reset drops the roles last applied for every user, e.g. when the store is
+// reset, so each user's next request applies them again.
+func Reset() {
+	seenMu.Lock()
+	seen = map[string]string{}
+	seenMu.Unlock()
+}
+
+// SplitRoles splits an x-user-role header ("user,admin") into roles.
+func SplitRoles(header string) []string {
+	var roles []string
+	for _, r := range strings.Split(header, ",") {
+		if r = strings.TrimSpace(r); r != "" {
+			roles = append(roles, r)
+		}
+	}
+	return roles
+}
+
+// Report is the outcome of a scheduled sync.
+type Report struct {
+	At      time.Time `json:"at"`
+	Users   int       `json:"users"`
+	Changes []Change  `json:"changes"`
+	Error   string    `json:"error,omitempty"`
+}
+
+var (
+	lastMu sync.Mutex
+	last   *Report
+)
+
+// LastReport returns the most recent scheduled or manual sync, or nil.
+func LastReport() *Report {
+	lastMu.Lock()
+	defer lastMu.Unlock()
+	return last
+}
+
+// Sync reads the holders of every mapped role from Keycloak and applies the
+// mappings to each of them and to every user holding a role grant.
+func Sync(ctx context.Context) Report {
+	rep := Report{At: time.Now().UTC(), Changes: []Change{}}
+	defer func() {
+		lastMu.Lock()
+		last = &rep
+		lastMu.Unlock()
+	}()
+	mappings := Mappings()
+	holders, err := roleHolders(mappings)
+	if err != nil {
+		rep.Error = err.Error()
+		return rep
+	}
+	sandbox.Live(func() {
+		roles := map[string][]string{}
+		for role, names := range holders {
+			for _, name := range names {
+				roles[name] = append(roles[name], role)
+			}
+		}
+		store.Mu.RLock()
+		for name := range store.Data.RoleGrants {
+			if _, ok := roles[name]; !ok {
+				roles[name] = nil
+			}
+		}
+		store.Mu.RUnlock()
+		names := make([]string, 0, len(roles))
+		for name := range roles {
+			names = append(names, name)
+		}
+		sort.Strings(names)
+		rep.Users = len(names)
+		for _, name := range names {
+			changes, err := Apply(ctx, name, roles[name], mappings)
+			if err != nil {
+				rep.Error = err.Error()
+				return
+			}
+			rep.Changes = append(rep.Changes, changes...)
+		}
+	})
+	return rep
+}
+
+// RunEvery syncs on a fixed interval until the process exits.
+func RunEvery(interval time.Duration) {
+	for {
+		time.Sleep(interval)
+		if !config.FgaReady {
+			continue
+		}
+		rep := Sync(context.Background())
+		if rep.Error != "" {
+			log.Printf("WARNING: role sync failed: %s", rep.Error)
+		} else if len(rep.Changes) > 0 {
+			log.Printf("Role sync: %d membership change(s) for %d user(s)", len(rep.Changes), rep.Users)
+		}
+	}
+}
+
+func contains(list []string, s string) bool {
+	for _, v := range list {
+		if v == s {
+			return true
+		}
+	}
+	return false
+}
+
+func remove(list []string, s string) []string {
+	out := []string{}
+	for _, v := range list {
+		if v != s {
+			out = append(out, v)
+		}
+	}
+	return out
+}
@@ -0,0 +1,154 @@
+package rolesync
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"test-app/internal/config"
+	"test-app/internal/store"
+)
+
+func TestParse(t *testing.T) {
+	ms, err := Parse("admin=admin@organization:platform, portal/auditor=member@organization:audit")
+	if err != nil || len(ms) != 2 || ms[1] != (Mapping{Role: "portal/auditor", Relation: "member", OrgId: "audit"}) {
+		t.Fatalf("Parse = %+v, %v", ms, err)
+	}
+	for _, bad := range []string{"admin", "admin=owner@organization:platform", "admin=admin@dossier:d1", "admin=member@organization:"} {
+		if _, err := Parse(bad); err == nil {
+			t.Errorf("Parse(%q) should fail", bad)
+		}
+	}
+}
+
+// fakeFGA records the tuples written and deleted through OpenFGA.
+func fakeFGA(t *testing.T) (writes, deletes *[]string) {
+	t.Helper()
+	writes, deletes = &[]string{}, &[]string{}
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var body struct {
+			Writes, Deletes struct {
+				TupleKeys []store.TupleKey `json:"tuple_keys"`
+			}
+		}
+		json.NewDecoder(r.Body).Decode(&body)
+		for _, tk := range body.Writes.TupleKeys {
+			*writes = append(*writes, tk.User+" "+tk.Relation+" "+tk.Object)
+		}
+		for _, tk := range body.Deletes.TupleKeys {
+			*deletes = append(*deletes, tk.User+" "+tk.Relation+" "+tk.Object)
+		}
+		json.NewEncoder(w).Encode(map[string]interface{}{})
+	}))
+	origURL, origData, origTuples := config.OpenfgaURL, store.Data, config.RoleTuples
+	config.OpenfgaURL = server.URL
+	store.Data = &store.DataStore{Organizations: map[string]*store.Organization{
+		"platform": {Name: "Platform", Members: []string{}, Admins: []string{"carol"}},
+	}}
+	t.Cleanup(func() {
+		server.Close()
+		config.OpenfgaURL, store.Data, config.RoleTuples = origURL, origData, origTuples
+	})
+	return writes, deletes
+}
+
+func TestApply(t *testing.T) {
+	writes, deletes := fakeFGA(t)
+	config.RoleTuples = "admin=admin@organization:platform,staff=member@organization:staff"
+
+	changes, err := Apply(context.Background(), "alice", []string{"admin", "staff"}, Mappings())
+	if err != nil || len(changes) != 2 {
+		t.Fatalf("Apply = %+v, %v", changes, err)
+	}
+	if strings.Join(*writes, ";") != "user:alice admin organization:platform;user:alice member organization:staff" {
+		t.Errorf("writes = %v", *writes)
+	}
+	if org := store.Data.Organizations["staff"]; org == nil || org.Members[0] != "alice" {
+		t.Errorf("staff organization = %+v, want it created with alice", org)
+	}
+	if changes, _ := Apply(context.Background(), "alice", []string{"admin", "staff"}, Mappings()); len(changes) != 0 {
+		t.Errorf("re-applying the same roles changed %+v", changes)
+	}
+
+	// Losing a role revokes only what the role granted: carol was made admin by hand.
+	if _, err := Apply(context.Background(), "alice", []string{"staff"}, Mappings()); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := Apply(context.Background(), "carol", nil, Mappings()); err != nil {
+		t.Fatal(err)
+	}
+	if strings.Join(*deletes, ";") != "user:alice admin organization:platform" {
+		t.Errorf("deletes = %v", *deletes)
+	}
+	if admins := store.Data.Organizations["platform"].Admins; len(admins) != 1 || admins[0] != "carol" {
+		t.Errorf("platform admins = %v, want carol only", admins)
+	}
+	if g := store.Data.RoleGrants["alice"]; len(g) != 1 || g[0] != "staff=member@organization:staff" {
+		t.Errorf("alice's role grants = %v", g)
+	}
+}
+
+func TestSync(t *testing.T) {
+	writes, deletes := fakeFGA(t)
+	config.RoleTuples = "admin=admin@organization:platform,portal/auditor=member@organization:platform"
+	store.Data.RoleGrants = map[string][]string{"dave": {"admin=admin@organization:platform"}}
+	store.Data.Organizations["platform"].Admins = []string{"carol", "dave"}
+
+	keycloak := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch {
+		case strings.HasSuffix(r.URL.Path, "/token"):
+			json.NewEncoder(w).Encode(map[string]string{"access_token": "t"})
+		case r.Header.Get("Authorization") != "Bearer t":
+			w.WriteHeader(401)
+		case strings.HasSuffix(r.URL.Path, "/clients"):
+			json.NewEncoder(w).Encode([]map[string]string{{"id": "c-1"}})
+		case r.URL.Path == "/admin/realms/AuthorizationRealm/roles/admin/users":
+			json.NewEncoder(w).Encode([]map[string]string{{"username": "Alice"}})
+		case r.URL.Path == "/admin/realms/AuthorizationRealm/clients/c-1/roles/auditor/users":
+			json.NewEncoder(w).Encode([]map[string]string{{"username": "bob"}})
+		default:
+			w.WriteHeader(404)
+		}
+	}))
+	defer keycloak.Close()
+	origURL := config.KeycloakURL
+	config.KeycloakURL = keycloak.URL
+	defer func() { config.KeycloakURL = origURL }()
+
+	rep := Sync(context.Background())
+	if rep.Error != "" || rep.Users != 3 || len(rep.Changes) != 3 {
+		t.Fatalf("report = %+v", rep)
+	}
+	if strings.Join(*writes, ";") != "user:alice admin organization:platform;user:bob member organization:platform" {
+		t.Errorf("writes = %v", *writes)
+	}
+	if strings.Join(*deletes, ";") != "user:dave admin organization:platform" {
+		t.Errorf("deletes = %v, want dave's role-granted admin revoked", *deletes)
+	}
+	if LastReport() == nil {
+		t.Error("the sync was not recorded")
+	}
+}
+
+func TestOnLogin(t *testing.T) {
+	writes, _ := fakeFGA(t)
+	config.RoleTuples = "admin=admin@organization:platform,portal/auditor=member@organization:platform"
+	origReady := config.FgaReady
+	config.FgaReady = true
+	defer func() { config.FgaReady = origReady }()
+	defer Forget("alice")
+
+	handler := OnLogin(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {}))
+	for i := 0; i < 2; i++ {
+		req := httptest.NewRequest("GET", "/api/me", nil)
+		req.Header.Set("x-current-user", "alice")
+		req.Header.Set("x-user-role", "user,admin")
+		handler.ServeHTTP(httptest.NewRecorder(), req)
+	}
+	if len(*writes) != 1 || (*writes)[0] != "user:alice admin organization:platform" {
+		t.Errorf("writes = %v, want one admin grant for the sign-in", *writes)
+	}
+}
@@ -0,0 +1,263 @@
+// Package sandbox runs requests against throwaway copies of the data store and
+// the OpenFGA store, so policy and data changes can be tried safely. A request
+// carrying the x-sandbox-id header is served with store.Data and the FGA store
+// swapped for the sandbox's own and the event bus muted.
+//
+// Those are process globals, so sandboxed requests are serialized: each one
+// holds the gate exclusively for as long as it runs, while live requests
+// share it. A sandboxed request therefore waits for every live request in
+// flight, and live requests wait for it. The wait for the gate is bounded by
+// GateWait, after which the sandboxed request is answered 503; the hold is
+// bounded by the handler itself, whose OpenFGA calls time out individually.
+package sandbox
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"errors"
+	"log"
+	"net/http"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"test-app/internal/events"
+	"test-app/internal/fga"
+	"test-app/internal/httputil"
+	"test-app/internal/i18n"
+	"test-app/internal/store"
+)
+
+// Header selects the sandbox a request runs against.
+const Header = "x-sandbox-id"
+
+// MaxSandboxes bounds how many sandboxes, each an OpenFGA store, may exist at once.
+const MaxSandboxes = 5
+
+// ErrTooMany is returned by Create when MaxSandboxes are already live.
+var ErrTooMany = errors.New("too many sandboxes")
+
+// GateWait is how long a sandboxed request waits for live requests to finish
+// before it is turned away.
+var GateWait = 10 * time.Second
+
+// Sandbox is a private copy of the data and tuples, discarded at ExpiresAt.
+type Sandbox struct {
+	Id        string    `json:"id"`
+	StoreId   string    `json:"storeId"`
+	ModelId   string    `json:"modelId"`
+	CreatedBy string    `json:"createdBy"`
+	CreatedAt time.Time `json:"createdAt"`
+	ExpiresAt time.Time `json:"expiresAt"`
+
+	dir  string
+	data *store.DataStore
+}
+
+var (
+	// gate is held for reading by live requests and for writing while a
+	// sandbox request has the globals swapped.
+	gate      sync.RWMutex
+	mu        sync.Mutex
+	sandboxes = map[string]*Sandbox{}
+)
+
+// Create copies the live data and tuples into a new sandbox that expires after ttl.
+func Create(user string, ttl time.Duration) (*Sandbox, error) {
+	mu.Lock()
+	n := len(sandboxes)
+	mu.Unlock()
+	if n >= MaxSandboxes {
+		return nil, ErrTooMany
+	}
+	b := make([]byte, 16)
+	if _, err := rand.Read(b); err != nil {
+		return nil, err
+	}
+	id := hex.EncodeToString(b)
+	dir, err := os.MkdirTemp("", "sandbox-"+id[:8]+"-")
+	if err != nil {
+		return nil, err
+	}
+	storeId, modelId, err := fga.CopyStore("sandbox-" + id)
+	if err != nil {
+		os.RemoveAll(dir)
+		return nil, err
+	}
+	now := time.Now().UTC()
+	sb := &Sandbox{
+		Id: id, StoreId: storeId, ModelId: modelId, CreatedBy: user,
+		CreatedAt: now, ExpiresAt: now.Add(ttl), dir: dir, data: store.Clone(),
+	}
+	mu.Lock()
+	sandboxes[id] = sb
+	mu.Unlock()
+	return sb, nil
+}
+
+// Get returns the sandbox id unless it is unknown or expired.
+func Get(id string) (*Sandbox, bool) {
+	mu.Lock()
+	defer mu.Unlock()
+	sb, ok := sandboxes[id]
+	if !ok || time.Now().After(sb.ExpiresAt) {
+		return nil, false
+	}
+	return sb, true
+}
+
+// List returns the live sandboxes, oldest first.
+func List() []*Sandbox {
+	mu.Lock()
+	defer mu.Unlock()
+	out := []*Sandbox{}
+	for _, sb := range sandboxes {
+		out = append(out, sb)
+	}
+	sort.Slice(out, func(i, j int) bool { return out[i].CreatedAt.Before(out[j].CreatedAt) })
+	return out
+}
+
+// Discard deletes a sandbox with its OpenFGA store and files. It must not be
+// called from inside a sandboxed request.
+func Discard(id string) error {
+	mu.Lock()
+	sb, ok := sandboxes[id]
+	delete(sandboxes, id)
+	mu.Unlock()
+	if !ok {
+		return os.ErrNotExist
+	}
+	os.RemoveAll(sb.dir)
+	return fga.DeleteStore(sb.StoreId)
+}
+
+// Route serves requests carrying Header against their sandbox and everything
+// else against the live data. Event streams skip the gate so a long-lived
+// connection cannot hold sandbox requests off indefinitely. A sandboxed
+// request that cannot take the gate within GateWait gets a 503.
+func Route(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		id := r.Header.Get(Header)
+		if strings.Contains(r.Header.Get("Accept"), "text/event-stream") {
+			next.ServeHTTP(w, r)
+			return
+		}
+		if id == "" || strings.HasPrefix(r.URL.Path, "/api/admin/sandbox") {
+			gate.RLock()
+			defer gate.RUnlock()
+			next.ServeHTTP(w, r.WithContext(context.WithValue(r.Context(), liveKey{}, true)))
+			return
+		}
+		// Backups live on disk outside the sandbox and would leak into, or
+		// overwrite, live data; a store or model switch would be undone when
+		// the sandbox's ids are swapped back out.
+		if strings.HasPrefix(r.URL.Path, "/api/admin/backups") || strings.HasPrefix(r.URL.Path, "/api/admin/fga/") || strings.HasPrefix(r.URL.Path, "/api/admin/model/") {
+			httputil.JSONError(w, i18n.T(r, "This endpoint is not available in a sandbox"), 400)
+			return
+		}
+		sb, ok := Get(id)
+		if !ok {
+			httputil.JSONError(w, i18n.T(r, "Sandbox not found or expired"), 404)
+			return
+		}
+
+		if !lockGate(GateWait) {
+			w.Header().Set("Retry-After", "1")
+			httputil.JSONError(w, i18n.T(r, "Sandbox busy, try again"), 503)
+			return
+		}
+		defer gate.Unlock()
+		prevData, prevFile := store.Swap(sb.data, filepath.Join(sb.dir, "dossiers.json"))
+		restoreFGA := fga.Use(sb.StoreId, sb.ModelId)
+		unmute := events.Mute()
+		defer func() {
+			unmute()
+			restoreFGA()
+			// Handlers may replace Data wholesale (e.g. a backup restore).
+			sb.data, _ = store.Swap(prevData, prevFile)
+		}()
+		w.Header().Set(Header, id)
+		next.ServeHTTP(w, r.WithContext(context.WithValue(r.Context(), exclusiveKey{}, true)))
+	})
+}
+
+// lockGate takes the gate for writing, giving up after wait. It polls TryLock
+// instead of blocking in Lock, which would also hold off every live request
+// arriving while it waits.
+func lockGate(wait time.Duration) bool {
+	deadline := time.Now().Add(wait)
+	for !gate.TryLock() {
+		if time.Now().After(deadline) {
+			return false
+		}
+		time.Sleep(5 * time.Millisecond)
+	}
+	return true
+}
+
+// Live runs fn against the live data, waiting out any sandboxed request. Background
+// jobs that read store.Data or OpenFGA outside a request use it.
+func Live(fn func()) {
+	gate.RLock()
+	defer gate.RUnlock()
+	fn()
+}
+
+// liveKey marks the context of a live request holding the gate for reading.
+type liveKey struct{}
+
+// exclusiveKey marks the context of a sandboxed request holding the gate for writing.
+type exclusiveKey struct{}
+
+// Exclusive runs fn while no other request or background job uses the data
+// or OpenFGA, for changes to state they read without locking, such as the
+// OpenFGA store and model ids in config. A live request served by Route gives
+// up its shared hold on the gate meanwhile and takes it back before Exclusive
+// returns; r is nil outside requests. A sandboxed request already holds the
+// gate exclusively, so fn runs straight away.
+func Exclusive(r *http.Request, fn func()) {
+	if r != nil && r.Context().Value(exclusiveKey{}) != nil {
+		fn()
+		return
+	}
+	held := r != nil && r.Context().Value(liveKey{}) != nil
+	if held {
+		gate.RUnlock()
+		defer gate.RLock()
+	}
+	gate.Lock()
+	defer gate.Unlock()
+	fn()
+}
+
+// LiveHandler serves every request through next against the live data, like
+// Live. It is for listeners outside the main handler chain, such as ext_authz,
+// whose checks must never see a sandbox's store or model.
+func LiveHandler(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		Live(func() { next.ServeHTTP(w, r) })
+	})
+}
+
+// RunJanitor discards expired sandboxes every interval.
+func RunJanitor(interval time.Duration) {
+	for {
+		time.Sleep(interval)
+		now := time.Now()
+		for _, sb := range List() {
+			if now.Before(sb.ExpiresAt) {
+				continue
+			}
+			var err error
+			Live(func() { err = Discard(sb.Id) })
+			if err != nil {
+				log.Printf("WARNING: discarding sandbox %s: %v", sb.Id, err)
+			}
+		}
+	}
+}
@@ -0,0 +1,200 @@
+package sandbox
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"test-app/internal/config"
+	"test-app/internal/store"
+)
+
+// fakeFGA answers the OpenFGA calls CopyStore and DeleteStore make.
+func fakeFGA(t *testing.T) (deleted *[]string) {
+	deleted = &[]string{}
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch {
+		case r.Method == "DELETE":
+			*deleted = append(*deleted, strings.TrimPrefix(r.URL.Path, "/stores/"))
+			w.WriteHeader(204)
+		case r.URL.Path == "/stores":
+			json.NewEncoder(w).Encode(map[string]string{"id": "sandbox-store"})
+		case strings.HasSuffix(r.URL.Path, "/authorization-models/live-model"):
+			json.NewEncoder(w).Encode(map[string]interface{}{"authorization_model": map[string]interface{}{"id": "live-model", "schema_version": "1.1"}})
+		case strings.HasSuffix(r.URL.Path, "/authorization-models"):
+			json.NewEncoder(w).Encode(map[string]string{"authorization_model_id": "sandbox-model"})
+		case strings.HasSuffix(r.URL.Path, "/read"):
+			json.NewEncoder(w).Encode(map[string]interface{}{"tuples": []interface{}{}})
+		default:
+			json.NewEncoder(w).Encode(map[string]interface{}{})
+		}
+	}))
+	origURL, origStore, origModel := config.OpenfgaURL, config.FgaStoreId, config.FgaModelId
+	config.OpenfgaURL, config.FgaStoreId, config.FgaModelId = srv.URL, "live-store", "live-model"
+	t.Cleanup(func() {
+		srv.Close()
+		config.OpenfgaURL, config.FgaStoreId, config.FgaModelId = origURL, origStore, origModel
+	})
+	return deleted
+}
+
+func TestRoute_SwapsDataAndStore(t *testing.T) {
+	deleted := fakeFGA(t)
+	origData := store.Data
+	defer func() { store.Data = origData }()
+	store.Data = &store.DataStore{Dossiers: map[string]*store.Dossier{"d1": {Title: "Live", Owners: []string{"alice"}}}}
+	live := store.Data
+
+	sb, err := Create("admin", time.Hour)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if sb.StoreId != "sandbox-store" || sb.ModelId != "sandbox-model" {
+		t.Errorf("sandbox ids = %s/%s", sb.StoreId, sb.ModelId)
+	}
+
+	var seenStore string
+	handler := Route(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		seenStore = config.FgaStoreId
+		store.Mu.Lock()
+		store.Data.Dossiers["d1"].Title = "Changed"
+		store.Mu.Unlock()
+		store.Save()
+	}))
+	req := httptest.NewRequest("POST", "/api/dossiers/d1", nil)
+	req.Header.Set(Header, sb.Id)
+	handler.ServeHTTP(httptest.NewRecorder(), req)
+
+	if seenStore != "sandbox-store" {
+		t.Errorf("sandboxed request used store %q", seenStore)
+	}
+	if store.Data != live || live.Dossiers["d1"].Title != "Live" || config.FgaStoreId != "live-store" {
+		t.Error("live data or store changed by a sandboxed request")
+	}
+	if _, err := os.Stat(filepath.Join(sb.dir, "dossiers.json")); err != nil {
+		t.Errorf("sandbox save not written to its own file: %v", err)
+	}
+	if sb.data.Dossiers["d1"].Title != "Changed" {
+		t.Error("sandbox lost its change")
+	}
+
+	w := httptest.NewRecorder()
+	req = httptest.NewRequest("GET", "/api/dossiers", nil)
+	req.Header.Set(Header, "unknown")
+	handler.ServeHTTP(w, req)
+	if w.Code != 404 {
+		t.Errorf("unknown sandbox status = %d, want 404", w.Code)
+	}
+
+	if err := Discard(sb.Id); err != nil {
+		t.Fatal(err)
+	}
+	if len(*deleted) != 1 || (*deleted)[0] != "sandbox-store" {
+		t.Errorf("deleted stores = %v", *deleted)
+	}
+	if _, err := os.Stat(sb.dir); !os.IsNotExist(err) {
+		t.Error("sandbox directory not removed")
+	}
+}
+
+func TestExclusive_FromLiveRequest(t *testing.T) {
+	handler := Route(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		ran := false
+		Exclusive(r, func() {
+			// No one else, not even a background job, holds the gate.
+			if gate.TryRLock() {
+				gate.RUnlock()
+				t.Error("gate shared during Exclusive")
+			}
+			ran = true
+		})
+		if !ran {
+			t.Error("Exclusive did not run its function")
+		}
+	}))
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		handler.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest("POST", "/api/admin/fga/config", nil))
+	}()
+	select {
+	case <-done:
+	case <-time.After(5 * time.Second):
+		t.Fatal("Exclusive deadlocked inside a live request")
+	}
+	// The request gave its hold back: the gate is free again.
+	if !gate.TryLock() {
+		t.Fatal("gate still held after the request")
+	}
+	gate.Unlock()
+
+	w := httptest.NewRecorder()
+	req := httptest.NewRequest("POST", "/api/admin/fga/config", nil)
+	req.Header.Set(Header, "any")
+	handler.ServeHTTP(w, req)
+	if w.Code != 400 {
+		t.Errorf("store switch in a sandbox: status = %d, want 400", w.Code)
+	}
+}
+
+func TestRoute_GateWait(t *testing.T) {
+	origWait := GateWait
+	GateWait = 50 * time.Millisecond
+	mu.Lock()
+	sandboxes["busy"] = &Sandbox{Id: "busy", ExpiresAt: time.Now().Add(time.Hour), dir: t.TempDir(), data: &store.DataStore{}}
+	mu.Unlock()
+	defer func() {
+		GateWait = origWait
+		mu.Lock()
+		delete(sandboxes, "busy")
+		mu.Unlock()
+	}()
+	ran := false
+	handler := Route(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		Exclusive(r, func() { ran = true })
+	}))
+
+	// A live request in flight keeps the sandboxed one waiting, then turned away.
+	gate.RLock()
+	waiting := make(chan *httptest.ResponseRecorder)
+	go func() {
+		w := httptest.NewRecorder()
+		req := httptest.NewRequest("GET", "/api/dossiers", nil)
+		req.Header.Set(Header, "busy")
+		handler.ServeHTTP(w, req)
+		waiting <- w
+	}()
+	// Live requests arriving meanwhile are not held off by the waiting one.
+	if !gate.TryRLock() {
+		t.Error("a waiting sandboxed request blocks live requests")
+	} else {
+		gate.RUnlock()
+	}
+	w := <-waiting
+	gate.RUnlock()
+	if w.Code != 503 || ran {
+		t.Errorf("busy gate: status = %d, ran = %v, want 503 without running", w.Code, ran)
+	}
+
+	// Once the gate is free it runs, and Exclusive within it does not wait on itself.
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		req := httptest.NewRequest("GET", "/api/dossiers", nil)
+		req.Header.Set(Header, "busy")
+		handler.ServeHTTP(httptest.NewRecorder(), req)
+	}()
+	select {
+	case <-done:
+	case <-time.After(5 * time.Second):
+		t.Fatal("Exclusive deadlocked inside a sandboxed request")
+	}
+	if !ran {
+		t.Error("sandboxed request did not run once the gate was free")
+	}
+}
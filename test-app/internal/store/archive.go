@@ -0,0 +1,83 @@
+package store
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"time"
+)
+
+// ArchivedContent is the cold-storage copy of a dossier's content. Metadata,
+// relations and tuples stay in the main store so the dossier remains listed.
+type ArchivedContent struct {
+	Content     string    `json:"content"`
+	ContentType string    `json:"contentType,omitempty"`
+	ArchivedAt  time.Time `json:"archivedAt"`
+}
+
+// archivePath returns the archive file for dossier id, next to the data file.
+func archivePath(id string) string {
+	return filepath.Join(filepath.Dir(dataFile), "archive", id+".json")
+}
+
+// Archive moves the content of dossier id into its archive file and clears it
+// from Data. Callers hold Mu for writing and call Save afterwards.
+func Archive(id string) error {
+	d, ok := Data.Dossiers[id]
+	if !ok {
+		return fmt.Errorf("dossier %s not found", id)
+	}
+	if d.ArchivedAt != nil {
+		return fmt.Errorf("dossier %s is already archived", id)
+	}
+	now := time.Now().UTC()
+	raw, _ := json.MarshalIndent(ArchivedContent{Content: sealContent(d.Content), ContentType: d.ContentType, ArchivedAt: now}, "", "  ")
+	path := archivePath(id)
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		return err
+	}
+	if err := writeAtomic(path, raw); err != nil {
+		return err
+	}
+	d.Content = ""
+	d.ArchivedAt = &now
+	d.Updated()
+	return nil
+}
+
+// Restore brings the archived content of dossier id back into Data. Callers
+// hold Mu for writing and call Save afterwards, then DropArchive once the save
+// succeeded: until then the archive file is the only copy of the content on disk.
+func Restore(id string) error {
+	d, ok := Data.Dossiers[id]
+	if !ok {
+		return fmt.Errorf("dossier %s not found", id)
+	}
+	if d.ArchivedAt == nil {
+		return fmt.Errorf("dossier %s is not archived", id)
+	}
+	raw, err := os.ReadFile(archivePath(id))
+	if err != nil {
+		return err
+	}
+	var archived ArchivedContent
+	if err := json.Unmarshal(raw, &archived); err != nil {
+		return fmt.Errorf("corrupt archive for dossier %s: %w", id, err)
+	}
+	content, err := openContent(archived.Content)
+	if err != nil {
+		return fmt.Errorf("archive for dossier %s: %w", id, err)
+	}
+	d.Content = content
+	d.ContentType = archived.ContentType
+	d.ArchivedAt = nil
+	d.Updated()
+	return nil
+}
+
+// DropArchive deletes the archive file of a dossier that no longer exists or
+// whose restored content was saved.
+func DropArchive(id string) {
+	os.Remove(archivePath(id))
+}
@@ -0,0 +1,183 @@
+package store
+
+import (
+	"log"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+)
+
+// SizeReport describes how large the persisted store has grown.
+type SizeReport struct {
+	DataFile     string         `json:"dataFile"`
+	DataBytes    int64          `json:"dataBytes"`
+	ArchiveFiles int            `json:"archiveFiles"`
+	ArchiveBytes int64          `json:"archiveBytes"`
+	Entities     map[string]int `json:"entities"`
+	// WarnBytes is the threshold passed to Size; OverThreshold is set when the data file exceeds it.
+	WarnBytes     int64 `json:"warnBytes,omitempty"`
+	OverThreshold bool  `json:"overThreshold,omitempty"`
+}
+
+// Size reports the size of the data file and the archive directory, and how
+// many entities of each kind Data holds. warnBytes is the data file size past
+// which OverThreshold is set; 0 disables the check.
+func Size(warnBytes int64) SizeReport {
+	Mu.RLock()
+	rep := SizeReport{
+		DataFile: dataFile,
+		Entities: map[string]int{
+			"dossiers":             len(Data.Dossiers),
+			"resources":            len(Data.Resources),
+			"organizations":        len(Data.Organizations),
+			"guardianships":        len(Data.Guardianships),
+			"guardianshipRequests": len(Data.GuardianshipRequests),
+			"joinRequests":         len(Data.JoinRequests),
+			"accessRequests":       len(Data.AccessRequests),
+		},
+		WarnBytes: warnBytes,
+	}
+	Mu.RUnlock()
+	if fi, err := os.Stat(dataFile); err == nil {
+		rep.DataBytes = fi.Size()
+	}
+	entries, _ := os.ReadDir(filepath.Dir(archivePath("x")))
+	for _, e := range entries {
+		if fi, err := e.Info(); err == nil && !e.IsDir() {
+			rep.ArchiveFiles++
+			rep.ArchiveBytes += fi.Size()
+		}
+	}
+	rep.OverThreshold = warnBytes > 0 && rep.DataBytes > warnBytes
+	return rep
+}
+
+// WarnIfLarge logs a warning when the data file exceeds warnBytes. The JSON
+// file is rewritten in full on every save, so past a few megabytes a database
+// backend is the better fit.
+func WarnIfLarge(warnBytes int64) {
+	if rep := Size(warnBytes); rep.OverThreshold {
+		log.Printf("WARNING: data file %s is %d bytes, over the %d byte threshold; every save rewrites it in full, consider moving the store to a database backend", rep.DataFile, rep.DataBytes, warnBytes)
+	}
+}
+
+// orphanArchives are the archive files without a dossier found by the last
+// Compact (guarded by Mu).
+var orphanArchives = map[string]bool{}
+
+// CompactReport summarises what Compact removed.
+type CompactReport struct {
+	DecidedRequests    int `json:"decidedRequests"`
+	EmptyGuardianships int `json:"emptyGuardianships"`
+	OrphanArchives     int `json:"orphanArchives"`
+	StaleFavorites     int `json:"staleFavorites"`
+}
+
+// Removed is the number of entries Compact dropped.
+func (c CompactReport) Removed() int {
+	return c.DecidedRequests + c.EmptyGuardianships + c.OrphanArchives + c.StaleFavorites
+}
+
+// Compact drops what the store keeps growing but no longer needs: guardianship,
+// join and access requests decided more than retention ago, wards left without
+// guardians, and favorites and archive files of dossiers that no longer exist.
+// Pending requests are always kept. Runs should be further apart than the undo
+// window. The caller is responsible for calling Save afterwards.
+func Compact(retention time.Duration) CompactReport {
+	cutoff := time.Now().Add(-retention)
+	var rep CompactReport
+	Mu.Lock()
+	defer Mu.Unlock()
+
+	guardianshipReqs := []GuardianshipRequest{}
+	for _, req := range Data.GuardianshipRequests {
+		if req.Status != "pending" && req.UpdatedAt.Before(cutoff) {
+			rep.DecidedRequests++
+			continue
+		}
+		guardianshipReqs = append(guardianshipReqs, req)
+	}
+	Data.GuardianshipRequests = guardianshipReqs
+
+	var joins []JoinRequest
+	for _, req := range Data.JoinRequests {
+		if req.Status != "pending" && req.UpdatedAt.Before(cutoff) {
+			rep.DecidedRequests++
+			continue
+		}
+		joins = append(joins, req)
+	}
+	Data.JoinRequests = joins
+
+	var access []AccessRequest
+	for _, req := range Data.AccessRequests {
+		if req.Status != "pending" && req.UpdatedAt.Before(cutoff) {
+			rep.DecidedRequests++
+			continue
+		}
+		access = append(access, req)
+	}
+	Data.AccessRequests = access
+
+	for ward, guardians := range Data.Guardianships {
+		if len(guardians) == 0 {
+			delete(Data.Guardianships, ward)
+			rep.EmptyGuardianships++
+		}
+	}
+
+	for user, ids := range Data.Favorites {
+		var kept []string
+		for _, id := range ids {
+			if _, exists := Data.Dossiers[id]; exists {
+				kept = append(kept, id)
+			} else {
+				rep.StaleFavorites++
+			}
+		}
+		if len(kept) == 0 {
+			delete(Data.Favorites, user)
+		} else {
+			Data.Favorites[user] = kept
+		}
+	}
+
+	// An archive is removed only once it was already orphaned at the previous
+	// run, so the archive of a deletion that can still be undone survives.
+	seen := map[string]bool{}
+	entries, _ := os.ReadDir(filepath.Dir(archivePath("x")))
+	for _, e := range entries {
+		id, ok := strings.CutSuffix(e.Name(), ".json")
+		if !ok || e.IsDir() {
+			continue
+		}
+		if _, exists := Data.Dossiers[id]; exists {
+			continue
+		}
+		if orphanArchives[id] && os.Remove(archivePath(id)) == nil {
+			rep.OrphanArchives++
+			continue
+		}
+		seen[id] = true
+	}
+	orphanArchives = seen
+	return rep
+}
+
+// CompactEvery runs Compact every interval, saving when anything was removed,
+// and warns when the data file grows past warnBytes. It accepts live, which
+// runs its argument against the live data, to avoid importing the sandbox
+// package directly.
+func CompactEvery(interval, retention time.Duration, warnBytes int64, live func(func())) {
+	for {
+		time.Sleep(interval)
+		live(func() {
+			if rep := Compact(retention); rep.Removed() > 0 {
+				Save()
+				log.Printf("Compacted store: %d decided requests, %d empty guardianships, %d stale favorites, %d orphan archives", rep.DecidedRequests, rep.EmptyGuardianships, rep.StaleFavorites, rep.OrphanArchives)
+			}
+			WarnIfLarge(warnBytes)
+		})
+	}
+}
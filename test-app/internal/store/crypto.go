@@ -0,0 +1,214 @@
+package store
+
+import (
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/rand"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"log"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+)
+
+// sealedPrefix marks dossier content encrypted at rest: "enc:v1:<key id>:<base64 nonce+ciphertext>".
+const sealedPrefix = "enc:v1:"
+
+var (
+	keysMu sync.RWMutex
+	// contentKeys holds an AES-GCM cipher per key id; activeKey seals new
+	// content and the others are kept to open content sealed before a rotation.
+	contentKeys = map[string]cipher.AEAD{}
+	activeKey   string
+)
+
+// SetContentKeys configures encryption at rest from a comma-separated list of
+// "id:base64key" AES-256 keys, the first of which encrypts. An empty spec
+// turns encryption off: content is then written in plaintext, but content
+// sealed earlier can only be read while its key is configured.
+func SetContentKeys(spec string) error {
+	keys := map[string]cipher.AEAD{}
+	active := ""
+	for _, part := range strings.Split(spec, ",") {
+		part = strings.TrimSpace(part)
+		if part == "" {
+			continue
+		}
+		id, encoded, ok := strings.Cut(part, ":")
+		if !ok || id == "" || strings.Contains(id, ":") {
+			return fmt.Errorf("content key %q: want id:base64key", part)
+		}
+		raw, err := base64.StdEncoding.DecodeString(encoded)
+		if err != nil || len(raw) != 32 {
+			return fmt.Errorf("content key %s: want 32 base64-encoded bytes", id)
+		}
+		block, _ := aes.NewCipher(raw)
+		aead, _ := cipher.NewGCM(block)
+		if _, dup := keys[id]; dup {
+			return fmt.Errorf("content key %s listed twice", id)
+		}
+		keys[id] = aead
+		if active == "" {
+			active = id
+		}
+	}
+	keysMu.Lock()
+	contentKeys, activeKey = keys, active
+	keysMu.Unlock()
+	return nil
+}
+
+// sealContent encrypts s with the active key. Empty content, content already
+// sealed (e.g. under a key no longer configured) and content written while
+// encryption is off are returned unchanged.
+func sealContent(s string) string {
+	if s == "" || strings.HasPrefix(s, sealedPrefix) {
+		return s
+	}
+	keysMu.RLock()
+	aead, id := contentKeys[activeKey], activeKey
+	keysMu.RUnlock()
+	if aead == nil {
+		return s
+	}
+	nonce := make([]byte, aead.NonceSize())
+	rand.Read(nonce)
+	sealed := aead.Seal(nonce, nonce, []byte(s), []byte(id))
+	return sealedPrefix + id + ":" + base64.StdEncoding.EncodeToString(sealed)
+}
+
+// openContent decrypts content sealed by sealContent; plaintext is returned as is.
+func openContent(s string) (string, error) {
+	rest, ok := strings.CutPrefix(s, sealedPrefix)
+	if !ok {
+		return s, nil
+	}
+	id, encoded, _ := strings.Cut(rest, ":")
+	keysMu.RLock()
+	aead := contentKeys[id]
+	keysMu.RUnlock()
+	if aead == nil {
+		return s, fmt.Errorf("content key %s is not configured", id)
+	}
+	sealed, err := base64.StdEncoding.DecodeString(encoded)
+	if err != nil || len(sealed) < aead.NonceSize() {
+		return s, fmt.Errorf("content sealed with key %s is corrupt", id)
+	}
+	plain, err := aead.Open(nil, sealed[:aead.NonceSize()], sealed[aead.NonceSize():], []byte(id))
+	if err != nil {
+		return s, fmt.Errorf("content sealed with key %s does not decrypt: %w", id, err)
+	}
+	return string(plain), nil
+}
+
+// sealedKey returns the key id content was sealed with, or "" for plaintext.
+func sealedKey(s string) string {
+	rest, ok := strings.CutPrefix(s, sealedPrefix)
+	if !ok {
+		return ""
+	}
+	id, _, _ := strings.Cut(rest, ":")
+	return id
+}
+
+// openDossiers decrypts dossier content in place after a load. Content that
+// cannot be decrypted stays sealed, so it is written back unchanged rather than lost.
+func openDossiers(ds *DataStore) {
+	for id, d := range ds.Dossiers {
+		plain, err := openContent(d.Content)
+		if err != nil {
+			log.Printf("WARNING: dossier %s: %v", id, err)
+			continue
+		}
+		d.Content = plain
+	}
+}
+
+// sealedCopy returns ds for writing: a shallow copy whose dossiers carry sealed content.
+func sealedCopy(ds *DataStore) *DataStore {
+	out := *ds
+	out.Dossiers = make(map[string]*Dossier, len(ds.Dossiers))
+	for id, d := range ds.Dossiers {
+		sealed := *d
+		sealed.Content = sealContent(d.Content)
+		out.Dossiers[id] = &sealed
+	}
+	return &out
+}
+
+// EncryptionStatus describes the keys configured and how the persisted content is sealed.
+type EncryptionStatus struct {
+	Enabled   bool           `json:"enabled"`
+	ActiveKey string         `json:"activeKey,omitempty"`
+	Keys      []string       `json:"keys"`
+	Sealed    map[string]int `json:"sealed"` // persisted contents by key id; "" is plaintext
+}
+
+// Encryption reports the configured keys and, from the data file and the
+// archive, how many contents are sealed with each key or still in plaintext.
+func Encryption() EncryptionStatus {
+	keysMu.RLock()
+	st := EncryptionStatus{Enabled: activeKey != "", ActiveKey: activeKey, Keys: []string{}, Sealed: map[string]int{}}
+	for id := range contentKeys {
+		st.Keys = append(st.Keys, id)
+	}
+	keysMu.RUnlock()
+	sort.Strings(st.Keys)
+
+	Mu.RLock()
+	file := dataFile
+	Mu.RUnlock()
+	var persisted DataStore
+	if raw, err := os.ReadFile(file); err == nil && json.Unmarshal(raw, &persisted) == nil {
+		for _, d := range persisted.Dossiers {
+			if d.Content != "" {
+				st.Sealed[sealedKey(d.Content)]++
+			}
+		}
+	}
+	archives, _ := filepath.Glob(filepath.Join(filepath.Dir(file), "archive", "*.json"))
+	for _, path := range archives {
+		var a ArchivedContent
+		if raw, err := os.ReadFile(path); err == nil && json.Unmarshal(raw, &a) == nil && a.Content != "" {
+			st.Sealed[sealedKey(a.Content)]++
+		}
+	}
+	return st
+}
+
+// Reseal rewrites every persisted content with the active key: plaintext from
+// before encryption was enabled is encrypted, and content sealed with an older
+// key is re-encrypted so that key can be retired. It returns how many archive
+// files were rewritten; the data file is always rewritten in full.
+func Reseal() (int, error) {
+	Save()
+	Mu.RLock()
+	dir := filepath.Join(filepath.Dir(dataFile), "archive")
+	Mu.RUnlock()
+	archives, _ := filepath.Glob(filepath.Join(dir, "*.json"))
+	rewritten := 0
+	for _, path := range archives {
+		raw, err := os.ReadFile(path)
+		if err != nil {
+			return rewritten, err
+		}
+		var a ArchivedContent
+		if err := json.Unmarshal(raw, &a); err != nil {
+			return rewritten, fmt.Errorf("corrupt archive %s: %w", filepath.Base(path), err)
+		}
+		if a.Content, err = openContent(a.Content); err != nil {
+			return rewritten, fmt.Errorf("archive %s: %w", filepath.Base(path), err)
+		}
+		a.Content = sealContent(a.Content)
+		out, _ := json.MarshalIndent(a, "", "  ")
+		if err := writeAtomic(path, out); err != nil {
+			return rewritten, err
+		}
+		rewritten++
+	}
+	return rewritten, nil
+}
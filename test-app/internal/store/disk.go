@@ -0,0 +1,67 @@
+package store
+
+import (
+	"errors"
+	"log"
+	"os"
+	"path/filepath"
+	"sync"
+)
+
+// errDiskUnsupported is reported where free space cannot be measured.
+var errDiskUnsupported = errors.New("free space not available on this platform")
+
+// DiskReport describes the free space on the volume holding the data file and
+// the archives.
+type DiskReport struct {
+	Path       string `json:"path"`
+	FreeBytes  uint64 `json:"freeBytes"`
+	TotalBytes uint64 `json:"totalBytes"`
+	// MinFreeBytes is the threshold passed to Disk; Low is set when free space is under it
+	// or under the size of the data file, which every save writes in full before replacing it.
+	MinFreeBytes int64  `json:"minFreeBytes,omitempty"`
+	Low          bool   `json:"low,omitempty"`
+	Error        string `json:"error,omitempty"`
+}
+
+// Disk reports the free space of the data volume against minFree bytes.
+func Disk(minFree int64) DiskReport {
+	dir := filepath.Dir(dataFile)
+	rep := DiskReport{Path: dir, MinFreeBytes: minFree}
+	free, total, err := diskSpace(dir)
+	if err != nil {
+		rep.Error = err.Error()
+		return rep
+	}
+	rep.FreeBytes, rep.TotalBytes = free, total
+	var dataBytes int64
+	if fi, err := os.Stat(dataFile); err == nil {
+		dataBytes = fi.Size()
+	}
+	rep.Low = (minFree > 0 && free < uint64(minFree)) || free < uint64(dataBytes)
+	return rep
+}
+
+var (
+	diskMu  sync.Mutex
+	diskLow bool
+)
+
+// WarnIfLowDisk logs a warning when the data volume runs low on space, and a
+// note when it recovers, so a full volume is noticed before Save starts failing.
+func WarnIfLowDisk(minFree int64) DiskReport {
+	rep := Disk(minFree)
+	if rep.Error != "" {
+		return rep
+	}
+	diskMu.Lock()
+	defer diskMu.Unlock()
+	switch {
+	case rep.Low && !diskLow:
+		log.Printf("WARNING: data volume %s is low on space: %d bytes free of %d (threshold %d); saves will fail once it is full", rep.Path, rep.FreeBytes, rep.TotalBytes, minFree)
+	case !rep.Low && diskLow:
+		log.Printf("Data volume %s has %d bytes free again", rep.Path, rep.FreeBytes)
+	}
+	diskLow = rep.Low
+	return rep
+}
@@ -0,0 +1,13 @@
+package store
+
+import "syscall"
+
+// diskSpace returns the bytes available to the app and the size of the
+// filesystem holding dir.
+func diskSpace(dir string) (free, total uint64, err error) {
+	var st syscall.Statfs_t
+	if err := syscall.Statfs(dir, &st); err != nil {
+		return 0, 0, err
+	}
+	return st.Bavail * uint64(st.Bsize), st.Blocks * uint64(st.Bsize), nil
+}
@@ -0,0 +1,8 @@
+//go:build !linux
+
+package store
+
+// diskSpace is not implemented off Linux; Disk reports the error instead.
+func diskSpace(dir string) (free, total uint64, err error) {
+	return 0, 0, errDiskUnsupported
+}
@@ -0,0 +1,57 @@
+package store
+
+import "sort"
+
+// Graph is a copy of the guardianship graph captured once per request, so a
+// handler and the helpers it calls share one consistent view without taking
+// the store lock again for every lookup.
+type Graph struct {
+	guardians map[string][]string // ward -> guardians
+}
+
+// SnapshotGraph copies the guardianship graph under a read lock.
+func SnapshotGraph() *Graph {
+	Mu.RLock()
+	defer Mu.RUnlock()
+	g := &Graph{guardians: make(map[string][]string, len(Data.Guardianships))}
+	for ward, list := range Data.Guardianships {
+		g.guardians[ward] = append([]string(nil), list...)
+	}
+	return g
+}
+
+// Guardians returns the users who are guardians of user.
+func (g *Graph) Guardians(user string) []string {
+	return append([]string{}, g.guardians[user]...)
+}
+
+// Wards returns the users user is a guardian of, sorted.
+func (g *Graph) Wards(user string) []string {
+	wards := []string{}
+	for ward, list := range g.guardians {
+		if ward != user && contains(list, user) {
+			wards = append(wards, ward)
+		}
+	}
+	sort.Strings(wards)
+	return wards
+}
+
+// IsGuardianOf reports whether guardian is a guardian of ward.
+func (g *Graph) IsGuardianOf(guardian, ward string) bool {
+	return contains(g.guardians[ward], guardian)
+}
+
+// Related reports whether a and b are in a guardianship in either direction.
+func (g *Graph) Related(a, b string) bool {
+	return g.IsGuardianOf(a, b) || g.IsGuardianOf(b, a)
+}
+
+func contains(list []string, s string) bool {
+	for _, v := range list {
+		if v == s {
+			return true
+		}
+	}
+	return false
+}
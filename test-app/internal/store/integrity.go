@@ -0,0 +1,186 @@
+package store
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+)
+
+// Issue is one referential-integrity problem found in Data. Warnings need a
+// human decision but leave the data consistent, so they never block startup.
+type Issue struct {
+	Object   string `json:"object"`
+	Problem  string `json:"problem"`
+	Repaired bool   `json:"repaired"`
+	Warning  bool   `json:"warning,omitempty"`
+}
+
+func (i Issue) String() string {
+	state := "unrepaired"
+	if i.Repaired {
+		state = "repaired"
+	} else if i.Warning {
+		state = "warning"
+	}
+	return fmt.Sprintf("%s: %s (%s)", i.Object, i.Problem, state)
+}
+
+// CheckIntegrity validates the references inside Data: dossiers and resources
+// pointing to missing organizations, relations and blocks naming an owner,
+// duplicate relations, and self or duplicate guardianships. With repair set,
+// every fixable issue is corrected in place; ownerless dossiers and resources
+// are never fixable. Organizations without an admin are reported as warnings:
+// only a manager admin can appoint a new one. Issues are returned sorted by object.
+func CheckIntegrity(repair bool) []Issue {
+	Mu.Lock()
+	defer Mu.Unlock()
+	var issues []Issue
+	report := func(object, problem string, fixable bool) bool {
+		issues = append(issues, Issue{Object: object, Problem: problem, Repaired: repair && fixable})
+		return repair && fixable
+	}
+
+	for id, d := range Data.Dossiers {
+		obj := "dossier:" + id
+		if d == nil {
+			if report(obj, "empty entry", true) {
+				delete(Data.Dossiers, id)
+			}
+			continue
+		}
+		if len(d.Owners) == 0 {
+			report(obj, "has no owner", false)
+		}
+		if d.OrgId != "" && Data.Organizations[d.OrgId] == nil {
+			if report(obj, "organization "+d.OrgId+" does not exist", true) {
+				d.OrgId = ""
+			}
+		}
+		if rels, ok := checkRelations(obj, d.Owners, d.Relations, report); ok {
+			d.Relations = rels
+		}
+		var blocked []string
+		for _, u := range d.BlockedUsers {
+			if d.IsOwner(u) {
+				if report(obj, "owner "+u+" is blocked", true) {
+					continue
+				}
+			}
+			blocked = append(blocked, u)
+		}
+		d.BlockedUsers = blocked
+	}
+
+	for key, res := range Data.Resources {
+		if res == nil {
+			if report(key, "empty entry", true) {
+				delete(Data.Resources, key)
+			}
+			continue
+		}
+		if res.Object() != key {
+			report(key, "stored under the wrong key for "+res.Object(), false)
+		}
+		if len(res.Owners) == 0 {
+			report(key, "has no owner", false)
+		}
+		if res.OrgId != "" && Data.Organizations[res.OrgId] == nil {
+			if report(key, "organization "+res.OrgId+" does not exist", true) {
+				res.OrgId = ""
+			}
+		}
+		if rels, ok := checkRelations(key, res.Owners, res.Relations, report); ok {
+			res.Relations = rels
+		}
+	}
+
+	for ward, guardians := range Data.Guardianships {
+		obj := "user:" + ward
+		seen := map[string]bool{}
+		var kept []string
+		for _, g := range guardians {
+			switch {
+			case g == ward:
+				if report(obj, "is their own guardian", true) {
+					continue
+				}
+			case seen[g]:
+				if report(obj, "guardian "+g+" is listed twice", true) {
+					continue
+				}
+			}
+			seen[g] = true
+			kept = append(kept, g)
+		}
+		Data.Guardianships[ward] = kept
+	}
+
+	for id, org := range Data.Organizations {
+		if org != nil && len(org.Admins) == 0 {
+			issues = append(issues, Issue{Object: "organization:" + id, Problem: "has no admin", Warning: true})
+		}
+	}
+
+	sort.SliceStable(issues, func(i, j int) bool { return issues[i].Object < issues[j].Object })
+	return issues
+}
+
+// checkRelations reports relations that name an owner or repeat another
+// relation, returning the repaired list and whether it changed.
+func checkRelations(obj string, owners []string, rels []Relation, report func(object, problem string, fixable bool) bool) ([]Relation, bool) {
+	seen := map[string]bool{}
+	var kept []Relation
+	changed := false
+	for _, rel := range rels {
+		key := rel.User + "#" + rel.Relation
+		switch {
+		case contains(owners, rel.User):
+			if report(obj, "relation "+rel.Relation+" names owner "+rel.User, true) {
+				changed = true
+				continue
+			}
+		case seen[key]:
+			if report(obj, "relation "+rel.Relation+" for "+rel.User+" is duplicated", true) {
+				changed = true
+				continue
+			}
+		}
+		seen[key] = true
+		kept = append(kept, rel)
+	}
+	return kept, changed
+}
+
+// Unrepaired counts the issues, warnings aside, that still need manual attention.
+func Unrepaired(issues []Issue) int {
+	n := 0
+	for _, i := range issues {
+		if !i.Repaired && !i.Warning {
+			n++
+		}
+	}
+	return n
+}
+
+// OrphanedOrganizations returns the ids of organizations with no admin, sorted.
+func OrphanedOrganizations() []string {
+	Mu.RLock()
+	defer Mu.RUnlock()
+	var ids []string
+	for id, org := range Data.Organizations {
+		if org != nil && len(org.Admins) == 0 {
+			ids = append(ids, id)
+		}
+	}
+	sort.Strings(ids)
+	return ids
+}
+
+// FormatIssues renders issues one per line for logs.
+func FormatIssues(issues []Issue) string {
+	lines := make([]string, len(issues))
+	for i, issue := range issues {
+		lines[i] = "  - " + issue.String()
+	}
+	return strings.Join(lines, "\n")
+}
@@ -0,0 +1,112 @@
+package store
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"sort"
+	"strings"
+
+	"test-app/internal/events"
+)
+
+// digestObjects hashes every persisted object under its object id, so Save can
+// tell which ones changed since the last save.
+func digestObjects(ds *DataStore) map[string]string {
+	out := map[string]string{}
+	add := func(key string, v interface{}) {
+		b, _ := json.Marshal(v)
+		sum := sha256.Sum256(b)
+		out[key] = hex.EncodeToString(sum[:16])
+	}
+	for id, d := range ds.Dossiers {
+		add("dossier:"+id, d)
+	}
+	for id, org := range ds.Organizations {
+		add("organization:"+id, org)
+	}
+	for key, res := range ds.Resources {
+		add(key, res)
+	}
+	for ward, guardians := range ds.Guardianships {
+		add("guardianship:"+ward, guardians)
+	}
+	for _, req := range ds.GuardianshipRequests {
+		add("guardianship_request:"+req.Id, req)
+	}
+	for _, req := range ds.JoinRequests {
+		add("join_request:"+req.Id, req)
+	}
+	for _, req := range ds.AccessRequests {
+		add("access_request:"+req.Id, req)
+	}
+	return out
+}
+
+// diffDigests returns a change event per object added, modified or removed
+// between two digests, in object order.
+func diffDigests(before, after map[string]string) []events.Event {
+	var out []events.Event
+	for key, sum := range after {
+		if before[key] != sum {
+			out = append(out, events.Event{Type: events.ObjectChanged, Object: key})
+		}
+	}
+	for key := range before {
+		if _, ok := after[key]; !ok {
+			out = append(out, events.Event{Type: events.ObjectDeleted, Object: key})
+		}
+	}
+	sort.Slice(out, func(i, j int) bool { return out[i].Object < out[j].Object })
+	return out
+}
+
+// ObjectJSON returns the current persisted value of an object id as used in
+// change events, or false once it no longer exists.
+func ObjectJSON(object string) (json.RawMessage, bool) {
+	Mu.RLock()
+	defer Mu.RUnlock()
+	var v interface{}
+	typ, id, _ := strings.Cut(object, ":")
+	switch typ {
+	case "dossier":
+		if d, ok := Data.Dossiers[id]; ok {
+			v = d
+		}
+	case "organization":
+		if org, ok := Data.Organizations[id]; ok {
+			v = org
+		}
+	case "guardianship":
+		if g, ok := Data.Guardianships[id]; ok {
+			v = g
+		}
+	case "guardianship_request":
+		for _, req := range Data.GuardianshipRequests {
+			if req.Id == id {
+				v = req
+			}
+		}
+	case "join_request":
+		for _, req := range Data.JoinRequests {
+			if req.Id == id {
+				v = req
+			}
+		}
+	case "access_request":
+		for _, req := range Data.AccessRequests {
+			if req.Id == id {
+				v = req
+			}
+		}
+	default:
+		if res, ok := Data.Resources[object]; ok {
+			v = res
+		}
+	}
+	if v == nil {
+		return nil, false
+	}
+	b, err := json.Marshal(v)
+	return b, err == nil
+}
@@ -0,0 +1,49 @@
+package store
+
+// GrantPath returns the provenance chain of user's mandate on d, from the
+// first known grantor to user (e.g. alice, bob, carol). Grants made before
+// grantors were recorded start the path at user.
+func (d *Dossier) GrantPath(user string) []string {
+	path := []string{user}
+	seen := map[string]bool{user: true}
+	for current := user; ; {
+		grantor := ""
+		for _, rel := range d.Relations {
+			if rel.User == current && rel.GrantedBy != "" {
+				grantor = rel.GrantedBy
+				break
+			}
+		}
+		if grantor == "" || seen[grantor] {
+			break
+		}
+		path = append([]string{grantor}, path...)
+		seen[grantor] = true
+		current = grantor
+	}
+	return path
+}
+
+// GrantChain returns user's relations on d together with every relation
+// granted onward from them, directly or through further re-sharing. Relations
+// someone in the chain also holds from another grantor (e.g. straight from an
+// owner) are not part of it.
+func (d *Dossier) GrantChain(user string) []Relation {
+	inChain := map[string]bool{user: true}
+	for changed := true; changed; {
+		changed = false
+		for _, rel := range d.Relations {
+			if !inChain[rel.User] && inChain[rel.GrantedBy] && !d.IsOwner(rel.User) {
+				inChain[rel.User] = true
+				changed = true
+			}
+		}
+	}
+	var chain []Relation
+	for _, rel := range d.Relations {
+		if rel.User == user || inChain[rel.GrantedBy] && !d.IsOwner(rel.User) {
+			chain = append(chain, rel)
+		}
+	}
+	return chain
+}
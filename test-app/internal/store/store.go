@@ -6,7 +6,12 @@ import (
 	"math/rand"
 	"os"
 	"path/filepath"
+	"sort"
 	"sync"
+	"sync/atomic"
+	"time"
+
+	"test-app/internal/events"
 )
 
 var (
@@ -15,13 +20,49 @@ var (
 		GuardianshipRequests: []GuardianshipRequest{},
 		Guardianships:        make(map[string][]string),
 		Organizations:        make(map[string]*Organization),
+		Resources:            make(map[string]*Resource),
+		Favorites:            make(map[string][]string),
+		SavedViews:           make(map[string]*SavedView),
 	}
 	Mu       sync.RWMutex
 	dataFile = "/data/dossiers.json"
 
-	AssignableRelations = []string{"owner", "mandate_holder"}
+	// LastSave and LastSaveErr record the outcome of the most recent Save (guarded by Mu)
+	LastSave    time.Time
+	LastSaveErr error
+
+	AssignableRelations = []string{"owner", "mandate_holder", "mandate_viewer", "mandate_editor", "mandate_sharer"}
+
+	// MandateLevels maps the level a mandate is granted at to its FGA relation.
+	// mandate_holder is the original all-round mandate (view, edit and share).
+	MandateLevels = map[string]string{"view": "mandate_viewer", "edit": "mandate_editor", "share": "mandate_sharer"}
+
+	version atomic.Uint64
 )
 
+// IsMandate reports whether relation is one of the mandate relations.
+func IsMandate(relation string) bool {
+	if relation == "mandate_holder" {
+		return true
+	}
+	for _, rel := range MandateLevels {
+		if rel == relation {
+			return true
+		}
+	}
+	return false
+}
+
+// Version is bumped on every Save and tuple write; list endpoints derive their ETags from it.
+func Version() uint64 {
+	return version.Load()
+}
+
+// Touch bumps Version for changes that are not persisted by Save, such as FGA-only tuple writes.
+func Touch() {
+	version.Add(1)
+}
+
 func Load() {
 	data, err := os.ReadFile(dataFile)
 	if err != nil {
@@ -33,32 +74,229 @@ func Load() {
 		log.Printf("WARNING: failed to unmarshal data file: %v", err)
 		return
 	}
-	if Data.Dossiers == nil {
-		Data.Dossiers = make(map[string]*Dossier)
+	initMaps(Data)
+	openDossiers(Data)
+	migrateOwners()
+	migrateOrganizations()
+	Data.digests = digestObjects(Data)
+}
+
+// initMaps allocates the maps a data file may omit.
+func initMaps(ds *DataStore) {
+	if ds.Dossiers == nil {
+		ds.Dossiers = make(map[string]*Dossier)
+	}
+	if ds.GuardianshipRequests == nil {
+		ds.GuardianshipRequests = []GuardianshipRequest{}
+	}
+	if ds.Guardianships == nil {
+		ds.Guardianships = make(map[string][]string)
+	}
+	if ds.Organizations == nil {
+		ds.Organizations = make(map[string]*Organization)
+	}
+	if ds.Resources == nil {
+		ds.Resources = make(map[string]*Resource)
 	}
-	if Data.Guardianships == nil {
-		Data.Guardianships = make(map[string][]string)
+	if ds.Favorites == nil {
+		ds.Favorites = make(map[string][]string)
 	}
-	if Data.Organizations == nil {
-		Data.Organizations = make(map[string]*Organization)
+	if ds.SavedViews == nil {
+		ds.SavedViews = make(map[string]*SavedView)
+	}
+	if ds.RoleGrants == nil {
+		ds.RoleGrants = make(map[string][]string)
+	}
+	if ds.Profiles == nil {
+		ds.Profiles = make(map[string]*Profile)
+	}
+}
+
+// Snapshot returns Data serialised exactly as Save writes it, content sealed.
+func Snapshot() []byte {
+	Mu.RLock()
+	defer Mu.RUnlock()
+	data, _ := json.MarshalIndent(sealedCopy(Data), "", "  ")
+	return data
+}
+
+// Replace swaps Data for the contents of a data file, e.g. a backup.
+// The caller is responsible for calling Save afterwards.
+func Replace(raw []byte) error {
+	var ds DataStore
+	if err := json.Unmarshal(raw, &ds); err != nil {
+		return err
+	}
+	initMaps(&ds)
+	openDossiers(&ds)
+	Mu.Lock()
+	defer Mu.Unlock()
+	ds.digests = Data.digests
+	Data = &ds
+	migrateOwners()
+	migrateOrganizations()
+	return nil
+}
+
+// migrateOwners moves the single owner of data files written before
+// co-ownership into the Owners list.
+func migrateOwners() {
+	for _, d := range Data.Dossiers {
+		if d.LegacyOwner == "" {
+			continue
+		}
+		if !d.IsOwner(d.LegacyOwner) {
+			d.Owners = append([]string{d.LegacyOwner}, d.Owners...)
+		}
+		d.LegacyOwner = ""
+	}
+}
+
+// migrateOrganizations fills in the member and admin lists of organizations
+// written before admins existed, and makes every admin a member.
+func migrateOrganizations() {
+	for _, org := range Data.Organizations {
+		if org == nil {
+			continue
+		}
+		if org.Members == nil {
+			org.Members = []string{}
+		}
+		if org.Admins == nil {
+			org.Admins = []string{}
+		}
+		for _, a := range org.Admins {
+			if !contains(org.Members, a) {
+				org.Members = append(org.Members, a)
+			}
+		}
 	}
 }
 
+// Save persists Data and then publishes a change event for every object that
+// differs from the previous save.
 func Save() {
+	for _, e := range save() {
+		events.Publish(e)
+	}
+}
+
+func save() []events.Event {
 	Mu.Lock()
 	defer Mu.Unlock()
+	Touch()
 	dir := filepath.Dir(dataFile)
 	os.MkdirAll(dir, 0755)
-	data, _ := json.MarshalIndent(Data, "", "  ")
-	os.WriteFile(dataFile, data, 0644)
+	data, _ := json.MarshalIndent(sealedCopy(Data), "", "  ")
+	if err := writeAtomic(dataFile, data); err != nil {
+		log.Printf("WARNING: failed to save data file, changes since %s are only in memory: %v", LastSave.Format(time.RFC3339), err)
+		LastSaveErr = err
+		return nil
+	}
+	LastSave = time.Now()
+	LastSaveErr = nil
+	digests := digestObjects(Data)
+	changes := diffDigests(Data.digests, digests)
+	Data.digests = digests
+	return changes
 }
 
-// RehydrateTuples rebuilds all FGA tuples from persisted data.
-// It accepts a write function to avoid importing the fga package directly.
-func RehydrateTuples(fgaWrite func(writes []TupleKey, deletes []TupleKey) error) {
+// writeAtomic replaces path with data through a temporary file, so a write
+// that fails part way (e.g. on a full volume) leaves the previous file intact.
+func writeAtomic(path string, data []byte) error {
+	tmp := path + ".tmp"
+	f, err := os.OpenFile(tmp, os.O_WRONLY|os.O_CREATE|os.O_TRUNC, 0600)
+	if err != nil {
+		return err
+	}
+	_, err = f.Write(data)
+	if err == nil {
+		err = f.Sync()
+	}
+	if cerr := f.Close(); err == nil {
+		err = cerr
+	}
+	if err == nil {
+		err = os.Rename(tmp, path)
+	}
+	if err != nil {
+		os.Remove(tmp)
+	}
+	return err
+}
+
+// DataFile returns the path of the persisted data file.
+func DataFile() string {
+	return dataFile
+}
+
+// CheckWritable verifies the data directory accepts new files.
+func CheckWritable() error {
+	dir := filepath.Dir(dataFile)
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		return err
+	}
+	f, err := os.CreateTemp(dir, ".healthcheck-*")
+	if err != nil {
+		return err
+	}
+	name := f.Name()
+	f.Close()
+	return os.Remove(name)
+}
+
+// KnownUsers returns every user named in the data: dossier owners, relations
+// and blocks, guardianships and their requests, and organization members.
+func KnownUsers() []string {
+	Mu.RLock()
+	defer Mu.RUnlock()
+	userSet := make(map[string]bool)
+	for _, d := range Data.Dossiers {
+		for _, owner := range d.Owners {
+			userSet[owner] = true
+		}
+		for _, rel := range d.Relations {
+			userSet[rel.User] = true
+		}
+		for _, blocked := range d.BlockedUsers {
+			userSet[blocked] = true
+		}
+	}
+	for userId, guardians := range Data.Guardianships {
+		userSet[userId] = true
+		for _, g := range guardians {
+			userSet[g] = true
+		}
+	}
+	for _, req := range Data.GuardianshipRequests {
+		userSet[req.From] = true
+		userSet[req.To] = true
+	}
+	for _, org := range Data.Organizations {
+		for _, m := range org.Members {
+			userSet[m] = true
+		}
+		for _, a := range org.Admins {
+			userSet[a] = true
+		}
+	}
+	users := make([]string, 0, len(userSet))
+	for u := range userSet {
+		users = append(users, u)
+	}
+	sort.Strings(users)
+	return users
+}
+
+// DesiredTuples returns every FGA tuple implied by the persisted data.
+func DesiredTuples() []TupleKey {
+	Mu.RLock()
+	defer Mu.RUnlock()
 	var writes []TupleKey
 	for id, dossier := range Data.Dossiers {
-		writes = append(writes, TupleKey{User: "user:" + dossier.Owner, Relation: "owner", Object: "dossier:" + id})
+		for _, owner := range dossier.Owners {
+			writes = append(writes, TupleKey{User: "user:" + owner, Relation: "owner", Object: "dossier:" + id})
+		}
 		for _, rel := range dossier.Relations {
 			writes = append(writes, TupleKey{User: "user:" + rel.User, Relation: rel.Relation, Object: "dossier:" + id})
 		}
@@ -77,6 +315,17 @@ func RehydrateTuples(fgaWrite func(writes []TupleKey, deletes []TupleKey) error)
 			writes = append(writes, TupleKey{User: "user:" + guardianId, Relation: "guardian", Object: "user:" + userId})
 		}
 	}
+	for _, res := range Data.Resources {
+		for _, owner := range res.Owners {
+			writes = append(writes, TupleKey{User: "user:" + owner, Relation: "owner", Object: res.Object()})
+		}
+		for _, rel := range res.Relations {
+			writes = append(writes, TupleKey{User: "user:" + rel.User, Relation: rel.Relation, Object: res.Object()})
+		}
+		if res.OrgId != "" {
+			writes = append(writes, TupleKey{User: "organization:" + res.OrgId, Relation: "org_parent", Object: res.Object()})
+		}
+	}
 	for orgId, org := range Data.Organizations {
 		for _, member := range org.Members {
 			writes = append(writes, TupleKey{User: "user:" + member, Relation: "member", Object: "organization:" + orgId})
@@ -85,6 +334,13 @@ func RehydrateTuples(fgaWrite func(writes []TupleKey, deletes []TupleKey) error)
 			writes = append(writes, TupleKey{User: "user:" + admin, Relation: "admin", Object: "organization:" + orgId})
 		}
 	}
+	return writes
+}
+
+// RehydrateTuples rebuilds all FGA tuples from persisted data.
+// It accepts a write function to avoid importing the fga package directly.
+func RehydrateTuples(fgaWrite func(writes []TupleKey, deletes []TupleKey) error) {
+	writes := DesiredTuples()
 	for i := 0; i < len(writes); i += 10 {
 		end := i + 10
 		if end > len(writes) {
@@ -99,6 +355,52 @@ func RehydrateTuples(fgaWrite func(writes []TupleKey, deletes []TupleKey) error)
 	}
 }
 
+// DiffTuples compares the tuples implied by persisted data with the tuples
+// actually present in OpenFGA. missing are expected but absent; extra are
+// present in OpenFGA but not backed by any persisted data.
+func DiffTuples(actual []TupleKey) (missing, extra []TupleKey) {
+	desired := DesiredTuples()
+	have := make(map[TupleKey]bool, len(actual))
+	for _, t := range actual {
+		have[t] = true
+	}
+	want := make(map[TupleKey]bool, len(desired))
+	for _, t := range desired {
+		want[t] = true
+		if !have[t] {
+			missing = append(missing, t)
+		}
+	}
+	for _, t := range actual {
+		if !want[t] {
+			extra = append(extra, t)
+		}
+	}
+	return missing, extra
+}
+
+// VerifyTuples reports drift between persisted data and OpenFGA without
+// writing anything. It accepts a read function to avoid importing the fga package directly.
+func VerifyTuples(fgaRead func() ([]TupleKey, error)) {
+	actual, err := fgaRead()
+	if err != nil {
+		log.Printf("Verify: failed to read tuples from OpenFGA: %v", err)
+		return
+	}
+	missing, extra := DiffTuples(actual)
+	if len(missing) == 0 && len(extra) == 0 {
+		log.Printf("Verify: OpenFGA is in sync with persisted data (%d tuples)", len(actual))
+		return
+	}
+	log.Printf("Verify: %d tuples missing from OpenFGA, %d tuples not backed by persisted data", len(missing), len(extra))
+	for _, t := range missing {
+		log.Printf("Verify: missing %s %s %s", t.User, t.Relation, t.Object)
+	}
+	for _, t := range extra {
+		log.Printf("Verify: extra %s %s %s", t.User, t.Relation, t.Object)
+	}
+}
+
 func RandId() string {
 	const chars = "abcdefghijklmnopqrstuvwxyz0123456789"
 	b := make([]byte, 8)
@@ -107,3 +409,191 @@ func RandId() string {
 	}
 	return string(b)
 }
+
+// Clone returns a deep copy of Data, used to roll back multi-step changes.
+func Clone() *DataStore {
+	Mu.RLock()
+	defer Mu.RUnlock()
+	b, _ := json.Marshal(Data)
+	var c DataStore
+	json.Unmarshal(b, &c)
+	c.digests = Data.digests
+	return &c
+}
+
+// ErasureReport summarises what EraseUser removed or reassigned. Generic
+// resources are listed alongside dossiers by their FGA object id.
+type ErasureReport struct {
+	User               string   `json:"user"`
+	DeletedDossiers    []string `json:"deletedDossiers"`
+	ReassignedDossiers []string `json:"reassignedDossiers"`
+	CoOwnedDossiers    []string `json:"coOwnedDossiers"`
+	RevokedRelations   int      `json:"revokedRelations"`
+	Unblocked          int      `json:"unblocked"`
+	Guardianships      int      `json:"guardianships"`
+	Requests           int      `json:"requests"`
+	Organizations      []string `json:"organizations"`
+}
+
+// EraseUser removes every trace of user from Data. Dossiers left without an
+// owner are handed to reassignTo, or deleted when reassignTo is empty.
+func EraseUser(user, reassignTo string) ErasureReport {
+	Mu.Lock()
+	defer Mu.Unlock()
+	rep := ErasureReport{User: user, DeletedDossiers: []string{}, ReassignedDossiers: []string{}, CoOwnedDossiers: []string{}, Organizations: []string{}}
+
+	for id, d := range Data.Dossiers {
+		if d.IsOwner(user) {
+			d.Owners = removeString(d.Owners, user)
+			if len(d.Owners) == 0 && reassignTo == "" {
+				delete(Data.Dossiers, id)
+				rep.DeletedDossiers = append(rep.DeletedDossiers, id)
+				continue
+			}
+			if len(d.Owners) == 0 {
+				d.Owners = []string{reassignTo}
+				rep.ReassignedDossiers = append(rep.ReassignedDossiers, id)
+			} else {
+				rep.CoOwnedDossiers = append(rep.CoOwnedDossiers, id)
+			}
+		}
+		var kept []Relation
+		for _, rel := range d.Relations {
+			if rel.User == user {
+				rep.RevokedRelations++
+				continue
+			}
+			if reassignTo != "" && rel.User == reassignTo && d.IsOwner(reassignTo) {
+				// The new owner no longer needs a relation on their own dossier
+				continue
+			}
+			kept = append(kept, rel)
+		}
+		d.Relations = kept
+		var blocked []string
+		for _, b := range d.BlockedUsers {
+			if b == user {
+				rep.Unblocked++
+				continue
+			}
+			blocked = append(blocked, b)
+		}
+		d.BlockedUsers = blocked
+	}
+
+	for key, res := range Data.Resources {
+		if contains(res.Owners, user) {
+			res.Owners = removeString(res.Owners, user)
+			if len(res.Owners) == 0 && reassignTo == "" {
+				delete(Data.Resources, key)
+				rep.DeletedDossiers = append(rep.DeletedDossiers, key)
+				continue
+			}
+			if len(res.Owners) == 0 {
+				res.Owners = []string{reassignTo}
+				rep.ReassignedDossiers = append(rep.ReassignedDossiers, key)
+			}
+		}
+		var kept []Relation
+		for _, rel := range res.Relations {
+			if rel.User == user {
+				rep.RevokedRelations++
+				continue
+			}
+			kept = append(kept, rel)
+		}
+		res.Relations = kept
+	}
+
+	rep.Guardianships += len(Data.Guardianships[user])
+	delete(Data.Guardianships, user)
+	for ward, guardians := range Data.Guardianships {
+		var kept []string
+		for _, g := range guardians {
+			if g == user {
+				rep.Guardianships++
+				continue
+			}
+			kept = append(kept, g)
+		}
+		if len(kept) == 0 {
+			delete(Data.Guardianships, ward)
+		} else {
+			Data.Guardianships[ward] = kept
+		}
+	}
+
+	var requests []GuardianshipRequest
+	for _, req := range Data.GuardianshipRequests {
+		if req.From == user || req.To == user {
+			rep.Requests++
+			continue
+		}
+		requests = append(requests, req)
+	}
+	if requests == nil {
+		requests = []GuardianshipRequest{}
+	}
+	Data.GuardianshipRequests = requests
+
+	var joins []JoinRequest
+	for _, req := range Data.JoinRequests {
+		if req.User == user {
+			rep.Requests++
+			continue
+		}
+		joins = append(joins, req)
+	}
+	Data.JoinRequests = joins
+
+	var access []AccessRequest
+	for _, req := range Data.AccessRequests {
+		if req.User == user {
+			rep.Requests++
+			continue
+		}
+		access = append(access, req)
+	}
+	Data.AccessRequests = access
+	delete(Data.Favorites, user)
+	delete(Data.RoleGrants, user)
+	delete(Data.Profiles, user)
+	for id, v := range Data.SavedViews {
+		if v.Owner == user {
+			delete(Data.SavedViews, id)
+		}
+	}
+
+	for id, org := range Data.Organizations {
+		members, admins := removeString(org.Members, user), removeString(org.Admins, user)
+		if len(members) != len(org.Members) || len(admins) != len(org.Admins) {
+			rep.Organizations = append(rep.Organizations, id)
+		}
+		org.Members, org.Admins = members, admins
+	}
+	sort.Strings(rep.DeletedDossiers)
+	sort.Strings(rep.ReassignedDossiers)
+	sort.Strings(rep.CoOwnedDossiers)
+	sort.Strings(rep.Organizations)
+	return rep
+}
+
+func removeString(list []string, s string) []string {
+	out := []string{}
+	for _, v := range list {
+		if v != s {
+			out = append(out, v)
+		}
+	}
+	return out
+}
+
+// Swap installs ds as Data, persisted to file, and returns the previous pair
+// so the caller can swap them back. It is how sandboxes get their own copy.
+func Swap(ds *DataStore, file string) (*DataStore, string) {
+	Mu.Lock()
+	defer Mu.Unlock()
+	prev, prevFile := Data, dataFile
+	Data, dataFile = ds, file
+	return prev, prevFile
+}
@@ -1,11 +1,16 @@
 package store
 
 import (
+	"bytes"
+	"encoding/base64"
 	"encoding/json"
+	"math"
 	"os"
 	"path/filepath"
 	"regexp"
+	"strings"
 	"testing"
+	"time"
 )
 
 func TestRandId(t *testing.T) {
@@ -49,7 +54,7 @@ func TestRehydrateTuples_WithData(t *testing.T) {
 
 	Data = &DataStore{
 		Dossiers: map[string]*Dossier{
-			"d1": {Title: "Tax Return 2024", Owner: "alice", Relations: []Relation{
+			"d1": {Title: "Tax Return 2024", Owners: []string{"alice"}, Relations: []Relation{
 				{User: "bob", Relation: "mandate_holder"},
 			}},
 		},
@@ -79,7 +84,7 @@ func TestRehydrateTuples_BatchSplitting(t *testing.T) {
 	dossiers := make(map[string]*Dossier)
 	for i := 0; i < 12; i++ {
 		id := RandId()
-		dossiers[id] = &Dossier{Title: "dossier", Owner: "alice"}
+		dossiers[id] = &Dossier{Title: "dossier", Owners: []string{"alice"}}
 	}
 	Data = &DataStore{
 		Dossiers:             dossiers,
@@ -115,7 +120,7 @@ func TestLoadSave_Roundtrip(t *testing.T) {
 
 	Data = &DataStore{
 		Dossiers: map[string]*Dossier{
-			"x1": {Title: "Health Record", Content: "Annual checkup", Type: "health", Owner: "alice"},
+			"x1": {Title: "Health Record", Content: "Annual checkup", Type: "health", Owners: []string{"alice"}},
 		},
 		GuardianshipRequests: []GuardianshipRequest{{Id: "r1", From: "alice", To: "bob", Status: "pending"}},
 		Guardianships:        map[string][]string{"alice": {"bob"}},
@@ -161,7 +166,7 @@ func TestRehydrateTuples_WithOrg(t *testing.T) {
 
 	Data = &DataStore{
 		Dossiers: map[string]*Dossier{
-			"d1": {Title: "Org Doc", Owner: "alice", OrgId: "org1"},
+			"d1": {Title: "Org Doc", Owners: []string{"alice"}, OrgId: "org1"},
 		},
 		GuardianshipRequests: []GuardianshipRequest{},
 		Guardianships:        make(map[string][]string),
@@ -200,7 +205,7 @@ func TestRehydrateTuples_WithPublic(t *testing.T) {
 
 	Data = &DataStore{
 		Dossiers: map[string]*Dossier{
-			"d1": {Title: "Public Doc", Owner: "alice", Public: true},
+			"d1": {Title: "Public Doc", Owners: []string{"alice"}, Public: true},
 		},
 		GuardianshipRequests: []GuardianshipRequest{},
 		Guardianships:        make(map[string][]string),
@@ -236,7 +241,7 @@ func TestRehydrateTuples_WithBlocked(t *testing.T) {
 
 	Data = &DataStore{
 		Dossiers: map[string]*Dossier{
-			"d1": {Title: "Blocked Doc", Owner: "alice", BlockedUsers: []string{"bob", "charlie"}},
+			"d1": {Title: "Blocked Doc", Owners: []string{"alice"}, BlockedUsers: []string{"bob", "charlie"}},
 		},
 		GuardianshipRequests: []GuardianshipRequest{},
 		Guardianships:        make(map[string][]string),
@@ -274,3 +279,403 @@ func TestLoad_MissingFile(t *testing.T) {
 	// Should not panic
 	Load()
 }
+
+func TestDiffTuples(t *testing.T) {
+	origData := Data
+	defer func() { Data = origData }()
+
+	Data = &DataStore{
+		Dossiers: map[string]*Dossier{
+			"d1": {Title: "Tax Return 2024", Owners: []string{"alice"}, Relations: []Relation{
+				{User: "bob", Relation: "mandate_holder"},
+			}},
+		},
+		GuardianshipRequests: []GuardianshipRequest{},
+		Guardianships:        make(map[string][]string),
+		Organizations:        make(map[string]*Organization),
+	}
+
+	actual := []TupleKey{
+		{User: "user:alice", Relation: "owner", Object: "dossier:d1"},
+		{User: "user:mallory", Relation: "owner", Object: "dossier:d1"},
+	}
+	missing, extra := DiffTuples(actual)
+
+	if len(missing) != 1 || missing[0].User != "user:bob" {
+		t.Errorf("missing = %+v, want bob mandate_holder", missing)
+	}
+	if len(extra) != 1 || extra[0].User != "user:mallory" {
+		t.Errorf("extra = %+v, want mallory owner", extra)
+	}
+}
+
+func TestEraseUser(t *testing.T) {
+	origData := Data
+	defer func() { Data = origData }()
+	Data = &DataStore{
+		Dossiers: map[string]*Dossier{
+			"own":    {Owners: []string{"alice"}, Relations: []Relation{{User: "carol", Relation: "mandate_holder"}}},
+			"shared": {Owners: []string{"bob"}, Relations: []Relation{{User: "alice", Relation: "mandate_holder"}}, BlockedUsers: []string{"alice"}},
+		},
+		GuardianshipRequests: []GuardianshipRequest{{Id: "r1", From: "alice", To: "bob", Status: "pending"}},
+		Guardianships:        map[string][]string{"bob": {"alice"}, "alice": {"carol"}},
+		Organizations:        map[string]*Organization{"o1": {Name: "Acme", Members: []string{"alice", "bob"}, Admins: []string{"alice"}}},
+	}
+
+	rep := EraseUser("alice", "carol")
+	if Data.Dossiers["own"].PrimaryOwner() != "carol" || len(Data.Dossiers["own"].Relations) != 0 {
+		t.Errorf("own dossier = %+v, want owned by carol without her old mandate", Data.Dossiers["own"])
+	}
+	if len(Data.Dossiers["shared"].Relations) != 0 || len(Data.Dossiers["shared"].BlockedUsers) != 0 {
+		t.Errorf("shared dossier still references alice: %+v", Data.Dossiers["shared"])
+	}
+	if len(Data.Guardianships) != 0 || len(Data.GuardianshipRequests) != 0 {
+		t.Errorf("guardianships = %v requests = %v, want none", Data.Guardianships, Data.GuardianshipRequests)
+	}
+	if o := Data.Organizations["o1"]; len(o.Members) != 1 || len(o.Admins) != 0 {
+		t.Errorf("org = %+v, want only bob as member", o)
+	}
+	if rep.RevokedRelations != 1 || rep.Guardianships != 2 || rep.Requests != 1 {
+		t.Errorf("report = %+v", rep)
+	}
+	for _, tk := range DesiredTuples() {
+		if tk.User == "user:alice" || tk.Object == "user:alice" {
+			t.Errorf("tuple %+v still references alice", tk)
+		}
+	}
+}
+
+func TestLoad_MigratesSingleOwner(t *testing.T) {
+	origData, origFile := Data, dataFile
+	defer func() { Data, dataFile = origData, origFile }()
+
+	dataFile = filepath.Join(t.TempDir(), "data.json")
+	os.WriteFile(dataFile, []byte(`{"dossiers":{"d1":{"title":"Old","type":"tax","owner":"alice"}}}`), 0644)
+	Data = &DataStore{}
+	Load()
+
+	d := Data.Dossiers["d1"]
+	if d == nil || len(d.Owners) != 1 || d.Owners[0] != "alice" || d.LegacyOwner != "" {
+		t.Fatalf("dossier = %+v, want owners [alice] and legacy owner cleared", d)
+	}
+	b, _ := json.Marshal(d)
+	if strings.Contains(string(b), `"owner"`) {
+		t.Errorf("migrated dossier still serializes the legacy owner: %s", b)
+	}
+}
+
+func TestSnapshotGraph_IsolatedFromLaterWrites(t *testing.T) {
+	origData := Data
+	defer func() { Data = origData }()
+	Data = &DataStore{Guardianships: map[string][]string{"alice": {"bob"}, "carol": {"bob"}}}
+
+	g := SnapshotGraph()
+	Data.Guardianships["alice"] = append(Data.Guardianships["alice"], "dave")
+
+	if got := g.Guardians("alice"); len(got) != 1 || got[0] != "bob" {
+		t.Errorf("Guardians(alice) = %v, want [bob] from the snapshot", got)
+	}
+	if got := g.Wards("bob"); len(got) != 2 || got[0] != "alice" || got[1] != "carol" {
+		t.Errorf("Wards(bob) = %v, want [alice carol]", got)
+	}
+	if !g.Related("alice", "bob") || !g.Related("bob", "alice") || g.Related("alice", "carol") {
+		t.Error("Related should hold in both directions for guardianships only")
+	}
+}
+
+func TestArchiveRestore(t *testing.T) {
+	origData := Data
+	origFile := dataFile
+	defer func() {
+		Data = origData
+		dataFile = origFile
+	}()
+	dataFile = filepath.Join(t.TempDir(), "dossiers.json")
+	Data = &DataStore{Dossiers: map[string]*Dossier{
+		"d1": {Title: "Tax", Content: "# 2024", ContentType: "markdown", Owners: []string{"alice"}},
+	}}
+
+	if err := Archive("d1"); err != nil {
+		t.Fatalf("Archive: %v", err)
+	}
+	if d := Data.Dossiers["d1"]; d.Content != "" || d.ArchivedAt == nil {
+		t.Errorf("after archive: content = %q, archivedAt = %v", d.Content, d.ArchivedAt)
+	}
+	if err := Archive("d1"); err == nil {
+		t.Error("archiving twice should fail")
+	}
+	if _, err := os.Stat(archivePath("d1")); err != nil {
+		t.Fatalf("archive file missing: %v", err)
+	}
+
+	if err := Restore("d1"); err != nil {
+		t.Fatalf("Restore: %v", err)
+	}
+	if d := Data.Dossiers["d1"]; d.Content != "# 2024" || d.ContentType != "markdown" || d.ArchivedAt != nil {
+		t.Errorf("after restore: %+v", d)
+	}
+	// The archive file stays until the restored content is saved.
+	if _, err := os.Stat(archivePath("d1")); err != nil {
+		t.Errorf("archive file removed before the restore was saved: %v", err)
+	}
+	DropArchive("d1")
+	if _, err := os.Stat(archivePath("d1")); !os.IsNotExist(err) {
+		t.Error("archive file should be removed by DropArchive")
+	}
+}
+
+func TestCheckIntegrity(t *testing.T) {
+	origData := Data
+	defer func() { Data = origData }()
+	newData := func() *DataStore {
+		return &DataStore{
+			Dossiers: map[string]*Dossier{
+				"d1": {Title: "Tax", Owners: []string{"alice"}, OrgId: "gone",
+					BlockedUsers: []string{"alice", "eve"},
+					Relations: []Relation{
+						{User: "alice", Relation: "mandate_holder"},
+						{User: "bob", Relation: "mandate_holder"},
+						{User: "bob", Relation: "mandate_holder"},
+					}},
+			},
+			Guardianships: map[string][]string{"carol": {"carol", "dave", "dave"}},
+			Organizations: map[string]*Organization{},
+		}
+	}
+
+	Data = newData()
+	issues := CheckIntegrity(false)
+	if len(issues) != 6 || Unrepaired(issues) != 6 {
+		t.Fatalf("check only: %d issues, %d unrepaired, want 6 and 6:\n%s", len(issues), Unrepaired(issues), FormatIssues(issues))
+	}
+	if Data.Dossiers["d1"].OrgId != "gone" {
+		t.Error("check-only mode must not modify data")
+	}
+
+	Data = newData()
+	issues = CheckIntegrity(true)
+	if Unrepaired(issues) != 0 {
+		t.Errorf("unrepaired issues after repair:\n%s", FormatIssues(issues))
+	}
+	d := Data.Dossiers["d1"]
+	if d.OrgId != "" || len(d.Relations) != 1 || len(d.BlockedUsers) != 1 || d.BlockedUsers[0] != "eve" {
+		t.Errorf("dossier not repaired: %+v", d)
+	}
+	if g := Data.Guardianships["carol"]; len(g) != 1 || g[0] != "dave" {
+		t.Errorf("guardianships not repaired: %v", g)
+	}
+
+	Data = &DataStore{Dossiers: map[string]*Dossier{"d2": {Title: "Orphan"}}}
+	if issues := CheckIntegrity(true); Unrepaired(issues) != 1 {
+		t.Errorf("ownerless dossier should stay unrepaired: %v", issues)
+	}
+
+	Data = &DataStore{Organizations: map[string]*Organization{
+		"o1": {Name: "Lost", Members: []string{"bob"}},
+		"o2": {Name: "Kept", Admins: []string{"alice"}, Members: []string{"alice"}},
+	}}
+	issues = CheckIntegrity(true)
+	if len(issues) != 1 || !issues[0].Warning || Unrepaired(issues) != 0 {
+		t.Errorf("adminless organization should be a warning only: %v", issues)
+	}
+	if ids := OrphanedOrganizations(); len(ids) != 1 || ids[0] != "o1" {
+		t.Errorf("OrphanedOrganizations() = %v, want [o1]", ids)
+	}
+}
+
+func TestLoad_MigratesOrganizations(t *testing.T) {
+	origData := Data
+	origFile := dataFile
+	defer func() {
+		Data = origData
+		dataFile = origFile
+	}()
+	dataFile = filepath.Join(t.TempDir(), "dossiers.json")
+	os.WriteFile(dataFile, []byte(`{"organizations":{"o1":{"name":"Old"},"o2":{"name":"Acme","members":["bob"],"admins":["carol"]}}}`), 0644)
+	Data = &DataStore{}
+	Load()
+
+	if o := Data.Organizations["o1"]; o.Members == nil || o.Admins == nil {
+		t.Errorf("o1 lists not initialised: %+v", o)
+	}
+	if o := Data.Organizations["o2"]; !contains(o.Members, "carol") {
+		t.Errorf("admin carol should be a member of o2: %+v", o.Members)
+	}
+}
+
+func TestGrantPathAndChain(t *testing.T) {
+	d := &Dossier{Owners: []string{"alice"}, Relations: []Relation{
+		{User: "bob", Relation: "mandate_sharer", GrantedBy: "alice"},
+		{User: "carol", Relation: "mandate_sharer", GrantedBy: "bob"},
+		{User: "dave", Relation: "mandate_viewer", GrantedBy: "carol"},
+		{User: "erin", Relation: "mandate_viewer", GrantedBy: "alice"},
+		{User: "frank", Relation: "mandate_holder"},
+		{User: "carol", Relation: "can_view", GrantedBy: "alice"},
+	}}
+	if got := strings.Join(d.GrantPath("dave"), ","); got != "alice,bob,carol,dave" {
+		t.Errorf("GrantPath(dave) = %s", got)
+	}
+	if got := d.GrantPath("frank"); len(got) != 1 {
+		t.Errorf("legacy grant path = %v, want [frank]", got)
+	}
+	var users []string
+	for _, rel := range d.GrantChain("bob") {
+		users = append(users, rel.User)
+	}
+	// carol's can_view comes straight from the owner and survives bob's revocation
+	if strings.Join(users, ",") != "bob,carol,dave" {
+		t.Errorf("GrantChain(bob) = %v", users)
+	}
+}
+
+func TestContentEncryption_RoundtripAndRotation(t *testing.T) {
+	origData, origFile := Data, dataFile
+	defer func() { Data, dataFile = origData, origFile }()
+	defer SetContentKeys("")
+	key1 := base64.StdEncoding.EncodeToString(bytes.Repeat([]byte{1}, 32))
+	key2 := base64.StdEncoding.EncodeToString(bytes.Repeat([]byte{2}, 32))
+
+	if err := SetContentKeys("k1:short"); err == nil {
+		t.Error("a key that is not 32 bytes should be rejected")
+	}
+
+	// Plaintext written before encryption was enabled.
+	dataFile = filepath.Join(t.TempDir(), "dossiers.json")
+	os.WriteFile(dataFile, []byte(`{"dossiers":{"d1":{"title":"Health","content":"Blood test results","type":"health","owners":["alice"]}}}`), 0644)
+	Data = &DataStore{}
+	Load()
+	if st := Encryption(); st.Enabled || st.Sealed[""] != 1 {
+		t.Fatalf("status before = %+v", st)
+	}
+
+	if err := SetContentKeys("k1:" + key1); err != nil {
+		t.Fatal(err)
+	}
+	Reseal()
+	raw, _ := os.ReadFile(dataFile)
+	if strings.Contains(string(raw), "Blood test") || !strings.Contains(string(raw), "enc:v1:k1:") {
+		t.Fatalf("data file still holds plaintext: %s", raw)
+	}
+	if Data.Dossiers["d1"].Content != "Blood test results" {
+		t.Errorf("in-memory content = %q, want plaintext", Data.Dossiers["d1"].Content)
+	}
+
+	// Rotate: k2 encrypts from now on, k1 still opens what it sealed.
+	SetContentKeys("k2:" + key2 + ",k1:" + key1)
+	Data = &DataStore{}
+	Load()
+	if Data.Dossiers["d1"].Content != "Blood test results" {
+		t.Fatalf("content sealed with k1 did not open after rotation: %q", Data.Dossiers["d1"].Content)
+	}
+	Reseal()
+	if st := Encryption(); st.ActiveKey != "k2" || st.Sealed["k2"] != 1 || st.Sealed["k1"] != 0 {
+		t.Errorf("status after rotation = %+v", st)
+	}
+
+	// Without its key the content stays sealed rather than being lost.
+	SetContentKeys("k1:" + key1)
+	Data = &DataStore{}
+	Load()
+	Save()
+	if st := Encryption(); st.Sealed["k2"] != 1 {
+		t.Errorf("content under a missing key was rewritten: %+v", st)
+	}
+}
+
+func TestCompactAndSize(t *testing.T) {
+	origData := Data
+	origFile := dataFile
+	defer func() {
+		Data = origData
+		dataFile = origFile
+		orphanArchives = map[string]bool{}
+	}()
+	dataFile = filepath.Join(t.TempDir(), "dossiers.json")
+	old := Meta{UpdatedAt: time.Now().Add(-48 * time.Hour)}
+	Data = &DataStore{
+		Dossiers: map[string]*Dossier{
+			"d1": {Title: "Tax", Content: "x", Owners: []string{"alice"}},
+			"d2": {Title: "Gone", Content: "y", Owners: []string{"alice"}},
+		},
+		GuardianshipRequests: []GuardianshipRequest{
+			{Id: "g1", Status: "accepted", Meta: old},
+			{Id: "g2", Status: "pending", Meta: old},
+			{Id: "g3", Status: "denied", Meta: NewMeta("bob")},
+		},
+		Guardianships:  map[string][]string{"alice": {"bob"}, "carol": {}},
+		JoinRequests:   []JoinRequest{{Id: "j1", Status: "approved", Meta: old}},
+		AccessRequests: []AccessRequest{{Id: "a1", Status: "pending", Meta: old}},
+	}
+	if err := Archive("d2"); err != nil {
+		t.Fatalf("Archive: %v", err)
+	}
+	delete(Data.Dossiers, "d2")
+
+	rep := Compact(24 * time.Hour)
+	if rep.DecidedRequests != 2 || rep.EmptyGuardianships != 1 || rep.OrphanArchives != 0 {
+		t.Errorf("first compaction = %+v", rep)
+	}
+	if len(Data.GuardianshipRequests) != 2 || len(Data.JoinRequests) != 0 || len(Data.AccessRequests) != 1 {
+		t.Errorf("requests left: %d guardianship, %d join, %d access", len(Data.GuardianshipRequests), len(Data.JoinRequests), len(Data.AccessRequests))
+	}
+	if _, ok := Data.Guardianships["carol"]; ok {
+		t.Error("empty guardianship should be dropped")
+	}
+	// The orphaned archive is only removed once it was already orphaned at the previous run.
+	if rep := Compact(24 * time.Hour); rep.OrphanArchives != 1 {
+		t.Errorf("second compaction = %+v", rep)
+	}
+	if _, err := os.Stat(archivePath("d2")); !os.IsNotExist(err) {
+		t.Error("orphaned archive should be removed")
+	}
+
+	Save()
+	size := Size(1)
+	if size.DataBytes == 0 || !size.OverThreshold || size.Entities["dossiers"] != 1 || size.ArchiveFiles != 0 {
+		t.Errorf("Size = %+v", size)
+	}
+	if Size(0).OverThreshold {
+		t.Error("a zero threshold disables the check")
+	}
+}
+
+func TestSave_FailureKeepsPreviousFile(t *testing.T) {
+	origData, origFile := Data, dataFile
+	defer func() {
+		Data, dataFile, LastSaveErr = origData, origFile, nil
+	}()
+	dataFile = filepath.Join(t.TempDir(), "dossiers.json")
+	Data = &DataStore{Dossiers: map[string]*Dossier{"x1": {Title: "Kept", Owners: []string{"alice"}}}}
+	Save()
+	before, _ := os.ReadFile(dataFile)
+
+	// A directory in the way of the temporary file makes the next save fail.
+	if err := os.Mkdir(dataFile+".tmp", 0755); err != nil {
+		t.Fatal(err)
+	}
+	Data.Dossiers["x2"] = &Dossier{Title: "Lost", Owners: []string{"bob"}}
+	Save()
+	if LastSaveErr == nil {
+		t.Fatal("LastSaveErr not set")
+	}
+	if after, _ := os.ReadFile(dataFile); string(after) != string(before) {
+		t.Error("failed save changed the data file")
+	}
+}
+
+func TestDisk(t *testing.T) {
+	origFile := dataFile
+	defer func() { dataFile = origFile }()
+	dataFile = filepath.Join(t.TempDir(), "dossiers.json")
+
+	rep := Disk(0)
+	if rep.Error == errDiskUnsupported.Error() {
+		t.Skip(rep.Error)
+	}
+	if rep.Error != "" || rep.FreeBytes == 0 || rep.TotalBytes < rep.FreeBytes || rep.Low {
+		t.Errorf("Disk(0) = %+v", rep)
+	}
+	if rep := Disk(math.MaxInt64); !rep.Low {
+		t.Errorf("Disk(max) = %+v, want low", rep)
+	}
+}
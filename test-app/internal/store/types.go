@@ -1,32 +1,90 @@
 package store
 
+import "time"
+
 type Dossier struct {
 	Title        string     `json:"title"`
 	Content      string     `json:"content"`
+	ContentType  string     `json:"contentType,omitempty"`
 	Type         string     `json:"type"`
-	Owner        string     `json:"owner"`
+	Owners       []string   `json:"owners"`
+	LegacyOwner  string     `json:"owner,omitempty"`
 	Relations    []Relation `json:"relations,omitempty"`
 	OrgId        string     `json:"orgId,omitempty"`
 	Public       bool       `json:"public,omitempty"`
 	BlockedUsers []string   `json:"blockedUsers,omitempty"`
+	Sensitivity  string     `json:"sensitivity,omitempty"`
+	ArchivedAt   *time.Time `json:"archivedAt,omitempty"`
+	Meta
+}
+
+// Meta is the provenance every stored entity carries. Handlers set it with
+// NewMeta on creation and call Updated after each change.
+type Meta struct {
+	CreatedAt time.Time `json:"createdAt"`
+	UpdatedAt time.Time `json:"updatedAt"`
+	CreatedBy string    `json:"createdBy,omitempty"`
+}
+
+// NewMeta returns metadata for an entity user creates now.
+func NewMeta(user string) Meta {
+	now := time.Now().UTC()
+	return Meta{CreatedAt: now, UpdatedAt: now, CreatedBy: user}
+}
+
+// Updated stamps the entity as changed now.
+func (m *Meta) Updated() {
+	m.UpdatedAt = time.Now().UTC()
+}
+
+// IsOwner reports whether user is one of the dossier's owners.
+func (d *Dossier) IsOwner(user string) bool {
+	for _, o := range d.Owners {
+		if o == user {
+			return true
+		}
+	}
+	return false
+}
+
+// PrimaryOwner returns the first owner, who created the dossier.
+func (d *Dossier) PrimaryOwner() string {
+	if len(d.Owners) == 0 {
+		return ""
+	}
+	return d.Owners[0]
 }
 
 type Organization struct {
-	Name    string   `json:"name"`
-	Members []string `json:"members"`
-	Admins  []string `json:"admins"`
+	Name        string   `json:"name"`
+	Description string   `json:"description,omitempty"`
+	Contact     string   `json:"contact,omitempty"`
+	Members     []string `json:"members"`
+	Admins      []string `json:"admins"`
+	Meta
 }
 
 type Relation struct {
-	User     string `json:"user"`
-	Relation string `json:"relation"`
+	User         string        `json:"user"`
+	Relation     string        `json:"relation"`
+	Restrictions *Restrictions `json:"restrictions,omitempty"`
+	GrantedBy    string        `json:"grantedBy,omitempty"` // who shared it onward; empty for legacy grants
+}
+
+// Restrictions limit when and from where a mandate can be used.
+type Restrictions struct {
+	Hours    string   `json:"hours,omitempty"`
+	Weekdays bool     `json:"weekdays,omitempty"`
+	CIDRs    []string `json:"cidrs,omitempty"`
 }
 
 type GuardianshipRequest struct {
-	Id     string `json:"id"`
-	From   string `json:"from"`
-	To     string `json:"to"`
-	Status string `json:"status"`
+	Id        string `json:"id"`
+	From      string `json:"from"`
+	To        string `json:"to"`
+	Status    string `json:"status"` // pending, accepted, denied
+	DecidedBy string `json:"decidedBy,omitempty"`
+	Meta
 }
 
 type DataStore struct {
@@ -34,6 +92,99 @@ type DataStore struct {
 	GuardianshipRequests []GuardianshipRequest      `json:"guardianshipRequests"`
 	Guardianships        map[string][]string        `json:"guardianships"`
 	Organizations        map[string]*Organization   `json:"organizations,omitempty"`
+	Resources            map[string]*Resource       `json:"resources,omitempty"`
+	JoinRequests         []JoinRequest              `json:"joinRequests,omitempty"`
+	AccessRequests       []AccessRequest            `json:"accessRequests,omitempty"`
+	// Favorites maps a user to the ids of the dossiers they pinned, most recent last.
+	Favorites            map[string][]string        `json:"favorites,omitempty"`
+	SavedViews           map[string]*SavedView      `json:"savedViews,omitempty"`
+	// RoleGrants maps a user to the role mappings (see package rolesync) whose
+	// organization membership was granted to them because of a Keycloak role.
+	RoleGrants           map[string][]string        `json:"roleGrants,omitempty"`
+	// Profiles holds a record per user who signed in, keyed by username.
+	Profiles             map[string]*Profile        `json:"profiles,omitempty"`
+
+	// digests of each object as last saved, for the change events Save publishes
+	digests map[string]string
+}
+
+// JoinRequest is a user's request to become a member of an organization,
+// decided by one of its admins.
+type JoinRequest struct {
+	Id        string `json:"id"`
+	OrgId     string `json:"orgId"`
+	User      string `json:"user"`
+	Message   string `json:"message,omitempty"`
+	Status    string `json:"status"` // pending, approved, denied
+	DecidedBy string `json:"decidedBy,omitempty"`
+	Meta
+}
+
+// AccessRequest is a user's request for a mandate on a dossier they know the
+// id of, decided by one of its owners.
+type AccessRequest struct {
+	Id        string `json:"id"`
+	DossierId string `json:"dossierId"`
+	User      string `json:"user"`
+	Message   string `json:"message,omitempty"`
+	Status    string `json:"status"` // pending, approved, denied
+	DecidedBy string `json:"decidedBy,omitempty"`
+	Meta
+}
+
+// ViewFilter selects dossiers from a user's authorized dossier list. Empty
+// fields match everything.
+type ViewFilter struct {
+	Type       string `json:"type,omitempty"`
+	OrgId      string `json:"orgId,omitempty"`
+	Public     *bool  `json:"public,omitempty"`
+	Owned      bool   `json:"owned,omitempty"`      // only dossiers the user owns
+	SharedWith string `json:"sharedWith,omitempty"` // only dossiers with a relation for this user
+	CreatedBy  string `json:"createdBy,omitempty"`
+	Favorites  bool   `json:"favorites,omitempty"`
+	Query      string `json:"q,omitempty"` // case-insensitive title substring
+	Sort       string `json:"sort,omitempty"`
+}
+
+// SavedView is a named ViewFilter saved by its owner, keyed in
+// DataStore.SavedViews by id.
+type SavedView struct {
+	Id     string     `json:"id"`
+	Name   string     `json:"name"`
+	Owner  string     `json:"owner"`
+	Filter ViewFilter `json:"filter"`
+	Meta
+}
+
+// Profile is what the app knows of a user beyond their username (see
+// package profiles), provisioned from their OIDC claims or when Keycloak
+// reports their first sign-in or registration.
+type Profile struct {
+	DisplayName string     `json:"displayName,omitempty"`
+	Email       string     `json:"email,omitempty"`
+	Color       string     `json:"color"` // avatar initial background
+	Locale      string     `json:"locale,omitempty"`
+	Logins      int        `json:"logins"`
+	LastLogin   *time.Time `json:"lastLogin,omitempty"`
+	LastLogout  *time.Time `json:"lastLogout,omitempty"`
+	Meta
+}
+
+// Resource is an instance of a type registered with the resources package,
+// keyed in DataStore.Resources by its FGA object id ("type:id").
+type Resource struct {
+	Type      string            `json:"type"`
+	Id        string            `json:"id"`
+	Fields    map[string]string `json:"fields"`
+	Owners    []string          `json:"owners"`
+	Relations []Relation        `json:"relations,omitempty"`
+	OrgId     string            `json:"orgId,omitempty"`
+	Meta
+}
+
+// Object returns the resource's FGA object id.
+func (res *Resource) Object() string {
+	return res.Type + ":" + res.Id
 }
 
 type TupleKey struct {
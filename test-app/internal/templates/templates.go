@@ -1,10 +1,16 @@
 package templates
 
 import (
+	"embed"
 	"html/template"
 	"net/http"
 	"strings"
 	"time"
+
+	"test-app/internal/dossiertypes"
+	"test-app/internal/i18n"
+	"test-app/internal/profiles"
+	"test-app/internal/resources"
 )
 
 type PageData struct {
@@ -18,20 +24,62 @@ type PageData struct {
 	IsPublic   bool
 	Decision   string
 	StatusIcon string
+	Lang       string
 }
 
 type DossiersPageData struct {
 	Username string
+	Path     string
+	IsPublic bool
+	Lang     string
+}
+
+// ResourcesPageData renders the page of one registered resource type.
+type ResourcesPageData struct {
+	Username string
+	Path     string
+	IsPublic bool
+	Lang     string
+	Type     *resources.Type
+}
+
+// FragmentData is passed to the server-rendered /partials/* fragments.
+type FragmentData struct {
+	Lang  string
+	Items interface{}
 }
 
+//go:embed *.html partials/*.html fragments/*.html
+var files embed.FS
+
 var (
-	Page     *template.Template
-	Dossiers *template.Template
+	Page      *template.Template
+	Dossiers  *template.Template
+	Resources *template.Template
+	Fragments *template.Template
 )
 
-func Init(templateDir string) {
-	Page = template.Must(template.New("home.html").ParseFiles(templateDir + "/home.html"))
-	Dossiers = template.Must(template.New("dossiers.html").ParseFiles(templateDir + "/dossiers.html"))
+// Init parses each page together with the shared partials (head, nav, footer)
+// from the embedded filesystem, so rendering does not depend on the working directory.
+func Init() {
+	Page = parsePage("home.html")
+	Dossiers = parsePage("dossiers.html")
+	Resources = parsePage("resources.html")
+	Fragments = template.Must(template.New("fragments").Funcs(funcs).ParseFS(files, "fragments/*.html"))
+}
+
+// funcs exposes {{T .Lang "message"}} to templates for translated UI strings,
+// users' display names, the registered resource types the nav links to and
+// the dossier types the new-dossier form offers.
+var funcs = template.FuncMap{
+	"T":             func(lang, msg string) string { return i18n.Translate(lang, msg) },
+	"displayName":   profiles.DisplayName,
+	"resourceTypes": resources.Types,
+	"dossierTypes":  dossiertypes.All,
+}
+
+func parsePage(name string) *template.Template {
+	return template.Must(template.New(name).Funcs(funcs).ParseFS(files, "partials/*.html", name))
 }
 
 func BuildPageData(r *http.Request, isPublic bool) PageData {
@@ -70,5 +118,6 @@ func BuildPageData(r *http.Request, isPublic bool) PageData {
 		IsPublic:   isPublic,
 		Decision:   decision,
 		StatusIcon: statusIcon,
+		Lang:       i18n.Lang(r),
 	}
 }
@@ -2,6 +2,7 @@ package templates
 
 import (
 	"net/http/httptest"
+	"strings"
 	"testing"
 )
 
@@ -80,8 +81,7 @@ func TestBuildPageData_RoleParsing(t *testing.T) {
 }
 
 func TestInit(t *testing.T) {
-	// Uses actual template files in same directory
-	Init(".")
+	Init()
 	if Page == nil {
 		t.Error("Page template is nil after Init")
 	}
@@ -89,3 +89,54 @@ func TestInit(t *testing.T) {
 		t.Error("Dossiers template is nil after Init")
 	}
 }
+
+func TestPagesRenderSharedPartials(t *testing.T) {
+	Init()
+
+	var home strings.Builder
+	r := httptest.NewRequest("GET", "/home", nil)
+	r.Header.Set("x-current-user", "alice")
+	if err := Page.Execute(&home, BuildPageData(r, false)); err != nil {
+		t.Fatalf("home render: %v", err)
+	}
+	var dossiers strings.Builder
+	if err := Dossiers.Execute(&dossiers, DossiersPageData{Username: "alice", Path: "/dossiers"}); err != nil {
+		t.Fatalf("dossiers render: %v", err)
+	}
+
+	for name, out := range map[string]string{"home": home.String(), "dossiers": dossiers.String()} {
+		if !strings.Contains(out, `<nav>`) || !strings.Contains(out, `<footer>`) {
+			t.Errorf("%s page missing shared nav/footer", name)
+		}
+	}
+	if !strings.Contains(dossiers.String(), `<a href="/dossiers" class="active">`) {
+		t.Error("dossiers nav link should be active")
+	}
+	if !strings.Contains(dossiers.String(), `id="liveIndicator"`) {
+		t.Error("dossiers page should render its nav-extra block")
+	}
+	if strings.Contains(home.String(), `id="liveIndicator"`) {
+		t.Error("home page should not render the dossiers nav-extra block")
+	}
+	if !strings.Contains(dossiers.String(), "<title>AuthZ POC - Citizen Mandate System</title>") {
+		t.Error("dossiers page should override the title block")
+	}
+}
+
+func TestPagesTranslated(t *testing.T) {
+	Init()
+	r := httptest.NewRequest("GET", "/home", nil)
+	r.Header.Set("x-current-user", "alice")
+	r.Header.Set("Accept-Language", "nl")
+
+	var out strings.Builder
+	if err := Page.Execute(&out, BuildPageData(r, false)); err != nil {
+		t.Fatalf("render: %v", err)
+	}
+	if !strings.Contains(out.String(), "Afmelden") {
+		t.Error("nav should be rendered in Dutch")
+	}
+	if !strings.Contains(out.String(), `<html lang="nl">`) {
+		t.Error("html lang attribute should reflect negotiated language")
+	}
+}
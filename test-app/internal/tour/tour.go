@@ -0,0 +1,142 @@
+// Package tour is the machine-readable manifest of the demo scenarios: what
+// each one needs in place, the requests a presenter makes and what they should
+// answer. Each scenario carries a fixture so it can be set up on demand.
+package tour
+
+import (
+	"test-app/internal/analytics"
+	"test-app/internal/store"
+)
+
+// Step is one request of a scenario, made as the given user.
+type Step struct {
+	As      string `json:"as"`
+	Method  string `json:"method"`
+	Path    string `json:"path"`
+	Body    string `json:"body,omitempty"`
+	Expect  int    `json:"expect"` // HTTP status the step answers once set up
+	Explain string `json:"explain"`
+}
+
+// Fixture is the state a scenario starts from. Its entities use fixed tour-*
+// ids, so setting a scenario up again replaces them rather than adding copies.
+type Fixture struct {
+	Dossiers      map[string]*store.Dossier
+	Organizations map[string]*store.Organization
+	// Guardianships maps a ward to the guardians added for the scenario. Both
+	// sides are tour-* users, so setup never changes who real users can see.
+	Guardianships map[string][]string
+}
+
+// Scenario is one walkthrough of the tour.
+type Scenario struct {
+	Id            string   `json:"id"`
+	Title         string   `json:"title"`
+	Milestone     string   `json:"milestone,omitempty"` // analytics milestone the scenario exercises
+	Preconditions []string `json:"preconditions"`
+	Steps         []Step   `json:"steps"`
+	// Fixture builds fresh entities on every call, since setup hands them to the store.
+	Fixture func() Fixture `json:"-"`
+}
+
+// dossier returns a dossier owned by owner, created by the tour.
+func dossier(title, typ, owner string) *store.Dossier {
+	return &store.Dossier{Title: title, Type: typ, Content: "Prepared by the guided tour.", Owners: []string{owner}, Meta: store.NewMeta(owner)}
+}
+
+// Scenarios lists the tour in presentation order.
+var Scenarios = []Scenario{
+	{
+		Id:    "org-access",
+		Title: "Organization members view the organization's dossiers",
+		Preconditions: []string{
+			"organization tour-org with alice (admin) and bob as members",
+			"dossier tour-org-dossier owned by alice, attached to tour-org",
+		},
+		Steps: []Step{
+			{As: "bob", Method: "GET", Path: "/api/dossiers/tour-org-dossier", Expect: 200, Explain: "bob views it through org_parent -> member"},
+			{As: "bob", Method: "PUT", Path: "/api/dossiers/tour-org-dossier", Body: `{"title":"Edited"}`, Expect: 403, Explain: "membership grants viewing, not editing"},
+		},
+		Fixture: func() Fixture {
+			d := dossier("Shared budget", "general", "alice")
+			d.OrgId = "tour-org"
+			return Fixture{
+				Dossiers: map[string]*store.Dossier{"tour-org-dossier": d},
+				Organizations: map[string]*store.Organization{"tour-org": {
+					Name: "Tour organization", Members: []string{"alice", "bob"}, Admins: []string{"alice"}, Meta: store.NewMeta("alice"),
+				}},
+			}
+		},
+	},
+	{
+		Id:            "blocked-user",
+		Title:         "A blocked user loses access even to a public dossier",
+		Milestone:     analytics.UserBlocked,
+		Preconditions: []string{"public dossier tour-blocked owned by alice, with bob blocked"},
+		Steps: []Step{
+			{As: "bob", Method: "GET", Path: "/api/dossiers/tour-blocked", Expect: 403, Explain: "blocked overrides public"},
+			{As: "alice", Method: "POST", Path: "/api/dossiers/tour-blocked/unblock", Body: `{"targetUser":"bob"}`, Expect: 200, Explain: "the owner lifts the block"},
+			{As: "bob", Method: "GET", Path: "/api/dossiers/tour-blocked", Expect: 200, Explain: "bob sees the public dossier again"},
+		},
+		Fixture: func() Fixture {
+			d := dossier("Neighbourhood notice", "general", "alice")
+			d.Public, d.BlockedUsers = true, []string{"bob"}
+			return Fixture{Dossiers: map[string]*store.Dossier{"tour-blocked": d}}
+		},
+	},
+	{
+		Id:            "public-dossier",
+		Title:         "Public dossiers are readable by everyone, editable by their owners",
+		Milestone:     analytics.DossierMadePublic,
+		Preconditions: []string{"public dossier tour-public owned by alice"},
+		Steps: []Step{
+			{As: "bob", Method: "GET", Path: "/api/dossiers/tour-public", Expect: 200, Explain: "user:* public grants viewing"},
+			{As: "bob", Method: "PUT", Path: "/api/dossiers/tour-public", Body: `{"title":"Edited"}`, Expect: 403, Explain: "but not editing"},
+		},
+		Fixture: func() Fixture {
+			d := dossier("Published report", "general", "alice")
+			d.Public = true
+			return Fixture{Dossiers: map[string]*store.Dossier{"tour-public": d}}
+		},
+	},
+	{
+		Id:            "emergency-access",
+		Title:         "Emergency access is evaluated with contextual tuples, nothing is granted",
+		Milestone:     analytics.EmergencyCheck,
+		Preconditions: []string{"private health dossier tour-emergency owned by alice; bob has no relation to it"},
+		Steps: []Step{
+			{As: "bob", Method: "GET", Path: "/api/dossiers/tour-emergency", Expect: 403, Explain: "bob has no access"},
+			{As: "bob", Method: "POST", Path: "/api/dossiers/tour-emergency/emergency-check", Body: `{"user":"bob"}`, Expect: 200, Explain: "allowed: true under the contextual can_view tuple"},
+			{As: "bob", Method: "GET", Path: "/api/dossiers/tour-emergency", Expect: 403, Explain: "the check stored nothing"},
+		},
+		Fixture: func() Fixture {
+			return Fixture{Dossiers: map[string]*store.Dossier{"tour-emergency": dossier("Medical record", "health", "alice")}}
+		},
+	},
+	{
+		Id:            "guardian-access",
+		Title:         "Guardians view their wards' dossiers",
+		Milestone:     analytics.GuardianshipAccepted,
+		Preconditions: []string{"tour-parent is a guardian of tour-child", "private dossier tour-guardian owned by tour-child"},
+		Steps: []Step{
+			{As: "tour-parent", Method: "GET", Path: "/api/dossiers/tour-guardian", Expect: 200, Explain: "owner -> guardian grants viewing"},
+			{As: "tour-parent", Method: "DELETE", Path: "/api/dossiers/tour-guardian", Expect: 403, Explain: "guardianship does not grant deletion"},
+		},
+		Fixture: func() Fixture {
+			return Fixture{
+				Dossiers:      map[string]*store.Dossier{"tour-guardian": dossier("Tax return", "tax", "tour-child")},
+				Guardianships: map[string][]string{"tour-child": {"tour-parent"}},
+			}
+		},
+	},
+}
+
+// Lookup returns the scenario with the given id.
+func Lookup(id string) (Scenario, bool) {
+	for _, s := range Scenarios {
+		if s.Id == id {
+			return s, true
+		}
+	}
+	return Scenario{}, false
+}
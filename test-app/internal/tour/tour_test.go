@@ -0,0 +1,47 @@
+package tour
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestScenarios_StepsUseTheirFixture(t *testing.T) {
+	seen := map[string]bool{}
+	for _, s := range Scenarios {
+		if seen[s.Id] {
+			t.Errorf("duplicate scenario id %s", s.Id)
+		}
+		seen[s.Id] = true
+		if len(s.Preconditions) == 0 || len(s.Steps) == 0 {
+			t.Errorf("%s: preconditions and steps are required", s.Id)
+		}
+		f := s.Fixture()
+		for _, step := range s.Steps {
+			id := strings.Split(strings.TrimPrefix(step.Path, "/api/dossiers/"), "/")[0]
+			if f.Dossiers[id] == nil {
+				t.Errorf("%s: step %s %s targets %s, which the fixture does not create", s.Id, step.Method, step.Path, id)
+			}
+		}
+		for id := range f.Dossiers {
+			if !strings.HasPrefix(id, "tour-") {
+				t.Errorf("%s: fixture dossier %s must use a tour- id", s.Id, id)
+			}
+		}
+		for ward, guardians := range f.Guardianships {
+			for _, g := range append([]string{ward}, guardians...) {
+				if !strings.HasPrefix(g, "tour-") {
+					t.Errorf("%s: guardianship of %s involves %s, which is not a tour- user", s.Id, ward, g)
+				}
+			}
+		}
+		again := s.Fixture()
+		for id := range f.Dossiers {
+			if again.Dossiers[id] == f.Dossiers[id] {
+				t.Errorf("%s: Fixture must build fresh dossiers", s.Id)
+			}
+		}
+	}
+	if _, ok := Lookup("blocked-user"); !ok {
+		t.Error("Lookup(blocked-user) failed")
+	}
+}
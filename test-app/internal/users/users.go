@@ -0,0 +1,38 @@
+// Package users validates usernames before they are embedded in OpenFGA
+// identifiers such as "user:<name>". A name containing ':' or '#' would
+// produce a malformed tuple, and "*" the public wildcard "user:*".
+package users
+
+import (
+	"errors"
+	"strings"
+	"unicode"
+)
+
+// MaxLength matches Keycloak's username limit.
+const MaxLength = 255
+
+// ErrInvalid is returned for names that cannot be used as an FGA user id.
+var ErrInvalid = errors.New("usernames cannot contain ':', '#', '*' or whitespace")
+
+// Normalize trims and lowercases name, as Keycloak does, and rejects names
+// that are not safe in a tuple. An empty name is returned as is so callers
+// keep reporting missing fields themselves.
+func Normalize(name string) (string, error) {
+	name = strings.ToLower(strings.TrimSpace(name))
+	if len(name) > MaxLength {
+		return "", ErrInvalid
+	}
+	for _, c := range name {
+		if c == ':' || c == '#' || c == '*' || unicode.IsSpace(c) || unicode.IsControl(c) {
+			return "", ErrInvalid
+		}
+	}
+	return name, nil
+}
+
+// Valid reports whether name is already in normalized form.
+func Valid(name string) bool {
+	n, err := Normalize(name)
+	return err == nil && n == name && name != ""
+}
@@ -0,0 +1,24 @@
+package users
+
+import "testing"
+
+func TestNormalize(t *testing.T) {
+	for in, want := range map[string]string{
+		"alice":    "alice",
+		" Alice ":  "alice",
+		"jean.dup": "jean.dup",
+		"":         "",
+	} {
+		if got, err := Normalize(in); err != nil || got != want {
+			t.Errorf("Normalize(%q) = %q, %v; want %q", in, got, err, want)
+		}
+	}
+	for _, in := range []string{"*", "bob:admin", "org#member", "al ice", "tab\tbed", "user:*"} {
+		if _, err := Normalize(in); err != ErrInvalid {
+			t.Errorf("Normalize(%q) error = %v, want ErrInvalid", in, err)
+		}
+	}
+	if Valid("Alice") || Valid("") || !Valid("alice") {
+		t.Error("Valid accepts only non-empty normalized names")
+	}
+}
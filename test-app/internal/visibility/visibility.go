@@ -0,0 +1,246 @@
+// Package visibility keeps an optional materialized index of the dossiers each
+// user can view, so read-heavy list pages need not call ListObjects every
+// time. The index is rebuilt in the background from tuple events and OpenFGA's
+// ReadChanges feed; a stale entry is never served, the caller falls back to a
+// live ListObjects instead.
+package visibility
+
+import (
+	"context"
+	"errors"
+	"log"
+	"strings"
+	"sync"
+	"time"
+
+	"test-app/internal/backoff"
+	"test-app/internal/config"
+	"test-app/internal/events"
+	"test-app/internal/fga"
+	"test-app/internal/sandbox"
+)
+
+// Mark tells the caller where a list came from and how current it is.
+// Watermark counts the relevant tuple changes the index has seen; an index
+// answer reflects every change up to it.
+type Mark struct {
+	Source    string    `json:"source"` // "index" or "live"
+	Watermark uint64    `json:"watermark"`
+	BuiltAt   time.Time `json:"builtAt,omitempty"`
+}
+
+type entry struct {
+	ids       []string
+	watermark uint64
+	built     time.Time
+	storeId   string
+}
+
+var (
+	mu        sync.Mutex
+	index     = map[string]entry{}
+	watermark uint64
+	// liveStore is the store the index was last built from; a request seeing
+	// another one runs in a sandbox.
+	liveStore string
+	queued    = map[string]bool{}
+	queue     = make(chan string, 1024)
+)
+
+var errNotReady = errors.New("OpenFGA config not loaded")
+
+// Visible returns the dossier objects user can view, from the index when its
+// entry is current and from a live ListObjects otherwise (which also queues
+// the user for indexing). Sandboxed requests always go live. It fails when
+// the live list cannot be obtained. Live lists count against the budget of
+// the request ctx belongs to.
+func Visible(ctx context.Context, user string) ([]string, Mark, error) {
+	if config.VisibilityIndex {
+		mu.Lock()
+		e, ok := index[user]
+		wm := watermark
+		fresh := ok && e.watermark == wm && e.storeId == config.FgaStoreId && time.Since(e.built) <= config.VisibilityMaxLag.Get()
+		mu.Unlock()
+		if fresh {
+			return append([]string(nil), e.ids...), Mark{Source: "index", Watermark: e.watermark, BuiltAt: e.built}, nil
+		}
+		if !sandboxed() {
+			enqueue(user)
+		}
+		ids, err := fga.ListObjects(ctx, fga.UserRef(user), "viewer", fga.TypeDossier)
+		return ids, Mark{Source: "live", Watermark: wm}, err
+	}
+	ids, err := fga.ListObjects(ctx, fga.UserRef(user), "viewer", fga.TypeDossier)
+	return ids, Mark{Source: "live"}, err
+}
+
+// Run maintains the index until the process exits: it rebuilds queued users,
+// invalidates on tuple events and polls ReadChanges every interval for writes
+// made outside the app. Entries older than half of VisibilityMaxLag are
+// refreshed on each poll so hot users keep being served from the index.
+func Run(interval time.Duration) {
+	// Woken by LoadConfig as soon as OpenFGA's config is in.
+	backoff.Retry(backoff.Policy{Initial: 50 * time.Millisecond, Max: time.Second}, func(int) error {
+		if !config.FgaReady {
+			return errNotReady
+		}
+		return nil
+	})
+	events.Subscribe(func(e events.Event) {
+		if e.Type == events.TupleWritten || e.Type == events.TupleDeleted {
+			if affectsDossiers(e.Object) {
+				invalidate()
+			}
+		}
+	})
+	go func() {
+		for user := range queue {
+			rebuild(user)
+		}
+	}()
+
+	// The first drain only finds the current position; the index starts empty.
+	token := ""
+	sandbox.Live(func() { token, _ = drainChanges("") })
+	for {
+		time.Sleep(interval)
+		changed := false
+		sandbox.Live(func() { token, changed = drainChanges(token) })
+		if changed {
+			invalidate()
+		}
+		mu.Lock()
+		var refresh []string
+		for user, e := range index {
+			if time.Since(e.built) > config.VisibilityMaxLag.Get()/2 {
+				refresh = append(refresh, user)
+			}
+		}
+		mu.Unlock()
+		for _, user := range refresh {
+			enqueue(user)
+		}
+	}
+}
+
+// drainChanges reads ReadChanges from token until it stops advancing and
+// reports whether any dossier-relevant tuple changed on the way.
+func drainChanges(token string) (next string, changed bool) {
+	for {
+		tuples, after, err := fga.ReadChanges(token)
+		if err != nil {
+			log.Printf("WARNING: visibility index: reading changes: %v", err)
+			return token, changed
+		}
+		for _, t := range tuples {
+			if affectsDossiers(t.Object) {
+				changed = true
+			}
+		}
+		if len(tuples) == 0 || after == token {
+			return after, changed
+		}
+		token = after
+	}
+}
+
+// affectsDossiers reports whether a change to object can change who views a
+// dossier: the dossier itself, or a user or organization reached through it.
+func affectsDossiers(object string) bool {
+	typ, _, _ := strings.Cut(object, ":")
+	return typ == "dossier" || typ == "user" || typ == "organization"
+}
+
+// invalidate moves the watermark past every entry and queues them for rebuilding.
+func invalidate() {
+	mu.Lock()
+	watermark++
+	users := make([]string, 0, len(index))
+	for user := range index {
+		users = append(users, user)
+	}
+	mu.Unlock()
+	for _, user := range users {
+		enqueue(user)
+	}
+}
+
+func enqueue(user string) {
+	mu.Lock()
+	defer mu.Unlock()
+	if queued[user] {
+		return
+	}
+	select {
+	case queue <- user:
+		queued[user] = true
+	default:
+		// Full: the user stays live until a later request queues it again.
+	}
+}
+
+// Prime builds user's entry right away, for the startup warm-up.
+func Prime(user string) {
+	rebuild(user)
+}
+
+// rebuild recomputes user's entry against the live store. The watermark is
+// read first, so a change racing the ListObjects leaves the entry stale.
+func rebuild(user string) {
+	mu.Lock()
+	delete(queued, user)
+	wm := watermark
+	mu.Unlock()
+	var ids []string
+	var storeId string
+	var err error
+	sandbox.Live(func() {
+		storeId = config.FgaStoreId
+		ids, err = fga.ListObjects(context.Background(), fga.UserRef(user), "viewer", fga.TypeDossier)
+	})
+	if err != nil {
+		// Keep serving the user live rather than indexing an empty list.
+		log.Printf("Visibility index: rebuilding %s failed: %v", user, err)
+		return
+	}
+	mu.Lock()
+	index[user] = entry{ids: ids, watermark: wm, built: time.Now(), storeId: storeId}
+	liveStore = storeId
+	mu.Unlock()
+}
+
+// sandboxed reports whether the current request runs against a sandbox store,
+// whose tuples the index does not follow.
+func sandboxed() bool {
+	mu.Lock()
+	defer mu.Unlock()
+	return liveStore != "" && liveStore != config.FgaStoreId
+}
+
+// Stats describes the index for the admin API.
+type Stats struct {
+	Enabled   bool   `json:"enabled"`
+	Users     int    `json:"users"`
+	Queued    int    `json:"queued"`
+	Watermark uint64 `json:"watermark"`
+}
+
+// Status returns the index size, queue length and watermark.
+func Status() Stats {
+	mu.Lock()
+	defer mu.Unlock()
+	return Stats{Enabled: config.VisibilityIndex, Users: len(index), Queued: len(queued), Watermark: watermark}
+}
+
+// Reset empties the index (for tests).
+func Reset() {
+	mu.Lock()
+	defer mu.Unlock()
+	index = map[string]entry{}
+	queued = map[string]bool{}
+	watermark = 0
+	liveStore = ""
+	for len(queue) > 0 {
+		<-queue
+	}
+}
@@ -0,0 +1,69 @@
+package visibility
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"test-app/internal/config"
+	"test-app/internal/fga"
+)
+
+func TestVisibleServesCurrentEntriesOnly(t *testing.T) {
+	calls := 0
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if strings.HasSuffix(r.URL.Path, "/list-objects") {
+			calls++
+		}
+		json.NewEncoder(w).Encode(map[string]interface{}{"objects": []interface{}{"dossier:d1"}})
+	}))
+	defer server.Close()
+	origURL, origStore, origIndex, origTTL := config.OpenfgaURL, config.FgaStoreId, config.VisibilityIndex, config.ListObjectsCacheTTL.Get()
+	config.OpenfgaURL, config.FgaStoreId, config.VisibilityIndex = server.URL, "live-store", true
+	config.ListObjectsCacheTTL.Set(0)
+	defer func() {
+		config.OpenfgaURL, config.FgaStoreId, config.VisibilityIndex = origURL, origStore, origIndex
+		config.ListObjectsCacheTTL.Set(origTTL)
+	}()
+	Reset()
+	defer Reset()
+	fga.FlushListCache()
+
+	if _, mark, _ := Visible(context.Background(), "alice"); mark.Source != "live" || len(queue) != 1 {
+		t.Fatalf("first call: mark %+v, queued %d", mark, len(queue))
+	}
+	rebuild(<-queue)
+	ids, mark, _ := Visible(context.Background(), "alice")
+	if mark.Source != "index" || len(ids) != 1 || ids[0] != "dossier:d1" {
+		t.Errorf("after rebuild: %v %+v", ids, mark)
+	}
+
+	invalidate()
+	if _, mark, _ := Visible(context.Background(), "alice"); mark.Source != "live" || mark.Watermark != 1 {
+		t.Errorf("after a change: %+v", mark)
+	}
+	rebuild(<-queue)
+	if _, mark, _ := Visible(context.Background(), "alice"); mark.Source != "index" {
+		t.Errorf("after second rebuild: %+v", mark)
+	}
+
+	// A sandboxed request sees another store and is neither served nor indexed.
+	config.FgaStoreId = "sandbox-store"
+	if _, mark, _ := Visible(context.Background(), "alice"); mark.Source != "live" || len(queue) != 0 {
+		t.Errorf("sandbox: %+v, queued %d", mark, len(queue))
+	}
+	config.FgaStoreId = "live-store"
+
+	origLag := config.VisibilityMaxLag.Get()
+	config.VisibilityMaxLag.Set(time.Nanosecond)
+	calls = 0
+	defer config.VisibilityMaxLag.Set(origLag)
+	time.Sleep(time.Millisecond)
+	if _, mark, _ := Visible(context.Background(), "alice"); mark.Source != "live" || calls != 1 {
+		t.Errorf("expired entry: %+v after %d calls", mark, calls)
+	}
+}
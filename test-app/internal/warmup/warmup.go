@@ -0,0 +1,106 @@
+// Package warmup runs once OpenFGA's config is loaded: it validates the model
+// with a canary write and check, then primes the ListObjects cache and the
+// visibility index for every known user, so the first requests of a demo do
+// not pay for cold caches. Readiness is reported only once it succeeded.
+package warmup
+
+import (
+	"context"
+	"log"
+	"sync"
+	"time"
+
+	"test-app/internal/backoff"
+	"test-app/internal/config"
+	"test-app/internal/fga"
+	"test-app/internal/sandbox"
+	"test-app/internal/store"
+	"test-app/internal/visibility"
+)
+
+// Status describes the outcome of the warm-up.
+type Status struct {
+	Done     bool      `json:"done"`
+	Users    int       `json:"users"`
+	Attempts int       `json:"attempts"`
+	Error    string    `json:"error,omitempty"`
+	Finished time.Time `json:"finished,omitempty"`
+	Duration string    `json:"duration,omitempty"`
+}
+
+// canaryAttempts bounds how often a failing canary is retried before the
+// warm-up gives up and the app stays not ready.
+const canaryAttempts = 5
+
+var (
+	mu     sync.Mutex
+	status Status
+	// retry paces the canary attempts (without delays in tests).
+	retry = backoff.Policy{Initial: 250 * time.Millisecond, Max: 4 * time.Second, Attempts: canaryAttempts}
+)
+
+// Run performs the warm-up. It expects config.FgaReady to be set.
+func Run() {
+	start := time.Now()
+	err := backoff.Retry(retry, func(attempt int) error {
+		setAttempt(attempt)
+		err := fga.Canary()
+		if err != nil {
+			log.Printf("WARNING: warm-up canary failed (%d/%d): %v", attempt, canaryAttempts, err)
+		}
+		return err
+	})
+	if err != nil {
+		mu.Lock()
+		status.Error = err.Error()
+		mu.Unlock()
+		return
+	}
+
+	users := store.KnownUsers()
+	for _, user := range users {
+		User(user)
+	}
+
+	mu.Lock()
+	status = Status{Done: true, Users: len(users), Attempts: status.Attempts, Finished: time.Now(), Duration: time.Since(start).Round(time.Millisecond).String()}
+	mu.Unlock()
+	log.Printf("Warm-up done: %d user(s) primed in %s", len(users), status.Duration)
+}
+
+// User primes the caches of one user: their visibility index entry, or the
+// ListObjects cache when the index is off. The Keycloak hook calls it at sign-in.
+func User(user string) {
+	if config.VisibilityIndex {
+		visibility.Prime(user)
+		return
+	}
+	sandbox.Live(func() { fga.ListObjects(context.Background(), fga.UserRef(user), "viewer", fga.TypeDossier) })
+}
+
+func setAttempt(n int) {
+	mu.Lock()
+	status.Attempts = n
+	mu.Unlock()
+}
+
+// Ready reports whether the warm-up succeeded.
+func Ready() bool {
+	mu.Lock()
+	defer mu.Unlock()
+	return status.Done
+}
+
+// Current returns the warm-up status.
+func Current() Status {
+	mu.Lock()
+	defer mu.Unlock()
+	return status
+}
+
+// Reset forgets the outcome (for tests).
+func Reset() {
+	mu.Lock()
+	status = Status{}
+	mu.Unlock()
+}
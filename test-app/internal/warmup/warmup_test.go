@@ -0,0 +1,54 @@
+package warmup
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"test-app/internal/backoff"
+	"test-app/internal/config"
+	"test-app/internal/store"
+)
+
+func TestRun(t *testing.T) {
+	allowed := true
+	var listed, deleted []string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var body map[string]interface{}
+		json.NewDecoder(r.Body).Decode(&body)
+		switch {
+		case strings.HasSuffix(r.URL.Path, "/write"):
+			if _, ok := body["deletes"]; ok {
+				deleted = append(deleted, r.URL.Path)
+			}
+			json.NewEncoder(w).Encode(map[string]interface{}{})
+		case strings.HasSuffix(r.URL.Path, "/check"):
+			json.NewEncoder(w).Encode(map[string]interface{}{"allowed": allowed})
+		case strings.HasSuffix(r.URL.Path, "/list-objects"):
+			listed = append(listed, body["user"].(string))
+			json.NewEncoder(w).Encode(map[string]interface{}{"objects": []interface{}{}})
+		}
+	}))
+	defer server.Close()
+	origURL, origData, origRetry := config.OpenfgaURL, store.Data, retry
+	config.OpenfgaURL, retry = server.URL, backoff.Policy{Attempts: canaryAttempts}
+	store.Data = &store.DataStore{Dossiers: map[string]*store.Dossier{"d1": {Owners: []string{"alice"}, Relations: []store.Relation{{User: "bob", Relation: "mandate_holder"}}}}}
+	defer func() { config.OpenfgaURL, store.Data, retry = origURL, origData, origRetry }()
+	defer Reset()
+
+	Reset()
+	Run()
+	if !Ready() || Current().Users != 2 || len(listed) != 2 || len(deleted) != 1 {
+		t.Errorf("status %+v, listed %v, canary deletes %d", Current(), listed, len(deleted))
+	}
+
+	// A model that does not derive viewer from owner never becomes ready.
+	Reset()
+	allowed, listed = false, nil
+	Run()
+	if st := Current(); Ready() || st.Attempts != canaryAttempts || st.Error == "" || len(listed) != 0 {
+		t.Errorf("failing canary: %+v, listed %v", st, listed)
+	}
+}
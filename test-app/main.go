@@ -6,15 +6,34 @@ import (
 	"net/http"
 	"net/url"
 	"os"
+	"os/signal"
+	"strconv"
 	"strings"
+	"syscall"
 	"time"
 
+	"test-app/internal/audit"
+	"test-app/internal/backoff"
+	"test-app/internal/backup"
+	"test-app/internal/budget"
 	"test-app/internal/config"
+	"test-app/internal/extauthz"
 	"test-app/internal/fga"
 	"test-app/internal/handlers"
 	"test-app/internal/httputil"
+	"test-app/internal/i18n"
+	"test-app/internal/identity"
+	"test-app/internal/jobs"
+	"test-app/internal/privacy"
+	"test-app/internal/profiles"
+	"test-app/internal/recent"
+	"test-app/internal/resources"
+	"test-app/internal/rolesync"
+	"test-app/internal/sandbox"
 	"test-app/internal/store"
 	"test-app/internal/templates"
+	"test-app/internal/visibility"
+	"test-app/internal/warmup"
 )
 
 func main() {
@@ -34,14 +53,419 @@ func main() {
 	if config.AuditURL == "" {
 		config.AuditURL = "http://ai-manager:5000"
 	}
+	if v := os.Getenv("ENDPOINT_COOLDOWN"); v != "" {
+		if d, err := time.ParseDuration(v); err == nil && d > 0 {
+			config.EndpointCooldown.Set(d)
+		} else {
+			log.Printf("WARNING: invalid ENDPOINT_COOLDOWN %q, using %s", v, config.EndpointCooldown.Get())
+		}
+	}
+	if v := os.Getenv("AUDIT_ALLOW_SAMPLE_RATE"); v != "" {
+		if f, err := strconv.ParseFloat(v, 64); err == nil && f >= 0 && f <= 1 {
+			config.AuditAllowSampleRate.Set(f)
+		} else {
+			log.Printf("WARNING: invalid AUDIT_ALLOW_SAMPLE_RATE %q, using %g", v, config.AuditAllowSampleRate.Get())
+		}
+	}
+	if v := os.Getenv("AUDIT_BURST_PER_SECOND"); v != "" {
+		if n, err := strconv.Atoi(v); err == nil && n >= 0 {
+			config.AuditBurstPerSecond.Set(n)
+		} else {
+			log.Printf("WARNING: invalid AUDIT_BURST_PER_SECOND %q, using %d", v, config.AuditBurstPerSecond.Get())
+		}
+	}
+	config.AIManagerURL = os.Getenv("AI_MANAGER_URL")
+	if config.AIManagerURL == "" {
+		config.AIManagerURL = "http://ai-manager:5000"
+	}
+	if mode := os.Getenv("REHYDRATE"); mode != "" {
+		config.RehydrateMode = mode
+	}
+	if v := os.Getenv("STEP_UP_ACR"); v != "" {
+		config.StepUpAcr = v
+	}
+	if v := os.Getenv("ASSERTIONS_INTERVAL"); v != "" {
+		if d, err := time.ParseDuration(v); err == nil {
+			config.AssertionsInterval = d
+		} else {
+			log.Printf("WARNING: invalid ASSERTIONS_INTERVAL %q, using %s", v, config.AssertionsInterval)
+		}
+	}
+
+	if v := os.Getenv("CACHE_MAX_AGE"); v != "" {
+		if n, err := strconv.Atoi(v); err == nil && n >= 0 {
+			config.CacheMaxAge.Set(n)
+		} else {
+			log.Printf("WARNING: invalid CACHE_MAX_AGE %q, using %d", v, config.CacheMaxAge.Get())
+		}
+	}
+
+	if v := os.Getenv("LIST_OBJECTS_CACHE_TTL"); v != "" {
+		if d, err := time.ParseDuration(v); err == nil && d >= 0 {
+			config.ListObjectsCacheTTL.Set(d)
+		} else {
+			log.Printf("WARNING: invalid LIST_OBJECTS_CACHE_TTL %q, using %s", v, config.ListObjectsCacheTTL.Get())
+		}
+	}
+
+	config.VisibilityIndex = os.Getenv("VISIBILITY_INDEX") == "true"
+	if v := os.Getenv("VISIBILITY_MAX_LAG"); v != "" {
+		if d, err := time.ParseDuration(v); err == nil && d > 0 {
+			config.VisibilityMaxLag.Set(d)
+		} else {
+			log.Printf("WARNING: invalid VISIBILITY_MAX_LAG %q, using %s", v, config.VisibilityMaxLag.Get())
+		}
+	}
+
+	if v := os.Getenv("COMPRESS_MIN_SIZE"); v != "" {
+		if n, err := strconv.Atoi(v); err == nil && n >= 0 {
+			config.CompressMinSize = n
+		} else {
+			log.Printf("WARNING: invalid COMPRESS_MIN_SIZE %q, using %d", v, config.CompressMinSize)
+		}
+	}
 
-	templates.Init("internal/templates")
+	if v := os.Getenv("MAX_CONTENT_SIZE"); v != "" {
+		if n, err := strconv.Atoi(v); err == nil && n > 0 {
+			config.MaxContentSize.Set(n)
+		} else {
+			log.Printf("WARNING: invalid MAX_CONTENT_SIZE %q, using %d", v, config.MaxContentSize.Get())
+		}
+	}
+	if v := os.Getenv("INTEGRITY_MODE"); v != "" {
+		config.IntegrityMode = v
+	}
+	if v := os.Getenv("BACKUP_DIR"); v != "" {
+		config.BackupDir = v
+	}
+	if v := os.Getenv("BACKUP_INTERVAL"); v != "" {
+		if d, err := time.ParseDuration(v); err == nil {
+			config.BackupInterval = d
+		} else {
+			log.Printf("WARNING: invalid BACKUP_INTERVAL %q, using %s", v, config.BackupInterval)
+		}
+	}
+	if v := os.Getenv("STORE_COMPACT_INTERVAL"); v != "" {
+		if d, err := time.ParseDuration(v); err == nil {
+			config.StoreCompactInterval = d
+		} else {
+			log.Printf("WARNING: invalid STORE_COMPACT_INTERVAL %q, using %s", v, config.StoreCompactInterval)
+		}
+	}
+	if v := os.Getenv("STORE_REQUEST_RETENTION"); v != "" {
+		if d, err := time.ParseDuration(v); err == nil && d >= 0 {
+			config.StoreRequestRetention = d
+		} else {
+			log.Printf("WARNING: invalid STORE_REQUEST_RETENTION %q, using %s", v, config.StoreRequestRetention)
+		}
+	}
+	if v := os.Getenv("STORE_SIZE_WARN_BYTES"); v != "" {
+		if n, err := strconv.ParseInt(v, 10, 64); err == nil && n >= 0 {
+			config.StoreSizeWarnBytes = n
+		} else {
+			log.Printf("WARNING: invalid STORE_SIZE_WARN_BYTES %q, using %d", v, config.StoreSizeWarnBytes)
+		}
+	}
+	if v := os.Getenv("DATA_MIN_FREE_BYTES"); v != "" {
+		if n, err := strconv.Atoi(v); err == nil && n >= 0 {
+			config.DataMinFreeBytes.Set(n)
+		} else {
+			log.Printf("WARNING: invalid DATA_MIN_FREE_BYTES %q, using %d", v, config.DataMinFreeBytes.Get())
+		}
+	}
+	if v := os.Getenv("STALE_GRANT_AGE"); v != "" {
+		if d, err := time.ParseDuration(v); err == nil && d > 0 {
+			config.StaleGrantAge.Set(d)
+		} else {
+			log.Printf("WARNING: invalid STALE_GRANT_AGE %q, using %s", v, config.StaleGrantAge.Get())
+		}
+	}
+	if v := os.Getenv("RECENT_VIEWS_MAX"); v != "" {
+		if n, err := strconv.Atoi(v); err == nil && n >= 0 {
+			config.RecentViewsMax.Set(n)
+		} else {
+			log.Printf("WARNING: invalid RECENT_VIEWS_MAX %q, using %d", v, config.RecentViewsMax.Get())
+		}
+	}
+	config.SeedFile = os.Getenv("SEED_FILE")
+	if v := os.Getenv("FGA_WRITE_BATCH_MAX"); v != "" {
+		if n, err := strconv.Atoi(v); err == nil && n > 0 {
+			config.FgaWriteBatchMax.Set(n)
+		} else {
+			log.Printf("WARNING: invalid FGA_WRITE_BATCH_MAX %q, using %d", v, config.FgaWriteBatchMax.Get())
+		}
+	}
+	if v := os.Getenv("UNDO_WINDOW"); v != "" {
+		if d, err := time.ParseDuration(v); err == nil && d >= 0 {
+			config.UndoWindow.Set(d)
+		} else {
+			log.Printf("WARNING: invalid UNDO_WINDOW %q, using %s", v, config.UndoWindow.Get())
+		}
+	}
+	switch provider := os.Getenv("SECRETS_PROVIDER"); provider {
+	case "", "env":
+	case "file":
+		config.Secrets = config.FileSecrets{Dir: "/run/secrets"}
+		if v := os.Getenv("SECRETS_DIR"); v != "" {
+			config.Secrets = config.FileSecrets{Dir: v}
+		}
+	case "vault":
+		token := os.Getenv("VAULT_TOKEN")
+		if path := os.Getenv("VAULT_TOKEN_FILE"); path != "" {
+			raw, err := os.ReadFile(path)
+			if err != nil {
+				log.Fatalf("Reading VAULT_TOKEN_FILE: %v", err)
+			}
+			token = strings.TrimSpace(string(raw))
+		}
+		path := os.Getenv("VAULT_SECRET_PATH")
+		if path == "" {
+			path = "secret/data/authz-poc"
+		}
+		config.Secrets = config.VaultSecrets{Addr: os.Getenv("VAULT_ADDR"), Token: token, Path: path}
+	default:
+		log.Fatalf("Unknown SECRETS_PROVIDER %q (env, file or vault)", provider)
+	}
+	config.OnSecret(config.ContentKeys, store.SetContentKeys)
+	if err := config.LoadSecrets(); err != nil {
+		log.Fatalf("Loading secrets from %s: %v", config.Secrets.Name(), err)
+	}
+	if _, ok := config.Secrets.(config.EnvSecrets); !ok {
+		interval := time.Minute
+		if v := os.Getenv("SECRETS_REFRESH_INTERVAL"); v != "" {
+			if d, err := time.ParseDuration(v); err == nil {
+				interval = d
+			} else {
+				log.Printf("WARNING: invalid SECRETS_REFRESH_INTERVAL %q, using %s", v, interval)
+			}
+		}
+		if interval > 0 {
+			go config.WatchSecrets(interval)
+		}
+	}
+	if v := os.Getenv("FGA_CALL_BUDGET"); v != "" {
+		if n, err := strconv.Atoi(v); err == nil && n >= 0 {
+			config.FgaCallBudget.Set(n)
+		} else {
+			log.Printf("WARNING: invalid FGA_CALL_BUDGET %q, using %d", v, config.FgaCallBudget.Get())
+		}
+	}
+	if v := os.Getenv("LIST_CHECK_CONCURRENCY"); v != "" {
+		if n, err := strconv.Atoi(v); err == nil && n >= 1 {
+			config.ListCheckConcurrency.Set(n)
+		} else {
+			log.Printf("WARNING: invalid LIST_CHECK_CONCURRENCY %q, using %d", v, config.ListCheckConcurrency.Get())
+		}
+	}
+	switch v := os.Getenv("FGA_BUDGET_MODE"); v {
+	case "":
+	case "log", "reject":
+		config.FgaBudgetMode.Set(v)
+	default:
+		log.Printf("WARNING: invalid FGA_BUDGET_MODE %q, using %s", v, config.FgaBudgetMode.Get())
+	}
+	fga.SetShadow(fga.ShadowConfig{StoreId: os.Getenv("FGA_SHADOW_STORE_ID"), ModelId: os.Getenv("FGA_SHADOW_MODEL_ID")})
+	switch v := os.Getenv("IDENTITY_SIGNATURES"); v {
+	case "":
+	case "enforce", "log", "off":
+		config.IdentitySignatures.Set(v)
+	default:
+		log.Printf("WARNING: invalid IDENTITY_SIGNATURES %q, using %s", v, config.IdentitySignatures.Get())
+	}
+	switch v := os.Getenv("RESPONSE_ENVELOPE"); v {
+	case "":
+	case "legacy", "both", "envelope":
+		config.ResponseEnvelope.Set(v)
+	default:
+		log.Printf("WARNING: invalid RESPONSE_ENVELOPE %q, using %s", v, config.ResponseEnvelope.Get())
+	}
+	config.GuestMode.Set(os.Getenv("GUEST_MODE") == "true")
+	config.HideExistence.Set(os.Getenv("HIDE_EXISTENCE") == "true")
+	if config.Secret(config.SigningKey) == "" {
+		log.Printf("WARNING: SIGNING_KEY is not set; identity headers are trusted without verification")
+	}
+	config.ExtAuthzAddr = os.Getenv("EXT_AUTHZ_ADDR")
+	if v := os.Getenv("API_SUNSET"); v != "" {
+		if t, err := time.Parse("2006-01-02", v); err == nil {
+			config.APISunset = t
+		} else {
+			log.Printf("WARNING: invalid API_SUNSET %q (want YYYY-MM-DD), omitting the Sunset header", v)
+		}
+	}
+	config.OPALogsRelayURL = config.AIManagerURL + "/logs"
+	if v, ok := os.LookupEnv("OPA_LOGS_RELAY_URL"); ok {
+		config.OPALogsRelayURL = v
+	}
+	if v := os.Getenv("OPA_POLICIES_DIR"); v != "" {
+		config.OPAPoliciesDir = v
+	}
+	if v := os.Getenv("ANONYMIZE"); v != "" {
+		if on, err := strconv.ParseBool(v); err == nil {
+			privacy.SetEnabled(on)
+		} else {
+			log.Printf("WARNING: invalid ANONYMIZE %q, using false", v)
+		}
+	}
+	if v := os.Getenv("SANDBOX_TTL"); v != "" {
+		if d, err := time.ParseDuration(v); err == nil && d > 0 {
+			config.SandboxTTL = d
+		} else {
+			log.Printf("WARNING: invalid SANDBOX_TTL %q, using %s", v, config.SandboxTTL)
+		}
+	}
+	if v := os.Getenv("ROLE_TUPLES"); v != "" {
+		if _, err := rolesync.Parse(v); err == nil {
+			config.RoleTuples = v
+		} else {
+			log.Printf("WARNING: invalid ROLE_TUPLES: %v, role sync disabled", err)
+		}
+	}
+	if v := os.Getenv("ROLE_SYNC_INTERVAL"); v != "" {
+		if d, err := time.ParseDuration(v); err == nil && d >= 0 {
+			config.RoleSyncInterval = d
+		} else {
+			log.Printf("WARNING: invalid ROLE_SYNC_INTERVAL %q, using %s", v, config.RoleSyncInterval)
+		}
+	}
+	config.KeycloakURL = os.Getenv("KEYCLOAK_URL")
+	if v := os.Getenv("KEYCLOAK_REALM"); v != "" {
+		config.KeycloakRealm = v
+	}
+	if v := os.Getenv("KEYCLOAK_SYNC_CLIENT_ID"); v != "" {
+		config.KeycloakSyncClientId = v
+	}
+	if v := os.Getenv("JOB_RETENTION"); v != "" {
+		if d, err := time.ParseDuration(v); err == nil && d > 0 {
+			config.JobRetention = d
+		} else {
+			log.Printf("WARNING: invalid JOB_RETENTION %q, using %s", v, config.JobRetention)
+		}
+	}
+	if v := os.Getenv("BACKUP_RETENTION"); v != "" {
+		if n, err := strconv.Atoi(v); err == nil && n > 0 {
+			config.BackupRetention = n
+		} else {
+			log.Printf("WARNING: invalid BACKUP_RETENTION %q, using %d", v, config.BackupRetention)
+		}
+	}
+	if v := os.Getenv("CORS_ALLOWED_ORIGINS"); v != "" {
+		for _, o := range strings.Split(v, ",") {
+			if o = strings.TrimSpace(o); o != "" {
+				config.CORSAllowedOrigins = append(config.CORSAllowedOrigins, o)
+			}
+		}
+	}
+	config.CORSAllowCredentials = os.Getenv("CORS_ALLOW_CREDENTIALS") == "true"
+	config.CORSAllowUserHeader = os.Getenv("CORS_ALLOW_USER_HEADER") == "true"
+	if config.CORSAllowCredentials && httputil.Contains(config.CORSAllowedOrigins, "*") {
+		log.Printf("WARNING: CORS_ALLOW_CREDENTIALS ignored with wildcard origin")
+		config.CORSAllowCredentials = false
+	}
+	if config.CORSAllowUserHeader {
+		log.Printf("WARNING: CORS_ALLOW_USER_HEADER is set; cross-origin callers can choose their identity")
+	}
+
+	if path := os.Getenv("RESOURCE_TYPES_FILE"); path != "" {
+		if err := resources.LoadFile(path); err != nil {
+			log.Printf("WARNING: failed to load resource types from %s: %v", path, err)
+		}
+	}
+
+	// CONFIG_FILE is a mounted file of tunables (e.g. LIST_OBJECTS_CACHE_TTL: 30s)
+	// that is re-read while the app runs; see GET /api/admin/config.
+	configFile := os.Getenv("CONFIG_FILE")
+	if err := config.InitTunables(configFile); err != nil {
+		log.Printf("WARNING: config file %s: %v", configFile, err)
+	}
+	if configFile != "" {
+		interval := 10 * time.Second
+		if v := os.Getenv("CONFIG_REFRESH_INTERVAL"); v != "" {
+			if d, err := time.ParseDuration(v); err == nil {
+				interval = d
+			} else {
+				log.Printf("WARNING: invalid CONFIG_REFRESH_INTERVAL %q, using %s", v, interval)
+			}
+		}
+		if interval > 0 {
+			go config.WatchTunables(interval)
+		}
+	}
+
+	templates.Init()
 	store.Load()
+	// "reseal-content" encrypts plaintext content and re-encrypts content sealed
+	// with older keys under the active CONTENT_KEYS key, then exits.
+	if len(os.Args) > 1 && os.Args[1] == "reseal-content" {
+		n, err := store.Reseal()
+		if err == nil {
+			err = store.LastSaveErr
+		}
+		if err != nil {
+			log.Fatalf("Resealing content: %v", err)
+		}
+		log.Printf("Resealed %s and %d archive file(s); sealed contents by key: %v", store.DataFile(), n, store.Encryption().Sealed)
+		return
+	}
+	if config.IntegrityMode != "off" {
+		issues := store.CheckIntegrity(config.IntegrityMode == "repair")
+		if len(issues) > 0 {
+			log.Printf("Data integrity: %d issue(s) in %s:\n%s", len(issues), store.DataFile(), store.FormatIssues(issues))
+			if n := store.Unrepaired(issues); n > 0 {
+				log.Fatalf("Refusing to start: %d integrity issue(s) need manual repair (INTEGRITY_MODE=off skips the check)", n)
+			}
+			store.Save()
+		}
+	}
+	if config.BackupInterval > 0 {
+		go backup.RunEvery(config.BackupDir, config.BackupInterval, config.BackupRetention)
+	}
+	store.WarnIfLarge(config.StoreSizeWarnBytes)
+	go func() {
+		for {
+			store.WarnIfLowDisk(int64(config.DataMinFreeBytes.Get()))
+			time.Sleep(time.Minute)
+		}
+	}()
+	if config.StoreCompactInterval > 0 {
+		go store.CompactEvery(config.StoreCompactInterval, config.StoreRequestRetention, config.StoreSizeWarnBytes, sandbox.Live)
+	}
+	go sandbox.RunJanitor(time.Minute)
+	go jobs.RunJanitor(time.Minute)
+	go recent.Run()
+	if config.VisibilityIndex {
+		go visibility.Run(5 * time.Second)
+	}
 
+	// SIGHUP (podman kill -s HUP test-app) retries the startup waits at once,
+	// e.g. right after openfga-init was restarted, and re-reads CONFIG_FILE.
+	hup := make(chan os.Signal, 1)
+	signal.Notify(hup, syscall.SIGHUP)
+	go func() {
+		for range hup {
+			log.Println("SIGHUP: retrying startup dependencies now")
+			backoff.Kick()
+			changed, err := config.ReloadTunables()
+			if len(changed) > 0 {
+				log.Printf("SIGHUP: config file changed %v", changed)
+			}
+			if err != nil {
+				log.Printf("WARNING: config file %s: %v", configFile, err)
+			}
+		}
+	}()
 	go func() {
 		fga.LoadConfig()
-		store.RehydrateTuples(fga.Write)
+		fga.Rehydrate()
+		if config.FgaReady {
+			warmup.Run()
+		}
+		if config.AssertionsInterval > 0 {
+			fga.RunAssertionsEvery(config.AssertionsInterval, sandbox.Live)
+		}
 	}()
+	if config.RoleTuples != "" && config.KeycloakURL != "" && config.RoleSyncInterval > 0 {
+		go rolesync.RunEvery(config.RoleSyncInterval)
+	}
 
 	http.HandleFunc("/public", func(w http.ResponseWriter, r *http.Request) {
 		if httputil.WantsJSON(r) {
@@ -82,10 +506,7 @@ func main() {
 
 	http.HandleFunc("/api/health", func(w http.ResponseWriter, r *http.Request) {
 		if httputil.WantsJSON(r) {
-			httputil.JSONResponse(w, map[string]interface{}{
-				"status": "healthy", "service": "test-app",
-				"uptime": time.Since(config.StartTime).String(), "fgaReady": config.FgaReady,
-			}, http.StatusOK)
+			handlers.Health(w, r)
 			return
 		}
 		w.Header().Set("Content-Type", "text/html; charset=utf-8")
@@ -94,12 +515,46 @@ func main() {
 
 	http.HandleFunc("/dossiers", func(w http.ResponseWriter, r *http.Request) {
 		user := httputil.GetUser(r)
-		if user == "anonymous" {
+		if user == httputil.Anonymous {
+			http.Redirect(w, r, "/home", http.StatusFound)
+			return
+		}
+		w.Header().Set("Content-Type", "text/html; charset=utf-8")
+		templates.Dossiers.Execute(w, templates.DossiersPageData{Username: user, Path: r.URL.Path, Lang: i18n.Lang(r)})
+	})
+
+	http.HandleFunc("/resources/", func(w http.ResponseWriter, r *http.Request) {
+		user := httputil.GetUser(r)
+		if user == httputil.Anonymous {
 			http.Redirect(w, r, "/home", http.StatusFound)
 			return
 		}
+		t, ok := resources.ByPlural(strings.TrimPrefix(r.URL.Path, "/resources/"))
+		if !ok {
+			http.NotFound(w, r)
+			return
+		}
 		w.Header().Set("Content-Type", "text/html; charset=utf-8")
-		templates.Dossiers.Execute(w, templates.DossiersPageData{Username: user})
+		templates.Resources.Execute(w, templates.ResourcesPageData{Username: user, Path: r.URL.Path, Lang: i18n.Lang(r), Type: t})
+	})
+
+	http.HandleFunc("/partials/dossiers", func(w http.ResponseWriter, r *http.Request) {
+		if r.Method == "GET" {
+			handlers.PartialDossierList(w, r)
+		}
+	})
+	http.HandleFunc("/partials/dossiers/", func(w http.ResponseWriter, r *http.Request) {
+		parts := strings.Split(strings.TrimPrefix(r.URL.Path, "/partials/dossiers/"), "/")
+		if len(parts) == 2 && parts[1] == "relations" && r.Method == "GET" {
+			handlers.PartialRelationRows(w, r, parts[0])
+			return
+		}
+		http.NotFound(w, r)
+	})
+	http.HandleFunc("/partials/guardianships/requests", func(w http.ResponseWriter, r *http.Request) {
+		if r.Method == "GET" {
+			handlers.PartialRequestList(w, r)
+		}
 	})
 
 	http.HandleFunc("/api/dossiers/list", func(w http.ResponseWriter, r *http.Request) {
@@ -107,6 +562,11 @@ func main() {
 			handlers.DossiersList(w, r)
 		}
 	})
+	http.HandleFunc("/api/dossiers/shared-by-me", func(w http.ResponseWriter, r *http.Request) {
+		if r.Method == "GET" {
+			handlers.DossiersSharedByMe(w, r)
+		}
+	})
 	http.HandleFunc("/api/dossiers/admin/list", func(w http.ResponseWriter, r *http.Request) {
 		if r.Method == "GET" {
 			handlers.DossiersListAll(w, r)
@@ -144,7 +604,7 @@ func main() {
 		case "POST":
 			handlers.OrganizationsCreate(w, r)
 		default:
-			httputil.JSONError(w, "Method not allowed", 405)
+			httputil.JSONError(w, i18n.T(r, "Method not allowed"), 405)
 		}
 	})
 	http.HandleFunc("/api/dossiers/organizations/", func(w http.ResponseWriter, r *http.Request) {
@@ -157,10 +617,26 @@ func main() {
 			case "DELETE":
 				handlers.OrganizationsRemoveMember(w, r, parts[0])
 			default:
-				httputil.JSONError(w, "Method not allowed", 405)
+				httputil.JSONError(w, i18n.T(r, "Method not allowed"), 405)
 			}
 			return
 		}
+		if len(parts) == 2 && parts[1] == "dossiers" && r.Method == "GET" {
+			handlers.OrganizationsDossiers(w, r, parts[0])
+			return
+		}
+		if len(parts) == 2 && parts[1] == "join" && r.Method == "POST" {
+			handlers.OrganizationsJoin(w, r, parts[0])
+			return
+		}
+		if len(parts) == 2 && parts[1] == "join-requests" && r.Method == "GET" {
+			handlers.OrganizationsJoinRequests(w, r, parts[0])
+			return
+		}
+		if len(parts) == 4 && parts[1] == "join-requests" && (parts[3] == "approve" || parts[3] == "deny") && r.Method == "POST" {
+			handlers.OrganizationsJoinDecide(w, r, parts[0], parts[2], parts[3] == "approve")
+			return
+		}
 		if len(parts) == 2 && parts[1] == "admins" {
 			switch r.Method {
 			case "POST":
@@ -168,7 +644,7 @@ func main() {
 			case "DELETE":
 				handlers.OrganizationsRemoveAdmin(w, r, parts[0])
 			default:
-				httputil.JSONError(w, "Method not allowed", 405)
+				httputil.JSONError(w, i18n.T(r, "Method not allowed"), 405)
 			}
 			return
 		}
@@ -177,7 +653,350 @@ func main() {
 			handlers.OrganizationsDelete(w, r, parts[0])
 			return
 		}
-		httputil.JSONError(w, "Not found", 404)
+		if len(parts) == 1 && parts[0] != "" && r.Method == "PUT" {
+			handlers.OrganizationsUpdate(w, r, parts[0])
+			return
+		}
+		httputil.JSONError(w, i18n.T(r, "Not found"), 404)
+	})
+	http.HandleFunc("/api/authz/explain", func(w http.ResponseWriter, r *http.Request) {
+		if r.Method == "GET" {
+			handlers.AuthzExplain(w, r)
+		}
+	})
+	http.HandleFunc("/api/authz/nl-command", func(w http.ResponseWriter, r *http.Request) {
+		if r.Method == "POST" {
+			handlers.AuthzNLCommand(w, r)
+		}
+	})
+	http.HandleFunc("/api/audit/trace/", func(w http.ResponseWriter, r *http.Request) {
+		id := strings.TrimPrefix(r.URL.Path, "/api/audit/trace/")
+		if r.Method == "GET" && id != "" {
+			handlers.AuditTrace(w, r, id)
+		}
+	})
+	http.HandleFunc("/api/me/export", func(w http.ResponseWriter, r *http.Request) {
+		if r.Method == "GET" {
+			handlers.MeExport(w, r)
+		}
+	})
+	http.HandleFunc("/api/me/organizations", func(w http.ResponseWriter, r *http.Request) {
+		if r.Method == "GET" {
+			handlers.MeOrganizations(w, r)
+		}
+	})
+	http.HandleFunc("/api/me/inbox", func(w http.ResponseWriter, r *http.Request) {
+		if r.Method == "GET" {
+			handlers.MeInbox(w, r)
+		}
+	})
+	http.HandleFunc("/api/me/notifications", func(w http.ResponseWriter, r *http.Request) {
+		if r.Method == "GET" {
+			handlers.MeNotifications(w, r)
+		}
+	})
+	http.HandleFunc("/api/me/recent", func(w http.ResponseWriter, r *http.Request) {
+		if r.Method == "GET" {
+			handlers.MeRecent(w, r)
+		}
+	})
+	http.HandleFunc("/api/views", func(w http.ResponseWriter, r *http.Request) {
+		switch r.Method {
+		case "GET":
+			handlers.ViewsList(w, r)
+		case "POST":
+			handlers.ViewsCreate(w, r)
+		default:
+			httputil.JSONError(w, i18n.T(r, "Method not allowed"), 405)
+		}
+	})
+	http.HandleFunc("/api/views/", func(w http.ResponseWriter, r *http.Request) {
+		parts := strings.Split(strings.TrimPrefix(r.URL.Path, "/api/views/"), "/")
+		switch {
+		case len(parts) == 1 && parts[0] != "" && r.Method == "DELETE":
+			handlers.ViewsDelete(w, r, parts[0])
+		case len(parts) == 2 && parts[1] == "results" && r.Method == "GET":
+			handlers.ViewsResults(w, r, parts[0])
+		default:
+			httputil.JSONError(w, i18n.T(r, "Not found"), 404)
+		}
+	})
+	http.HandleFunc("/api/tour", func(w http.ResponseWriter, r *http.Request) {
+		if r.Method == "GET" {
+			handlers.Tour(w, r)
+			return
+		}
+		httputil.JSONError(w, i18n.T(r, "Method not allowed"), 405)
+	})
+	http.HandleFunc("/api/tour/", func(w http.ResponseWriter, r *http.Request) {
+		parts := strings.Split(strings.TrimPrefix(r.URL.Path, "/api/tour/"), "/")
+		if len(parts) == 2 && parts[1] == "setup" && r.Method == "POST" {
+			handlers.TourSetup(w, r, parts[0])
+			return
+		}
+		httputil.JSONError(w, i18n.T(r, "Not found"), 404)
+	})
+	http.HandleFunc("/api/users/", func(w http.ResponseWriter, r *http.Request) {
+		parts := strings.Split(strings.TrimPrefix(r.URL.Path, "/api/users/"), "/")
+		if len(parts) == 2 && parts[1] == "profile" && r.Method == "GET" {
+			handlers.UsersProfile(w, r, parts[0])
+			return
+		}
+		httputil.JSONError(w, i18n.T(r, "Not found"), 404)
+	})
+	http.HandleFunc("/api/undo/", func(w http.ResponseWriter, r *http.Request) {
+		token := strings.TrimPrefix(r.URL.Path, "/api/undo/")
+		if r.Method == "POST" && token != "" {
+			handlers.Undo(w, r, token)
+			return
+		}
+		httputil.JSONError(w, i18n.T(r, "Not found"), 404)
+	})
+	http.HandleFunc("/api/admin/stats", func(w http.ResponseWriter, r *http.Request) {
+		if r.Method == "GET" {
+			handlers.AdminStats(w, r)
+		}
+	})
+	http.HandleFunc("/api/admin/posture", func(w http.ResponseWriter, r *http.Request) {
+		if r.Method == "GET" {
+			handlers.AdminPosture(w, r)
+		}
+	})
+	http.HandleFunc("/api/admin/stale-grants", func(w http.ResponseWriter, r *http.Request) {
+		if r.Method == "GET" {
+			handlers.AdminStaleGrants(w, r)
+		}
+	})
+	http.HandleFunc("/api/admin/stale-grants/revoke", func(w http.ResponseWriter, r *http.Request) {
+		if r.Method == "POST" {
+			handlers.AdminStaleGrantsRevoke(w, r)
+		}
+	})
+	http.HandleFunc("/api/admin/role-sync", func(w http.ResponseWriter, r *http.Request) {
+		switch r.Method {
+		case "GET":
+			handlers.AdminRoleSync(w, r)
+		case "POST":
+			handlers.AdminRoleSyncRun(w, r)
+		default:
+			httputil.JSONError(w, i18n.T(r, "Method not allowed"), 405)
+		}
+	})
+	http.HandleFunc("/api/admin/wildcard-tuples", func(w http.ResponseWriter, r *http.Request) {
+		if r.Method == "GET" {
+			handlers.AdminWildcardTuples(w, r)
+		}
+	})
+	http.HandleFunc("/api/admin/cache", func(w http.ResponseWriter, r *http.Request) {
+		switch r.Method {
+		case "GET", "DELETE":
+			handlers.AdminListCache(w, r)
+		default:
+			httputil.JSONError(w, i18n.T(r, "Method not allowed"), 405)
+		}
+	})
+	http.HandleFunc("/api/admin/faults", func(w http.ResponseWriter, r *http.Request) {
+		switch r.Method {
+		case "GET", "PUT", "DELETE":
+			handlers.AdminFaults(w, r)
+		default:
+			httputil.JSONError(w, i18n.T(r, "Method not allowed"), 405)
+		}
+	})
+	http.HandleFunc("/api/admin/config", func(w http.ResponseWriter, r *http.Request) {
+		switch r.Method {
+		case "GET":
+			handlers.AdminConfig(w, r)
+		default:
+			httputil.JSONError(w, i18n.T(r, "Method not allowed"), 405)
+		}
+	})
+	http.HandleFunc("/api/admin/audit/sampling", func(w http.ResponseWriter, r *http.Request) {
+		switch r.Method {
+		case "GET", "PUT", "DELETE":
+			handlers.AdminAuditSampling(w, r)
+		default:
+			httputil.JSONError(w, i18n.T(r, "Method not allowed"), 405)
+		}
+	})
+	http.HandleFunc("/api/admin/shadow", func(w http.ResponseWriter, r *http.Request) {
+		switch r.Method {
+		case "GET", "PUT", "DELETE":
+			handlers.AdminShadow(w, r)
+		default:
+			httputil.JSONError(w, i18n.T(r, "Method not allowed"), 405)
+		}
+	})
+	http.HandleFunc("/api/admin/encryption", func(w http.ResponseWriter, r *http.Request) {
+		if r.Method == "GET" {
+			handlers.AdminEncryption(w, r)
+		}
+	})
+	http.HandleFunc("/api/admin/encryption/reseal", func(w http.ResponseWriter, r *http.Request) {
+		if r.Method == "POST" {
+			handlers.AdminReseal(w, r)
+		}
+	})
+	http.HandleFunc("/api/admin/reset", func(w http.ResponseWriter, r *http.Request) {
+		if r.Method == "POST" {
+			handlers.AdminReset(w, r)
+		}
+	})
+	http.HandleFunc("/api/admin/graph.dot", func(w http.ResponseWriter, r *http.Request) {
+		if r.Method == "GET" {
+			handlers.AdminGraphDOT(w, r)
+		}
+	})
+	http.HandleFunc("/api/admin/decisions", func(w http.ResponseWriter, r *http.Request) {
+		if r.Method == "GET" {
+			handlers.AdminDecisions(w, r)
+		}
+	})
+	http.HandleFunc("/api/admin/model/diff", func(w http.ResponseWriter, r *http.Request) {
+		if r.Method == "POST" {
+			handlers.AdminModelDiff(w, r)
+		}
+	})
+	http.HandleFunc("/api/admin/fga/config", func(w http.ResponseWriter, r *http.Request) {
+		if r.Method == "POST" {
+			handlers.AdminFgaConfig(w, r)
+		}
+	})
+	http.HandleFunc("/api/admin/tuples/export", func(w http.ResponseWriter, r *http.Request) {
+		if r.Method == "GET" {
+			handlers.TuplesExport(w, r)
+		}
+	})
+	http.HandleFunc("/api/admin/jobs", func(w http.ResponseWriter, r *http.Request) {
+		switch r.Method {
+		case "GET":
+			handlers.JobsList(w, r)
+		case "POST":
+			handlers.JobsCreate(w, r)
+		default:
+			httputil.JSONError(w, i18n.T(r, "Method not allowed"), 405)
+		}
+	})
+	http.HandleFunc("/api/admin/jobs/", func(w http.ResponseWriter, r *http.Request) {
+		parts := strings.Split(strings.TrimPrefix(r.URL.Path, "/api/admin/jobs/"), "/")
+		switch {
+		case len(parts) == 1 && parts[0] != "" && r.Method == "GET":
+			handlers.JobsGet(w, r, parts[0])
+		case len(parts) == 1 && parts[0] != "" && r.Method == "DELETE":
+			handlers.JobsCancel(w, r, parts[0])
+		case len(parts) == 2 && parts[1] == "download" && r.Method == "GET":
+			handlers.JobsDownload(w, r, parts[0])
+		default:
+			httputil.JSONError(w, i18n.T(r, "Not found"), 404)
+		}
+	})
+	http.HandleFunc("/api/admin/tuples/import", func(w http.ResponseWriter, r *http.Request) {
+		if r.Method == "POST" {
+			handlers.TuplesImport(w, r)
+		}
+	})
+	http.HandleFunc("/api/admin/assertions", func(w http.ResponseWriter, r *http.Request) {
+		switch r.Method {
+		case "GET":
+			handlers.AssertionsList(w, r)
+		case "POST":
+			handlers.AssertionsAdd(w, r)
+		}
+	})
+	http.HandleFunc("/api/admin/assertions/run", func(w http.ResponseWriter, r *http.Request) {
+		if r.Method == "POST" {
+			handlers.AssertionsRun(w, r)
+		}
+	})
+	http.HandleFunc("/api/admin/analytics", func(w http.ResponseWriter, r *http.Request) {
+		if r.Method == "GET" || r.Method == "DELETE" {
+			handlers.AdminAnalytics(w, r)
+		}
+	})
+	http.HandleFunc("/api/admin/users/", func(w http.ResponseWriter, r *http.Request) {
+		id := strings.TrimPrefix(r.URL.Path, "/api/admin/users/")
+		if r.Method == "DELETE" && id != "" {
+			handlers.AdminEraseUser(w, r, id)
+		}
+	})
+	http.HandleFunc("/api/admin/backups", func(w http.ResponseWriter, r *http.Request) {
+		switch r.Method {
+		case "GET":
+			handlers.BackupsList(w, r)
+		case "POST":
+			handlers.BackupsCreate(w, r)
+		}
+	})
+	http.HandleFunc("/api/admin/backups/", func(w http.ResponseWriter, r *http.Request) {
+		parts := strings.Split(strings.TrimPrefix(r.URL.Path, "/api/admin/backups/"), "/")
+		if len(parts) == 2 && parts[1] == "restore" && r.Method == "POST" {
+			handlers.BackupsRestore(w, r, parts[0])
+		}
+	})
+	http.HandleFunc("/api/admin/organizations/", func(w http.ResponseWriter, r *http.Request) {
+		parts := strings.Split(strings.TrimPrefix(r.URL.Path, "/api/admin/organizations/"), "/")
+		if len(parts) == 1 && parts[0] == "orphaned" && r.Method == "GET" {
+			handlers.AdminOrganizationsOrphaned(w, r)
+			return
+		}
+		if len(parts) == 2 && parts[1] == "admins" && r.Method == "POST" {
+			handlers.AdminAppointOrgAdmin(w, r, parts[0])
+		}
+	})
+	http.HandleFunc("/api/admin/privacy", func(w http.ResponseWriter, r *http.Request) {
+		if r.Method == "GET" || r.Method == "PUT" {
+			handlers.AdminPrivacy(w, r)
+		}
+	})
+	http.HandleFunc("/api/admin/changes", func(w http.ResponseWriter, r *http.Request) {
+		if r.Method == "GET" {
+			handlers.AdminChanges(w, r)
+		}
+	})
+	http.HandleFunc("/api/admin/sandbox", func(w http.ResponseWriter, r *http.Request) {
+		switch r.Method {
+		case "GET":
+			handlers.SandboxList(w, r)
+		case "POST":
+			handlers.SandboxCreate(w, r)
+		}
+	})
+	http.HandleFunc("/api/admin/sandbox/", func(w http.ResponseWriter, r *http.Request) {
+		id := strings.TrimPrefix(r.URL.Path, "/api/admin/sandbox/")
+		if r.Method == "DELETE" && id != "" {
+			handlers.SandboxDelete(w, r, id)
+		}
+	})
+	http.HandleFunc("/api/admin/resources/model", func(w http.ResponseWriter, r *http.Request) {
+		if r.Method == "GET" {
+			handlers.AdminResourceModel(w, r)
+		}
+	})
+	http.HandleFunc("/hooks/keycloak", func(w http.ResponseWriter, r *http.Request) {
+		if r.Method == "POST" {
+			handlers.KeycloakHook(w, r)
+		} else {
+			httputil.JSONError(w, i18n.T(r, "Method not allowed"), 405)
+		}
+	})
+	http.HandleFunc("/opa/decision-logs", func(w http.ResponseWriter, r *http.Request) {
+		if r.Method == "POST" {
+			handlers.OPADecisionLogs(w, r)
+		}
+	})
+	http.HandleFunc("/opa/bundles/", func(w http.ResponseWriter, r *http.Request) {
+		name := strings.TrimPrefix(r.URL.Path, "/opa/bundles/")
+		if r.Method == "GET" || r.Method == "HEAD" {
+			handlers.OPABundle(w, r, name)
+		}
+	})
+	http.HandleFunc("/api/resources", handlers.ResourcesRouter)
+	http.HandleFunc("/api/resources/", handlers.ResourcesRouter)
+	http.HandleFunc("/api/admin/replay/", func(w http.ResponseWriter, r *http.Request) {
+		id := strings.TrimPrefix(r.URL.Path, "/api/admin/replay/")
+		if r.Method == "POST" && id != "" {
+			handlers.AdminReplay(w, r, id)
+		}
 	})
 	http.HandleFunc("/api/dossiers/debug/tuples", func(w http.ResponseWriter, r *http.Request) {
 		handlers.DebugTuples(w, r)
@@ -199,27 +1018,36 @@ func main() {
 			handlers.GuardianshipRemove(w, r, parts[0])
 			return
 		}
-		httputil.JSONError(w, "Not found", 404)
+		httputil.JSONError(w, i18n.T(r, "Not found"), 404)
 	})
 
 	http.HandleFunc("/api/dossiers/", func(w http.ResponseWriter, r *http.Request) {
 		path := strings.TrimPrefix(r.URL.Path, "/api/dossiers/")
 		if strings.HasPrefix(path, "list") || strings.HasPrefix(path, "create") ||
 			strings.HasPrefix(path, "guardianships") || strings.HasPrefix(path, "debug") ||
-			strings.HasPrefix(path, "status") || strings.HasPrefix(path, "organizations") {
+			strings.HasPrefix(path, "status") || strings.HasPrefix(path, "organizations") ||
+			strings.HasPrefix(path, "shared-by-me") {
 			return
 		}
 
 		parts := strings.Split(path, "/")
+		// Access requests come from users who cannot view the dossier yet, and
+		// unpinning a favorite succeeds whether or not the dossier exists.
+		exempt := len(parts) == 2 && ((parts[1] == "access-requests" && r.Method == "POST") || (parts[1] == "favorite" && r.Method == "DELETE"))
+		if parts[0] != "" && !exempt && handlers.DossierHidden(w, r, parts[0]) {
+			return
+		}
 		if len(parts) == 1 && parts[0] != "" {
 			id := parts[0]
 			switch r.Method {
+			case "GET":
+				handlers.DossiersGet(w, r, id)
 			case "PUT":
 				handlers.DossiersUpdate(w, r, id)
 			case "DELETE":
 				handlers.DossiersDelete(w, r, id)
 			default:
-				httputil.JSONError(w, "Method not allowed", 405)
+				httputil.JSONError(w, i18n.T(r, "Method not allowed"), 405)
 			}
 			return
 		}
@@ -233,10 +1061,65 @@ func main() {
 			case "DELETE":
 				handlers.DossiersRelationsDelete(w, r, id)
 			default:
-				httputil.JSONError(w, "Method not allowed", 405)
+				httputil.JSONError(w, i18n.T(r, "Method not allowed"), 405)
 			}
 			return
 		}
+		if len(parts) == 3 && parts[1] == "relations" && parts[2] == "revoke-chain" && r.Method == "POST" {
+			handlers.DossiersRelationsRevokeChain(w, r, parts[0])
+			return
+		}
+		if len(parts) == 2 && parts[1] == "owners" {
+			switch r.Method {
+			case "POST":
+				handlers.DossiersOwnersAdd(w, r, parts[0])
+			case "DELETE":
+				handlers.DossiersOwnersRemove(w, r, parts[0])
+			default:
+				httputil.JSONError(w, i18n.T(r, "Method not allowed"), 405)
+			}
+			return
+		}
+		if len(parts) == 2 && parts[1] == "share-suggestions" && r.Method == "GET" {
+			handlers.DossiersShareSuggestions(w, r, parts[0])
+			return
+		}
+		if len(parts) == 2 && parts[1] == "org" && r.Method == "POST" {
+			handlers.DossiersSetOrg(w, r, parts[0])
+			return
+		}
+		if len(parts) == 2 && parts[1] == "access-requests" {
+			switch r.Method {
+			case "GET":
+				handlers.DossiersAccessRequests(w, r, parts[0])
+			case "POST":
+				handlers.DossiersAccessRequest(w, r, parts[0])
+			default:
+				httputil.JSONError(w, i18n.T(r, "Method not allowed"), 405)
+			}
+			return
+		}
+		if len(parts) == 4 && parts[1] == "access-requests" && (parts[3] == "approve" || parts[3] == "deny") && r.Method == "POST" {
+			handlers.DossiersAccessDecide(w, r, parts[0], parts[2], parts[3] == "approve")
+			return
+		}
+		if len(parts) == 2 && parts[1] == "favorite" {
+			switch r.Method {
+			case "POST", "DELETE":
+				handlers.DossiersFavorite(w, r, parts[0])
+			default:
+				httputil.JSONError(w, i18n.T(r, "Method not allowed"), 405)
+			}
+			return
+		}
+		if len(parts) == 2 && parts[1] == "archive" && r.Method == "POST" {
+			handlers.DossiersArchive(w, r, parts[0])
+			return
+		}
+		if len(parts) == 2 && parts[1] == "restore" && r.Method == "POST" {
+			handlers.DossiersRestore(w, r, parts[0])
+			return
+		}
 		if len(parts) == 2 && parts[1] == "toggle-public" && r.Method == "POST" {
 			handlers.DossiersTogglePublic(w, r, parts[0])
 			return
@@ -253,11 +1136,11 @@ func main() {
 			handlers.DossiersEmergencyCheck(w, r, parts[0])
 			return
 		}
-		httputil.JSONError(w, "Not found", 404)
+		httputil.JSONError(w, i18n.T(r, "Not found"), 404)
 	})
 
 	http.HandleFunc("/api/dossiers/status", func(w http.ResponseWriter, r *http.Request) {
-		httputil.JSONResponse(w, map[string]interface{}{"ready": config.FgaReady, "storeId": config.FgaStoreId, "modelId": config.FgaModelId}, 200)
+		httputil.JSONResponse(w, map[string]interface{}{"ready": config.FgaReady && warmup.Ready(), "storeId": config.FgaStoreId, "modelId": config.FgaModelId, "warmup": warmup.Current()}, 200)
 	})
 
 	http.HandleFunc("/home", func(w http.ResponseWriter, r *http.Request) {
@@ -282,8 +1165,29 @@ func main() {
 		fmt.Fprintf(w, "Not found: %s", r.URL.Path)
 	})
 
+	corsHeaders := []string{"Content-Type", "Accept", "Accept-Language", "If-None-Match", "x-request-id", httputil.VersionHeader, sandbox.Header}
+	if config.CORSAllowUserHeader {
+		corsHeaders = append(corsHeaders, "x-current-user")
+	}
+	// /api/v1/... reaches the handlers registered under /api/ above; see httputil.Versioned.
+	handler := httputil.CORS(httputil.Versioned(audit.TraceRequests(identity.Verify(identity.Require(httputil.Compress(budget.Track(rolesync.OnLogin(profiles.Track(sandbox.Route(http.DefaultServeMux))), http.DefaultServeMux), config.CompressMinSize)))), config.APISunset), httputil.CORSConfig{
+		AllowedOrigins:   config.CORSAllowedOrigins,
+		AllowedHeaders:   corsHeaders,
+		AllowCredentials: config.CORSAllowCredentials,
+		MaxAge:           600,
+	})
+
+	if config.ExtAuthzAddr != "" {
+		go func() {
+			log.Printf("ext_authz service listening on %s", config.ExtAuthzAddr)
+			if err := http.ListenAndServe(config.ExtAuthzAddr, sandbox.LiveHandler(extauthz.Handler())); err != nil {
+				log.Printf("WARNING: ext_authz service stopped: %v", err)
+			}
+		}()
+	}
+
 	log.Printf("Server starting on port %s", port)
-	if err := http.ListenAndServe(":"+port, nil); err != nil {
+	if err := http.ListenAndServe(":"+port, handler); err != nil {
 		log.Fatal(err)
 	}
 }